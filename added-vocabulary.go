@@ -5,6 +5,7 @@ import (
 	"log"
 	"regexp"
 	"sort"
+	"sync"
 	"unicode"
 
 	"github.com/sugarme/regexpset"
@@ -17,7 +18,9 @@ import (
 // AddedToken can be configured to specify the behaviour they should
 // have in various situations. I.e.,:
 // - Whether they should only match single words
-// - Whether to include any whitespace on its left or right
+// - Whether to include any whitespace on its left or right (e.g. RoBERTa
+// `<mask>` strips the whitespaces on its left)
+// - Whether it is a special token, skipped when decoding
 type AddedToken struct {
 	// Content is the content of added token
 	Content string
@@ -29,6 +32,9 @@ type AddedToken struct {
 	RStrip bool
 	// Whether this token should be normalized
 	Normalized bool
+	// Whether this token is a special token. `Tokenizer.AddSpecialTokens`
+	// sets it.
+	Special bool
 }
 
 // DefaultAddedToken initiates a default AddedToken
@@ -39,6 +45,7 @@ func DefaultAddedToken() (retVal AddedToken) {
 		LStrip:     false,
 		RStrip:     false,
 		Normalized: true,
+		Special:    false,
 	}
 }
 
@@ -83,6 +90,7 @@ func NewAddedToken(s string, special bool, opts ...ATOption) (retVal AddedToken)
 	addedTok := DefaultAddedToken()
 	addedTok.Content = s
 	addedTok.Normalized = !special
+	addedTok.Special = special
 
 	for _, opt := range opts {
 		opt(&addedTok)
@@ -119,12 +127,29 @@ func (at AddedToken) SetNormalized(normalized bool) (retVal AddedToken) {
 	return at
 }
 
+// Specify whether this token is a special token.
+func (at AddedToken) SetSpecial(special bool) (retVal AddedToken) {
+	at.Special = special
+	return at
+}
+
 // GetPattern retrieves the pattern built for this token, according to all the specified parameters.
 //
-// NOTE. normalizer input is optional
+// NOTE. normalizer input is optional. If given, the token content is normalized
+// first so that the pattern matches against a normalized string.
 func (at AddedToken) GetPattern(n normalizer.Normalizer) (retVal string) {
 	var reStr string // regular expression pattern
 
+	// normalize the content
+	content := at.Content
+	if n != nil {
+		normalized, err := n.Normalize(normalizer.NewNormalizedFrom(at.Content))
+		if err != nil {
+			log.Fatal(err)
+		}
+		content = normalized.GetNormalized()
+	}
+
 	if at.SingleWord {
 		var firstB, lastB string
 		runes := []rune(at.Content)
@@ -141,31 +166,18 @@ func (at AddedToken) GetPattern(n normalizer.Normalizer) (retVal string) {
 			lastB = ``
 		}
 
-		// normalize the content
-		content := normalizer.NewNormalizedFrom(at.Content)
-		var normalized string
-		if n != nil {
-			normalizedString, err := n.Normalize(content)
-			if err != nil {
-				log.Fatal(err)
-			}
-			normalized = normalizedString.GetNormalized()
-		} else { // don't have a normalizer, just use content as is
-			normalized = at.Content
-		}
-
-		reStr = fmt.Sprintf("%v%v%v", firstB, regexp.QuoteMeta(normalized), lastB)
+		reStr = fmt.Sprintf("%v%v%v", firstB, regexp.QuoteMeta(content), lastB)
 
 	} else {
-		reStr = regexp.QuoteMeta(at.Content)
+		reStr = regexp.QuoteMeta(content)
 	}
 
-	if at.LStrip && at.RStrip {
-		reStr = fmt.Sprintf("(\\s)?%v(\\s)?", reStr)
-	} else if at.LStrip {
-		reStr = fmt.Sprintf("(\\s)?%v", reStr)
-	} else if at.RStrip {
-		reStr = fmt.Sprintf("%v(\\s)?", reStr)
+	// All the whitespaces on the stripped side are part of the match.
+	if at.LStrip {
+		reStr = `\s*` + reStr
+	}
+	if at.RStrip {
+		reStr = reStr + `\s*`
 	}
 
 	return reStr
@@ -197,7 +209,12 @@ type matchingSet struct {
 // in the vocabulary can be decomposed in other tokens, down to the original alphabet. If we
 // were to add new tokens after this training process, we couldn't make sure the merges pairs
 // exist as required.
+//
+// It is safe for concurrent use: tokens can be added while encoding.
 type AddedVocabulary struct {
+	// mu guards all the fields below.
+	mu sync.RWMutex
+
 	// Contains the mapping from String (token content) to ID. This map contains both special
 	// tokens and classic added tokens that were added to the this vocabulary.
 	addedTokenMap map[string]int
@@ -231,17 +248,34 @@ func NewAddedVocabulary() (retVal AddedVocabulary) {
 
 // Len returns size of the additional vocabulary
 func (av *AddedVocabulary) Len() int {
+	av.mu.RLock()
+	defer av.mu.RUnlock()
+
 	return len(av.addedTokenMap)
 }
 
-// GetVocab gets the additional vocabulary
+// GetVocab gets a copy of the additional vocabulary
 func (av *AddedVocabulary) GetVocab() (retVal map[string]int) {
-	return av.addedTokenMap
+	av.mu.RLock()
+	defer av.mu.RUnlock()
+
+	retVal = make(map[string]int, len(av.addedTokenMap))
+	for k, v := range av.addedTokenMap {
+		retVal[k] = v
+	}
+
+	return retVal
 }
 
 // Get the id matching one of our token if it exists
 func (av *AddedVocabulary) TokenToId(token string, model Model) (retVal int, ok bool) {
+	av.mu.RLock()
+	defer av.mu.RUnlock()
 
+	return av.tokenToId(token, model)
+}
+
+func (av *AddedVocabulary) tokenToId(token string, model Model) (retVal int, ok bool) {
 	retVal, ok = av.addedTokenMap[token]
 	if !ok {
 		return model.TokenToId(token)
@@ -252,7 +286,10 @@ func (av *AddedVocabulary) TokenToId(token string, model Model) (retVal int, ok
 
 // Get the token matching the given id if it exists
 func (av *AddedVocabulary) IdToToken(id int, model Model) (retVal string, ok bool) {
+	av.mu.RLock()
 	retVal, ok = av.addedTokenMapR[id]
+	av.mu.RUnlock()
+
 	if !ok {
 		return model.IdToToken(id)
 	}
@@ -262,30 +299,61 @@ func (av *AddedVocabulary) IdToToken(id int, model Model) (retVal string, ok boo
 
 // Check if a token is a special token
 func (av *AddedVocabulary) IsSpecialToken(token string) bool {
-	_, ok := av.specialTokensSet[token]
+	av.mu.RLock()
+	defer av.mu.RUnlock()
+
+	return av.specialTokensSet[token]
+}
+
+// isSpecialId checks whether the given id is the one of a special token.
+func (av *AddedVocabulary) isSpecialId(id int) bool {
+	av.mu.RLock()
+	defer av.mu.RUnlock()
+
+	token, ok := av.addedTokenMapR[id]
 
-	return ok
+	return ok && av.specialTokensSet[token]
+}
+
+// tokens returns copies of the special and classic added tokens, in the
+// order they were added.
+func (av *AddedVocabulary) tokens() (special, added []AddedToken) {
+	av.mu.RLock()
+	defer av.mu.RUnlock()
+
+	special = append([]AddedToken(nil), av.specialTokens...)
+	added = append([]AddedToken(nil), av.addedTokens...)
+
+	return special, added
 }
 
 // Add some special tokens to the vocabulary
 // It returns number of added tokens
 func (av *AddedVocabulary) AddSpecialTokens(tokens []AddedToken, model Model, normalizer normalizer.Normalizer) (retVal int) {
+	av.mu.Lock()
+	defer av.mu.Unlock()
 
-	for _, tok := range tokens {
-		_, isExist := av.specialTokensSet[tok.Content]
-		if tok.Content != "" && !isExist {
-			av.specialTokens = append(av.specialTokens, tok)
-			av.specialTokensSet[tok.Content] = true
-		}
+	special := make([]AddedToken, len(tokens))
+	for i, tok := range tokens {
+		tok.Special = true
+		special[i] = tok
 	}
+	tokens = special
 
 	// Then we delegate to `add_tokens`, that will take care of refreshing added tokens too.
-	return av.AddTokens(tokens, model, normalizer)
+	return av.addTokens(tokens, model, normalizer)
 }
 
 // Add some tokens to the vocabulary
 // It returns number of added tokens
 func (av *AddedVocabulary) AddTokens(tokens []AddedToken, model Model, normalizer normalizer.Normalizer) (retVal int) {
+	av.mu.Lock()
+	defer av.mu.Unlock()
+
+	return av.addTokens(tokens, model, normalizer)
+}
+
+func (av *AddedVocabulary) addTokens(tokens []AddedToken, model Model, normalizer normalizer.Normalizer) (retVal int) {
 
 	ignored := 0
 	for _, token := range tokens {
@@ -294,8 +362,13 @@ func (av *AddedVocabulary) AddTokens(tokens []AddedToken, model Model, normalize
 			continue
 		}
 
+		if token.Special && !av.specialTokensSet[token.Content] {
+			av.specialTokens = append(av.specialTokens, token)
+			av.specialTokensSet[token.Content] = true
+		}
+
 		var id int
-		if i, ok := av.TokenToId(token.Content, model); ok {
+		if i, ok := av.tokenToId(token.Content, model); ok {
 			ignored++
 			id = i
 		} else {
@@ -329,20 +402,21 @@ type tokenId struct {
 func (av *AddedVocabulary) refreshAddedTokens(model Model, normalizer normalizer.Normalizer) {
 	var normIds, nnormIds []int
 	var normPatterns, nnormPatterns []string
-	tokens := append(av.specialTokens, av.addedTokens...)
+	var tokens []AddedToken
+	tokens = append(tokens, av.specialTokens...)
+	tokens = append(tokens, av.addedTokens...)
 	for _, token := range tokens {
-		id, ok := av.TokenToId(token.Content, model)
+		id, ok := av.tokenToId(token.Content, model)
 		if !ok {
 			log.Fatalf("Missing additional token.\n")
 		}
 
-		pattern := token.GetPattern(normalizer)
 		if token.Normalized {
 			normIds = append(normIds, id)
-			normPatterns = append(normPatterns, pattern)
+			normPatterns = append(normPatterns, token.GetPattern(normalizer))
 		} else {
 			nnormIds = append(nnormIds, id)
-			nnormPatterns = append(nnormPatterns, pattern)
+			nnormPatterns = append(nnormPatterns, token.GetPattern(nil))
 		}
 	}
 
@@ -364,22 +438,18 @@ type idOffsets struct {
 	offsets []int
 }
 
-// helper functions to sort idOffsets
-// By implement sort interface of package sort
-
-// byStart sort by offset.Start
+// byStart sorts idOffsets by offset start, then by pattern id. It implements
+// sort.Interface.
 type byStart []idOffsets
 
-func (s byStart) Len() int           { return len(s) }
-func (s byStart) Less(i, j int) bool { return s[i].offsets[0] < s[j].offsets[0] }
-func (s byStart) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
-
-// byId sort by id
-type byId []idOffsets
-
-func (bi byId) Len() int           { return len(bi) }
-func (bi byId) Less(i, j int) bool { return bi[i].id < bi[j].id }
-func (bi byId) Swap(i, j int)      { bi[i], bi[j] = bi[j], bi[i] }
+func (s byStart) Len() int { return len(s) }
+func (s byStart) Less(i, j int) bool {
+	if s[i].offsets[0] != s[j].offsets[0] {
+		return s[i].offsets[0] < s[j].offsets[0]
+	}
+	return s[i].id < s[j].id
+}
+func (s byStart) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 
 // findMatches finds any AddedToken in the given sentence, using the provided MatchingSet.
 // This method returns a list "splits", each of them being a pair of Offsets
@@ -405,9 +475,8 @@ func (av *AddedVocabulary) findMatches(sentence string, splitRe matchingSet) (re
 
 	// Sort id-offsets by start then by pattern id
 	sort.Sort(byStart(ioPairs))
-	sort.Sort(byId(ioPairs))
 
-	// Select the matches, if they overlap, keep them
+	// Select the matches, if they overlap, keep the one with the lowest pattern id
 	var (
 		i              int         = 0
 		currentOffsets int         = 0
@@ -423,22 +492,21 @@ func (av *AddedVocabulary) findMatches(sentence string, splitRe matchingSet) (re
 			continue
 		}
 
-		// Find out whether having overlapping neighbours.
-		// If so, keep the one with lowest Idx. All other will be skipped
-		// because `currentOffsets` will have been increased.
-		if i+1 < len(ioPairs) {
-			overlapPairs := ioPairs[i:]
-			sort.Sort(byId(overlapPairs))
-			lowestPair := overlapPairs[0] // lowest Id one
-			splits = append(splits, lowestPair)
-			currentOffsets = ioPair.offsets[1]
-			i++
-			continue
+		// Among the neighbours overlapping with the current match, apply the
+		// one with the lowest pattern id. All other will be skipped because
+		// `currentOffsets` will have been increased.
+		lowest := ioPair
+		for _, other := range ioPairs[i+1:] {
+			if other.offsets[0] >= ioPair.offsets[1] {
+				break
+			}
+			if other.id < lowest.id {
+				lowest = other
+			}
 		}
 
-		// Not found overlap neighbours. Just apply itself
-		splits = append(splits, ioPair)
-		currentOffsets = ioPair.offsets[1]
+		splits = append(splits, lowest)
+		currentOffsets = lowest.offsets[1]
 		i++
 	}
 
@@ -500,13 +568,38 @@ func (av *AddedVocabulary) splitWithIndices(sentence *normalizer.NormalizedStrin
 // non-normalized one. For example, when we expect to extract the token `yesterday` in the
 // input sentence `I read a book Yesterday`, if the normalizer is supposed to lowercase
 // everything, we expect a match.
+//
+// A leading byte order mark is stripped (see `Tokenizer.WithStripBOM`).
 func (av *AddedVocabulary) ExtractAndNormalize(sequence string, n normalizer.Normalizer) *PreTokenizedString {
+	return av.extractAndNormalize(sequence, n, true, true)
+}
+
+// extractAndNormalize is `ExtractAndNormalize`, optionally without tracking
+// alignments (see `normalizer.NormalizedString.WithoutAlignments`) or
+// stripping a leading BOM.
+func (av *AddedVocabulary) extractAndNormalize(sequence string, n normalizer.Normalizer, alignments, stripBOM bool) *PreTokenizedString {
+	ns := normalizer.NewNormalizedFrom(sequence)
+	if !alignments {
+		ns.WithoutAlignments()
+	}
+	pretokenized := NewPreTokenizedStringFromNS(ns)
+
+	// 0. Strip leading BOM if any. Offsets still point to the original input.
+	if stripBOM {
+		for _, split := range pretokenized.splits {
+			split.normalized.StripBOM()
+		}
+	}
 
-	pretokenized := NewPreTokenizedString(sequence)
+	// Added tokens can be added meanwhile, the matching sets are replaced
+	// (never modified) on refresh.
+	av.mu.RLock()
+	splitRe, splitNormalizedRe := av.splitRe, av.splitNormalizedRe
+	av.mu.RUnlock()
 
 	// 1. Extract all non-normalized tokens from the non-normalized string
 	pretok1 := pretokenized.Split(func(idx int, seq *normalizer.NormalizedString) []SplitIdx {
-		return av.splitWithIndices(seq, av.splitRe)
+		return av.splitWithIndices(seq, splitRe)
 	})
 
 	// 2. Extract the normalized tokens from the normalized pieces of the string
@@ -519,7 +612,7 @@ func (av *AddedVocabulary) ExtractAndNormalize(sequence string, n normalizer.Nor
 				log.Fatal(err)
 			}
 		}
-		return av.splitWithIndices(newSeq, av.splitNormalizedRe)
+		return av.splitWithIndices(newSeq, splitNormalizedRe)
 	})
 
 	return pretok2
@@ -228,3 +228,123 @@ func TestOptionUseCases(t *testing.T) {
 		t.Errorf("Got %+v\n", got)
 	}
 }
+
+// extractedTokens returns the (value, ids) pairs of the splits of ExtractAndNormalize result.
+func extractedTokens(result *tokenizer.PreTokenizedString) [][]interface{} {
+	var got [][]interface{}
+	for _, pretok := range result.GetSplits(normalizer.OriginalTarget, tokenizer.Byte) {
+		var tokIds []int
+		for _, tok := range pretok.Tokens {
+			tokIds = append(tokIds, tok.Id)
+		}
+		got = append(got, []interface{}{pretok.Value, tokIds})
+	}
+
+	return got
+}
+
+func TestExtractAddedTokens_Overlap(t *testing.T) {
+	model := newModelMock([]string{}, []int{})
+	vocab := tokenizer.NewAddedVocabulary()
+
+	vocab.AddSpecialTokens([]tokenizer.AddedToken{
+		tokenizer.NewAddedToken("[CLS]", true),
+		tokenizer.NewAddedToken("[SEP]", true),
+	}, model, nil)
+	vocab.AddTokens([]tokenizer.AddedToken{
+		tokenizer.NewAddedToken("ab", false),
+		tokenizer.NewAddedToken("abc", false),
+	}, model, nil)
+
+	// Matches are picked in order of appearance, whatever their pattern
+	// order. Overlapping matches keep the first added token.
+	got := extractedTokens(vocab.ExtractAndNormalize("[SEP] abcd [CLS]", nil))
+	want := [][]interface{}{
+		{"[SEP]", []int{1}},
+		{" ", []int(nil)},
+		{"ab", []int{2}},
+		{"cd ", []int(nil)},
+		{"[CLS]", []int{0}},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+}
+
+func TestExtractAddedTokens_Normalized(t *testing.T) {
+	model := newModelMock([]string{}, []int{})
+	vocab := tokenizer.NewAddedVocabulary()
+	n := normalizer.Lowercase()
+
+	// Normalized tokens are matched against the normalized input with their
+	// normalized content.
+	vocab.AddTokens([]tokenizer.AddedToken{
+		tokenizer.NewAddedToken("Yesterday", false),
+		tokenizer.NewAddedToken("BOOK", false).SetNormalized(false),
+	}, model, n)
+
+	got := extractedTokens(vocab.ExtractAndNormalize("I read a book YESTERDAY", n))
+	want := [][]interface{}{
+		{"i read a book ", []int(nil)},
+		{"yesterday", []int{0}},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+}
+
+func TestExtractAddedTokens_Strip(t *testing.T) {
+	model := newModelMock([]string{}, []int{})
+	vocab := tokenizer.NewAddedVocabulary()
+
+	// Like RoBERTa `<mask>`, a left stripped token takes all the whitespaces
+	// on its left.
+	vocab.AddSpecialTokens([]tokenizer.AddedToken{
+		tokenizer.NewAddedToken("<mask>", true, tokenizer.WithLStrip(true)),
+		tokenizer.NewAddedToken("<sep>", true, tokenizer.WithRStrip(true)),
+	}, model, nil)
+
+	got := extractedTokens(vocab.ExtractAndNormalize("I  <mask>\tand<sep>  you", nil))
+	want := [][]interface{}{
+		{"I", []int(nil)},
+		{"  <mask>", []int{0}},
+		{"\tand", []int(nil)},
+		{"<sep>  ", []int{1}},
+		{"you", []int(nil)},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+}
+
+func TestTokenizer_AddTokens_Special(t *testing.T) {
+	tk := newWordLevelTokenizer()
+
+	// A token flagged `Special` is special whichever method adds it.
+	tk.AddTokens([]tokenizer.AddedToken{
+		tokenizer.NewAddedToken("<mask>", false, tokenizer.WithLStrip(true)).SetSpecial(true),
+		tokenizer.NewAddedToken("<new>", false),
+	})
+	tk.AddSpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[CLS]", false)})
+
+	if got, want := tk.GetSpecialTokens(), []string{"<mask>", "[CLS]"}; !reflect.DeepEqual(want, got) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	en, err := tk.EncodeSingle("hello   <mask> <new> world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{4, 11, 12, 5}; !reflect.DeepEqual(want, en.Ids) {
+		t.Errorf("want %v, got %v", want, en.Ids)
+	}
+	if want := [][]int{{0, 5}, {5, 14}, {15, 20}, {21, 26}}; !reflect.DeepEqual(want, en.Offsets) {
+		t.Errorf("want %v, got %v", want, en.Offsets)
+	}
+	if got, want := tk.Decode(en.Ids, true), "hello <new> world"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,220 @@
+package bench_test
+
+import (
+	"flag"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/bench"
+)
+
+var (
+	baselineFile   = flag.String("baseline", "", "baseline file of TestBaseline")
+	updateBaseline = flag.Bool("update", false, "record the baseline instead of comparing to it")
+	tolerance      = flag.Float64("tolerance", 0.2, "allowed slowdown relative to the baseline")
+)
+
+const vocabSize = 2000
+
+var (
+	tokenizersMu sync.Mutex
+	tokenizers   = make(map[string]*tokenizer.Tokenizer)
+)
+
+// trained returns the tokenizer of model trained on corpus, training it once.
+func trained(b *testing.B, model string, corpus bench.Corpus) *tokenizer.Tokenizer {
+	tokenizersMu.Lock()
+	defer tokenizersMu.Unlock()
+
+	key := model + "/" + corpus.Name
+	if tk, ok := tokenizers[key]; ok {
+		return tk
+	}
+	tk, err := bench.NewTokenizer(model, corpus, vocabSize)
+	if err != nil {
+		b.Fatal(err)
+	}
+	tokenizers[key] = tk
+
+	return tk
+}
+
+func BenchmarkEncode(b *testing.B) {
+	for _, corpus := range bench.Corpora() {
+		for _, model := range bench.Models {
+			b.Run(fmt.Sprintf("%v/%v", model, corpus.Name), func(b *testing.B) {
+				tk := trained(b, model, corpus)
+				b.SetBytes(int64(corpus.Bytes()))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					for _, s := range corpus.Sequences {
+						if _, err := tk.EncodeSingle(s); err != nil {
+							b.Fatal(err)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkEncodeBatch(b *testing.B) {
+	for _, corpus := range bench.Corpora() {
+		inputs := make([]tokenizer.EncodeInput, len(corpus.Sequences))
+		for i, s := range corpus.Sequences {
+			inputs[i] = tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence(s))
+		}
+		for _, model := range bench.Models {
+			b.Run(fmt.Sprintf("%v/%v", model, corpus.Name), func(b *testing.B) {
+				tk := trained(b, model, corpus)
+				b.SetBytes(int64(corpus.Bytes()))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := tk.EncodeBatch(inputs, false); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	for _, corpus := range bench.Corpora() {
+		for _, model := range bench.Models {
+			b.Run(fmt.Sprintf("%v/%v", model, corpus.Name), func(b *testing.B) {
+				tk := trained(b, model, corpus)
+				ids := make([][]int, len(corpus.Sequences))
+				for i, s := range corpus.Sequences {
+					en, err := tk.EncodeSingle(s)
+					if err != nil {
+						b.Fatal(err)
+					}
+					ids[i] = en.Ids
+				}
+
+				b.SetBytes(int64(corpus.Bytes()))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					for _, seq := range ids {
+						tk.Decode(seq, false)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkTrain(b *testing.B) {
+	corpus := bench.Multilingual()
+	for _, model := range bench.Models {
+		b.Run(model, func(b *testing.B) {
+			b.SetBytes(int64(corpus.Bytes()))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := bench.NewTokenizer(model, corpus, vocabSize); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestCorpora(t *testing.T) {
+	for _, corpus := range bench.Corpora() {
+		if corpus.Bytes() == 0 {
+			t.Errorf("%v: empty corpus", corpus.Name)
+		}
+	}
+	// Generated corpora are deterministic.
+	a, b := bench.Code(), bench.Code()
+	for i := range a.Sequences {
+		if a.Sequences[i] != b.Sequences[i] {
+			t.Fatalf("sequence %d differs between runs", i)
+		}
+	}
+}
+
+func TestNewTokenizer(t *testing.T) {
+	corpus := bench.Multilingual()
+	corpus.Sequences = corpus.Sequences[:200]
+	for _, model := range bench.Models {
+		tk, err := bench.NewTokenizer(model, corpus, 500)
+		if err != nil {
+			t.Fatalf("%v: %v", model, err)
+		}
+		if n := tk.GetVocabSize(true); n == 0 || n > 500 {
+			t.Errorf("%v: want vocab size in (0, 500], got %v", model, n)
+		}
+	}
+
+	if _, err := bench.NewTokenizer("unknown", corpus, 500); err == nil {
+		t.Errorf("want error on unknown model")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := bench.Baseline{"a": 100, "b": 100, "c": 100}
+	results := []bench.Result{
+		{Name: "c", BytesPerSec: 50},
+		{Name: "b", BytesPerSec: 85},
+		{Name: "a", BytesPerSec: 200},
+		{Name: "new", BytesPerSec: 1},
+	}
+
+	got := bench.Compare(results, baseline, 0.1)
+	want := []bench.Regression{
+		{Name: "b", Expected: 100, Got: 85},
+		{Name: "c", Expected: 100, Got: 50},
+	}
+	if fmt.Sprint(want) != fmt.Sprint(got) {
+		t.Errorf("want %v\ngot  %v", want, got)
+	}
+	if got[1].Ratio() != 0.5 {
+		t.Errorf("want ratio 0.5, got %v", got[1].Ratio())
+	}
+
+	file := t.TempDir() + "/baseline.json"
+	if err := bench.NewBaseline(results).Save(file); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := bench.LoadBaseline(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bench.Compare(results, loaded, 0)) != 0 {
+		t.Errorf("want no regression against own baseline, got %v", loaded)
+	}
+}
+
+// TestBaseline compares throughputs to the -baseline file, or records it with
+// -update. It is skipped without -baseline.
+func TestBaseline(t *testing.T) {
+	if *baselineFile == "" {
+		t.Skip("no -baseline file")
+	}
+
+	results, err := bench.Run(vocabSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *updateBaseline {
+		if err := bench.NewBaseline(results).Save(*baselineFile); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	baseline, err := bench.LoadBaseline(*baselineFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range bench.Compare(results, baseline, *tolerance) {
+		t.Errorf("regression %v", r)
+	}
+}
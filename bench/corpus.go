@@ -0,0 +1,138 @@
+// Package bench provides corpora, tokenizers and a throughput comparison
+// harness to benchmark the tokenizer and catch performance regressions.
+//
+// Corpora are generated deterministically so that results are comparable
+// between runs and machines, without downloading any data. Run the
+// benchmarks with:
+//
+//	go test ./bench -run XXX -bench .
+//
+// and compare throughputs against a baseline, or record it, with:
+//
+//	go test ./bench -run TestBaseline -baseline baseline.json [-update]
+package bench
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// Corpus is a named set of sequences.
+type Corpus struct {
+	Name      string
+	Sequences []string
+}
+
+// Bytes returns the total size in bytes of the corpus sequences.
+func (c Corpus) Bytes() int {
+	n := 0
+	for _, s := range c.Sequences {
+		n += len(s)
+	}
+	return n
+}
+
+// Iterator returns an iterator over the corpus sequences, as used by
+// `Tokenizer.TrainFromIterator`.
+func (c Corpus) Iterator() func() (string, bool) {
+	i := 0
+	return func() (string, bool) {
+		if i >= len(c.Sequences) {
+			return "", false
+		}
+		i++
+		return c.Sequences[i-1], true
+	}
+}
+
+// seed makes the generated corpora identical between runs.
+const seed = 42
+
+var languages = [][]string{
+	// English
+	strings.Fields("the quick brown fox jumps over lazy dog while tokenizers split text into words and subwords for language models"),
+	// French
+	strings.Fields("le renard brun rapide saute par-dessus le chien paresseux pendant que l'été arrive déjà à Paris"),
+	// German
+	strings.Fields("der schnelle braune Fuchs springt über den faulen Hund während Straßenbahnen Größe und Übermut zeigen"),
+	// Russian
+	strings.Fields("быстрая коричневая лиса прыгает через ленивую собаку пока токенизатор разбивает текст на слова"),
+	// Chinese
+	strings.Fields("敏捷的 棕色 狐狸 跳过了 懒惰的 狗 分词器 将 文本 切分 为 子词"),
+	// Japanese
+	strings.Fields("素早い 茶色の 狐が 怠惰な 犬を 飛び越える トークナイザーは テキストを 分割する"),
+	// Arabic
+	strings.Fields("الثعلب البني السريع يقفز فوق الكلب الكسول بينما يقسم المحلل النص إلى كلمات"),
+	// Hindi
+	strings.Fields("तेज़ भूरी लोमड़ी आलसी कुत्ते के ऊपर कूदती है और टोकनाइज़र पाठ को शब्दों में बाँटता है"),
+	// Emoji and symbols
+	strings.Fields("🙂 👍🏽 🚀 ✨ ❤️ 100% €42 #tokenizer @user https://example.com/path?q=1"),
+}
+
+// Multilingual returns sentences of several languages and scripts.
+func Multilingual() Corpus {
+	r := rand.New(rand.NewSource(seed))
+	sequences := make([]string, 500)
+	for i := range sequences {
+		words := languages[r.Intn(len(languages))]
+		sequences[i] = sentence(r, words, 8+r.Intn(24))
+	}
+
+	return Corpus{Name: "multilingual", Sequences: sequences}
+}
+
+var snippets = []string{
+	"func (t *Tokenizer) Encode(input EncodeInput, addSpecialTokens bool) (*Encoding, error) {\n\tif t.trunc != nil {\n\t\treturn nil, fmt.Errorf(\"invalid input: %v\", input)\n\t}\n}",
+	"def encode(self, text: str, add_special_tokens: bool = True) -> List[int]:\n    ids = [self.vocab.get(tok, self.unk_id) for tok in text.split()]\n    return ids",
+	"{\"model\": {\"type\": \"BPE\", \"vocab\": {\"a\": 0, \"b\": 1}, \"merges\": [\"a b\"]}, \"added_tokens\": []}",
+	"for (int i = 0; i < n; ++i) { sum += values[i] * weights[i]; } // accumulate",
+	"SELECT id, name, created_at FROM users WHERE email LIKE '%@example.com' ORDER BY id DESC LIMIT 10;",
+	"const fetchData = async (url) => { const res = await fetch(url); return res.json(); };",
+}
+
+// Code returns source code snippets of several languages, with their
+// indentation and punctuation.
+func Code() Corpus {
+	r := rand.New(rand.NewSource(seed))
+	sequences := make([]string, 200)
+	for i := range sequences {
+		var b strings.Builder
+		for j := 0; j < 1+r.Intn(4); j++ {
+			b.WriteString(snippets[r.Intn(len(snippets))])
+			b.WriteString("\n\n")
+		}
+		sequences[i] = b.String()
+	}
+
+	return Corpus{Name: "code", Sequences: sequences}
+}
+
+// LongDocuments returns a few documents of about 16KB each.
+func LongDocuments() Corpus {
+	r := rand.New(rand.NewSource(seed))
+	sequences := make([]string, 4)
+	for i := range sequences {
+		var b strings.Builder
+		for b.Len() < 16*1024 {
+			b.WriteString(sentence(r, languages[0], 10+r.Intn(30)))
+			b.WriteString(".\n")
+		}
+		sequences[i] = b.String()
+	}
+
+	return Corpus{Name: "long", Sequences: sequences}
+}
+
+// Corpora returns all the corpora of the package.
+func Corpora() []Corpus {
+	return []Corpus{Multilingual(), Code(), LongDocuments()}
+}
+
+// sentence joins n random words.
+func sentence(r *rand.Rand, words []string, n int) string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = words[r.Intn(len(words))]
+	}
+	return strings.Join(out, " ")
+}
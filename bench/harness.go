@@ -0,0 +1,160 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+)
+
+// Result is the measured throughput of a benchmark.
+type Result struct {
+	Name        string
+	BytesPerSec float64
+}
+
+// Measure runs fn as a benchmark processing n bytes per call and returns its
+// throughput.
+func Measure(name string, n int, fn func() error) (Result, error) {
+	var err error
+	res := testing.Benchmark(func(b *testing.B) {
+		b.SetBytes(int64(n))
+		for i := 0; i < b.N && err == nil; i++ {
+			err = fn()
+		}
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("Measure %q failed: %w", name, err)
+	}
+
+	bytesPerSec := 0.0
+	if res.T > 0 {
+		bytesPerSec = float64(res.Bytes) * float64(res.N) / res.T.Seconds()
+	}
+
+	return Result{Name: name, BytesPerSec: bytesPerSec}, nil
+}
+
+// Baseline holds the expected throughput (bytes/s) of each benchmark.
+type Baseline map[string]float64
+
+// LoadBaseline reads a baseline from a JSON file.
+func LoadBaseline(file string) (Baseline, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("LoadBaseline failed: %w", err)
+	}
+
+	return b, nil
+}
+
+// Save writes the baseline to a JSON file.
+func (b Baseline) Save(file string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(file, append(data, '\n'), 0644)
+}
+
+// NewBaseline returns the baseline of the given results.
+func NewBaseline(results []Result) Baseline {
+	b := make(Baseline, len(results))
+	for _, r := range results {
+		b[r.Name] = r.BytesPerSec
+	}
+	return b
+}
+
+// Regression is a benchmark slower than its baseline.
+type Regression struct {
+	Name     string
+	Expected float64 // bytes/s
+	Got      float64 // bytes/s
+}
+
+// Ratio returns the measured throughput relative to the expected one.
+func (r Regression) Ratio() float64 {
+	return r.Got / r.Expected
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%v: %.2f MB/s, expected %.2f MB/s (%.0f%%)", r.Name, r.Got/1e6, r.Expected/1e6, r.Ratio()*100)
+}
+
+// Compare returns the results slower than their baseline by more than
+// tolerance (ie. 0.1 for 10%), sorted by name. Results missing from the
+// baseline are ignored.
+func Compare(results []Result, baseline Baseline, tolerance float64) []Regression {
+	var regressions []Regression
+	for _, r := range results {
+		expected, ok := baseline[r.Name]
+		if !ok || expected <= 0 {
+			continue
+		}
+		if r.BytesPerSec < expected*(1-tolerance) {
+			regressions = append(regressions, Regression{Name: r.Name, Expected: expected, Got: r.BytesPerSec})
+		}
+	}
+	sort.Slice(regressions, func(i, j int) bool {
+		return regressions[i].Name < regressions[j].Name
+	})
+
+	return regressions
+}
+
+// Run trains a tokenizer of every model on every corpus, then measures its
+// encode and decode throughputs. Results are named `encode/<model>/<corpus>`
+// and `decode/<model>/<corpus>`.
+func Run(vocabSize int) ([]Result, error) {
+	var results []Result
+	for _, corpus := range Corpora() {
+		inputs := make([]tokenizer.EncodeInput, len(corpus.Sequences))
+		for i, s := range corpus.Sequences {
+			inputs[i] = tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence(s))
+		}
+
+		for _, model := range Models {
+			tk, err := NewTokenizer(model, corpus, vocabSize)
+			if err != nil {
+				return nil, err
+			}
+
+			var encodings []tokenizer.Encoding
+			encode := func() (err error) {
+				encodings, err = tk.EncodeBatch(inputs, false)
+				return err
+			}
+			res, err := Measure(fmt.Sprintf("encode/%v/%v", model, corpus.Name), corpus.Bytes(), encode)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, res)
+
+			ids := make([][]int, len(encodings))
+			for i, en := range encodings {
+				ids[i] = en.Ids
+			}
+			decode := func() error {
+				tk.DecodeBatch(ids, false)
+				return nil
+			}
+			res, err = Measure(fmt.Sprintf("decode/%v/%v", model, corpus.Name), corpus.Bytes(), decode)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, res)
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,117 @@
+package bench
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/decoder"
+	"github.com/sugarme/tokenizer/model/bpe"
+	"github.com/sugarme/tokenizer/model/unigram"
+	"github.com/sugarme/tokenizer/model/wordlevel"
+	"github.com/sugarme/tokenizer/model/wordpiece"
+	"github.com/sugarme/tokenizer/normalizer"
+	"github.com/sugarme/tokenizer/pretokenizer"
+)
+
+// Models lists the models supported by `NewTokenizer`.
+var Models = []string{"bpe", "wordpiece", "wordlevel", "unigram"}
+
+// NewTokenizer returns a tokenizer with a typical pipeline for the given
+// model, trained on corpus:
+//   - bpe: byte-level BPE (GPT-2 like)
+//   - wordpiece: BERT normalizer and pre-tokenizer
+//   - wordlevel: whitespace split, most frequent words
+//   - unigram: NFKC normalizer and Metaspace (SentencePiece like)
+func NewTokenizer(model string, corpus Corpus, vocabSize int) (*tokenizer.Tokenizer, error) {
+	tk := tokenizer.NewTokenizer(wordlevel.NewWordLevel())
+
+	var trainer tokenizer.Trainer
+	switch model {
+	case "bpe":
+		byteLevel := pretokenizer.NewByteLevel()
+		tk.WithPreTokenizer(byteLevel)
+		tk.WithDecoder(byteLevel)
+
+		builder := bpe.NewBPETrainerBuilder()
+		builder.VocabSize(vocabSize)
+		builder.ShowProgress(false)
+		builder.InitialAlphabet(byteLevel.Alphabet())
+		trainer = builder.Build()
+
+	case "wordpiece":
+		tk.WithNormalizer(normalizer.NewBertNormalizer(true, true, true, true))
+		tk.WithPreTokenizer(pretokenizer.NewBertPreTokenizer())
+		tk.WithDecoder(decoder.DefaultWordpieceDecoder())
+
+		trainer = wordpiece.NewWordPieceTrainerBuilder().
+			VocabSize(vocabSize).
+			ShowProgress(false).
+			SpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[UNK]", true)}).
+			Build()
+
+	case "wordlevel":
+		tk.WithPreTokenizer(pretokenizer.NewWhitespaceSplit())
+		m, err := wordLevel(corpus, vocabSize)
+		if err != nil {
+			return nil, err
+		}
+		tk.WithModel(m)
+		return tk, nil
+
+	case "unigram":
+		metaspace := pretokenizer.DefaultMetaspace()
+		tk.WithNormalizer(normalizer.NewNFKC())
+		tk.WithPreTokenizer(metaspace)
+		tk.WithDecoder(metaspace)
+
+		trainer = unigram.NewUnigramTrainerBuilder().
+			VocabSize(vocabSize).
+			ShowProgress(false).
+			UnkToken("<unk>").
+			Build()
+
+	default:
+		return nil, fmt.Errorf("NewTokenizer failed: unknown model %q.", model)
+	}
+
+	err := tk.TrainFromIterator(corpus.Iterator(), trainer, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return tk, nil
+}
+
+// wordLevel builds a WordLevel model of the vocabSize most frequent words of
+// corpus, `[UNK]` included.
+func wordLevel(corpus Corpus, vocabSize int) (*wordlevel.WordLevel, error) {
+	counts := make(map[string]int)
+	for _, s := range corpus.Sequences {
+		for _, w := range strings.Fields(s) {
+			counts[w]++
+		}
+	}
+
+	words := make([]string, 0, len(counts))
+	for w := range counts {
+		words = append(words, w)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if counts[words[i]] != counts[words[j]] {
+			return counts[words[i]] > counts[words[j]]
+		}
+		return words[i] < words[j]
+	})
+
+	vocab := map[string]int{"[UNK]": 0}
+	for _, w := range words {
+		if len(vocab) >= vocabSize {
+			break
+		}
+		vocab[w] = len(vocab)
+	}
+
+	return wordlevel.New(vocab, "[UNK]")
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/model"
 	"github.com/sugarme/tokenizer/model/bpe"
 
 	// "github.com/sugarme/tokenizer/normalizer"
@@ -269,3 +270,41 @@ func TestByteLevelPreTokenizedSequenceWithTrimming(t *testing.T) {
 		t.Errorf("Got: %v\n", got3)
 	}
 }
+
+// TestByteLevelAlphabet runs the full byte-level pipeline offline with a
+// vocab made of the 256 byte-level characters only.
+func TestByteLevelAlphabet(t *testing.T) {
+	vocab := make(model.Vocab)
+	for b := 0; b < 256; b++ {
+		vocab[pretokenizer.GenerateBytesChar()[uint8(b)]] = b
+	}
+	tk := tokenizer.NewTokenizer(bpe.NewBPE(vocab, bpe.Merges{}))
+
+	bl := pretokenizer.NewByteLevel()
+	tk.WithPreTokenizer(bl)
+	tk.WithPostProcessor(bl)
+	tk.WithDecoder(bl)
+
+	input := "Hi 世"
+	output, err := tk.EncodeSingle(input, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantTokens := []string{"Ġ", "H", "i", "Ġ", "ä", "¸", "ĸ"}
+	if !reflect.DeepEqual(wantTokens, output.GetTokens()) {
+		t.Errorf("want %#v\ngot %#v\n", wantTokens, output.GetTokens())
+	}
+
+	// Spaces are trimmed and every byte of a multi-byte char maps back to the whole char.
+	wantOffsets := [][]int{{0, 0}, {0, 1}, {1, 2}, {3, 3}, {3, 6}, {3, 6}, {3, 6}}
+	if !reflect.DeepEqual(wantOffsets, output.GetOffsets()) {
+		t.Errorf("want %#v\ngot %#v\n", wantOffsets, output.GetOffsets())
+	}
+
+	want := " Hi 世"
+	got := tk.Decode(output.GetIds(), false)
+	if want != got {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+}
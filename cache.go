@@ -0,0 +1,244 @@
+package tokenizer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// This file provides the cache of downloaded files, shared by `HubFile`,
+// `CachedPath` and so `pretrained.FromPretrained`.
+//
+// Files are stored in `<CachedDir>/<repoId>/<revision>/<fileName>`, along
+// with metadata in `.meta/<fileName>.json` next to them: the ETag of the
+// file, to revalidate it against the Hub, and its sha256, to check it has
+// not been truncated or altered.
+
+// ErrCorruptedCache is returned, wrapped, when a cached file does not match
+// its recorded sha256 in offline mode, or a downloaded file its ETag.
+var ErrCorruptedCache = errors.New("corrupted cached file")
+
+// cacheMeta is the metadata of a cached file.
+type cacheMeta struct {
+	RepoId   string    `json:"repo_id"`
+	Revision string    `json:"revision"`
+	FileName string    `json:"file_name"`
+	URL      string    `json:"url"`
+	ETag     string    `json:"etag"`
+	Sha256   string    `json:"sha256"`
+	Size     int64     `json:"size"`
+	Time     time.Time `json:"time"`
+}
+
+func metaPath(file string) string {
+	return filepath.Join(filepath.Dir(file), ".meta", filepath.Base(file)+".json")
+}
+
+func readMeta(file string) (*cacheMeta, error) {
+	data, err := os.ReadFile(metaPath(file))
+	if err != nil {
+		return nil, err
+	}
+	var meta cacheMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+
+	return &meta, nil
+}
+
+func writeMeta(file string, meta *cacheMeta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(metaPath(file)), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(metaPath(file), data, 0644)
+}
+
+// removeCached removes a cached file and its metadata.
+func removeCached(file string) {
+	os.Remove(file)
+	os.Remove(metaPath(file))
+}
+
+// fileSha256 returns the hex sha256 of a file.
+func fileSha256(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyCached checks a cached file against its metadata, if any (files
+// copied to the cache by hand have none).
+func verifyCached(file string, meta *cacheMeta) error {
+	if meta == nil || meta.Sha256 == "" {
+		return nil
+	}
+	sum, err := fileSha256(file)
+	if err != nil {
+		return err
+	}
+	if sum != meta.Sha256 {
+		return fmt.Errorf("%w: %q: sha256 %s, want %s", ErrCorruptedCache, file, sum, meta.Sha256)
+	}
+
+	return nil
+}
+
+// isSha256 returns whether s is a hex sha256, e.g. the ETag of a LFS file.
+func isSha256(s string) bool {
+	return len(s) == 64 && isHex(s)
+}
+
+// isCommitHash returns whether revision is a git commit hash, whose files
+// never change.
+func isCommitHash(revision string) bool {
+	return len(revision) == 40 && isHex(revision)
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cacheRelPath returns the cleaned path p, checking it is relative and stays
+// within the directory it is joined to, ie. the cache.
+func cacheRelPath(p string) (string, error) {
+	rel := filepath.Clean(p)
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return "", fmt.Errorf("path %q out of the cache directory", p)
+	}
+
+	return rel, nil
+}
+
+// CacheEntry is a file of the cache.
+type CacheEntry struct {
+	Path     string
+	RepoId   string // empty if unknown
+	Revision string // empty if unknown
+	FileName string
+	ETag     string
+	Sha256   string
+	Size     int64
+	// Time of the download, or last modification if unknown
+	Time time.Time
+	// Whether the file does not match its recorded sha256
+	Corrupted bool
+}
+
+// CacheInfo is the content of the cache.
+type CacheInfo struct {
+	Dir     string
+	Size    int64 // total size of the files, in bytes
+	Entries []CacheEntry
+}
+
+// ScanCache lists the files of the cache, checking their integrity. Files
+// are stored in `<dir>/<repoId>/<revision>/<fileName>`.
+//
+// Params:
+// - dirOpt: optional cache directory (default = `CachedDir`)
+func ScanCache(dirOpt ...string) (*CacheInfo, error) {
+	dir := CachedDir
+	if len(dirOpt) > 0 {
+		dir = dirOpt[0]
+	}
+
+	info := &CacheInfo{Dir: dir}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".meta" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entry := CacheEntry{Path: path, Size: fi.Size(), Time: fi.ModTime()}
+		if meta, err := readMeta(path); err == nil {
+			entry.RepoId, entry.Revision, entry.FileName = meta.RepoId, meta.Revision, meta.FileName
+			entry.ETag, entry.Sha256, entry.Time = meta.ETag, meta.Sha256, meta.Time
+			entry.Corrupted = verifyCached(path, meta) != nil
+		} else {
+			entry.FileName, _ = filepath.Rel(dir, path)
+		}
+		info.Entries = append(info.Entries, entry)
+		info.Size += entry.Size
+
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		err = fmt.Errorf("ScanCache() failed: %w", err)
+		return nil, err
+	}
+
+	sort.Slice(info.Entries, func(i, j int) bool {
+		return info.Entries[i].Path < info.Entries[j].Path
+	})
+
+	return info, nil
+}
+
+// CleanCache removes files cached in the cache directory `CachedDir`: all
+// of them, or those of the given repos only.
+//
+// NOTE. custom `CachedDir` can be changed by setting environment `GO_TOKENIZER`
+func CleanCache(repoIdsOpt ...string) error {
+	dirs := []string{CachedDir}
+	if len(repoIdsOpt) > 0 {
+		dirs = dirs[:0]
+		for _, repoId := range repoIdsOpt {
+			rel, err := cacheRelPath(repoId)
+			if err != nil {
+				return fmt.Errorf("CleanCache() failed: invalid repo id: %w", err)
+			}
+			dirs = append(dirs, filepath.Join(CachedDir, rel))
+		}
+	}
+
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			err = fmt.Errorf("CleanCache() failed: %w", err)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package tokenizer_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+)
+
+func TestHubFile_Cache(t *testing.T) {
+	data := `{"version": "1.0"}`
+	sum := sha256.Sum256([]byte(data))
+	etag := hex.EncodeToString(sum[:])
+
+	var downloads, revalidations int
+	badEtag := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"`+etag+`"` {
+			revalidations++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		if badEtag {
+			w.Header().Set("X-Linked-Etag", `"`+etag[1:]+`0"`)
+		} else {
+			w.Header().Set("X-Linked-Etag", `"`+etag+`"`)
+		}
+		w.Write([]byte(data))
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	opts := []tokenizer.HubOpt{tokenizer.WithEndpoint(server.URL), tokenizer.WithCacheDir(cacheDir)}
+
+	file, err := tokenizer.HubFile("org/model", "tokenizer.json", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Revalidated with the ETag
+	if _, err := tokenizer.HubFile("org/model", "tokenizer.json", opts...); err != nil {
+		t.Fatal(err)
+	}
+	if downloads != 1 || revalidations != 1 {
+		t.Errorf("want 1 download and 1 revalidation, got %v and %v", downloads, revalidations)
+	}
+
+	// Not revalidated for a commit hash
+	commit := tokenizer.WithRevision("0123456789abcdef0123456789abcdef01234567")
+	for i := 0; i < 2; i++ {
+		if _, err := tokenizer.HubFile("org/model", "tokenizer.json", append(opts, commit)...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if downloads != 2 || revalidations != 1 {
+		t.Errorf("want 2 downloads and 1 revalidation, got %v and %v", downloads, revalidations)
+	}
+
+	info, err := tokenizer.ScanCache(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Entries) != 2 || info.Size != int64(2*len(data)) {
+		t.Fatalf("want 2 entries of %v bytes, got %+v", 2*len(data), info)
+	}
+	entry := info.Entries[1]
+	if entry.RepoId != "org/model" || entry.Revision != "main" || entry.FileName != "tokenizer.json" ||
+		entry.Sha256 != etag || entry.ETag != etag || entry.Corrupted {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+
+	// Corrupted files are detected, and downloaded again when online.
+	if err := os.WriteFile(file, []byte(`{"version": "2.0"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err = tokenizer.ScanCache(cacheDir)
+	if err != nil || !info.Entries[1].Corrupted {
+		t.Errorf("want corrupted entry, got %+v, %v", info, err)
+	}
+	_, err = tokenizer.HubFile("org/model", "tokenizer.json", append(opts, tokenizer.WithOffline(true))...)
+	if !errors.Is(err, tokenizer.ErrCorruptedCache) {
+		t.Errorf("want ErrCorruptedCache, got %v", err)
+	}
+	if _, err := tokenizer.HubFile("org/model", "tokenizer.json", opts...); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(file); string(got) != data || downloads != 3 {
+		t.Errorf("want file downloaded again, got %q", got)
+	}
+
+	// Downloads not matching their ETag are rejected.
+	badEtag = true
+	_, err = tokenizer.HubFile("org/model", "vocab.json", opts...)
+	if !errors.Is(err, tokenizer.ErrCorruptedCache) {
+		t.Errorf("want ErrCorruptedCache, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "org/model/main/vocab.json")); !os.IsNotExist(err) {
+		t.Errorf("want no cached file, got %v", err)
+	}
+}
+
+func TestCleanCache(t *testing.T) {
+	cachedDir := tokenizer.CachedDir
+	defer func() { tokenizer.CachedDir = cachedDir }()
+	tokenizer.CachedDir = t.TempDir()
+
+	for _, file := range []string{"org/a/main/tokenizer.json", "org/b/main/tokenizer.json"} {
+		path := filepath.Join(tokenizer.CachedDir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tokenizer.CleanCache("../outside"); err == nil {
+		t.Errorf("want error on repo id outside of the cache")
+	}
+	if err := tokenizer.CleanCache("org/a"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := tokenizer.ScanCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Entries) != 1 || info.Entries[0].FileName != "org/b/main/tokenizer.json" {
+		t.Errorf("want org/b entry only, got %+v", info.Entries)
+	}
+
+	if err := tokenizer.CleanCache(); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := tokenizer.ScanCache(); err != nil || len(info.Entries) != 0 {
+		t.Errorf("want empty cache, got %+v, %v", info, err)
+	}
+}
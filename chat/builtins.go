@@ -0,0 +1,810 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Globals
+// =======
+
+// globals returns the global functions of a template.
+func globals() map[string]interface{} {
+	return map[string]interface{}{
+		"range": function(func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			ints, err := intArgs("range", args, 1, 3)
+			if err != nil {
+				return nil, err
+			}
+			start, stop, step := 0, ints[0], 1
+			if len(ints) > 1 {
+				start, stop = ints[0], ints[1]
+			}
+			if len(ints) > 2 {
+				step = ints[2]
+			}
+			if step == 0 {
+				return nil, errors.New("range() arg 3 must not be zero")
+			}
+			l := []interface{}{}
+			for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+				l = append(l, i)
+			}
+			return l, nil
+		}),
+
+		"namespace": function(func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			ns := make(namespace)
+			for _, a := range args {
+				d, ok := a.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("namespace() takes dicts, not %s", typeName(a))
+				}
+				for k, v := range d {
+					ns[k] = v
+				}
+			}
+			for k, v := range kwargs {
+				ns[k] = v
+			}
+			return ns, nil
+		}),
+
+		"dict": function(func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			d := make(map[string]interface{}, len(kwargs))
+			for k, v := range kwargs {
+				d[k] = v
+			}
+			return d, nil
+		}),
+
+		// raise_exception is how chat templates report invalid messages.
+		"raise_exception": function(func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			msg := "raise_exception() called"
+			if len(args) > 0 {
+				msg = toString(args[0])
+			}
+			return nil, &TemplateError{Message: msg}
+		}),
+
+		"strftime_now": function(func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, errors.New("strftime_now() takes exactly one argument")
+			}
+			return strftime(Now(), toString(args[0])), nil
+		}),
+	}
+}
+
+// Now returns the time used by the `strftime_now` function of templates. It
+// can be replaced, e.g. for reproducible prompts.
+var Now = time.Now
+
+// strftime formats t with the Python format directives.
+func strftime(t time.Time, format string) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		switch format[i] {
+		case 'd':
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case 'e', '-':
+			if format[i] == '-' && i+1 < len(format) && format[i+1] == 'd' {
+				i++
+			}
+			fmt.Fprintf(&b, "%d", t.Day())
+		case 'm':
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+		case 'Y':
+			fmt.Fprintf(&b, "%d", t.Year())
+		case 'y':
+			fmt.Fprintf(&b, "%02d", t.Year()%100)
+		case 'b':
+			b.WriteString(t.Format("Jan"))
+		case 'B':
+			b.WriteString(t.Format("January"))
+		case 'a':
+			b.WriteString(t.Format("Mon"))
+		case 'A':
+			b.WriteString(t.Format("Monday"))
+		case 'H':
+			fmt.Fprintf(&b, "%02d", t.Hour())
+		case 'I':
+			fmt.Fprintf(&b, "%02d", (t.Hour()+11)%12+1)
+		case 'M':
+			fmt.Fprintf(&b, "%02d", t.Minute())
+		case 'S':
+			fmt.Fprintf(&b, "%02d", t.Second())
+		case 'p':
+			b.WriteString(t.Format("PM"))
+		case 'j':
+			fmt.Fprintf(&b, "%03d", t.YearDay())
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(format[i])
+		}
+	}
+
+	return b.String()
+}
+
+// intArgs checks that args holds between minN and maxN ints.
+func intArgs(fn string, args []interface{}, minN, maxN int) ([]int, error) {
+	if len(args) < minN || len(args) > maxN {
+		return nil, fmt.Errorf("%s() takes %v to %v arguments, got %v", fn, minN, maxN, len(args))
+	}
+	ints := make([]int, len(args))
+	for i, a := range args {
+		n, ok := a.(int)
+		if !ok {
+			return nil, fmt.Errorf("%s() takes ints, not %s", fn, typeName(a))
+		}
+		ints[i] = n
+	}
+
+	return ints, nil
+}
+
+// arg returns the i-th positional argument, or the keyword one, or def.
+func arg(args []interface{}, kwargs map[string]interface{}, i int, key string, def interface{}) interface{} {
+	if i < len(args) {
+		return args[i]
+	}
+	if v, ok := kwargs[key]; ok {
+		return v
+	}
+
+	return def
+}
+
+// Methods
+// =======
+
+func stringMethod(s, name string) function {
+	switch name {
+	case "strip", "lstrip", "rstrip":
+		return func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			return strip(name, s, arg(args, kwargs, 0, "chars", nil)), nil
+		}
+	case "upper":
+		return func([]interface{}, map[string]interface{}) (interface{}, error) { return strings.ToUpper(s), nil }
+	case "lower":
+		return func([]interface{}, map[string]interface{}) (interface{}, error) { return strings.ToLower(s), nil }
+	case "title":
+		return func([]interface{}, map[string]interface{}) (interface{}, error) { return title(s), nil }
+	case "capitalize":
+		return func([]interface{}, map[string]interface{}) (interface{}, error) { return capitalize(s), nil }
+	case "startswith", "endswith":
+		return func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			affixes := []interface{}{arg(args, kwargs, 0, "prefix", "")}
+			if l, ok := affixes[0].([]interface{}); ok {
+				affixes = l
+			}
+			for _, a := range affixes {
+				if name == "startswith" && strings.HasPrefix(s, toString(a)) ||
+					name == "endswith" && strings.HasSuffix(s, toString(a)) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+	case "split":
+		return func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			n := -1
+			if m, ok := arg(args, kwargs, 1, "maxsplit", -1).(int); ok && m >= 0 {
+				n = m + 1
+			}
+			var parts []string
+			if sep := arg(args, kwargs, 0, "sep", nil); sep != nil {
+				parts = strings.SplitN(s, toString(sep), n)
+			} else {
+				parts = strings.Fields(s)
+			}
+			l := make([]interface{}, len(parts))
+			for i, p := range parts {
+				l[i] = p
+			}
+			return l, nil
+		}
+	case "replace":
+		return func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			return replace(s, args)
+		}
+	case "find":
+		return func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			i := strings.Index(s, toString(arg(args, kwargs, 0, "sub", "")))
+			if i > 0 {
+				i = len([]rune(s[:i]))
+			}
+			return i, nil
+		}
+	case "join":
+		return func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			items, err := iterate(arg(args, kwargs, 0, "iterable", nil))
+			if err != nil {
+				return nil, err
+			}
+			return join(items, s), nil
+		}
+	}
+
+	return nil
+}
+
+func dictMethod(d map[string]interface{}, name string) function {
+	switch name {
+	case "get":
+		return func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			if v, ok := d[toString(arg(args, kwargs, 0, "key", nil))]; ok {
+				return v, nil
+			}
+			return arg(args, kwargs, 1, "default", nil), nil
+		}
+	case "items":
+		return func([]interface{}, map[string]interface{}) (interface{}, error) { return items(d), nil }
+	case "keys":
+		return func([]interface{}, map[string]interface{}) (interface{}, error) { return iterate(d) }
+	case "values":
+		return func([]interface{}, map[string]interface{}) (interface{}, error) {
+			l := []interface{}{}
+			for _, k := range sortedKeys(d) {
+				l = append(l, d[k])
+			}
+			return l, nil
+		}
+	}
+
+	return nil
+}
+
+func strip(method, s string, chars interface{}) string {
+	cutset := " \t\n\r\v\f"
+	if chars != nil {
+		cutset = toString(chars)
+	}
+	switch method {
+	case "lstrip":
+		return strings.TrimLeft(s, cutset)
+	case "rstrip":
+		return strings.TrimRight(s, cutset)
+	}
+	if chars == nil {
+		return strings.TrimSpace(s)
+	}
+
+	return strings.Trim(s, cutset)
+}
+
+func title(s string) string {
+	var b strings.Builder
+	prev := ' '
+	for _, r := range s {
+		if unicode.IsLetter(prev) {
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(unicode.ToUpper(r))
+		}
+		prev = r
+	}
+
+	return b.String()
+}
+
+func capitalize(s string) string {
+	for i, r := range s {
+		return string(unicode.ToUpper(r)) + strings.ToLower(s[i+len(string(r)):])
+	}
+
+	return s
+}
+
+func replace(s string, args []interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, errors.New("replace() takes at least 2 arguments")
+	}
+	n := -1
+	if len(args) > 2 {
+		if c, ok := args[2].(int); ok {
+			n = c
+		}
+	}
+
+	return strings.Replace(s, toString(args[0]), toString(args[1]), n), nil
+}
+
+func join(items []interface{}, sep string) string {
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = toString(item)
+	}
+
+	return strings.Join(parts, sep)
+}
+
+// items returns the (key, value) pairs of d, sorted by key.
+func items(d map[string]interface{}) []interface{} {
+	l := make([]interface{}, 0, len(d))
+	for _, k := range sortedKeys(d) {
+		l = append(l, []interface{}{k, d[k]})
+	}
+
+	return l
+}
+
+// Filters
+// =======
+
+type filter func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error)
+
+var filters map[string]filter
+
+func init() {
+	filters = map[string]filter{
+		"trim": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			return strip("strip", toString(v), arg(args, kwargs, 0, "chars", nil)), nil
+		},
+		"length": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			return length(v)
+		},
+		"upper": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			return strings.ToUpper(toString(v)), nil
+		},
+		"lower": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			return strings.ToLower(toString(v)), nil
+		},
+		"title": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			return title(toString(v)), nil
+		},
+		"capitalize": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			return capitalize(toString(v)), nil
+		},
+		"string": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			return toString(v), nil
+		},
+		"safe": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			return v, nil
+		},
+		"int": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			switch v := v.(type) {
+			case int:
+				return v, nil
+			case float64:
+				return int(v), nil
+			case bool:
+				if v {
+					return 1, nil
+				}
+				return 0, nil
+			case string:
+				if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+					return n, nil
+				}
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					return int(f), nil
+				}
+			}
+			return arg(args, kwargs, 0, "default", 0), nil
+		},
+		"float": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			if f, ok := toNumber(v); ok {
+				return f, nil
+			}
+			if f, err := strconv.ParseFloat(strings.TrimSpace(toString(v)), 64); err == nil {
+				return f, nil
+			}
+			return arg(args, kwargs, 0, "default", 0.0), nil
+		},
+		"abs": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			switch n := v.(type) {
+			case int:
+				if n < 0 {
+					return -n, nil
+				}
+				return n, nil
+			case float64:
+				if n < 0 {
+					return -n, nil
+				}
+				return n, nil
+			}
+			return nil, fmt.Errorf("bad operand type for abs(): %s", typeName(v))
+		},
+		"first": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			l, err := iterate(v)
+			if err != nil || len(l) == 0 {
+				return undefined{}, err
+			}
+			return l[0], nil
+		},
+		"last": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			l, err := iterate(v)
+			if err != nil || len(l) == 0 {
+				return undefined{}, err
+			}
+			return l[len(l)-1], nil
+		},
+		"list": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			l, err := iterate(v)
+			return append([]interface{}{}, l...), err
+		},
+		"reverse": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			l, err := iterate(v)
+			if err != nil {
+				return nil, err
+			}
+			out := make([]interface{}, len(l))
+			for i, item := range l {
+				out[len(l)-1-i] = item
+			}
+			if _, ok := v.(string); ok {
+				return join(out, ""), nil
+			}
+			return out, nil
+		},
+		"join": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			l, err := iterate(v)
+			if err != nil {
+				return nil, err
+			}
+			if attr, ok := kwargs["attribute"]; ok {
+				for i, item := range l {
+					l[i] = getItem(item, attr)
+				}
+			}
+			return join(l, toString(arg(args, kwargs, 0, "d", ""))), nil
+		},
+		"default": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			_, isUndefined := v.(undefined)
+			if isUndefined || truthy(arg(args, kwargs, 1, "boolean", false)) && !truthy(v) {
+				return arg(args, kwargs, 0, "default_value", ""), nil
+			}
+			return v, nil
+		},
+		"items": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			switch d := v.(type) {
+			case map[string]interface{}:
+				return items(d), nil
+			case undefined:
+				return []interface{}{}, nil
+			}
+			return nil, fmt.Errorf("cannot get items of %s", typeName(v))
+		},
+		"replace": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			return replace(toString(v), args)
+		},
+		"indent": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			width := arg(args, kwargs, 0, "width", 4)
+			prefix, ok := width.(string)
+			if !ok {
+				n, _ := width.(int)
+				prefix = strings.Repeat(" ", n)
+			}
+			lines := strings.Split(toString(v), "\n")
+			for i := range lines {
+				if i == 0 && !truthy(arg(args, kwargs, 1, "first", false)) ||
+					lines[i] == "" && !truthy(arg(args, kwargs, 2, "blank", false)) {
+					continue
+				}
+				lines[i] = prefix + lines[i]
+			}
+			return strings.Join(lines, "\n"), nil
+		},
+		"tojson": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			indent, _ := arg(args, kwargs, 0, "indent", nil).(int)
+			var b strings.Builder
+			if err := writeJSON(&b, v, indent, 0); err != nil {
+				return nil, err
+			}
+			return b.String(), nil
+		},
+		"unique": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			l, err := iterate(v)
+			if err != nil {
+				return nil, err
+			}
+			out := []interface{}{}
+			for _, item := range l {
+				if ok, _ := contains(out, item); !ok {
+					out = append(out, item)
+				}
+			}
+			return out, nil
+		},
+		"sort": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			l, err := iterate(v)
+			if err != nil {
+				return nil, err
+			}
+			out := append([]interface{}{}, l...)
+			key := func(item interface{}) interface{} {
+				if attr, ok := kwargs["attribute"]; ok {
+					return getItem(item, attr)
+				}
+				return item
+			}
+			reverse := truthy(arg(args, kwargs, 0, "reverse", false))
+			sort.SliceStable(out, func(i, j int) bool {
+				a, b := key(out[i]), key(out[j])
+				if reverse {
+					a, b = b, a
+				}
+				less, _ := compare("<", a, b)
+				return less
+			})
+			return out, nil
+		},
+		"map": func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			l, err := iterate(v)
+			if err != nil {
+				return nil, err
+			}
+			out := make([]interface{}, len(l))
+			for i, item := range l {
+				if attr, ok := kwargs["attribute"]; ok {
+					out[i] = getItem(item, attr)
+					if _, ok := out[i].(undefined); ok {
+						out[i] = arg(nil, kwargs, 0, "default", undefined{})
+					}
+					continue
+				}
+				if len(args) == 0 {
+					return nil, errors.New("map() takes a filter name or an attribute")
+				}
+				f, ok := filters[toString(args[0])]
+				if !ok {
+					return nil, fmt.Errorf("unknown filter %q", toString(args[0]))
+				}
+				out[i], err = f(item, args[1:], nil)
+				if err != nil {
+					return nil, err
+				}
+			}
+			return out, nil
+		},
+		"select":     selectFilter(false, false),
+		"reject":     selectFilter(true, false),
+		"selectattr": selectFilter(false, true),
+		"rejectattr": selectFilter(true, true),
+	}
+	filters["count"] = filters["length"]
+	filters["d"] = filters["default"]
+	filters["e"] = filters["safe"]
+	filters["escape"] = filters["safe"]
+}
+
+// selectFilter returns the `select` filters, keeping the items (or item
+// attributes) which pass a test, or which do not if reject.
+func selectFilter(reject, attr bool) filter {
+	return func(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		l, err := iterate(v)
+		if err != nil {
+			return nil, err
+		}
+		var key interface{}
+		if attr {
+			if len(args) == 0 {
+				return nil, errors.New("selectattr() takes an attribute")
+			}
+			key, args = args[0], args[1:]
+		}
+		test := ""
+		if len(args) > 0 {
+			test, args = toString(args[0]), args[1:]
+		}
+
+		out := []interface{}{}
+		for _, item := range l {
+			value := item
+			if attr {
+				value = getItem(item, key)
+			}
+			var ok bool
+			if test == "" {
+				ok = truthy(value)
+			} else {
+				ok, err = runTest(test, value, args)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if ok != reject {
+				out = append(out, item)
+			}
+		}
+		return out, nil
+	}
+}
+
+// writeJSON writes v as Python `json.dumps` does, with non-ASCII chars
+// unescaped and the keys of dicts sorted.
+func writeJSON(b *strings.Builder, v interface{}, indent, depth int) error {
+	newline := func(depth int) {
+		if indent > 0 {
+			b.WriteString("\n" + strings.Repeat(" ", indent*depth))
+		}
+	}
+	sep := ", "
+	if indent > 0 {
+		sep = ","
+	}
+
+	switch v := v.(type) {
+	case nil, undefined:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case int, float64:
+		b.WriteString(repr(v))
+	case string:
+		var buf strings.Builder
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		b.WriteString(strings.TrimSuffix(buf.String(), "\n"))
+	case []interface{}:
+		if len(v) == 0 {
+			b.WriteString("[]")
+			return nil
+		}
+		b.WriteString("[")
+		for i, item := range v {
+			if i > 0 {
+				b.WriteString(sep)
+			}
+			newline(depth + 1)
+			if err := writeJSON(b, item, indent, depth+1); err != nil {
+				return err
+			}
+		}
+		newline(depth)
+		b.WriteString("]")
+	case map[string]interface{}, namespace:
+		d, ok := v.(map[string]interface{})
+		if !ok {
+			d = map[string]interface{}(v.(namespace))
+		}
+		if len(d) == 0 {
+			b.WriteString("{}")
+			return nil
+		}
+		b.WriteString("{")
+		for i, k := range sortedKeys(d) {
+			if i > 0 {
+				b.WriteString(sep)
+			}
+			newline(depth + 1)
+			if err := writeJSON(b, k, indent, depth+1); err != nil {
+				return err
+			}
+			b.WriteString(": ")
+			if err := writeJSON(b, d[k], indent, depth+1); err != nil {
+				return err
+			}
+		}
+		newline(depth)
+		b.WriteString("}")
+	default:
+		return fmt.Errorf("object of type %s is not JSON serializable", typeName(v))
+	}
+
+	return nil
+}
+
+// Tests
+// =====
+
+func runTest(name string, v interface{}, args []interface{}) (bool, error) {
+	other := func() (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("test %q takes one argument", name)
+		}
+		return args[0], nil
+	}
+
+	switch name {
+	case "defined":
+		_, ok := v.(undefined)
+		return !ok, nil
+	case "undefined":
+		_, ok := v.(undefined)
+		return ok, nil
+	case "none":
+		return v == nil, nil
+	case "boolean":
+		_, ok := v.(bool)
+		return ok, nil
+	case "true":
+		return v == true, nil
+	case "false":
+		return v == false, nil
+	case "integer":
+		_, ok := v.(int)
+		return ok, nil
+	case "float":
+		_, ok := v.(float64)
+		return ok, nil
+	case "number":
+		_, isBool := v.(bool)
+		_, ok := toNumber(v)
+		return ok && !isBool, nil
+	case "string":
+		_, ok := v.(string)
+		return ok, nil
+	case "mapping":
+		switch v.(type) {
+		case map[string]interface{}, namespace:
+			return true, nil
+		}
+		return false, nil
+	case "sequence", "iterable":
+		switch v.(type) {
+		case []interface{}, string, map[string]interface{}:
+			return true, nil
+		}
+		return false, nil
+	case "callable":
+		_, ok := v.(function)
+		return ok, nil
+	case "lower":
+		s, ok := v.(string)
+		return ok && s == strings.ToLower(s), nil
+	case "upper":
+		s, ok := v.(string)
+		return ok && s == strings.ToUpper(s), nil
+	case "even", "odd":
+		n, ok := v.(int)
+		if !ok {
+			return false, fmt.Errorf("test %q takes an int, not %s", name, typeName(v))
+		}
+		return (n%2 == 0) == (name == "even"), nil
+	case "divisibleby":
+		o, err := other()
+		if err != nil {
+			return false, err
+		}
+		n, ok1 := v.(int)
+		d, ok2 := o.(int)
+		if !ok1 || !ok2 || d == 0 {
+			return false, fmt.Errorf("test %q takes non-zero ints", name)
+		}
+		return n%d == 0, nil
+	case "eq", "equalto", "==", "ne", "!=", "sameas":
+		o, err := other()
+		if err != nil {
+			return false, err
+		}
+		return equal(v, o) != (name == "ne" || name == "!="), nil
+	case "lt", "<", "gt", ">", "le", "<=", "ge", ">=":
+		o, err := other()
+		if err != nil {
+			return false, err
+		}
+		op := map[string]string{"lt": "<", "gt": ">", "le": "<=", "ge": ">="}[name]
+		if op == "" {
+			op = name
+		}
+		return compare(op, v, o)
+	case "in":
+		o, err := other()
+		if err != nil {
+			return false, err
+		}
+		return contains(o, v)
+	}
+
+	return false, fmt.Errorf("unknown test %q", name)
+}
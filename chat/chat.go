@@ -0,0 +1,266 @@
+// Package chat formats conversations into model prompts with the chat
+// templates of HuggingFace `tokenizer_config.json` files, as transformers
+// `apply_chat_template` does.
+//
+// Chat templates are written in Jinja. The package implements the subset of
+// Jinja they use: `if`, `for` (with `loop` and `break`/`continue`), `set`
+// (including `namespace()` attributes), `generation` blocks, whitespace
+// control, expressions with Python semantics, and the usual filters, tests
+// and string and dict methods.
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sugarme/tokenizer"
+)
+
+// Message is a message of a conversation.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// TemplateError is the error raised by a template with `raise_exception()`,
+// usually because the messages do not fit it (e.g. roles do not alternate).
+type TemplateError struct {
+	Message string
+}
+
+func (e *TemplateError) Error() string {
+	return e.Message
+}
+
+// Template is a parsed chat template.
+type Template struct {
+	nodes []node
+
+	// Variables are passed to every rendering, e.g. the special tokens
+	// (`bos_token`, `eos_token`...) of the tokenizer config.
+	Variables map[string]interface{}
+}
+
+// NewTemplate parses a chat template.
+func NewTemplate(src string) (*Template, error) {
+	nodes, err := parse(src)
+	if err != nil {
+		err = fmt.Errorf("Parsing chat template failed: %w", err)
+		return nil, err
+	}
+
+	return &Template{nodes: nodes, Variables: make(map[string]interface{})}, nil
+}
+
+// FromFile loads the chat template of a `tokenizer_config.json` file.
+//
+// Params:
+// - file: path to `tokenizer_config.json`
+// - nameOpt: optional name of the template, if the config has several
+// (default = "default")
+func FromFile(file string, nameOpt ...string) (*Template, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return FromReader(f, nameOpt...)
+}
+
+// specialTokens are the tokenizer config fields passed to templates.
+var specialTokens = []string{
+	"bos_token", "eos_token", "unk_token", "pad_token", "sep_token", "cls_token", "mask_token",
+}
+
+// FromReader loads the chat template of `tokenizer_config.json` data. See
+// FromFile.
+func FromReader(r io.Reader, nameOpt ...string) (*Template, error) {
+	templateName := "default"
+	if len(nameOpt) > 0 {
+		templateName = nameOpt[0]
+	}
+
+	var config map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&config); err != nil {
+		return nil, err
+	}
+
+	raw, ok := config["chat_template"]
+	if !ok {
+		return nil, fmt.Errorf("Loading chat template failed: no 'chat_template' in tokenizer config")
+	}
+
+	// Either a template, or a list of named ones.
+	var src string
+	if err := json.Unmarshal(raw, &src); err != nil {
+		var named []struct {
+			Name     string `json:"name"`
+			Template string `json:"template"`
+		}
+		if err := json.Unmarshal(raw, &named); err != nil {
+			return nil, fmt.Errorf("Loading chat template failed: invalid 'chat_template': %w", err)
+		}
+		found := false
+		for _, t := range named {
+			if t.Name == templateName {
+				src, found = t.Template, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Loading chat template failed: no template named %q", templateName)
+		}
+	}
+
+	tmpl, err := NewTemplate(src)
+	if err != nil {
+		return nil, err
+	}
+
+	// Special tokens are either strings or added tokens objects.
+	for _, key := range specialTokens {
+		raw, ok := config[key]
+		if !ok {
+			continue
+		}
+		var content string
+		if err := json.Unmarshal(raw, &content); err != nil {
+			var addedToken struct {
+				Content string `json:"content"`
+			}
+			if err := json.Unmarshal(raw, &addedToken); err != nil {
+				continue
+			}
+			content = addedToken.Content
+		}
+		tmpl.Variables[key] = content
+	}
+
+	return tmpl, nil
+}
+
+// ApplyOpts are options of rendering a template.
+type ApplyOpts struct {
+	// AddGenerationPrompt appends the tokens starting an assistant message.
+	AddGenerationPrompt bool
+	// Variables are extra template variables, e.g. `tools` or `documents`.
+	// They take precedence over the Template ones.
+	Variables map[string]interface{}
+}
+
+type ApplyOpt func(o *ApplyOpts)
+
+// WithGenerationPrompt sets whether to append the generation prompt.
+func WithGenerationPrompt(v bool) ApplyOpt {
+	return func(o *ApplyOpts) {
+		o.AddGenerationPrompt = v
+	}
+}
+
+// WithVariables sets extra template variables. Values must be JSON-like:
+// nil, bool, numbers, strings, slices and maps with string keys, or structs
+// marshalled as such.
+func WithVariables(vars map[string]interface{}) ApplyOpt {
+	return func(o *ApplyOpts) {
+		o.Variables = vars
+	}
+}
+
+// Render formats messages into a prompt.
+func (t *Template) Render(messages []Message, opts ...ApplyOpt) (string, error) {
+	o := &ApplyOpts{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	vars := make(map[string]interface{}, len(t.Variables)+len(o.Variables)+2)
+	for k, v := range t.Variables {
+		vars[k] = v
+	}
+	for k, v := range o.Variables {
+		value, err := toValue(v)
+		if err != nil {
+			err = fmt.Errorf("Rendering chat template failed: invalid variable %q: %w", k, err)
+			return "", err
+		}
+		vars[k] = value
+	}
+	msgs, err := toValue(messages)
+	if err != nil {
+		return "", err
+	}
+	vars["messages"] = msgs
+	vars["add_generation_prompt"] = o.AddGenerationPrompt
+
+	r := newRenderer(vars)
+	if err := r.exec(t.nodes); err != nil {
+		var templateErr *TemplateError
+		if errors.As(err, &templateErr) {
+			return "", templateErr
+		}
+		err = fmt.Errorf("Rendering chat template failed: %w", err)
+		return "", err
+	}
+
+	return r.out.String(), nil
+}
+
+// Apply formats messages into a prompt and encodes it with tk. As the
+// template adds the special tokens, the tokenizer does not.
+func (t *Template) Apply(tk *tokenizer.Tokenizer, messages []Message, opts ...ApplyOpt) (string, *tokenizer.Encoding, error) {
+	prompt, err := t.Render(messages, opts...)
+	if err != nil {
+		return "", nil, err
+	}
+
+	en, err := tk.EncodeSingle(prompt, false)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return prompt, en, nil
+}
+
+// toValue converts v to a template value through JSON, ie. structs to maps,
+// integral numbers to int and other numbers to float64.
+func toValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return fromJSON(value), nil
+}
+
+func fromJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return int(n)
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = fromJSON(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = fromJSON(v[k])
+		}
+	}
+
+	return v
+}
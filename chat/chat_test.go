@@ -0,0 +1,211 @@
+package chat
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/model/wordlevel"
+	"github.com/sugarme/tokenizer/pretokenizer"
+)
+
+var conversation = []Message{
+	{Role: "system", Content: "You are helpful."},
+	{Role: "user", Content: "Hello"},
+	{Role: "assistant", Content: "Hi!"},
+	{Role: "user", Content: "How are you?"},
+}
+
+const chatML = `{% for message in messages %}{{'<|im_start|>' + message['role'] + '\n' + message['content'] + '<|im_end|>' + '\n'}}{% endfor %}{% if add_generation_prompt %}{{ '<|im_start|>assistant\n' }}{% endif %}`
+
+const llama2 = `{% if messages[0]['role'] == 'system' %}{% set loop_messages = messages[1:] %}{% set system_message = messages[0]['content'] %}{% else %}{% set loop_messages = messages %}{% set system_message = false %}{% endif %}{% for message in loop_messages %}{% if (message['role'] == 'user') != (loop.index0 % 2 == 0) %}{{ raise_exception('Conversation roles must alternate user/assistant/user/assistant/...') }}{% endif %}{% if loop.index0 == 0 and system_message != false %}{% set content = '<<SYS>>\n' + system_message + '\n<</SYS>>\n\n' + message['content'] %}{% else %}{% set content = message['content'] %}{% endif %}{% if message['role'] == 'user' %}{{ bos_token + '[INST] ' + content.strip() + ' [/INST]' }}{% elif message['role'] == 'assistant' %}{{ ' '  + content.strip() + ' ' + eos_token }}{% endif %}{% endfor %}`
+
+const zephyr = `{% for message in messages %}
+{% if message['role'] == 'user' %}
+{{ '<|user|>\n' + message['content'] + eos_token }}
+{% elif message['role'] == 'system' %}
+{{ '<|system|>\n' + message['content'] + eos_token }}
+{% elif message['role'] == 'assistant' %}
+{{ '<|assistant|>\n'  + message['content'] + eos_token }}
+{% endif %}
+{% if loop.last and add_generation_prompt %}
+{{ '<|assistant|>' }}
+{% endif %}
+{% endfor %}`
+
+func TestTemplate_Render(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		vars     map[string]interface{}
+		genOpt   bool
+		want     string
+	}{
+		{
+			name:     "ChatML",
+			template: chatML,
+			genOpt:   true,
+			want: "<|im_start|>system\nYou are helpful.<|im_end|>\n" +
+				"<|im_start|>user\nHello<|im_end|>\n" +
+				"<|im_start|>assistant\nHi!<|im_end|>\n" +
+				"<|im_start|>user\nHow are you?<|im_end|>\n" +
+				"<|im_start|>assistant\n",
+		},
+		{
+			name:     "Llama 2",
+			template: llama2,
+			vars:     map[string]interface{}{"bos_token": "<s>", "eos_token": "</s>"},
+			want:     "<s>[INST] <<SYS>>\nYou are helpful.\n<</SYS>>\n\nHello [/INST] Hi! </s><s>[INST] How are you? [/INST]",
+		},
+		{
+			name:     "Zephyr",
+			template: zephyr,
+			vars:     map[string]interface{}{"eos_token": "</s>"},
+			genOpt:   true,
+			want: "<|system|>\nYou are helpful.</s>\n<|user|>\nHello</s>\n" +
+				"<|assistant|>\nHi!</s>\n<|user|>\nHow are you?</s>\n<|assistant|>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tmpl, err := NewTemplate(tt.template)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got, err := tmpl.Render(conversation, WithGenerationPrompt(tt.genOpt), WithVariables(tt.vars))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s:\nwant: %q\ngot:  %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestTemplate_RaiseException(t *testing.T) {
+	tmpl, err := NewTemplate(llama2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl.Variables["bos_token"] = "<s>"
+	_, err = tmpl.Render([]Message{{Role: "user", Content: "a"}, {Role: "user", Content: "b"}})
+	var templateErr *TemplateError
+	if !errors.As(err, &templateErr) {
+		t.Fatalf("want TemplateError, got %v", err)
+	}
+	want := "Conversation roles must alternate user/assistant/user/assistant/..."
+	if templateErr.Message != want {
+		t.Errorf("want %q, got %q", want, templateErr.Message)
+	}
+}
+
+func TestTemplate_Expressions(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"a  {{- ' b ' -}}  c", "a b c"},
+		{"  {% if true %}\nx{% endif %}\n", "x"},
+		{"  {%+ if true %}x{% endif %}", "  x"},
+		{"{# comment #}x", "x"},
+		{"{% set ns = namespace(n=0) %}{% for i in range(3) %}{% set ns.n = ns.n + i %}{% endfor %}{{ ns.n }}", "3"},
+		{"{% for x in [1, 2, 3, 4] if x is even %}{{ x }}{% if not loop.last %},{% endif %}{% endfor %}", "2,4"},
+		{"{% for x in 'abc' %}{% if x == 'b' %}{% continue %}{% endif %}{{ x }}{% endfor %}", "ac"},
+		{"{% for x in [] %}x{% else %}empty{% endfor %}", "empty"},
+		{"{{ 'hello'[1:-1] }} {{ [1, 2, 3][::-1] }} {{ 'abc'[-1] }}", "ell [3, 2, 1] c"},
+		{"{{ 7 // 2 }} {{ 7 / 2 }} {{ -7 % 3 }} {{ 2 ** 3 }} {{ 1 ~ 'a' }}", "3 3.5 2 8 1a"},
+		{"{{ 'a' if false else 'b' }}{{ 'c' if false }}", "b"},
+		{"{{ x is defined }} {{ x | default('d') }} {{ none is none }}", "False d True"},
+		{"{{ ' Hi ' | trim | upper }} {{ ['a', 'b'] | join('-') }} {{ [3, 1, 2] | sort | list }}", "HI a-b [1, 2, 3]"},
+		{"{{ {'b': 1, 'a': 'é'} | tojson }} {{ [1, none, true] | tojson }}", `{"a": "é", "b": 1} [1, null, true]`},
+		{"{{ 'a,b'.split(',') }} {{ 'abc'.startswith('ab') }} {{ 'x y'.title() }}", "['a', 'b'] True X Y"},
+		{"{% for k, v in {'b': 2, 'a': 1}.items() %}{{ k }}={{ v }};{% endfor %}", "a=1;b=2;"},
+		{"{{ [{'r': 'a'}, {'r': 'b'}] | selectattr('r', 'equalto', 'b') | map(attribute='r') | list }}", "['b']"},
+		{"{{ 'a' in 'cat' }} {{ 1 not in [1] }} {{ [1, 2] | length }}", "True False 2"},
+		{"{% generation %}x{% endgeneration %}", "x"},
+	}
+
+	for _, tt := range tests {
+		tmpl, err := NewTemplate(tt.template)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.template, err)
+		}
+		got, err := tmpl.Render(nil)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.template, err)
+		}
+		if got != tt.want {
+			t.Errorf("%q:\nwant: %q\ngot:  %q", tt.template, tt.want, got)
+		}
+	}
+}
+
+func TestFromFile(t *testing.T) {
+	config := `{
+		"bos_token": {"content": "<s>", "lstrip": false},
+		"eos_token": "</s>",
+		"chat_template": [
+			{"name": "default", "template": "{{ bos_token }}{% for m in messages %}{{ m.content }}{{ eos_token }}{% endfor %}"},
+			{"name": "tool_use", "template": "{{ tools | length }}"}
+		]
+	}`
+	file := filepath.Join(t.TempDir(), "tokenizer_config.json")
+	if err := os.WriteFile(file, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := FromFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tmpl.Render([]Message{{Role: "user", Content: "Hi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<s>Hi</s>"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	tmpl, err = FromFile(file, "tool_use")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = tmpl.Render(nil, WithVariables(map[string]interface{}{"tools": []string{"a", "b"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestTemplate_Apply(t *testing.T) {
+	vocab := map[string]int{"[UNK]": 0, "[CLS]": 1, "[SEP]": 2, "hello": 3, "world": 4}
+	model, err := wordlevel.New(vocab, "[UNK]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := tokenizer.NewTokenizer(model)
+	tk.WithPreTokenizer(pretokenizer.NewWhitespaceSplit())
+
+	tmpl, err := NewTemplate("{{ bos_token }}{% for m in messages %} {{ m.content }} [SEP]{% endfor %}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.Variables["bos_token"] = "[CLS]"
+
+	prompt, en, err := tmpl.Apply(tk, []Message{{Role: "user", Content: "hello"}, {Role: "assistant", Content: "world"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[CLS] hello [SEP] world [SEP]"; prompt != want {
+		t.Errorf("want %q, got %q", want, prompt)
+	}
+	if want := []int{1, 3, 2, 4, 2}; !reflect.DeepEqual(en.Ids, want) {
+		t.Errorf("want %v, got %v", want, en.Ids)
+	}
+}
@@ -0,0 +1,943 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Values
+// ======
+//
+// Template values are Go values mirroring the Python ones: nil (None), bool,
+// int, float64, string, []interface{} (list), map[string]interface{} (dict),
+// plus the types below.
+
+// undefined is the value of a missing variable, attribute or item. It renders
+// as an empty string and is false.
+type undefined struct{}
+
+// namespace is the mutable object returned by `namespace()`, the only value
+// a `set` statement can modify from inside a loop.
+type namespace map[string]interface{}
+
+// function is a callable value: a global function, or a bound method.
+type function func(args []interface{}, kwargs map[string]interface{}) (interface{}, error)
+
+var (
+	errBreak    = errors.New("break outside of a loop")
+	errContinue = errors.New("continue outside of a loop")
+)
+
+// renderer renders statements.
+type renderer struct {
+	scopes []map[string]interface{}
+	out    strings.Builder
+}
+
+func newRenderer(vars map[string]interface{}) *renderer {
+	r := &renderer{}
+	r.scopes = []map[string]interface{}{globals(), vars}
+
+	return r
+}
+
+func (r *renderer) lookup(name string) interface{} {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if v, ok := r.scopes[i][name]; ok {
+			return v
+		}
+	}
+
+	return undefined{}
+}
+
+func (r *renderer) exec(nodes []node) error {
+	for _, n := range nodes {
+		if err := r.execNode(n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *renderer) execNode(n node) error {
+	switch n := n.(type) {
+	case *textNode:
+		r.out.WriteString(n.text)
+
+	case *outputNode:
+		v, err := r.eval(n.expr)
+		if err != nil {
+			return err
+		}
+		r.out.WriteString(toString(v))
+
+	case *ifNode:
+		for i, cond := range n.conds {
+			v, err := r.eval(cond)
+			if err != nil {
+				return err
+			}
+			if truthy(v) {
+				return r.exec(n.bodies[i])
+			}
+		}
+		return r.exec(n.orElse)
+
+	case *forNode:
+		return r.execFor(n)
+
+	case *setNode:
+		v, err := r.eval(n.expr)
+		if err != nil {
+			return err
+		}
+		if n.attr == "" {
+			r.scopes[len(r.scopes)-1][n.name] = v
+			return nil
+		}
+		ns, ok := r.lookup(n.name).(namespace)
+		if !ok {
+			return fmt.Errorf("cannot set attribute %q of %q: not a namespace", n.attr, n.name)
+		}
+		ns[n.attr] = v
+
+	case *breakNode:
+		return errBreak
+
+	case *continueNode:
+		return errContinue
+	}
+
+	return nil
+}
+
+func (r *renderer) execFor(n *forNode) error {
+	v, err := r.eval(n.iterable)
+	if err != nil {
+		return err
+	}
+	items, err := iterate(v)
+	if err != nil {
+		return err
+	}
+
+	scope := make(map[string]interface{})
+	r.scopes = append(r.scopes, scope)
+	defer func() { r.scopes = r.scopes[:len(r.scopes)-1] }()
+
+	if n.filter != nil {
+		var kept []interface{}
+		for _, item := range items {
+			if err := unpack(scope, n.vars, item); err != nil {
+				return err
+			}
+			ok, err := r.eval(n.filter)
+			if err != nil {
+				return err
+			}
+			if truthy(ok) {
+				kept = append(kept, item)
+			}
+		}
+		items = kept
+	}
+
+	if len(items) == 0 {
+		return r.exec(n.orElse)
+	}
+
+	for i, item := range items {
+		for k := range scope {
+			delete(scope, k)
+		}
+		if err := unpack(scope, n.vars, item); err != nil {
+			return err
+		}
+		loop := map[string]interface{}{
+			"index":     i + 1,
+			"index0":    i,
+			"revindex":  len(items) - i,
+			"revindex0": len(items) - i - 1,
+			"first":     i == 0,
+			"last":      i == len(items)-1,
+			"length":    len(items),
+		}
+		if i > 0 {
+			loop["previtem"] = items[i-1]
+		}
+		if i < len(items)-1 {
+			loop["nextitem"] = items[i+1]
+		}
+		scope["loop"] = loop
+
+		err := r.exec(n.body)
+		switch {
+		case err == errBreak:
+			return nil
+		case err == errContinue:
+		case err != nil:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unpack sets the loop variables of item in scope.
+func unpack(scope map[string]interface{}, vars []string, item interface{}) error {
+	if len(vars) == 1 {
+		scope[vars[0]] = item
+		return nil
+	}
+
+	values, ok := item.([]interface{})
+	if !ok || len(values) != len(vars) {
+		return fmt.Errorf("cannot unpack %s into %v variables", repr(item), len(vars))
+	}
+	for i, name := range vars {
+		scope[name] = values[i]
+	}
+
+	return nil
+}
+
+// Expressions
+// ===========
+
+func (r *renderer) eval(e expr) (interface{}, error) {
+	switch e := e.(type) {
+	case *literal:
+		return e.value, nil
+
+	case *name:
+		return r.lookup(e.name), nil
+
+	case *listExpr:
+		l := make([]interface{}, len(e.items))
+		for i, item := range e.items {
+			v, err := r.eval(item)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = v
+		}
+		return l, nil
+
+	case *dictExpr:
+		d := make(map[string]interface{}, len(e.keys))
+		for i := range e.keys {
+			k, err := r.eval(e.keys[i])
+			if err != nil {
+				return nil, err
+			}
+			v, err := r.eval(e.values[i])
+			if err != nil {
+				return nil, err
+			}
+			d[toString(k)] = v
+		}
+		return d, nil
+
+	case *unaryExpr:
+		v, err := r.eval(e.operand)
+		if err != nil {
+			return nil, err
+		}
+		switch e.op {
+		case "not":
+			return !truthy(v), nil
+		case "-":
+			return arith("-", 0, v)
+		default:
+			return arith("+", 0, v)
+		}
+
+	case *binaryExpr:
+		return r.evalBinary(e)
+
+	case *condExpr:
+		cond, err := r.eval(e.cond)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(cond) {
+			return r.eval(e.then)
+		}
+		if e.orElse == nil {
+			return undefined{}, nil
+		}
+		return r.eval(e.orElse)
+
+	case *attrExpr:
+		v, err := r.eval(e.value)
+		if err != nil {
+			return nil, err
+		}
+		return getAttr(v, e.attr), nil
+
+	case *indexExpr:
+		v, err := r.eval(e.value)
+		if err != nil {
+			return nil, err
+		}
+		index, err := r.eval(e.index)
+		if err != nil {
+			return nil, err
+		}
+		return getItem(v, index), nil
+
+	case *sliceExpr:
+		v, err := r.eval(e.value)
+		if err != nil {
+			return nil, err
+		}
+		var bounds [3]interface{}
+		for i, b := range []expr{e.start, e.stop, e.step} {
+			if b == nil {
+				continue
+			}
+			bounds[i], err = r.eval(b)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return slice(v, bounds[0], bounds[1], bounds[2])
+
+	case *callExpr:
+		fn, err := r.eval(e.fn)
+		if err != nil {
+			return nil, err
+		}
+		f, ok := fn.(function)
+		if !ok {
+			return nil, fmt.Errorf("%s is not callable", describe(e.fn))
+		}
+		args, kwargs, err := r.evalArgs(e.args, e.kwargs)
+		if err != nil {
+			return nil, err
+		}
+		return f(args, kwargs)
+
+	case *filterExpr:
+		v, err := r.eval(e.value)
+		if err != nil {
+			return nil, err
+		}
+		f, ok := filters[e.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown filter %q", e.name)
+		}
+		args, kwargs, err := r.evalArgs(e.args, e.kwargs)
+		if err != nil {
+			return nil, err
+		}
+		return f(v, args, kwargs)
+
+	case *testExpr:
+		v, err := r.eval(e.value)
+		if err != nil {
+			return nil, err
+		}
+		args, _, err := r.evalArgs(e.args, nil)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := runTest(e.name, v, args)
+		if err != nil {
+			return nil, err
+		}
+		return ok != e.negate, nil
+	}
+
+	return nil, fmt.Errorf("invalid expression %T", e)
+}
+
+func (r *renderer) evalArgs(argExprs []expr, kwargExprs map[string]expr) ([]interface{}, map[string]interface{}, error) {
+	args := make([]interface{}, len(argExprs))
+	for i, a := range argExprs {
+		v, err := r.eval(a)
+		if err != nil {
+			return nil, nil, err
+		}
+		args[i] = v
+	}
+	kwargs := make(map[string]interface{}, len(kwargExprs))
+	for k, a := range kwargExprs {
+		v, err := r.eval(a)
+		if err != nil {
+			return nil, nil, err
+		}
+		kwargs[k] = v
+	}
+
+	return args, kwargs, nil
+}
+
+func (r *renderer) evalBinary(e *binaryExpr) (interface{}, error) {
+	left, err := r.eval(e.left)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuits, returning an operand as Python does.
+	switch e.op {
+	case "and":
+		if !truthy(left) {
+			return left, nil
+		}
+		return r.eval(e.right)
+	case "or":
+		if truthy(left) {
+			return left, nil
+		}
+		return r.eval(e.right)
+	}
+
+	right, err := r.eval(e.right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "<", ">", "<=", ">=":
+		return compare(e.op, left, right)
+	case "in":
+		return contains(right, left)
+	case "not in":
+		ok, err := contains(right, left)
+		return !ok, err
+	case "~":
+		return toString(left) + toString(right), nil
+	default:
+		return arith(e.op, left, right)
+	}
+}
+
+// describe returns the source name of e, for error messages.
+func describe(e expr) string {
+	switch e := e.(type) {
+	case *name:
+		return e.name
+	case *attrExpr:
+		return describe(e.value) + "." + e.attr
+	default:
+		return "expression"
+	}
+}
+
+// Operations
+// ==========
+
+func truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil, undefined:
+		return false
+	case bool:
+		return v
+	case int:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+
+	return true
+}
+
+// toNumber returns v as a float64 and whether it is a number.
+func toNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+func equal(a, b interface{}) bool {
+	if x, ok := toNumber(a); ok {
+		y, ok := toNumber(b)
+		return ok && x == y
+	}
+
+	switch a := a.(type) {
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if w, ok := b[k]; !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+
+	case namespace, function:
+		return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+	}
+
+	return a == b
+}
+
+func compare(op string, a, b interface{}) (bool, error) {
+	var c int
+	x, okx := toNumber(a)
+	y, oky := toNumber(b)
+	sa, oksa := a.(string)
+	sb, oksb := b.(string)
+	switch {
+	case okx && oky:
+		c = cmpFloat(x, y)
+	case oksa && oksb:
+		c = strings.Compare(sa, sb)
+	default:
+		return false, fmt.Errorf("cannot compare %s and %s with %q", typeName(a), typeName(b), op)
+	}
+
+	switch op {
+	case "<":
+		return c < 0, nil
+	case ">":
+		return c > 0, nil
+	case "<=":
+		return c <= 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+func cmpFloat(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func contains(container, item interface{}) (bool, error) {
+	switch c := container.(type) {
+	case string:
+		s, ok := item.(string)
+		if !ok {
+			return false, fmt.Errorf("'in <string>' requires string as left operand, not %s", typeName(item))
+		}
+		return strings.Contains(c, s), nil
+	case []interface{}:
+		for _, v := range c {
+			if equal(v, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]interface{}:
+		k, ok := item.(string)
+		_, found := c[k]
+		return ok && found, nil
+	case namespace:
+		k, ok := item.(string)
+		_, found := c[k]
+		return ok && found, nil
+	case undefined:
+		return false, nil
+	}
+
+	return false, fmt.Errorf("argument of type %s is not iterable", typeName(container))
+}
+
+func arith(op string, a, b interface{}) (interface{}, error) {
+	switch op {
+	case "+":
+		switch a := a.(type) {
+		case string:
+			if b, ok := b.(string); ok {
+				return a + b, nil
+			}
+		case []interface{}:
+			if b, ok := b.([]interface{}); ok {
+				return append(append([]interface{}{}, a...), b...), nil
+			}
+		}
+	case "*":
+		if n, ok := b.(int); ok {
+			switch a := a.(type) {
+			case string:
+				if n < 0 {
+					n = 0
+				}
+				return strings.Repeat(a, n), nil
+			case []interface{}:
+				var l []interface{}
+				for i := 0; i < n; i++ {
+					l = append(l, a...)
+				}
+				return l, nil
+			}
+		}
+	}
+
+	x, okx := toNumber(a)
+	y, oky := toNumber(b)
+	if !okx || !oky {
+		return nil, fmt.Errorf("unsupported operand types for %s: %s and %s", op, typeName(a), typeName(b))
+	}
+	_, floatA := a.(float64)
+	_, floatB := b.(float64)
+	ints := !floatA && !floatB
+
+	var z float64
+	switch op {
+	case "+":
+		z = x + y
+	case "-":
+		z = x - y
+	case "*":
+		z = x * y
+	case "/":
+		if y == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return x / y, nil
+	case "//":
+		if y == 0 {
+			return nil, errors.New("division by zero")
+		}
+		z = math.Floor(x / y)
+	case "%":
+		if y == 0 {
+			return nil, errors.New("modulo by zero")
+		}
+		z = x - y*math.Floor(x/y)
+	case "**":
+		z = math.Pow(x, y)
+		ints = ints && y >= 0
+	}
+	if ints {
+		return int(z), nil
+	}
+
+	return z, nil
+}
+
+// iterate returns the items of v when iterating over it.
+func iterate(v interface{}) ([]interface{}, error) {
+	switch v := v.(type) {
+	case []interface{}:
+		return v, nil
+	case map[string]interface{}:
+		var keys []interface{}
+		for _, k := range sortedKeys(v) {
+			keys = append(keys, k)
+		}
+		return keys, nil
+	case string:
+		var chars []interface{}
+		for _, c := range v {
+			chars = append(chars, string(c))
+		}
+		return chars, nil
+	case nil, undefined:
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("%s is not iterable", typeName(v))
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func getAttr(v interface{}, attr string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if item, ok := v[attr]; ok {
+			return item
+		}
+		if m := dictMethod(v, attr); m != nil {
+			return m
+		}
+	case namespace:
+		if item, ok := v[attr]; ok {
+			return item
+		}
+	case string:
+		if m := stringMethod(v, attr); m != nil {
+			return m
+		}
+	}
+
+	return undefined{}
+}
+
+func getItem(v, index interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		if i, ok := index.(int); ok {
+			if i < 0 {
+				i += len(v)
+			}
+			if 0 <= i && i < len(v) {
+				return v[i]
+			}
+		}
+	case string:
+		if i, ok := index.(int); ok {
+			chars := []rune(v)
+			if i < 0 {
+				i += len(chars)
+			}
+			if 0 <= i && i < len(chars) {
+				return string(chars[i])
+			}
+		}
+	case map[string]interface{}, namespace:
+		if k, ok := index.(string); ok {
+			return getAttr(v, k)
+		}
+	}
+
+	return undefined{}
+}
+
+// slice returns v[start:stop:step] with Python semantics.
+func slice(v, start, stop, step interface{}) (interface{}, error) {
+	var items []interface{}
+	switch v := v.(type) {
+	case []interface{}:
+		items = v
+	case string:
+		for _, c := range v {
+			items = append(items, string(c))
+		}
+	case undefined:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("%s is not subscriptable", typeName(v))
+	}
+
+	bound := func(b interface{}, def int) (int, error) {
+		switch b := b.(type) {
+		case nil:
+			return def, nil
+		case int:
+			return b, nil
+		}
+		return 0, fmt.Errorf("slice indices must be integers or none, not %s", typeName(b))
+	}
+	n := len(items)
+	st, err := bound(step, 1)
+	if err != nil {
+		return nil, err
+	}
+	if st == 0 {
+		return nil, errors.New("slice step cannot be zero")
+	}
+	lower, upper := 0, n
+	if st < 0 {
+		lower, upper = -1, n-1
+	}
+	clamp := func(i int) int {
+		if i < 0 {
+			i += n
+		}
+		switch {
+		case i < lower:
+			return lower
+		case i > upper:
+			return upper
+		}
+		return i
+	}
+	defStart, defStop := lower, upper
+	if st < 0 {
+		defStart, defStop = upper, lower
+	}
+	i, err := bound(start, defStart)
+	if err != nil {
+		return nil, err
+	}
+	j, err := bound(stop, defStop)
+	if err != nil {
+		return nil, err
+	}
+	if start != nil {
+		i = clamp(i)
+	}
+	if stop != nil {
+		j = clamp(j)
+	}
+
+	out := []interface{}{}
+	for ; (st > 0 && i < j) || (st < 0 && i > j); i += st {
+		out = append(out, items[i])
+	}
+	if _, ok := v.(string); ok {
+		var b strings.Builder
+		for _, c := range out {
+			b.WriteString(c.(string))
+		}
+		return b.String(), nil
+	}
+
+	return out, nil
+}
+
+// Strings
+// =======
+
+// toString returns v as Python `str()` does.
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case undefined:
+		return ""
+	}
+
+	return repr(v)
+}
+
+// repr returns v as Python `repr()` does.
+func repr(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "None"
+	case undefined:
+		return ""
+	case bool:
+		if v {
+			return "True"
+		}
+		return "False"
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		switch {
+		case math.IsInf(v, 1):
+			return "inf"
+		case math.IsInf(v, -1):
+			return "-inf"
+		case math.IsNaN(v):
+			return "nan"
+		}
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s
+	case string:
+		quote := "'"
+		if strings.Contains(v, "'") && !strings.Contains(v, `"`) {
+			quote = `"`
+		}
+		r := strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, "\t", `\t`, quote, `\`+quote)
+		return quote + r.Replace(v) + quote
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = repr(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		items := make([]string, 0, len(v))
+		for _, k := range sortedKeys(v) {
+			items = append(items, repr(k)+": "+repr(v[k]))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	case namespace:
+		return "<Namespace " + repr(map[string]interface{}(v)) + ">"
+	case function:
+		return "<function>"
+	}
+
+	return fmt.Sprint(v)
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "NoneType"
+	case undefined:
+		return "Undefined"
+	case bool:
+		return "bool"
+	case int:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "str"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "dict"
+	case namespace:
+		return "Namespace"
+	case function:
+		return "function"
+	}
+
+	return fmt.Sprintf("%T", v)
+}
+
+// length returns the length of v, as `len()`.
+func length(v interface{}) (int, error) {
+	switch v := v.(type) {
+	case string:
+		return utf8.RuneCountInString(v), nil
+	case []interface{}:
+		return len(v), nil
+	case map[string]interface{}:
+		return len(v), nil
+	case namespace:
+		return len(v), nil
+	case undefined:
+		return 0, nil
+	}
+
+	return 0, fmt.Errorf("object of type %s has no length", typeName(v))
+}
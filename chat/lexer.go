@@ -0,0 +1,289 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Template tokens
+// ===============
+
+type tagKind int
+
+const (
+	textTag tagKind = iota
+	exprTag         // {{ ... }}
+	stmtTag         // {% ... %}
+)
+
+// tag is a piece of template: raw text, or the content of an expression or
+// statement tag.
+type tag struct {
+	kind    tagKind
+	content string
+	line    int
+}
+
+// lex splits src into text and tags, applying whitespace control as
+// transformers does for chat templates: `-` markers strip all the whitespaces
+// next to a tag, and `trim_blocks` and `lstrip_blocks` are on, ie. the newline
+// after a statement (or comment) tag and the spaces before it on its line are
+// removed.
+func lex(src string) ([]tag, error) {
+	var (
+		tags      []tag
+		text      strings.Builder
+		lstripNew bool // strip the leading whitespaces of the next text
+	)
+
+	pos := 0
+	for pos < len(src) {
+		start := nextTagStart(src, pos)
+		if start < 0 {
+			start = len(src)
+		}
+		raw := src[pos:start]
+		if lstripNew {
+			raw = strings.TrimLeftFunc(raw, unicode.IsSpace)
+			lstripNew = false
+		}
+		text.WriteString(raw)
+		if start == len(src) {
+			break
+		}
+
+		line := 1 + strings.Count(src[:start], "\n")
+		open := src[start : start+2]
+		closing := map[string]string{"{{": "}}", "{%": "%}", "{#": "#}"}[open]
+		bodyStart := start + 2
+		trimLeft := bodyStart < len(src) && src[bodyStart] == '-'
+		keepLeft := bodyStart < len(src) && src[bodyStart] == '+'
+		if trimLeft || keepLeft {
+			bodyStart++
+		}
+
+		end := tagEnd(src, bodyStart, closing, open == "{#")
+		if end < 0 {
+			return nil, fmt.Errorf("line %v: unclosed %q", line, open)
+		}
+		bodyEnd := end
+		trimRight := bodyEnd > bodyStart && src[bodyEnd-1] == '-'
+		if trimRight {
+			bodyEnd--
+		}
+		pos = end + 2
+
+		// Whitespaces before the tag
+		pending := text.String()
+		switch {
+		case trimLeft:
+			pending = strings.TrimRightFunc(pending, unicode.IsSpace)
+		case open != "{{" && !keepLeft:
+			// lstrip_blocks
+			lineStart := strings.LastIndexByte(src[:start], '\n') + 1
+			if strings.Trim(src[lineStart:start], " \t") == "" {
+				pending = strings.TrimRight(pending, " \t")
+			}
+		}
+		text.Reset()
+		if pending != "" {
+			tags = append(tags, tag{kind: textTag, content: pending, line: line})
+		}
+
+		// Whitespaces after the tag
+		switch {
+		case trimRight:
+			lstripNew = true
+		case open != "{{":
+			// trim_blocks
+			if strings.HasPrefix(src[pos:], "\r\n") {
+				pos += 2
+			} else if strings.HasPrefix(src[pos:], "\n") {
+				pos++
+			}
+		}
+
+		content := strings.TrimSpace(src[bodyStart:bodyEnd])
+		switch open {
+		case "{{":
+			tags = append(tags, tag{kind: exprTag, content: content, line: line})
+		case "{%":
+			tags = append(tags, tag{kind: stmtTag, content: content, line: line})
+		}
+	}
+	if text.Len() > 0 {
+		tags = append(tags, tag{kind: textTag, content: text.String()})
+	}
+
+	return tags, nil
+}
+
+// nextTagStart returns the index of the next tag opening in src from pos, or
+// -1 if none.
+func nextTagStart(src string, pos int) int {
+	for i := pos; i+1 < len(src); i++ {
+		if src[i] == '{' && (src[i+1] == '{' || src[i+1] == '%' || src[i+1] == '#') {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// tagEnd returns the index of closing in src from pos, skipping string
+// literals unless inside a comment, or -1 if not found.
+func tagEnd(src string, pos int, closing string, comment bool) int {
+	var quote byte
+	for i := pos; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case !comment && (c == '\'' || c == '"'):
+			quote = c
+		case strings.HasPrefix(src[i:], closing):
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Expression tokens
+// =================
+
+type tokenKind int
+
+const (
+	eofToken tokenKind = iota
+	nameToken
+	stringToken
+	intToken
+	floatToken
+	opToken
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+}
+
+func (t token) String() string {
+	if t.kind == eofToken {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.val)
+}
+
+// operators, longest first.
+var operators = []string{
+	"**", "//", "==", "!=", "<=", ">=",
+	"+", "-", "*", "/", "%", "~", "|", ".", ",", ":", "(", ")", "[", "]", "{", "}", "=", "<", ">",
+}
+
+// lexExpr splits the content of a tag into tokens.
+func lexExpr(s string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '_' || isLetter(c):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || isLetter(s[j]) || isDigit(s[j])) {
+				j++
+			}
+			tokens = append(tokens, token{nameToken, s[i:j]})
+			i = j
+
+		case isDigit(c):
+			j := i + 1
+			for j < len(s) && isDigit(s[j]) {
+				j++
+			}
+			kind := intToken
+			if j+1 < len(s) && s[j] == '.' && isDigit(s[j+1]) {
+				kind = floatToken
+				j++
+				for j < len(s) && isDigit(s[j]) {
+					j++
+				}
+			}
+			tokens = append(tokens, token{kind, s[i:j]})
+			i = j
+
+		case c == '\'' || c == '"':
+			val, n, err := unquote(s[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{stringToken, val})
+			i += n
+
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected char %q", c)
+			}
+			tokens = append(tokens, token{opToken, op})
+			i += len(op)
+		}
+	}
+
+	return append(tokens, token{kind: eofToken}), nil
+}
+
+// unquote returns the value of the string literal at the start of s and its
+// length in s.
+func unquote(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '\\', '\'', '"':
+				b.WriteByte(s[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated string %s", s)
+}
+
+func isLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,871 @@
+package chat
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Statements
+// ==========
+
+// node is a statement of a template.
+type node interface{}
+
+type textNode struct {
+	text string
+}
+
+type outputNode struct {
+	expr expr
+}
+
+type ifNode struct {
+	conds  []expr
+	bodies [][]node
+	orElse []node
+}
+
+type forNode struct {
+	vars     []string
+	iterable expr
+	filter   expr // optional
+	body     []node
+	orElse   []node
+}
+
+type setNode struct {
+	name string
+	attr string // optional, ie. `set ns.attr = ...`
+	expr expr
+}
+
+type breakNode struct{}
+
+type continueNode struct{}
+
+// parser builds the statements of a list of tags.
+type parser struct {
+	tags []tag
+	pos  int
+}
+
+// parse parses the template src.
+func parse(src string) ([]node, error) {
+	tags, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tags: tags}
+	body, end, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	if end != "" {
+		return nil, fmt.Errorf("unexpected %q", end)
+	}
+
+	return body, nil
+}
+
+// parseBody parses statements until the end of the template or a tag ending
+// a block (e.g. `endif`, `else`) which is returned, with its tokens.
+func (p *parser) parseBody(ends ...string) (body []node, end string, err error) {
+	for p.pos < len(p.tags) {
+		t := p.tags[p.pos]
+		p.pos++
+
+		switch t.kind {
+		case textTag:
+			body = append(body, &textNode{t.content})
+
+		case exprTag:
+			ts, err := newTokenStream(t)
+			if err != nil {
+				return nil, "", err
+			}
+			e, err := ts.parseExpression()
+			if err != nil {
+				return nil, "", err
+			}
+			if err := ts.expectEnd(); err != nil {
+				return nil, "", err
+			}
+			body = append(body, &outputNode{e})
+
+		case stmtTag:
+			ts, err := newTokenStream(t)
+			if err != nil {
+				return nil, "", err
+			}
+			keyword := ts.next()
+			if keyword.kind != nameToken {
+				return nil, "", ts.errorf("expected a statement, got %v", keyword)
+			}
+			for _, e := range ends {
+				if keyword.val == e {
+					p.pos-- // re-read by the caller
+					return body, keyword.val, nil
+				}
+			}
+
+			n, err := p.parseStatement(keyword.val, ts)
+			if err != nil {
+				return nil, "", err
+			}
+			if n != nil {
+				body = append(body, n)
+			}
+		}
+	}
+
+	return body, "", nil
+}
+
+// parseStatement parses the statement starting with keyword.
+func (p *parser) parseStatement(keyword string, ts *tokenStream) (node, error) {
+	switch keyword {
+	case "if":
+		return p.parseIf(ts)
+	case "for":
+		return p.parseFor(ts)
+	case "set":
+		return p.parseSet(ts)
+	case "break":
+		return &breakNode{}, ts.expectEnd()
+	case "continue":
+		return &continueNode{}, ts.expectEnd()
+	case "generation":
+		// Marks the assistant answers for `return_assistant_tokens_mask`,
+		// rendered as is.
+		if err := ts.expectEnd(); err != nil {
+			return nil, err
+		}
+		body, err := p.parseBlock(ts, "endgeneration")
+		if err != nil {
+			return nil, err
+		}
+		return &ifNode{conds: []expr{&literal{true}}, bodies: [][]node{body}}, nil
+	default:
+		return nil, ts.errorf("unsupported statement %q", keyword)
+	}
+}
+
+// parseBlock parses a body ended by end.
+func (p *parser) parseBlock(ts *tokenStream, end string) ([]node, error) {
+	body, got, err := p.parseBody(end)
+	if err != nil {
+		return nil, err
+	}
+	if got == "" {
+		return nil, ts.errorf("missing %q", end)
+	}
+	p.pos++
+
+	return body, nil
+}
+
+func (p *parser) parseIf(ts *tokenStream) (node, error) {
+	n := &ifNode{}
+	for {
+		cond, err := ts.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if err := ts.expectEnd(); err != nil {
+			return nil, err
+		}
+		body, end, err := p.parseBody("elif", "else", "endif")
+		if err != nil {
+			return nil, err
+		}
+		n.conds = append(n.conds, cond)
+		n.bodies = append(n.bodies, body)
+
+		if end == "" {
+			return nil, ts.errorf("missing %q", "endif")
+		}
+		ts, err = newTokenStream(p.tags[p.pos])
+		if err != nil {
+			return nil, err
+		}
+		ts.next()
+		p.pos++
+
+		switch end {
+		case "elif":
+			continue
+		case "else":
+			if err := ts.expectEnd(); err != nil {
+				return nil, err
+			}
+			n.orElse, err = p.parseBlock(ts, "endif")
+			return n, err
+		default:
+			return n, ts.expectEnd()
+		}
+	}
+}
+
+func (p *parser) parseFor(ts *tokenStream) (node, error) {
+	n := &forNode{}
+	for {
+		name, err := ts.expectName()
+		if err != nil {
+			return nil, err
+		}
+		n.vars = append(n.vars, name)
+		if !ts.skipOp(",") {
+			break
+		}
+	}
+	if !ts.skipName("in") {
+		return nil, ts.errorf("expected \"in\", got %v", ts.peek())
+	}
+
+	var err error
+	n.iterable, err = ts.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if ts.skipName("if") {
+		n.filter, err = ts.parseOr()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := ts.expectEnd(); err != nil {
+		return nil, err
+	}
+
+	body, end, err := p.parseBody("else", "endfor")
+	if err != nil {
+		return nil, err
+	}
+	if end == "" {
+		return nil, ts.errorf("missing %q", "endfor")
+	}
+	n.body = body
+	p.pos++
+	if end == "else" {
+		n.orElse, err = p.parseBlock(ts, "endfor")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return n, nil
+}
+
+func (p *parser) parseSet(ts *tokenStream) (node, error) {
+	name, err := ts.expectName()
+	if err != nil {
+		return nil, err
+	}
+	n := &setNode{name: name}
+	if ts.skipOp(".") {
+		n.attr, err = ts.expectName()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !ts.skipOp("=") {
+		return nil, ts.errorf("expected \"=\", got %v", ts.peek())
+	}
+	n.expr, err = ts.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	return n, ts.expectEnd()
+}
+
+// Expressions
+// ===========
+
+// expr is an expression, evaluated to a value (see `eval`).
+type expr interface{}
+
+type literal struct {
+	value interface{}
+}
+
+type name struct {
+	name string
+}
+
+type listExpr struct {
+	items []expr
+}
+
+type dictExpr struct {
+	keys, values []expr
+}
+
+type unaryExpr struct {
+	op      string // "not", "-" or "+"
+	operand expr
+}
+
+type binaryExpr struct {
+	op          string
+	left, right expr
+}
+
+type condExpr struct {
+	cond, then, orElse expr // orElse is optional
+}
+
+type attrExpr struct {
+	value expr
+	attr  string
+}
+
+type indexExpr struct {
+	value, index expr
+}
+
+type sliceExpr struct {
+	value, start, stop, step expr // optional bounds
+}
+
+type callExpr struct {
+	fn     expr
+	args   []expr
+	kwargs map[string]expr
+}
+
+type filterExpr struct {
+	value  expr
+	name   string
+	args   []expr
+	kwargs map[string]expr
+}
+
+type testExpr struct {
+	value  expr
+	name   string
+	args   []expr
+	negate bool
+}
+
+// tokenStream parses the expressions of a tag.
+type tokenStream struct {
+	tokens []token
+	pos    int
+	line   int
+}
+
+func newTokenStream(t tag) (*tokenStream, error) {
+	tokens, err := lexExpr(t.content)
+	if err != nil {
+		return nil, fmt.Errorf("line %v: %w", t.line, err)
+	}
+
+	return &tokenStream{tokens: tokens, line: t.line}, nil
+}
+
+func (ts *tokenStream) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %v: %s", ts.line, fmt.Sprintf(format, args...))
+}
+
+func (ts *tokenStream) peek() token {
+	return ts.tokens[ts.pos]
+}
+
+func (ts *tokenStream) next() token {
+	t := ts.tokens[ts.pos]
+	if t.kind != eofToken {
+		ts.pos++
+	}
+	return t
+}
+
+func (ts *tokenStream) isOp(op string) bool {
+	t := ts.peek()
+	return t.kind == opToken && t.val == op
+}
+
+func (ts *tokenStream) isName(name string) bool {
+	t := ts.peek()
+	return t.kind == nameToken && t.val == name
+}
+
+func (ts *tokenStream) skipOp(op string) bool {
+	if ts.isOp(op) {
+		ts.pos++
+		return true
+	}
+	return false
+}
+
+func (ts *tokenStream) skipName(name string) bool {
+	if ts.isName(name) {
+		ts.pos++
+		return true
+	}
+	return false
+}
+
+func (ts *tokenStream) expectOp(op string) error {
+	if !ts.skipOp(op) {
+		return ts.errorf("expected %q, got %v", op, ts.peek())
+	}
+	return nil
+}
+
+func (ts *tokenStream) expectName() (string, error) {
+	t := ts.next()
+	if t.kind != nameToken {
+		return "", ts.errorf("expected a name, got %v", t)
+	}
+	return t.val, nil
+}
+
+func (ts *tokenStream) expectEnd() error {
+	if t := ts.peek(); t.kind != eofToken {
+		return ts.errorf("unexpected %v", t)
+	}
+	return nil
+}
+
+// parseExpression parses a conditional expression, the lowest precedence.
+func (ts *tokenStream) parseExpression() (expr, error) {
+	e, err := ts.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	for ts.skipName("if") {
+		cond, err := ts.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		c := &condExpr{cond: cond, then: e}
+		if ts.skipName("else") {
+			c.orElse, err = ts.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+		}
+		e = c
+	}
+
+	return e, nil
+}
+
+func (ts *tokenStream) parseOr() (expr, error) {
+	left, err := ts.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for ts.skipName("or") {
+		right, err := ts.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{"or", left, right}
+	}
+
+	return left, nil
+}
+
+func (ts *tokenStream) parseAnd() (expr, error) {
+	left, err := ts.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for ts.skipName("and") {
+		right, err := ts.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{"and", left, right}
+	}
+
+	return left, nil
+}
+
+func (ts *tokenStream) parseNot() (expr, error) {
+	if ts.skipName("not") {
+		operand, err := ts.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{"not", operand}, nil
+	}
+
+	return ts.parseCompare()
+}
+
+func (ts *tokenStream) parseCompare() (expr, error) {
+	left, err := ts.parseMath1()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		switch t := ts.peek(); {
+		case t.kind == opToken && (t.val == "==" || t.val == "!=" || t.val == "<" || t.val == ">" || t.val == "<=" || t.val == ">="):
+			op = t.val
+			ts.next()
+		case ts.skipName("in"):
+			op = "in"
+		case ts.isName("not") && ts.tokens[ts.pos+1].kind == nameToken && ts.tokens[ts.pos+1].val == "in":
+			ts.pos += 2
+			op = "not in"
+		default:
+			return left, nil
+		}
+
+		right, err := ts.parseMath1()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op, left, right}
+	}
+}
+
+func (ts *tokenStream) parseMath1() (expr, error) {
+	left, err := ts.parseConcat()
+	if err != nil {
+		return nil, err
+	}
+	for ts.isOp("+") || ts.isOp("-") {
+		op := ts.next().val
+		right, err := ts.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op, left, right}
+	}
+
+	return left, nil
+}
+
+func (ts *tokenStream) parseConcat() (expr, error) {
+	left, err := ts.parseMath2()
+	if err != nil {
+		return nil, err
+	}
+	for ts.skipOp("~") {
+		right, err := ts.parseMath2()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{"~", left, right}
+	}
+
+	return left, nil
+}
+
+func (ts *tokenStream) parseMath2() (expr, error) {
+	left, err := ts.parsePow()
+	if err != nil {
+		return nil, err
+	}
+	for ts.isOp("*") || ts.isOp("/") || ts.isOp("//") || ts.isOp("%") {
+		op := ts.next().val
+		right, err := ts.parsePow()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op, left, right}
+	}
+
+	return left, nil
+}
+
+func (ts *tokenStream) parsePow() (expr, error) {
+	left, err := ts.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for ts.skipOp("**") {
+		right, err := ts.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{"**", left, right}
+	}
+
+	return left, nil
+}
+
+func (ts *tokenStream) parseUnary() (expr, error) {
+	var (
+		e   expr
+		err error
+	)
+	if ts.isOp("-") || ts.isOp("+") {
+		op := ts.next().val
+		operand, err := ts.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		e = &unaryExpr{op, operand}
+	} else {
+		e, err = ts.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		e, err = ts.parsePostfix(e)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ts.parseFilters(e)
+}
+
+func (ts *tokenStream) parsePrimary() (expr, error) {
+	t := ts.next()
+	switch t.kind {
+	case nameToken:
+		switch t.val {
+		case "true", "True":
+			return &literal{true}, nil
+		case "false", "False":
+			return &literal{false}, nil
+		case "none", "None":
+			return &literal{nil}, nil
+		}
+		return &name{t.val}, nil
+
+	case stringToken:
+		s := t.val
+		// Adjacent string literals are concatenated.
+		for ts.peek().kind == stringToken {
+			s += ts.next().val
+		}
+		return &literal{s}, nil
+
+	case intToken:
+		v, err := strconv.Atoi(t.val)
+		if err != nil {
+			return nil, ts.errorf("invalid int %v", t)
+		}
+		return &literal{v}, nil
+
+	case floatToken:
+		v, err := strconv.ParseFloat(t.val, 64)
+		if err != nil {
+			return nil, ts.errorf("invalid float %v", t)
+		}
+		return &literal{v}, nil
+
+	case opToken:
+		switch t.val {
+		case "(":
+			e, err := ts.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			if !ts.isOp(",") {
+				return e, ts.expectOp(")")
+			}
+			// A tuple, evaluated as a list.
+			items := []expr{e}
+			for ts.skipOp(",") && !ts.isOp(")") {
+				e, err := ts.parseExpression()
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, e)
+			}
+			return &listExpr{items}, ts.expectOp(")")
+
+		case "[":
+			l := &listExpr{}
+			for !ts.isOp("]") {
+				e, err := ts.parseExpression()
+				if err != nil {
+					return nil, err
+				}
+				l.items = append(l.items, e)
+				if !ts.skipOp(",") {
+					break
+				}
+			}
+			return l, ts.expectOp("]")
+
+		case "{":
+			d := &dictExpr{}
+			for !ts.isOp("}") {
+				k, err := ts.parseExpression()
+				if err != nil {
+					return nil, err
+				}
+				if err := ts.expectOp(":"); err != nil {
+					return nil, err
+				}
+				v, err := ts.parseExpression()
+				if err != nil {
+					return nil, err
+				}
+				d.keys = append(d.keys, k)
+				d.values = append(d.values, v)
+				if !ts.skipOp(",") {
+					break
+				}
+			}
+			return d, ts.expectOp("}")
+		}
+	}
+
+	return nil, ts.errorf("unexpected %v", t)
+}
+
+// parsePostfix parses the attributes, subscripts and calls following e.
+func (ts *tokenStream) parsePostfix(e expr) (expr, error) {
+	for {
+		switch {
+		case ts.skipOp("."):
+			t := ts.next()
+			if t.kind != nameToken && t.kind != intToken {
+				return nil, ts.errorf("expected an attribute, got %v", t)
+			}
+			if t.kind == intToken {
+				i, _ := strconv.Atoi(t.val)
+				e = &indexExpr{e, &literal{i}}
+			} else {
+				e = &attrExpr{e, t.val}
+			}
+
+		case ts.skipOp("["):
+			var err error
+			e, err = ts.parseSubscript(e)
+			if err != nil {
+				return nil, err
+			}
+
+		case ts.isOp("("):
+			args, kwargs, err := ts.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			e = &callExpr{e, args, kwargs}
+
+		default:
+			return e, nil
+		}
+	}
+}
+
+// parseSubscript parses `[index]` or `[start:stop:step]` after `[`.
+func (ts *tokenStream) parseSubscript(value expr) (expr, error) {
+	var (
+		bounds [3]expr
+		slice  bool
+	)
+	for i := 0; i < 3; i++ {
+		if !ts.isOp(":") && !ts.isOp("]") {
+			e, err := ts.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			bounds[i] = e
+		}
+		if i == 2 || !ts.skipOp(":") {
+			break
+		}
+		slice = true
+	}
+	if err := ts.expectOp("]"); err != nil {
+		return nil, err
+	}
+
+	if !slice {
+		if bounds[0] == nil {
+			return nil, ts.errorf("expected an index")
+		}
+		return &indexExpr{value, bounds[0]}, nil
+	}
+
+	return &sliceExpr{value, bounds[0], bounds[1], bounds[2]}, nil
+}
+
+// parseArgs parses the arguments of a call, starting at `(`.
+func (ts *tokenStream) parseArgs() (args []expr, kwargs map[string]expr, err error) {
+	if err := ts.expectOp("("); err != nil {
+		return nil, nil, err
+	}
+	for !ts.isOp(")") {
+		if t := ts.peek(); t.kind == nameToken && ts.tokens[ts.pos+1].kind == opToken && ts.tokens[ts.pos+1].val == "=" {
+			ts.pos += 2
+			v, err := ts.parseExpression()
+			if err != nil {
+				return nil, nil, err
+			}
+			if kwargs == nil {
+				kwargs = make(map[string]expr)
+			}
+			kwargs[t.val] = v
+		} else {
+			v, err := ts.parseExpression()
+			if err != nil {
+				return nil, nil, err
+			}
+			args = append(args, v)
+		}
+		if !ts.skipOp(",") {
+			break
+		}
+	}
+
+	return args, kwargs, ts.expectOp(")")
+}
+
+// parseFilters parses the filters (`|name(args)`) and tests (`is [not]
+// name args`) applied to e.
+func (ts *tokenStream) parseFilters(e expr) (expr, error) {
+	for {
+		switch {
+		case ts.skipOp("|"):
+			name, err := ts.expectName()
+			if err != nil {
+				return nil, err
+			}
+			f := &filterExpr{value: e, name: name}
+			if ts.isOp("(") {
+				f.args, f.kwargs, err = ts.parseArgs()
+				if err != nil {
+					return nil, err
+				}
+			}
+			e = f
+
+		case ts.skipName("is"):
+			t := &testExpr{value: e, negate: ts.skipName("not")}
+			var err error
+			t.name, err = ts.expectName()
+			if err != nil {
+				return nil, err
+			}
+			switch next := ts.peek(); {
+			case ts.isOp("("):
+				t.args, _, err = ts.parseArgs()
+			case next.kind == stringToken || next.kind == intToken || next.kind == floatToken,
+				next.kind == nameToken && !isKeyword(next.val):
+				// A single argument without parentheses, e.g. `is divisibleby 3`.
+				var arg expr
+				arg, err = ts.parsePrimary()
+				t.args = []expr{arg}
+			}
+			if err != nil {
+				return nil, err
+			}
+			e = t
+
+		default:
+			return e, nil
+		}
+	}
+}
+
+func isKeyword(s string) bool {
+	switch s {
+	case "and", "or", "not", "in", "is", "if", "else":
+		return true
+	}
+	return false
+}
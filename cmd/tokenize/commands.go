@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/decoder"
+	"github.com/sugarme/tokenizer/model/bpe"
+	"github.com/sugarme/tokenizer/model/unigram"
+	"github.com/sugarme/tokenizer/model/wordpiece"
+	"github.com/sugarme/tokenizer/normalizer"
+	"github.com/sugarme/tokenizer/pretokenizer"
+	"github.com/sugarme/tokenizer/pretrained"
+)
+
+// tokenizerFlags are the flags selecting the tokenizer to load.
+type tokenizerFlags struct {
+	file, vocab, merges string
+	lowercase           bool
+}
+
+func (f *tokenizerFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.file, "tokenizer", "", "tokenizer.json file")
+	fs.StringVar(&f.vocab, "vocab", "", "vocab.json (with -merges) or vocab.txt file")
+	fs.StringVar(&f.merges, "merges", "", "merges.txt file")
+	fs.BoolVar(&f.lowercase, "lowercase", true, "lowercase inputs of a vocab.txt tokenizer")
+}
+
+func (f *tokenizerFlags) load() (*tokenizer.Tokenizer, error) {
+	switch {
+	case f.file != "":
+		return pretrained.FromFile(f.file)
+
+	case f.vocab != "" && f.merges != "":
+		model, err := bpe.NewBpeFromFiles(f.vocab, f.merges)
+		if err != nil {
+			return nil, err
+		}
+		byteLevel := pretokenizer.NewByteLevel()
+		tk := tokenizer.NewTokenizer(model)
+		tk.WithPreTokenizer(byteLevel)
+		tk.WithDecoder(byteLevel)
+		return tk, nil
+
+	case f.vocab != "":
+		model, err := wordpiece.NewWordPieceFromFile(f.vocab, "[UNK]")
+		if err != nil {
+			return nil, err
+		}
+		tk := tokenizer.NewTokenizer(model)
+		tk.WithNormalizer(normalizer.NewBertNormalizer(true, f.lowercase, true, f.lowercase))
+		tk.WithPreTokenizer(pretokenizer.NewBertPreTokenizer())
+		tk.WithDecoder(decoder.NewWordPieceDecoder("##", true))
+		return tk, nil
+
+	default:
+		return nil, fmt.Errorf("missing -tokenizer or -vocab file")
+	}
+}
+
+// ioFlags are the flags of the input and output of a command.
+type ioFlags struct {
+	in, input, output string
+}
+
+func (f *ioFlags) register(fs *flag.FlagSet, outputs string) {
+	fs.StringVar(&f.in, "in", "", "input file (default stdin)")
+	fs.StringVar(&f.input, "input", "text", "input format: text or jsonl")
+	fs.StringVar(&f.output, "output", "text", "output format: "+outputs)
+}
+
+// lines calls fn with each line of the input.
+func (f *ioFlags) lines(stdin io.Reader, fn func(n int, line string) error) error {
+	r := stdin
+	if f.in != "" {
+		file, err := os.Open(f.in)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if err := fn(n, scanner.Text()); err != nil {
+			return fmt.Errorf("line %v: %w", n, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (f *ioFlags) jsonl() (bool, error) {
+	switch f.input {
+	case "text":
+		return false, nil
+	case "jsonl", "ndjson":
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid -input format %q", f.input)
+	}
+}
+
+func runEncode(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var (
+		fs      = flag.NewFlagSet("encode", flag.ContinueOnError)
+		tkFlags tokenizerFlags
+		inout   ioFlags
+		special = fs.Bool("special", false, "add special tokens")
+	)
+	fs.SetOutput(stderr)
+	tkFlags.register(fs)
+	inout.register(fs, "text, ids or jsonl")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	jsonl, err := inout.jsonl()
+	if err != nil {
+		return err
+	}
+	switch inout.output {
+	case "text", "ids", "jsonl", "ndjson":
+	default:
+		return fmt.Errorf("invalid -output format %q", inout.output)
+	}
+
+	tk, err := tkFlags.load()
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(stdout)
+	defer w.Flush()
+
+	return inout.lines(stdin, func(n int, line string) error {
+		input := tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence(line))
+		if jsonl {
+			var v struct {
+				Text string  `json:"text"`
+				Pair *string `json:"pair"`
+			}
+			if err := json.Unmarshal([]byte(line), &v); err != nil {
+				return err
+			}
+			input = tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence(v.Text))
+			if v.Pair != nil {
+				input = tokenizer.NewDualEncodeInput(tokenizer.NewInputSequence(v.Text), tokenizer.NewInputSequence(*v.Pair))
+			}
+		}
+
+		en, err := tk.Encode(input, *special)
+		if err != nil {
+			return err
+		}
+
+		switch inout.output {
+		case "text":
+			_, err = fmt.Fprintln(w, strings.Join(en.Tokens, " "))
+		case "ids":
+			_, err = fmt.Fprintln(w, joinInts(en.Ids))
+		default:
+			var data []byte
+			data, err = json.Marshal(en)
+			if err == nil {
+				_, err = fmt.Fprintln(w, string(data))
+			}
+		}
+		return err
+	})
+}
+
+func runDecode(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var (
+		fs          = flag.NewFlagSet("decode", flag.ContinueOnError)
+		tkFlags     tokenizerFlags
+		inout       ioFlags
+		skipSpecial = fs.Bool("skip-special", false, "skip special tokens")
+	)
+	fs.SetOutput(stderr)
+	tkFlags.register(fs)
+	inout.register(fs, "text or jsonl")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	jsonl, err := inout.jsonl()
+	if err != nil {
+		return err
+	}
+	switch inout.output {
+	case "text", "jsonl", "ndjson":
+	default:
+		return fmt.Errorf("invalid -output format %q", inout.output)
+	}
+
+	tk, err := tkFlags.load()
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(stdout)
+	defer w.Flush()
+
+	return inout.lines(stdin, func(n int, line string) error {
+		var ids []int
+		if jsonl {
+			var v struct {
+				Ids []int `json:"ids"`
+			}
+			if err := json.Unmarshal([]byte(line), &v); err != nil {
+				return err
+			}
+			ids = v.Ids
+		} else {
+			for _, field := range strings.Fields(line) {
+				id, err := strconv.Atoi(field)
+				if err != nil {
+					return fmt.Errorf("invalid id %q", field)
+				}
+				ids = append(ids, id)
+			}
+		}
+
+		text := tk.Decode(ids, *skipSpecial)
+		if inout.output == "text" {
+			_, err := fmt.Fprintln(w, text)
+			return err
+		}
+		data, err := json.Marshal(map[string]string{"text": text})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	})
+}
+
+func runTrain(args []string, stdout, stderr io.Writer) error {
+	var (
+		fs            = flag.NewFlagSet("train", flag.ContinueOnError)
+		out           = fs.String("o", "", "output tokenizer.json file")
+		modelType     = fs.String("model", "bpe", "model to train: bpe, wordpiece or unigram")
+		vocabSize     = fs.Int("vocab-size", 30000, "size of the vocabulary")
+		minFrequency  = fs.Int("min-frequency", 2, "minimum frequency of a merge (bpe, wordpiece)")
+		specialTokens = fs.String("special-tokens", "", "comma separated special tokens")
+		modelDir      = fs.String("model-dir", "", "also save the model files (vocab.json and merges.txt, vocab.txt or unigram.json) to this directory")
+	)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return fmt.Errorf("missing -o output file")
+	}
+	files := fs.Args()
+	if len(files) == 0 {
+		return fmt.Errorf("missing training files")
+	}
+
+	var specials []tokenizer.AddedToken
+	for _, tok := range strings.Split(*specialTokens, ",") {
+		if tok = strings.TrimSpace(tok); tok != "" {
+			specials = append(specials, tokenizer.NewAddedToken(tok, true))
+		}
+	}
+
+	model, err := bpe.DefaultBPE()
+	if err != nil {
+		return err
+	}
+	tk := tokenizer.NewTokenizer(model)
+
+	var trainer tokenizer.Trainer
+	switch *modelType {
+	case "bpe":
+		b := bpe.NewBPETrainerBuilder()
+		b.VocabSize(*vocabSize)
+		b.MinFrequency(*minFrequency)
+		b.SpecialTokens(specials)
+		b.ShowProgress(false)
+		trainer = b.Build()
+		byteLevel := pretokenizer.NewByteLevel()
+		tk.WithPreTokenizer(byteLevel)
+		tk.WithDecoder(byteLevel)
+	case "wordpiece":
+		t := wordpiece.NewWordPieceTrainerBuilder().
+			VocabSize(*vocabSize).
+			MinFrequency(*minFrequency).
+			SpecialTokens(specials).
+			ShowProgress(false).
+			Build()
+		trainer = &t
+		tk.WithPreTokenizer(pretokenizer.NewBertPreTokenizer())
+		tk.WithDecoder(decoder.NewWordPieceDecoder("##", true))
+	case "unigram":
+		trainer = unigram.NewUnigramTrainerBuilder().
+			VocabSize(*vocabSize).
+			SpecialTokens(specials).
+			ShowProgress(false).
+			Build()
+		tk.WithPreTokenizer(pretokenizer.NewWhitespaceSplit())
+	default:
+		return fmt.Errorf("invalid -model %q", *modelType)
+	}
+
+	if err := tk.Train(trainer, files); err != nil {
+		return err
+	}
+	if err := tk.Save(*out, true); err != nil {
+		return err
+	}
+	if *modelDir != "" {
+		if err := tk.GetModel().Save(*modelDir); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(stdout, "Saved %v tokenizer of %v tokens to %v\n", *modelType, tk.GetVocabSize(true), *out)
+	return nil
+}
+
+func runInspect(args []string, stdout, stderr io.Writer) error {
+	var (
+		fs      = flag.NewFlagSet("inspect", flag.ContinueOnError)
+		tkFlags tokenizerFlags
+	)
+	fs.SetOutput(stderr)
+	tkFlags.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	tk, err := tkFlags.load()
+	if err != nil {
+		return err
+	}
+
+	specials := tk.GetSpecialTokens()
+	sort.Strings(specials)
+
+	w := bufio.NewWriter(stdout)
+	defer w.Flush()
+	fmt.Fprintf(w, "Model:          %T\n", tk.GetModel())
+	fmt.Fprintf(w, "Vocab size:     %v (%v with added tokens)\n", tk.GetVocabSize(false), tk.GetVocabSize(true))
+	fmt.Fprintf(w, "Special tokens: %v\n", strings.Join(specials, " "))
+	fmt.Fprintf(w, "Normalizer:     %v\n", typeName(tk.GetNormalizer()))
+	fmt.Fprintf(w, "Pre-tokenizer:  %v\n", typeName(tk.GetPreTokenizer()))
+	fmt.Fprintf(w, "Post-processor: %v\n", typeName(tk.GetPostProcessor()))
+	fmt.Fprintf(w, "Decoder:        %v\n", typeName(tk.GetDecoder()))
+	if trunc := tk.GetTruncation(); trunc != nil {
+		fmt.Fprintf(w, "Truncation:     max length %v, stride %v\n", trunc.MaxLength, trunc.Stride)
+	}
+	if tk.GetPadding() != nil {
+		fmt.Fprintf(w, "Padding:        %+v\n", *tk.GetPadding())
+	}
+	if fp, err := tk.Fingerprint(); err == nil {
+		fmt.Fprintf(w, "Fingerprint:    %v\n", fp)
+	}
+
+	return nil
+}
+
+func typeName(v interface{}) string {
+	if v == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func joinInts(values []int) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, " ")
+}
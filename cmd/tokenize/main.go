@@ -0,0 +1,69 @@
+// Command tokenize encodes, decodes and trains tokenizers from the command
+// line.
+//
+// Usage:
+//
+//	tokenize encode  [-tokenizer tokenizer.json | -vocab FILE [-merges FILE]] [-in FILE] [-input text|jsonl] [-output text|ids|jsonl] [-special]
+//	tokenize decode  [-tokenizer tokenizer.json | -vocab FILE [-merges FILE]] [-in FILE] [-input text|jsonl] [-output text|jsonl] [-skip-special]
+//	tokenize train   -o tokenizer.json [-model bpe|wordpiece|unigram] [-vocab-size N] [-min-frequency N] [-special-tokens LIST] [-model-dir DIR] FILE...
+//	tokenize inspect [-tokenizer tokenizer.json | -vocab FILE [-merges FILE]]
+//
+// A `vocab.json` and `merges.txt` pair is loaded as a GPT-2 like byte-level
+// BPE tokenizer, a single `vocab.txt` as a BERT like WordPiece tokenizer.
+//
+// Input is read from stdin unless `-in` is given, one sequence per line:
+//   - encode: `text` lines are sequences, `jsonl` (or `ndjson`) lines are
+//     objects `{"text": ..., "pair": ...}` where "pair" is optional.
+//   - decode: `text` lines are space separated ids, `jsonl` lines are objects
+//     `{"ids": [...]}`.
+//
+// Output is written to stdout, one line per input line.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `Usage: tokenize <command> [flags]
+
+Commands:
+  encode   encode sequences into tokens and ids
+  decode   decode ids into sequences
+  train    train a tokenizer on text files
+  inspect  print a summary of a tokenizer
+
+Run 'tokenize <command> -h' for the flags of a command.
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "tokenize: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return fmt.Errorf("missing command")
+	}
+
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "encode":
+		return runEncode(args, stdin, stdout, stderr)
+	case "decode":
+		return runDecode(args, stdin, stdout, stderr)
+	case "train":
+		return runTrain(args, stdout, stderr)
+	case "inspect":
+		return runInspect(args, stdout, stderr)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	default:
+		fmt.Fprint(stderr, usage)
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/model/wordlevel"
+	"github.com/sugarme/tokenizer/pretokenizer"
+)
+
+func saveTokenizer(t *testing.T) string {
+	vocab := map[string]int{"[UNK]": 0, "hello": 1, "world": 2, "you": 3}
+	model, err := wordlevel.New(vocab, "[UNK]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := tokenizer.NewTokenizer(model)
+	tk.WithPreTokenizer(pretokenizer.NewWhitespaceSplit())
+
+	file := filepath.Join(t.TempDir(), "tokenizer.json")
+	if err := tk.Save(file, false); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestRun(t *testing.T) {
+	file := saveTokenizer(t)
+
+	tests := []struct {
+		args  []string
+		stdin string
+		want  string
+	}{
+		{[]string{"encode"}, "hello world\nyou there\n", "hello world\nyou [UNK]\n"},
+		{[]string{"encode", "-output", "ids"}, "hello world\n", "1 2\n"},
+		{
+			[]string{"encode", "-input", "jsonl", "-output", "jsonl"},
+			`{"text": "hello", "pair": "you"}` + "\n",
+			`{"input_ids":[1,3],"token_type_ids":[0,1],"tokens":["hello","you"],"offset_mapping":[[0,5],[0,3]],"special_tokens_mask":[0,0],"attention_mask":[1,1],"word_ids":[0,0],"sequence_ranges":{"0":[0],"1":[1]}}` + "\n",
+		},
+		{[]string{"decode"}, "1 2\n3\n", "hello world\nyou\n"},
+		{[]string{"decode", "-input", "ndjson", "-output", "jsonl"}, `{"ids": [3, 1]}` + "\n", `{"text":"you hello"}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		args := append(tt.args, "-tokenizer", file)
+		if err := run(args, strings.NewReader(tt.stdin), &stdout, &stderr); err != nil {
+			t.Errorf("%v: %v (%s)", tt.args, err, stderr.String())
+			continue
+		}
+		if stdout.String() != tt.want {
+			t.Errorf("%v: want %q, got %q", tt.args, tt.want, stdout.String())
+		}
+	}
+}
+
+func TestRun_Inspect(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"inspect", "-tokenizer", saveTokenizer(t)}, nil, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"Model:          *wordlevel.WordLevel", "Vocab size:     4 (4 with added tokens)", "Pre-tokenizer:  *pretokenizer.WhitespaceSplit", "Fingerprint:    "} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("want %q in:\n%s", want, stdout.String())
+		}
+	}
+}
+
+func TestRun_Train(t *testing.T) {
+	dir := t.TempDir()
+	corpus := filepath.Join(dir, "corpus.txt")
+	if err := os.WriteFile(corpus, []byte(strings.Repeat("hello world hello you\n", 20)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "tokenizer.json")
+
+	var stdout, stderr bytes.Buffer
+	modelDir := filepath.Join(dir, "model")
+	err := run([]string{"train", "-o", out, "-vocab-size", "100", "-special-tokens", "<unk>,<pad>", "-model-dir", modelDir, corpus}, nil, &stdout, &stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"vocab.json", "merges.txt"} {
+		if _, err := os.Stat(filepath.Join(modelDir, f)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	stdout.Reset()
+	if err := run([]string{"encode", "-tokenizer", out}, strings.NewReader("hello world\n"), &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Ġhello Ġworld\n"; stdout.String() != want {
+		t.Errorf("want %q, got %q", want, stdout.String())
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"unknown"},
+		{"encode"},
+		{"encode", "-tokenizer", "x.json", "-output", "xml"},
+		{"train", "corpus.txt"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if err := run(args, strings.NewReader(""), &stdout, &stderr); err == nil {
+			t.Errorf("%v: want error", args)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+module github.com/sugarme/tokenizer/cmd/tokenizer-server
+
+go 1.22
+
+require (
+	github.com/sugarme/tokenizer v0.0.0
+	google.golang.org/grpc v1.64.1
+	google.golang.org/protobuf v1.36.6
+)
+
+require (
+	github.com/emirpasic/gods v1.12.0 // indirect
+	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db // indirect
+	github.com/rivo/uniseg v0.4.7 // indirect
+	github.com/schollz/progressbar/v2 v2.15.0 // indirect
+	github.com/sugarme/regexpset v0.0.0-20200920021344-4d4ec8eaf93c // indirect
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
+)
+
+replace github.com/sugarme/tokenizer => ../..
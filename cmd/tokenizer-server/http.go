@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sugarme/tokenizer"
+)
+
+// httpOptions are the limits of the HTTP server.
+type httpOptions struct {
+	// MaxBodySize is the maximum size in bytes of a request body.
+	MaxBodySize int64
+	// MaxConcurrency is the maximum number of requests processed at the same
+	// time. Other requests wait for their turn. Zero or less means no limit.
+	MaxConcurrency int
+}
+
+type httpInput struct {
+	Sequence string  `json:"sequence"`
+	Pair     *string `json:"pair,omitempty"`
+}
+
+type encodeRequest struct {
+	httpInput
+	AddSpecialTokens bool `json:"add_special_tokens"`
+}
+
+type encodeBatchRequest struct {
+	Inputs           []httpInput `json:"inputs"`
+	AddSpecialTokens bool        `json:"add_special_tokens"`
+}
+
+type decodeRequest struct {
+	Ids               []int `json:"ids"`
+	SkipSpecialTokens bool  `json:"skip_special_tokens"`
+}
+
+type httpEncoding struct {
+	Ids               []int          `json:"ids"`
+	TypeIds           []int          `json:"type_ids"`
+	Tokens            []string       `json:"tokens"`
+	Offsets           [][]int        `json:"offsets"`
+	SpecialTokensMask []int          `json:"special_tokens_mask"`
+	AttentionMask     []int          `json:"attention_mask"`
+	WordIds           []int          `json:"word_ids"`
+	Overflowing       []httpEncoding `json:"overflowing,omitempty"`
+}
+
+// newHTTPHandler returns the handler of the HTTP/JSON API:
+//
+//	POST /encode        {"sequence", "pair", "add_special_tokens"} -> encoding
+//	POST /encode_batch  {"inputs": [{"sequence", "pair"}], "add_special_tokens"} -> {"encodings"}
+//	POST /decode        {"ids", "skip_special_tokens"} -> {"text"}
+//	POST /tokenize      {"sequence", "pair", "add_special_tokens"} -> {"tokens"}
+//
+// Errors are returned as {"error"}.
+func newHTTPHandler(tk *tokenizer.Tokenizer, opts httpOptions) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/encode", func(w http.ResponseWriter, r *http.Request) {
+		var req encodeRequest
+		if !decodeJSON(w, r, opts, &req) {
+			return
+		}
+		en, err := tk.Encode(req.httpInput.encodeInput(), req.AddSpecialTokens)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, toHTTPEncoding(en))
+	})
+	mux.HandleFunc("/encode_batch", func(w http.ResponseWriter, r *http.Request) {
+		var req encodeBatchRequest
+		if !decodeJSON(w, r, opts, &req) {
+			return
+		}
+		inputs := make([]tokenizer.EncodeInput, len(req.Inputs))
+		for i, input := range req.Inputs {
+			inputs[i] = input.encodeInput()
+		}
+		encodings, err := tk.EncodeBatch(inputs, req.AddSpecialTokens)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		out := make([]httpEncoding, len(encodings))
+		for i := range encodings {
+			out[i] = toHTTPEncoding(&encodings[i])
+		}
+		writeJSON(w, map[string]interface{}{"encodings": out})
+	})
+	mux.HandleFunc("/decode", func(w http.ResponseWriter, r *http.Request) {
+		var req decodeRequest
+		if !decodeJSON(w, r, opts, &req) {
+			return
+		}
+		writeJSON(w, map[string]string{"text": tk.Decode(req.Ids, req.SkipSpecialTokens)})
+	})
+	mux.HandleFunc("/tokenize", func(w http.ResponseWriter, r *http.Request) {
+		var req encodeRequest
+		if !decodeJSON(w, r, opts, &req) {
+			return
+		}
+		en, err := tk.Encode(req.httpInput.encodeInput(), req.AddSpecialTokens)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, map[string][]string{"tokens": en.Tokens})
+	})
+
+	if opts.MaxConcurrency <= 0 {
+		return mux
+	}
+
+	sem := make(chan struct{}, opts.MaxConcurrency)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+			mux.ServeHTTP(w, r)
+		case <-r.Context().Done():
+			writeError(w, http.StatusServiceUnavailable, r.Context().Err())
+		}
+	})
+}
+
+func (input httpInput) encodeInput() tokenizer.EncodeInput {
+	seq := tokenizer.NewInputSequence(input.Sequence)
+	if input.Pair == nil {
+		return tokenizer.NewSingleEncodeInput(seq)
+	}
+
+	return tokenizer.NewDualEncodeInput(seq, tokenizer.NewInputSequence(*input.Pair))
+}
+
+// decodeJSON decodes the JSON body of a POST request into v. It writes the
+// error response and returns false if it fails.
+func decodeJSON(w http.ResponseWriter, r *http.Request, opts httpOptions, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("Method %v not allowed", r.Method))
+		return false
+	}
+
+	body := r.Body
+	if opts.MaxBodySize > 0 {
+		body = http.MaxBytesReader(w, r.Body, opts.MaxBodySize)
+	}
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		status := http.StatusBadRequest
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, status, fmt.Errorf("Invalid request: %w", err))
+		return false
+	}
+
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+func toHTTPEncoding(en *tokenizer.Encoding) httpEncoding {
+	out := httpEncoding{
+		Ids:               en.Ids,
+		TypeIds:           en.TypeIds,
+		Tokens:            en.Tokens,
+		Offsets:           en.Offsets,
+		SpecialTokensMask: en.SpecialTokenMask,
+		AttentionMask:     en.AttentionMask,
+		WordIds:           wordIds(en),
+	}
+	for i := range en.Overflowing {
+		out.Overflowing = append(out.Overflowing, toHTTPEncoding(&en.Overflowing[i]))
+	}
+
+	return out
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	handler := newHTTPHandler(newTokenizer(t), httpOptions{MaxBodySize: 200, MaxConcurrency: 2})
+
+	tests := []struct {
+		method, path, body string
+		wantStatus         int
+		wantBody           string
+	}{
+		{
+			"POST", "/encode", `{"sequence": "hello world", "pair": "you"}`,
+			http.StatusOK,
+			`{"ids":[1,2,3],"type_ids":[0,0,1],"tokens":["hello","world","you"],"offsets":[[0,5],[6,11],[0,3]],"special_tokens_mask":[0,0,0],"attention_mask":[1,1,1],"word_ids":[0,1,0]}`,
+		},
+		{
+			"POST", "/encode_batch", `{"inputs": [{"sequence": "hello"}, {"sequence": "you"}]}`,
+			http.StatusOK,
+			`{"encodings":[{"ids":[1],"type_ids":[0],"tokens":["hello"],"offsets":[[0,5]],"special_tokens_mask":[0],"attention_mask":[1],"word_ids":[0]},{"ids":[3],"type_ids":[0],"tokens":["you"],"offsets":[[0,3]],"special_tokens_mask":[0],"attention_mask":[1],"word_ids":[0]}]}`,
+		},
+		{"POST", "/decode", `{"ids": [1, 2]}`, http.StatusOK, `{"text":"hello world"}`},
+		{"POST", "/tokenize", `{"sequence": "hello there"}`, http.StatusOK, `{"tokens":["hello","[UNK]"]}`},
+		{"GET", "/encode", ``, http.StatusMethodNotAllowed, `{"error":"Method GET not allowed"}`},
+		{"POST", "/encode", `{"text": "hello"}`, http.StatusBadRequest, `{"error":"Invalid request: json: unknown field \"text\""}`},
+		{"POST", "/encode", `{"sequence": "` + strings.Repeat("a", 300) + `"}`, http.StatusRequestEntityTooLarge, ``},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%v %v: want status %v, got %v (%s)", tt.method, tt.path, tt.wantStatus, rec.Code, rec.Body)
+			continue
+		}
+		if got := strings.TrimSpace(rec.Body.String()); tt.wantBody != "" && got != tt.wantBody {
+			t.Errorf("%v %v: want %s\ngot %s", tt.method, tt.path, tt.wantBody, got)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Command tokenizer-server serves a tokenizer over gRPC (see
+// `tokenizerpb/tokenizer.proto` for the API) and optionally over HTTP/JSON
+// (see `newHTTPHandler`), so that services written in any language can use
+// it.
+//
+// Usage:
+//
+//	tokenizer-server -tokenizer path/to/tokenizer.json [-addr :50051] [-http :8080]
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc"
+
+	"github.com/sugarme/tokenizer/cmd/tokenizer-server/tokenizerpb"
+	"github.com/sugarme/tokenizer/pretrained"
+)
+
+func main() {
+	var (
+		addr           = flag.String("addr", ":50051", "address of the gRPC server, empty to disable it")
+		httpAddr       = flag.String("http", "", "address of the HTTP/JSON server, empty to disable it")
+		maxBodySize    = flag.Int64("max-body-size", 1<<20, "maximum size in bytes of an HTTP request body")
+		maxConcurrency = flag.Int("max-concurrency", 0, "maximum number of HTTP requests processed at the same time, 0 for no limit")
+		file           = flag.String("tokenizer", "", "tokenizer.json file of the tokenizer to serve")
+	)
+	flag.Parse()
+
+	if *file == "" {
+		log.Fatal("Missing -tokenizer file")
+	}
+	if *addr == "" && *httpAddr == "" {
+		log.Fatal("Both -addr and -http are disabled")
+	}
+
+	tk, err := pretrained.FromFile(*file)
+	if err != nil {
+		log.Fatalf("Loading tokenizer %q failed: %v", *file, err)
+	}
+
+	errc := make(chan error, 2)
+	if *addr != "" {
+		lis, err := net.Listen("tcp", *addr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		s := grpc.NewServer()
+		tokenizerpb.RegisterTokenizerServer(s, newServer(tk))
+
+		log.Printf("Serving %q over gRPC on %v\n", *file, lis.Addr())
+		go func() { errc <- s.Serve(lis) }()
+	}
+	if *httpAddr != "" {
+		handler := newHTTPHandler(tk, httpOptions{
+			MaxBodySize:    *maxBodySize,
+			MaxConcurrency: *maxConcurrency,
+		})
+
+		log.Printf("Serving %q over HTTP on %v\n", *file, *httpAddr)
+		go func() { errc <- http.ListenAndServe(*httpAddr, handler) }()
+	}
+
+	log.Fatal(<-errc)
+}
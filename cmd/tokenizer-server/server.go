@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/cmd/tokenizer-server/tokenizerpb"
+)
+
+// server implements the gRPC `Tokenizer` service with a loaded tokenizer.
+type server struct {
+	tokenizerpb.UnimplementedTokenizerServer
+
+	tk *tokenizer.Tokenizer
+}
+
+func newServer(tk *tokenizer.Tokenizer) *server {
+	return &server{tk: tk}
+}
+
+func (s *server) Encode(ctx context.Context, req *tokenizerpb.EncodeRequest) (*tokenizerpb.EncodeResponse, error) {
+	if req.GetInput() == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing input")
+	}
+
+	en, err := s.tk.Encode(encodeInput(req.GetInput()), req.GetAddSpecialTokens())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &tokenizerpb.EncodeResponse{Encoding: toProto(en)}, nil
+}
+
+func (s *server) EncodeBatch(ctx context.Context, req *tokenizerpb.EncodeBatchRequest) (*tokenizerpb.EncodeBatchResponse, error) {
+	inputs := make([]tokenizer.EncodeInput, len(req.GetInputs()))
+	for i, input := range req.GetInputs() {
+		if input == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "missing input %v", i)
+		}
+		inputs[i] = encodeInput(input)
+	}
+
+	encodings, err := s.tk.EncodeBatch(inputs, req.GetAddSpecialTokens())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	resp := &tokenizerpb.EncodeBatchResponse{
+		Encodings: make([]*tokenizerpb.Encoding, len(encodings)),
+	}
+	for i := range encodings {
+		resp.Encodings[i] = toProto(&encodings[i])
+	}
+
+	return resp, nil
+}
+
+func (s *server) Decode(ctx context.Context, req *tokenizerpb.DecodeRequest) (*tokenizerpb.DecodeResponse, error) {
+	ids := make([]int, len(req.GetIds()))
+	for i, id := range req.GetIds() {
+		ids[i] = int(id)
+	}
+
+	return &tokenizerpb.DecodeResponse{Text: s.tk.Decode(ids, req.GetSkipSpecialTokens())}, nil
+}
+
+func (s *server) GetVocab(ctx context.Context, req *tokenizerpb.GetVocabRequest) (*tokenizerpb.GetVocabResponse, error) {
+	vocab := s.tk.GetVocab(req.GetWithAddedTokens())
+	resp := &tokenizerpb.GetVocabResponse{
+		Vocab: make(map[string]int32, len(vocab)),
+	}
+	for token, id := range vocab {
+		resp.Vocab[token] = int32(id)
+	}
+
+	return resp, nil
+}
+
+func encodeInput(input *tokenizerpb.Input) tokenizer.EncodeInput {
+	seq := tokenizer.NewInputSequence(input.GetSequence())
+	if input.Pair == nil {
+		return tokenizer.NewSingleEncodeInput(seq)
+	}
+
+	return tokenizer.NewDualEncodeInput(seq, tokenizer.NewInputSequence(input.GetPair()))
+}
+
+func toProto(en *tokenizer.Encoding) *tokenizerpb.Encoding {
+	out := &tokenizerpb.Encoding{
+		Ids:               toInt32(en.Ids),
+		TypeIds:           toInt32(en.TypeIds),
+		Tokens:            en.Tokens,
+		Offsets:           make([]*tokenizerpb.Offsets, len(en.Offsets)),
+		SpecialTokensMask: toInt32(en.SpecialTokenMask),
+		AttentionMask:     toInt32(en.AttentionMask),
+		WordIds:           toInt32(wordIds(en)),
+	}
+	for i, o := range en.Offsets {
+		out.Offsets[i] = &tokenizerpb.Offsets{Start: int32(o[0]), End: int32(o[1])}
+	}
+	for i := range en.Overflowing {
+		out.Overflowing = append(out.Overflowing, toProto(&en.Overflowing[i]))
+	}
+
+	return out
+}
+
+// wordIds returns the word index of each token of en, -1 if none.
+func wordIds(en *tokenizer.Encoding) []int {
+	ids := make([]int, len(en.Ids))
+	for i := range ids {
+		ids[i] = -1
+		if i < len(en.Words) {
+			ids[i] = en.Words[i]
+		}
+	}
+
+	return ids
+}
+
+func toInt32(values []int) []int32 {
+	out := make([]int32, len(values))
+	for i, v := range values {
+		out[i] = int32(v)
+	}
+
+	return out
+}
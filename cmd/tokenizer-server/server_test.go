@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/cmd/tokenizer-server/tokenizerpb"
+	"github.com/sugarme/tokenizer/model/wordlevel"
+	"github.com/sugarme/tokenizer/pretokenizer"
+)
+
+func newTokenizer(t *testing.T) *tokenizer.Tokenizer {
+	vocab := map[string]int{"[UNK]": 0, "hello": 1, "world": 2, "you": 3}
+	model, err := wordlevel.New(vocab, "[UNK]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := tokenizer.NewTokenizer(model)
+	tk.WithPreTokenizer(pretokenizer.NewWhitespaceSplit())
+
+	return tk
+}
+
+func newClient(t *testing.T) tokenizerpb.TokenizerClient {
+	tk := newTokenizer(t)
+
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	tokenizerpb.RegisterTokenizerServer(s, newServer(tk))
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return tokenizerpb.NewTokenizerClient(conn)
+}
+
+func TestServer(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+
+	resp, err := client.Encode(ctx, &tokenizerpb.EncodeRequest{
+		Input: &tokenizerpb.Input{Sequence: "hello world", Pair: proto.String("you there")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &tokenizerpb.Encoding{
+		Ids:               []int32{1, 2, 3, 0},
+		TypeIds:           []int32{0, 0, 1, 1},
+		Tokens:            []string{"hello", "world", "you", "[UNK]"},
+		Offsets:           []*tokenizerpb.Offsets{{Start: 0, End: 5}, {Start: 6, End: 11}, {Start: 0, End: 3}, {Start: 4, End: 9}},
+		SpecialTokensMask: []int32{0, 0, 0, 0},
+		AttentionMask:     []int32{1, 1, 1, 1},
+		WordIds:           []int32{0, 1, 0, 1},
+	}
+	if !proto.Equal(want, resp.GetEncoding()) {
+		t.Errorf("Encode: want %v\ngot %v", want, resp.GetEncoding())
+	}
+
+	batch, err := client.EncodeBatch(ctx, &tokenizerpb.EncodeBatchRequest{
+		Inputs: []*tokenizerpb.Input{{Sequence: "hello"}, {Sequence: "world you"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotIds [][]int32
+	for _, en := range batch.GetEncodings() {
+		gotIds = append(gotIds, en.GetIds())
+	}
+	if wantIds := [][]int32{{1}, {2, 3}}; !reflect.DeepEqual(wantIds, gotIds) {
+		t.Errorf("EncodeBatch: want %v, got %v", wantIds, gotIds)
+	}
+
+	decoded, err := client.Decode(ctx, &tokenizerpb.DecodeRequest{Ids: []int32{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.GetText() != "hello world" {
+		t.Errorf("Decode: want %q, got %q", "hello world", decoded.GetText())
+	}
+
+	vocab, err := client.GetVocab(ctx, &tokenizerpb.GetVocabRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vocab.GetVocab()) != 4 || vocab.GetVocab()["world"] != 2 {
+		t.Errorf("GetVocab: got %v", vocab.GetVocab())
+	}
+
+	if _, err := client.Encode(ctx, &tokenizerpb.EncodeRequest{}); err == nil {
+		t.Errorf("Encode: want error for a missing input")
+	}
+}
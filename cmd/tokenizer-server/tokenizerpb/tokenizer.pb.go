@@ -0,0 +1,727 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: tokenizer.proto
+
+package tokenizerpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Input struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sequence      string                 `protobuf:"bytes,1,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Pair          *string                `protobuf:"bytes,2,opt,name=pair,proto3,oneof" json:"pair,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Input) Reset() {
+	*x = Input{}
+	mi := &file_tokenizer_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Input) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Input) ProtoMessage() {}
+
+func (x *Input) ProtoReflect() protoreflect.Message {
+	mi := &file_tokenizer_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Input.ProtoReflect.Descriptor instead.
+func (*Input) Descriptor() ([]byte, []int) {
+	return file_tokenizer_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Input) GetSequence() string {
+	if x != nil {
+		return x.Sequence
+	}
+	return ""
+}
+
+func (x *Input) GetPair() string {
+	if x != nil && x.Pair != nil {
+		return *x.Pair
+	}
+	return ""
+}
+
+type EncodeRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Input            *Input                 `protobuf:"bytes,1,opt,name=input,proto3" json:"input,omitempty"`
+	AddSpecialTokens bool                   `protobuf:"varint,2,opt,name=add_special_tokens,json=addSpecialTokens,proto3" json:"add_special_tokens,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *EncodeRequest) Reset() {
+	*x = EncodeRequest{}
+	mi := &file_tokenizer_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EncodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncodeRequest) ProtoMessage() {}
+
+func (x *EncodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tokenizer_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncodeRequest.ProtoReflect.Descriptor instead.
+func (*EncodeRequest) Descriptor() ([]byte, []int) {
+	return file_tokenizer_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *EncodeRequest) GetInput() *Input {
+	if x != nil {
+		return x.Input
+	}
+	return nil
+}
+
+func (x *EncodeRequest) GetAddSpecialTokens() bool {
+	if x != nil {
+		return x.AddSpecialTokens
+	}
+	return false
+}
+
+type EncodeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Encoding      *Encoding              `protobuf:"bytes,1,opt,name=encoding,proto3" json:"encoding,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EncodeResponse) Reset() {
+	*x = EncodeResponse{}
+	mi := &file_tokenizer_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EncodeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncodeResponse) ProtoMessage() {}
+
+func (x *EncodeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tokenizer_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncodeResponse.ProtoReflect.Descriptor instead.
+func (*EncodeResponse) Descriptor() ([]byte, []int) {
+	return file_tokenizer_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *EncodeResponse) GetEncoding() *Encoding {
+	if x != nil {
+		return x.Encoding
+	}
+	return nil
+}
+
+type EncodeBatchRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Inputs           []*Input               `protobuf:"bytes,1,rep,name=inputs,proto3" json:"inputs,omitempty"`
+	AddSpecialTokens bool                   `protobuf:"varint,2,opt,name=add_special_tokens,json=addSpecialTokens,proto3" json:"add_special_tokens,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *EncodeBatchRequest) Reset() {
+	*x = EncodeBatchRequest{}
+	mi := &file_tokenizer_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EncodeBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncodeBatchRequest) ProtoMessage() {}
+
+func (x *EncodeBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tokenizer_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncodeBatchRequest.ProtoReflect.Descriptor instead.
+func (*EncodeBatchRequest) Descriptor() ([]byte, []int) {
+	return file_tokenizer_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *EncodeBatchRequest) GetInputs() []*Input {
+	if x != nil {
+		return x.Inputs
+	}
+	return nil
+}
+
+func (x *EncodeBatchRequest) GetAddSpecialTokens() bool {
+	if x != nil {
+		return x.AddSpecialTokens
+	}
+	return false
+}
+
+type EncodeBatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Encodings     []*Encoding            `protobuf:"bytes,1,rep,name=encodings,proto3" json:"encodings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EncodeBatchResponse) Reset() {
+	*x = EncodeBatchResponse{}
+	mi := &file_tokenizer_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EncodeBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncodeBatchResponse) ProtoMessage() {}
+
+func (x *EncodeBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tokenizer_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncodeBatchResponse.ProtoReflect.Descriptor instead.
+func (*EncodeBatchResponse) Descriptor() ([]byte, []int) {
+	return file_tokenizer_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *EncodeBatchResponse) GetEncodings() []*Encoding {
+	if x != nil {
+		return x.Encodings
+	}
+	return nil
+}
+
+type DecodeRequest struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Ids               []int32                `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	SkipSpecialTokens bool                   `protobuf:"varint,2,opt,name=skip_special_tokens,json=skipSpecialTokens,proto3" json:"skip_special_tokens,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *DecodeRequest) Reset() {
+	*x = DecodeRequest{}
+	mi := &file_tokenizer_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DecodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecodeRequest) ProtoMessage() {}
+
+func (x *DecodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tokenizer_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecodeRequest.ProtoReflect.Descriptor instead.
+func (*DecodeRequest) Descriptor() ([]byte, []int) {
+	return file_tokenizer_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DecodeRequest) GetIds() []int32 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *DecodeRequest) GetSkipSpecialTokens() bool {
+	if x != nil {
+		return x.SkipSpecialTokens
+	}
+	return false
+}
+
+type DecodeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DecodeResponse) Reset() {
+	*x = DecodeResponse{}
+	mi := &file_tokenizer_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DecodeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecodeResponse) ProtoMessage() {}
+
+func (x *DecodeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tokenizer_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecodeResponse.ProtoReflect.Descriptor instead.
+func (*DecodeResponse) Descriptor() ([]byte, []int) {
+	return file_tokenizer_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DecodeResponse) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type GetVocabRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	WithAddedTokens bool                   `protobuf:"varint,1,opt,name=with_added_tokens,json=withAddedTokens,proto3" json:"with_added_tokens,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *GetVocabRequest) Reset() {
+	*x = GetVocabRequest{}
+	mi := &file_tokenizer_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVocabRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVocabRequest) ProtoMessage() {}
+
+func (x *GetVocabRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tokenizer_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVocabRequest.ProtoReflect.Descriptor instead.
+func (*GetVocabRequest) Descriptor() ([]byte, []int) {
+	return file_tokenizer_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetVocabRequest) GetWithAddedTokens() bool {
+	if x != nil {
+		return x.WithAddedTokens
+	}
+	return false
+}
+
+type GetVocabResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Vocab         map[string]int32       `protobuf:"bytes,1,rep,name=vocab,proto3" json:"vocab,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetVocabResponse) Reset() {
+	*x = GetVocabResponse{}
+	mi := &file_tokenizer_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVocabResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVocabResponse) ProtoMessage() {}
+
+func (x *GetVocabResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tokenizer_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVocabResponse.ProtoReflect.Descriptor instead.
+func (*GetVocabResponse) Descriptor() ([]byte, []int) {
+	return file_tokenizer_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetVocabResponse) GetVocab() map[string]int32 {
+	if x != nil {
+		return x.Vocab
+	}
+	return nil
+}
+
+type Offsets struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         int32                  `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           int32                  `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Offsets) Reset() {
+	*x = Offsets{}
+	mi := &file_tokenizer_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Offsets) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Offsets) ProtoMessage() {}
+
+func (x *Offsets) ProtoReflect() protoreflect.Message {
+	mi := &file_tokenizer_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Offsets.ProtoReflect.Descriptor instead.
+func (*Offsets) Descriptor() ([]byte, []int) {
+	return file_tokenizer_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Offsets) GetStart() int32 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *Offsets) GetEnd() int32 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+type Encoding struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Ids               []int32                `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	TypeIds           []int32                `protobuf:"varint,2,rep,packed,name=type_ids,json=typeIds,proto3" json:"type_ids,omitempty"`
+	Tokens            []string               `protobuf:"bytes,3,rep,name=tokens,proto3" json:"tokens,omitempty"`
+	Offsets           []*Offsets             `protobuf:"bytes,4,rep,name=offsets,proto3" json:"offsets,omitempty"`
+	SpecialTokensMask []int32                `protobuf:"varint,5,rep,packed,name=special_tokens_mask,json=specialTokensMask,proto3" json:"special_tokens_mask,omitempty"`
+	AttentionMask     []int32                `protobuf:"varint,6,rep,packed,name=attention_mask,json=attentionMask,proto3" json:"attention_mask,omitempty"`
+	WordIds           []int32                `protobuf:"varint,7,rep,packed,name=word_ids,json=wordIds,proto3" json:"word_ids,omitempty"`
+	Overflowing       []*Encoding            `protobuf:"bytes,8,rep,name=overflowing,proto3" json:"overflowing,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Encoding) Reset() {
+	*x = Encoding{}
+	mi := &file_tokenizer_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Encoding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Encoding) ProtoMessage() {}
+
+func (x *Encoding) ProtoReflect() protoreflect.Message {
+	mi := &file_tokenizer_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Encoding.ProtoReflect.Descriptor instead.
+func (*Encoding) Descriptor() ([]byte, []int) {
+	return file_tokenizer_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Encoding) GetIds() []int32 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *Encoding) GetTypeIds() []int32 {
+	if x != nil {
+		return x.TypeIds
+	}
+	return nil
+}
+
+func (x *Encoding) GetTokens() []string {
+	if x != nil {
+		return x.Tokens
+	}
+	return nil
+}
+
+func (x *Encoding) GetOffsets() []*Offsets {
+	if x != nil {
+		return x.Offsets
+	}
+	return nil
+}
+
+func (x *Encoding) GetSpecialTokensMask() []int32 {
+	if x != nil {
+		return x.SpecialTokensMask
+	}
+	return nil
+}
+
+func (x *Encoding) GetAttentionMask() []int32 {
+	if x != nil {
+		return x.AttentionMask
+	}
+	return nil
+}
+
+func (x *Encoding) GetWordIds() []int32 {
+	if x != nil {
+		return x.WordIds
+	}
+	return nil
+}
+
+func (x *Encoding) GetOverflowing() []*Encoding {
+	if x != nil {
+		return x.Overflowing
+	}
+	return nil
+}
+
+var File_tokenizer_proto protoreflect.FileDescriptor
+
+const file_tokenizer_proto_rawDesc = "" +
+	"\n" +
+	"\x0ftokenizer.proto\x12\ftokenizer.v1\"E\n" +
+	"\x05Input\x12\x1a\n" +
+	"\bsequence\x18\x01 \x01(\tR\bsequence\x12\x17\n" +
+	"\x04pair\x18\x02 \x01(\tH\x00R\x04pair\x88\x01\x01B\a\n" +
+	"\x05_pair\"h\n" +
+	"\rEncodeRequest\x12)\n" +
+	"\x05input\x18\x01 \x01(\v2\x13.tokenizer.v1.InputR\x05input\x12,\n" +
+	"\x12add_special_tokens\x18\x02 \x01(\bR\x10addSpecialTokens\"D\n" +
+	"\x0eEncodeResponse\x122\n" +
+	"\bencoding\x18\x01 \x01(\v2\x16.tokenizer.v1.EncodingR\bencoding\"o\n" +
+	"\x12EncodeBatchRequest\x12+\n" +
+	"\x06inputs\x18\x01 \x03(\v2\x13.tokenizer.v1.InputR\x06inputs\x12,\n" +
+	"\x12add_special_tokens\x18\x02 \x01(\bR\x10addSpecialTokens\"K\n" +
+	"\x13EncodeBatchResponse\x124\n" +
+	"\tencodings\x18\x01 \x03(\v2\x16.tokenizer.v1.EncodingR\tencodings\"Q\n" +
+	"\rDecodeRequest\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\x05R\x03ids\x12.\n" +
+	"\x13skip_special_tokens\x18\x02 \x01(\bR\x11skipSpecialTokens\"$\n" +
+	"\x0eDecodeResponse\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\"=\n" +
+	"\x0fGetVocabRequest\x12*\n" +
+	"\x11with_added_tokens\x18\x01 \x01(\bR\x0fwithAddedTokens\"\x8d\x01\n" +
+	"\x10GetVocabResponse\x12?\n" +
+	"\x05vocab\x18\x01 \x03(\v2).tokenizer.v1.GetVocabResponse.VocabEntryR\x05vocab\x1a8\n" +
+	"\n" +
+	"VocabEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"1\n" +
+	"\aOffsets\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\x05R\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\x05R\x03end\"\xac\x02\n" +
+	"\bEncoding\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\x05R\x03ids\x12\x19\n" +
+	"\btype_ids\x18\x02 \x03(\x05R\atypeIds\x12\x16\n" +
+	"\x06tokens\x18\x03 \x03(\tR\x06tokens\x12/\n" +
+	"\aoffsets\x18\x04 \x03(\v2\x15.tokenizer.v1.OffsetsR\aoffsets\x12.\n" +
+	"\x13special_tokens_mask\x18\x05 \x03(\x05R\x11specialTokensMask\x12%\n" +
+	"\x0eattention_mask\x18\x06 \x03(\x05R\rattentionMask\x12\x19\n" +
+	"\bword_ids\x18\a \x03(\x05R\awordIds\x128\n" +
+	"\voverflowing\x18\b \x03(\v2\x16.tokenizer.v1.EncodingR\voverflowing2\xb4\x02\n" +
+	"\tTokenizer\x12C\n" +
+	"\x06Encode\x12\x1b.tokenizer.v1.EncodeRequest\x1a\x1c.tokenizer.v1.EncodeResponse\x12R\n" +
+	"\vEncodeBatch\x12 .tokenizer.v1.EncodeBatchRequest\x1a!.tokenizer.v1.EncodeBatchResponse\x12C\n" +
+	"\x06Decode\x12\x1b.tokenizer.v1.DecodeRequest\x1a\x1c.tokenizer.v1.DecodeResponse\x12I\n" +
+	"\bGetVocab\x12\x1d.tokenizer.v1.GetVocabRequest\x1a\x1e.tokenizer.v1.GetVocabResponseB?Z=github.com/sugarme/tokenizer/cmd/tokenizer-server/tokenizerpbb\x06proto3"
+
+var (
+	file_tokenizer_proto_rawDescOnce sync.Once
+	file_tokenizer_proto_rawDescData []byte
+)
+
+func file_tokenizer_proto_rawDescGZIP() []byte {
+	file_tokenizer_proto_rawDescOnce.Do(func() {
+		file_tokenizer_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_tokenizer_proto_rawDesc), len(file_tokenizer_proto_rawDesc)))
+	})
+	return file_tokenizer_proto_rawDescData
+}
+
+var file_tokenizer_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_tokenizer_proto_goTypes = []any{
+	(*Input)(nil),               // 0: tokenizer.v1.Input
+	(*EncodeRequest)(nil),       // 1: tokenizer.v1.EncodeRequest
+	(*EncodeResponse)(nil),      // 2: tokenizer.v1.EncodeResponse
+	(*EncodeBatchRequest)(nil),  // 3: tokenizer.v1.EncodeBatchRequest
+	(*EncodeBatchResponse)(nil), // 4: tokenizer.v1.EncodeBatchResponse
+	(*DecodeRequest)(nil),       // 5: tokenizer.v1.DecodeRequest
+	(*DecodeResponse)(nil),      // 6: tokenizer.v1.DecodeResponse
+	(*GetVocabRequest)(nil),     // 7: tokenizer.v1.GetVocabRequest
+	(*GetVocabResponse)(nil),    // 8: tokenizer.v1.GetVocabResponse
+	(*Offsets)(nil),             // 9: tokenizer.v1.Offsets
+	(*Encoding)(nil),            // 10: tokenizer.v1.Encoding
+	nil,                         // 11: tokenizer.v1.GetVocabResponse.VocabEntry
+}
+var file_tokenizer_proto_depIdxs = []int32{
+	0,  // 0: tokenizer.v1.EncodeRequest.input:type_name -> tokenizer.v1.Input
+	10, // 1: tokenizer.v1.EncodeResponse.encoding:type_name -> tokenizer.v1.Encoding
+	0,  // 2: tokenizer.v1.EncodeBatchRequest.inputs:type_name -> tokenizer.v1.Input
+	10, // 3: tokenizer.v1.EncodeBatchResponse.encodings:type_name -> tokenizer.v1.Encoding
+	11, // 4: tokenizer.v1.GetVocabResponse.vocab:type_name -> tokenizer.v1.GetVocabResponse.VocabEntry
+	9,  // 5: tokenizer.v1.Encoding.offsets:type_name -> tokenizer.v1.Offsets
+	10, // 6: tokenizer.v1.Encoding.overflowing:type_name -> tokenizer.v1.Encoding
+	1,  // 7: tokenizer.v1.Tokenizer.Encode:input_type -> tokenizer.v1.EncodeRequest
+	3,  // 8: tokenizer.v1.Tokenizer.EncodeBatch:input_type -> tokenizer.v1.EncodeBatchRequest
+	5,  // 9: tokenizer.v1.Tokenizer.Decode:input_type -> tokenizer.v1.DecodeRequest
+	7,  // 10: tokenizer.v1.Tokenizer.GetVocab:input_type -> tokenizer.v1.GetVocabRequest
+	2,  // 11: tokenizer.v1.Tokenizer.Encode:output_type -> tokenizer.v1.EncodeResponse
+	4,  // 12: tokenizer.v1.Tokenizer.EncodeBatch:output_type -> tokenizer.v1.EncodeBatchResponse
+	6,  // 13: tokenizer.v1.Tokenizer.Decode:output_type -> tokenizer.v1.DecodeResponse
+	8,  // 14: tokenizer.v1.Tokenizer.GetVocab:output_type -> tokenizer.v1.GetVocabResponse
+	11, // [11:15] is the sub-list for method output_type
+	7,  // [7:11] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_tokenizer_proto_init() }
+func file_tokenizer_proto_init() {
+	if File_tokenizer_proto != nil {
+		return
+	}
+	file_tokenizer_proto_msgTypes[0].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_tokenizer_proto_rawDesc), len(file_tokenizer_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_tokenizer_proto_goTypes,
+		DependencyIndexes: file_tokenizer_proto_depIdxs,
+		MessageInfos:      file_tokenizer_proto_msgTypes,
+	}.Build()
+	File_tokenizer_proto = out.File
+	file_tokenizer_proto_goTypes = nil
+	file_tokenizer_proto_depIdxs = nil
+}
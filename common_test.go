@@ -0,0 +1,37 @@
+package tokenizer_test
+
+// This file provides some helpers for unit testing only.
+
+import (
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/model/wordlevel"
+	"github.com/sugarme/tokenizer/pretokenizer"
+)
+
+// newWordLevelTokenizer creates a small offline tokenizer with a WordLevel model
+// and a WhitespaceSplit pre-tokenizer.
+func newWordLevelTokenizer() *tokenizer.Tokenizer {
+	vocab := map[string]int{
+		"[PAD]": 0,
+		"[UNK]": 1,
+		"[CLS]": 2,
+		"[SEP]": 3,
+		"hello": 4,
+		"world": 5,
+		"how":   6,
+		"are":   7,
+		"you":   8,
+		"good":  9,
+		"day":   10,
+	}
+
+	model, err := wordlevel.New(vocab, "[UNK]")
+	if err != nil {
+		panic(err)
+	}
+
+	tk := tokenizer.NewTokenizer(model)
+	tk.WithPreTokenizer(pretokenizer.NewWhitespaceSplit())
+
+	return tk
+}
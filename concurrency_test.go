@@ -0,0 +1,210 @@
+package tokenizer_test
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/model"
+	"github.com/sugarme/tokenizer/model/bpe"
+	"github.com/sugarme/tokenizer/model/unigram"
+	"github.com/sugarme/tokenizer/model/wordpiece"
+	"github.com/sugarme/tokenizer/normalizer"
+	"github.com/sugarme/tokenizer/pretokenizer"
+	"github.com/sugarme/tokenizer/processor"
+	"github.com/sugarme/tokenizer/util"
+)
+
+// These tests are meant to be run with the race detector:
+//
+//	go test -race -run Concurrent
+
+func newBPETokenizer(t *testing.T) *tokenizer.Tokenizer {
+	vocab := model.Vocab{"[UNK]": 0, "[CLS]": 1, "[SEP]": 2}
+	for _, tok := range []string{"h", "e", "l", "o", "w", "r", "d", "he", "ll", "hell", "hello", "or", "wor", "worl", "world"} {
+		vocab[tok] = len(vocab)
+	}
+	unk := "[UNK]"
+	m, err := bpe.New(vocab, []string{"h e", "l l", "he ll", "hell o", "o r", "w or", "wor l", "worl d"}, nil, &unk, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return newConcurrentTokenizer(m)
+}
+
+func newWordPieceTokenizer(t *testing.T) *tokenizer.Tokenizer {
+	vocab := model.Vocab{"[UNK]": 0, "[CLS]": 1, "[SEP]": 2}
+	for _, tok := range []string{"hello", "world", "wor", "##ld", "##l", "##d", "h", "##e", "##llo"} {
+		vocab[tok] = len(vocab)
+	}
+	m, err := wordpiece.New(vocab, util.NewParams(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return newConcurrentTokenizer(m)
+}
+
+func newUnigramTokenizer(t *testing.T) *tokenizer.Tokenizer {
+	var vocab []unigram.TokenScore
+	for i, tok := range []string{"[UNK]", "[CLS]", "[SEP]", "h", "e", "l", "o", "w", "r", "d", "hello", "world", "wor", "ld"} {
+		vocab = append(vocab, unigram.TokenScore{Token: tok, Score: -float64(i)})
+	}
+	unkId := 0
+	m, err := unigram.New(vocab, &unkId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return newConcurrentTokenizer(m)
+}
+
+// newConcurrentTokenizer sets up the whole pipeline, added tokens,
+// truncation and padding included, around model.
+func newConcurrentTokenizer(m tokenizer.Model) *tokenizer.Tokenizer {
+	tk := tokenizer.NewTokenizer(m)
+	tk.WithNormalizer(normalizer.NewBertNormalizer(true, true, true, true))
+	tk.WithPreTokenizer(pretokenizer.NewBertPreTokenizer())
+	tk.WithPostProcessor(processor.NewBertProcessing(processor.PostToken{Value: "[SEP]", Id: 2}, processor.PostToken{Value: "[CLS]", Id: 1}))
+	tk.AddSpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[CLS]", true), tokenizer.NewAddedToken("[SEP]", true)})
+	tk.AddTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("<new>", false)})
+	tk.WithTruncation(&tokenizer.TruncationParams{MaxLength: 8, Strategy: tokenizer.LongestFirst, Stride: 1})
+	tk.WithPadding(tokenizer.DefaultPaddingParams())
+
+	return tk
+}
+
+func TestTokenizer_ConcurrentEncode(t *testing.T) {
+	inputs := []string{
+		"Hello world",
+		"hello <new> world hello",
+		"World, hello! héllo wörld hello world hello world",
+		"unknown words hello",
+		"",
+	}
+
+	for name, newFn := range map[string]func(*testing.T) *tokenizer.Tokenizer{
+		"BPE":       newBPETokenizer,
+		"WordPiece": newWordPieceTokenizer,
+		"Unigram":   newUnigramTokenizer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			tk := newFn(t)
+
+			want := make([]*tokenizer.Encoding, len(inputs))
+			wantDecoded := make([]string, len(inputs))
+			for i, input := range inputs {
+				en, err := tk.EncodePair(input, "hello world", true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want[i] = en
+				wantDecoded[i] = tk.Decode(en.Ids, true)
+			}
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 16*len(inputs))
+			for g := 0; g < 16; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for j := range inputs {
+						i := (g + j) % len(inputs)
+						en, err := tk.EncodePair(inputs[i], "hello world", true)
+						if err != nil {
+							errs <- err
+							continue
+						}
+						if !en.Equal(want[i]) {
+							errs <- fmt.Errorf("%q: want %v, got %v", inputs[i], want[i].Tokens, en.Tokens)
+						}
+						if got := tk.Decode(en.Ids, true); got != wantDecoded[i] {
+							errs <- fmt.Errorf("%q: want decoded %q, got %q", inputs[i], wantDecoded[i], got)
+						}
+					}
+
+					if _, err := tk.EncodeBatch([]tokenizer.EncodeInput{
+						tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence(inputs[g%len(inputs)])),
+					}, true); err != nil {
+						errs <- err
+					}
+				}(g)
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestTokenizer_ConcurrentAddTokens(t *testing.T) {
+	tk := newBPETokenizer(t)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(2)
+		go func(g int) {
+			defer wg.Done()
+			tk.AddTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken(fmt.Sprintf("<tok%d>", g), false)})
+			tk.AddSpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken(fmt.Sprintf("<special%d>", g), true)})
+		}(g)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				input := fmt.Sprintf("hello <tok%d> world <special%d>", g, g)
+				en, err := tk.EncodeSingle(input, true)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				tk.Decode(en.Ids, true)
+				tk.GetVocabSize(true)
+				tk.GetSpecialTokens()
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if got, want := tk.GetVocabSize(true), tk.GetVocabSize(false)+17; got != want { // "<new>" included
+		t.Errorf("want vocab size %d, got %d", want, got)
+	}
+	en, err := tk.EncodeSingle("hello <tok3> world <special5>", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"hello", "<tok3>", "world", "<special5>"}; !reflect.DeepEqual(want, en.Tokens) {
+		t.Errorf("want %q, got %q", want, en.Tokens)
+	}
+}
+
+func TestEncoding_ConcurrentClone(t *testing.T) {
+	en := tokenizer.NewEncodingFromTokens([]tokenizer.Token{
+		tokenizer.NewToken(1, "Hello", []int{0, 5}),
+	}, 0)
+	en.SetMeta("doc", 42)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				if v, ok := en.Clone().GetMeta("doc"); !ok || v != 42 {
+					t.Errorf("want metadata 42, got %v", v)
+					return
+				}
+				if v, ok := en.GetMeta("doc"); !ok || v != 42 {
+					t.Errorf("want source metadata 42, got %v", v)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,15 @@
+package decoder
+
+import (
+	"testing"
+)
+
+func TestBpeDecoder_Decode(t *testing.T) {
+	dec := DefaultBpeDecoder()
+
+	got := dec.Decode([]string{"My</w>", "na", "me</w>", "is</w>", "John</w>"})
+	want := "My name is John"
+	if want != got {
+		t.Errorf("want %q got %q", want, got)
+	}
+}
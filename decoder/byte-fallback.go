@@ -40,10 +40,11 @@ func (d *ByteFallback) DecodeChain(tokens []string) []string {
 		var err error
 
 		if len(token) == 6 && strings.HasPrefix(token, "<0x") && strings.HasSuffix(token, ">") {
-			// convert hex string to bytes
+			// convert hex string to bytes, a token which is not a valid byte
+			// (eg. `<0xZZ>`) is kept as is.
 			bytes, err = hex.DecodeString(token[3:5])
 			if err != nil {
-				panic(err)
+				bytes = nil
 			}
 		}
 
@@ -75,4 +75,15 @@ func TestByteFallbackDecodeChain(t *testing.T) {
 	if !reflect.DeepEqual(got6, want6) {
 		t.Errorf("Want %x - Got %x\n", want6, got6)
 	}
+
+	tokens = []string{
+		"<0x61>",
+		"<0xZZ>",
+	}
+
+	got7 := dec.DecodeChain(tokens)
+	want7 := []string{"a", "<0xZZ>"}
+	if !reflect.DeepEqual(got7, want7) {
+		t.Errorf("Want %v - Got %v\n", want7, got7)
+	}
 }
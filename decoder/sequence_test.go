@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/normalizer"
 	"github.com/sugarme/tokenizer/pretokenizer"
 )
 
@@ -44,3 +45,26 @@ fn sequence_basic() {
         assert_eq!(out_tokens, "Hi you");
     }
 */
+
+func TestSequence_Llama(t *testing.T) {
+	replace := normalizer.NewReplace(normalizer.String, "▁", " ")
+
+	dec := NewSequence([]tokenizer.Decoder{
+		replace,
+		NewByteFallback(),
+		NewFuse(),
+		NewStrip(" ", 1, 0),
+	})
+
+	tokens := []string{"▁Hey", "▁", "<0xE5>", "<0x8F>", "<0xAB>", "▁friend", "!"}
+	got := dec.Decode(tokens)
+	want := "Hey 叫 friend!"
+
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	if got := dec.Decode(nil); got != "" {
+		t.Errorf("want empty string, got %q", got)
+	}
+}
@@ -0,0 +1,64 @@
+package decoder
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/util"
+)
+
+// This file implements json.Marshaler for decoders so that they are
+// serialized in HuggingFace `tokenizer.json` format.
+
+func (bd *BpeDecoder) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("BPEDecoder", map[string]interface{}{
+		"suffix": bd.suffix,
+	})
+}
+
+func (d *ByteFallback) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("ByteFallback", nil)
+}
+
+func (c *CTC) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("CTC", map[string]interface{}{
+		"pad_token":            c.PadToken,
+		"word_delimiter_token": c.WordDelimiterToken,
+		"cleanup":              c.Cleanup,
+	})
+}
+
+func (f *Fuse) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("Fuse", nil)
+}
+
+func (s *Sequence) MarshalJSON() ([]byte, error) {
+	decoders := s.decoders
+	if decoders == nil {
+		decoders = []tokenizer.Decoder{}
+	}
+	for _, d := range decoders {
+		if _, ok := d.(json.Marshaler); !ok {
+			return nil, fmt.Errorf("Cannot serialize decoder of type %T", d)
+		}
+	}
+	return util.MarshalTyped("Sequence", map[string]interface{}{
+		"decoders": decoders,
+	})
+}
+
+func (s *Strip) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("Strip", map[string]interface{}{
+		"content": s.Content,
+		"start":   s.Start,
+		"stop":    s.Stop,
+	})
+}
+
+func (wd *WordPieceDecoder) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("WordPiece", map[string]interface{}{
+		"prefix":  wd.prefix,
+		"cleanup": wd.cleanup,
+	})
+}
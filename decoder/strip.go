@@ -36,25 +36,21 @@ func (d *Strip) DecodeChain(tokens []string) []string {
 		chars := strings.Split(token, "")
 
 		startCut := 0
-		for i := 0; i < d.Start; i++ {
-			c := chars[i]
-			if c == d.Content {
-				startCut = i + 1
-				continue
-			} else {
+		for i := 0; i < d.Start && i < len(chars); i++ {
+			if chars[i] != d.Content {
 				break
 			}
+			startCut = i + 1
 		}
 
+		// Chars cut from the start can't be cut again from the end.
 		stopCut := len(chars)
-		for i := 0; i < d.Stop; i++ {
+		for i := 0; i < d.Stop && stopCut > startCut; i++ {
 			index := len(chars) - i - 1
-			if chars[index] == d.Content {
-				stopCut = index
-				continue
-			} else {
+			if chars[index] != d.Content {
 				break
 			}
+			stopCut = index
 		}
 
 		newToken := strings.Join(chars[startCut:stopCut], "")
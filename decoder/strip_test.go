@@ -39,4 +39,15 @@ func TestStrip_DecodeChain(t *testing.T) {
 	if !reflect.DeepEqual(want2, got2) {
 		t.Errorf("Want %v, got %v\n", want2, got2)
 	}
+
+	// Tokens shorter than the cuts
+	dec = NewStrip(" ", 2, 2)
+	tokens = []string{"", " ", "  ", "   ", " a "}
+
+	got3 := dec.DecodeChain(tokens)
+	want3 := []string{"", "", "", "", "a"}
+
+	if !reflect.DeepEqual(want3, got3) {
+		t.Errorf("Want %q, got %q\n", want3, got3)
+	}
 }
@@ -17,7 +17,7 @@ type WordPieceDecoder struct {
 	cleanup bool
 }
 
-// NewBpeDecoder creates a new BpeDecoder
+// NewWordPieceDecoder creates a new WordPieceDecoder
 func NewWordPieceDecoder(prefix string, cleanup bool) *WordPieceDecoder {
 	base := new(DecoderBase)
 	d := &WordPieceDecoder{
@@ -31,12 +31,10 @@ func NewWordPieceDecoder(prefix string, cleanup bool) *WordPieceDecoder {
 	return d
 }
 
-// DefaultBpeDecoder create a new BpeDecoder with default suffix (`</w>`)
+// DefaultWordpieceDecoder create a new WordPieceDecoder with default prefix
+// (`##`) and cleanup enabled.
 func DefaultWordpieceDecoder() *WordPieceDecoder {
-	return &WordPieceDecoder{
-		prefix:  "##",
-		cleanup: true,
-	}
+	return NewWordPieceDecoder("##", true)
 }
 
 /*
@@ -81,7 +79,7 @@ func (wd *WordPieceDecoder) Cleanup(tok string) string {
 func (wd *WordPieceDecoder) DecodeChain(tokens []string) []string {
 	var toks []string
 	for i, token := range tokens {
-		var tok string
+		tok := token
 		if i != 0 {
 			if strings.HasPrefix(token, wd.prefix) {
 				tok = strings.Replace(token, wd.prefix, "", 1)
@@ -0,0 +1,22 @@
+package decoder
+
+import (
+	"testing"
+)
+
+func TestWordPieceDecoder_Decode(t *testing.T) {
+	dec := NewWordPieceDecoder("##", false)
+
+	got := dec.Decode([]string{"##uelo", "Ara", "##új", "##o", "No", "##guera"})
+	want := "##uelo Araújo Noguera"
+	if want != got {
+		t.Errorf("want %q got %q", want, got)
+	}
+
+	dec = DefaultWordpieceDecoder()
+	got = dec.Decode([]string{"hello", "world", "##s", "!"})
+	want = "hello worlds!"
+	if want != got {
+		t.Errorf("want %q got %q", want, got)
+	}
+}
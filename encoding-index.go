@@ -0,0 +1,246 @@
+package tokenizer
+
+import (
+	"sync"
+)
+
+// indexMu guards the lazy building of encoding indexes, so that lookups on
+// a shared Encoding are safe for concurrent use.
+var indexMu sync.RWMutex
+
+// encodingIndex holds the word and char lookup maps of an Encoding.
+//
+// It is built once, on first lookup. Methods changing the encoding words,
+// offsets or sequences drop it with `resetIndex`, so that it is rebuilt on
+// next lookup. The lengths of the indexed fields are also recorded, as a
+// guard against direct changes of the exported fields.
+type encodingIndex struct {
+	nWords    int
+	nOffsets  int
+	nRanges   int
+	sequences []int // sequence id of each token, -1 if none
+
+	words    map[int][2]int    // word => [start, end) tokens
+	seqWords map[[2]int][2]int // (sequence, word) => [start, end) tokens
+	chars    map[int]int       // char => first token containing it
+	seqChars map[[2]int]int    // (sequence, char) => first token containing it
+}
+
+// isValid returns whether the indexed fields of e still have the lengths
+// they had when idx was built.
+func (idx *encodingIndex) isValid(e *Encoding) bool {
+	return idx.nWords == len(e.Words) &&
+		idx.nOffsets == len(e.Offsets) &&
+		idx.nRanges == len(e.SequenceRanges)
+}
+
+// newEncodingIndex builds the lookup maps of e.
+func newEncodingIndex(e *Encoding) *encodingIndex {
+	n := len(e.Words)
+	if len(e.Offsets) > n {
+		n = len(e.Offsets)
+	}
+
+	idx := &encodingIndex{
+		nWords:    len(e.Words),
+		nOffsets:  len(e.Offsets),
+		nRanges:   len(e.SequenceRanges),
+		sequences: make([]int, n),
+		words:     make(map[int][2]int),
+		seqWords:  make(map[[2]int][2]int),
+		chars:     make(map[int]int),
+		seqChars:  make(map[[2]int]int),
+	}
+
+	if len(e.SequenceRanges) > 0 {
+		for i := range idx.sequences {
+			idx.sequences[i] = -1
+		}
+		for seqId, r := range e.SequenceRanges {
+			for _, i := range r {
+				if i < n {
+					idx.sequences[i] = seqId
+				}
+			}
+		}
+	}
+
+	for i, w := range e.Words {
+		if w < 0 {
+			continue
+		}
+		addSpan(idx.words, w, i)
+		addSpan(idx.seqWords, [2]int{idx.sequences[i], w}, i)
+	}
+
+	for i, o := range e.Offsets {
+		if len(o) < 2 {
+			continue
+		}
+		seq := idx.sequences[i]
+		for c := o[0]; c < o[1]; c++ {
+			if _, ok := idx.chars[c]; !ok {
+				idx.chars[c] = i
+			}
+			if _, ok := idx.seqChars[[2]int{seq, c}]; !ok {
+				idx.seqChars[[2]int{seq, c}] = i
+			}
+		}
+	}
+
+	return idx
+}
+
+// addSpan extends the token span of key to token i.
+func addSpan[K comparable](spans map[K][2]int, key K, i int) {
+	if span, ok := spans[key]; ok {
+		spans[key] = [2]int{span[0], i + 1}
+	} else {
+		spans[key] = [2]int{i, i + 1}
+	}
+}
+
+// getIndex returns the lookup maps of e, building them if needed.
+func (e *Encoding) getIndex() *encodingIndex {
+	indexMu.RLock()
+	idx := e.index
+	indexMu.RUnlock()
+	if idx != nil && idx.isValid(e) {
+		return idx
+	}
+
+	indexMu.Lock()
+	defer indexMu.Unlock()
+	if e.index == nil || !e.index.isValid(e) {
+		e.index = newEncodingIndex(e)
+	}
+
+	return e.index
+}
+
+// resetIndex drops the lookup maps of e after an in-place change.
+func (e *Encoding) resetIndex() {
+	indexMu.Lock()
+	e.index = nil
+	indexMu.Unlock()
+}
+
+// WordToTokens gets the encoded tokens corresponding to the word at the given
+// index in the input sequence, in the form `(startToken, endToken)`.
+//
+// The optional `sequenceIdOpt` restricts the search to the tokens of the given
+// sequence, as words of a pair of sequences are indexed separately.
+func (e *Encoding) WordToTokens(word int, sequenceIdOpt ...int) (startTok, endTok int, ok bool) {
+	idx := e.getIndex()
+
+	var span [2]int
+	if len(sequenceIdOpt) == 0 {
+		span, ok = idx.words[word]
+	} else {
+		span, ok = idx.seqWords[[2]int{sequenceIdOpt[0], word}]
+	}
+	if !ok {
+		return -1, -1, false
+	}
+
+	return span[0], span[1], true
+}
+
+// WordToChars gets the offsets of the word at the given index in the input
+// sequence. See `WordToTokens` for `sequenceIdOpt`.
+func (e *Encoding) WordToChars(word int, sequenceIdOpt ...int) (retVal []int, ok bool) {
+	start, end, ok := e.WordToTokens(word, sequenceIdOpt...)
+	if !ok || end > len(e.Offsets) {
+		return retVal, false
+	}
+
+	return []int{e.Offsets[start][0], e.Offsets[end-1][1]}, true
+}
+
+// TokenToWord gets the index of the word containing the token at the given
+// index. Tokens without word (ie. special or padding tokens) are not found.
+func (e *Encoding) TokenToWord(tokenIdx int) (retVal int, ok bool) {
+	if tokenIdx < 0 || tokenIdx >= len(e.Words) || e.Words[tokenIdx] < 0 {
+		return -1, false
+	}
+
+	return e.Words[tokenIdx], true
+}
+
+// CharToToken returns the index of the token containing the char at the given
+// index.
+//
+// The optional `sequenceIdOpt` restricts the search to the tokens of the given
+// sequence, as offsets of a pair of sequences are relative to each input.
+func (e *Encoding) CharToToken(pos int, sequenceIdOpt ...int) (retVal int, ok bool) {
+	idx := e.getIndex()
+
+	if len(sequenceIdOpt) == 0 {
+		retVal, ok = idx.chars[pos]
+	} else {
+		retVal, ok = idx.seqChars[[2]int{sequenceIdOpt[0], pos}]
+	}
+	if !ok {
+		return -1, false
+	}
+
+	return retVal, true
+}
+
+// CharToWord returns the index of the word containing the char at the given
+// index. See `CharToToken` for `sequenceIdOpt`.
+func (e *Encoding) CharToWord(pos int, sequenceIdOpt ...int) (retVal int, ok bool) {
+	if tokenIdx, ok := e.CharToToken(pos, sequenceIdOpt...); ok {
+		return e.TokenToWord(tokenIdx)
+	}
+
+	return -1, false
+}
+
+// CharSpanToTokenSpan converts the span of chars `[start, end)` of the input
+// sequence to the span of tokens `[tokStart, tokEnd)` covering it, eg. to
+// convert SQuAD like character answers to token labels. Tokens partially
+// overlapping the span are included, special and padding tokens are skipped.
+// It returns false if no token overlaps the span.
+//
+// The optional `sequenceIdOpt` restricts the search to the tokens of the given
+// sequence. Otherwise, the tokens are those of the sequence of the first
+// overlapping token, as offsets of a pair of sequences are relative to each
+// input.
+func (e *Encoding) CharSpanToTokenSpan(start, end int, sequenceIdOpt ...int) (tokStart, tokEnd int, ok bool) {
+	if start >= end {
+		return -1, -1, false
+	}
+
+	idx := e.getIndex()
+	seq, hasSeq := 0, len(sequenceIdOpt) > 0
+	if hasSeq {
+		seq = sequenceIdOpt[0]
+	}
+
+	tokStart, tokEnd = -1, -1
+	for i, o := range e.Offsets {
+		if len(o) < 2 || o[0] >= o[1] || o[0] >= end || o[1] <= start {
+			continue
+		}
+		if i < len(e.SpecialTokenMask) && e.SpecialTokenMask[i] == 1 {
+			continue
+		}
+		if !hasSeq {
+			seq, hasSeq = idx.sequences[i], true
+		}
+		if idx.sequences[i] != seq {
+			continue
+		}
+
+		if tokStart < 0 {
+			tokStart = i
+		}
+		tokEnd = i + 1
+	}
+	if tokStart < 0 {
+		return -1, -1, false
+	}
+
+	return tokStart, tokEnd, true
+}
@@ -0,0 +1,112 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// encodingJSON is the JSON form of `Encoding`. Field names follow the
+// HuggingFace conventions (`input_ids`, `token_type_ids`, `offset_mapping`,
+// ...) so that encodings can be dumped to datasets or passed to Python
+// services as is.
+type encodingJSON struct {
+	InputIds          []int                  `json:"input_ids"`
+	TokenTypeIds      []int                  `json:"token_type_ids"`
+	Tokens            []string               `json:"tokens"`
+	OffsetMapping     [][]int                `json:"offset_mapping"`
+	SpecialTokensMask []int                  `json:"special_tokens_mask"`
+	AttentionMask     []int                  `json:"attention_mask"`
+	WordIds           []*int                 `json:"word_ids,omitempty"`
+	SequenceRanges    map[int]Range          `json:"sequence_ranges,omitempty"`
+	Overflowing       []Encoding             `json:"overflowing,omitempty"`
+	Meta              map[string]interface{} `json:"meta,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// Offsets are written as `[start, end]` pairs and word indexes of -1 (tokens
+// not belonging to any word) as `null`, as HuggingFace does. `Normalized` is
+// not serialized.
+func (e Encoding) MarshalJSON() ([]byte, error) {
+	var wordIds []*int
+	if e.Words != nil {
+		wordIds = e.WordIds()
+	}
+
+	return json.Marshal(encodingJSON{
+		InputIds:          e.Ids,
+		TokenTypeIds:      e.TypeIds,
+		Tokens:            e.Tokens,
+		OffsetMapping:     e.Offsets,
+		SpecialTokensMask: e.SpecialTokenMask,
+		AttentionMask:     e.AttentionMask,
+		WordIds:           wordIds,
+		SequenceRanges:    e.SequenceRanges,
+		Overflowing:       e.Overflowing,
+		Meta:              e.Meta,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It reads the format written by
+// `MarshalJSON`.
+func (e *Encoding) UnmarshalJSON(data []byte) error {
+	var v encodingJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	n := len(v.InputIds)
+	for _, f := range []struct {
+		name string
+		len  int
+	}{
+		{"token_type_ids", len(v.TokenTypeIds)},
+		{"tokens", len(v.Tokens)},
+		{"offset_mapping", len(v.OffsetMapping)},
+		{"special_tokens_mask", len(v.SpecialTokensMask)},
+		{"attention_mask", len(v.AttentionMask)},
+	} {
+		if f.len != n {
+			return fmt.Errorf("UnmarshalJSON failed: '%s' has %d items, 'input_ids' has %d", f.name, f.len, n)
+		}
+	}
+	if v.WordIds != nil && len(v.WordIds) != n {
+		return fmt.Errorf("UnmarshalJSON failed: 'word_ids' has %d items, 'input_ids' has %d", len(v.WordIds), n)
+	}
+	for i, o := range v.OffsetMapping {
+		if len(o) != 2 {
+			return fmt.Errorf("UnmarshalJSON failed: invalid offsets %v at index %d", o, i)
+		}
+	}
+
+	var words []int
+	if v.WordIds != nil {
+		words = make([]int, n)
+		for i, w := range v.WordIds {
+			words[i] = -1
+			if w != nil {
+				words[i] = *w
+			}
+		}
+	}
+
+	sequenceRanges := v.SequenceRanges
+	if sequenceRanges == nil {
+		sequenceRanges = make(map[int]Range)
+	}
+
+	*e = Encoding{
+		Ids:              v.InputIds,
+		TypeIds:          v.TokenTypeIds,
+		Tokens:           v.Tokens,
+		Offsets:          v.OffsetMapping,
+		SpecialTokenMask: v.SpecialTokensMask,
+		AttentionMask:    v.AttentionMask,
+		Overflowing:      v.Overflowing,
+		Words:            words,
+		SequenceRanges:   sequenceRanges,
+		Meta:             v.Meta,
+	}
+
+	return nil
+}
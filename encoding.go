@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"log"
 	"reflect"
+	"sync"
 
+	"github.com/sugarme/tokenizer/normalizer"
 	"github.com/sugarme/tokenizer/util"
 )
 
@@ -26,6 +28,21 @@ type Encoding struct {
 	Overflowing      []Encoding    // A list of overflowing generated when being truncated
 	Words            []int         // Optional - Indexes of the word associated with each token/ID. None value = -1
 	SequenceRanges   map[int]Range // Range of tokens covered by each sequence. If empty -> only one sequence and covers the entire range.
+
+	// Meta holds arbitrary user metadata (e.g. source document id). It is
+	// (shallow) copied by `Clone` and ignored by `Equal`.
+	Meta map[string]interface{}
+
+	// Normalized holds the normalized input sequences (one per sequence) if
+	// the tokenizer was set with `WithAttachNormalized(true)`. It lets callers
+	// convert offsets themselves (`ConvertOffset`, `RangeOriginal`, ...).
+	Normalized []*normalizer.NormalizedString
+
+	// index holds the word and char lookup maps, built on first lookup.
+	index *encodingIndex
+	// maxOverflowing caps the overflowing encodings of `MergeWith`, if
+	// greater than zero. It is set by `TruncateEncodings`.
+	maxOverflowing int
 }
 
 type EncodingOpts struct {
@@ -70,21 +87,53 @@ func NewEncoding(ids []int, typeIds []int, tokens []string, offsets [][]int, spe
 		overflowing,
 		o.Words,
 		o.SequenceRange,
+		nil,
+		nil,
+		nil,
+		0,
 	}
 }
 
 func NewEncodingWithCapacity(l int) (retVal *Encoding) {
-	return &Encoding{
-		Ids:              make([]int, l),
-		TypeIds:          make([]int, l),
-		Tokens:           make([]string, l),
-		Offsets:          make([][]int, l),
-		SpecialTokenMask: make([]int, l),
-		AttentionMask:    make([]int, l),
-		Overflowing:      []Encoding{},
-		Words:            make([]int, l),
-		SequenceRanges:   make(map[int]Range),
+	retVal = allocEncoding(l)
+	for i := range retVal.Words {
+		retVal.Words[i] = -1
+	}
+
+	return retVal
+}
+
+// allocEncoding returns an encoding of n zero tokens. See `alloc`.
+func allocEncoding(n int) *Encoding {
+	en := &Encoding{
+		Overflowing:    []Encoding{},
+		SequenceRanges: make(map[int]Range),
+	}
+	en.alloc(n)
+
+	return en
+}
+
+// alloc sets the token fields of e to n zero tokens, whose int data (ids, type
+// ids, masks, words and offsets) lives in a single contiguous array, the
+// fields being views on it. Only the tokens and the offset headers take an
+// allocation of their own.
+//
+// NOTE: each view is capped to its length, so appending to it (ie. padding)
+// moves it out of the array rather than overwriting the next one.
+func (e *Encoding) alloc(n int) {
+	arena := make([]int, 7*n)
+	view := func(i int) []int {
+		return arena[i*n : (i+1)*n : (i+1)*n]
 	}
+
+	e.Ids = view(0)
+	e.TypeIds = view(1)
+	e.Tokens = make([]string, n)
+	e.Offsets = offsetViews(arena[5*n:])
+	e.SpecialTokenMask = view(2)
+	e.AttentionMask = view(3)
+	e.Words = view(4)
 }
 
 // Default creates an encoding with default values
@@ -104,46 +153,152 @@ func DefaultEncoding() *Encoding {
 
 // NewEncodingFromTokens initiate Encoding from input tokens
 func NewEncodingFromTokens(tokens []Token, typeId int) (retVal *Encoding) {
-	var (
-		ids     []int
-		offsets [][]int
-		toks    []string
-	)
-	for _, t := range tokens {
-		ids = append(ids, t.Id)
-		offsets = append(offsets, t.Offsets)
-		toks = append(toks, t.Value)
+	retVal = allocEncoding(len(tokens))
+	for i, t := range tokens {
+		retVal.Ids[i] = t.Id
+		retVal.Tokens[i] = t.Value
+		copy(retVal.Offsets[i], t.Offsets)
+		retVal.AttentionMask[i] = 1
 	}
+	retVal.Words = nil
 
-	typeIds := make([]int, len(tokens))
-	// words := make([]int, len(tokens))
-	var words []int
-	specialTokenMask := util.Repeat(0, len(tokens))
-	attentionMask := util.Repeat(1, len(tokens))
+	return retVal
+}
 
-	return &Encoding{
-		Ids:              ids,
-		TypeIds:          typeIds,
-		Tokens:           toks,
-		Offsets:          offsets,
-		SpecialTokenMask: specialTokenMask,
-		AttentionMask:    attentionMask,
-		Overflowing:      []Encoding{},
-		Words:            words,
-		SequenceRanges:   make(map[int]Range),
+// encodingPool holds the encodings released with `PutEncoding`.
+var encodingPool = sync.Pool{
+	New: func() interface{} { return DefaultEncoding() },
+}
+
+// GetEncoding returns an empty encoding from a pool of encodings. Its slices
+// may have the capacity of a previously released encoding, so that filling it
+// with `Tokenizer.EncodeInto` does not need to allocate them again.
+func GetEncoding() *Encoding {
+	return encodingPool.Get().(*Encoding)
+}
+
+// PutEncoding resets e and puts it back to the pool of `GetEncoding`. e, and
+// its slices, must not be used afterwards.
+func PutEncoding(e *Encoding) {
+	e.Reset()
+	encodingPool.Put(e)
+}
+
+// Reset empties the encoding, keeping the capacity of its slices.
+func (e *Encoding) Reset() {
+	e.Ids = e.Ids[:0]
+	e.TypeIds = e.TypeIds[:0]
+	e.Tokens = e.Tokens[:0]
+	e.Offsets = e.Offsets[:0]
+	e.SpecialTokenMask = e.SpecialTokenMask[:0]
+	e.AttentionMask = e.AttentionMask[:0]
+	e.Overflowing = e.Overflowing[:0]
+	e.Words = e.Words[:0]
+	if e.SequenceRanges == nil {
+		e.SequenceRanges = make(map[int]Range)
+	}
+	for k := range e.SequenceRanges {
+		delete(e.SequenceRanges, k)
+	}
+	e.Meta = nil
+	e.Normalized = nil
+	e.resetIndex()
+}
+
+// reuse resets e and sets its token fields to n zero tokens as `alloc` does,
+// reusing the capacity of its slices when they can hold n tokens.
+func (e *Encoding) reuse(n int) {
+	offsets := e.Offsets[:cap(e.Offsets)]
+	e.Reset()
+	if e.Overflowing == nil {
+		e.Overflowing = []Encoding{}
+	}
+	if cap(e.Ids) < n || cap(e.TypeIds) < n || cap(e.Tokens) < n || len(offsets) < n ||
+		cap(e.SpecialTokenMask) < n || cap(e.AttentionMask) < n || cap(e.Words) < n {
+		e.alloc(n)
+		return
+	}
+
+	e.Ids = e.Ids[:n]
+	e.TypeIds = e.TypeIds[:n]
+	e.Tokens = e.Tokens[:n]
+	e.Offsets = offsets[:n]
+	e.SpecialTokenMask = e.SpecialTokenMask[:n]
+	e.AttentionMask = e.AttentionMask[:n]
+	e.Words = e.Words[:n]
+	for i := 0; i < n; i++ {
+		e.Ids[i], e.TypeIds[i], e.SpecialTokenMask[i], e.AttentionMask[i], e.Words[i] = 0, 0, 0, 0, 0
+		e.Tokens[i] = ""
+		if cap(e.Offsets[i]) < 2 {
+			e.Offsets[i] = make([]int, 2)
+		} else {
+			e.Offsets[i] = e.Offsets[i][:2]
+			e.Offsets[i][0], e.Offsets[i][1] = 0, 0
+		}
 	}
 }
 
 func (e *Encoding) Clone() *Encoding {
+	// NOTE. `Meta` values can be of any type that `gob` can't encode,
+	// so it is copied separately, as unexported fields `gob` drops. e is
+	// left untouched so that it can be cloned concurrently.
+	c := *e
+	c.Meta = nil
 	out := new(Encoding)
-	err := util.DeepCopy(e, out)
-	if err != nil {
+	if err := util.DeepCopy(&c, out); err != nil {
 		panic(err)
 	}
+	copyMaxOverflowing(out, e)
+
+	if e.Meta != nil {
+		out.Meta = make(map[string]interface{}, len(e.Meta))
+		for k, v := range e.Meta {
+			out.Meta[k] = v
+		}
+	}
 
 	return out
 }
 
+// copyMaxOverflowing copies the `maxOverflowing` cap of src and of its
+// overflowing encodings into their clones in dst.
+func copyMaxOverflowing(dst, src *Encoding) {
+	dst.maxOverflowing = src.maxOverflowing
+	for i := range dst.Overflowing {
+		if i < len(src.Overflowing) {
+			copyMaxOverflowing(&dst.Overflowing[i], &src.Overflowing[i])
+		}
+	}
+}
+
+// Equal returns whether 2 encodings hold the same data. `Meta` is not compared.
+func (e *Encoding) Equal(other *Encoding) bool {
+	if e == nil || other == nil {
+		return e == other
+	}
+
+	a, b := *e, *other
+	a.Meta, b.Meta = nil, nil
+	a.index, b.index = nil, nil
+	a.maxOverflowing, b.maxOverflowing = 0, 0
+
+	return reflect.DeepEqual(a, b)
+}
+
+// SetMeta sets a metadata value with given key.
+func (e *Encoding) SetMeta(key string, value interface{}) {
+	if e.Meta == nil {
+		e.Meta = make(map[string]interface{})
+	}
+	e.Meta[key] = value
+}
+
+// GetMeta returns the metadata value with given key if existing.
+func (e *Encoding) GetMeta(key string) (interface{}, bool) {
+	v, ok := e.Meta[key]
+	return v, ok
+}
+
 // IsEmpty returns whether Encoding is empty
 func (e *Encoding) IsEmpty() (retVal bool) {
 	return len(e.Ids) == 0
@@ -173,34 +328,79 @@ func (e *Encoding) GetWords() []int {
 	return e.Words
 }
 
+// WordIds returns the index of the word of each token, nil for tokens which
+// are not part of a word (special tokens, padding).
+func (e *Encoding) WordIds() []*int {
+	words := e.words()
+	wordIds := make([]*int, len(words))
+	for i, w := range words {
+		if w >= 0 {
+			w := w
+			wordIds[i] = &w
+		}
+	}
+
+	return wordIds
+}
+
+// BatchWordIds returns the `WordIds` of each encoding.
+func BatchWordIds(encodings []Encoding) [][]*int {
+	batch := make([][]*int, len(encodings))
+	for i := range encodings {
+		batch[i] = encodings[i].WordIds()
+	}
+
+	return batch
+}
+
+// words returns `Words`, or -1 (no word) for all the tokens if its length does
+// not match, ie. the encoding has no word information.
+func (e *Encoding) words() []int {
+	if len(e.Words) == len(e.Ids) {
+		return e.Words
+	}
+
+	words := make([]int, len(e.Ids))
+	for i := range words {
+		words[i] = -1
+	}
+
+	return words
+}
+
 // SetWord set word index value at given index of word in e.Words slice
 func (e *Encoding) SetWord(index int, val int) {
 	e.Words[index] = val
+	e.resetIndex()
 }
 
 // SetSequenceIds set the given sequence id for the whole range of tokens contained in this Encoding
 func (e *Encoding) SetSequenceIds(sequenceId int) {
 	if e.Len() > 0 {
-		e.SequenceRanges[sequenceId] = NewRange(0, e.Len())
+		e.SequenceRanges = map[int]Range{sequenceId: NewRange(0, e.Len())}
+		e.resetIndex()
 	}
 }
 
+// GetSequenceIds returns the sequence id of each token. Tokens that do not
+// belong to any sequence (ie. special tokens added by a post-processor) are
+// given -1.
 func (e *Encoding) GetSequenceIds() []int {
 	sequences := make([]int, e.Len())
-	for seqId := 0; seqId < e.NSequences(); seqId++ {
-		r := e.SequenceRanges[seqId]
-		seqLen := r.Len()
-		var a []int
-		for i := 0; i < seqLen; i++ {
-			a = append(a, seqId)
+	if len(e.SequenceRanges) == 0 {
+		// Single sequence covering the whole encoding
+		return sequences
+	}
+
+	for i := range sequences {
+		sequences[i] = -1
+	}
+	for seqId, r := range e.SequenceRanges {
+		for _, i := range r {
+			if i < len(sequences) {
+				sequences[i] = seqId
+			}
 		}
-		// replace items in Range r with seqId
-		start := r[0]
-		end := r[len(r)-1]
-		before := sequences[:start]
-		after := sequences[end:]
-		sequences = append(before, a...)
-		sequences = append(sequences, after...)
 	}
 
 	return sequences
@@ -211,6 +411,17 @@ func (e *Encoding) GetIds() []int {
 	return e.Ids
 }
 
+// ToInt64Slice returns Ids as int64, the id type expected by most ML
+// frameworks.
+func (e *Encoding) ToInt64Slice() []int64 {
+	ids := make([]int64, len(e.Ids))
+	for i, id := range e.Ids {
+		ids[i] = int64(id)
+	}
+
+	return ids
+}
+
 // GetTypeIds returns type Ids from encoding
 func (e *Encoding) GetTypeIds() []int {
 	return e.TypeIds
@@ -225,11 +436,148 @@ func (e *Encoding) GetOffsets() [][]int {
 	return e.Offsets
 }
 
+// ToCharOffsets converts the byte offsets of the encoding, and of its
+// overflowing encodings, to char (rune) offsets, in place. It returns e.
+//
+// sequences are the sequences the offsets refer to: the input sequence and
+// its pair if any (or their normalized forms with `normalizer.NormalizedTarget`
+// offsets). Offsets of tokens out of these sequences (ie. special tokens added
+// by a post-processor) are kept as is.
+func (e *Encoding) ToCharOffsets(sequences ...string) *Encoding {
+	return e.convertOffsets(sequences, byteToCharIndex)
+}
+
+// ToByteOffsets converts the char (rune) offsets of the encoding, and of its
+// overflowing encodings, to byte offsets, in place. It returns e.
+//
+// See `ToCharOffsets` for sequences.
+func (e *Encoding) ToByteOffsets(sequences ...string) *Encoding {
+	return e.convertOffsets(sequences, charToByteIndex)
+}
+
+// convertOffsets converts the offsets of each token using the index of its
+// sequence built by indexFn.
+func (e *Encoding) convertOffsets(sequences []string, indexFn func(string) []int) *Encoding {
+	indexes := make([][]int, len(sequences))
+	for i, s := range sequences {
+		indexes[i] = indexFn(s)
+	}
+
+	convert := func(en *Encoding) {
+		for i, seqId := range en.GetSequenceIds() {
+			if seqId < 0 || seqId >= len(indexes) || i >= len(en.Offsets) {
+				continue
+			}
+			index := indexes[seqId]
+			offsets := make([]int, len(en.Offsets[i]))
+			for j, o := range en.Offsets[i] {
+				switch {
+				case o < 0:
+					o = 0
+				case o >= len(index):
+					o = len(index) - 1
+				}
+				offsets[j] = index[o]
+			}
+			en.Offsets[i] = offsets
+		}
+	}
+
+	convert(e)
+	e.resetIndex()
+	for i := range e.Overflowing {
+		convert(&e.Overflowing[i])
+		e.Overflowing[i].resetIndex()
+	}
+
+	return e
+}
+
+// byteToCharIndex returns the char index of each byte of s, and the number of
+// chars of s at index len(s).
+func byteToCharIndex(s string) []int {
+	index := make([]int, 0, len(s)+1)
+	n := 0
+	for i := range s {
+		for len(index) < i {
+			index = append(index, n-1) // inside the previous char
+		}
+		index = append(index, n)
+		n++
+	}
+	for len(index) < len(s) {
+		index = append(index, n-1)
+	}
+
+	return append(index, n)
+}
+
+// charToByteIndex returns the byte index of each char of s, and len(s) at
+// index the number of chars of s.
+func charToByteIndex(s string) []int {
+	index := make([]int, 0, len(s)+1)
+	for i := range s {
+		index = append(index, i)
+	}
+
+	return append(index, len(s))
+}
+
 // GetSpecialTokenMask returns specialTokenMask from encoding
 func (e *Encoding) GetSpecialTokenMask() []int {
 	return e.SpecialTokenMask
 }
 
+// SpecialTokenPositions returns indexes of tokens marked as special tokens
+// in `SpecialTokenMask`.
+func (e *Encoding) SpecialTokenPositions() []int {
+	var positions []int
+	for i, m := range e.SpecialTokenMask {
+		if m == 1 {
+			positions = append(positions, i)
+		}
+	}
+
+	return positions
+}
+
+// TokenText is a token with the original text it covers.
+type TokenText struct {
+	Token   string
+	Text    string
+	Offsets []int
+}
+
+// TokensWithText zips each token with the substring of `original` it covers.
+// Special and padding tokens are skipped.
+//
+// NOTE. offsets are expected to be byte offsets (see `Tokenizer.Encode`).
+func (e *Encoding) TokensWithText(original string) []TokenText {
+	var tokens []TokenText
+	for i, tok := range e.Tokens {
+		if i < len(e.SpecialTokenMask) && e.SpecialTokenMask[i] == 1 {
+			continue
+		}
+
+		offsets := e.Offsets[i]
+		start, end := offsets[0], offsets[1]
+		if end > len(original) {
+			end = len(original)
+		}
+		if start > end {
+			start = end
+		}
+
+		tokens = append(tokens, TokenText{
+			Token:   tok,
+			Text:    original[start:end],
+			Offsets: offsets,
+		})
+	}
+
+	return tokens
+}
+
 // GetAttentionMask returns attentionMask from encoding
 func (e *Encoding) GetAttentionMask() []int {
 	return e.AttentionMask
@@ -252,53 +600,25 @@ func (e *Encoding) TakeOverflowing() []Encoding {
 	return o
 }
 
-// Word2Tokens gets the encoded tokens corresponding the word
-// at the given index in the input sequence
-// in the form `(startToken, endToken + 1)`
+// Word2Tokens gets the encoded tokens corresponding to the word at the given
+// index in the input sequence, in the form `(startToken, endToken)`.
 //
-// NOTE. e.Words is optional, therefore, there's case of `none` result
-// if `none` result, `ok` will be false.
-func (e *Encoding) Word2Tokens(word int) (startTok, endTok int, ok bool) {
-
-	var start, end int = -1, -1
-
-	var inRangeWords []int
-	for _, w := range e.Words {
-		if w <= word {
-			inRangeWords = append(inRangeWords, w)
-		}
-	}
-	for i, w := range inRangeWords {
-		if w == word && start < 0 {
-			start = i
-		}
-	}
-
-	end = len(inRangeWords)
-
-	if start != -1 && end != -1 {
-		return start, end, true
-	} else {
-		return startTok, endTok, false
-	}
+// Deprecated: use `WordToTokens`.
+func (e *Encoding) Word2Tokens(word int, sequenceIdOpt ...int) (startTok, endTok int, ok bool) {
+	return e.WordToTokens(word, sequenceIdOpt...)
 }
 
 // Word2Chars get the offsets of the word at a given index in
-// the input sequence
-func (e *Encoding) Word2Chars(word int) (retVal []int, ok bool) {
-	start, end, ok := e.Word2Tokens(word)
-	if end == 0 {
-		return retVal, false
-	} else {
-		oStart := e.Offsets[start][0]
-		oEnd := e.Offsets[end-1][1]
-		return []int{oStart, oEnd}, true // Should we check whether `ok`?
-	}
+// the input sequence.
+//
+// Deprecated: use `WordToChars`.
+func (e *Encoding) Word2Chars(word int, sequenceIdOpt ...int) (retVal []int, ok bool) {
+	return e.WordToChars(word, sequenceIdOpt...)
 }
 
 // Token2Chars get the offsets of the token at the given index
 func (e *Encoding) Token2Chars(tokenIdx int) (retVal []int, ok bool) {
-	if tokenIdx < 0 || tokenIdx > len(e.Offsets) {
+	if tokenIdx < 0 || tokenIdx >= len(e.Offsets) {
 		return retVal, false
 	} else {
 		return e.Offsets[tokenIdx], true
@@ -306,41 +626,35 @@ func (e *Encoding) Token2Chars(tokenIdx int) (retVal []int, ok bool) {
 }
 
 // Token2Word get the word index of corresponding token if existing
+//
+// Deprecated: use `TokenToWord`.
 func (e *Encoding) Token2Word(tokenIdx int) (retVal int, ok bool) {
-	// naive search. TODO. improve algorithm
-	for i, w := range e.Words {
-		if i == tokenIdx {
-			return w, true
-		}
-	}
-	return retVal, false
+	return e.TokenToWord(tokenIdx)
 }
 
-// Char2Token returns a token index that contains the given `char` index
-func (e *Encoding) Char2Token(pos int) (retVal int, ok bool) {
-	for i, o := range e.Offsets {
-		if pos >= o[0] && pos < o[1] {
-			return i, true
-		}
-	}
-
-	return -1, false
+// Char2Token returns a token index that contains the given `char` index.
+//
+// Deprecated: use `CharToToken`.
+func (e *Encoding) Char2Token(pos int, sequenceIdOpt ...int) (retVal int, ok bool) {
+	return e.CharToToken(pos, sequenceIdOpt...)
 }
 
-// Char2Word get the word index that contain the given `char` index
-func (e *Encoding) Char2Word(pos int) (retVal int, ok bool) {
-	if idx, ok := e.Char2Token(pos); ok {
-		return e.Token2Word(idx)
-	}
-
-	return -1, false
+// Char2Word get the word index that contain the given `char` index.
+//
+// Deprecated: use `CharToWord`.
+func (e *Encoding) Char2Word(pos int, sequenceIdOpt ...int) (retVal int, ok bool) {
+	return e.CharToWord(pos, sequenceIdOpt...)
 }
 
-// Truncate truncates the current encoding
+// Truncate truncates the current encoding to maxLen tokens. The removed tokens
+// are split into overflowing encodings of maxLen tokens, each one repeating
+// the last `stride` tokens of the previous part. With maxLen zero, the whole
+// encoding is moved into `Overflowing`.
+//
+// It modifies e in place and returns it. See `Truncated` to keep e unchanged.
 func (e *Encoding) Truncate(maxLen int, stride int) (retVal *Encoding, err error) {
-
-	if stride >= maxLen || maxLen == 0 {
-		return retVal, fmt.Errorf("Invalid input maxLen or stride (stride must be less than maxLen and maxLen must be greater than zero.)")
+	if maxLen < 0 {
+		return retVal, fmt.Errorf("Invalid input maxLen %v: must not be negative.", maxLen)
 	}
 
 	if maxLen >= len(e.Ids) {
@@ -348,21 +662,35 @@ func (e *Encoding) Truncate(maxLen int, stride int) (retVal *Encoding, err error
 		return e, nil
 	}
 
+	if maxLen == 0 {
+		o := *e
+		o.Overflowing = make([]Encoding, 0)
+		*e = *DefaultEncoding()
+		e.Overflowing = []Encoding{o}
+		return e, nil
+	}
+
+	if stride >= maxLen {
+		return retVal, fmt.Errorf("Invalid input maxLen or stride (stride must be less than maxLen and maxLen must be greater than zero.)")
+	}
+
 	// Truncating at maxLen (exclusive) to keep.
 	// The rest (overflowing) from maxLen (inclusive)
-	newIds := e.Ids[0:maxLen]
+	// NOTE: the capacity of the kept part is limited to maxLen so that
+	// appending to it (ie. padding) does not overwrite the overflowing part.
+	newIds := e.Ids[0:maxLen:maxLen]
 	oIds := e.Ids[maxLen:len(e.Ids)] // overflowing
-	newTypeIds := e.TypeIds[0:maxLen]
+	newTypeIds := e.TypeIds[0:maxLen:maxLen]
 	oTypeIds := e.TypeIds[maxLen:len(e.TypeIds)]
-	newTokens := e.Tokens[0:maxLen]
+	newTokens := e.Tokens[0:maxLen:maxLen]
 	oTokens := e.Tokens[maxLen:len(e.Tokens)]
-	newOffsets := e.Offsets[0:maxLen]
+	newOffsets := e.Offsets[0:maxLen:maxLen]
 	oOffsets := e.Offsets[maxLen:len(e.Offsets)]
-	newSpeToks := e.SpecialTokenMask[0:maxLen]
+	newSpeToks := e.SpecialTokenMask[0:maxLen:maxLen]
 	oSpeToks := e.SpecialTokenMask[maxLen:len(e.SpecialTokenMask)]
-	newAttent := e.AttentionMask[0:maxLen]
+	newAttent := e.AttentionMask[0:maxLen:maxLen]
 	oAttent := e.AttentionMask[maxLen:len(e.AttentionMask)]
-	newWords := e.Words[0:maxLen]
+	newWords := e.Words[0:maxLen:maxLen]
 	oWords := e.Words[maxLen:len(e.Words)]
 
 	e.Ids = newIds
@@ -372,6 +700,7 @@ func (e *Encoding) Truncate(maxLen int, stride int) (retVal *Encoding, err error
 	e.SpecialTokenMask = newSpeToks
 	e.AttentionMask = newAttent
 	e.Words = newWords
+	e.resetIndex()
 
 	// Separate the overflowing part into as many Encoding as needed
 	partSize := maxLen - stride
@@ -382,13 +711,13 @@ func (e *Encoding) Truncate(maxLen int, stride int) (retVal *Encoding, err error
 	// while loop
 	for int(partSize)*partId < len(oIds) {
 		o := Encoding{
-			Ids:              reflect.ValueOf(getCurrentPart(prevEncoding.Ids, oIds, partSize, partId, stride)).Interface().([]int),
-			TypeIds:          reflect.ValueOf(getCurrentPart(prevEncoding.TypeIds, oTypeIds, partSize, partId, stride)).Interface().([]int),
-			Tokens:           reflect.ValueOf(getCurrentPart(prevEncoding.Tokens, oTokens, partSize, partId, stride)).Interface().([]string),
-			Offsets:          reflect.ValueOf(getCurrentPart(prevEncoding.Offsets, oOffsets, partSize, partId, stride)).Interface().([][]int),
-			SpecialTokenMask: reflect.ValueOf(getCurrentPart(prevEncoding.SpecialTokenMask, oSpeToks, partSize, partId, stride)).Interface().([]int),
-			AttentionMask:    reflect.ValueOf(getCurrentPart(prevEncoding.AttentionMask, oAttent, partSize, partId, stride)).Interface().([]int),
-			Words:            reflect.ValueOf(getCurrentPart(prevEncoding.Words, oWords, partSize, partId, stride)).Interface().([]int),
+			Ids:              chunkWithStride(prevEncoding.Ids, oIds, partSize, partId, stride),
+			TypeIds:          chunkWithStride(prevEncoding.TypeIds, oTypeIds, partSize, partId, stride),
+			Tokens:           chunkWithStride(prevEncoding.Tokens, oTokens, partSize, partId, stride),
+			Offsets:          chunkWithStride(prevEncoding.Offsets, oOffsets, partSize, partId, stride),
+			SpecialTokenMask: chunkWithStride(prevEncoding.SpecialTokenMask, oSpeToks, partSize, partId, stride),
+			AttentionMask:    chunkWithStride(prevEncoding.AttentionMask, oAttent, partSize, partId, stride),
+			Words:            chunkWithStride(prevEncoding.Words, oWords, partSize, partId, stride),
 			Overflowing:      make([]Encoding, 0),
 		}
 
@@ -402,7 +731,13 @@ func (e *Encoding) Truncate(maxLen int, stride int) (retVal *Encoding, err error
 	return e, nil
 }
 
-// Merge merges all Encodings together
+// Truncated returns a truncated copy of e, leaving e unchanged. See
+// `Truncate`.
+func (e *Encoding) Truncated(maxLen int, stride int) (*Encoding, error) {
+	return e.Clone().Truncate(maxLen, stride)
+}
+
+// Merge merges all Encodings together, in place. It returns e.
 func (e *Encoding) Merge(encodings []Encoding, growingOffsets bool) (retVal *Encoding) {
 	retVal = e
 	for _, encoding := range encodings {
@@ -412,8 +747,21 @@ func (e *Encoding) Merge(encodings []Encoding, growingOffsets bool) (retVal *Enc
 	return retVal
 }
 
-// MergeWith merges the current encoding with other (pair) encoding
+// MergeWith merges the current encoding with other (pair) encoding, in place.
+// It returns e.
 func (e *Encoding) MergeWith(pair *Encoding, growingOffsets bool) (retVal *Encoding) {
+	maxOverflowing := e.maxOverflowing
+	if maxOverflowing == 0 {
+		maxOverflowing = pair.maxOverflowing
+	}
+
+	return e.mergeWith(pair, growingOffsets, maxOverflowing)
+}
+
+// mergeWith is `MergeWith`, capping the overflowing encodings to
+// maxOverflowing, if greater than zero, down to the overflowing encodings of
+// the merged overflowing encodings.
+func (e *Encoding) mergeWith(pair *Encoding, growingOffsets bool, maxOverflowing int) (retVal *Encoding) {
 	// Merge overflowing
 	var overflowings []Encoding
 	var (
@@ -425,25 +773,38 @@ func (e *Encoding) MergeWith(pair *Encoding, growingOffsets bool) (retVal *Encod
 	en.Overflowing = []Encoding{}
 	pen.Overflowing = []Encoding{}
 
+	full := func() bool {
+		return maxOverflowing > 0 && len(overflowings) >= maxOverflowing
+	}
+
 	// 1. All our overflowings with all other overflowings
 	for _, o := range enOverflowings {
+		if full() {
+			break
+		}
 		nEncoding := o.Clone()
 		// 1.1. The pair itself
-		merge := nEncoding.MergeWith(pair.Clone(), growingOffsets)
+		merge := nEncoding.mergeWith(pair.Clone(), growingOffsets, maxOverflowing)
 		overflowings = append(overflowings, *merge)
 
 		// 1.2. Its overflowings
 		for _, otherO := range penOverflowings {
+			if full() {
+				break
+			}
 			nEncoding := o.Clone()
-			merge := nEncoding.MergeWith(otherO.Clone(), growingOffsets)
+			merge := nEncoding.mergeWith(otherO.Clone(), growingOffsets, maxOverflowing)
 			overflowings = append(overflowings, *merge)
 		}
 	}
 
 	// 2. Ourself with all the other overflowings
 	for _, otherO := range penOverflowings {
+		if full() {
+			break
+		}
 		nEncoding := e.Clone()
-		merge := nEncoding.MergeWith(otherO.Clone(), growingOffsets)
+		merge := nEncoding.mergeWith(otherO.Clone(), growingOffsets, maxOverflowing)
 		overflowings = append(overflowings, *merge)
 	}
 
@@ -452,45 +813,88 @@ func (e *Encoding) MergeWith(pair *Encoding, growingOffsets bool) (retVal *Encod
 	// Merging others
 	originalLen := e.Len()
 	if len(pair.SequenceRanges) > 0 {
+		sequenceRanges := make(map[int]Range, len(e.SequenceRanges)+len(pair.SequenceRanges))
+		for seqId, r := range e.SequenceRanges {
+			sequenceRanges[seqId] = append(Range{}, r...)
+		}
 		for seqId, r := range pair.SequenceRanges {
+			if r.IsEmpty() {
+				continue
+			}
 			start := originalLen + r[0]
 			end := originalLen + r[r.Len()-1] + 1
-			newRange := NewRange(start, end)
-			var oldRange Range
-			util.DeepCopy(e.SequenceRanges[seqId], oldRange)
-			e.SequenceRanges[seqId] = util.Merge(oldRange, newRange)
+			sequenceRanges[seqId] = append(sequenceRanges[seqId], NewRange(start, end)...)
 		}
+		e.SequenceRanges = sequenceRanges
 	}
 
-	e.Ids = util.Merge(e.Ids, pair.Ids)
-	e.Tokens = util.Merge(e.Tokens, pair.Tokens)
-	e.Words = util.Merge(e.Words, pair.Words)
-	e.TypeIds = util.Merge(e.TypeIds, pair.TypeIds)
-	e.SpecialTokenMask = util.Merge(e.SpecialTokenMask, pair.SpecialTokenMask)
-	e.AttentionMask = util.Merge(e.AttentionMask, pair.AttentionMask)
-
 	// Offsets
 	var startingOffset int = 0
-	offsets := e.Offsets
 	if growingOffsets {
-		if len(offsets) > 0 {
-			last := offsets[len(offsets)-1]
+		if len(e.Offsets) > 0 {
+			last := e.Offsets[len(e.Offsets)-1]
 			startingOffset = last[1]
 		}
 	}
 
-	for _, o := range pair.Offsets {
-		adjustedO := []int{
-			o[0] + startingOffset,
-			o[1] + startingOffset,
+	var merged Encoding
+	merged.alloc(originalLen + pair.Len())
+
+	// Before `Ids`, which `words` depends on
+	if e.Words != nil || pair.Words != nil {
+		e.Words = mergeInto(merged.Words, e.words(), pair.words())
+	}
+	e.Ids = mergeInto(merged.Ids, e.Ids, pair.Ids)
+	e.Tokens = mergeInto(merged.Tokens, e.Tokens, pair.Tokens)
+	e.TypeIds = mergeInto(merged.TypeIds, e.TypeIds, pair.TypeIds)
+	e.SpecialTokenMask = mergeInto(merged.SpecialTokenMask, e.SpecialTokenMask, pair.SpecialTokenMask)
+	e.AttentionMask = mergeInto(merged.AttentionMask, e.AttentionMask, pair.AttentionMask)
+
+	if e.Offsets != nil || pair.Offsets != nil {
+		n := len(e.Offsets)
+		offsets := merged.Offsets
+		if n+len(pair.Offsets) != len(offsets) {
+			offsets = offsetViews(make([]int, 2*(n+len(pair.Offsets))))
 		}
-		offsets = append(offsets, adjustedO)
+		for i, o := range e.Offsets {
+			copy(offsets[i], o)
+		}
+		for i, o := range pair.Offsets {
+			offsets[n+i][0] = o[0] + startingOffset
+			offsets[n+i][1] = o[1] + startingOffset
+		}
+		e.Offsets = offsets
 	}
-	e.Offsets = offsets
+	e.resetIndex()
 
 	return e
 }
 
+// offsetViews returns the [start, end] offsets held in flat, each one
+// being a view on it capped to its 2 ints.
+func offsetViews(flat []int) [][]int {
+	offsets := make([][]int, len(flat)/2)
+	for i := range offsets {
+		offsets[i] = flat[2*i : 2*i+2 : 2*i+2]
+	}
+
+	return offsets
+}
+
+// mergeInto returns a followed by b, copied into dst if it has the exact
+// length for them. As `util.Merge`, it returns nil if both a and b are nil.
+func mergeInto[T any](dst, a, b []T) []T {
+	if len(a)+len(b) != len(dst) {
+		return util.Merge(a, b)
+	}
+	if a == nil && b == nil {
+		return nil
+	}
+
+	copy(dst[copy(dst, a):], b)
+	return dst
+}
+
 // mergeEncoding merges 2 encodings those have `Overflowing` field empty.
 // Otherwise, it will be panic.
 func mergeEncoding(en1, en2 Encoding, growingOffsets bool) Encoding {
@@ -502,7 +906,9 @@ func mergeEncoding(en1, en2 Encoding, growingOffsets bool) Encoding {
 	merge.Overflowing = []Encoding{}
 	merge.Ids = util.Merge(en1.Ids, en2.Ids)
 	merge.TypeIds = util.Merge(en1.TypeIds, en2.TypeIds)
-	merge.Words = util.Merge(en1.Words, en2.Words)
+	if en1.Words != nil || en2.Words != nil {
+		merge.Words = util.Merge(en1.words(), en2.words())
+	}
 	merge.Tokens = util.Merge(en1.Tokens, en2.Tokens)
 	merge.SpecialTokenMask = util.Merge(en1.SpecialTokenMask, en2.SpecialTokenMask)
 	merge.AttentionMask = util.Merge(en1.AttentionMask, en2.AttentionMask)
@@ -547,8 +953,17 @@ func mergeEncoding(en1, en2 Encoding, growingOffsets bool) Encoding {
 	return merge
 }
 
-// Pad pads current encoding with given length, values to either Left or Right direction
-func (e *Encoding) Pad(targetLength, padId, padTypeId int, padToken string, direction PaddingDirection) *Encoding {
+// Pad pads current encoding with given length, values to either Left or Right direction.
+//
+// Optional `padToMultipleOfOpt` rounds targetLength up to a multiple of the
+// given value when it is greater than zero.
+//
+// It modifies e in place and returns it. See `Padded` to keep e unchanged.
+func (e *Encoding) Pad(targetLength, padId, padTypeId int, padToken string, direction PaddingDirection, padToMultipleOfOpt ...int) *Encoding {
+	if len(padToMultipleOfOpt) > 0 {
+		targetLength = padToMultipleOf(targetLength, padToMultipleOfOpt[0])
+	}
+
 	// 1. Overflowing
 	var overflowing []Encoding
 	for _, o := range e.Overflowing {
@@ -567,115 +982,167 @@ func (e *Encoding) Pad(targetLength, padId, padTypeId int, padToken string, dire
 	return paddedEn
 }
 
-func (e *Encoding) pad(targetLength, padId, padTypeId int, padToken string, direction PaddingDirection) *Encoding {
-	padLength := targetLength - len(e.Ids)
+// Padded returns a padded copy of e, leaving e unchanged. See `Pad`.
+func (e *Encoding) Padded(targetLength, padId, padTypeId int, padToken string, direction PaddingDirection, padToMultipleOfOpt ...int) *Encoding {
+	return e.Clone().Pad(targetLength, padId, padTypeId, padToken, direction, padToMultipleOfOpt...)
+}
 
-	switch direction {
-	case Left:
-		newIds := make([]int, padLength)
-		for i := 0; i < len(newIds); i++ {
-			newIds[i] = padId
-		}
-		newIds = append(newIds, e.Ids...)
-		e.Ids = newIds
+// padToMultipleOf rounds length up to a multiple of `multiple` if greater
+// than zero.
+func padToMultipleOf(length, multiple int) int {
+	if multiple <= 0 || length%multiple == 0 {
+		return length
+	}
 
-		newTypeIds := make([]int, padLength)
-		for i := 0; i < len(newTypeIds); i++ {
-			newTypeIds[i] = padTypeId
-		}
-		newTypeIds = append(newTypeIds, e.Ids...)
-		e.TypeIds = newTypeIds
+	return (length/multiple + 1) * multiple
+}
 
-		newTokens := make([]string, padLength)
-		for i := 0; i < len(newTokens); i++ {
-			newTokens[i] = padToken
-		}
-		newTokens = append(newTokens, e.Tokens...)
-		e.Tokens = newTokens
+// FitToLength truncates current encoding if it is longer than given length,
+// or pads it if it is shorter. Overflowing tokens from truncation are kept in
+// `Overflowing` and padded to the same length. It modifies e in place.
+func (e *Encoding) FitToLength(length, padId, padTypeId int, padToken string, direction PaddingDirection) (*Encoding, error) {
+	truncated, err := e.Truncate(length, 0)
+	if err != nil {
+		return nil, err
+	}
 
-		newSpecialTokenMask := make([]int, padLength)
-		for i := 0; i < len(newSpecialTokenMask); i++ {
-			newSpecialTokenMask[i] = 1
-		}
-		newSpecialTokenMask = append(newSpecialTokenMask, e.SpecialTokenMask...)
-		e.SpecialTokenMask = newSpecialTokenMask
+	return truncated.Pad(length, padId, padTypeId, padToken, direction), nil
+}
 
-		newAttentionMask := make([]int, padLength)
-		for i := 0; i < len(newAttentionMask); i++ {
-			newAttentionMask[i] = 0
-		}
-		newAttentionMask = append(newAttentionMask, e.AttentionMask...)
-		e.AttentionMask = newAttentionMask
+func (e *Encoding) pad(targetLength, padId, padTypeId int, padToken string, direction PaddingDirection) *Encoding {
+	// Longer encodings, e.g. overflowings of a larger truncation, are kept
+	// as they are.
+	padLength := targetLength - len(e.Ids)
+	if padLength <= 0 {
+		return e
+	}
 
-		newOffsets := make([][]int, padLength)
-		for i := 0; i < len(newIds); i++ {
-			newOffsets[i] = []int{0, 0}
-		}
-		newOffsets = append(newOffsets, e.Offsets...)
-		e.Offsets = newOffsets
+	// Pad positions, either before or after the current tokens.
+	pads, toks := 0, padLength
+	if direction == Right {
+		pads, toks = len(e.Ids), 0
+	}
 
-		newWords := make([]int, padLength)
-		for i := 0; i < len(newWords); i++ {
-			newWords[i] = -1
-		}
-		newWords = append(newWords, e.Words...)
-		e.Words = newWords
-
-	case Right:
-		for i := 0; i < padLength; i++ {
-			e.Ids = append(e.Ids, padId)
-			e.TypeIds = append(e.TypeIds, padTypeId)
-			e.Tokens = append(e.Tokens, padToken)
-			e.SpecialTokenMask = append(e.SpecialTokenMask, 1)
-			e.AttentionMask = append(e.AttentionMask, 0)
-			e.Offsets = append(e.Offsets, []int{0, 0})
-			e.Words = append(e.Words, -1)
+	var en Encoding
+	en.alloc(targetLength)
+	for i := range en.Words {
+		en.Words[i] = -1
+	}
+	for i := pads; i < pads+padLength; i++ {
+		en.Ids[i] = padId
+		en.TypeIds[i] = padTypeId
+		en.Tokens[i] = padToken
+		en.SpecialTokenMask[i] = 1
+	}
+	copy(en.Ids[toks:], e.Ids)
+	copy(en.TypeIds[toks:], e.TypeIds)
+	copy(en.Tokens[toks:], e.Tokens)
+	copy(en.SpecialTokenMask[toks:], e.SpecialTokenMask)
+	copy(en.AttentionMask[toks:], e.AttentionMask)
+	if len(e.Words) == len(e.Ids) {
+		copy(en.Words[toks:], e.Words)
+	}
+	for i, o := range e.Offsets {
+		copy(en.Offsets[toks+i], o)
+	}
+	if toks > 0 && len(e.SequenceRanges) > 0 {
+		sequenceRanges := make(map[int]Range, len(e.SequenceRanges))
+		for seqId, r := range e.SequenceRanges {
+			shifted := make(Range, len(r))
+			for i, tok := range r {
+				shifted[i] = tok + toks
+			}
+			sequenceRanges[seqId] = shifted
 		}
+		e.SequenceRanges = sequenceRanges
 	}
 
+	e.Ids = en.Ids
+	e.TypeIds = en.TypeIds
+	e.Tokens = en.Tokens
+	e.Offsets = en.Offsets
+	e.SpecialTokenMask = en.SpecialTokenMask
+	e.AttentionMask = en.AttentionMask
+	e.Words = en.Words
+	e.resetIndex()
+
 	return e
 }
 
-func getCurrentPart(previous, current interface{}, size, idx, stride int) interface{} {
+// TrimToAttention returns a new encoding with all padding positions
+// (ie. `AttentionMask == 0`) removed. It is the inverse of `Pad` and works
+// for both left and right padding. Overflowing encodings are trimmed as well.
+func (e *Encoding) TrimToAttention() *Encoding {
+	var overflowing []Encoding
+	for _, o := range e.Overflowing {
+		overflowing = append(overflowing, *o.TrimToAttention())
+	}
 
-	switch current.(type) {
-	case []int:
-		var curr, prev []int
-		if int((idx+1)*size) > reflect.ValueOf(current).Len() {
-			curr = current.([]int)[(idx * size):]
-		} else {
-			curr = current.([]int)[(idx * size) : (idx+1)*size]
+	trimmed := &Encoding{
+		Overflowing:    overflowing,
+		SequenceRanges: make(map[int]Range, len(e.SequenceRanges)),
+		Normalized:     e.Normalized,
+	}
+	if e.Meta != nil {
+		trimmed.Meta = make(map[string]interface{}, len(e.Meta))
+		for k, v := range e.Meta {
+			trimmed.Meta[k] = v
 		}
-		prev = previous.([]int)[len(previous.([]int))-stride:]
-		return append(prev, curr...)
-	case []string:
-		var curr, prev []string
-		if (idx+1)*size > reflect.ValueOf(current).Len() {
-			curr = current.([]string)[(idx * size):]
-		} else {
-			curr = current.([]string)[(idx * size) : (idx+1)*size]
+	}
+
+	// Index of each kept token in trimmed, -1 if removed
+	newIndex := make([]int, len(e.AttentionMask))
+	for i, mask := range e.AttentionMask {
+		if mask == 0 {
+			newIndex[i] = -1
+			continue
 		}
-		prev = previous.([]string)[len(previous.([]string))-stride:]
-		return append(prev, curr...)
-	case [][]int:
-		var curr, prev [][]int
-		if (idx+1)*size > reflect.ValueOf(current).Len() {
-			curr = current.([][]int)[(idx * size):]
-		} else {
-			curr = current.([][]int)[(idx * size) : (idx+1)*size]
+		newIndex[i] = len(trimmed.Ids)
+		trimmed.Ids = append(trimmed.Ids, e.Ids[i])
+		trimmed.TypeIds = append(trimmed.TypeIds, e.TypeIds[i])
+		trimmed.Tokens = append(trimmed.Tokens, e.Tokens[i])
+		trimmed.Offsets = append(trimmed.Offsets, e.Offsets[i])
+		trimmed.SpecialTokenMask = append(trimmed.SpecialTokenMask, e.SpecialTokenMask[i])
+		trimmed.AttentionMask = append(trimmed.AttentionMask, mask)
+		if i < len(e.Words) {
+			trimmed.Words = append(trimmed.Words, e.Words[i])
+		}
+	}
+
+	for seqId, r := range e.SequenceRanges {
+		var kept Range
+		for _, tok := range r {
+			if tok >= 0 && tok < len(newIndex) && newIndex[tok] >= 0 {
+				kept = append(kept, newIndex[tok])
+			}
 		}
-		prev = previous.([][]int)[len(previous.([][]int))-stride:]
-		return append(prev, curr...)
-	default:
-		log.Fatalf("getCurrentPart method call: invalid type\n")
+		trimmed.SequenceRanges[seqId] = kept
 	}
 
-	return nil
+	return trimmed
 }
 
-// Token2Sequence returns the index of the sequence containing the given token.
+// chunkWithStride returns the overflowing part at index idx of a truncated
+// sequence: the last `stride` items of the previous part, followed by at most
+// `size` items of overflow. The returned slice never shares memory with its
+// inputs.
+func chunkWithStride[T any](previous, overflow []T, size, idx, stride int) []T {
+	start := idx * size
+	end := start + size
+	if end > len(overflow) {
+		end = len(overflow)
+	}
+
+	chunk := make([]T, 0, stride+end-start)
+	chunk = append(chunk, previous[len(previous)-stride:]...)
+	return append(chunk, overflow[start:end]...)
+}
+
+// Token2Sequence returns the index of the sequence containing the given
+// token. Tokens that do not belong to any sequence (ie. special tokens added
+// by a post-processor) are not found.
 func (e *Encoding) Token2Sequence(token int) (int, bool) {
-	if token > e.Len() {
+	if token < 0 || token >= e.Len() {
 		return -1, false
 	} else if len(e.SequenceRanges) == 0 {
 		return 0, true
@@ -693,11 +1160,18 @@ func (e *Encoding) Token2Sequence(token int) (int, bool) {
 // SequenceRange returns the range to target to retrieve something (word id, offsets, ...)
 // related to the given sequence id.
 func (e *Encoding) SequenceRange(sequencId int) (Range, error) {
+	if len(e.SequenceRanges) == 0 && sequencId == 0 {
+		if e.Len() == 0 {
+			return Range{}, nil
+		}
+		return NewRange(0, e.Len()), nil
+	}
+
 	r, ok := e.SequenceRanges[sequencId]
 	if !ok {
 		err := fmt.Errorf("input 'sequence_id' is out of range.\n")
 		return nil, err
 	}
 
-	return r[0:e.Len()], nil
+	return r, nil
 }
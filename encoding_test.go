@@ -1,11 +1,14 @@
 package tokenizer_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/processor"
 )
 
 func TestTokenizer_MergeWith(t *testing.T) {
@@ -218,9 +221,809 @@ func TestTokenizer_Mapping(t *testing.T) {
 	testMapping(t, word, 3)
 }
 
+func TestEncoding_SpecialTokenPositions(t *testing.T) {
+	encoding := tokenizer.DefaultEncoding()
+	encoding.Tokens = []string{"[CLS]", "a", "b", "[SEP]"}
+	encoding.Ids = []int{101, 1037, 1038, 102}
+	encoding.SpecialTokenMask = []int{1, 0, 0, 1}
+
+	got := encoding.SpecialTokenPositions()
+	want := []int{0, 3}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Want: %v\n", want)
+		t.Errorf("Got: %v\n", got)
+	}
+}
+
+func TestEncoding_MergeWithMaxOverflowing(t *testing.T) {
+	newEncoding := func(id int, nOverflowing int) *tokenizer.Encoding {
+		en := tokenizer.NewEncodingFromTokens([]tokenizer.Token{tokenizer.NewToken(id, "a", []int{0, 1})}, 0)
+		en.Words = []int{0}
+		for i := 0; i < nOverflowing; i++ {
+			o := tokenizer.NewEncodingFromTokens([]tokenizer.Token{tokenizer.NewToken(id+i+1, "b", []int{1, 2})}, 0)
+			o.Words = []int{0}
+			en.Overflowing = append(en.Overflowing, *o)
+		}
+		return en
+	}
+
+	// Without cap: 3 * (1 + 3) + 3 = 15 overflowings
+	got := newEncoding(1, 3).MergeWith(newEncoding(10, 3), false)
+	testMapping(t, len(got.Overflowing), 15)
+
+	// Capped by the truncation params of a tokenizer: sequences of 4 tokens
+	// cut to 1 token each have 3 overflowing parts.
+	tk := newWordLevelTokenizer()
+	tk.WithTruncation(&tokenizer.TruncationParams{MaxLength: 2, Strategy: tokenizer.LongestFirst, MaxOverflowing: 4})
+	en, err := tk.EncodePair("hello world how are", "you good day hello", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMapping(t, len(en.Overflowing), 4)
+	testMapping(t, en.Tokens, []string{"hello", "you"})
+
+	tk.WithTruncation(&tokenizer.TruncationParams{MaxLength: 2, Strategy: tokenizer.LongestFirst, MaxOverflowing: -1})
+	en, err = tk.EncodePair("hello world how are", "you good day hello", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMapping(t, len(en.Overflowing), 15)
+
+	// Nested overflowing encodings (merged overflowings with their own
+	// overflowings) are capped too.
+	tk.WithTruncation(&tokenizer.TruncationParams{MaxLength: 2, Strategy: tokenizer.LongestFirst, MaxOverflowing: 2})
+	en, err = tk.EncodePair("hello world how are", "you good day hello", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count func(en *tokenizer.Encoding, depth int) int
+	count = func(en *tokenizer.Encoding, depth int) int {
+		if len(en.Overflowing) > 2 {
+			t.Errorf("depth %v: want at most 2 overflowings, got %v", depth, len(en.Overflowing))
+		}
+		n := len(en.Overflowing)
+		for i := range en.Overflowing {
+			n += count(&en.Overflowing[i], depth+1)
+		}
+		return n
+	}
+	// The first part merged with the pair and its 2 capped overflowings,
+	// then the first part merged with the first pair overflowing.
+	if n := count(en, 0); n != 4 {
+		t.Errorf("want 4 overflowings in total, got %v", n)
+	}
+
+	// The cap survives cloning.
+	clone := en.Clone()
+	clone.Overflowing = nil
+	got = clone.MergeWith(newEncoding(10, 3), false)
+	testMapping(t, len(got.Overflowing), 2)
+}
+
+func TestEncoding_Meta(t *testing.T) {
+	type docId struct{ Id int }
+
+	encoding := tokenizer.NewEncodingFromTokens([]tokenizer.Token{
+		tokenizer.NewToken(1, "Hello", []int{0, 5}),
+		tokenizer.NewToken(2, "World", []int{6, 11}),
+	}, 0)
+	encoding.SetMeta("doc", docId{42})
+
+	clone := encoding.Clone()
+	got, ok := clone.GetMeta("doc")
+	if !ok {
+		t.Fatalf("Want metadata to survive Clone")
+	}
+	testMapping(t, got, docId{42})
+
+	// Metadata doesn't affect equality
+	other := tokenizer.NewEncodingFromTokens([]tokenizer.Token{
+		tokenizer.NewToken(1, "Hello", []int{0, 5}),
+		tokenizer.NewToken(2, "World", []int{6, 11}),
+	}, 0)
+	testMapping(t, encoding.Equal(other), true)
+
+	other.SetMeta("doc", docId{7})
+	testMapping(t, encoding.Equal(other), true)
+
+	other.Ids[1] = 3
+	testMapping(t, encoding.Equal(other), false)
+}
+
 func testMapping(t *testing.T, got, want interface{}) {
 	if !reflect.DeepEqual(want, got) {
 		t.Errorf("Want: %v\n", want)
 		t.Errorf("Got: %v\n", got)
 	}
 }
+
+func TestEncoding_TrimToAttention(t *testing.T) {
+	tk := newWordLevelTokenizer()
+
+	for _, direction := range []tokenizer.PaddingDirection{tokenizer.Right, tokenizer.Left} {
+		want, err := tk.EncodeSingle("hello world")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		en, err := tk.EncodeSingle("hello world")
+		if err != nil {
+			t.Fatal(err)
+		}
+		padded := en.Pad(5, 0, 0, "[PAD]", direction)
+		if padded.Len() != 5 {
+			t.Fatalf("want padded length 5, got %v\n", padded.Len())
+		}
+
+		got := padded.TrimToAttention()
+		if !want.Equal(got) {
+			t.Errorf("direction %v: want %#v\ngot %#v\n", direction, want, got)
+		}
+	}
+}
+
+func TestEncoding_PadToMultipleOf(t *testing.T) {
+	tk := newWordLevelTokenizer()
+
+	tests := []struct {
+		input    string
+		multiple int
+		wantLen  int
+	}{
+		{"hello world how", 4, 4},
+		{"hello world how are", 4, 4},
+		{"hello world how are you", 4, 8},
+		{"hello world how", 0, 3},
+	}
+
+	for _, tt := range tests {
+		en, err := tk.EncodeSingle(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := en.Pad(en.Len(), 0, 0, "[PAD]", tokenizer.Right, tt.multiple)
+		if got.Len() != tt.wantLen {
+			t.Errorf("%q padded to multiple of %v: want length %v, got %v\n", tt.input, tt.multiple, tt.wantLen, got.Len())
+		}
+	}
+
+	// Batch padding rounds the longest length up as well.
+	batch, err := tk.EncodeBatch([]tokenizer.EncodeInput{
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("hello world how")),
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("good day")),
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := tokenizer.DefaultPaddingParams()
+	params.PadToMultipleOf = 8
+	for _, en := range tokenizer.PadEncodings(batch, *params) {
+		if en.Len() != 8 {
+			t.Errorf("want length 8, got %v\n", en.Len())
+		}
+	}
+}
+
+func TestEncoding_FitToLength(t *testing.T) {
+	tk := newWordLevelTokenizer()
+
+	// 10 tokens => truncated
+	long, err := tk.EncodeSingle("hello world how are you good day hello world how")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := long.FitToLength(5, 0, 0, "[PAD]", tokenizer.Right)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantToks := []string{"hello", "world", "how", "are", "you"}
+	if !reflect.DeepEqual(wantToks, got.Tokens) {
+		t.Errorf("want %#v\ngot %#v\n", wantToks, got.Tokens)
+	}
+	wantOverflow := []string{"good", "day", "hello", "world", "how"}
+	if len(got.Overflowing) != 1 || !reflect.DeepEqual(wantOverflow, got.Overflowing[0].Tokens) {
+		t.Errorf("want overflowing %#v\ngot %#v\n", wantOverflow, got.Overflowing)
+	}
+
+	// 3 tokens => padded
+	short, err := tk.EncodeSingle("hello world how")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = short.FitToLength(5, 0, 0, "[PAD]", tokenizer.Right)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantToks = []string{"hello", "world", "how", "[PAD]", "[PAD]"}
+	if !reflect.DeepEqual(wantToks, got.Tokens) {
+		t.Errorf("want %#v\ngot %#v\n", wantToks, got.Tokens)
+	}
+	wantMask := []int{1, 1, 1, 0, 0}
+	if !reflect.DeepEqual(wantMask, got.AttentionMask) {
+		t.Errorf("want %#v\ngot %#v\n", wantMask, got.AttentionMask)
+	}
+}
+
+func TestEncoding_TokensWithText(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	tk.WithPostProcessor(processor.NewBertProcessing(processor.PostToken{Value: "[SEP]", Id: 3}, processor.PostToken{Value: "[CLS]", Id: 2}))
+
+	input := "hello  world, you"
+	en, err := tk.EncodeSingle(input, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	en = en.Pad(8, 0, 0, "[PAD]", tokenizer.Right)
+
+	got := en.TokensWithText(input)
+	want := []tokenizer.TokenText{
+		{Token: "hello", Text: "hello", Offsets: []int{0, 5}},
+		{Token: "[UNK]", Text: "world,", Offsets: []int{7, 13}},
+		{Token: "you", Text: "you", Offsets: []int{14, 17}},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+}
+
+func TestEncoding_SequenceIdsOverflowing(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	tk.WithTruncation(&tokenizer.TruncationParams{MaxLength: 3, Strategy: tokenizer.OnlyFirst})
+	en, err := tk.EncodePair("hello world how", "are", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(en.Overflowing) != 1 {
+		t.Fatalf("want 1 overflowing encoding, got %v", len(en.Overflowing))
+	}
+	o := en.Overflowing[0]
+	wantTokens := []string{"how", "are"}
+	if !reflect.DeepEqual(wantTokens, o.Tokens) {
+		t.Errorf("want %#v\ngot %#v\n", wantTokens, o.Tokens)
+	}
+	wantIds := []int{0, 1}
+	if got := o.GetSequenceIds(); !reflect.DeepEqual(wantIds, got) {
+		t.Errorf("want %#v\ngot %#v\n", wantIds, got)
+	}
+	if !reflect.DeepEqual(wantIds, o.TypeIds) {
+		t.Errorf("want type ids %#v\ngot %#v\n", wantIds, o.TypeIds)
+	}
+}
+
+func TestEncoding_SequenceIds(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	en, err := tk.EncodePair("hello world", "hello you", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantIds := []int{0, 0, 1, 1}
+	if got := en.GetSequenceIds(); !reflect.DeepEqual(wantIds, got) {
+		t.Errorf("want %#v\ngot %#v\n", wantIds, got)
+	}
+	if seq, ok := en.Token2Sequence(2); !ok || seq != 1 {
+		t.Errorf("want token 2 in sequence 1, got %v (%v)\n", seq, ok)
+	}
+	if _, ok := en.Token2Sequence(4); ok {
+		t.Errorf("want token 4 out of range\n")
+	}
+
+	// Words and chars are indexed per sequence.
+	tests := []struct {
+		word, sequenceId int
+		wantTokens       []int
+		wantChars        []int
+	}{
+		{0, 0, []int{0, 1}, []int{0, 5}},
+		{1, 0, []int{1, 2}, []int{6, 11}},
+		{0, 1, []int{2, 3}, []int{0, 5}},
+		{1, 1, []int{3, 4}, []int{6, 9}},
+	}
+	for _, tt := range tests {
+		start, end, ok := en.Word2Tokens(tt.word, tt.sequenceId)
+		if !ok || !reflect.DeepEqual(tt.wantTokens, []int{start, end}) {
+			t.Errorf("word %v/%v: want tokens %v, got %v (%v)\n", tt.word, tt.sequenceId, tt.wantTokens, []int{start, end}, ok)
+		}
+		chars, ok := en.Word2Chars(tt.word, tt.sequenceId)
+		if !ok || !reflect.DeepEqual(tt.wantChars, chars) {
+			t.Errorf("word %v/%v: want chars %v, got %v (%v)\n", tt.word, tt.sequenceId, tt.wantChars, chars, ok)
+		}
+	}
+
+	if token, ok := en.Char2Token(7, 1); !ok || token != 3 {
+		t.Errorf("want char 7 of sequence 1 in token 3, got %v (%v)\n", token, ok)
+	}
+	if word, ok := en.Char2Word(7, 1); !ok || word != 1 {
+		t.Errorf("want char 7 of sequence 1 in word 1, got %v (%v)\n", word, ok)
+	}
+	if _, _, ok := en.Word2Tokens(0, 2); ok {
+		t.Errorf("want no sequence 2\n")
+	}
+}
+
+func TestEncoding_WordToTokens(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	tk.WithPostProcessor(processor.NewBertProcessing(processor.PostToken{Value: "[SEP]", Id: 3}, processor.PostToken{Value: "[CLS]", Id: 2}))
+	en, err := tk.EncodeSingle("hello world how", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if start, end, ok := en.WordToTokens(1); !ok || start != 2 || end != 3 {
+		t.Errorf("want word 1 in tokens [2, 3), got [%v, %v) (%v)\n", start, end, ok)
+	}
+	if word, ok := en.TokenToWord(3); !ok || word != 2 {
+		t.Errorf("want token 3 in word 2, got %v (%v)\n", word, ok)
+	}
+	// special tokens have no word
+	if _, ok := en.TokenToWord(0); ok {
+		t.Errorf("want no word for [CLS]\n")
+	}
+	if word, ok := en.CharToWord(13); !ok || word != 2 {
+		t.Errorf("want char 13 in word 2, got %v (%v)\n", word, ok)
+	}
+
+	// Lookups follow changes of the encoding.
+	if _, err := en.Truncate(2, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := en.WordToTokens(1); ok {
+		t.Errorf("want word 1 truncated\n")
+	}
+	en.SetWord(1, 5)
+	if start, _, ok := en.WordToTokens(5); !ok || start != 1 {
+		t.Errorf("want word 5 at token 1, got %v (%v)\n", start, ok)
+	}
+}
+
+func TestEncoding_PaddedTruncated(t *testing.T) {
+	tk := newWordLevelTokenizer()
+
+	en, err := tk.EncodeSingle("hello world how are you")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := []string{"hello", "world", "how", "are", "you"}
+
+	padded := en.Padded(7, 0, 0, "[PAD]", tokenizer.Right)
+	wantToks := []string{"hello", "world", "how", "are", "you", "[PAD]", "[PAD]"}
+	if !reflect.DeepEqual(wantToks, padded.Tokens) {
+		t.Errorf("want %#v\ngot %#v\n", wantToks, padded.Tokens)
+	}
+
+	truncated, err := en.Truncated(3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantToks = []string{"hello", "world", "how"}
+	if !reflect.DeepEqual(wantToks, truncated.Tokens) {
+		t.Errorf("want %#v\ngot %#v\n", wantToks, truncated.Tokens)
+	}
+
+	if !reflect.DeepEqual(orig, en.Tokens) || len(en.Overflowing) != 0 {
+		t.Errorf("want unchanged %#v\ngot %#v\n", orig, en.Tokens)
+	}
+
+	// Padding a truncated encoding in place keeps its overflowing tokens.
+	if _, err := en.Truncate(3, 0); err != nil {
+		t.Fatal(err)
+	}
+	en.Pad(5, 0, 0, "[PAD]", tokenizer.Right)
+	wantOverflow := []string{"are", "you", "[PAD]", "[PAD]", "[PAD]"}
+	if len(en.Overflowing) != 1 || !reflect.DeepEqual(wantOverflow, en.Overflowing[0].Tokens) {
+		t.Errorf("want overflowing %#v\ngot %#v\n", wantOverflow, en.Overflowing)
+	}
+}
+
+func TestEncoding_TruncateStride(t *testing.T) {
+	tk := newWordLevelTokenizer()
+
+	en, err := tk.EncodeSingle("hello world how are you good day")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := en.Truncate(3, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"how", "are", "you"},
+		{"you", "good", "day"},
+	}
+	var got [][]string
+	for _, o := range en.Overflowing {
+		got = append(got, o.Tokens)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+
+	// Padding a part in place must not overwrite the next one.
+	en.Overflowing[0].Pad(5, 0, 0, "[PAD]", tokenizer.Right)
+	if !reflect.DeepEqual(want[1], en.Overflowing[1].Tokens) {
+		t.Errorf("want %#v\ngot %#v\n", want[1], en.Overflowing[1].Tokens)
+	}
+}
+
+func BenchmarkEncoding_Truncate(b *testing.B) {
+	tk := newWordLevelTokenizer()
+
+	input := strings.Repeat("hello world how are you good day ", 2000)
+	en, err := tk.EncodeSingle(input)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := en.Truncated(16, 2); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestEncoding_ToCharOffsets(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	seq, pair := "día hello", "héllo you"
+
+	byteEn, err := tk.EncodePair(seq, pair, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBytes := [][]int{{0, 4}, {5, 10}, {0, 6}, {7, 10}}
+	if !reflect.DeepEqual(wantBytes, byteEn.Offsets) {
+		t.Errorf("byte offsets: want %v, got %v", wantBytes, byteEn.Offsets)
+	}
+
+	tk.WithOffsetType(tokenizer.Char)
+	charEn, err := tk.EncodePair(seq, pair, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantChars := [][]int{{0, 3}, {4, 9}, {0, 5}, {6, 9}}
+	if !reflect.DeepEqual(wantChars, charEn.Offsets) {
+		t.Errorf("char offsets: want %v, got %v", wantChars, charEn.Offsets)
+	}
+
+	if got := byteEn.Clone().ToCharOffsets(seq, pair).Offsets; !reflect.DeepEqual(wantChars, got) {
+		t.Errorf("ToCharOffsets: want %v, got %v", wantChars, got)
+	}
+	if got := charEn.Clone().ToByteOffsets(seq, pair).Offsets; !reflect.DeepEqual(wantBytes, got) {
+		t.Errorf("ToByteOffsets: want %v, got %v", wantBytes, got)
+	}
+
+	// Lookups made before the conversion do not leave a stale index.
+	en := byteEn.Clone()
+	if tok, ok := en.CharToToken(9, 0); !ok || tok != 1 {
+		t.Errorf("byte 9: want token 1, got %v, %v", tok, ok)
+	}
+	en.ToCharOffsets(seq, pair)
+	if tok, ok := en.CharToToken(9, 0); ok {
+		t.Errorf("char 9: want no token, got %v", tok)
+	}
+	if tok, ok := en.CharToToken(4, 0); !ok || tok != 1 {
+		t.Errorf("char 4: want token 1, got %v, %v", tok, ok)
+	}
+	if got, ok := en.WordToChars(1, 0); !ok || !reflect.DeepEqual([]int{4, 9}, got) {
+		t.Errorf("word 1: want chars [4 9], got %v, %v", got, ok)
+	}
+}
+
+func TestTokenizer_EncodeInto(t *testing.T) {
+	plain := newWordLevelTokenizer()
+	processed := newWordLevelTokenizer()
+	processed.WithPostProcessor(processor.NewBertProcessing(processor.PostToken{Value: "[SEP]", Id: 3}, processor.PostToken{Value: "[CLS]", Id: 2}))
+	trunc := tokenizer.DefaultTruncationParams()
+	trunc.MaxLength = 6
+	processed.WithTruncation(trunc)
+	processed.WithPadding(&tokenizer.PaddingParams{
+		Strategy:  *tokenizer.NewPaddingStrategy(tokenizer.WithFixed(8)),
+		Direction: tokenizer.Left,
+		PadToken:  "[PAD]",
+	})
+	chunked := newWordLevelTokenizer()
+	chunked.WithMaxInputChars(10, tokenizer.ChunkLongInput)
+
+	inputs := []tokenizer.EncodeInput{
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("hello world how are you")),
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("good day")),
+		tokenizer.NewDualEncodeInput(tokenizer.NewInputSequence("hello world"), tokenizer.NewInputSequence("good day")),
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence([]string{"hello", "you"})),
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("")),
+	}
+
+	enc := tokenizer.GetEncoding()
+	defer tokenizer.PutEncoding(enc)
+
+	for name, tk := range map[string]*tokenizer.Tokenizer{"plain": plain, "processed": processed, "chunked": chunked} {
+		for i, in := range inputs {
+			want, err := tk.Encode(in, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tk.EncodeInto(in, enc, true); err != nil {
+				t.Fatal(err)
+			}
+			if !enc.Equal(want) {
+				t.Errorf("%s, input %v: want %+v\ngot %+v\n", name, i, want, enc)
+			}
+		}
+	}
+
+	enc.Reset()
+	if enc.Len() != 0 || len(enc.Offsets) != 0 || len(enc.SequenceRanges) != 0 {
+		t.Errorf("want an empty encoding, got %+v", enc)
+	}
+}
+
+func TestTokenizer_EncodeIntoAllocs(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	in := tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("hello world how are you good day"))
+
+	enc := tokenizer.GetEncoding()
+	defer tokenizer.PutEncoding(enc)
+	if err := tk.EncodeInto(in, enc, false); err != nil {
+		t.Fatal(err)
+	}
+
+	encodeAllocs := testing.AllocsPerRun(100, func() {
+		if _, err := tk.Encode(in, false); err != nil {
+			t.Fatal(err)
+		}
+	})
+	intoAllocs := testing.AllocsPerRun(100, func() {
+		if err := tk.EncodeInto(in, enc, false); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	// The encoding itself (its int data, tokens, offsets, struct and
+	// sequence ranges) and its merged copy are not allocated again.
+	if intoAllocs > encodeAllocs-10 {
+		t.Errorf("want at least 10 allocations less than Encode (%v), got %v", encodeAllocs, intoAllocs)
+	}
+}
+
+func TestEncoding_JSON(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	tk.WithPostProcessor(processor.NewBertProcessing(processor.PostToken{Value: "[SEP]", Id: 3}, processor.PostToken{Value: "[CLS]", Id: 2}))
+
+	en, err := tk.EncodePair("hello world", "good day", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(en)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"input_ids":[2,4,5,3,9,10,3],"token_type_ids":[0,0,0,0,1,1,1],"tokens":["[CLS]","hello","world","[SEP]","good","day","[SEP]"],"offset_mapping":[[0,0],[0,5],[6,11],[0,0],[0,4],[5,8],[0,0]],"special_tokens_mask":[1,0,0,1,0,0,1],"attention_mask":[1,1,1,1,1,1,1],"word_ids":[null,0,1,null,0,1,null],"sequence_ranges":{"0":[1,2],"1":[4,5]}}`
+	if string(data) != want {
+		t.Errorf("want %s\ngot  %s", want, data)
+	}
+
+	var got tokenizer.Encoding
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(en) {
+		t.Errorf("want %+v\ngot  %+v", en, got)
+	}
+
+	invalid := `{"input_ids":[1,2],"token_type_ids":[0],"tokens":["a","b"],"offset_mapping":[[0,1],[1,2]],"special_tokens_mask":[0,0],"attention_mask":[1,1]}`
+	if err := json.Unmarshal([]byte(invalid), &got); err == nil {
+		t.Errorf("want error on mismatched lengths")
+	}
+}
+
+func TestEncoding_WordIds(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	builder := processor.DefaultTemplateProcessing().Builder()
+	builder.NewSingle([]string{"[CLS]", "$0", "[SEP]"})
+	builder.NewPair("[CLS]:0 $A:0 [SEP]:0 $B:1 [SEP]:1")
+	builder.NewSpecialTokens([]tokenizer.Token{{Id: 2, Value: "[CLS]"}, {Id: 3, Value: "[SEP]"}})
+	tk.WithPostProcessor(builder.Build())
+	tk.WithPadding(&tokenizer.PaddingParams{
+		Strategy:  *tokenizer.NewPaddingStrategy(tokenizer.WithFixed(8)),
+		Direction: tokenizer.Left,
+		PadToken:  "[PAD]",
+	})
+
+	wordIds := func(words ...int) []*int {
+		out := make([]*int, len(words))
+		for i, w := range words {
+			if w >= 0 {
+				w := w
+				out[i] = &w
+			}
+		}
+		return out
+	}
+
+	single, err := tk.EncodeSingle("hello world how", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, err := tk.EncodePair("hello world", "how are", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Special tokens and padding are not part of any word, the pair sequence
+	// words are indexed from 0.
+	// [PAD] [PAD] [PAD] [CLS] hello world how [SEP]
+	// [PAD] [CLS] hello world [SEP] how are [SEP]
+	want := [][]*int{
+		wordIds(-1, -1, -1, -1, 0, 1, 2, -1),
+		wordIds(-1, -1, 0, 1, -1, 0, 1, -1),
+	}
+	got := tokenizer.BatchWordIds([]tokenizer.Encoding{*single, *pair})
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v\ngot %v", fmtWordIds(want), fmtWordIds(got))
+	}
+
+	// No word information
+	en := tokenizer.NewEncodingFromTokens([]tokenizer.Token{{Id: 4, Value: "hello", Offsets: []int{0, 5}}}, 0)
+	en.Pad(3, 0, 0, "[PAD]", tokenizer.Right)
+	if got, want := en.WordIds(), wordIds(-1, -1, -1); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", fmtWordIds([][]*int{want}), fmtWordIds([][]*int{got}))
+	}
+}
+
+func fmtWordIds(batch [][]*int) string {
+	var lines []string
+	for _, wordIds := range batch {
+		var words []string
+		for _, w := range wordIds {
+			if w == nil {
+				words = append(words, "nil")
+				continue
+			}
+			words = append(words, fmt.Sprint(*w))
+		}
+		lines = append(lines, strings.Join(words, " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestEncoding_CharSpanToTokenSpan(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	tk.WithPostProcessor(processor.NewBertProcessing(processor.PostToken{Value: "[SEP]", Id: 3}, processor.PostToken{Value: "[CLS]", Id: 2}))
+	// [CLS] how are you [SEP] hello world [SEP]
+	en, err := tk.EncodePair("how are you", "hello world", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		start, end int
+		sequenceId []int
+		want       []int
+		ok         bool
+	}{
+		{4, 7, nil, []int{2, 3}, true},          // "are"
+		{5, 9, nil, []int{2, 4}, true},          // "re y", partial overlaps
+		{0, 11, nil, []int{1, 4}, true},         // whole sequence, without special tokens
+		{3, 4, []int{0}, []int{-1, -1}, false},  // space between words
+		{6, 11, []int{1}, []int{6, 7}, true},    // "world"
+		{0, 20, []int{1}, []int{5, 7}, true},    // past the end
+		{7, 7, nil, []int{-1, -1}, false},       // empty span
+		{6, 11, []int{2}, []int{-1, -1}, false}, // no sequence 2
+	}
+	for _, tt := range tests {
+		start, end, ok := en.CharSpanToTokenSpan(tt.start, tt.end, tt.sequenceId...)
+		if ok != tt.ok || !reflect.DeepEqual(tt.want, []int{start, end}) {
+			t.Errorf("[%v, %v) %v: want %v (%v), got %v (%v)", tt.start, tt.end, tt.sequenceId, tt.want, tt.ok, []int{start, end}, ok)
+		}
+	}
+}
+
+func TestEncoding_Arena(t *testing.T) {
+	newTokens := func(n int) []tokenizer.Token {
+		toks := make([]tokenizer.Token, n)
+		for i := range toks {
+			toks[i] = tokenizer.NewToken(i, "a", []int{i, i + 1})
+		}
+		return toks
+	}
+	newEncoding := func(n int) *tokenizer.Encoding {
+		return tokenizer.NewEncodingFromTokens(newTokens(n), 0)
+	}
+
+	// Allocations do not depend on the number of tokens.
+	allocs := func(n int) float64 {
+		toks := newTokens(n)
+		return testing.AllocsPerRun(10, func() {
+			tokenizer.NewEncodingFromTokens(toks, 0).Pad(2*n, 0, 0, "[PAD]", tokenizer.Right)
+		})
+	}
+	if small, large := allocs(1), allocs(100); small != large {
+		t.Errorf("want allocations independent of length, got %v for 1 token, %v for 100 tokens", small, large)
+	}
+
+	// Growing a field does not overwrite the next one.
+	en := newEncoding(3)
+	en.Ids = append(en.Ids, 9)
+	en.Offsets[0] = append(en.Offsets[0], 9)
+	if want := []int{0, 0, 0}; !reflect.DeepEqual(want, en.TypeIds) {
+		t.Errorf("want %v, got %v", want, en.TypeIds)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(want, en.Offsets[1]) {
+		t.Errorf("want %v, got %v", want, en.Offsets[1])
+	}
+
+	en = newEncoding(2)
+	en.MergeWith(newEncoding(1), true)
+	en.Pad(5, 7, 1, "[PAD]", tokenizer.Left)
+	if want := []int{7, 7, 0, 1, 0}; !reflect.DeepEqual(want, en.Ids) {
+		t.Errorf("want %v, got %v", want, en.Ids)
+	}
+	if want := [][]int{{0, 0}, {0, 0}, {0, 1}, {1, 2}, {2, 3}}; !reflect.DeepEqual(want, en.Offsets) {
+		t.Errorf("want %v, got %v", want, en.Offsets)
+	}
+	if want := []int{0, 0, 1, 1, 1}; !reflect.DeepEqual(want, en.AttentionMask) {
+		t.Errorf("want %v, got %v", want, en.AttentionMask)
+	}
+}
+
+func TestEncoding_PadShorterTarget(t *testing.T) {
+	toks := []tokenizer.Token{
+		tokenizer.NewToken(1, "a", []int{0, 1}),
+		tokenizer.NewToken(2, "b", []int{1, 2}),
+		tokenizer.NewToken(3, "c", []int{2, 3}),
+	}
+	en := tokenizer.NewEncodingFromTokens(toks[:1], 0)
+	en.Overflowing = []tokenizer.Encoding{*tokenizer.NewEncodingFromTokens(toks, 0)}
+
+	en.Pad(2, 0, 0, "[PAD]", tokenizer.Left)
+
+	if want := []int{0, 1}; !reflect.DeepEqual(want, en.Ids) {
+		t.Errorf("want %v, got %v", want, en.Ids)
+	}
+	// Longer than the target length, the overflowing is left unchanged.
+	o := en.Overflowing[0]
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(want, o.Ids) {
+		t.Errorf("want %v, got %v", want, o.Ids)
+	}
+	if want := [][]int{{0, 1}, {1, 2}, {2, 3}}; !reflect.DeepEqual(want, o.Offsets) {
+		t.Errorf("want %v, got %v", want, o.Offsets)
+	}
+}
+
+func TestEncoding_TrimToAttentionLeftPadding(t *testing.T) {
+	tk := newWordLevelTokenizer()
+
+	en, err := tk.EncodePair("hello", "world", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	en.SetMeta("doc", 1)
+	en.Pad(4, 0, 0, "[PAD]", tokenizer.Left)
+	if want := []int{-1, -1, 0, 1}; !reflect.DeepEqual(want, en.GetSequenceIds()) {
+		t.Errorf("padded: want sequence ids %v, got %v", want, en.GetSequenceIds())
+	}
+
+	trimmed := en.TrimToAttention()
+	if want := []int{0, 1}; !reflect.DeepEqual(want, trimmed.GetSequenceIds()) {
+		t.Errorf("trimmed: want sequence ids %v, got %v", want, trimmed.GetSequenceIds())
+	}
+	if r, err := trimmed.SequenceRange(1); err != nil || !reflect.DeepEqual(tokenizer.Range{1}, r) {
+		t.Errorf("trimmed: want range [1] of sequence 1, got %v, %v", r, err)
+	}
+
+	// Maps are not shared with the source encoding.
+	trimmed.SetMeta("doc", 2)
+	trimmed.SequenceRanges[0] = tokenizer.Range{}
+	if v, _ := en.GetMeta("doc"); v != 1 {
+		t.Errorf("want source metadata 1, got %v", v)
+	}
+	if want := []int{-1, -1, 0, 1}; !reflect.DeepEqual(want, en.GetSequenceIds()) {
+		t.Errorf("source: want sequence ids %v, got %v", want, en.GetSequenceIds())
+	}
+}
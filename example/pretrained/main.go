@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"log"
 
-	"github.com/sugarme/tokenizer"
 	"github.com/sugarme/tokenizer/pretrained"
 )
 
@@ -21,13 +20,7 @@ func main() {
 	flag.Parse()
 
 	// any model with file `tokenizer.json` available. Eg. `tiiuae/falcon-7b`, `TheBloke/guanaco-7B-HF`, `mosaicml/mpt-7b-instruct`
-	// configFile, err := tokenizer.CachedPath("bert-base-uncased", "tokenizer.json")
-	configFile, err := tokenizer.CachedPath(modelName, "tokenizer.json")
-	if err != nil {
-		panic(err)
-	}
-
-	tk, err := pretrained.FromFile(configFile)
+	tk, err := pretrained.FromPretrained(modelName)
 	if err != nil {
 		panic(err)
 	}
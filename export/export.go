@@ -0,0 +1,148 @@
+// Package export writes batches of encodings to columnar formats (Apache
+// Arrow record batches and Parquet files) for data-loading pipelines and
+// dataset tooling such as Spark or DuckDB.
+//
+// Every encoding is a row with the columns:
+//
+//	input_ids       list<int32>
+//	attention_mask  list<int8>
+//	token_type_ids  list<int8>
+//	offsets         list<list<int32>>  // [start, end] pairs
+//
+// It lives in its own module so that the tokenizer library does not depend on
+// Arrow.
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+
+	"github.com/sugarme/tokenizer"
+)
+
+// Column names.
+const (
+	InputIds      = "input_ids"
+	AttentionMask = "attention_mask"
+	TypeIds       = "token_type_ids"
+	Offsets       = "offsets"
+)
+
+// Schema is the Arrow schema of exported encodings.
+var Schema = arrow.NewSchema([]arrow.Field{
+	{Name: InputIds, Type: arrow.ListOf(arrow.PrimitiveTypes.Int32)},
+	{Name: AttentionMask, Type: arrow.ListOf(arrow.PrimitiveTypes.Int8)},
+	{Name: TypeIds, Type: arrow.ListOf(arrow.PrimitiveTypes.Int8)},
+	{Name: Offsets, Type: arrow.ListOf(arrow.ListOf(arrow.PrimitiveTypes.Int32))},
+}, nil)
+
+// NewRecord builds an Arrow record batch with one row per encoding. The caller
+// must call `Release` on the returned record. A nil allocator defaults to
+// `memory.DefaultAllocator`.
+func NewRecord(mem memory.Allocator, encodings []tokenizer.Encoding) (arrow.Record, error) {
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+
+	b := array.NewRecordBuilder(mem, Schema)
+	defer b.Release()
+
+	var (
+		ids     = b.Field(0).(*array.ListBuilder)
+		mask    = b.Field(1).(*array.ListBuilder)
+		typeIds = b.Field(2).(*array.ListBuilder)
+		offsets = b.Field(3).(*array.ListBuilder)
+
+		idValues      = ids.ValueBuilder().(*array.Int32Builder)
+		maskValues    = mask.ValueBuilder().(*array.Int8Builder)
+		typeIdValues  = typeIds.ValueBuilder().(*array.Int8Builder)
+		offsetLists   = offsets.ValueBuilder().(*array.ListBuilder)
+		offsetsValues = offsetLists.ValueBuilder().(*array.Int32Builder)
+	)
+
+	for i, en := range encodings {
+		n := len(en.Ids)
+		if len(en.AttentionMask) != n || len(en.TypeIds) != n || len(en.Offsets) != n {
+			return nil, fmt.Errorf("NewRecord failed: encoding %d has inconsistent lengths", i)
+		}
+
+		ids.Append(true)
+		mask.Append(true)
+		typeIds.Append(true)
+		offsets.Append(true)
+		for j := 0; j < n; j++ {
+			idValues.Append(int32(en.Ids[j]))
+			maskValues.Append(int8(en.AttentionMask[j]))
+			typeIdValues.Append(int8(en.TypeIds[j]))
+
+			if len(en.Offsets[j]) != 2 {
+				return nil, fmt.Errorf("NewRecord failed: encoding %d has invalid offsets %v at index %d", i, en.Offsets[j], j)
+			}
+			offsetLists.Append(true)
+			offsetsValues.AppendValues([]int32{int32(en.Offsets[j][0]), int32(en.Offsets[j][1])}, nil)
+		}
+	}
+
+	return b.NewRecord(), nil
+}
+
+// ParquetWriter writes batches of encodings to a Parquet file, one row group
+// per batch.
+type ParquetWriter struct {
+	mem memory.Allocator
+	fw  *pqarrow.FileWriter
+}
+
+// NewParquetWriter creates a ParquetWriter writing to w. Data is compressed
+// with Snappy unless other writer properties are given.
+func NewParquetWriter(w io.Writer, props ...parquet.WriterProperty) (*ParquetWriter, error) {
+	if len(props) == 0 {
+		props = []parquet.WriterProperty{parquet.WithCompression(compress.Codecs.Snappy)}
+	}
+
+	mem := memory.DefaultAllocator
+	fw, err := pqarrow.NewFileWriter(Schema, w, parquet.NewWriterProperties(props...), pqarrow.NewArrowWriterProperties(pqarrow.WithAllocator(mem)))
+	if err != nil {
+		return nil, fmt.Errorf("NewParquetWriter failed: %w", err)
+	}
+
+	return &ParquetWriter{mem: mem, fw: fw}, nil
+}
+
+// Write writes a batch of encodings as a row group.
+func (pw *ParquetWriter) Write(encodings []tokenizer.Encoding) error {
+	rec, err := NewRecord(pw.mem, encodings)
+	if err != nil {
+		return err
+	}
+	defer rec.Release()
+
+	return pw.fw.Write(rec)
+}
+
+// Close flushes the Parquet footer and closes the underlying writer if it
+// is an io.Closer.
+func (pw *ParquetWriter) Close() error {
+	return pw.fw.Close()
+}
+
+// WriteParquet writes encodings to w as a Parquet file.
+func WriteParquet(w io.Writer, encodings []tokenizer.Encoding) error {
+	pw, err := NewParquetWriter(w)
+	if err != nil {
+		return err
+	}
+	if err := pw.Write(encodings); err != nil {
+		pw.Close()
+		return err
+	}
+
+	return pw.Close()
+}
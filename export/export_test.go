@@ -0,0 +1,104 @@
+package export_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/export"
+	"github.com/sugarme/tokenizer/model/wordlevel"
+	"github.com/sugarme/tokenizer/pretokenizer"
+)
+
+func encodings(t *testing.T) []tokenizer.Encoding {
+	vocab := map[string]int{"[UNK]": 0, "hello": 1, "world": 2, "how": 3, "are": 4, "you": 5}
+	model, err := wordlevel.New(vocab, "[UNK]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := tokenizer.NewTokenizer(model)
+	tk.WithPreTokenizer(pretokenizer.NewWhitespaceSplit())
+
+	ens, err := tk.EncodeBatch([]tokenizer.EncodeInput{
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("hello world")),
+		tokenizer.NewDualEncodeInput(tokenizer.NewInputSequence("how are"), tokenizer.NewInputSequence("you")),
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ens
+}
+
+func TestNewRecord(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	rec, err := export.NewRecord(mem, encodings(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+
+	if rec.NumRows() != 2 || rec.NumCols() != 4 {
+		t.Fatalf("want 2 rows and 4 columns, got %d and %d", rec.NumRows(), rec.NumCols())
+	}
+
+	want := []string{
+		`[[1 2] [3 4 5]]`,
+		`[[1 1] [1 1 1]]`,
+		`[[0 0] [0 0 1]]`,
+		`[[[0 5] [6 11]] [[0 3] [4 7] [0 3]]]`,
+	}
+	for i, col := range rec.Columns() {
+		if got := col.String(); got != want[i] {
+			t.Errorf("%s: want %s, got %s", rec.ColumnName(i), want[i], got)
+		}
+	}
+
+	_, err = export.NewRecord(mem, []tokenizer.Encoding{{Ids: []int{1}}})
+	if err == nil {
+		t.Errorf("want error on inconsistent encoding")
+	}
+}
+
+func TestWriteParquet(t *testing.T) {
+	var buf bytes.Buffer
+	if err := export.WriteParquet(&buf, encodings(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	rdr, err := file.NewParquetReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Close()
+
+	fr, err := pqarrow.NewFileReader(rdr, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbl, err := fr.ReadTable(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tbl.Release()
+
+	for i, f := range export.Schema.Fields() {
+		if got := tbl.Schema().Field(i).Name; got != f.Name {
+			t.Errorf("column %d: want %q, got %q", i, f.Name, got)
+		}
+	}
+
+	ids := tbl.Column(0).Data().Chunk(0).(*array.List)
+	values := ids.ListValues().(*array.Int32)
+	if got, want := values.Int32Values(), []int32{1, 2, 3, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("input_ids: want %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,265 @@
+package tokenizer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// This file provides downloading of files from the HuggingFace Hub, as the
+// Python `huggingface_hub.hf_hub_download` does. See
+// `pretrained.FromPretrained` to load a tokenizer from the Hub.
+
+var (
+	// ErrFileNotFound is returned, wrapped, when a repo has no such file.
+	ErrFileNotFound = errors.New("file not found")
+	// ErrNotCached is returned, wrapped, when a file is not cached in
+	// offline mode.
+	ErrNotCached = errors.New("file not cached")
+)
+
+// HubOpts are options of downloading files from the HuggingFace Hub.
+type HubOpts struct {
+	// Branch, tag or commit hash of the repo (default = "main")
+	Revision string
+	// Token of private or gated repos (default = $HF_TOKEN)
+	AuthToken string
+	// Whether to use cached files only (default = $HF_HUB_OFFLINE)
+	Offline bool
+	// Directory of downloaded files (default = `CachedDir`)
+	CacheDir string
+	// URL of the Hub (default = $HF_ENDPOINT or `HFpath`)
+	Endpoint string
+}
+
+type HubOpt func(o *HubOpts)
+
+// DefaultHubOpts returns the hub options set by the environment, as for the
+// Python library: `HF_TOKEN`, `HF_HUB_OFFLINE` and `HF_ENDPOINT`.
+func DefaultHubOpts() *HubOpts {
+	endpoint := HFpath
+	if v := os.Getenv("HF_ENDPOINT"); v != "" {
+		endpoint = v
+	}
+	offline := false
+	switch strings.ToLower(os.Getenv("HF_HUB_OFFLINE")) {
+	case "1", "true", "yes", "on":
+		offline = true
+	}
+
+	return &HubOpts{
+		Revision:  "main",
+		AuthToken: os.Getenv("HF_TOKEN"),
+		Offline:   offline,
+		CacheDir:  CachedDir,
+		Endpoint:  endpoint,
+	}
+}
+
+// WithRevision pins the branch, tag or commit hash of the repo.
+func WithRevision(revision string) HubOpt {
+	return func(o *HubOpts) {
+		o.Revision = revision
+	}
+}
+
+// WithAuthToken sets the token to access private or gated repos.
+func WithAuthToken(token string) HubOpt {
+	return func(o *HubOpts) {
+		o.AuthToken = token
+	}
+}
+
+// WithOffline sets whether to use cached files only, never the network.
+func WithOffline(offline bool) HubOpt {
+	return func(o *HubOpts) {
+		o.Offline = offline
+	}
+}
+
+// WithCacheDir sets the directory of downloaded files.
+func WithCacheDir(dir string) HubOpt {
+	return func(o *HubOpts) {
+		o.CacheDir = dir
+	}
+}
+
+// WithEndpoint sets the URL of the Hub, e.g. a mirror.
+func WithEndpoint(endpoint string) HubOpt {
+	return func(o *HubOpts) {
+		o.Endpoint = endpoint
+	}
+}
+
+// HubFile returns the local path to a file of a HuggingFace Hub repo,
+// downloading it unless cached.
+//
+// Params:
+// - repoId: repo name, e.g. "bert-base-uncased" or "google/flan-t5-base",
+// or path to a local directory holding the file.
+// - fileName: name of the file in the repo, e.g. "tokenizer.json"
+// - opts: optional hub options (see `DefaultHubOpts`)
+//
+// Cached files are checked against their sha256. Unless the revision is a
+// commit hash or in offline mode, they are also revalidated against the Hub
+// with their ETag, and used as is if the Hub cannot be reached. See
+// `ScanCache` for the layout of the cache.
+func HubFile(repoId, fileName string, opts ...HubOpt) (string, error) {
+	o := DefaultHubOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// Local directory
+	if info, err := os.Stat(repoId); err == nil && info.IsDir() {
+		file := filepath.Join(repoId, fileName)
+		if _, err := os.Stat(file); err != nil {
+			return "", fmt.Errorf("HubFile() failed: %w: %q", ErrFileNotFound, file)
+		}
+		return file, nil
+	}
+
+	// Each part is checked so that the file cannot be written out of the
+	// cache.
+	parts := []string{o.CacheDir}
+	for _, p := range []string{repoId, strings.ReplaceAll(o.Revision, "/", "--"), fileName} {
+		rel, err := cacheRelPath(p)
+		if err != nil {
+			return "", fmt.Errorf("HubFile() failed: %w", err)
+		}
+		parts = append(parts, rel)
+	}
+	cachedFile := filepath.Join(parts...)
+	meta := &cacheMeta{
+		RepoId:   repoId,
+		Revision: o.Revision,
+		FileName: fileName,
+		URL:      fmt.Sprintf("%s/%s/resolve/%s/%s", strings.TrimSuffix(o.Endpoint, "/"), repoId, url.PathEscape(o.Revision), fileName),
+	}
+
+	cached := false
+	if _, err := os.Stat(cachedFile); err == nil {
+		cachedMeta, _ := readMeta(cachedFile)
+		err := verifyCached(cachedFile, cachedMeta)
+		switch {
+		case err == nil:
+			cached = true
+			if cachedMeta != nil {
+				meta.ETag = cachedMeta.ETag
+			}
+		case o.Offline:
+			return "", fmt.Errorf("HubFile() failed: %w", err)
+		default:
+			removeCached(cachedFile)
+		}
+	}
+
+	switch {
+	case cached && (o.Offline || isCommitHash(o.Revision)):
+		return cachedFile, nil
+	case o.Offline:
+		return "", fmt.Errorf("HubFile() failed: %w in offline mode: %q", ErrNotCached, cachedFile)
+	}
+
+	if err := hubDownload(cachedFile, meta, o.AuthToken); err != nil {
+		// Offline usage of cached files, unless removed from the repo
+		if cached && !errors.Is(err, ErrFileNotFound) {
+			return cachedFile, nil
+		}
+		return "", fmt.Errorf("HubFile() failed: %w", err)
+	}
+
+	return cachedFile, nil
+}
+
+// hubDownload downloads meta.URL to file, unless not modified since it was
+// cached with meta.ETag. It writes to a temporary file first so that a
+// failed download does not leave a partial file in the cache, and checks the
+// data against the ETag if it is a sha256 (files stored with git LFS).
+func hubDownload(file string, meta *cacheMeta, authToken string) error {
+	req, err := http.NewRequest(http.MethodGet, meta.URL, nil)
+	if err != nil {
+		return err
+	}
+	if authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+authToken)
+	}
+	if meta.ETag != "" {
+		req.Header.Set("If-None-Match", `"`+meta.ETag+`"`)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %q", ErrFileNotFound, meta.URL)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("access denied to %q (%s): private or gated repo needs an auth token", meta.URL, resp.Status)
+	default:
+		return fmt.Errorf("downloading %q failed: %s", meta.URL, resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), resp.Body)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	meta.Sha256 = hex.EncodeToString(h.Sum(nil))
+	meta.Size = size
+	meta.Time = time.Now()
+	meta.ETag = responseETag(resp)
+	if resp.ContentLength >= 0 && resp.ContentLength != size {
+		return fmt.Errorf("downloading %q failed: got %v bytes, want %v", meta.URL, size, resp.ContentLength)
+	}
+	if isSha256(meta.ETag) && meta.ETag != meta.Sha256 {
+		return fmt.Errorf("downloading %q failed: %w: sha256 %s, want %s", meta.URL, ErrCorruptedCache, meta.Sha256, meta.ETag)
+	}
+
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return err
+	}
+
+	return writeMeta(file, meta)
+}
+
+// responseETag returns the ETag of a Hub file: the sha256 of LFS files
+// (`X-Linked-Etag` as the response may be a redirect), the git hash of
+// others.
+func responseETag(resp *http.Response) string {
+	etag := resp.Header.Get("X-Linked-Etag")
+	if etag == "" {
+		etag = resp.Header.Get("ETag")
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+
+	return strings.Trim(etag, `"`)
+}
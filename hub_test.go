@@ -0,0 +1,93 @@
+package tokenizer_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+)
+
+func TestHubFile(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/org/model/resolve/v1.0/tokenizer.json":
+			w.Write([]byte(`{"version": "1.0"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	opts := []tokenizer.HubOpt{
+		tokenizer.WithEndpoint(server.URL),
+		tokenizer.WithCacheDir(cacheDir),
+		tokenizer.WithRevision("v1.0"),
+		tokenizer.WithAuthToken("secret"),
+		tokenizer.WithOffline(false),
+	}
+
+	file, err := tokenizer.HubFile("org/model", "tokenizer.json", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(cacheDir, "org/model/v1.0/tokenizer.json"); file != want {
+		t.Errorf("want %q, got %q", want, file)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil || string(data) != `{"version": "1.0"}` {
+		t.Errorf("want downloaded data, got %q, %v", data, err)
+	}
+
+	// Revalidated, as a tag may be moved
+	if _, err := tokenizer.HubFile("org/model", "tokenizer.json", opts...); err != nil || requests != 2 {
+		t.Errorf("want revalidated file, got %v requests, %v", requests, err)
+	}
+	if _, err := tokenizer.HubFile("org/model", "tokenizer.json", append(opts, tokenizer.WithOffline(true))...); err != nil {
+		t.Errorf("want cached file in offline mode, got %v", err)
+	}
+
+	_, err = tokenizer.HubFile("org/model", "vocab.txt", opts...)
+	if !errors.Is(err, tokenizer.ErrFileNotFound) {
+		t.Errorf("want ErrFileNotFound, got %v", err)
+	}
+	_, err = tokenizer.HubFile("org/model", "vocab.txt", append(opts, tokenizer.WithOffline(true))...)
+	if !errors.Is(err, tokenizer.ErrNotCached) {
+		t.Errorf("want ErrNotCached, got %v", err)
+	}
+	_, err = tokenizer.HubFile("org/model", "tokenizer.json", append(opts, tokenizer.WithRevision("main"), tokenizer.WithAuthToken(""))...)
+	if err == nil {
+		t.Errorf("want error without auth token")
+	}
+
+	// Paths out of the cache are rejected, before any request.
+	requests = 0
+	for _, tt := range [][2]string{
+		{"org/model", "../../x"},
+		{"../org", "tokenizer.json"},
+		{"/org/model", "tokenizer.json"},
+	} {
+		if _, err := tokenizer.HubFile(tt[0], tt[1], opts...); err == nil {
+			t.Errorf("%q, %q: want error", tt[0], tt[1])
+		}
+	}
+	if _, err := tokenizer.HubFile("org/model", "tokenizer.json", append(opts, tokenizer.WithRevision(".."))...); err == nil {
+		t.Errorf("revision \"..\": want error")
+	}
+	if requests != 0 {
+		t.Errorf("want no request, got %v", requests)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(cacheDir), "x")); !os.IsNotExist(err) {
+		t.Errorf("want no file written out of the cache, got %v", err)
+	}
+}
@@ -12,9 +12,9 @@ var (
 )
 
 func init() {
-	// default path: {$HOME}/.cache/tokenizer
+	// default path: {$HOME}/.cache/gotokenizer
 	homeDir := os.Getenv("HOME")
-	CachedDir = fmt.Sprintf("%s/.cache/tokenizer", homeDir)
+	CachedDir = fmt.Sprintf("%s/.cache/gotokenizer", homeDir)
 
 	initEnv()
 
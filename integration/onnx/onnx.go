@@ -0,0 +1,118 @@
+// Package onnx prepares tokenizer encodings as inputs of ONNX models and
+// decodes their generated ids.
+//
+// Inputs are int64 flat (row-major) buffers with their shape, as expected by
+// onnxruntime-go tensors:
+//
+//	inputs, err := onnx.NewInputs(encodings)
+//	ids, err := ort.NewTensor(ort.NewShape(inputs.Shape...), inputs.InputIds)
+//
+// The package does not depend on onnxruntime itself.
+package onnx
+
+import (
+	"fmt"
+
+	"github.com/sugarme/tokenizer"
+)
+
+// Standard input names of transformer models exported to ONNX.
+const (
+	InputIdsName      = "input_ids"
+	AttentionMaskName = "attention_mask"
+	TokenTypeIdsName  = "token_type_ids"
+)
+
+// Inputs holds a batch of encodings as flat int64 buffers of shape
+// (batch size, sequence length).
+type Inputs struct {
+	InputIds      []int64
+	AttentionMask []int64
+	TokenTypeIds  []int64
+	Shape         []int64
+}
+
+// NewInputs flattens a batch of encodings. All encodings must have the same
+// length, ie. be padded (see `Tokenizer.WithPadding` or
+// `tokenizer.PadEncodings`).
+func NewInputs(encodings []tokenizer.Encoding) (*Inputs, error) {
+	if len(encodings) == 0 {
+		return nil, fmt.Errorf("NewInputs failed: empty batch")
+	}
+
+	seqLen := encodings[0].Len()
+	size := len(encodings) * seqLen
+	in := &Inputs{
+		InputIds:      make([]int64, 0, size),
+		AttentionMask: make([]int64, 0, size),
+		TokenTypeIds:  make([]int64, 0, size),
+		Shape:         []int64{int64(len(encodings)), int64(seqLen)},
+	}
+
+	for i, en := range encodings {
+		if en.Len() != seqLen {
+			return nil, fmt.Errorf("NewInputs failed: encoding %d has length %d, want %d (encodings must be padded)", i, en.Len(), seqLen)
+		}
+		if len(en.AttentionMask) != seqLen || len(en.TypeIds) != seqLen {
+			return nil, fmt.Errorf("NewInputs failed: encoding %d has inconsistent lengths", i)
+		}
+
+		for j := 0; j < seqLen; j++ {
+			in.InputIds = append(in.InputIds, int64(en.Ids[j]))
+			in.AttentionMask = append(in.AttentionMask, int64(en.AttentionMask[j]))
+			in.TokenTypeIds = append(in.TokenTypeIds, int64(en.TypeIds[j]))
+		}
+	}
+
+	return in, nil
+}
+
+// Map returns the input buffers keyed by their standard input name. Models
+// without `token_type_ids` input (e.g. RoBERTa, DistilBERT) should drop it.
+func (in *Inputs) Map() map[string][]int64 {
+	return map[string][]int64{
+		InputIdsName:      in.InputIds,
+		AttentionMaskName: in.AttentionMask,
+		TokenTypeIdsName:  in.TokenTypeIds,
+	}
+}
+
+// Ids splits a flat int64 output buffer of shape (batch size, sequence
+// length), e.g. generated ids, into one slice of ids per sequence. A 1-D
+// shape is read as a single sequence.
+func Ids(data []int64, shape []int64) ([][]int, error) {
+	var rows, cols int64
+	switch len(shape) {
+	case 1:
+		rows, cols = 1, shape[0]
+	case 2:
+		rows, cols = shape[0], shape[1]
+	default:
+		return nil, fmt.Errorf("Ids failed: want a 1-D or 2-D shape, got %v", shape)
+	}
+	if rows < 0 || cols < 0 || rows*cols != int64(len(data)) {
+		return nil, fmt.Errorf("Ids failed: shape %v doesn't match %d values", shape, len(data))
+	}
+
+	ids := make([][]int, rows)
+	for i := range ids {
+		row := make([]int, cols)
+		for j := range row {
+			row[j] = int(data[int64(i)*cols+int64(j)])
+		}
+		ids[i] = row
+	}
+
+	return ids, nil
+}
+
+// Decode decodes a flat int64 output buffer of given shape (see `Ids`) into
+// one string per sequence.
+func Decode(tk *tokenizer.Tokenizer, data []int64, shape []int64, skipSpecialTokens bool) ([]string, error) {
+	ids, err := Ids(data, shape)
+	if err != nil {
+		return nil, err
+	}
+
+	return tk.DecodeBatch(ids, skipSpecialTokens), nil
+}
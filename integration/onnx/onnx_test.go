@@ -0,0 +1,86 @@
+package onnx_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/integration/onnx"
+	"github.com/sugarme/tokenizer/model/wordlevel"
+	"github.com/sugarme/tokenizer/pretokenizer"
+)
+
+func newTokenizer(t *testing.T) *tokenizer.Tokenizer {
+	vocab := map[string]int{"[PAD]": 0, "[UNK]": 1, "hello": 2, "world": 3, "how": 4, "are": 5, "you": 6}
+	model, err := wordlevel.New(vocab, "[UNK]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := tokenizer.NewTokenizer(model)
+	tk.WithPreTokenizer(pretokenizer.NewWhitespaceSplit())
+	tk.AddSpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[PAD]", true)})
+
+	return tk
+}
+
+func TestNewInputs(t *testing.T) {
+	tk := newTokenizer(t)
+	tk.WithPadding(tokenizer.DefaultPaddingParams())
+
+	encodings, err := tk.EncodeBatch([]tokenizer.EncodeInput{
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("hello world")),
+		tokenizer.NewDualEncodeInput(tokenizer.NewInputSequence("how are"), tokenizer.NewInputSequence("you")),
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in, err := onnx.NewInputs(encodings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &onnx.Inputs{
+		InputIds:      []int64{2, 3, 0, 4, 5, 6},
+		AttentionMask: []int64{1, 1, 0, 1, 1, 1},
+		TokenTypeIds:  []int64{0, 0, 0, 0, 0, 1},
+		Shape:         []int64{2, 3},
+	}
+	if !reflect.DeepEqual(want, in) {
+		t.Errorf("want %+v\ngot  %+v", want, in)
+	}
+	if got := in.Map()[onnx.AttentionMaskName]; !reflect.DeepEqual(want.AttentionMask, got) {
+		t.Errorf("want %v, got %v", want.AttentionMask, got)
+	}
+
+	encodings[0].Ids = encodings[0].Ids[:2]
+	if _, err := onnx.NewInputs(encodings); err == nil {
+		t.Errorf("want error on unpadded batch")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tk := newTokenizer(t)
+
+	got, err := onnx.Decode(tk, []int64{2, 3, 0, 4, 5, 6}, []int64{2, 3}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"hello world", "how are you"}; !reflect.DeepEqual(want, got) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	ids, err := onnx.Ids([]int64{4, 5}, []int64{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]int{{4, 5}}; !reflect.DeepEqual(want, ids) {
+		t.Errorf("want %v, got %v", want, ids)
+	}
+
+	for _, shape := range [][]int64{{2, 2}, {1, 2, 3}, {}} {
+		if _, err := onnx.Ids([]int64{1, 2, 3}, shape); err == nil {
+			t.Errorf("%v: want error", shape)
+		}
+	}
+}
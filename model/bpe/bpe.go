@@ -13,9 +13,11 @@ import (
 	// "strconv"
 	"log"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/sugarme/tokenizer"
 	"github.com/sugarme/tokenizer/model"
+	"github.com/sugarme/tokenizer/spm"
 	"github.com/sugarme/tokenizer/util"
 )
 
@@ -35,6 +37,8 @@ type Config struct {
 	unkToken                *string
 	continuingSubwordPrefix *string
 	endOfWordSuffix         *string
+	fuseUnk                 bool
+	byteFallback            bool
 }
 
 // BpeBuilder can be used to create a `BPE` model with
@@ -99,6 +103,16 @@ func (bb *BpeBuilder) EndOfWordSuffix(endOfWordSuffix string) {
 	bb.config.endOfWordSuffix = &endOfWordSuffix
 }
 
+// FuseUnk set the `fuseUnk` option.
+func (bb *BpeBuilder) FuseUnk(fuseUnk bool) {
+	bb.config.fuseUnk = fuseUnk
+}
+
+// ByteFallback set the `byteFallback` option.
+func (bb *BpeBuilder) ByteFallback(byteFallback bool) {
+	bb.config.byteFallback = byteFallback
+}
+
 // Build returns a `BPE` model that uses the BpeBuilder configuration
 func (bb *BpeBuilder) Build() (*BPE, error) {
 	var (
@@ -153,6 +167,8 @@ func (bb *BpeBuilder) Build() (*BPE, error) {
 		UnkToken:                bb.config.unkToken,
 		ContinuingSubwordPrefix: bb.config.continuingSubwordPrefix,
 		EndOfWordSuffix:         bb.config.endOfWordSuffix,
+		FuseUnk:                 bb.config.fuseUnk,
+		ByteFallback:            bb.config.byteFallback,
 	}
 
 	return &bpe, nil
@@ -190,6 +206,13 @@ type BPE struct {
 	// EndOfWordSuffix is an optional suffix
 	// to caracterize and end-of-word subword
 	EndOfWordSuffix *string
+
+	// FuseUnk fuses consecutive `unk` tokens into a single one.
+	FuseUnk bool
+
+	// ByteFallback encodes characters not found in the vocab as `<0xXX>` byte
+	// tokens (SentencePiece style) instead of `unk`.
+	ByteFallback bool
 }
 
 func (b *BPE) builder() *BpeBuilder {
@@ -208,9 +231,11 @@ func (b *BPE) new() {
 // `Clone` can't be derive because it's not implemented for `Cache`.
 // To keep things simple when we clone, the new BPE will start with a fresh cache.
 func (b *BPE) clone() {
-	newBpe := b
-	newBpe.Cache.Fresh()
-	b = newBpe
+	newBpe := *b
+	if b.Cache != nil {
+		newBpe.Cache = b.Cache.Fresh()
+	}
+	b = &newBpe
 }
 
 // newBPE create a default BPE from sratch using its pbeBuilder
@@ -230,6 +255,59 @@ func NewBpeFromFiles(vocab, merges string) (*BPE, error) {
 	return b.Build()
 }
 
+// NewBpeFromSentencePiece creates a BPE model from a SentencePiece `.model`
+// file of a BPE model.
+//
+// SentencePiece BPE models have no merges: as in HuggingFace conversion, each
+// piece that splits into two pieces of the vocab is a merge, ranked by the id
+// of the merged piece.
+func NewBpeFromSentencePiece(file string) (*BPE, error) {
+	m, err := spm.ReadModel(file)
+	if err != nil {
+		return nil, err
+	}
+	if m.TrainerSpec.ModelType != spm.BpeModel {
+		return nil, fmt.Errorf("BPE: SentencePiece model %q is not a BPE model (type %v)", file, m.TrainerSpec.ModelType)
+	}
+
+	var vocab model.Vocab = make(map[string]int, len(m.Pieces))
+	for id, p := range m.Pieces {
+		vocab[p.Piece] = id
+	}
+
+	var merges Merges = make(map[Pair]PairVal)
+	for id, p := range m.Pieces {
+		runes := []rune(p.Piece)
+		var local []Pair
+		for i := 1; i < len(runes); i++ {
+			l, okL := vocab[string(runes[:i])]
+			r, okR := vocab[string(runes[i:])]
+			if okL && okR {
+				local = append(local, Pair{l, r})
+			}
+		}
+		sort.Slice(local, func(i, j int) bool {
+			if local[i].C1 != local[j].C1 {
+				return local[i].C1 < local[j].C1
+			}
+			return local[i].C2 < local[j].C2
+		})
+		for _, pair := range local {
+			merges[pair] = PairVal{len(merges), id}
+		}
+	}
+
+	b := NewBpeBuilder()
+	b.VocabAndMerges(vocab, merges)
+	if id := m.TrainerSpec.UnkId; id >= 0 && id < len(m.Pieces) {
+		b.UnkToken(m.Pieces[id].Piece)
+	}
+	b.FuseUnk(true)
+	b.ByteFallback(m.TrainerSpec.ByteFallback)
+
+	return b.Build()
+}
+
 // NewBPE creates new BPE model with given vocab and merges
 func NewBPE(vocab model.Vocab, merges Merges) *BPE {
 	b, err := newBPE()
@@ -355,6 +433,16 @@ func (b *BPE) ClearCache() {
 	}
 }
 
+// ResizeCache replaces the cache with an empty one of the given capacity.
+// A capacity of 0 disables the cache.
+func (b *BPE) ResizeCache(capacity int) {
+	if capacity <= 0 {
+		b.Cache = nil
+		return
+	}
+	b.Cache = NewCache(capacity)
+}
+
 // GetVocab returns BPE vocab
 // func (b *BPE) GetVocab() *model.Vocab {
 func (b BPE) GetVocab() map[string]int {
@@ -372,7 +460,14 @@ func (b *BPE) GetContinuingSubwordPrfix() *string {
 }
 
 // MergeWord merges given word
-func (b *BPE) MergeWord(w string) *Word {
+//
+// The continuing subword prefix is attached to every character but the first
+// one and the end-of-word suffix to the last one before looking them up in
+// the vocab. Characters not found in the vocab are encoded as `<0xXX>` byte
+// tokens if `ByteFallback` is set and all of them are in the vocab, otherwise
+// replaced by the `unk` token (fused with a preceding `unk` if `FuseUnk` is
+// set). An error is returned if there is no usable `unk` token.
+func (b *BPE) MergeWord(w string) (*Word, error) {
 
 	word := NewWord()
 	var (
@@ -391,8 +486,14 @@ func (b *BPE) MergeWord(w string) *Word {
 		suffix = ""
 	}
 
-	chars := []rune(w)
-	currRuneIdx := 0
+	// Pending `unk` length to be fused with following ones
+	var (
+		unkId  int
+		unkLen int
+	)
+
+	_, lastSize := utf8.DecodeLastRuneInString(w)
+	lastByteIdx := len(w) - lastSize
 	for byteIdx, r := range w {
 		var (
 			s       string
@@ -400,31 +501,59 @@ func (b *BPE) MergeWord(w string) *Word {
 		)
 		byteLen = len(string(r))
 
-		// if first rune, add prefix
-		if byteIdx == 0 {
-			s = fmt.Sprintf("%v%v", prefix, string(r))
-		} else if currRuneIdx == len(chars) { // last rune, add suffix
-			s = fmt.Sprintf("%v%v", string(r), suffix)
-		} else { // the rest
-			s = string(r)
+		s = string(r)
+		// add prefix to all but the first rune
+		if byteIdx > 0 {
+			s = fmt.Sprintf("%v%v", prefix, s)
+		}
+		// add suffix to the last rune
+		if byteIdx == lastByteIdx {
+			s = fmt.Sprintf("%v%v", s, suffix)
 		}
-		currRuneIdx++
 
-		// If `s` exists in vocab, add its id, otherwise add id of `unk`
+		// If `s` exists in vocab, add its id, otherwise add its bytes or `unk`
 		vocab := *b.Vocab
 		if id, ok := vocab[s]; ok { // found
+			if unkLen > 0 {
+				word.Add(unkId, unkLen)
+				unkLen = 0
+			}
 			word.Add(id, byteLen)
-		} else { // not found, add `unk`
-			if b.UnkToken != nil {
-				// get `unk` id
-				unkId := (*b.Vocab)[*b.UnkToken]
-				// add `unk`
-				word.Add(unkId, byteLen)
-			} else {
-				fmt.Printf("cannot find '%s' in the vocab. \n", s)
-				panic("Can't find `unk` token in the vocab. Have you added one when initiating the model?")
+			continue
+		}
+
+		if b.ByteFallback {
+			if ids, ok := b.byteIds(string(r)); ok {
+				if unkLen > 0 {
+					word.Add(unkId, unkLen)
+					unkLen = 0
+				}
+				for _, id := range ids {
+					word.Add(id, 1)
+				}
+				continue
 			}
 		}
+
+		// not found, add `unk`
+		if b.UnkToken == nil {
+			err := fmt.Errorf("Cannot find %q in the vocab and no `unk` token was set.", s)
+			return nil, err
+		}
+		id, ok := vocab[*b.UnkToken]
+		if !ok {
+			err := fmt.Errorf("Unk token %q not found in the vocab.", *b.UnkToken)
+			return nil, err
+		}
+		if unkLen > 0 && !b.FuseUnk {
+			word.Add(unkId, unkLen)
+			unkLen = 0
+		}
+		unkId = id
+		unkLen += byteLen
+	}
+	if unkLen > 0 {
+		word.Add(unkId, unkLen)
 	}
 
 	if b.Dropout != nil {
@@ -433,7 +562,22 @@ func (b *BPE) MergeWord(w string) *Word {
 		word.MergeAll(*b.Merges)
 	}
 
-	return word
+	return word, nil
+}
+
+// byteIds returns the ids of the `<0xXX>` tokens of s bytes. It returns false if
+// any of them is missing from the vocab.
+func (b *BPE) byteIds(s string) ([]int, bool) {
+	ids := make([]int, len(s))
+	for i := 0; i < len(s); i++ {
+		id, ok := (*b.Vocab)[fmt.Sprintf("<0x%02X>", s[i])]
+		if !ok {
+			return nil, false
+		}
+		ids[i] = id
+	}
+
+	return ids, true
 }
 
 // WordToTokens slices word to tokens
@@ -484,28 +628,38 @@ func (b BPE) Tokenize(sequence string) (retVal []tokenizer.Token, err error) {
 	}
 
 	if b.Dropout == nil {
-		return b.TokenizeWithCache(sequence), nil
+		return b.TokenizeWithCache(sequence)
 	}
 
-	word := b.MergeWord(sequence)
+	word, err := b.MergeWord(sequence)
+	if err != nil {
+		return nil, err
+	}
 
 	return b.WordToTokens(*word), nil
 }
 
-func (b BPE) TokenizeWithCache(sequence string) (retVal []tokenizer.Token) {
-
-	if hit, ok := b.Cache.cmap[sequence]; ok {
-		return b.WordToTokens(hit)
-	} else {
-		word := b.MergeWord(sequence)
-		retVal = b.WordToTokens(*word)
-		if b.Cache != nil {
-			b.Cache.SetValues([]CacheItem{
-				{sequence, *word},
-			})
+// TokenizeWithCache tokenizes sequence and stores the merged word in cache if
+// the model has one.
+func (b BPE) TokenizeWithCache(sequence string) (retVal []tokenizer.Token, err error) {
+	if b.Cache != nil {
+		if hit, ok := b.Cache.Get(sequence); ok {
+			return b.WordToTokens(hit), nil
 		}
-		return retVal
 	}
+
+	word, err := b.MergeWord(sequence)
+	if err != nil {
+		return nil, err
+	}
+	retVal = b.WordToTokens(*word)
+	if b.Cache != nil {
+		b.Cache.SetValues([]CacheItem{
+			{sequence, *word},
+		})
+	}
+
+	return retVal, nil
 }
 
 func (b BPE) TokenToId(token string) (id int, ok bool) {
@@ -518,10 +672,45 @@ func (b BPE) IdToToken(id int) (token string, ok bool) {
 	return token, ok
 }
 
+// IsValidId returns whether the given id maps to a token in the vocab.
+func (b BPE) IsValidId(id int) bool {
+	_, ok := (*b.VocabR)[id]
+	return ok
+}
+
 func (b BPE) GetVocabSize() int {
 	return len(*b.Vocab)
 }
 
+// PruneVocab keeps the tokens for which keep returns true, along with the
+// `unk` token, and remaps their ids contiguously. Merges involving a removed
+// token are dropped. It returns the mapping from old to new ids.
+func (b BPE) PruneVocab(keep func(token string, id int) bool) (map[int]int, error) {
+	var mustKeep []string
+	if b.UnkToken != nil {
+		mustKeep = append(mustKeep, *b.UnkToken)
+	}
+	mapping := model.PruneVocab(*b.Vocab, keep, mustKeep...)
+	*b.Vocab, *b.VocabR = model.Remap(*b.Vocab, mapping)
+
+	merges := make(Merges, len(*b.Merges))
+	for pair, val := range *b.Merges {
+		c1, ok1 := mapping[pair.C1]
+		c2, ok2 := mapping[pair.C2]
+		newId, ok := mapping[val.NewId]
+		if ok1 && ok2 && ok {
+			merges[Pair{c1, c2}] = PairVal{val.Rank, newId}
+		}
+	}
+	*b.Merges = merges
+	b.ClearCache()
+
+	return mapping, nil
+}
+
+// Save saves the model to `vocab.json` and `merges.txt` (or
+// `<name>-vocab.json` and `<name>-merges.txt`) in dir, in the format read by
+// `NewBpeFromFiles`.
 func (b BPE) Save(dir string, nameOpt ...string) error {
 	var vfile string
 	var mfile string
@@ -589,11 +778,11 @@ func (b BPE) Save(dir string, nameOpt ...string) error {
 	defer file.Close()
 
 	w := bufio.NewWriter(file)
+	fmt.Fprintln(w, "#version: 0.2")
 	for _, line := range lines {
 		fmt.Fprintln(w, line)
 	}
 	return w.Flush()
-
 }
 
 func deleteWord(a []Word, i int) ([]Word, error) {
@@ -607,13 +796,9 @@ func deleteWord(a []Word, i int) ([]Word, error) {
 }
 
 // makeFilePath creates a filePath. If dir not existing, create it
+// makeFilePath creates the directory of filename if it does not exist.
 func makeFilePath(filename string) error {
-	var err error
-	dirName := filepath.Dir(filename)
-	if _, err = os.Stat(dirName); err != nil {
-		return err
-	}
-	return os.MkdirAll(dirName, os.ModePerm)
+	return os.MkdirAll(filepath.Dir(filename), os.ModePerm)
 }
 
 func CreateMerges(vocab map[string]int, mergesData []string) (*Merges, error) {
@@ -693,3 +878,34 @@ func New(
 
 	return builder.Build()
 }
+
+// MarshalJSON serializes BPE in HuggingFace `tokenizer.json` model format.
+func (b BPE) MarshalJSON() ([]byte, error) {
+	type pairRank struct {
+		Pair Pair
+		Rank int
+	}
+	var pairRanks []pairRank
+	for pair, pairVal := range *b.Merges {
+		pairRanks = append(pairRanks, pairRank{pair, pairVal.Rank})
+	}
+	sort.Slice(pairRanks, func(i, j int) bool {
+		return pairRanks[i].Rank < pairRanks[j].Rank
+	})
+
+	merges := []string{}
+	for _, pr := range pairRanks {
+		merges = append(merges, fmt.Sprintf("%v %v", (*b.VocabR)[pr.Pair.C1], (*b.VocabR)[pr.Pair.C2]))
+	}
+
+	return util.MarshalTyped("BPE", map[string]interface{}{
+		"dropout":                   b.Dropout,
+		"unk_token":                 b.UnkToken,
+		"continuing_subword_prefix": b.ContinuingSubwordPrefix,
+		"end_of_word_suffix":        b.EndOfWordSuffix,
+		"fuse_unk":                  b.FuseUnk,
+		"byte_fallback":             b.ByteFallback,
+		"vocab":                     b.Vocab,
+		"merges":                    merges,
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 
 	// "reflect"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/sugarme/tokenizer"
 	bpe "github.com/sugarme/tokenizer/model/bpe"
+	"github.com/sugarme/tokenizer/spm"
 	"github.com/sugarme/tokenizer/util"
 )
 
@@ -164,3 +166,263 @@ func TestBPE_TokenizeWithAndWithoutDropout(t *testing.T) {
 	}
 
 }
+
+func TestBPE_UnkToken(t *testing.T) {
+	var vocab map[string]int = make(map[string]int)
+	vocab["<unk>"] = 0
+	vocab["a"] = 1
+	vocab["b"] = 2
+
+	var merges bpe.Merges = make(map[bpe.Pair]bpe.PairVal)
+
+	model := bpe.NewBPE(vocab, merges)
+
+	// No `unk` token set.
+	_, err := model.Tokenize("c")
+	if err == nil {
+		t.Errorf("want error on unknown char without unk token\n")
+	}
+
+	// `unk` token set but missing from the vocab.
+	missing := "[UNK]"
+	model.UnkToken = &missing
+	_, err = model.Tokenize("c")
+	if err == nil {
+		t.Errorf("want error on unk token missing from the vocab\n")
+	}
+
+	unk := "<unk>"
+	model.UnkToken = &unk
+	got, err := model.Tokenize("cé")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tokenizer.Token{
+		{Id: 0, Value: "<unk>", Offsets: []int{0, 1}},
+		{Id: 0, Value: "<unk>", Offsets: []int{1, 3}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+}
+
+func TestBPE_ByteFallback(t *testing.T) {
+	var vocab map[string]int = make(map[string]int)
+	vocab["<unk>"] = 0
+	vocab["<0x61>"] = 1
+	vocab["<0xC3>"] = 2
+	vocab["<0xA9>"] = 3
+	vocab["b"] = 4
+
+	var merges bpe.Merges = make(map[bpe.Pair]bpe.PairVal)
+
+	builder := bpe.NewBpeBuilder()
+	builder.VocabAndMerges(vocab, merges)
+	builder.UnkToken("<unk>")
+	builder.ByteFallback(true)
+	model, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "é" is encoded as its 2 bytes, "c" has no byte token and falls back to `unk`.
+	got, err := model.Tokenize("béc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tokenizer.Token{
+		{Id: 4, Value: "b", Offsets: []int{0, 1}},
+		{Id: 2, Value: "<0xC3>", Offsets: []int{1, 2}},
+		{Id: 3, Value: "<0xA9>", Offsets: []int{2, 3}},
+		{Id: 0, Value: "<unk>", Offsets: []int{3, 4}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+
+	// Consecutive `unk` tokens are fused.
+	model.FuseUnk = true
+	got, err = model.Tokenize("cdbe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []tokenizer.Token{
+		{Id: 0, Value: "<unk>", Offsets: []int{0, 2}},
+		{Id: 4, Value: "b", Offsets: []int{2, 3}},
+		{Id: 0, Value: "<unk>", Offsets: []int{3, 4}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+}
+
+func TestBPE_PrefixAndSuffix(t *testing.T) {
+	var vocab map[string]int = make(map[string]int)
+	vocab["a"] = 0
+	vocab["##b"] = 1
+	vocab["##c</w>"] = 2
+
+	var merges bpe.Merges = make(map[bpe.Pair]bpe.PairVal)
+
+	builder := bpe.NewBpeBuilder()
+	builder.VocabAndMerges(vocab, merges)
+	builder.ContinuingSubwordPrefix("##")
+	builder.EndOfWordSuffix("</w>")
+	builder.CacheCapacity(0)
+	model, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := model.Tokenize("abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tokenizer.Token{
+		{Id: 0, Value: "a", Offsets: []int{0, 1}},
+		{Id: 1, Value: "##b", Offsets: []int{1, 2}},
+		{Id: 2, Value: "##c</w>", Offsets: []int{2, 3}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+}
+
+func TestNewBpeFromSentencePiece(t *testing.T) {
+	m := spm.Model{
+		Pieces: []spm.Piece{
+			{Piece: "<unk>", Type: spm.UnknownPiece},
+			{Piece: "h", Type: spm.NormalPiece},
+			{Piece: "e", Type: spm.NormalPiece},
+			{Piece: "l", Type: spm.NormalPiece},
+			{Piece: "o", Type: spm.NormalPiece},
+			{Piece: "he", Type: spm.NormalPiece},
+			{Piece: "ll", Type: spm.NormalPiece},
+			{Piece: "hell", Type: spm.NormalPiece},
+			{Piece: "hello", Type: spm.NormalPiece},
+		},
+		TrainerSpec: spm.TrainerSpec{ModelType: spm.BpeModel, PadId: -1},
+	}
+	file := filepath.Join(t.TempDir(), "bpe.model")
+	if err := os.WriteFile(file, m.Marshal(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	model, err := bpe.NewBpeFromSentencePiece(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input string
+		want  []tokenizer.Token
+	}{
+		{"hello", []tokenizer.Token{{Id: 8, Value: "hello", Offsets: []int{0, 5}}}},
+		{"hole", []tokenizer.Token{
+			{Id: 1, Value: "h", Offsets: []int{0, 1}},
+			{Id: 4, Value: "o", Offsets: []int{1, 2}},
+			{Id: 3, Value: "l", Offsets: []int{2, 3}},
+			{Id: 2, Value: "e", Offsets: []int{3, 4}},
+		}},
+		{"hex", []tokenizer.Token{
+			{Id: 5, Value: "he", Offsets: []int{0, 2}},
+			{Id: 0, Value: "<unk>", Offsets: []int{2, 3}},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := model.Tokenize(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("%q: want %+v, got %+v", tt.input, tt.want, got)
+		}
+	}
+
+	m.TrainerSpec.ModelType = spm.UnigramModel
+	if err := os.WriteFile(file, m.Marshal(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bpe.NewBpeFromSentencePiece(file); err == nil {
+		t.Errorf("want error for a unigram model")
+	}
+}
+
+func TestBPE_Save(t *testing.T) {
+	var vocab map[string]int = make(map[string]int)
+	vocab["<unk>"] = 0
+	vocab["a"] = 1
+	vocab["b"] = 2
+	vocab["c"] = 3
+	vocab["ab"] = 4
+	vocab["abc"] = 5
+
+	m, err := bpe.New(vocab, []string{"a b", "ab c"}, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The directory is created if needed
+	dir := filepath.Join(t.TempDir(), "model")
+	if err := m.Save(dir, "es"); err != nil {
+		t.Fatal(err)
+	}
+
+	merges, err := os.ReadFile(filepath.Join(dir, "es-merges.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "#version: 0.2\na b\nab c\n"; string(merges) != want {
+		t.Errorf("want %q, got %q\n", want, merges)
+	}
+
+	got, err := bpe.NewBpeFromFiles(filepath.Join(dir, "es-vocab.json"), filepath.Join(dir, "es-merges.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*m.Vocab, *got.Vocab) || !reflect.DeepEqual(*m.Merges, *got.Merges) {
+		t.Errorf("want %v %v\ngot %v %v\n", *m.Vocab, *m.Merges, *got.Vocab, *got.Merges)
+	}
+}
+
+func TestBPE_PruneVocab(t *testing.T) {
+	vocab := map[string]int{"<unk>": 0, "a": 1, "b": 2, "c": 3, "ab": 4, "abc": 5}
+	merges := bpe.Merges{
+		bpe.Pair{C1: 1, C2: 2}: bpe.PairVal{Rank: 0, NewId: 4},
+		bpe.Pair{C1: 4, C2: 3}: bpe.PairVal{Rank: 1, NewId: 5},
+	}
+
+	builder := bpe.NewBpeBuilder()
+	builder.VocabAndMerges(vocab, merges)
+	builder.UnkToken("<unk>")
+	model, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mapping, err := model.PruneVocab(func(token string, id int) bool {
+		return token != "<unk>" && token != "c"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int]int{0: 0, 1: 1, 2: 2, 4: 3, 5: 4}; !reflect.DeepEqual(want, mapping) {
+		t.Errorf("want %v, got %v", want, mapping)
+	}
+	// The merge producing "abc" involves "c" and is dropped.
+	if want := (bpe.Merges{bpe.Pair{C1: 1, C2: 2}: bpe.PairVal{Rank: 0, NewId: 3}}); !reflect.DeepEqual(want, *model.Merges) {
+		t.Errorf("want %v, got %v", want, *model.Merges)
+	}
+
+	got, err := model.Tokenize("abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tokenizer.Token{
+		{Id: 3, Value: "ab", Offsets: []int{0, 2}},
+		{Id: 0, Value: "<unk>", Offsets: []int{2, 3}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+}
@@ -1,23 +1,21 @@
 package bpe
 
 import (
+	"container/list"
 	"sync"
 )
 
-// Cache is a map with read-write mutex included
-// to hold map of `word` strings
-// E.g. https://tour.golang.org/concurrency/9
-// NOTE: can we you sync.Map struct instead???
+// Cache is a least recently used (LRU) cache of merged words, safe for
+// concurrent use. When full, adding a word evicts the least recently used
+// one.
 type Cache struct {
-	mux sync.RWMutex
-	// cmap     map[interface{}]interface{}
-	cmap     map[string]Word
+	mux      sync.Mutex
+	cmap     map[string]*list.Element
+	lru      *list.List // of CacheItem, most recently used first
 	Capacity int
 }
 
 type CacheItem struct {
-	// Key   interface{}
-	// Value interface{}
 	Key   string
 	Value Word // `word` string
 }
@@ -25,56 +23,82 @@ type CacheItem struct {
 // NewCache create an empty Cache with a specified capacity
 func NewCache(capacity int) *Cache {
 	return &Cache{
-		// cmap:     make(map[interface{}]interface{}, capacity),
-		cmap:     make(map[string]Word, capacity),
+		cmap:     make(map[string]*list.Element),
+		lru:      list.New(),
 		Capacity: capacity,
 	}
 }
 
-// Fresh create a fresh `Cache` with the same configuration
-func (c *Cache) Fresh() {
-	c = NewCache(c.Capacity)
+// Fresh creates a fresh `Cache` with the same configuration
+func (c *Cache) Fresh() *Cache {
+	return NewCache(c.Capacity)
 }
 
 // Clear clears the cache
 func (c *Cache) Clear() {
-	// NOTE:Should we just make a new map instead of use `for` loop
-	// Ref. https://stackoverflow.com/questions/13812121
-	for k := range c.cmap {
-		delete(c.cmap, k)
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.cmap = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+// Len returns the number of words in the cache.
+func (c *Cache) Len() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.lru.Len()
+}
+
+// Get returns the value associated with key if any.
+func (c *Cache) Get(key string) (Word, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	e, ok := c.cmap[key]
+	if !ok {
+		return Word{}, false
 	}
+	c.lru.MoveToFront(e)
+
+	return e.Value.(CacheItem).Value, true
 }
 
 // GetValues returns slices of values associated with input keys
 func (c *Cache) GetValues(keys []string) []Word {
-	c.mux.Lock() // Lock so only one goroutine at a time can access
-	defer c.mux.Unlock()
-
 	var res []Word
-
 	for _, k := range keys {
-		res = append(res, c.cmap[k])
+		w, _ := c.Get(k)
+		res = append(res, w)
 	}
 
 	return res
 }
 
+// SetValues adds values to the cache, evicting the least recently used ones
+// if needed.
 func (c *Cache) SetValues(values []CacheItem) {
-
-	// Before trying to acquire a write lock, we check if we are already at
-	// capacity with a read handler.
 	c.mux.Lock()
 	defer c.mux.Unlock()
 
-	if len(c.cmap) == c.Capacity {
+	if c.Capacity <= 0 {
 		return
 	}
 
 	for _, v := range values {
-		if len(c.cmap) == c.Capacity {
-			break
+		if e, ok := c.cmap[v.Key]; ok {
+			e.Value = v
+			c.lru.MoveToFront(e)
+			continue
 		}
 
-		c.cmap[v.Key] = v.Value
+		c.cmap[v.Key] = c.lru.PushFront(v)
+	}
+
+	for c.lru.Len() > c.Capacity {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.cmap, e.Value.(CacheItem).Key)
 	}
 }
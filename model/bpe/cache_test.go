@@ -0,0 +1,73 @@
+package bpe_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	bpe "github.com/sugarme/tokenizer/model/bpe"
+)
+
+func TestCache_LRU(t *testing.T) {
+	c := bpe.NewCache(2)
+	c.SetValues([]bpe.CacheItem{{Key: "a"}, {Key: "b"}})
+
+	// "a" becomes the most recently used, "b" is evicted.
+	if _, ok := c.Get("a"); !ok {
+		t.Fatalf("want a cached")
+	}
+	c.SetValues([]bpe.CacheItem{{Key: "c"}})
+
+	if c.Len() != 2 {
+		t.Errorf("want 2 words, got %v", c.Len())
+	}
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := c.Get(key); ok != want {
+			t.Errorf("%q: want cached %v, got %v", key, want, ok)
+		}
+	}
+
+	c.Clear()
+	if c.Len() != 0 {
+		t.Errorf("want empty cache, got %v words", c.Len())
+	}
+}
+
+func TestCache_Concurrent(t *testing.T) {
+	c := bpe.NewCache(10)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("%v", (i+j)%20)
+				if _, ok := c.Get(key); !ok {
+					c.SetValues([]bpe.CacheItem{{Key: key}})
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if c.Len() > 10 {
+		t.Errorf("want at most 10 words, got %v", c.Len())
+	}
+}
+
+func TestBPE_ResizeCache(t *testing.T) {
+	model, err := bpe.DefaultBPE()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model.ResizeCache(0)
+	if model.Cache != nil {
+		t.Errorf("want no cache")
+	}
+	model.ResizeCache(5)
+	if model.Cache == nil || model.Cache.Capacity != 5 {
+		t.Errorf("want a cache of capacity 5, got %+v", model.Cache)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"sort"
 	"testing"
 
+	"github.com/sugarme/tokenizer"
 	bpe "github.com/sugarme/tokenizer/model/bpe"
 )
 
@@ -23,6 +24,7 @@ func TestBpeTrainer_Train(t *testing.T) {
 	wordCounts["GPT-2"] = 1
 
 	trainer := bpe.NewBpeTrainer(2, 30)
+	trainer.ShowProgress = false
 
 	model, _ := trainer.Train(wordCounts)
 
@@ -67,7 +69,7 @@ func TestBpeTrainer_Train(t *testing.T) {
 	}
 
 	for _, k := range gKeys {
-		val := want[k]
+		val := got[k]
 		sortedGot[k] = val
 	}
 
@@ -78,6 +80,47 @@ func TestBpeTrainer_Train(t *testing.T) {
 
 }
 
+func TestBpeTrainer_Options(t *testing.T) {
+	wordCounts := map[string]int{
+		"hug":  10,
+		"pug":  5,
+		"pun":  12,
+		"bun":  4,
+		"hugs": 5,
+	}
+
+	builder := bpe.NewBPETrainerBuilder()
+	builder.VocabSize(14)
+	builder.MinFrequency(2)
+	builder.ShowProgress(false)
+	builder.SpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("<unk>", true)})
+	builder.ContinuingSubwordPrefix("##")
+	builder.InitialAlphabet(bpe.CharSet{"z": struct{}{}})
+	builder.LimitAlphabet(6)
+	trainer := builder.Build()
+
+	model, specialTokens := trainer.Train(wordCounts)
+	got := map[string]int(*model.(bpe.BPE).Vocab)
+
+	// Special tokens come first, then the alphabet limited to its 6 most
+	// frequent chars (the initial alphabet is always kept, "b" and "s" are
+	// dropped), then the prefixed chars and the merges.
+	want := map[string]int{
+		"<unk>": 0,
+		"g":     1, "h": 2, "n": 3, "p": 4, "u": 5, "z": 6,
+		"##u": 7, "##n": 8, "##g": 9,
+		"##ug": 10, "##un": 11, "hug": 12, "pun": 13,
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v\ngot %v\n", want, got)
+	}
+
+	if len(specialTokens) != 1 || specialTokens[0].Content != "<unk>" {
+		t.Errorf("want special token <unk>, got %v\n", specialTokens)
+	}
+}
+
 func sortedKeys(m map[string]int) []string {
 	keys := make([]string, len(m))
 	i := 0
@@ -1,4 +1,48 @@
 package model
 
+import (
+	"sort"
+)
+
 type Vocab map[string]int
 type VocabR map[int]string
+
+// PruneVocab selects the tokens of vocab to keep and returns the mapping from
+// their old id to their new id. New ids are contiguous from 0 and follow the
+// order of old ids. Tokens of mustKeep (ie. `unk`) are kept regardless of keep.
+func PruneVocab(vocab map[string]int, keep func(token string, id int) bool, mustKeep ...string) map[int]int {
+	required := make(map[string]bool, len(mustKeep))
+	for _, tok := range mustKeep {
+		required[tok] = true
+	}
+
+	var ids []int
+	for tok, id := range vocab {
+		if required[tok] || keep(tok, id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	mapping := make(map[int]int, len(ids))
+	for newId, oldId := range ids {
+		mapping[oldId] = newId
+	}
+
+	return mapping
+}
+
+// Remap returns a copy of vocab restricted to the ids of mapping, with their
+// new ids, and its reversed vocabulary.
+func Remap(vocab map[string]int, mapping map[int]int) (Vocab, VocabR) {
+	v := make(Vocab, len(mapping))
+	vr := make(VocabR, len(mapping))
+	for tok, id := range vocab {
+		if newId, ok := mapping[id]; ok {
+			v[tok] = newId
+			vr[newId] = tok
+		}
+	}
+
+	return v, vr
+}
@@ -0,0 +1,770 @@
+package unigram
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"unicode/utf8"
+
+	progressbar "github.com/schollz/progressbar/v2"
+
+	"github.com/sugarme/tokenizer"
+)
+
+const (
+	// sentenceBoundary separates sentences when looking for seed pieces.
+	sentenceBoundary rune = 0
+	// expectedFrequencyThreshold is the minimum expected frequency for a
+	// piece to survive the M step.
+	expectedFrequencyThreshold float64 = 0.5
+	// minScorePenaltyDelta lowers the score of each required char that is
+	// missing from the trained pieces, so they keep a stable order.
+	minScorePenaltyDelta float64 = 0.0001
+)
+
+// UnigramTrainerBuilder can be used to create a `UnigramTrainer` with a
+// custom configuration.
+type UnigramTrainerBuilder struct {
+	trainer UnigramTrainer
+}
+
+// NewUnigramTrainerBuilder creates a UnigramTrainerBuilder with default
+// values.
+func NewUnigramTrainerBuilder() (retVal UnigramTrainerBuilder) {
+	return UnigramTrainerBuilder{
+		trainer: UnigramTrainer{
+			VocabSize:       8000,
+			NSubIterations:  2,
+			ShrinkingFactor: 0.75,
+			MaxPieceLength:  16,
+			SeedSize:        1000000,
+			ShowProgress:    true,
+		},
+	}
+}
+
+// VocabSize sets the target vocabulary size, special tokens included.
+func (utb UnigramTrainerBuilder) VocabSize(size int) (retVal UnigramTrainerBuilder) {
+	utb.trainer.VocabSize = size
+	return utb
+}
+
+// NSubIterations sets the number of EM iterations run between two pruning
+// steps.
+func (utb UnigramTrainerBuilder) NSubIterations(n int) (retVal UnigramTrainerBuilder) {
+	utb.trainer.NSubIterations = n
+	return utb
+}
+
+// ShrinkingFactor sets the ratio of pieces kept at each pruning step. It must
+// be in (0, 1).
+func (utb UnigramTrainerBuilder) ShrinkingFactor(factor float64) (retVal UnigramTrainerBuilder) {
+	utb.trainer.ShrinkingFactor = factor
+	return utb
+}
+
+// SpecialTokens sets the special tokens, added first to the vocabulary.
+func (utb UnigramTrainerBuilder) SpecialTokens(tokens []tokenizer.AddedToken) (retVal UnigramTrainerBuilder) {
+	utb.trainer.SpecialTokens = tokens
+	return utb
+}
+
+// InitialAlphabet sets chars to include in the vocabulary even if they are
+// not seen in the training data.
+func (utb UnigramTrainerBuilder) InitialAlphabet(alphabet []rune) (retVal UnigramTrainerBuilder) {
+	utb.trainer.InitialAlphabet = alphabet
+	return utb
+}
+
+// UnkToken sets the unknown token of the trained model. It is added to the
+// vocabulary if not already part of the special tokens.
+func (utb UnigramTrainerBuilder) UnkToken(unkToken string) (retVal UnigramTrainerBuilder) {
+	utb.trainer.UnkToken = &unkToken
+	return utb
+}
+
+// MaxPieceLength sets the maximum length, in chars, of a piece.
+func (utb UnigramTrainerBuilder) MaxPieceLength(length int) (retVal UnigramTrainerBuilder) {
+	utb.trainer.MaxPieceLength = length
+	return utb
+}
+
+// SeedSize sets the number of seed pieces the training starts from.
+func (utb UnigramTrainerBuilder) SeedSize(size int) (retVal UnigramTrainerBuilder) {
+	utb.trainer.SeedSize = size
+	return utb
+}
+
+// ShowProgress sets whether to show progress.
+func (utb UnigramTrainerBuilder) ShowProgress(show bool) (retVal UnigramTrainerBuilder) {
+	utb.trainer.ShowProgress = show
+	return utb
+}
+
+// Build constructs the final UnigramTrainer.
+func (utb UnigramTrainerBuilder) Build() (retVal *UnigramTrainer) {
+	trainer := utb.trainer
+	return &trainer
+}
+
+// UnigramTrainer is a trainer for Unigram model.
+//
+// It follows SentencePiece: the most frequent substrings of the training data
+// are used as seed pieces, then the piece scores are estimated with EM and the
+// pieces contributing the least to the likelihood are pruned, until the
+// vocabulary is close to `VocabSize`.
+type UnigramTrainer struct {
+	VocabSize       int
+	NSubIterations  int
+	ShrinkingFactor float64
+	SpecialTokens   []tokenizer.AddedToken
+	InitialAlphabet []rune
+	UnkToken        *string
+	MaxPieceLength  int
+	SeedSize        int
+	ShowProgress    bool
+
+	// Function called on progress, see `SetProgressFunc`
+	progressFn func(tokenizer.Progress)
+}
+
+// SetProgressFunc sets a function called on progress of the EM training
+// iterations. It implements `tokenizer.ProgressTrainer`.
+func (ut *UnigramTrainer) SetProgressFunc(fn func(tokenizer.Progress)) {
+	ut.progressFn = fn
+}
+
+// Implement Trainer interface for UnigramTrainer:
+// ===============================================
+
+var _ tokenizer.ProgressTrainer = new(UnigramTrainer)
+
+// WithProgressBar returns whether the training shows progress.
+func (ut *UnigramTrainer) WithProgressBar() bool {
+	return ut.ShowProgress
+}
+
+// Train trains a Unigram model on input wordCounts and returns the model along
+// with the special tokens to be added to the tokenizer.
+//
+// It panics if the configuration is invalid, see `TrainModel` to get an
+// error instead.
+func (ut *UnigramTrainer) Train(wordCounts map[string]int) (tokenizer.Model, []tokenizer.AddedToken) {
+	u, err := ut.TrainModel(wordCounts)
+	if err != nil {
+		panic(err)
+	}
+
+	return u, ut.SpecialTokens
+}
+
+// ProcessTokens processes a bunch of tokens, counting them
+func (ut *UnigramTrainer) ProcessTokens(words map[string]int, tokens []string) {
+	for _, token := range tokens {
+		words[token] += 1
+	}
+}
+
+// TrainModel trains a Unigram model on input wordCounts.
+func (ut *UnigramTrainer) TrainModel(wordCounts map[string]int) (*Unigram, error) {
+	if ut.ShrinkingFactor <= 0 || ut.ShrinkingFactor >= 1 {
+		err := fmt.Errorf("UnigramTrainer: shrinking factor must be in (0, 1), got %v.", ut.ShrinkingFactor)
+		return nil, err
+	}
+	if ut.VocabSize <= len(ut.SpecialTokens) {
+		err := fmt.Errorf("UnigramTrainer: vocab size %v is too small for %v special tokens.", ut.VocabSize, len(ut.SpecialTokens))
+		return nil, err
+	}
+
+	sentences := toSentences(wordCounts)
+	requiredChars := ut.requiredChars(sentences)
+	pieces := ut.seedPieces(sentences)
+	desiredVocabSize := ut.VocabSize * 11 / 10 // some extra room for the final pruning
+
+	u, err := New(pieces, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// The number of pruning loops is estimated from the shrinking factor.
+	loops := int((math.Log(float64(desiredVocabSize))-math.Log(float64(len(pieces))))/math.Log(ut.ShrinkingFactor)) + 1
+	if loops < 1 {
+		loops = 1
+	}
+	var pb *progressbar.ProgressBar
+	if ut.ShowProgress {
+		pb = progressbar.NewOptions(loops*ut.NSubIterations, progressbar.OptionSetDescription("EM training"))
+	}
+	var iterations int
+
+	for {
+		for i := 0; i < ut.NSubIterations; i++ {
+			expected := u.expectedFrequencies(sentences)
+			pieces = mStep(pieces, expected)
+			u, err = New(pieces, nil, false)
+			if err != nil {
+				return nil, err
+			}
+			if pb != nil {
+				pb.Add(1)
+			}
+			iterations++
+			if ut.progressFn != nil {
+				ut.progressFn(tokenizer.Progress{Stage: "EM training", Current: iterations, Total: loops * ut.NSubIterations})
+			}
+		}
+
+		if len(pieces) <= desiredVocabSize {
+			break
+		}
+
+		pruned := ut.prune(u, pieces, sentences, desiredVocabSize)
+		if len(pruned) == len(pieces) {
+			break
+		}
+		pieces = pruned
+		u, err = New(pieces, nil, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if pb != nil {
+		pb.Finish()
+		fmt.Println()
+	}
+
+	return ut.finalize(u, requiredChars)
+}
+
+// sentence is a training word with its count.
+type sentence struct {
+	value string
+	count int
+}
+
+// toSentences returns wordCounts sorted by word for determinism.
+func toSentences(wordCounts map[string]int) []sentence {
+	sentences := make([]sentence, 0, len(wordCounts))
+	for w, c := range wordCounts {
+		sentences = append(sentences, sentence{w, c})
+	}
+	sort.Slice(sentences, func(i, j int) bool {
+		return sentences[i].value < sentences[j].value
+	})
+
+	return sentences
+}
+
+// requiredChars returns all the chars of the training data and of the initial
+// alphabet, sorted.
+func (ut *UnigramTrainer) requiredChars(sentences []sentence) []string {
+	set := make(map[rune]struct{})
+	for _, s := range sentences {
+		for _, r := range s.value {
+			set[r] = struct{}{}
+		}
+	}
+	for _, r := range ut.InitialAlphabet {
+		set[r] = struct{}{}
+	}
+
+	chars := make([]rune, 0, len(set))
+	for r := range set {
+		chars = append(chars, r)
+	}
+	sort.Slice(chars, func(i, j int) bool { return chars[i] < chars[j] })
+
+	required := make([]string, len(chars))
+	for i, r := range chars {
+		required[i] = string(r)
+	}
+
+	return required
+}
+
+// seedPieces returns the initial pieces: every char of the training data,
+// followed by the repeated substrings with the highest `frequency * length`
+// score, up to `SeedSize` pieces. Scores are log probabilities.
+//
+// Repeated substrings are found by walking the LCP intervals of the suffix
+// array of all sentences joined with `sentenceBoundary`.
+func (ut *UnigramTrainer) seedPieces(sentences []sentence) []TokenScore {
+	var text []rune
+	charCounts := make(map[rune]int)
+	for _, s := range sentences {
+		if s.value == "" {
+			continue
+		}
+		for _, r := range s.value {
+			text = append(text, r)
+			if r != sentenceBoundary {
+				charCounts[r] += s.count
+			}
+		}
+		text = append(text, sentenceBoundary)
+	}
+
+	chars := make([]rune, 0, len(charCounts))
+	for r := range charCounts {
+		chars = append(chars, r)
+	}
+	sort.Slice(chars, func(i, j int) bool {
+		ci, cj := charCounts[chars[i]], charCounts[chars[j]]
+		if ci != cj {
+			return ci > cj
+		}
+		return chars[i] < chars[j]
+	})
+
+	var pieces []TokenScore
+	for _, r := range chars {
+		pieces = append(pieces, TokenScore{Token: string(r), Score: float64(charCounts[r])})
+	}
+
+	type substring struct {
+		value string
+		score int
+	}
+	var substrings []substring
+	sa := suffixArray(text)
+	lcpIntervals(lcpArray(text, sa), func(depth, lb, rb int) {
+		if depth <= 1 || depth > ut.MaxPieceLength {
+			return
+		}
+		runes := text[sa[lb] : sa[lb]+depth]
+		for _, r := range runes {
+			if r == sentenceBoundary {
+				return
+			}
+		}
+		freq := rb - lb + 1
+		substrings = append(substrings, substring{string(runes), freq * depth})
+	})
+	sort.Slice(substrings, func(i, j int) bool {
+		if substrings[i].score != substrings[j].score {
+			return substrings[i].score > substrings[j].score
+		}
+		return substrings[i].value < substrings[j].value
+	})
+
+	for _, s := range substrings {
+		if len(pieces) >= ut.SeedSize {
+			break
+		}
+		pieces = append(pieces, TokenScore{Token: s.value, Score: float64(s.score)})
+	}
+
+	// to log probabilities
+	sum := 0.0
+	for _, p := range pieces {
+		sum += p.Score
+	}
+	logSum := math.Log(sum)
+	for i := range pieces {
+		pieces[i].Score = math.Log(pieces[i].Score) - logSum
+	}
+
+	return pieces
+}
+
+// expectedFrequencies runs the E step: it returns the expected frequency of
+// every piece over all sentences.
+func (u *Unigram) expectedFrequencies(sentences []sentence) []float64 {
+	expected := make([]float64, len(u.vocab))
+	for _, s := range sentences {
+		u.populateMarginal(s.value, float64(s.count), expected)
+	}
+
+	return expected
+}
+
+// populateMarginal adds to expected the marginal probability of every piece
+// of sequence, weighted by freq, using the forward-backward algorithm over
+// all its segmentations.
+func (u *Unigram) populateMarginal(sequence string, freq float64, expected []float64) {
+	type node struct {
+		piece
+		score float64
+	}
+
+	// nodes are ordered by start position.
+	var nodes []node
+	unkScore := u.minScore - unkPenalty
+	for start := 0; start < len(sequence); {
+		_, charLen := utf8.DecodeRuneInString(sequence[start:])
+		hasSingle := false
+		for end := start + charLen; end <= len(sequence) && end-start <= u.maxPieceLen; {
+			if id, ok := u.tokenToIds[sequence[start:end]]; ok {
+				nodes = append(nodes, node{piece{id, start, end}, u.vocab[id].Score})
+				if end == start+charLen {
+					hasSingle = true
+				}
+			}
+			if end == len(sequence) {
+				break
+			}
+			_, size := utf8.DecodeRuneInString(sequence[end:])
+			end += size
+		}
+		if !hasSingle {
+			nodes = append(nodes, node{piece{-1, start, start + charLen}, unkScore})
+		}
+		start += charLen
+	}
+
+	alpha := make([]float64, len(sequence)+1)
+	beta := make([]float64, len(sequence)+1)
+	for i := range alpha {
+		alpha[i] = math.Inf(-1)
+		beta[i] = math.Inf(-1)
+	}
+	alpha[0] = 0
+	beta[len(sequence)] = 0
+
+	for _, n := range nodes {
+		alpha[n.end] = logSumExp(alpha[n.end], alpha[n.start]+n.score)
+	}
+	for i := len(nodes) - 1; i >= 0; i-- {
+		n := nodes[i]
+		beta[n.start] = logSumExp(beta[n.start], beta[n.end]+n.score)
+	}
+
+	z := alpha[len(sequence)]
+	for _, n := range nodes {
+		if n.id < 0 {
+			continue
+		}
+		expected[n.id] += freq * math.Exp(alpha[n.start]+n.score+beta[n.end]-z)
+	}
+}
+
+// mStep runs the M step: pieces with a too low expected frequency are
+// dropped and the scores of the others are re-estimated with the Bayesian
+// (DP) variant of EM used by SentencePiece.
+func mStep(pieces []TokenScore, expected []float64) []TokenScore {
+	var (
+		newPieces []TokenScore
+		sum       float64
+	)
+	for i, p := range pieces {
+		if expected[i] < expectedFrequencyThreshold {
+			continue
+		}
+		newPieces = append(newPieces, TokenScore{Token: p.Token, Score: expected[i]})
+		sum += expected[i]
+	}
+
+	logSum := digamma(sum)
+	for i := range newPieces {
+		newPieces[i].Score = digamma(newPieces[i].Score) - logSum
+	}
+
+	return newPieces
+}
+
+// prune removes the pieces whose removal decreases the likelihood the least,
+// keeping at least `ShrinkingFactor` of them and no less than
+// desiredVocabSize.
+//
+// The likelihood loss of a piece is estimated by re-assigning its frequency
+// to its best alternative segmentation.
+func (ut *UnigramTrainer) prune(u *Unigram, pieces []TokenScore, sentences []sentence, desiredVocabSize int) []TokenScore {
+	alwaysKeep := make([]bool, len(pieces))
+	alternatives := make([][]int, len(pieces))
+	for id, p := range pieces {
+		paths, _ := u.nbest(p.Token, 2)
+		switch {
+		case len(paths) < 2:
+			// no other segmentation
+			alwaysKeep[id] = true
+		case len(paths[0]) == 1:
+			alwaysKeep[id] = true
+			for _, alt := range paths[1] {
+				if alt.id < 0 {
+					// the alternative does not cover the piece, keep it.
+					alternatives[id] = nil
+					break
+				}
+				alternatives[id] = append(alternatives[id], alt.id)
+			}
+		}
+	}
+
+	freq := make([]float64, len(pieces))
+	inverted := make([][]int, len(pieces))
+	var sentencesSum float64
+	for i, s := range sentences {
+		sentencesSum += float64(s.count)
+		paths, _ := u.nbest(s.value, 1)
+		if len(paths) == 0 {
+			continue
+		}
+		for _, p := range paths[0] {
+			if p.id < 0 {
+				continue
+			}
+			freq[p.id] += float64(s.count)
+			inverted[p.id] = append(inverted[p.id], i)
+		}
+	}
+
+	var sum float64
+	for _, f := range freq {
+		sum += f
+	}
+	logSum := math.Log(sum)
+
+	type candidate struct {
+		id   int
+		loss float64
+	}
+	var (
+		newPieces  []TokenScore
+		candidates []candidate
+	)
+	for id, p := range pieces {
+		switch {
+		case freq[id] == 0 && !alwaysKeep[id]:
+			// not used by any Viterbi path, can be removed safely.
+			continue
+		case len(alternatives[id]) == 0:
+			newPieces = append(newPieces, p)
+			continue
+		case freq[id] == 0:
+			candidates = append(candidates, candidate{id, 0})
+			continue
+		}
+
+		// Frequency of the sentences using the piece.
+		var f float64
+		for _, i := range inverted[id] {
+			f += float64(sentences[i].count)
+		}
+		f /= sentencesSum
+
+		logProb := math.Log(freq[id]) - logSum
+		// Once removed, the piece frequency goes to each of its alternatives.
+		logSumAlt := math.Log(sum + freq[id]*float64(len(alternatives[id])-1))
+		var logProbAlt float64
+		for _, alt := range alternatives[id] {
+			logProbAlt += math.Log(freq[alt]+freq[id]) - logSumAlt
+		}
+
+		candidates = append(candidates, candidate{id, f * (logProb - logProbAlt)})
+	}
+
+	prunedSize := int(ut.ShrinkingFactor * float64(len(pieces)))
+	if prunedSize < desiredVocabSize {
+		prunedSize = desiredVocabSize
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].loss > candidates[j].loss
+	})
+	for _, c := range candidates {
+		if len(newPieces) >= prunedSize {
+			break
+		}
+		newPieces = append(newPieces, pieces[c.id])
+	}
+
+	return newPieces
+}
+
+// finalize builds the trained model: special tokens first, then the required
+// chars and the best pieces, sorted by descending score.
+func (ut *UnigramTrainer) finalize(u *Unigram, requiredChars []string) (*Unigram, error) {
+	inserted := make(map[string]struct{})
+
+	var specials []TokenScore
+	for _, tok := range ut.SpecialTokens {
+		specials = append(specials, TokenScore{Token: tok.Content, Score: 0})
+		inserted[tok.Content] = struct{}{}
+	}
+
+	var unkId *int
+	if ut.UnkToken != nil {
+		id := -1
+		for i, s := range specials {
+			if s.Token == *ut.UnkToken {
+				id = i
+				break
+			}
+		}
+		if id < 0 {
+			specials = append([]TokenScore{{Token: *ut.UnkToken, Score: 0}}, specials...)
+			inserted[*ut.UnkToken] = struct{}{}
+			id = 0
+		}
+		unkId = &id
+	}
+
+	var pieces []TokenScore
+	penalty := 0.0
+	for _, c := range requiredChars {
+		if _, ok := inserted[c]; ok {
+			continue
+		}
+		if id, ok := u.tokenToIds[c]; ok {
+			pieces = append(pieces, TokenScore{Token: c, Score: u.vocab[id].Score})
+		} else {
+			pieces = append(pieces, TokenScore{Token: c, Score: u.minScore - penalty})
+			penalty += minScorePenaltyDelta
+		}
+		inserted[c] = struct{}{}
+	}
+
+	best := make([]TokenScore, len(u.vocab))
+	copy(best, u.vocab)
+	sort.SliceStable(best, func(i, j int) bool { return best[i].Score > best[j].Score })
+
+	maxPieces := ut.VocabSize - len(specials)
+	for _, ts := range best {
+		if len(pieces) >= maxPieces {
+			break
+		}
+		if _, ok := inserted[ts.Token]; ok {
+			continue
+		}
+		pieces = append(pieces, ts)
+		inserted[ts.Token] = struct{}{}
+	}
+
+	sort.SliceStable(pieces, func(i, j int) bool { return pieces[i].Score > pieces[j].Score })
+
+	return New(append(specials, pieces...), unkId, false)
+}
+
+// logSumExp returns log(exp(a) + exp(b)).
+func logSumExp(a, b float64) float64 {
+	if math.IsInf(a, -1) {
+		return b
+	}
+	if math.IsInf(b, -1) {
+		return a
+	}
+	if a < b {
+		a, b = b, a
+	}
+	return a + math.Log1p(math.Exp(b-a))
+}
+
+// digamma approximates the digamma function (derivative of log Gamma) for
+// positive x.
+func digamma(x float64) float64 {
+	var result float64
+	for x < 7 {
+		result -= 1 / x
+		x += 1
+	}
+	x -= 1.0 / 2.0
+	xx := 1 / x
+	xx2 := xx * xx
+	xx4 := xx2 * xx2
+	result += math.Log(x) + (1.0/24.0)*xx2 - (7.0/960.0)*xx4 + (31.0/8064.0)*xx4*xx2 - (127.0/30720.0)*xx4*xx4
+
+	return result
+}
+
+// suffixArray returns the suffix array of text, built by prefix doubling.
+func suffixArray(text []rune) []int {
+	n := len(text)
+	sa := make([]int, n)
+	rank := make([]int, n)
+	tmp := make([]int, n)
+	if n == 0 {
+		return sa
+	}
+	for i := range sa {
+		sa[i] = i
+		rank[i] = int(text[i])
+	}
+
+	for k := 1; ; k <<= 1 {
+		second := func(i int) int {
+			if i+k < n {
+				return rank[i+k]
+			}
+			return -1
+		}
+		less := func(a, b int) bool {
+			if rank[a] != rank[b] {
+				return rank[a] < rank[b]
+			}
+			return second(a) < second(b)
+		}
+		sort.Slice(sa, func(i, j int) bool { return less(sa[i], sa[j]) })
+
+		tmp[sa[0]] = 0
+		for i := 1; i < n; i++ {
+			tmp[sa[i]] = tmp[sa[i-1]]
+			if less(sa[i-1], sa[i]) {
+				tmp[sa[i]]++
+			}
+		}
+		copy(rank, tmp)
+
+		if rank[sa[n-1]] == n-1 {
+			break
+		}
+	}
+
+	return sa
+}
+
+// lcpArray returns, for each position of sa, the length of the longest common
+// prefix between the suffix and the previous one (Kasai's algorithm).
+func lcpArray(text []rune, sa []int) []int {
+	n := len(text)
+	rank := make([]int, n)
+	for i, s := range sa {
+		rank[s] = i
+	}
+
+	lcp := make([]int, n)
+	h := 0
+	for i := 0; i < n; i++ {
+		if rank[i] == 0 {
+			h = 0
+			continue
+		}
+		j := sa[rank[i]-1]
+		for i+h < n && j+h < n && text[i+h] == text[j+h] {
+			h++
+		}
+		lcp[rank[i]] = h
+		if h > 0 {
+			h--
+		}
+	}
+
+	return lcp
+}
+
+// lcpIntervals calls fn for every LCP interval [lb, rb] of depth > 0, ie. for
+// every substring occurring at least twice and followed by different chars.
+func lcpIntervals(lcp []int, fn func(depth, lb, rb int)) {
+	type interval struct {
+		depth int
+		lb    int
+	}
+
+	stack := []interval{{0, 0}}
+	for i := 1; i <= len(lcp); i++ {
+		cur := 0
+		if i < len(lcp) {
+			cur = lcp[i]
+		}
+		lb := i - 1
+		for cur < stack[len(stack)-1].depth {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			fn(top.depth, top.lb, i-1)
+			lb = top.lb
+		}
+		if cur > stack[len(stack)-1].depth {
+			stack = append(stack, interval{cur, lb})
+		}
+	}
+}
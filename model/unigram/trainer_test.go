@@ -0,0 +1,82 @@
+package unigram_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/model/unigram"
+)
+
+func TestUnigramTrainer_Train(t *testing.T) {
+	wordCounts := map[string]int{
+		"hug":   10,
+		"pug":   5,
+		"pun":   12,
+		"bun":   4,
+		"hugs":  5,
+		"bugs":  3,
+		"buns":  2,
+		"hunts": 1,
+	}
+
+	trainer := unigram.NewUnigramTrainerBuilder().
+		VocabSize(14).
+		ShowProgress(false).
+		UnkToken("<unk>").
+		SpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("<pad>", true)}).
+		InitialAlphabet([]rune{'z'}).
+		Build()
+
+	m, err := trainer.TrainModel(wordCounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vocab := m.Vocab()
+	if len(vocab) > 14 {
+		t.Errorf("want at most 14 pieces, got %v", len(vocab))
+	}
+
+	// special tokens come first, then all required chars are kept.
+	if vocab[0].Token != "<unk>" || vocab[1].Token != "<pad>" {
+		t.Errorf("want special tokens first, got %#v", vocab[:2])
+	}
+	if unkId, ok := m.UnkId(); !ok || unkId != 0 {
+		t.Errorf("want unk id 0, got %v (%v)", unkId, ok)
+	}
+	for _, c := range []string{"b", "g", "h", "n", "p", "s", "t", "u", "z"} {
+		if _, ok := m.TokenToId(c); !ok {
+			t.Errorf("want %q in vocab", c)
+		}
+	}
+
+	// frequent words are kept as whole pieces.
+	got, err := m.Tokenize("hugs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"hug", "s"}; !reflect.DeepEqual(want, values(got)) {
+		t.Errorf("want %#v\ngot %#v\n", want, values(got))
+	}
+
+	got, err = m.Tokenize("hunts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(values(got), "") != "hunts" {
+		t.Errorf("want a segmentation of %q, got %#v", "hunts", values(got))
+	}
+}
+
+func TestUnigramTrainer_Invalid(t *testing.T) {
+	trainer := unigram.NewUnigramTrainerBuilder().
+		ShrinkingFactor(1).
+		ShowProgress(false).
+		Build()
+
+	if _, err := trainer.TrainModel(map[string]int{"hello": 1}); err == nil {
+		t.Errorf("want error on invalid shrinking factor")
+	}
+}
@@ -0,0 +1,604 @@
+package unigram
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/model"
+	"github.com/sugarme/tokenizer/spm"
+	"github.com/sugarme/tokenizer/util"
+)
+
+// unkPenalty is subtracted from the minimum score of the vocab to score
+// unknown pieces, so that they are only selected when nothing else covers
+// the input.
+const unkPenalty float64 = 10.0
+
+// TokenScore is a vocab entry: a token and its log probability.
+//
+// It is serialized as a `[token, score]` JSON array as in SentencePiece and
+// HuggingFace `tokenizer.json` files.
+type TokenScore struct {
+	Token string
+	Score float64
+}
+
+// MarshalJSON implements json.Marshaler.
+func (ts TokenScore) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{ts.Token, ts.Score})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (ts *TokenScore) UnmarshalJSON(data []byte) error {
+	var raw []interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 2 {
+		return fmt.Errorf("Invalid vocab entry %s: expected [token, score].", string(data))
+	}
+	tok, ok := raw[0].(string)
+	if !ok {
+		return fmt.Errorf("Invalid vocab entry %s: token must be a string.", string(data))
+	}
+	score, ok := raw[1].(float64)
+	if !ok {
+		return fmt.Errorf("Invalid vocab entry %s: score must be a number.", string(data))
+	}
+	ts.Token = tok
+	ts.Score = score
+
+	return nil
+}
+
+var (
+	_ tokenizer.Model       = new(Unigram)
+	_ tokenizer.VocabPruner = new(Unigram)
+)
+
+// Unigram is a SentencePiece-compatible unigram language model.
+//
+// Tokenization finds the segmentation of the input that maximizes the sum of
+// the piece scores (Viterbi decoding). Characters that are not covered by any
+// piece are mapped to the `unk` token, consecutive ones being fused together.
+// When byte fallback is enabled, unknown pieces are instead encoded as their
+// UTF-8 bytes using `<0xXX>` tokens if those exist in the vocab.
+type Unigram struct {
+	vocab        []TokenScore
+	tokenToIds   map[string]int
+	unkId        *int
+	byteFallback bool
+	minScore     float64
+	maxPieceLen  int
+}
+
+// New creates a Unigram model from vocab entries.
+//
+// `unkId` is optional and must be a valid index into vocab when given.
+func New(vocab []TokenScore, unkId *int, byteFallback bool) (*Unigram, error) {
+	if unkId != nil {
+		if len(vocab) == 0 {
+			err := fmt.Errorf("Unigram: unk id is set but the vocab is empty.")
+			return nil, err
+		}
+		if *unkId < 0 || *unkId >= len(vocab) {
+			err := fmt.Errorf("Unigram: unk id %v is out of vocab range (%v).", *unkId, len(vocab))
+			return nil, err
+		}
+	}
+
+	tokenToIds := make(map[string]int, len(vocab))
+	minScore := math.Inf(1)
+	maxPieceLen := 0
+	for id, ts := range vocab {
+		if _, ok := tokenToIds[ts.Token]; ok {
+			err := fmt.Errorf("Unigram: duplicated token %q in vocab.", ts.Token)
+			return nil, err
+		}
+		tokenToIds[ts.Token] = id
+		if ts.Score < minScore {
+			minScore = ts.Score
+		}
+		if len(ts.Token) > maxPieceLen {
+			maxPieceLen = len(ts.Token)
+		}
+	}
+	if len(vocab) == 0 {
+		minScore = 0
+	}
+
+	return &Unigram{
+		vocab:        vocab,
+		tokenToIds:   tokenToIds,
+		unkId:        unkId,
+		byteFallback: byteFallback,
+		minScore:     minScore,
+		maxPieceLen:  maxPieceLen,
+	}, nil
+}
+
+// NewUnigramFromFile creates a Unigram model from a JSON file as written by
+// `Save`.
+func NewUnigramFromFile(file string) (*Unigram, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var m struct {
+		UnkId        *int         `json:"unk_id"`
+		Vocab        []TokenScore `json:"vocab"`
+		ByteFallback bool         `json:"byte_fallback"`
+	}
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(m.Vocab, m.UnkId, m.ByteFallback)
+}
+
+// NewUnigramFromSentencePiece creates a Unigram model from a SentencePiece
+// `.model` file of a unigram model.
+func NewUnigramFromSentencePiece(file string) (*Unigram, error) {
+	m, err := spm.ReadModel(file)
+	if err != nil {
+		return nil, err
+	}
+	if m.TrainerSpec.ModelType != spm.UnigramModel {
+		return nil, fmt.Errorf("Unigram: SentencePiece model %q is not a unigram model (type %v)", file, m.TrainerSpec.ModelType)
+	}
+
+	vocab := make([]TokenScore, len(m.Pieces))
+	for i, p := range m.Pieces {
+		vocab[i] = TokenScore{Token: p.Piece, Score: float64(p.Score)}
+	}
+
+	var unkId *int
+	if id := m.TrainerSpec.UnkId; id >= 0 && id < len(vocab) {
+		unkId = &id
+	}
+
+	return New(vocab, unkId, m.TrainerSpec.ByteFallback)
+}
+
+// UnkId returns the `unk` token id if any.
+func (u *Unigram) UnkId() (int, bool) {
+	if u.unkId == nil {
+		return 0, false
+	}
+	return *u.unkId, true
+}
+
+// ByteFallback returns whether byte fallback is enabled.
+func (u *Unigram) ByteFallback() bool {
+	return u.byteFallback
+}
+
+// Vocab returns the model vocab entries ordered by id.
+func (u *Unigram) Vocab() []TokenScore {
+	return u.vocab
+}
+
+// piece is a segment of the input selected during decoding.
+type piece struct {
+	id    int // -1 for an unknown piece when the model has no `unk` id
+	start int
+	end   int
+}
+
+// hypothesis is a partial segmentation ending at a given byte position.
+type hypothesis struct {
+	score    float64
+	prevPos  int
+	prevRank int
+	id       int
+}
+
+// nbest returns up to n best segmentations of sequence with their scores,
+// highest score first.
+func (u *Unigram) nbest(sequence string, n int) ([][]piece, []float64) {
+	unkId := -1
+	if u.unkId != nil {
+		unkId = *u.unkId
+	}
+	unkScore := u.minScore - unkPenalty
+
+	// hyps[pos] holds the best partial segmentations ending at byte `pos`.
+	hyps := make([][]hypothesis, len(sequence)+1)
+	hyps[0] = []hypothesis{{score: 0, prevPos: -1, prevRank: -1, id: -1}}
+
+	extend := func(start, end, id int, score float64) {
+		for rank, h := range hyps[start] {
+			hyps[end] = append(hyps[end], hypothesis{
+				score:    h.score + score,
+				prevPos:  start,
+				prevRank: rank,
+				id:       id,
+			})
+		}
+	}
+
+	for start := 0; start < len(sequence); start++ {
+		if !utf8.RuneStart(sequence[start]) || len(hyps[start]) == 0 {
+			continue
+		}
+		prune(hyps, start, n)
+
+		_, charLen := utf8.DecodeRuneInString(sequence[start:])
+		hasSingle := false
+		for end := start + charLen; end <= len(sequence) && end-start <= u.maxPieceLen; {
+			if id, ok := u.tokenToIds[sequence[start:end]]; ok {
+				extend(start, end, id, u.vocab[id].Score)
+				if end == start+charLen {
+					hasSingle = true
+				}
+			}
+			if end == len(sequence) {
+				break
+			}
+			_, size := utf8.DecodeRuneInString(sequence[end:])
+			end += size
+		}
+		if !hasSingle {
+			extend(start, start+charLen, unkId, unkScore)
+		}
+	}
+	prune(hyps, len(sequence), n)
+
+	var (
+		paths  [][]piece
+		scores []float64
+	)
+	for rank, h := range hyps[len(sequence)] {
+		var path []piece
+		pos, r := len(sequence), rank
+		for pos > 0 {
+			cur := hyps[pos][r]
+			path = append(path, piece{id: cur.id, start: cur.prevPos, end: pos})
+			pos, r = cur.prevPos, cur.prevRank
+		}
+		for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+			path[i], path[j] = path[j], path[i]
+		}
+		paths = append(paths, path)
+		scores = append(scores, h.score)
+	}
+
+	return paths, scores
+}
+
+// prune keeps the n best hypotheses at pos, highest score first.
+func prune(hyps [][]hypothesis, pos, n int) {
+	h := hyps[pos]
+	// insertion sort: the number of hypotheses per position is small.
+	for i := 1; i < len(h); i++ {
+		for j := i; j > 0 && h[j].score > h[j-1].score; j-- {
+			h[j], h[j-1] = h[j-1], h[j]
+		}
+	}
+	if len(h) > n {
+		hyps[pos] = h[:n]
+	}
+}
+
+// toTokens fuses consecutive unknown pieces, applies byte fallback and
+// builds the resulting tokens.
+func (u *Unigram) toTokens(sequence string, path []piece) ([]tokenizer.Token, error) {
+	isUnk := func(p piece) bool {
+		return p.id == -1 || (u.unkId != nil && p.id == *u.unkId)
+	}
+
+	var fused []piece
+	for _, p := range path {
+		if isUnk(p) && len(fused) > 0 && isUnk(fused[len(fused)-1]) {
+			fused[len(fused)-1].end = p.end
+			continue
+		}
+		fused = append(fused, p)
+	}
+
+	var tokens []tokenizer.Token
+	for _, p := range fused {
+		if !isUnk(p) {
+			tokens = append(tokens, tokenizer.Token{
+				Id:      p.id,
+				Value:   u.vocab[p.id].Token,
+				Offsets: []int{p.start, p.end},
+			})
+			continue
+		}
+
+		if u.byteFallback {
+			if byteTokens, ok := u.byteTokens(sequence, p); ok {
+				tokens = append(tokens, byteTokens...)
+				continue
+			}
+		}
+
+		if u.unkId == nil {
+			err := fmt.Errorf("Unigram: cannot tokenize %q: no piece covers it and `unk_id` is not set.", sequence[p.start:p.end])
+			return nil, err
+		}
+		tokens = append(tokens, tokenizer.Token{
+			Id:      *u.unkId,
+			Value:   u.vocab[*u.unkId].Token,
+			Offsets: []int{p.start, p.end},
+		})
+	}
+
+	return tokens, nil
+}
+
+// byteTokens encodes an unknown piece as `<0xXX>` tokens. It returns false if
+// any of the byte tokens is missing from the vocab.
+func (u *Unigram) byteTokens(sequence string, p piece) ([]tokenizer.Token, bool) {
+	var tokens []tokenizer.Token
+	for i := p.start; i < p.end; i++ {
+		tok := fmt.Sprintf("<0x%02X>", sequence[i])
+		id, ok := u.tokenToIds[tok]
+		if !ok {
+			return nil, false
+		}
+		tokens = append(tokens, tokenizer.Token{
+			Id:      id,
+			Value:   tok,
+			Offsets: []int{i, i + 1},
+		})
+	}
+
+	return tokens, true
+}
+
+// NBest returns up to n best tokenizations of sequence, best first.
+func (u *Unigram) NBest(sequence string, n int) ([][]tokenizer.Token, error) {
+	if n <= 0 {
+		err := fmt.Errorf("Unigram: n must be positive, got %v.", n)
+		return nil, err
+	}
+	if len(sequence) == 0 {
+		return [][]tokenizer.Token{{}}, nil
+	}
+
+	paths, _ := u.nbest(sequence, n)
+	var out [][]tokenizer.Token
+	for _, path := range paths {
+		tokens, err := u.toTokens(sequence, path)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, tokens)
+	}
+
+	return out, nil
+}
+
+// SampleNBest samples a tokenization of sequence among its n best ones,
+// with probabilities proportional to `exp(alpha * score)`. This is the
+// subword regularization of https://arxiv.org/abs/1804.10959.
+func (u *Unigram) SampleNBest(sequence string, n int, alpha float64) ([]tokenizer.Token, error) {
+	if n <= 0 {
+		err := fmt.Errorf("Unigram: n must be positive, got %v.", n)
+		return nil, err
+	}
+	if len(sequence) == 0 {
+		return []tokenizer.Token{}, nil
+	}
+
+	paths, scores := u.nbest(sequence, n)
+	weights := make([]float64, len(scores))
+	var total float64
+	for i, s := range scores {
+		// scores are sorted, so scores[0] is the max.
+		weights[i] = math.Exp(alpha * (s - scores[0]))
+		total += weights[i]
+	}
+
+	idx := len(paths) - 1
+	r := rand.Float64() * total
+	for i, w := range weights {
+		if r < w {
+			idx = i
+			break
+		}
+		r -= w
+	}
+
+	return u.toTokens(sequence, paths[idx])
+}
+
+// Implement Model interface for Unigram
+// =====================================
+
+// Tokenize tokenizes sequence using the best segmentation.
+func (u *Unigram) Tokenize(sequence string) ([]tokenizer.Token, error) {
+	if len(sequence) == 0 {
+		return []tokenizer.Token{}, nil
+	}
+
+	paths, _ := u.nbest(sequence, 1)
+	return u.toTokens(sequence, paths[0])
+}
+
+// TokenToId returns id of a given token if existing.
+func (u *Unigram) TokenToId(token string) (int, bool) {
+	id, ok := u.tokenToIds[token]
+	return id, ok
+}
+
+// IdToToken gets token of given id if existing.
+func (u *Unigram) IdToToken(id int) (string, bool) {
+	if !u.IsValidId(id) {
+		return "", false
+	}
+	return u.vocab[id].Token, true
+}
+
+// IsValidId returns whether the given id maps to a token in the vocab.
+func (u *Unigram) IsValidId(id int) bool {
+	return id >= 0 && id < len(u.vocab)
+}
+
+// GetVocab returns model vocab.
+func (u *Unigram) GetVocab() map[string]int {
+	return u.tokenToIds
+}
+
+// GetVocabSize returns size of vocab.
+func (u *Unigram) GetVocabSize() int {
+	return len(u.vocab)
+}
+
+// PruneVocab keeps the pieces for which keep returns true, along with the
+// `unk` piece, and remaps their ids contiguously. It returns the mapping from
+// old to new ids.
+func (u *Unigram) PruneVocab(keep func(token string, id int) bool) (map[int]int, error) {
+	var mustKeep []string
+	if u.unkId != nil {
+		mustKeep = append(mustKeep, u.vocab[*u.unkId].Token)
+	}
+	mapping := model.PruneVocab(u.tokenToIds, keep, mustKeep...)
+
+	vocab := make([]TokenScore, len(mapping))
+	for oldId, newId := range mapping {
+		vocab[newId] = u.vocab[oldId]
+	}
+	var unkId *int
+	if u.unkId != nil {
+		id := mapping[*u.unkId]
+		unkId = &id
+	}
+
+	pruned, err := New(vocab, unkId, u.byteFallback)
+	if err != nil {
+		return nil, err
+	}
+	*u = *pruned
+
+	return mapping, nil
+}
+
+// Prune returns a copy of the model with at most targetSize pieces, keeping
+// those of highest score. The `unk` piece, the byte fallback `<0xXX>` pieces
+// and the single-char pieces are always kept so that the pruned model covers
+// the same inputs, which may leave more than targetSize pieces. Ids are
+// remapped contiguously in their original order, and the scores of the kept
+// pieces are renormalized to log probabilities summing to one.
+func (u *Unigram) Prune(targetSize int) *Unigram {
+	// Unscored pieces, whose score is not a probability
+	unscored := func(id int) bool {
+		return (u.unkId != nil && id == *u.unkId) || (u.byteFallback && isByteToken(u.vocab[id].Token))
+	}
+
+	keep := make([]bool, len(u.vocab))
+	size := 0
+	var candidates []int
+	for id, ts := range u.vocab {
+		if unscored(id) || utf8.RuneCountInString(ts.Token) == 1 {
+			keep[id] = true
+			size++
+			continue
+		}
+		candidates = append(candidates, id)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return u.vocab[candidates[i]].Score > u.vocab[candidates[j]].Score
+	})
+	for _, id := range candidates {
+		if size >= targetSize {
+			break
+		}
+		keep[id] = true
+		size++
+	}
+
+	logSum := math.Inf(-1)
+	for id, ts := range u.vocab {
+		if keep[id] && !unscored(id) {
+			logSum = logSumExp(logSum, ts.Score)
+		}
+	}
+
+	var vocab []TokenScore
+	var unkId *int
+	for id, ts := range u.vocab {
+		if !keep[id] {
+			continue
+		}
+		if u.unkId != nil && id == *u.unkId {
+			newId := len(vocab)
+			unkId = &newId
+		}
+		if !unscored(id) {
+			ts.Score -= logSum
+		}
+		vocab = append(vocab, ts)
+	}
+
+	pruned, err := New(vocab, unkId, u.byteFallback)
+	if err != nil {
+		// Cannot happen: tokens are unique and unk is kept.
+		panic(err)
+	}
+
+	return pruned
+}
+
+// isByteToken returns whether token is a byte fallback `<0xXX>` token.
+func isByteToken(token string) bool {
+	if len(token) != 6 || token[:3] != "<0x" || token[5] != '>' {
+		return false
+	}
+	for _, c := range token[3:5] {
+		if !('0' <= c && c <= '9' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Save saves the model to `unigram.json` (or `<name>-unigram.json`) in dir.
+func (u *Unigram) Save(dir string, nameOpt ...string) error {
+	var file string
+	if len(nameOpt) > 0 {
+		file = fmt.Sprintf("%v/%v-unigram.json", dir, nameOpt[0])
+	} else {
+		file = fmt.Sprintf("%v/unigram.json", dir)
+	}
+
+	err := os.MkdirAll(filepath.Dir(file), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	data, err := u.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(file, data, os.ModePerm)
+}
+
+// MarshalJSON serializes Unigram in HuggingFace `tokenizer.json` model
+// format.
+func (u *Unigram) MarshalJSON() ([]byte, error) {
+	vocab := u.vocab
+	if vocab == nil {
+		vocab = []TokenScore{}
+	}
+
+	return util.MarshalTyped("Unigram", map[string]interface{}{
+		"unk_id":        u.unkId,
+		"vocab":         vocab,
+		"byte_fallback": u.byteFallback,
+	})
+}
@@ -0,0 +1,300 @@
+package unigram_test
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/model/unigram"
+	"github.com/sugarme/tokenizer/spm"
+)
+
+func values(tokens []tokenizer.Token) []string {
+	var out []string
+	for _, tok := range tokens {
+		out = append(out, tok.Value)
+	}
+	return out
+}
+
+func newModel(t *testing.T, byteFallback bool, entries ...unigram.TokenScore) *unigram.Unigram {
+	unkId := 0
+	vocab := append([]unigram.TokenScore{{Token: "<unk>", Score: 0}}, entries...)
+	m, err := unigram.New(vocab, &unkId, byteFallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestUnigram_Tokenize(t *testing.T) {
+	m := newModel(t, false,
+		unigram.TokenScore{Token: "a", Score: 0},
+		unigram.TokenScore{Token: "b", Score: 0},
+		unigram.TokenScore{Token: "c", Score: 0},
+		unigram.TokenScore{Token: "d", Score: 0},
+		unigram.TokenScore{Token: "cd", Score: 1.0},
+		unigram.TokenScore{Token: "ab", Score: 2.0},
+		unigram.TokenScore{Token: "abc", Score: 5.0},
+		unigram.TokenScore{Token: "abcd", Score: 10.0},
+	)
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"abcd", []string{"abcd"}},
+		{"abc", []string{"abc"}},
+		{"abcdab", []string{"abcd", "ab"}},
+		{"bcd", []string{"b", "cd"}},
+		{"xyab", []string{"<unk>", "ab"}},
+	}
+
+	for _, tt := range tests {
+		got, err := m.Tokenize(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tt.want, values(got)) {
+			t.Errorf("%q: want %#v\ngot %#v\n", tt.input, tt.want, values(got))
+		}
+	}
+
+	// Fused unknown characters cover their whole byte span.
+	got, err := m.Tokenize("éxab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tokenizer.Token{
+		{Id: 0, Value: "<unk>", Offsets: []int{0, 3}},
+		{Id: 6, Value: "ab", Offsets: []int{3, 5}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+}
+
+func TestUnigram_ByteFallback(t *testing.T) {
+	m := newModel(t, true,
+		unigram.TokenScore{Token: "<0xC3>", Score: -0.01},
+		unigram.TokenScore{Token: "<0xA9>", Score: -0.03},
+	)
+
+	got, err := m.Tokenize("é")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tokenizer.Token{
+		{Id: 1, Value: "<0xC3>", Offsets: []int{0, 1}},
+		{Id: 2, Value: "<0xA9>", Offsets: []int{1, 2}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+
+	// "?" has no byte token so it falls back to `unk`.
+	got, err = m.Tokenize("?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]string{"<unk>"}, values(got)) {
+		t.Errorf("want %#v\ngot %#v\n", []string{"<unk>"}, values(got))
+	}
+}
+
+func TestUnigram_NoUnk(t *testing.T) {
+	m, err := unigram.New([]unigram.TokenScore{{Token: "a", Score: 0}}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Tokenize("ab"); err == nil {
+		t.Errorf("want error on unknown char without unk id\n")
+	}
+
+	unkId := 3
+	if _, err := unigram.New([]unigram.TokenScore{{Token: "a", Score: 0}}, &unkId, false); err == nil {
+		t.Errorf("want error on out of range unk id\n")
+	}
+}
+
+func TestUnigram_NBest(t *testing.T) {
+	m := newModel(t, false,
+		unigram.TokenScore{Token: "a", Score: -1},
+		unigram.TokenScore{Token: "b", Score: -1},
+		unigram.TokenScore{Token: "ab", Score: -1.5},
+	)
+
+	got, err := m.NBest("ab", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"ab"}, {"a", "b"}}
+	if len(got) != len(want) {
+		t.Fatalf("want %v results, got %v\n", len(want), len(got))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(want[i], values(got[i])) {
+			t.Errorf("want %#v\ngot %#v\n", want[i], values(got[i]))
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		sample, err := m.SampleNBest("ab", 2, 0.1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := values(sample)
+		if !reflect.DeepEqual(want[0], v) && !reflect.DeepEqual(want[1], v) {
+			t.Errorf("unexpected sample %#v\n", v)
+		}
+	}
+}
+
+func TestUnigram_Save(t *testing.T) {
+	m := newModel(t, true, unigram.TokenScore{Token: "a", Score: -1.5})
+
+	dir, err := os.MkdirTemp("", "unigram")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = m.Save(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := unigram.NewUnigramFromFile(dir + "/unigram.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m.Vocab(), got.Vocab()) || !got.ByteFallback() {
+		t.Errorf("want %#v\ngot %#v\n", m.Vocab(), got.Vocab())
+	}
+	if id, ok := got.UnkId(); !ok || id != 0 {
+		t.Errorf("want unk id 0, got %v\n", id)
+	}
+}
+
+func TestNewUnigramFromSentencePiece(t *testing.T) {
+	m := spm.Model{
+		Pieces: []spm.Piece{
+			{Piece: "<unk>", Type: spm.UnknownPiece},
+			{Piece: "a", Score: -1, Type: spm.NormalPiece},
+			{Piece: "b", Score: -1, Type: spm.NormalPiece},
+			{Piece: "ab", Score: -0.5, Type: spm.NormalPiece},
+		},
+		TrainerSpec: spm.TrainerSpec{ModelType: spm.UnigramModel, PadId: -1},
+	}
+	file := filepath.Join(t.TempDir(), "unigram.model")
+	if err := os.WriteFile(file, m.Marshal(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	model, err := unigram.NewUnigramFromSentencePiece(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := model.Tokenize("abxa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ab", "<unk>", "a"}
+	if !reflect.DeepEqual(want, values(got)) {
+		t.Errorf("want %#v, got %#v", want, values(got))
+	}
+
+	m.TrainerSpec.ModelType = spm.BpeModel
+	if err := os.WriteFile(file, m.Marshal(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unigram.NewUnigramFromSentencePiece(file); err == nil {
+		t.Errorf("want error for a BPE model")
+	}
+}
+
+func TestUnigram_PruneVocab(t *testing.T) {
+	m := newModel(t, false,
+		unigram.TokenScore{Token: "a", Score: 0},
+		unigram.TokenScore{Token: "b", Score: 0},
+		unigram.TokenScore{Token: "c", Score: 0},
+		unigram.TokenScore{Token: "ab", Score: 2.0},
+	)
+
+	mapping, err := m.PruneVocab(func(token string, id int) bool {
+		return token != "<unk>" && token != "b"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int]int{0: 0, 1: 1, 3: 2, 4: 3}; !reflect.DeepEqual(want, mapping) {
+		t.Errorf("want %v, got %v", want, mapping)
+	}
+	if unkId, ok := m.UnkId(); !ok || unkId != 0 {
+		t.Errorf("want unk id 0, got %v", unkId)
+	}
+
+	got, err := m.Tokenize("abcb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tokenizer.Token{
+		{Id: 3, Value: "ab", Offsets: []int{0, 2}},
+		{Id: 2, Value: "c", Offsets: []int{2, 3}},
+		{Id: 0, Value: "<unk>", Offsets: []int{3, 4}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+}
+
+func TestUnigram_Prune(t *testing.T) {
+	m := newModel(t, true,
+		unigram.TokenScore{Token: "<0x41>", Score: 0},
+		unigram.TokenScore{Token: "a", Score: -1},
+		unigram.TokenScore{Token: "b", Score: -1},
+		unigram.TokenScore{Token: "c", Score: -1},
+		unigram.TokenScore{Token: "bcd", Score: -3},
+		unigram.TokenScore{Token: "ab", Score: -2},
+		unigram.TokenScore{Token: "abc", Score: -0.5},
+		unigram.TokenScore{Token: "é", Score: -4},
+	)
+
+	pruned := m.Prune(7)
+
+	var tokens []string
+	sum := 0.0
+	for _, ts := range pruned.Vocab() {
+		tokens = append(tokens, ts.Token)
+		if ts.Token != "<unk>" && ts.Token != "<0x41>" {
+			sum += math.Exp(ts.Score)
+		}
+	}
+	// unk, byte and single-char pieces are kept, then the best of the others.
+	if want := []string{"<unk>", "<0x41>", "a", "b", "c", "abc", "é"}; !reflect.DeepEqual(want, tokens) {
+		t.Errorf("want %q, got %q", want, tokens)
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("want probabilities summing to 1, got %v", sum)
+	}
+	if m.GetVocabSize() != 9 {
+		t.Errorf("want source model unchanged, got %v pieces", m.GetVocabSize())
+	}
+
+	got, err := pruned.Tokenize("abcabé")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"abc", "a", "b", "é"}; !reflect.DeepEqual(want, values(got)) {
+		t.Errorf("want %q, got %q", want, values(got))
+	}
+
+	// Required pieces are kept whatever the target size.
+	if got := m.Prune(0).GetVocabSize(); got != 6 {
+		t.Errorf("want 6 pieces, got %v", got)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"sort"
 
 	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/model"
+	"github.com/sugarme/tokenizer/util"
 )
 
 type config struct {
@@ -43,10 +45,13 @@ func (wlb *WordLevelBuilder) Vocab(vocab map[string]int) {
 	wlb.config.vocab = vocab
 }
 
-// UnkToken set `UNK` token for the vocab
+// UnkToken set `UNK` token for the vocab. The token is appended to the vocab
+// if it is not already in it.
 func (wlb *WordLevelBuilder) UnkToken(unkToken string) {
 	wlb.config.unkToken = unkToken
-	wlb.config.vocab[unkToken] = len(wlb.config.vocab)
+	if _, ok := wlb.config.vocab[unkToken]; !ok {
+		wlb.config.vocab[unkToken] = len(wlb.config.vocab)
+	}
 }
 
 // Build builds a WordLevel using configuration
@@ -63,7 +68,10 @@ func (wlb *WordLevelBuilder) Build() *WordLevel {
 	}
 }
 
-var _ tokenizer.Model = new(WordLevel)
+var (
+	_ tokenizer.Model       = new(WordLevel)
+	_ tokenizer.VocabPruner = new(WordLevel)
+)
 
 // WordLevel is a model for building WordLevel tokenizer
 type WordLevel struct {
@@ -138,12 +146,19 @@ func (wl *WordLevel) GetVocabSize() (retVal int) {
 	return len(wl.vocab)
 }
 
-// Tokenize transforms given input to token
+// GetUnkToken returns the `UNK` token.
+func (wl *WordLevel) GetUnkToken() string {
+	return wl.unkToken
+}
+
+// Tokenize transforms given input to token. Words missing from the vocab are
+// mapped to the `UNK` token.
 func (wl *WordLevel) Tokenize(token string) ([]tokenizer.Token, error) {
 
 	var output []tokenizer.Token
 	var (
 		id        int
+		value     string = token
 		ok, unkOk bool
 	)
 
@@ -151,15 +166,15 @@ func (wl *WordLevel) Tokenize(token string) ([]tokenizer.Token, error) {
 	if !ok {
 		id, unkOk = wl.vocab[wl.unkToken]
 		if !unkOk {
-			fmt.Printf("token: %q\n", token)
-			err := fmt.Errorf("Missing 'unk' token in vocab.\n")
+			err := fmt.Errorf("Cannot tokenize %q: 'unk' token %q is missing from vocab.", token, wl.unkToken)
 			return nil, err
 		}
+		value = wl.unkToken
 	}
 
 	output = append(output, tokenizer.Token{
 		Id:      id,
-		Value:   token,
+		Value:   value,
 		Offsets: []int{0, len(token)},
 	})
 
@@ -178,7 +193,24 @@ func (wl *WordLevel) IdToToken(id int) (string, bool) {
 	return tok, ok
 }
 
-// Save saves vocab to a file
+// IsValidId returns whether the given id maps to a token in the vocab.
+func (wl *WordLevel) IsValidId(id int) bool {
+	_, ok := wl.vocabR[id]
+	return ok
+}
+
+// PruneVocab keeps the tokens for which keep returns true, along with the
+// `UNK` token, and remaps their ids contiguously. It returns the mapping from
+// old to new ids.
+func (wl *WordLevel) PruneVocab(keep func(token string, id int) bool) (map[int]int, error) {
+	mapping := model.PruneVocab(wl.vocab, keep, wl.unkToken)
+	wl.vocab, wl.vocabR = model.Remap(wl.vocab, mapping)
+
+	return mapping, nil
+}
+
+// Save saves the vocab to `vocab.txt` (or `<name>-vocab.txt`) in dir, one
+// token per line in id order, as read by `NewWorldLevelFromFile`.
 func (wl *WordLevel) Save(dir string, nameOpt ...string) (err error) {
 	var vfile string
 	if len(nameOpt) > 0 {
@@ -235,13 +267,9 @@ func (wl *WordLevel) Save(dir string, nameOpt ...string) (err error) {
 }
 
 // makeFilePath creates a filePath. If dir not existing, create it
+// makeFilePath creates the directory of filename if it does not exist.
 func makeFilePath(filename string) error {
-	var err error
-	dirName := filepath.Dir(filename)
-	if _, err = os.Stat(dirName); err != nil {
-		return err
-	}
-	return os.MkdirAll(dirName, os.ModePerm)
+	return os.MkdirAll(filepath.Dir(filename), os.ModePerm)
 }
 
 // New creates new WordLevel from input data.
@@ -261,3 +289,12 @@ func New(vocab map[string]int, unkToken string) (*WordLevel, error) {
 
 	return m, nil
 }
+
+// MarshalJSON serializes WordLevel in HuggingFace `tokenizer.json` model
+// format.
+func (wl *WordLevel) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("WordLevel", map[string]interface{}{
+		"vocab":     wl.vocab,
+		"unk_token": wl.unkToken,
+	})
+}
@@ -0,0 +1,107 @@
+package wordlevel_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/model/wordlevel"
+)
+
+func TestWordLevel_IsValidId(t *testing.T) {
+	vocab := map[string]int{
+		"<unk>": 0,
+		"hello": 1,
+		"world": 2,
+	}
+	m, err := wordlevel.New(vocab, "<unk>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id   int
+		want bool
+	}{
+		{0, true},
+		{2, true},
+		{3, false},
+		{-1, false},
+		{1_000_000, false},
+	}
+
+	for _, tt := range tests {
+		got := m.IsValidId(tt.id)
+		if got != tt.want {
+			t.Errorf("id %v: want %v, got %v\n", tt.id, tt.want, got)
+		}
+	}
+}
+
+func TestWordLevel_UnkToken(t *testing.T) {
+	vocab := map[string]int{
+		"[UNK]": 0,
+		"hello": 1,
+	}
+	m, err := wordlevel.New(vocab, "[UNK]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := m.Tokenize("world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tokenizer.Token{{Id: 0, Value: "[UNK]", Offsets: []int{0, 5}}}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+
+	// Missing `unk` token in the vocab.
+	m, err = wordlevel.New(map[string]int{"hello": 0}, "[UNK]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Tokenize("world"); err == nil {
+		t.Errorf("want error when unk token is missing from the vocab\n")
+	}
+
+	// Builder keeps the id of an existing `unk` token.
+	builder := wordlevel.NewWordLevelBuilder()
+	builder.Vocab(map[string]int{"hello": 0, "[UNK]": 1})
+	builder.UnkToken("[UNK]")
+	m = builder.Build()
+	if id, _ := m.TokenToId("[UNK]"); id != 1 || m.GetVocabSize() != 2 {
+		t.Errorf("want [UNK] id 1 and vocab size 2, got %v and %v\n", id, m.GetVocabSize())
+	}
+	if m.GetUnkToken() != "[UNK]" {
+		t.Errorf("want %q, got %q\n", "[UNK]", m.GetUnkToken())
+	}
+}
+
+func TestWordLevel_Save(t *testing.T) {
+	vocab := map[string]int{
+		"<unk>": 0,
+		"hello": 1,
+		"world": 2,
+	}
+	m, err := wordlevel.New(vocab, "<unk>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The directory is created if needed
+	dir := filepath.Join(t.TempDir(), "model")
+	if err := m.Save(dir, "en"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := wordlevel.NewWorldLevelFromFile(filepath.Join(dir, "en-vocab.txt"), "<unk>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vocab, got.GetVocab()) {
+		t.Errorf("want %v, got %v", vocab, got.GetVocab())
+	}
+}
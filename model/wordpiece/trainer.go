@@ -1,7 +1,14 @@
 package wordpiece
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
+	progressbar "github.com/schollz/progressbar/v2"
+
 	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/model"
 	"github.com/sugarme/tokenizer/model/bpe"
 )
 
@@ -9,6 +16,7 @@ import (
 // configuration.
 type WordPieceTrainerBuilder struct {
 	bpeTrainerBuilder bpe.BpeTrainerBuilder
+	unkToken          string
 }
 
 // NewWordPieceTrainerBuilder create a new WordPieceTrainerBuilder
@@ -18,6 +26,7 @@ func NewWordPieceTrainerBuilder() (retVal WordPieceTrainerBuilder) {
 
 	return WordPieceTrainerBuilder{
 		bpeTrainerBuilder: bpeTrainerBuilder,
+		unkToken:          "[UNK]",
 	}
 }
 
@@ -77,17 +86,36 @@ func (wptb WordPieceTrainerBuilder) EndOfWordSuffix(suffix string) (retVal WordP
 	return wptb
 }
 
-// Build constructs the final BpeTrainer
+// UnkToken set the unknown token of the trained model. It should also be
+// given as special token to be part of the vocabulary.
+func (wptb WordPieceTrainerBuilder) UnkToken(unkToken string) (retVal WordPieceTrainerBuilder) {
+	wptb.unkToken = unkToken
+	return wptb
+}
+
+// Build constructs the final WordPieceTrainer
 func (wptb WordPieceTrainerBuilder) Build() (retVal WordPieceTrainer) {
 
 	bpeTrainer := *wptb.bpeTrainerBuilder.Build()
-	return WordPieceTrainer{bpeTrainer: bpeTrainer}
-
+	return WordPieceTrainer{bpeTrainer: bpeTrainer, unkToken: wptb.unkToken}
 }
 
-// WordPieceTrainer is a trainer for WordPiece model
+// WordPieceTrainer is a trainer for WordPiece model.
+//
+// Like BPE, it starts from the alphabet and iteratively merges pairs of
+// symbols, but it picks the pair maximizing the likelihood of the training
+// data, ie. with the highest `count(ab) / (count(a) * count(b))` score,
+// rather than the most frequent one.
 type WordPieceTrainer struct {
 	bpeTrainer bpe.BpeTrainer
+	unkToken   string
+	progressFn func(tokenizer.Progress)
+}
+
+// SetProgressFunc sets a function called on progress of the merges. It
+// implements `tokenizer.ProgressTrainer`.
+func (wpt *WordPieceTrainer) SetProgressFunc(fn func(tokenizer.Progress)) {
+	wpt.progressFn = fn
 }
 
 // Builder creates WordPieceTrainerBuilder
@@ -98,17 +126,165 @@ func (wpt WordPieceTrainer) Builder() (retVal WordPieceTrainerBuilder) {
 // Implement Trainer interface for WordPieceTrainer:
 // =================================================
 
-func (wpt WordPieceTrainer) Train(wordCounts map[string]int) (retVal tokenizer.Model) {
+var _ tokenizer.ProgressTrainer = new(WordPieceTrainer)
+
+// Train trains a WordPiece model on input wordCounts and returns the model
+// along with the special tokens to be added to the tokenizer.
+func (wpt WordPieceTrainer) Train(wordCounts map[string]int) (tokenizer.Model, []tokenizer.AddedToken) {
+	wordToId, idToWord, words, counts := wpt.bpeTrainer.InitialWords(wordCounts)
+
+	var pb *progressbar.ProgressBar
+	if wpt.bpeTrainer.ShowProgress {
+		pb = progressbar.NewOptions(wpt.bpeTrainer.VocabSize, progressbar.OptionSetDescription("Compute merges"))
+		pb.Set(len(wordToId))
+	}
+
+	// Count symbols and pairs of symbols
+	symbolCounts := make(map[int]int)
+	pairCounts := make(map[bpe.Pair]int)
+	where := make(map[bpe.Pair]bpe.UintSet)
+	for i, word := range words {
+		for x, sym := range word.Symbols {
+			symbolCounts[sym.C] += counts[i]
+			if x+1 < len(word.Symbols) {
+				pair := bpe.Pair{C1: sym.C, C2: word.Symbols[x+1].C}
+				pairCounts[pair] += counts[i]
+				if _, ok := where[pair]; !ok {
+					where[pair] = make(bpe.UintSet)
+				}
+				where[pair][i] = struct{}{}
+			}
+		}
+	}
+
+	minFrequency := wpt.bpeTrainer.MinFrequency
+	if minFrequency < 1 {
+		minFrequency = 1
+	}
+
+	prefix := ""
+	if p := wpt.bpeTrainer.ContinuingSubwordPrefix; p != nil {
+		prefix = *p
+	}
+
+	for len(wordToId) < wpt.bpeTrainer.VocabSize {
+		top, ok := bestPair(pairCounts, symbolCounts, minFrequency)
+		if !ok {
+			break
+		}
+
+		newToken := idToWord[top.C1] + strings.TrimPrefix(idToWord[top.C2], prefix)
+		newTokenId, ok := wordToId[newToken]
+		if !ok {
+			newTokenId = len(idToWord)
+			idToWord = append(idToWord, newToken)
+			wordToId[newToken] = newTokenId
+		}
 
-	bpeModel, _ := wpt.bpeTrainer.Train(wordCounts)
+		// Merge the pair in every words containing it and update counts
+		for _, i := range sortedIndices(where[top]) {
+			before := countSymbol(words[i], newTokenId)
+			changes, err := words[i].Merge(top.C1, top.C2, newTokenId)
+			if err != nil {
+				panic(err)
+			}
 
-	return NewWordPieceFromBPE(bpeModel.(bpe.BPE))
+			for _, change := range changes {
+				pair := bpe.Pair{C1: change.C1, C2: change.C2}
+				pairCounts[pair] += change.Change * counts[i]
+				if change.Change > 0 {
+					if _, ok := where[pair]; !ok {
+						where[pair] = make(bpe.UintSet)
+					}
+					where[pair][i] = struct{}{}
+				}
+			}
+
+			merged := (countSymbol(words[i], newTokenId) - before) * counts[i]
+			symbolCounts[top.C1] -= merged
+			symbolCounts[top.C2] -= merged
+			symbolCounts[newTokenId] += merged
+		}
+		delete(pairCounts, top)
+		delete(where, top)
+
+		if pb != nil {
+			pb.Set(len(wordToId))
+		}
+		if wpt.progressFn != nil {
+			wpt.progressFn(tokenizer.Progress{Stage: "Compute merges", Current: len(wordToId), Total: wpt.bpeTrainer.VocabSize})
+		}
+	}
+
+	if pb != nil {
+		pb.Finish()
+		fmt.Println()
+	}
+
+	vocab := model.Vocab(wordToId)
+	wp := NewWordPieceBuilder().
+		Vocab(&vocab).
+		UnkToken(wpt.unkToken).
+		ContinuingSubwordPrefix(prefix).
+		Build()
+
+	return wp, wpt.bpeTrainer.SpecialTokens
+}
+
+// bestPair returns the pair with the highest likelihood score. Ties are broken
+// on the highest count, then on the smallest pair for determinism.
+func bestPair(pairCounts map[bpe.Pair]int, symbolCounts map[int]int, minFrequency int) (bpe.Pair, bool) {
+	var (
+		best      bpe.Pair
+		bestScore float64
+		bestCount int
+		found     bool
+	)
+
+	for pair, count := range pairCounts {
+		if count < minFrequency {
+			continue
+		}
+
+		score := float64(count) / (float64(symbolCounts[pair.C1]) * float64(symbolCounts[pair.C2]))
+		better := !found || score > bestScore ||
+			(score == bestScore && (count > bestCount ||
+				(count == bestCount && (pair.C1 < best.C1 || (pair.C1 == best.C1 && pair.C2 < best.C2)))))
+		if better {
+			best, bestScore, bestCount, found = pair, score, count, true
+		}
+	}
+
+	return best, found
+}
+
+// countSymbol returns the number of symbols `c` in word.
+func countSymbol(word bpe.Word, c int) int {
+	n := 0
+	for _, sym := range word.Symbols {
+		if sym.C == c {
+			n++
+		}
+	}
+	return n
+}
+
+// sortedIndices returns the indices of a UintSet in ascending order.
+func sortedIndices(set bpe.UintSet) []int {
+	indices := make([]int, 0, len(set))
+	for i := range set {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	return indices
 }
 
+// ProcessTokens processes a bunch of tokens, counting them
 func (wpt WordPieceTrainer) ProcessTokens(words map[string]int, tokens []string) {
 	wpt.bpeTrainer.ProcessTokens(words, tokens)
 }
 
+// WithProgressBar returns whether the training shows progress.
 func (wpt WordPieceTrainer) WithProgressBar() (retVal bool) {
 	return wpt.bpeTrainer.WithProgressBar()
 }
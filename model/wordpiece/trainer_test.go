@@ -0,0 +1,57 @@
+package wordpiece_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/model/wordpiece"
+)
+
+func TestWordPieceTrainer_Train(t *testing.T) {
+	wordCounts := map[string]int{
+		"hug":  10,
+		"pug":  5,
+		"pun":  12,
+		"bun":  4,
+		"hugs": 5,
+	}
+
+	trainer := wordpiece.NewWordPieceTrainerBuilder().
+		VocabSize(15).
+		ShowProgress(false).
+		SpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[UNK]", true)}).
+		Build()
+
+	m, specialTokens := trainer.Train(wordCounts)
+
+	// Merges are picked on likelihood: "##g" + "##s" first even though
+	// "##u" + "##g" is the most frequent pair.
+	want := map[string]int{
+		"[UNK]": 0,
+		"b":     1, "g": 2, "h": 3, "n": 4, "p": 5, "s": 6, "u": 7,
+		"##u": 8, "##n": 9, "##g": 10, "##s": 11,
+		"##gs": 12, "pu": 13, "hu": 14,
+	}
+	got := m.GetVocab()
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v\ngot %v\n", want, got)
+	}
+
+	if len(specialTokens) != 1 || specialTokens[0].Content != "[UNK]" {
+		t.Errorf("want special token [UNK], got %v\n", specialTokens)
+	}
+
+	// The trained model tokenizes with the continuing subword prefix
+	toks, err := m.Tokenize("hugs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []string
+	for _, tok := range toks {
+		values = append(values, tok.Value)
+	}
+	if wantValues := []string{"hu", "##gs"}; !reflect.DeepEqual(wantValues, values) {
+		t.Errorf("want %v, got %v\n", wantValues, values)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
+	"unicode"
 
 	"github.com/sugarme/tokenizer"
 	"github.com/sugarme/tokenizer/model"
@@ -152,14 +154,15 @@ func (wp WordPiece) ReadFiles(filename string) (retVal model.Vocab) {
 	defer file.Close()
 
 	var (
-		vocab model.Vocab
+		vocab model.Vocab = make(map[string]int)
 		line  string
 		idx   int = 0
 	)
 
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		line = scanner.Text()
+		// NOTE. trailing whitespaces (ie. "\r" of CRLF files) are not part of tokens
+		line = strings.TrimRightFunc(scanner.Text(), unicode.IsSpace)
 		vocab[line] = idx
 		idx += 1
 	}
@@ -201,7 +204,8 @@ func NewWordPieceFromFile(vocabFile string, unkToken string, maxInputCharsPerWor
 
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		line = scanner.Text()
+		// NOTE. trailing whitespaces (ie. "\r" of CRLF files) are not part of tokens
+		line = strings.TrimRightFunc(scanner.Text(), unicode.IsSpace)
 		vocab[line] = idx
 		idx += 1
 	}
@@ -341,6 +345,24 @@ func (wp WordPiece) IdToToken(id int) (retVal string, ok bool) {
 	return retVal, ok
 }
 
+// IsValidId returns whether the given id maps to a token in the vocab.
+func (wp WordPiece) IsValidId(id int) bool {
+	_, ok := (*wp.vocabR)[id]
+	return ok
+}
+
+// Save saves the vocab to `vocab.txt` (or `<name>-vocab.txt`) in dir, one
+// token per line in id order, as read by `NewWordPieceFromFile`.
+// PruneVocab keeps the tokens for which keep returns true, along with the
+// `unk` token, and remaps their ids contiguously. It returns the mapping from
+// old to new ids.
+func (wp WordPiece) PruneVocab(keep func(token string, id int) bool) (map[int]int, error) {
+	mapping := model.PruneVocab(*wp.vocab, keep, wp.unkToken)
+	*wp.vocab, *wp.vocabR = model.Remap(*wp.vocab, mapping)
+
+	return mapping, nil
+}
+
 func (wp WordPiece) Save(dir string, nameOpt ...string) (err error) {
 	var vfile string
 	if len(nameOpt) > 0 {
@@ -397,13 +419,9 @@ func (wp WordPiece) Save(dir string, nameOpt ...string) (err error) {
 }
 
 // makeFilePath creates a filePath. If dir not existing, create it
+// makeFilePath creates the directory of filename if it does not exist.
 func makeFilePath(filename string) error {
-	var err error
-	dirName := filepath.Dir(filename)
-	if _, err = os.Stat(dirName); err != nil {
-		return err
-	}
-	return os.MkdirAll(dirName, os.ModePerm)
+	return os.MkdirAll(filepath.Dir(filename), os.ModePerm)
 }
 
 // New creates WordPiece model from input data.
@@ -418,7 +436,7 @@ func New(
 	if opts.Has("unk_token") {
 		unkToken = opts.Get("unk_token").(string)
 	}
-	if opts.Has("continuingSubwordPrefix") {
+	if opts.Has("continuing_subword_prefix") {
 		continuingSubwordPrefix = opts.Get("continuing_subword_prefix").(string)
 	}
 	if opts.Has("max_input_chars_per_word") {
@@ -439,3 +457,14 @@ func New(
 
 	return &m, nil
 }
+
+// MarshalJSON serializes WordPiece in HuggingFace `tokenizer.json` model
+// format.
+func (wp WordPiece) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("WordPiece", map[string]interface{}{
+		"unk_token":                 wp.unkToken,
+		"continuing_subword_prefix": wp.continueSubwordPrefix,
+		"max_input_chars_per_word":  wp.maxInputCharsPerWord,
+		"vocab":                     wp.vocab,
+	})
+}
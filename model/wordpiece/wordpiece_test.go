@@ -1,10 +1,13 @@
 package wordpiece_test
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
 	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/model"
 	"github.com/sugarme/tokenizer/model/wordpiece"
 )
 
@@ -70,3 +73,36 @@ func TestWordpieceTokenize(t *testing.T) {
 		t.Errorf("\nwant %q,\ngot  %+v", want, got)
 	}
 }
+
+func TestWordPiece_ReadFilesCRLF(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "vocab.txt")
+	if err := os.WriteFile(file, []byte("[UNK]\r\nhello\r\n##ing \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := wordpiece.NewWordPiece().ReadFiles(file)
+	want := model.Vocab{"[UNK]": 0, "hello": 1, "##ing": 2}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestWordPiece_Save(t *testing.T) {
+	vocab := model.Vocab{"[UNK]": 0, "hello": 1, "##ing": 2}
+	m := wordpiece.NewWordPiece()
+	m = m.Builder().Vocab(&vocab).UnkToken("[UNK]").Build()
+
+	// The directory is created if needed
+	dir := filepath.Join(t.TempDir(), "model")
+	if err := m.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := wordpiece.NewWordPieceFromFile(filepath.Join(dir, "vocab.txt"), "[UNK]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m.GetVocab(), got.GetVocab()) {
+		t.Errorf("want %v, got %v", m.GetVocab(), got.GetVocab())
+	}
+}
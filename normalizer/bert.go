@@ -20,28 +20,21 @@ func NewBertNormalizer(cleanText, lowercase, handleChineseChars, stripAccents bo
 	}
 }
 
-// IsWhitespace checks whether rune c is a BERT whitespace character
+// IsWhitespace checks whether rune c is a BERT whitespace character, ie. any
+// char with the Unicode White_Space property (including `\t`, `\n`, `\r`).
 func isWhitespace(c rune) bool {
-	// NOTE. `unicode.IsSpace(c rune)` has more cases
-	switch c {
-	case ' ', '\t', '\n', '\r':
-		return true
-	default:
-		return false
-	}
+	return unicode.IsSpace(c)
 }
 
-// IsControl checks whether rune c is a BERT control character
+// IsControl checks whether rune c is a BERT control character. BERT extends
+// it to the whole Unicode `C` (Other) category except `\t`, `\n` and `\r`
+// which are counted as whitespaces.
 func isControl(c rune) bool {
 	switch c {
-	case '\t':
-		return false
-	case '\n':
-		return false
-	case '\r':
+	case '\t', '\n', '\r':
 		return false
 	}
-	return unicode.In(c, unicode.Cc, unicode.Cf)
+	return unicode.In(c, unicode.C)
 }
 
 // bpunc is the BERT extension of the Punctuation character range
@@ -106,53 +99,24 @@ func IsChinese(c rune) bool {
 	return isChinese(c)
 }
 
+// doCleanText removes invalid and control chars, and replaces all sorts of
+// whitespaces with a single ` `.
 func doCleanText(n *NormalizedString) *NormalizedString {
-
-	s := n.normalized
-	var changeMap []ChangeMap
-
-	// Fisrt, reverse the string
-	var oRunes []rune = []rune(s)
-
-	revRunes := make([]rune, 0)
-	for i := len(oRunes) - 1; i >= 0; i-- {
-		revRunes = append(revRunes, oRunes[i])
-	}
-
-	// Then, clean up
-	var removed int = 0
-	for _, r := range revRunes {
-		if r == 0 || r == 0xfffd || isControl(r) {
-			removed += 1
-		} else {
-			if removed > 0 {
-				if isWhitespace(r) {
-					r = ' '
-				}
-				changeMap = append(changeMap, ChangeMap{
-					RuneVal: string(r),
-					Changes: -removed,
-				})
-				removed = 0
-			} else if removed == 0 {
-				changeMap = append(changeMap, ChangeMap{
-					RuneVal: string(r),
-					Changes: 0,
-				})
-			}
+	return n.Filter(func(r rune) bool {
+		return !(r == 0 || r == 0xfffd || isControl(r))
+	}).Map(func(r rune) rune {
+		if isWhitespace(r) {
+			return ' '
 		}
-	}
-
-	// Flip back changeMap
-	var unrevMap []ChangeMap
-	for i := len(changeMap) - 1; i >= 0; i-- {
-		unrevMap = append(unrevMap, changeMap[i])
-	}
-
-	return n.Transform(unrevMap, removed)
+		return r
+	})
 }
 
 func doHandleChineseChars(n *NormalizedString) *NormalizedString {
+	if isASCII(n.normalized) {
+		return n
+	}
+
 	var changeMap []ChangeMap
 	runes := []rune(n.normalized)
 	for _, r := range runes {
@@ -184,8 +148,9 @@ func doLowercase(n *NormalizedString) *NormalizedString {
 	return n.Lowercase()
 }
 
+// stripAccents decomposes accented chars then removes the combining marks.
 func stripAccents(n *NormalizedString) *NormalizedString {
-	return n.RemoveAccents()
+	return n.NFD().RemoveAccents()
 }
 
 // Normalize implements Normalizer interface for BertNormalizer
@@ -198,14 +163,14 @@ func (bn *BertNormalizer) Normalize(n *NormalizedString) (*NormalizedString, err
 		n = doHandleChineseChars(n)
 	}
 
-	if bn.Lowercase {
-		n = doLowercase(n)
-	}
-
 	if bn.StripAccents {
 		n = stripAccents(n)
 	}
 
+	if bn.Lowercase {
+		n = doLowercase(n)
+	}
+
 	return n, nil
 }
 
@@ -0,0 +1,91 @@
+package normalizer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sugarme/tokenizer/normalizer"
+)
+
+func TestBertNormalizer_CleanText(t *testing.T) {
+	n := normalizer.NewNormalizedFrom("a\u0000b\tc\u00a0d\u200de\ufffd")
+
+	out, err := normalizer.NewBertNormalizer(true, false, false, false).Normalize(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test(t, "ab c de", out.GetNormalized())
+	// "c" and "d" are still aligned with the original
+	test(t, []int{4, 5}, out.ConvertOffset(normalizer.NewRange(3, 4, normalizer.NormalizedTarget)).Values())
+	test(t, []int{7, 8}, out.ConvertOffset(normalizer.NewRange(5, 6, normalizer.NormalizedTarget)).Values())
+}
+
+func TestBertNormalizer_ChineseChars(t *testing.T) {
+	n := normalizer.NewNormalizedFrom("ab東京")
+
+	out, err := normalizer.NewBertNormalizer(false, false, true, false).Normalize(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test(t, "ab 東  京 ", out.GetNormalized())
+	test(t, []int{2, 5}, out.ConvertOffset(normalizer.NewRange(3, 6, normalizer.NormalizedTarget)).Values())
+}
+
+func TestBertNormalizer_LowercaseStripAccents(t *testing.T) {
+	n := normalizer.NewNormalizedFrom("Héllo WÖRLD")
+
+	out, err := normalizer.NewBertNormalizer(true, true, true, true).Normalize(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test(t, "hello world", out.GetNormalized())
+
+	n = normalizer.NewNormalizedFrom("Héllo WÖRLD")
+	out, err = normalizer.NewBertNormalizer(true, true, true, false).Normalize(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test(t, "héllo wörld", out.GetNormalized())
+}
+
+func TestBertNormalizer_ASCII(t *testing.T) {
+	bert := normalizer.NewBertNormalizer(true, true, true, true)
+	input := "Hello,\tWORLD!\r\nHow are\x01 you?"
+
+	// The ASCII fast path gives the same result as the general one, forced
+	// by a non-ASCII suffix.
+	fast, err := bert.Normalize(normalizer.NewNormalizedFrom(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slow, err := bert.Normalize(normalizer.NewNormalizedFrom(input + "é"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "hello, world!  how are you?"
+	test(t, want, fast.GetNormalized())
+	test(t, want+"e", slow.GetNormalized())
+	test(t, slow.Alignments()[:len(want)], fast.Alignments())
+	test(t, slow.AlignmentsOriginal()[:len(input)], fast.AlignmentsOriginal())
+}
+
+func BenchmarkBertNormalizer(b *testing.B) {
+	bert := normalizer.NewBertNormalizer(true, true, true, true)
+	for name, input := range map[string]string{
+		"ascii":   strings.Repeat("The quick brown fox jumps over the lazy dog. ", 100),
+		"unicode": strings.Repeat("Thé quick brown fox jumps över the lazy dog. ", 100),
+	} {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := bert.Normalize(normalizer.NewNormalizedFrom(input)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
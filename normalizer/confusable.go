@@ -0,0 +1,53 @@
+package normalizer
+
+//go:generate go run ./internal/genconfusables -o confusables_table.go
+
+// SkeletonNormalize canonicalizes visually-similar (confusable) characters
+// to their Latin prototype, preserving alignments. It can be used to detect
+// homoglyph attacks (e.g. Cyrillic "а" in place of Latin "a").
+//
+// Chars are mapped as in the Unicode confusables data, so some Latin chars
+// are mapped too (e.g. "m" => "rn", "I" => "l"). NOTE. Unlike the UTS #39
+// skeleton, the string is not NFD normalized before and after the mapping.
+func (n *NormalizedString) SkeletonNormalize() (retVal *NormalizedString) {
+	var (
+		changeMap []ChangeMap
+		changed   bool
+	)
+
+	for _, r := range n.normalized {
+		s, ok := confusables[r]
+		if !ok {
+			changeMap = append(changeMap, ChangeMap{string(r), 0})
+			continue
+		}
+
+		changed = true
+		for i, c := range s {
+			if i == 0 {
+				changeMap = append(changeMap, ChangeMap{string(c), 0})
+			} else {
+				changeMap = append(changeMap, ChangeMap{string(c), 1})
+			}
+		}
+	}
+
+	if !changed {
+		return n
+	}
+
+	return n.Transform(changeMap, 0)
+}
+
+// Skeleton is a normalizer that replaces confusable characters with their
+// Latin prototype.
+type Skeleton struct{}
+
+func NewSkeleton() *Skeleton {
+	return new(Skeleton)
+}
+
+// Implement Normalizer for Skeleton
+func (s *Skeleton) Normalize(normalized *NormalizedString) (*NormalizedString, error) {
+	return normalized.SkeletonNormalize(), nil
+}
@@ -0,0 +1,76 @@
+package normalizer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSkeletonNormalize(t *testing.T) {
+	// Cyrillic "а" (U+0430, 2 bytes) in place of Latin "a"
+	original := "bаd"
+	n := NewNormalizedFrom(original)
+
+	out := n.SkeletonNormalize()
+
+	gotNormalized := out.GetNormalized()
+	wantNormalized := "bad"
+	if !reflect.DeepEqual(wantNormalized, gotNormalized) {
+		t.Errorf("want %v, got %v\n", wantNormalized, gotNormalized)
+	}
+
+	gotAlignments := out.Alignments()
+	wantAlignments := [][]int{
+		{0, 1},
+		{1, 3},
+		{3, 4},
+	}
+	if !reflect.DeepEqual(wantAlignments, gotAlignments) {
+		t.Errorf("want %v, got %v\n", wantAlignments, gotAlignments)
+	}
+
+	gotOriginal := out.AlignmentsOriginal()
+	wantOriginal := [][]int{
+		{0, 1},
+		{1, 2},
+		{1, 2},
+		{2, 3},
+	}
+	if !reflect.DeepEqual(wantOriginal, gotOriginal) {
+		t.Errorf("want %v, got %v\n", wantOriginal, gotOriginal)
+	}
+
+	gotRange := out.RangeOriginal(NewRange(1, 2, NormalizedTarget))
+	wantRange := "а"
+	if !reflect.DeepEqual(wantRange, gotRange) {
+		t.Errorf("want %v, got %v\n", wantRange, gotRange)
+	}
+}
+
+func TestSkeletonNormalize_MultipleChars(t *testing.T) {
+	// "ﬁ" (U+FB01, 3 bytes) maps to the 2 chars "fi"
+	n := NewNormalizedFrom("ﬁx")
+
+	out := n.SkeletonNormalize()
+
+	gotNormalized := out.GetNormalized()
+	wantNormalized := "fix"
+	if !reflect.DeepEqual(wantNormalized, gotNormalized) {
+		t.Errorf("want %v, got %v\n", wantNormalized, gotNormalized)
+	}
+
+	gotAlignments := out.Alignments()
+	wantAlignments := [][]int{
+		{0, 3},
+		{0, 3},
+		{3, 4},
+	}
+	if !reflect.DeepEqual(wantAlignments, gotAlignments) {
+		t.Errorf("want %v, got %v\n", wantAlignments, gotAlignments)
+	}
+
+	gotRange := out.RangeOriginal(NewRange(1, 2, NormalizedTarget))
+	wantRange := "ﬁ"
+	if !reflect.DeepEqual(wantRange, gotRange) {
+		t.Errorf("want %v, got %v\n", wantRange, gotRange)
+	}
+}
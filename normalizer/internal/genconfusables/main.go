@@ -0,0 +1,162 @@
+// Command genconfusables generates the confusables table of package
+// normalizer from the Unicode confusables data (UTS #39 - confusables.txt).
+//
+// Usage:
+//
+//	genconfusables [-in confusables.txt] [-o confusables_table.go]
+//
+// The data is downloaded from the Unicode website unless `-in` is given.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const confusablesURL = "https://www.unicode.org/Public/security/latest/confusables.txt"
+
+func main() {
+	in := flag.String("in", "", "confusables.txt file, downloaded from "+confusablesURL+" if empty")
+	out := flag.String("o", "confusables_table.go", "output Go file")
+	flag.Parse()
+
+	if err := run(*in, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "genconfusables: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(in, out string) error {
+	var r io.Reader
+	if in == "" {
+		resp, err := http.Get(confusablesURL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("downloading %s: %s", confusablesURL, resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	version, table, err := parse(r)
+	if err != nil {
+		return err
+	}
+	src, err := generate(version, table)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(out, src, 0o644)
+}
+
+// parse reads the mappings of confusables.txt, and its version if any.
+//
+// Each data line maps a source code point to a prototype of one or more
+// code points:
+//
+//	0430 ;	0061 ;	MA	# ( а → a ) CYRILLIC SMALL LETTER A → LATIN SMALL LETTER A	#
+func parse(r io.Reader) (version string, table map[rune]string, err error) {
+	table = make(map[rune]string)
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimPrefix(scanner.Text(), "\uFEFF")
+		if v, ok := cutPrefix(line, "# Version:"); ok {
+			version = strings.TrimSpace(v)
+		}
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.Split(line, ";")
+		if len(fields) < 3 {
+			return "", nil, fmt.Errorf("line %v: want 3 fields, got %q", n, line)
+		}
+		source, err := parseCodePoints(fields[0])
+		if err != nil || len(source) != 1 {
+			return "", nil, fmt.Errorf("line %v: invalid source %q", n, fields[0])
+		}
+		target, err := parseCodePoints(fields[1])
+		if err != nil || len(target) == 0 {
+			return "", nil, fmt.Errorf("line %v: invalid target %q", n, fields[1])
+		}
+		table[source[0]] = string(target)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", nil, err
+	}
+	if len(table) == 0 {
+		return "", nil, fmt.Errorf("no mapping found")
+	}
+
+	return version, table, nil
+}
+
+// parseCodePoints parses space separated hexadecimal code points.
+func parseCodePoints(s string) ([]rune, error) {
+	var runes []rune
+	for _, f := range strings.Fields(s) {
+		v, err := strconv.ParseUint(f, 16, 32)
+		if err != nil {
+			return nil, err
+		}
+		runes = append(runes, rune(v))
+	}
+
+	return runes, nil
+}
+
+// cutPrefix is `strings.CutPrefix`, not available in go 1.18.
+func cutPrefix(s, prefix string) (string, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+// generate returns the formatted Go source of table.
+func generate(version string, table map[rune]string) ([]byte, error) {
+	sources := make([]rune, 0, len(table))
+	for r := range table {
+		sources = append(sources, r)
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i] < sources[j] })
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by genconfusables. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package normalizer\n\n")
+	fmt.Fprintf(&buf, "// confusables maps each confusable char to its prototype, from the Unicode\n")
+	fmt.Fprintf(&buf, "// confusables data (UTS #39 - confusables.txt")
+	if version != "" {
+		fmt.Fprintf(&buf, ", version %s", version)
+	}
+	fmt.Fprintf(&buf, ").\n")
+	fmt.Fprintf(&buf, "var confusables = map[rune]string{\n")
+	for _, r := range sources {
+		fmt.Fprintf(&buf, "\t0x%04X: %s,\n", r, strconv.QuoteToASCII(table[r]))
+	}
+	fmt.Fprintf(&buf, "}\n")
+
+	return format.Source(buf.Bytes())
+}
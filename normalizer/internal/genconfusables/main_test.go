@@ -0,0 +1,51 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const data = "\uFEFF# confusables.txt\n" +
+	"# Version: 15.0.0\n" +
+	"\n" +
+	"006D ;\t0072 006E ;\tMA\t# ( m → rn ) LATIN SMALL LETTER M → LATIN SMALL LETTER R, LATIN SMALL LETTER N\t# \n" +
+	"0430 ;\t0061 ;\tMA\t# ( а → a ) CYRILLIC SMALL LETTER A → LATIN SMALL LETTER A\t# \n" +
+	"1D400 ;\t0041 ;\tMA\t# ( 𝐀 → A ) MATHEMATICAL BOLD CAPITAL A → LATIN CAPITAL LETTER A\t# \n"
+
+func TestParse(t *testing.T) {
+	version, table, err := parse(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "15.0.0" {
+		t.Errorf("want version 15.0.0, got %q", version)
+	}
+	want := map[rune]string{'m': "rn", 'а': "a", '𝐀': "A"}
+	if !reflect.DeepEqual(want, table) {
+		t.Errorf("want %q\ngot  %q\n", want, table)
+	}
+
+	for _, line := range []string{"0430 ; 0061\n", "x ; 0061 ; MA\n", "0430 ; ; MA\n", "# only comments\n"} {
+		if _, _, err := parse(strings.NewReader(line)); err == nil {
+			t.Errorf("want error on %q", line)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	src, err := generate("15.0.0", map[rune]string{'а': "a", '%': "º/₀"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "confusables_table.go", src, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"version 15.0.0", "0x0025: \"\\u00ba/\\u2080\",", "0x0430: \"a\","} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("want %q in\n%s", want, src)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package normalizer
+
+// Newline normalizes line endings ("\r\n" and "\r") to "\n".
+type Newline struct{}
+
+func NewNewline() *Newline {
+	return new(Newline)
+}
+
+// Implement Normalizer for Newline
+func (nl *Newline) Normalize(normalized *NormalizedString) (*NormalizedString, error) {
+	return normalized.NormalizeNewlines(), nil
+}
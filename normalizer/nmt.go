@@ -0,0 +1,43 @@
+package normalizer
+
+// Nmt removes control characters and maps a few space-like characters to a
+// plain space, as SentencePiece's `nmt_nfkc` normalization rule does.
+type Nmt struct{}
+
+func NewNmt() *Nmt {
+	return new(Nmt)
+}
+
+// Implement Normalizer for Nmt
+func (nmt *Nmt) Normalize(normalized *NormalizedString) (*NormalizedString, error) {
+	n := normalized.Filter(func(r rune) bool {
+		switch {
+		case r >= 0x0001 && r <= 0x0008,
+			r == 0x000B,
+			r >= 0x000E && r <= 0x001F,
+			r == 0x007F,
+			r == 0x008F,
+			r == 0x009F:
+			return false
+		}
+		return true
+	})
+
+	return n.Map(func(r rune) rune {
+		switch {
+		case r == 0x0009,
+			r == 0x000A,
+			r == 0x000C,
+			r == 0x000D,
+			r == 0x1680,
+			r >= 0x200B && r <= 0x200F,
+			r == 0x2028,
+			r == 0x2029,
+			r == 0x2581,
+			r == 0xFEFF,
+			r == 0xFFFD:
+			return ' '
+		}
+		return r
+	}), nil
+}
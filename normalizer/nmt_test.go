@@ -0,0 +1,32 @@
+package normalizer_test
+
+import (
+	"testing"
+
+	"github.com/sugarme/tokenizer/normalizer"
+)
+
+func TestNmt(t *testing.T) {
+	n := normalizer.NewNormalizedFrom("a\u0001b\tc​d")
+
+	out, err := normalizer.NewNmt().Normalize(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test(t, "ab c d", out.GetNormalized())
+	test(t, "a\u0001b\tc​d", out.GetOriginal())
+}
+
+func TestNmt_Alignments(t *testing.T) {
+	// the removed control char is dropped, U+2581 (3 bytes) maps to a space
+	n := normalizer.NewNormalizedFrom("a\u0001▁b")
+
+	out, err := normalizer.NewNmt().Normalize(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test(t, "a b", out.GetNormalized())
+	test(t, [][]int{{0, 1}, {2, 5}, {5, 6}}, out.Alignments())
+}
@@ -2,10 +2,12 @@ package normalizer
 
 import (
 	"bytes"
+	"encoding/gob"
 	"log"
 	"reflect"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/sugarme/tokenizer/util"
 	slice "github.com/sugarme/tokenizer/util/slice"
@@ -150,6 +152,8 @@ type NormalizedString struct {
 	// of the missing part, so that we can still give offsets from this original
 	// string.
 	originalShift int
+	// Whether alignments are not tracked, see `WithoutAlignments`
+	noAlignments bool
 }
 
 // NewNormalizedFrom creates a Normalized instance from string input
@@ -174,6 +178,17 @@ func NewNormalizedFrom(s string) (retVal *NormalizedString) {
 // (alignments and alignmentsOriginal) without sharing data
 // (data elements are in different memory locations).
 func createAligns(s string) [][]int {
+	if isASCII(s) {
+		// Identity alignments, sharing a single backing array.
+		buf := make([]int, 2*len(s))
+		alignments := make([][]int, len(s))
+		for i := range alignments {
+			buf[2*i], buf[2*i+1] = i, i+1
+			alignments[i] = buf[2*i : 2*i+2 : 2*i+2]
+		}
+		return alignments
+	}
+
 	var alignments [][]int
 	currIdx := 0
 	for i, r := range []rune(s) {
@@ -193,6 +208,24 @@ func createAligns(s string) [][]int {
 	return alignments
 }
 
+// WithoutAlignments stops tracking the alignments of n, in place, for
+// throughput-only workloads: transformations then only update the normalized
+// string, which also becomes the original referential of offsets (ie.
+// `ConvertOffset` returns the same range, `OffsetsOriginal` and slices refer to
+// the normalized string).
+func (n *NormalizedString) WithoutAlignments() (retVal *NormalizedString) {
+	n.alignments = nil
+	n.alignmentsOriginal = nil
+	n.noAlignments = true
+
+	return n
+}
+
+// HasAlignments returns whether n tracks its alignments.
+func (n *NormalizedString) HasAlignments() bool {
+	return !n.noAlignments
+}
+
 func NewNormalizedString(original, normalized string, alignments, alignmentsOriginal [][]int, originalShift int) *NormalizedString {
 	return &NormalizedString{
 		original:           original,
@@ -203,6 +236,50 @@ func NewNormalizedString(original, normalized string, alignments, alignmentsOrig
 	}
 }
 
+// normalizedStringGob is an exported mirror of NormalizedString for gob encoding.
+type normalizedStringGob struct {
+	Original           string
+	Normalized         string
+	Alignments         [][]int
+	AlignmentsOriginal [][]int
+	OriginalShift      int
+	NoAlignments       bool
+}
+
+// GobEncode implements gob.GobEncoder so that structs holding a
+// NormalizedString (e.g. `tokenizer.Encoding`) can be deep copied.
+func (n *NormalizedString) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(normalizedStringGob{
+		Original:           n.original,
+		Normalized:         n.normalized,
+		Alignments:         n.alignments,
+		AlignmentsOriginal: n.alignmentsOriginal,
+		OriginalShift:      n.originalShift,
+		NoAlignments:       n.noAlignments,
+	})
+
+	return buf.Bytes(), err
+}
+
+// GobDecode implements gob.GobDecoder.
+func (n *NormalizedString) GobDecode(data []byte) error {
+	var v normalizedStringGob
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v)
+	if err != nil {
+		return err
+	}
+
+	n.original = v.Original
+	n.normalized = v.Normalized
+	n.alignments = v.Alignments
+	n.alignmentsOriginal = v.AlignmentsOriginal
+	n.originalShift = v.OriginalShift
+	n.noAlignments = v.NoAlignments
+
+	return nil
+}
+
 // GetNormalized returns the Normalized struct
 func (n *NormalizedString) GetNormalized() string {
 	return n.normalized
@@ -238,6 +315,19 @@ func (n *NormalizedString) Shift() int {
 //
 // Returns `nil` when targeting something that is outside range
 func (n *NormalizedString) ConvertOffset(inputRange *Range) (retVal *Range) {
+	if n.noAlignments {
+		// Both referentials are the normalized string.
+		target := inputRange.IntoFullRange(n.Len())
+		if target.start < 0 || target.start > target.end {
+			return nil
+		}
+		var indexOn IndexOn = OriginalTarget
+		if target.indexOn == OriginalTarget {
+			indexOn = NormalizedTarget
+		}
+		return NewRange(target.start, target.end, indexOn)
+	}
+
 	lenOriginal := n.LenOriginal()
 	lenNormalized := n.Len()
 	var (
@@ -270,7 +360,7 @@ func (n *NormalizedString) ConvertOffset(inputRange *Range) (retVal *Range) {
 		return NewRange(0, lenNormalized, indexOn)
 	}
 
-	if !isOriginal && len(n.alignments) == 0 && reflect.DeepEqual(target.Values, []int{0, 0}) {
+	if !isOriginal && len(n.alignments) == 0 && reflect.DeepEqual(target.Values(), []int{0, 0}) {
 		return NewRange(0, lenOriginal, indexOn)
 	}
 
@@ -304,6 +394,9 @@ func (n *NormalizedString) Range(r *Range) (retVal string) {
 
 // RangeOriginal returns substring of ORIGINAL string
 func (n *NormalizedString) RangeOriginal(r *Range) (retVal string) {
+	if n.noAlignments {
+		return n.Range(r)
+	}
 
 	bytes := []byte(n.original)
 	switch r.indexOn {
@@ -328,11 +421,14 @@ func (n *NormalizedString) validateRange(inputRange *Range) (retVal *Range) {
 		s string
 	)
 
-	switch inputRange.indexOn {
-	case OriginalTarget:
+	switch {
+	case n.noAlignments:
+		r = inputRange.IntoFullRange(len(n.normalized))
+		s = n.normalized
+	case inputRange.indexOn == OriginalTarget:
 		r = inputRange.IntoFullRange(len(n.original)) // len in bytes
 		s = n.original
-	case NormalizedTarget:
+	case inputRange.indexOn == NormalizedTarget:
 		r = inputRange.IntoFullRange(len(n.normalized))
 		s = n.normalized
 	default:
@@ -383,35 +479,48 @@ func (n *NormalizedString) validateRange(inputRange *Range) (retVal *Range) {
 	return inputRange
 }
 
-// Slice returns a slice of the current NormalizedString
-// If the range is not on char boundaries, return `nil`
+// Slice returns a new NormalizedString holding the given range of the current
+// one, on either the original or the normalized string. The slice keeps its
+// alignments, and its offsets on the original string through `Shift()`.
+//
+// Out of bound ends are clamped. It returns `nil` if the range is not on char
+// boundaries.
 func (n *NormalizedString) Slice(inputRange *Range) (retVal *NormalizedString) {
 	fullRange := n.validateRange(inputRange)
 	if fullRange == nil {
 		return nil
 	}
 
+	if n.noAlignments {
+		r := fullRange.IntoFullRange(n.Len())
+		s := n.normalized[r.start:r.end]
+		return &NormalizedString{
+			original:      s,
+			normalized:    s,
+			originalShift: n.originalShift + r.start,
+			noAlignments:  true,
+		}
+	}
+
 	// 1. Find range on normalized (nRange) and original string (oRange)
 	var nRange, oRange *Range
 	switch fullRange.indexOn {
 	case OriginalTarget:
 		nRange = n.ConvertOffset(fullRange)
-		oRange = fullRange
+		oRange = fullRange.IntoFullRange(n.LenOriginal())
 	case NormalizedTarget:
-		nRange = fullRange
+		nRange = fullRange.IntoFullRange(n.Len())
 		oRange = n.ConvertOffset(fullRange)
 	}
+	if nRange == nil || oRange == nil {
+		return nil
+	}
 
-	// 2. `nShift` is normalized shift on original string
+	// 2. `nShift` shifts the alignments (offsets on the original string)
 	nShift := oRange.start
 
-	// 3. `oShift` is original shift on original string
-	var oShift int
-	if r := expandAlignments(n.alignmentsOriginal[0:nShift]); r == nil {
-		oShift = 0
-	} else {
-		oShift = r[1]
-	}
+	// 3. `oShift` shifts the original alignments (offsets on the normalized string)
+	oShift := nRange.start
 
 	var (
 		sOriginal, sNormalized           string
@@ -459,11 +568,18 @@ func (n *NormalizedString) TransformRange(inputRange *Range, changeMap []ChangeM
 	// fmt.Printf("normalized: %+v\n", n)
 	// fmt.Printf("inputRange: %v\n", inputRange)
 
-	// I. Update `original` alignments based on `initialOffset`.
-	// If `initialOffset` > 0, there are some chars being replaced.
+	if n.noAlignments {
+		// Both referentials are the normalized string.
+		nRange := inputRange.IntoFullRange(n.Len())
+		var b strings.Builder
+		for _, item := range changeMap {
+			b.WriteString(item.RuneVal)
+		}
+		n.normalized = n.normalized[:nRange.start] + b.String() + n.normalized[nRange.end:]
+		return n
+	}
 
-	// I.1. Determine range on `original` string and `normalized` string
-	// based on `inputRange`
+	// I. Determine the range on the normalized string based on `inputRange`
 	var nRange *Range
 	switch inputRange.indexOn {
 	case NormalizedTarget:
@@ -477,124 +593,33 @@ func (n *NormalizedString) TransformRange(inputRange *Range, changeMap []ChangeM
 		nRange.end = len(n.alignments)
 	}
 
-	// fmt.Printf("nRange: %v\n", nRange)
-
-	// I.2. Get removed chars to a slice (`removedChars`); number of removed
-	// bytes (`initialRemoved`); and update original alignments (`n.alignmensOriginal`)
-
-	// log.Printf("===== TransformRange call (initial offset: %v) ====\n", initialOffset)
-	// Retrieve the original characters that are being replaced. This let us
+	// Retrieve the normalized characters that are being replaced. This let us
 	// compute the change in byte sizes along the way.
 	nBytes := []byte(n.normalized)[nRange.start:nRange.end]
 	replacedNormalized := util.NewRuneIter(bytes.Runes(nBytes))
-	/*
-	 *   fmt.Printf("replaceddNoramlized len: %v\n", replacedNormalized.Len())
-	 *   fmt.Printf("replacedNormalized: %+q\n", func(it *util.RuneIter) []string {
-	 *     var chars []string
-	 *     for {
-	 *       r, ok := it.Next()
-	 *       if !ok {
-	 *         break
-	 *       }
-	 *       chars = append(chars, string(r))
-	 *     }
-	 *     return chars
-	 *   }(replacedNormalized))
-	 *  */
-	replacedNormalized.Reset()
 
-	// Handle the initial offset in the original alignment. All the characters
-	// that were removed from the normalized one should have their width reduced
-	// by the number of bytes we remove
-	endShiftStart := nRange.end
+	// Skip the chars removed at the very beginning
 	initialRemoved := 0
-	if initialOffset > 0 {
-		// log.Printf("=> Clearing alignment for %v chars\n", initialOffset)
-		removedBytes := 0
-		var removedChars []rune
-		for i := 0; i < initialOffset; i++ {
-			c, ok := replacedNormalized.Next()
-			if !ok {
-				// We want to panic here, because the NormalizedString is in
-				// a bad state if this happens. We already modified a lot of things
-				log.Fatalf("1. Expected to remove %v characters but couldn't find them ...\n", initialOffset)
-			}
-			removedBytes += len([]byte(string(c)))
-			removedChars = append(removedChars, c)
+	for i := 0; i < initialOffset; i++ {
+		c, ok := replacedNormalized.Next()
+		if !ok {
+			// We want to panic here, because the NormalizedString is in
+			// a bad state if this happens.
+			log.Fatalf("Expected to remove %v characters but couldn't find them ...\n", initialOffset)
 		}
-		initialRemoved = removedBytes
-		offset := nRange.start
-		oShift := 0
-		// Then we remove all these chars, updating the alignments along the way
-		for _, c := range removedChars {
-			removedORange := expandAlignments(n.alignments[offset : offset+len(string(c))])
-			offset += len(string(c))
-			oShift += len(string(c))
-			alignments := n.alignmentsOriginal[removedORange[0]:removedORange[1]]
-			// log.Printf("Clearing alignments for char: %q - alignments: %+v\n", c, alignments)
-			// 1. Get new alignments
-			var newAlignments [][]int
-			for _, offsets := range alignments {
-				// At the very end we will apply the global shift to the remaining
-				// original offsets. We should start after these to avoid doing it twice
-				if offsets[1] > endShiftStart {
-					endShiftStart = offsets[1]
-				}
-
-				offsets[1] = applySign(offsets[1], -(oShift))
-				// Make sure the starting offset is always smaller or equal to the end
-				if offsets[0] > offsets[1] {
-					offsets[0] = offsets[1]
-				}
-
-				newAlignments = append(newAlignments, offsets)
-			}
-			// 2. Update alignmentOriginal with new alignments in `removedORange` range
-			var aligns [][]int = append(n.alignmentsOriginal[:removedORange[0]], newAlignments...)
-			aligns = append(aligns, n.alignmentsOriginal[removedORange[1]:]...)
-			n.alignmentsOriginal = aligns
-
-			// log.Printf("Cleared: %+v\n", alignments)
-		}
-	} // end `If` block
-
-	// II.	Do the transformation based on input `changeMap`.
-	// 1. 	Update alignments on normalized string(`n.alignments`) and original
-	// 			string (`n.alignmentsOriginal`);
-	// 2. 	collect transformed `chars` to a string
-	// 			variable (`normalized`)
+		initialRemoved += len(string(c))
+	}
 
-	// oShift is the shift to be applied to all original alignments along the way
-	// NOTE. `oShift` and `offset` here are different from ones inside previous block
-	oShift := -(initialRemoved)
+	// II. Do the transformation based on input `changeMap`, collecting the
+	// new alignments of the normalized string (`normalizedAlignments`) and the
+	// transformed chars (`normalizedRunes`).
 	offset := initialRemoved + nRange.start
-	var normalizedAlignments [][]int
-
-	// log.Printf("Applying transformations...\n")
 	var (
-		normalizedRunes []rune
+		normalizedAlignments [][]int
+		normalizedRunes      []rune
 	)
-
-	replacedNormalized.Reset() // make sure iterating from begining
 	for _, item := range changeMap {
-		/*
-		 *     var changeType string
-		 *     switch {
-		 *     case item.Changes == 0:
-		 *       changeType = "Replacing"
-		 *     case item.Changes > 0:
-		 *       changeType = "Adding"
-		 *     case item.Changes < 0:
-		 *       changeType = fmt.Sprintf("Replacing + Removing %v following chars", item.Changes)
-		 *     default:
-		 *       changeType = "Undefined"
-		 *     }
-		 *     // log.Printf("### %+q with size %v : %v with offset %v ###\n", item.RuneVal, len(item.RuneVal), changeType, offset)
-		 *     // log.Printf("### '%v' with size %v : %v with offset %v ###\n", item.RuneVal, len(item.RuneVal), changeType, offset)
-		 *     fmt.Printf("'%v' - changes: %v\n", item.RuneVal, item.Changes)
-		 *  */
 		idx := offset
-		// fmt.Printf("idx: %v\n", idx)
 		var align []int
 		if item.Changes > 0 {
 			if idx < 1 {
@@ -608,155 +633,24 @@ func (n *NormalizedString) TransformRange(inputRange *Range, changeMap []ChangeM
 			align = n.alignments[idx]
 		}
 
-		// If we are replacing a character, find it and compute the change in size
-		var replacedChar rune
-		if item.Changes <= 0 {
-			replacedChar, _ = replacedNormalized.Next()
-		} else {
-			replacedChar = 0 // nil rune value
-		}
+		// If we are replacing a character, find it and compute its size
 		replacedCharSize := 0
-		if replacedChar > 0 {
-			replacedCharSize = len([]byte(string(replacedChar)))
-		}
-		replacedCharSizeChange := len(item.RuneVal) - replacedCharSize
-		if replacedChar > 0 {
-			// log.Printf("Replacing char: '%v' - with a change in size: %v", string(replacedChar), replacedCharSizeChange)
-		}
-
-		// If we are removing some characters, find them too
-		var nChanges int = 0
-		if item.Changes < 0 {
-			nChanges = -item.Changes
-		}
-
-		var (
-			removedChars       []rune
-			totalBytesToRemove int
-		)
-		for i := 0; i < nChanges; i++ {
-			c, ok := replacedNormalized.Next()
-			if !ok {
-				// We want to panic here, because the NormalizedString is in
-				// a bad state if this happens. We already modified a lot of things
-				// log.Fatalf("2. Expected to remove %v characters but couldn't find them ...\n", nChanges)
-			}
-			removedChars = append(removedChars, c)
-			totalBytesToRemove += len(string(c))
-		}
-		// fmt.Printf("removedChars: %+q\n", string(removedChars))
-		// log.Printf("Total bytes to remove: %v\n", totalBytesToRemove)
-
-		// If we are removing characters, there are two possible scenarios:
-		//   1. We remove characters that are part of the original string (most likely)
-		//   2. We remove characters that were previously added (with NFD for example)
-		//      and are just part of the normalized string
-
-		var removingFromOriginal, removingFromNormalized int = 0, 0
-		if totalBytesToRemove > 0 {
-			start := n.alignments[idx][1]
-			end := n.alignments[idx+totalBytesToRemove][1]
-			originalRange := util.MakeRange(start, end)
-			// fmt.Printf("start: %v - end: %v; range: (%+v)\n", start, end, originalRange)
-			removingFromOriginal = len(originalRange)
-			removingFromNormalized = totalBytesToRemove - len(originalRange)
-
-			// log.Printf("Bytes to remove from original alignments: %v\n", removingFromOriginal)
-			// log.Printf("Bytes to remove from normalized alignments: %v\n", removingFromNormalized)
-		}
-
-		// Update the original alignments for the **current** `char`
-		// fmt.Printf("align: %v\n", align)
-		// fmt.Printf("current alignmentsOriginal: %+v\n", n.alignmentsOriginal)
-		alignments := n.alignmentsOriginal[align[0]:align[1]]
-		// fmt.Printf("alignments: %+v\n", alignments)
-		if len(alignments) > 0 {
-			// log.Printf("Updating original alignments: %+v\n", alignments)
-
-			// Let's compute the actual change in size for the original alignment.
-			// This value may be different than `replacedCharSizeChange` because the
-			// char might not exist in the original string. It might have been added.
-			originalRange := expandAlignments(alignments)
-
-			replacedCharSizeChangeOriginal := len(item.RuneVal) - (originalRange[1] - originalRange[0])
-			// log.Printf("Change in size for the current char: %v\n", replacedCharSizeChange)
-			// log.Printf("Change in size in the original alignment for the current char: %v\n", replacedCharSizeChangeOriginal)
-
-			var newAlignments [][]int
-			for _, offsets := range alignments {
-				var newAlign []int = make([]int, 2)
-
-				// A new char is being added, we need to extend the current
-				// alignment. No need to apply the shift in this case as it
-				// should have been applied already (with a previous changes == 0).
-				if item.Changes > 0 {
-					newAlign[0] = offsets[0]
-					newAlign[1] = offsets[1] + len(item.RuneVal)
-				} else {
-					// Otherwise we just apply the shift
-					newAlign[0] = offsets[0]
-					newAlign[1] = applySign(offsets[1], replacedCharSizeChange)
-					newAlign[1] = applySign(newAlign[1], -(removingFromNormalized))
-
-					// NOTE: We only apply oShift if we are:
-					// - Removing characters
-					// - Replacing one, that has the same size in both normalized and
-					//   original alignments.
-					// Otherwise it means we are modifying multiple times the same
-					// original character. This happens when normalized characters
-					// are added (not part of the original), and then replaced.
-					if item.Changes < 0 || replacedCharSizeChange == replacedCharSizeChangeOriginal {
-						newAlign[0] = applySign(newAlign[0], oShift)
-						newAlign[1] = applySign(newAlign[1], oShift)
-					}
-				}
-
-				newAlignments = append(newAlignments, newAlign)
-			}
-
-			// Now, update original alignments for current `char` with new alignments
-			// fmt.Printf("newAlignments: %+v\n", newAlignments)
-			var aligns [][]int
-			if align[0] == 0 {
-				aligns = append(newAlignments, n.alignmentsOriginal[align[1]:]...)
-			} else {
-				aligns = append(n.alignmentsOriginal[:align[0]], newAlignments...)
-				aligns = append(aligns, n.alignmentsOriginal[align[1]:]...)
+		if item.Changes <= 0 {
+			if c, ok := replacedNormalized.Next(); ok {
+				replacedCharSize = len(string(c))
 			}
-			n.alignmentsOriginal = aligns
-			// log.Printf("Updated to: %+v\n", newAlignments)
-			// fmt.Printf("current n.alignmentsOriginal: %+v\n", n.alignmentsOriginal)
 		}
 
-		// If some were removed, we need to zero them out in the original alignments
-		if removingFromOriginal > 0 {
-			start := n.alignments[idx][1]
-			end := n.alignments[idx+totalBytesToRemove][1]
-			// They should use the original alignment of the current character
-			newIdx := n.alignmentsOriginal[align[0]][1]
-			alignments := n.alignmentsOriginal[start:end]
-			var newAlignments [][]int
-			if len(alignments) > 0 {
-				// log.Printf("Removing original alignments: %v\n", alignments)
-				for _, offsets := range alignments {
-					offsets[0] = newIdx
-					offsets[1] = newIdx
-					newAlignments = append(newAlignments, offsets)
-				}
-
-				aligns := append(n.alignmentsOriginal[:start], newAlignments...)
-				aligns = append(aligns, n.alignmentsOriginal[end:]...)
-				n.alignmentsOriginal = aligns
+		// If we are removing some characters, find them too
+		totalBytesToRemove := 0
+		for i := 0; i < -item.Changes; i++ {
+			if c, ok := replacedNormalized.Next(); ok {
+				totalBytesToRemove += len(string(c))
 			}
 		}
 
 		// Keep track of the changes for next offsets
-		offset += replacedCharSize
-		offset += totalBytesToRemove
-		// For the original only the real modifications count
-		oShift += replacedCharSizeChange
-		oShift -= totalBytesToRemove
-		// log.Printf("New normalized alignment: %vx %v\n", len(item.RuneVal), align)
+		offset += replacedCharSize + totalBytesToRemove
 
 		for i := 0; i < len(item.RuneVal); i++ {
 			normalizedAlignments = append(normalizedAlignments, align)
@@ -764,41 +658,6 @@ func (n *NormalizedString) TransformRange(inputRange *Range, changeMap []ChangeM
 
 		// Then we keep only the char for string reconstruction
 		normalizedRunes = append(normalizedRunes, []rune(item.RuneVal)...)
-
-		// fmt.Printf("replacedCharSize: %v\n", replacedCharSize)
-		// fmt.Printf("totalBytesToRemove: %v\n", totalBytesToRemove)
-		// fmt.Printf("next offset: %v\n", offset)
-
-	} // End of `For` block
-
-	// Apply the changes to the remaining original alignments
-	if oShift != 0 {
-		// log.Printf("Shifting the end  from %v using shift: %v\n", endShiftStart, oShift)
-
-		var endShift [][]int
-		for _, item := range n.alignments[endShiftStart:] {
-			endShift = append(endShift, item)
-		}
-		endRange := expandAlignments(endShift)
-
-		// log.Printf("End range: %+v\n", endRange)
-
-		if endRange != nil {
-			alignments := n.alignmentsOriginal[endRange[0]:endRange[1]]
-			var newAlignments [][]int
-			if len(alignments) > 0 {
-				// log.Printf("Alignments before shifting: %+v\n", alignments)
-				for _, offsets := range alignments {
-					newOffset0 := applySign(offsets[0], oShift)
-					newOffset1 := applySign(offsets[1], oShift)
-					newAlignments = append(newAlignments, []int{newOffset0, newOffset1})
-				}
-				aligns := append(n.alignmentsOriginal[:endRange[0]], newAlignments...)
-				aligns = append(aligns, n.alignmentsOriginal[endRange[1]:]...)
-				n.alignmentsOriginal = aligns
-				// log.Printf("After: %+v\n", newAlignments)
-			}
-		}
 	}
 
 	// replace alignments with new ones in range
@@ -828,6 +687,11 @@ func (n *NormalizedString) TransformRange(inputRange *Range, changeMap []ChangeM
 	newNormalized := n.normalized[:nRange.start] + string(normalizedRunes) + n.normalized[nRange.end:]
 	n.normalized = newNormalized
 
+	// NOTE. The original alignments are fully determined by the normalized
+	// ones. Rebuild them so that replacements of a different byte size (ie.
+	// `Replace` with a longer or shorter content) stay aligned.
+	n.alignmentsOriginal = originalAlignments(n.alignments, len(n.original))
+
 	// log.Printf("New normalized alignments: %+v\nNew original alignments: %+v\n", n.alignments, n.alignmentsOriginal)
 	// log.Printf("New normalized string: %q\n", n.normalized)
 
@@ -864,165 +728,64 @@ func (n *NormalizedString) TransformRange(inputRange *Range, changeMap []ChangeM
 // {5, 6},
 // {6, 7},
 func (n *NormalizedString) Transform(m []ChangeMap, initialOffset int) (retVal *NormalizedString) {
-	start := 0
-	end := len(n.original)
-	wholeRange := NewRange(start, end, OriginalTarget)
+	// NOTE. The whole normalized range is taken as is: converting the whole
+	// original range is not always the same. A split in the middle of a char
+	// expanded by the normalization (e.g. "ǆ" => "dž") keeps the whole original
+	// char, so its original alignments start before or end after its own
+	// normalized string.
+	wholeRange := NewRange(0, n.Len(), NormalizedTarget)
 	return n.TransformRange(wholeRange, m, initialOffset)
 }
 
+// NFD applies the Unicode canonical decomposition (NFD) on the normalized
+// string.
 func (n *NormalizedString) NFD() (retVal *NormalizedString) {
-
-	s := n.normalized
-	var (
-		changeMap []ChangeMap
-		it        norm.Iter
-	)
-	// Create slice of (char, changes) to map changing
-	// if added (inserted) rune, changes = 1; `-N` if char
-	// right before N removed chars
-	// changes = 0 if this represents the old one (even if changed)
-
-	// Iterating over string and apply tranformer (NFD). One character at a time
-	// A `character` is defined as:
-	// - a sequence of runes that starts with a starter,
-	// - a rune that does not modify or combine backwards with any other rune,
-	// - followed by possibly empty sequence of non-starters, that is, runes that do (typically accents).
-	// We will iterate over string and apply transformer to each char
-	// If a char composes of one rune, there no changes
-	// If more than one rune, first is no change, the rest is 1 changes
-	it.InitString(norm.NFD, s)
-	for !it.Done() {
-		// runes := []rune(string(it.Next()))
-		runes := bytes.Runes(it.Next())
-
-		for i, r := range runes {
-			switch i := i; {
-			case i == 0:
-				changeMap = append(changeMap, ChangeMap{
-					RuneVal: string(r),
-					Changes: 0,
-				})
-			case i > 0:
-				changeMap = append(changeMap, ChangeMap{
-					RuneVal: string(r),
-					Changes: 1,
-				})
-			}
-		}
-	}
-
-	// for i, c := range changeMap {
-	// fmt.Printf("%v - Char: %+q - changes: %v\n", i, c.RuneVal, c.Changes)
-	// }
-
-	return n.Transform(changeMap, 0)
+	return n.unicodeNormalize(norm.NFD)
 }
 
+// NFC applies the Unicode canonical composition (NFC) on the normalized string.
 func (n *NormalizedString) NFC() (retVal *NormalizedString) {
-	var (
-		changeMap []ChangeMap
-		it        norm.Iter
-	)
-
-	s := n.normalized
-
-	isNFC := norm.Form.IsNormalString(norm.NFC, s)
-	if isNFC {
-		return n
-	}
-
-	it.InitString(norm.NFD, s)
-
-	for !it.Done() {
-		// runes := []rune(string(it.Next()))
-		runes := bytes.Runes(it.Next())
-
-		for i, r := range runes {
-			switch i := i; {
-			case i == 0:
-				changeMap = append(changeMap, ChangeMap{
-					RuneVal: string(r),
-					Changes: 0,
-				})
-			case i > 0:
-				changeMap = append(changeMap, ChangeMap{
-					RuneVal: string(r),
-					Changes: 1,
-				})
-			}
-		}
-	}
-
-	return n.Transform(changeMap, 0)
+	return n.unicodeNormalize(norm.NFC)
 }
 
+// NFKD applies the Unicode compatibility decomposition (NFKD) on the
+// normalized string.
 func (n *NormalizedString) NFKD() (retVal *NormalizedString) {
-
-	s := n.normalized
-	isNFKD := norm.Form.IsNormalString(norm.NFKD, s)
-	if isNFKD {
-		return
-	}
-
-	var (
-		changeMap []ChangeMap
-		it        norm.Iter
-	)
-
-	it.InitString(norm.NFKD, s)
-	for !it.Done() {
-		runes := []rune(string(it.Next()))
-
-		for i, r := range runes {
-			switch i := i; {
-			case i == 0:
-				changeMap = append(changeMap, ChangeMap{
-					RuneVal: string(r),
-					Changes: 0,
-				})
-			case i > 0:
-				changeMap = append(changeMap, ChangeMap{
-					RuneVal: string(r),
-					Changes: 1,
-				})
-			}
-		}
-	}
-
-	return n.Transform(changeMap, 0)
+	return n.unicodeNormalize(norm.NFKD)
 }
 
+// NFKC applies the Unicode compatibility composition (NFKC) on the normalized
+// string.
 func (n *NormalizedString) NFKC() (retVal *NormalizedString) {
+	return n.unicodeNormalize(norm.NFKC)
+}
 
-	var (
-		changeMap []ChangeMap
-		it        norm.Iter
-	)
-
+// unicodeNormalize applies the given Unicode normal form on the normalized
+// string, in place.
+//
+// The string is normalized segment by segment, a segment being a sequence of
+// runes between two normalization boundaries (ie. a starter and its
+// combining marks), which are never composed across. Within a segment:
+//   - if runes are added (decomposition), the added ones are aligned with the
+//     segment;
+//   - if runes are removed (composition), the last remaining one is aligned
+//     with its first original rune, as in HuggingFace tokenizers.
+func (n *NormalizedString) unicodeNormalize(form norm.Form) (retVal *NormalizedString) {
 	s := n.normalized
-
-	isNFKC := norm.Form.IsNormalString(norm.NFKC, s)
-
-	if isNFKC {
+	if isASCII(s) || form.IsNormalString(s) {
 		return n
 	}
 
-	it.InitString(norm.NFKD, n.normalized)
-
-	for !it.Done() {
-		runes := []rune(string(it.Next()))
-
-		if len(runes) == 1 {
-			changeMap = append(changeMap, ChangeMap{
-				RuneVal: string(runes),
-				Changes: 0,
-			})
-		} else if len(runes) > 1 {
-			changeMap = append(changeMap, ChangeMap{
-				RuneVal: string(runes),
-				Changes: -1,
-			})
+	var changeMap []ChangeMap
+	for len(s) > 0 {
+		i := form.NextBoundaryInString(s, true)
+		if i <= 0 {
+			i = len(s)
 		}
+		segment := s[:i]
+		s = s[i:]
+
+		changeMap, _ = replace(changeMap, 0, segment, form.String(segment))
 	}
 
 	return n.Transform(changeMap, 0)
@@ -1030,6 +793,10 @@ func (n *NormalizedString) NFKC() (retVal *NormalizedString) {
 
 // Filter applies filtering on NormalizedString
 func (n *NormalizedString) Filter(fn func(rune) bool) (retVal *NormalizedString) {
+	if strings.IndexFunc(n.normalized, func(r rune) bool { return !fn(r) }) < 0 {
+		// nothing to remove
+		return n
+	}
 
 	var (
 		removed   int = 0
@@ -1076,7 +843,9 @@ func (n *NormalizedString) Filter(fn func(rune) bool) (retVal *NormalizedString)
 
 }
 
-// Prepend adds given string to the begining of NormalizedString
+// Prepend adds given string to the begining of NormalizedString, in place.
+// The added chars are aligned with the first char of the normalized string,
+// and nothing is added to an empty one.
 func (n *NormalizedString) Prepend(s string) (retVal *NormalizedString) {
 	chars := []rune(n.normalized)
 	var changeMap []ChangeMap
@@ -1100,7 +869,9 @@ func (n *NormalizedString) Prepend(s string) (retVal *NormalizedString) {
 	return n.TransformRange(inputRange, changeMap, 0)
 }
 
-// Append adds given string to the end of NormalizedString
+// Append adds given string to the end of NormalizedString, in place.
+// The added chars are aligned with the last char of the normalized string,
+// and nothing is added to an empty one.
 func (n *NormalizedString) Append(s string) (retVal *NormalizedString) {
 
 	if n.normalized == "" {
@@ -1129,9 +900,14 @@ type NormFn func(rune) rune
 // Map maps and applies function to each `char` of normalized string
 func (n *NormalizedString) Map(nfn NormFn) (retVal *NormalizedString) {
 	s := n.normalized
+	if out, ok := mapASCII(s, nfn); ok {
+		n.normalized = out
+		return n
+	}
+
 	var changeMap []ChangeMap
 	for _, r := range []rune(s) {
-		changeMap = append(changeMap, ChangeMap{string(r), 0})
+		changeMap = append(changeMap, ChangeMap{string(nfn(r)), 0})
 	}
 
 	return n.Transform(changeMap, 0)
@@ -1152,23 +928,128 @@ func (n *NormalizedString) ForEach(nfn NormFn) (retVal *NormalizedString) {
 
 // RemoveAccents removes all Unicode Mn group (M non-spacing)
 func (n *NormalizedString) RemoveAccents() (retVal *NormalizedString) {
+	if isASCII(n.normalized) {
+		return n
+	}
 	return n.Filter(func(r rune) bool {
 		return !unicode.Is(unicode.Mn, r)
 	})
 }
 
+// RemoveAccentsLatinOnly removes Unicode Mn group (M non-spacing) chars only
+// when they are attached to a Latin base character. Marks on other scripts
+// (e.g. Arabic or Hebrew vowel marks) are kept as they carry meaning.
+//
+// NOTE. accents need to be decomposed first (e.g. with NFD).
+func (n *NormalizedString) RemoveAccentsLatinOnly() (retVal *NormalizedString) {
+	if isASCII(n.normalized) {
+		return n
+	}
+	runes := []rune(n.normalized)
+	keep := make([]bool, len(runes))
+	latinBase := false
+	for i, r := range runes {
+		if unicode.Is(unicode.Mn, r) {
+			keep[i] = !latinBase
+			continue
+		}
+		keep[i] = true
+		latinBase = unicode.Is(unicode.Latin, r)
+	}
+
+	var (
+		removed   int = 0
+		changeMap []ChangeMap
+	)
+	for i := len(runes) - 1; i >= 0; i-- {
+		if !keep[i] {
+			removed += 1
+			continue
+		}
+		changeMap = append(changeMap, ChangeMap{
+			RuneVal: string(runes[i]),
+			Changes: -(removed),
+		})
+		removed = 0
+	}
+
+	revChangeMap := slice.Reverse(changeMap).([]ChangeMap)
+
+	return n.Transform(revChangeMap, removed)
+}
+
 // Lowercase transforms string to lowercase
+//
+// NOTE. As in HuggingFace tokenizers (Rust), 'İ' is lowercased to "i̇" ('i'
+// followed by U+0307) where Go `strings.ToLower` gives 'i'.
 func (n *NormalizedString) Lowercase() (retVal *NormalizedString) {
-	n.normalized = strings.ToLower(n.normalized)
-
-	return n
+	return n.mapCase(func(s string) string {
+		if s == "\u0130" {
+			return "i\u0307"
+		}
+		return strings.ToLower(s)
+	})
 }
 
 // Uppercase transforms string to uppercase
 func (n *NormalizedString) Uppercase() (retVal *NormalizedString) {
-	n.normalized = strings.ToUpper(n.normalized)
+	return n.mapCase(strings.ToUpper)
+}
 
-	return n
+// mapCase applies a case mapping rune by rune, keeping alignments when a rune
+// changes size or maps to several runes (ie. 'İ' lowercased to "i̇").
+func (n *NormalizedString) mapCase(fn func(string) string) (retVal *NormalizedString) {
+	if isASCII(n.normalized) {
+		// Case mappings map ASCII chars to a single ASCII char: alignments
+		// are unchanged.
+		if out := fn(n.normalized); len(out) == len(n.normalized) && isASCII(out) {
+			n.normalized = out
+			return n
+		}
+	}
+
+	var changeMap []ChangeMap
+	for _, r := range n.normalized {
+		for i, c := range fn(string(r)) {
+			changes := 0
+			if i > 0 {
+				changes = 1
+			}
+			changeMap = append(changeMap, ChangeMap{string(c), changes})
+		}
+	}
+
+	return n.Transform(changeMap, 0)
+}
+
+// isASCII returns whether s only contains ASCII chars.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// mapASCII is the fast path of `Map` on an ASCII string whose chars are all
+// mapped to ASCII chars: alignments are left unchanged. It returns false if s
+// or one of the mapped chars is not ASCII.
+func mapASCII(s string, fn NormFn) (string, bool) {
+	if !isASCII(s) {
+		return "", false
+	}
+
+	out := make([]byte, len(s))
+	for i := 0; i < len(s); i++ {
+		r := fn(rune(s[i]))
+		if r < 0 || r >= utf8.RuneSelf {
+			return "", false
+		}
+		out[i] = byte(r)
+	}
+
+	return string(out), true
 }
 
 // Clear clears the normalized part of the string
@@ -1374,6 +1255,9 @@ func (n *NormalizedString) Len() int {
 
 // LenOriginal returns the length of Original string in bytes
 func (n *NormalizedString) LenOriginal() int {
+	if n.noAlignments {
+		return n.Len()
+	}
 	return len(n.GetOriginal())
 }
 
@@ -1413,6 +1297,34 @@ func RangeOf(s string, r []int) (retVal string) {
 	return string(slicedRunes)
 }
 
+// originalAlignments computes the alignments of each byte of the original
+// string from the alignments of the normalized string. Original bytes with no
+// normalized counterpart (removed) get an empty alignment at the position they
+// were removed from.
+func originalAlignments(alignments [][]int, lenOriginal int) [][]int {
+	retVal := make([][]int, lenOriginal)
+	for i, a := range alignments {
+		for b := a[0]; b < a[1] && b < lenOriginal; b++ {
+			if retVal[b] == nil {
+				retVal[b] = []int{i, i + 1}
+			} else {
+				retVal[b][1] = i + 1
+			}
+		}
+	}
+
+	prevEnd := 0
+	for b, a := range retVal {
+		if a == nil {
+			retVal[b] = []int{prevEnd, prevEnd}
+		} else {
+			prevEnd = a[1]
+		}
+	}
+
+	return retVal
+}
+
 // expandAlignments returns an offsets slice covered by a slice of alignments.
 // Return value is a slice of 2 elements (start, end).
 func expandAlignments(alignments [][]int) (retVal []int) {
@@ -1435,41 +1347,161 @@ func applySign(origin int, signed int) int {
 	}
 }
 
+// Replace replaces each match of pattern in the normalized string with
+// content, in place. As in HuggingFace tokenizers, the new chars are aligned
+// with the last char of the match.
 func (n *NormalizedString) Replace(pattern Pattern, content string) (retVal *NormalizedString) {
-
-	offset := 0
 	matches := pattern.FindMatches(n.normalized)
 
-	if len(matches) == 0 {
-		// return nil
-		return n
+	// NOTE. All the matches are replaced in a single pass, which keeps
+	// replacing many matches (ie. all whitespaces) linear.
+	var (
+		b          strings.Builder
+		alignments [][]int
+		replaced   bool
+	)
+	if !n.noAlignments {
+		alignments = make([][]int, 0, len(n.alignments))
 	}
-
 	for _, m := range matches {
-		if m.Match {
-			start := m.Offsets[0]
-			end := m.Offsets[1]
-			r := []int{m.Offsets[0], m.Offsets[1]}
-			r0 := applySign(r[0], offset)
-			r1 := applySign(r[1], offset)
-
-			newLen := 0
-			var removedChars int // num of removed chars
-			removedStr := n.normalized[r0:r1]
-			removedChars = len([]rune(removedStr))
-
-			var changeMap []ChangeMap
-			for _, r := range []rune(content) {
-				newLen += len([]byte(string(r)))
-				changeMap = append(changeMap, ChangeMap{string(r), 1})
+		start, end := m.Offsets[0], m.Offsets[1]
+		if !m.Match {
+			b.WriteString(n.normalized[start:end])
+			if !n.noAlignments {
+				alignments = append(alignments, n.alignments[start:end]...)
 			}
+			continue
+		}
+
+		replaced = true
+		b.WriteString(content)
+		if n.noAlignments || len(content) == 0 {
+			continue
+		}
+
+		// The new chars share the alignment of the last replaced one.
+		var align []int
+		switch {
+		case end > start:
+			align = n.alignments[end-1]
+		case len(alignments) > 0:
+			align = alignments[len(alignments)-1]
+		default:
+			align = []int{0, 0}
+		}
+		for i := 0; i < len(content); i++ {
+			alignments = append(alignments, align)
+		}
+	}
+
+	if !replaced {
+		return n
+	}
+
+	n.normalized = b.String()
+	if !n.noAlignments {
+		n.alignments = alignments
+		n.alignmentsOriginal = originalAlignments(n.alignments, len(n.original))
+	}
+
+	return n
+}
+
+// NormalizeNewlines converts Windows ("\r\n") and old Mac ("\r") line endings
+// to "\n". The resulting "\n" of a "\r\n" pair is aligned to both original bytes.
+func (n *NormalizedString) NormalizeNewlines() (retVal *NormalizedString) {
+	if !strings.ContainsRune(n.normalized, '\r') {
+		return n
+	}
+	if n.noAlignments {
+		n.normalized = strings.ReplaceAll(strings.ReplaceAll(n.normalized, "\r\n", "\n"), "\r", "\n")
+		return n
+	}
+
+	type crlf struct {
+		nIdx  int // byte index of the new "\n" on normalized string
+		start int // original start of "\r"
+		end   int // original end of "\n"
+	}
+
+	var (
+		changeMap []ChangeMap
+		pairs     []crlf
+		nIdx      int
+	)
+
+	runes := []rune(n.normalized)
+	byteIdx := 0
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\r' && i+1 < len(runes) && runes[i+1] == '\n':
+			changeMap = append(changeMap, ChangeMap{"\n", -1})
+			pairs = append(pairs, crlf{nIdx, n.alignments[byteIdx][0], n.alignments[byteIdx+1][1]})
+			byteIdx += 2
+			nIdx += 1
+			i++
+		case r == '\r':
+			changeMap = append(changeMap, ChangeMap{"\n", 0})
+			byteIdx += 1
+			nIdx += 1
+		default:
+			changeMap = append(changeMap, ChangeMap{string(r), 0})
+			byteIdx += len(string(r))
+			nIdx += len(string(r))
+		}
+	}
+
+	n = n.Transform(changeMap, 0)
+
+	// Widen alignments so that "\n" covers the whole original "\r\n".
+	for _, p := range pairs {
+		n.alignments[p.nIdx] = []int{p.start, p.end}
+		for i := p.start; i < p.end && i < len(n.alignmentsOriginal); i++ {
+			n.alignmentsOriginal[i] = []int{p.nIdx, p.nIdx + 1}
+		}
+	}
+
+	return n
+}
 
-			n = n.TransformRange(NewRange(r0, r1, NormalizedTarget), changeMap, removedChars)
+// BOM is the Unicode byte order mark (U+FEFF), 3 bytes in UTF-8.
+const BOM = '\uFEFF'
 
-			oldLen := end - start
-			offset += newLen - oldLen
+// StripBOM removes a leading byte order mark if any. Alignments still
+// refer to the original string (i.e. including the 3 BOM bytes).
+func (n *NormalizedString) StripBOM() (retVal *NormalizedString) {
+	if !strings.HasPrefix(n.normalized, string(BOM)) {
+		return n
+	}
+
+	// NOTE. The BOM always occupies the first bytes of the normalized string,
+	// so we can just drop its alignments and shift the original ones back
+	// instead of going through `Transform`.
+	size := len(string(BOM))
+	if n.noAlignments {
+		n.normalized = n.normalized[size:]
+		return n
+	}
+	alignments := make([][]int, len(n.alignments)-size)
+	for i, a := range n.alignments[size:] {
+		alignments[i] = []int{a[0], a[1]}
+	}
+
+	shift := func(v int) int {
+		if v < size {
+			return 0
 		}
+		return v - size
 	}
+	alignmentsOriginal := make([][]int, len(n.alignmentsOriginal))
+	for i, a := range n.alignmentsOriginal {
+		alignmentsOriginal[i] = []int{shift(a[0]), shift(a[1])}
+	}
+
+	n.normalized = n.normalized[size:]
+	n.alignments = alignments
+	n.alignmentsOriginal = alignmentsOriginal
 
 	return n
 }
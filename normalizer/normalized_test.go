@@ -530,6 +530,58 @@ func TestNormalized_Replace(t *testing.T) {
 	}
 }
 
+func TestNormalized_NormalizeNewlines(t *testing.T) {
+	n := normalizer.NewNormalizedFrom("a\r\nb")
+	n = n.NormalizeNewlines()
+
+	want := normalizer.NewNormalizedString(
+		"a\r\nb",
+		"a\nb",
+		[][]int{{0, 1}, {1, 3}, {3, 4}},
+		[][]int{{0, 1}, {1, 2}, {1, 2}, {2, 3}},
+		0,
+	)
+	test(t, want, n)
+
+	// The newline covers the whole original "\r\n"
+	got := n.RangeOriginal(normalizer.NewRange(1, 2, normalizer.NormalizedTarget))
+	test(t, "\r\n", got)
+
+	// Lone "\r"
+	n1 := normalizer.NewNormalizedFrom("a\rb")
+	n1 = n1.NormalizeNewlines()
+	test(t, "a\nb", n1.GetNormalized())
+	test(t, [][]int{{0, 1}, {1, 2}, {2, 3}}, n1.Alignments())
+}
+
+func TestNormalized_RemoveAccentsLatinOnly(t *testing.T) {
+	// "é" + Arabic "مَرْحَبًا" (with fatha, sukun and fathatan marks)
+	arabic := "\u0645\u064E\u0631\u0652\u062D\u064E\u0628\u064B\u0627"
+	n := normalizer.NewNormalizedFrom("café " + arabic).NFD()
+	n = n.RemoveAccentsLatinOnly()
+
+	test(t, "cafe "+arabic, n.GetNormalized())
+
+	// "e" still maps to the original "é"
+	got := n.ConvertOffset(normalizer.NewRange(3, 4, normalizer.NormalizedTarget))
+	test(t, []int{3, 5}, got.Values())
+}
+
+func TestNormalized_StripBOM(t *testing.T) {
+	n := normalizer.NewNormalizedFrom("\uFEFFab")
+	n = n.StripBOM()
+
+	test(t, "ab", n.GetNormalized())
+	test(t, [][]int{{3, 4}, {4, 5}}, n.Alignments())
+
+	got := n.ConvertOffset(normalizer.NewRange(0, 1, normalizer.NormalizedTarget))
+	test(t, []int{3, 4}, got.Values())
+
+	// No BOM
+	n1 := normalizer.NewNormalizedFrom("ab")
+	test(t, "ab", n1.StripBOM().GetNormalized())
+}
+
 func TestNormalized_Split(t *testing.T) {
 	n := normalizer.NewNormalizedFrom("The-final--countdown")
 
@@ -563,6 +615,32 @@ func testSplit(t *testing.T, behavior normalizer.SplitDelimiterBehavior, n *norm
 
 }
 
+// A split in the middle of a ligature keeps the whole original char, so its
+// original alignments start before its normalized string.
+func TestNormalized_TransformSplitLigature(t *testing.T) {
+	n := normalizer.NewNormalizedFrom("ǆa").NFKC() // "dža"
+	splits := n.Split(normalizer.NewStringPattern("ž"), normalizer.IsolatedBehavior)
+
+	var got []string
+	var gotAlignments [][][]int
+	for _, split := range splits {
+		s := split.Map(unicode.ToUpper)
+		got = append(got, s.GetNormalized())
+		gotAlignments = append(gotAlignments, s.Alignments())
+	}
+
+	want := []string{"D", "Ž", "A"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Want: %q\n", want)
+		t.Errorf("Got: %q\n", got)
+	}
+	wantAlignments := [][][]int{{{0, 2}}, {{0, 2}, {0, 2}}, {{0, 1}}}
+	if !reflect.DeepEqual(wantAlignments, gotAlignments) {
+		t.Errorf("Want: %v\n", wantAlignments)
+		t.Errorf("Got: %v\n", gotAlignments)
+	}
+}
+
 func TestNormalized_TransformRange_SingleBytes(t *testing.T) {
 
 	n1 := normalizer.NewNormalizedFrom("Hello friend")
@@ -881,3 +959,99 @@ func test(t *testing.T, want, got interface{}) {
 		t.Errorf("Got: %v\n", got)
 	}
 }
+
+func TestNormalized_PrependSlice(t *testing.T) {
+	// RoBERTa-like prefix space: the added space is aligned with the first char.
+	n := normalizer.NewNormalizedFrom("Hello").Prepend(" ")
+	testSlice(t, " Hello", n.GetNormalized())
+	testSlice(t, "H", n.RangeOriginal(normalizer.NewRange(0, 1, normalizer.NormalizedTarget)))
+
+	word := n.Slice(normalizer.NewRange(1, 6, normalizer.NormalizedTarget))
+	testSlice(t, "Hello", word.GetNormalized())
+	testSlice(t, "Hello", word.GetOriginal())
+	testSlice(t, 0, word.Shift())
+
+	// A slice after some removed chars keeps its offsets on the original string.
+	s := normalizer.NewNormalizedFrom("  Hello world").Strip()
+	word = s.Slice(normalizer.NewRange(6, 11, normalizer.NormalizedTarget))
+	testSlice(t, "world", word.GetNormalized())
+	testSlice(t, 8, word.Shift())
+	testSlice(t, [][]int{{0, 1}, {1, 2}, {2, 3}, {3, 4}, {4, 5}}, word.Alignments())
+
+	// Not on char boundaries
+	if got := normalizer.NewNormalizedFrom("héllo").Slice(normalizer.NewRange(0, 2, normalizer.NormalizedTarget)); got != nil {
+		t.Errorf("Want: nil\nGot: %q\n", got.GetNormalized())
+	}
+}
+
+func TestNormalized_ReplaceAlignments(t *testing.T) {
+	n := normalizer.NewNormalizedFrom("Hello   friend")
+	n = n.Replace(normalizer.NewRegexpPattern(`\s+`), "_")
+	testSlice(t, "Hello_friend", n.GetNormalized())
+
+	got := n.ConvertOffset(normalizer.NewRange(6, 12, normalizer.NormalizedTarget))
+	testSlice(t, []int{8, 14}, got.Values())
+	testSlice(t, "friend", n.RangeOriginal(normalizer.NewRange(6, 12, normalizer.NormalizedTarget)))
+}
+
+func TestNormalized_Lowercase(t *testing.T) {
+	// 'İ' (2 bytes) maps to "i̇" (3 bytes), the Kelvin sign (3 bytes) to 'k'
+	n := normalizer.NewNormalizedFrom("\u0130A\u212Ab")
+	n.Lowercase()
+
+	test(t, "i\u0307akb", n.GetNormalized())
+	test(t, [][]int{{0, 2}, {0, 2}, {0, 2}, {2, 3}, {3, 6}, {6, 7}}, n.Alignments())
+	test(t, "Kb", n.RangeOriginal(normalizer.NewRange(4, 6, normalizer.NormalizedTarget)))
+
+	out, err := normalizer.Lowercase().Normalize(normalizer.NewNormalizedFrom("HeLLo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	test(t, "hello", out.GetNormalized())
+}
+
+func TestNormalized_ReplaceMany(t *testing.T) {
+	n := normalizer.NewNormalizedFrom("a  b")
+	n.Replace(normalizer.NewRegexpPattern(`\s`), "▁")
+	test(t, "a▁▁b", n.GetNormalized())
+	test(t, [][]int{{0, 1}, {1, 2}, {1, 2}, {1, 2}, {2, 3}, {2, 3}, {2, 3}, {3, 4}}, n.Alignments())
+	test(t, [][]int{{0, 1}, {1, 4}, {4, 7}, {7, 8}}, n.AlignmentsOriginal())
+
+	n = normalizer.NewNormalizedFrom("a--b")
+	n.Replace(normalizer.NewRunePattern('-'), "")
+	test(t, "ab", n.GetNormalized())
+	test(t, [][]int{{0, 1}, {3, 4}}, n.Alignments())
+	test(t, [][]int{{0, 1}, {1, 1}, {1, 1}, {1, 2}}, n.AlignmentsOriginal())
+}
+
+func TestNormalized_WithoutAlignments(t *testing.T) {
+	n := normalizer.NewNormalizedFrom("\ufeffHéllo\r\nWÖRLD  東京").WithoutAlignments()
+	if n.HasAlignments() {
+		t.Fatalf("want no alignments")
+	}
+
+	n, err := normalizer.NewBertNormalizer(true, true, true, true).Normalize(n.StripBOM().NormalizeNewlines())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Replace(normalizer.NewRunePattern(' '), "_")
+
+	want := "hello_world___東__京_"
+	test(t, want, n.GetNormalized())
+	test(t, [][]int(nil), n.Alignments())
+	test(t, []int{0, len(want)}, n.OffsetsOriginal())
+
+	// Both referentials are the normalized string.
+	test(t, []int{6, 11}, n.ConvertOffset(normalizer.NewRange(6, 11, normalizer.NormalizedTarget)).Values())
+	test(t, "world", n.RangeOriginal(normalizer.NewRange(6, 11, normalizer.NormalizedTarget)))
+
+	slice := n.Slice(normalizer.NewRange(6, 11, normalizer.OriginalTarget))
+	test(t, "world", slice.GetNormalized())
+	test(t, []int{6, 11}, slice.OffsetsOriginal())
+
+	var got []string
+	for _, s := range n.Split(normalizer.NewRunePattern('_'), normalizer.RemovedBehavior) {
+		got = append(got, s.GetNormalized())
+	}
+	test(t, []string{"hello", "world", "東", "京"}, got)
+}
@@ -96,6 +96,11 @@ func NewStringPattern(s string) *StringPattern {
 	return &StringPattern{s}
 }
 
+// String returns the string to match.
+func (s *StringPattern) String() string {
+	return s.string
+}
+
 func (s *StringPattern) FindMatches(inside string) []OffsetsMatch {
 	// If we try to find the matches with an empty string, just don't match anything
 	if s.string == "" {
@@ -118,6 +123,12 @@ func findMatches(re *regexp.Regexp, inside string) []OffsetsMatch {
 
 	matches := re.FindAllStringIndex(inside, -1)
 
+	return offsetsMatches(matches, inside)
+}
+
+// offsetsMatches builds the list of matched and unmatched parts covering the
+// whole `inside` string from the given (ordered) matches.
+func offsetsMatches(matches [][]int, inside string) []OffsetsMatch {
 	// 0. If no matches, just return
 	if len(matches) == 0 {
 		return []OffsetsMatch{
@@ -186,15 +197,28 @@ func findMatches(re *regexp.Regexp, inside string) []OffsetsMatch {
 
 type RegexpPattern struct {
 	re *regexp.Regexp
+
+	// source is the pattern as given by the user (see `String`).
+	source string
+
+	// wsLookahead emulates `\s+(?!\S)` (see `CompileRegexpPattern`)
+	wsLookahead bool
+	wsPrefix    *regexp.Regexp
 }
 
 func NewRegexpPattern(s string) *RegexpPattern {
 	re := regexp.MustCompile(s)
 	return &RegexpPattern{
-		re: re,
+		re:     re,
+		source: s,
 	}
 }
 
+// String returns the pattern as given when it was created.
+func (rp *RegexpPattern) String() string {
+	return rp.source
+}
+
 // FindMatches implements Pattern interface for RegexpPattern
 func (rp *RegexpPattern) FindMatches(inside string) []OffsetsMatch {
 	if len(inside) == 0 {
@@ -206,6 +230,10 @@ func (rp *RegexpPattern) FindMatches(inside string) []OffsetsMatch {
 		}
 	}
 
+	if rp.wsLookahead {
+		return offsetsMatches(findMatchesWsLookahead(rp.re, rp.wsPrefix, inside), inside)
+	}
+
 	return findMatches(rp.re, inside)
 }
 
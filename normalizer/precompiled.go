@@ -1,98 +1,103 @@
 package normalizer
 
 import (
-	"strings"
+	"unicode/utf8"
 
 	"github.com/sugarme/tokenizer/spm"
 
 	"github.com/rivo/uniseg"
 )
 
-func replace(transformations []ChangeMap, oldPart, newPart string) []ChangeMap {
-	oldCount := len(strings.Split(oldPart, ""))
-	newCount := len(strings.Split(newPart, ""))
+// replace appends to transformations the changes replacing oldPart with
+// newPart. Chars removed before any transformation are counted in the
+// returned initial offset instead.
+func replace(transformations []ChangeMap, initialOffset int, oldPart, newPart string) ([]ChangeMap, int) {
+	oldCount := utf8.RuneCountInString(oldPart)
+	newCount := utf8.RuneCountInString(newPart)
 	diff := newCount - oldCount
 
 	// If just replacing characters, all changes should be == 0
-	for _, c := range strings.Split(newPart, "") {
-		t := ChangeMap{
-			RuneVal: c,
+	for _, r := range newPart {
+		transformations = append(transformations, ChangeMap{
+			RuneVal: string(r),
 			Changes: 0,
-		}
-		transformations = append(transformations, t)
+		})
 	}
 
-	// log.Printf("transformations: %+v - diff: %v\n", transformations, diff)
-
 	n := len(transformations)
 	switch {
 	case diff > 0:
 		// If adding some characters, the last diff characters should be == 1
-		for i := n - 1; i >= diff || i >= 0; i-- {
+		for i := n - 1; i >= n-diff; i-- {
 			transformations[i].Changes = 1
 		}
 	case diff < 0:
 		// If removing some characters, the last one should include the diff
-		n := len(transformations)
-		transformations[n-1].Changes += diff
+		if n > 0 {
+			transformations[n-1].Changes += diff
+		} else {
+			initialOffset -= diff
+		}
 	}
 
-	return transformations
+	return transformations, initialOffset
 }
 
+// Precompiled is the normalizer of SentencePiece models, built from their
+// `precompiled_charsmap`.
 type Precompiled struct {
 	*spm.Precompiled
 }
 
-// Implement Normalizer for spm.Precompiled
-func (m *Precompiled) Normalize(normalized *NormalizedString) (*NormalizedString, error) {
-	original := normalized.GetNormalized()
-	var transformations []ChangeMap
-	for i := 0; i < len(original); i++ {
-		transformations = append(transformations, ChangeMap{})
+// NewPrecompiled creates a Precompiled normalizer from a SentencePiece
+// `precompiled_charsmap`.
+func NewPrecompiled(precompiledCharsmap []byte) (*Precompiled, error) {
+	p, err := spm.NewPrecompiledFrom(precompiledCharsmap)
+	if err != nil {
+		return nil, err
 	}
 
-	graphemes := uniseg.NewGraphemes(original)
+	return &Precompiled{p}, nil
+}
 
-	var modified bool
+// Implement Normalizer for spm.Precompiled
+func (m *Precompiled) Normalize(normalized *NormalizedString) (*NormalizedString, error) {
+	var (
+		transformations []ChangeMap
+		initialOffset   int
+		modified        bool
+	)
 
+	graphemes := uniseg.NewGraphemes(normalized.GetNormalized())
 	for graphemes.Next() {
 		grapheme := graphemes.Str()
 
+		// NOTE. See `spm.Precompiled.NormalizeString` on why graphemes are
+		// transformed as a whole or char by char.
 		if len(grapheme) < 6 {
-			norm := m.Transform(grapheme)
-			if len(norm) > 0 {
+			if norm, ok := m.Lookup(grapheme); ok {
 				modified = true
-				transformations = replace(transformations, grapheme, norm)
-
+				transformations, initialOffset = replace(transformations, initialOffset, grapheme, norm)
 				continue
 			}
 		}
 
-		// TT. This is a hacky way to turn non-spacing marks into hexa string
-		// and pass the unit tests.
-		grapheme = spm.NormalizeMn(grapheme)
-		var charIdx int = 0
 		for _, r := range grapheme {
-			part := string(grapheme)[charIdx : charIdx+len(string(r))]
-			norm := m.Transform(part)
-			norm = spm.NormalizeMn(norm)
-			if len(norm) > 0 {
+			part := string(r)
+			if norm, ok := m.Lookup(part); ok {
 				modified = true
-				transformations = replace(transformations, part, norm)
+				transformations, initialOffset = replace(transformations, initialOffset, part, norm)
 			} else {
-				t := ChangeMap{
-					RuneVal: string(r),
+				transformations = append(transformations, ChangeMap{
+					RuneVal: part,
 					Changes: 0,
-				}
-				transformations = append(transformations, t)
+				})
 			}
-			// charIdx += len(string(r))
 		}
 	}
 
 	if modified {
-		normalized = normalized.Transform(transformations, 0)
+		normalized = normalized.Transform(transformations, initialOffset)
 	}
 
 	return normalized, nil
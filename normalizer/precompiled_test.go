@@ -3,22 +3,23 @@ package normalizer
 import (
 	"reflect"
 	"testing"
+
+	"github.com/sugarme/tokenizer/spm"
 )
 
-func TestPrecompiled_Normalize(t *testing.T) {
-	var transformations []ChangeMap = make([]ChangeMap, 10)
+func TestPrecompiled_Replace(t *testing.T) {
+	var transformations []ChangeMap
+	var initialOffset int
 
 	n := NewNormalizedFrom("™\x1eg")
-	transformations = replace(transformations, "™", "TM")
-
-	transformations = replace(transformations, "\x1e", "")
-
+	transformations, initialOffset = replace(transformations, initialOffset, "™", "TM")
+	transformations, initialOffset = replace(transformations, initialOffset, "\x1e", "")
 	transformations = append(transformations, ChangeMap{
 		RuneVal: "g",
 		Changes: 0,
 	})
 
-	n = n.Transform(transformations, 0)
+	n = n.Transform(transformations, initialOffset)
 
 	got := n.GetNormalized()
 	want := "TMg"
@@ -27,3 +28,37 @@ func TestPrecompiled_Normalize(t *testing.T) {
 		t.Errorf("want %s, got %s\n", want, got)
 	}
 }
+
+func TestPrecompiled_Normalize(t *testing.T) {
+	m, err := NewPrecompiled(spm.NmtNfkc())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input string
+		want  string
+		// original range of each normalized byte
+		wantAlignments [][]int
+	}{
+		// the removed char is merged into the last change, as in HuggingFace
+		{"™\x1eg", "TMg", [][]int{{0, 3}, {3, 4}, {4, 5}}},
+		// removed at the very beginning
+		{"\x1eﬁx", "fix", [][]int{{1, 4}, {1, 4}, {4, 5}}},
+		// several graphemes after a transformed one
+		{"ﬁ a", "fi a", [][]int{{0, 3}, {0, 3}, {3, 4}, {4, 5}}},
+	}
+
+	for _, tt := range tests {
+		got, err := m.Normalize(NewNormalizedFrom(tt.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.GetNormalized() != tt.want {
+			t.Errorf("want %q, got %q\n", tt.want, got.GetNormalized())
+		}
+		if !reflect.DeepEqual(tt.wantAlignments, got.Alignments()) {
+			t.Errorf("%q: want %v, got %v\n", tt.input, tt.wantAlignments, got.Alignments())
+		}
+	}
+}
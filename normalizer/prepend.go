@@ -1,6 +1,8 @@
 package normalizer
 
-// Prepend creates a normalizer that strip the normalized string inplace.
+// Prepend adds a prefix to the normalized string (ie. "▁" for SentencePiece
+// BPE models). The prefix is aligned with the first char, and nothing is
+// added to an empty string.
 type Prepend struct {
 	Prepend string `json:"prepend"`
 }
@@ -11,9 +13,5 @@ func NewPrepend(prepend string) *Prepend {
 
 // Implement Normalizer for Prepend
 func (p *Prepend) Normalize(normalized *NormalizedString) (*NormalizedString, error) {
-	if normalized.IsEmpty() {
-		return nil, nil
-	}
-
 	return normalized.Prepend(p.Prepend), nil
 }
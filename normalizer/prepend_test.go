@@ -54,3 +54,13 @@ func TestPrepend(t *testing.T) {
 	}
 
 }
+
+func TestPrepend_Empty(t *testing.T) {
+	out, err := NewPrepend("▁").Normalize(NewNormalizedFrom(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out == nil || out.GetNormalized() != "" {
+		t.Errorf("want empty normalized string, got %+v\n", out)
+	}
+}
@@ -0,0 +1,235 @@
+package normalizer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Translations of Rust (`onig`/`regex` crate) Unicode aware classes to Go
+// `regexp` equivalents. Go `\w`, `\d` and `\s` are ASCII only.
+var (
+	// To be used inside a character class
+	wordClass  = `\p{L}\p{M}\p{Nd}\p{Pc}`
+	digitClass = `\p{Nd}`
+	spaceClass = `\s\p{Z}\x{85}`
+)
+
+// wsLookahead is the whitespace construct used by GPT-2 like patterns. Go
+// `regexp` does not support lookahead, it is emulated instead.
+const wsLookahead = `\s+(?!\S)`
+
+// CompileRegexpPattern compiles a regex pattern as found in `tokenizer.json`
+// files (written for Rust) to a RegexpPattern.
+//
+// Unicode aware `\w`, `\d`, `\s` (and negated `\W`, `\D`, `\S` outside of
+// character classes) are translated to Go equivalents. `\p{...}` classes are
+// kept as is. The GPT-2 `\s+(?!\S)` construct is emulated. Other lookaround,
+// backreference, atomic group or possessive quantifier constructs are not
+// supported by Go and return an error.
+func CompileRegexpPattern(pattern string) (*RegexpPattern, error) {
+	rp := &RegexpPattern{source: pattern}
+
+	source := pattern
+	if strings.Contains(source, wsLookahead) {
+		rp.wsLookahead = true
+		source = strings.ReplaceAll(source, wsLookahead, `\s+`)
+
+		// Alternatives before the construct (eg. `\s*[\r\n]+` of GPT-4 like
+		// patterns) may match whitespaces as well, those matches are kept as is.
+		if prefix := wsLookaheadPrefix(pattern); prefix != "" {
+			re, err := compileTranslated(`^(?:` + prefix + `)`)
+			if err != nil {
+				return nil, err
+			}
+			rp.wsPrefix = re
+		}
+	}
+
+	re, err := compileTranslated(source)
+	if err != nil {
+		return nil, err
+	}
+	rp.re = re
+
+	return rp, nil
+}
+
+func compileTranslated(pattern string) (*regexp.Regexp, error) {
+	translated, err := translateRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	re, err := regexp.Compile(translated)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid regex pattern %q (translated to %q): %w", pattern, translated, err)
+	}
+
+	return re, nil
+}
+
+// wsLookaheadPrefix returns the top level alternatives preceding the `\s+(?!\S)`
+// construct in pattern, or an empty string if there is none.
+func wsLookaheadPrefix(pattern string) string {
+	var (
+		depth   int
+		inClass bool
+	)
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\':
+			i++
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+		case c == '[':
+			inClass = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '|' && depth == 0:
+			if strings.HasPrefix(pattern[i+1:], wsLookahead) {
+				return pattern[:i]
+			}
+		}
+	}
+
+	return ""
+}
+
+// translateRegexp translates Rust regex conventions to Go `regexp` ones.
+func translateRegexp(pattern string) (string, error) {
+	unsupported := []struct {
+		construct string
+		name      string
+	}{
+		{"(?=", "lookahead"},
+		{"(?!", "negative lookahead"},
+		{"(?<=", "lookbehind"},
+		{"(?<!", "negative lookbehind"},
+		{"(?>", "atomic group"},
+		{"++", "possessive quantifier"},
+		{"*+", "possessive quantifier"},
+		{"?+", "possessive quantifier"},
+	}
+
+	var (
+		sb      strings.Builder
+		inClass bool
+		runes   = []rune(pattern)
+	)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		if r != '\\' {
+			if !inClass {
+				for _, u := range unsupported {
+					if strings.HasPrefix(string(runes[i:]), u.construct) {
+						return "", fmt.Errorf("Unsupported regex construct %q (%v) in pattern %q", u.construct, u.name, pattern)
+					}
+				}
+			}
+			switch {
+			case r == '[' && !inClass:
+				inClass = true
+				sb.WriteRune(r)
+				// A leading `]` or `^]` is literal
+				if i+1 < len(runes) && runes[i+1] == '^' {
+					sb.WriteRune('^')
+					i++
+				}
+				if i+1 < len(runes) && runes[i+1] == ']' {
+					sb.WriteRune(']')
+					i++
+				}
+			case r == ']' && inClass:
+				inClass = false
+				sb.WriteRune(r)
+			default:
+				sb.WriteRune(r)
+			}
+			continue
+		}
+
+		// Escape sequence
+		if i+1 >= len(runes) {
+			return "", fmt.Errorf("Invalid regex pattern %q: trailing backslash", pattern)
+		}
+		i++
+		e := runes[i]
+
+		var class string
+		switch e {
+		case 'w', 'W':
+			class = wordClass
+		case 'd', 'D':
+			class = digitClass
+		case 's', 'S':
+			class = spaceClass
+		case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			return "", fmt.Errorf("Unsupported regex construct %q (backreference) in pattern %q", `\`+string(e), pattern)
+		default:
+			sb.WriteRune('\\')
+			sb.WriteRune(e)
+			continue
+		}
+
+		negated := unicode.IsUpper(e)
+		switch {
+		case inClass && negated:
+			return "", fmt.Errorf("Unsupported regex construct %q inside a character class in pattern %q", `\`+string(e), pattern)
+		case inClass:
+			sb.WriteString(class)
+		case negated:
+			sb.WriteString("[^" + class + "]")
+		default:
+			sb.WriteString("[" + class + "]")
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// findMatchesWsLookahead finds all matches emulating `\s+(?!\S)`: a match of
+// whitespaces only followed by a non-whitespace char gives back its last
+// whitespace so that it can be matched with the following word, unless it is a
+// match of the alternatives preceding the construct (prefix).
+func findMatchesWsLookahead(re, prefix *regexp.Regexp, inside string) [][]int {
+	var (
+		matches [][]int
+		pos     int
+	)
+
+	for pos < len(inside) {
+		loc := re.FindStringIndex(inside[pos:])
+		if loc == nil {
+			break
+		}
+		start, end := pos+loc[0], pos+loc[1]
+
+		if start == end {
+			// Empty match, move on to next char
+			_, size := utf8.DecodeRuneInString(inside[end:])
+			pos = end + size
+			continue
+		}
+
+		m := inside[start:end]
+		if end < len(inside) && utf8.RuneCountInString(m) > 1 && strings.TrimFunc(m, unicode.IsSpace) == "" &&
+			(prefix == nil || prefix.FindStringIndex(inside[start:]) == nil) {
+			_, size := utf8.DecodeLastRuneInString(m)
+			end -= size
+		}
+
+		matches = append(matches, []int{start, end})
+		pos = end
+	}
+
+	return matches
+}
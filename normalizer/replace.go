@@ -13,6 +13,11 @@ const (
 	Regex
 )
 
+// Replace replaces the matches of a literal string or regex pattern with a
+// given content. It keeps the alignments of the normalized string, whatever
+// the sizes of the matches and the content (see `NormalizedString.Replace`).
+//
+// It is used both as a normalizer and as a decoder.
 type Replace struct {
 	PatternType ReplacePattern `json:"pattern_type"`
 	Pattern     Pattern        `json:"pattern"`
@@ -21,13 +26,22 @@ type Replace struct {
 
 var _ Normalizer = new(Replace)
 
+// NewReplace creates a Replace from a literal string or a regex pattern.
+// Regex patterns are compiled with `CompileRegexpPattern`.
+//
+// It panics if the regex pattern is invalid. See `NewReplacePattern` to
+// handle the error.
 func NewReplace(patternType ReplacePattern, pattern string, content string) *Replace {
 	var pat Pattern
 	switch patternType {
 	case String:
 		pat = NewStringPattern(pattern)
 	case Regex:
-		pat = NewRegexpPattern(pattern)
+		rp, err := CompileRegexpPattern(pattern)
+		if err != nil {
+			panic(err)
+		}
+		pat = rp
 	default:
 		msg := fmt.Sprintf("Not supported ReplacePattern %q", patternType)
 		panic(msg)
@@ -40,6 +54,26 @@ func NewReplace(patternType ReplacePattern, pattern string, content string) *Rep
 	}
 }
 
+// NewReplacePattern creates a Replace from a literal (`*StringPattern`,
+// `*RunePattern`) or a compiled regex (`*RegexpPattern`) pattern.
+func NewReplacePattern(pattern Pattern, content string) (*Replace, error) {
+	var patternType ReplacePattern
+	switch pattern.(type) {
+	case *StringPattern, *RunePattern:
+		patternType = String
+	case *RegexpPattern:
+		patternType = Regex
+	default:
+		return nil, fmt.Errorf("Replace: unsupported pattern of type %T", pattern)
+	}
+
+	return &Replace{
+		PatternType: patternType,
+		Pattern:     pattern,
+		Content:     content,
+	}, nil
+}
+
 // Implement Normalizer for Replace
 func (r *Replace) Normalize(normalized *NormalizedString) (*NormalizedString, error) {
 	return normalized.Replace(r.Pattern, r.Content), nil
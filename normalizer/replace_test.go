@@ -3,6 +3,7 @@ package normalizer
 import (
 	"reflect"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestReplace_Normalize(t *testing.T) {
@@ -57,3 +58,79 @@ func TestReplace_Decode(t *testing.T) {
 		t.Errorf("want %v, got %v\n", want, got)
 	}
 }
+
+func TestReplace_Alignments(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		content   string
+		input     string
+		want      string
+		wantOrigs [][]int // original range of each normalized char
+	}{
+		// longer content
+		{" ", "▁", "a b", "a▁b", [][]int{{0, 1}, {1, 2}, {2, 3}}},
+		// shorter content
+		{"é", "e", "héllo", "hello", [][]int{{0, 1}, {1, 3}, {3, 4}, {4, 5}, {5, 6}}},
+		// empty content
+		{"é", "", "héllo", "hllo", [][]int{{0, 1}, {3, 4}, {4, 5}, {5, 6}}},
+		// several chars: aligned with the last replaced one, as in HuggingFace
+		{"ab", "xyz", "abcab", "xyzcxyz", [][]int{{1, 2}, {1, 2}, {1, 2}, {2, 3}, {4, 5}, {4, 5}, {4, 5}}},
+	}
+
+	for _, tt := range tests {
+		r := NewReplace(String, tt.pattern, tt.content)
+		out, err := r.Normalize(NewNormalizedFrom(tt.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := out.GetNormalized(); got != tt.want {
+			t.Errorf("want %q, got %q\n", tt.want, got)
+		}
+
+		var gotOrigs [][]int
+		for i := range tt.want {
+			if !utf8.RuneStart(tt.want[i]) {
+				continue
+			}
+			_, size := utf8.DecodeRuneInString(tt.want[i:])
+			gotOrigs = append(gotOrigs, out.ConvertOffset(NewRange(i, i+size, NormalizedTarget)).Values())
+		}
+		if !reflect.DeepEqual(tt.wantOrigs, gotOrigs) {
+			t.Errorf("%q: want %v, got %v\n", tt.input, tt.wantOrigs, gotOrigs)
+		}
+	}
+
+	// Original offsets map to the normalized replacement
+	out := NewNormalizedFrom("héllo").Replace(NewStringPattern("é"), "e")
+	if got := out.Range(NewRange(1, 3, OriginalTarget)); got != "e" {
+		t.Errorf("want %q, got %q\n", "e", got)
+	}
+	if got := out.Range(NewRange(3, 6, OriginalTarget)); got != "llo" {
+		t.Errorf("want %q, got %q\n", "llo", got)
+	}
+}
+
+func TestNewReplacePattern(t *testing.T) {
+	rp, err := CompileRegexpPattern(`\d+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReplacePattern(rp, "#")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.PatternType != Regex {
+		t.Errorf("want Regex pattern type, got %v\n", r.PatternType)
+	}
+	out, err := r.Normalize(NewNormalizedFrom("a 123 b 4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.GetNormalized(); got != "a # b #" {
+		t.Errorf("want %q, got %q\n", "a # b #", got)
+	}
+
+	if _, err := NewReplacePattern(NewFnPattern(func(rune) bool { return true }), ""); err == nil {
+		t.Errorf("want error for unsupported pattern, got nil\n")
+	}
+}
@@ -0,0 +1,50 @@
+package normalizer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSequence(t *testing.T) {
+	seq := NewSequence([]Normalizer{
+		NewStrip(true, true),
+		NewNFD(),
+		NewStripAccents(),
+		NewPrepend("▁"),
+	})
+
+	out, err := seq.Normalize(NewNormalizedFrom("  Café "))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantNormalized := "▁Cafe"
+	gotNormalized := out.GetNormalized()
+	if wantNormalized != gotNormalized {
+		t.Errorf("want %v, got %v\n", wantNormalized, gotNormalized)
+	}
+
+	// Every step keeps the alignments with the original string
+	wantAlignments := [][]int{
+		{2, 3}, {2, 3}, {2, 3}, // "▁" is attached to "C"
+		{2, 3},
+		{3, 4},
+		{4, 5},
+		{5, 7}, // "e" comes from "é"
+	}
+	gotAlignments := out.Alignments()
+	if !reflect.DeepEqual(wantAlignments, gotAlignments) {
+		t.Errorf("want %v, got %v\n", wantAlignments, gotAlignments)
+	}
+}
+
+func TestSequence_Empty(t *testing.T) {
+	out, err := NewSequence(nil).Normalize(NewNormalizedFrom("Hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := out.GetNormalized(); got != "Hello" {
+		t.Errorf("want %v, got %v\n", "Hello", got)
+	}
+}
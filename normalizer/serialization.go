@@ -0,0 +1,141 @@
+package normalizer
+
+import (
+	"fmt"
+
+	"github.com/sugarme/tokenizer/util"
+	"golang.org/x/text/unicode/norm"
+)
+
+// This file implements json.Marshaler for normalizers so that they are
+// serialized in HuggingFace `tokenizer.json` format.
+
+// SerializePattern returns the `tokenizer.json` representation of a pattern,
+// ie. `{"String": "..."}` or `{"Regex": "..."}`.
+func SerializePattern(p Pattern) (map[string]string, error) {
+	switch v := p.(type) {
+	case *RunePattern:
+		return map[string]string{"String": string(v.rune)}, nil
+	case *StringPattern:
+		return map[string]string{"String": v.string}, nil
+	case *RegexpPattern:
+		return map[string]string{"Regex": v.source}, nil
+	default:
+		return nil, fmt.Errorf("Cannot serialize pattern of type %T", p)
+	}
+}
+
+func (bn *BertNormalizer) MarshalJSON() ([]byte, error) {
+	type alias BertNormalizer
+	return util.MarshalTyped("BertNormalizer", (*alias)(bn))
+}
+
+func (s *Strip) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("Strip", map[string]interface{}{
+		"strip_left":  s.stripLeft,
+		"strip_right": s.stripRight,
+	})
+}
+
+func (sa *StripAccents) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("StripAccents", nil)
+}
+
+// MarshalJSON serializes a UnicodeNormalizer as the normalizer of its form.
+func (un *UnicodeNormalizer) MarshalJSON() ([]byte, error) {
+	switch un.Form {
+	case norm.NFC:
+		return util.MarshalTyped("NFC", nil)
+	case norm.NFD:
+		return util.MarshalTyped("NFD", nil)
+	case norm.NFKC:
+		return util.MarshalTyped("NFKC", nil)
+	case norm.NFKD:
+		return util.MarshalTyped("NFKD", nil)
+	default:
+		return nil, fmt.Errorf("Cannot serialize unicode normal form %v", un.Form)
+	}
+}
+
+func (nfc *NFC) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("NFC", nil)
+}
+
+func (nfd *NFD) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("NFD", nil)
+}
+
+func (nfkc *NFKC) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("NFKC", nil)
+}
+
+func (nfkd *NFKD) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("NFKD", nil)
+}
+
+func (nmt *Nmt) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("Nmt", nil)
+}
+
+func (s *Sequence) MarshalJSON() ([]byte, error) {
+	type alias Sequence
+	return util.MarshalTyped("Sequence", (*alias)(s))
+}
+
+func (p *Prepend) MarshalJSON() ([]byte, error) {
+	type alias Prepend
+	return util.MarshalTyped("Prepend", (*alias)(p))
+}
+
+func (r *Replace) MarshalJSON() ([]byte, error) {
+	pattern, err := SerializePattern(r.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	return util.MarshalTyped("Replace", map[string]interface{}{
+		"pattern": pattern,
+		"content": r.Content,
+	})
+}
+
+func (m *Precompiled) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("Precompiled", map[string]interface{}{
+		"precompiled_charsmap": m.PrecompiledCharsmap, // []byte is base64 encoded
+	})
+}
+
+// MarshalJSON serializes the DefaultNormalizer configurations that have a
+// `tokenizer.json` equivalent (ie. lowercase and/or strip).
+func (dn *DefaultNormalizer) MarshalJSON() ([]byte, error) {
+	norms := []map[string]interface{}{}
+	if dn.lower {
+		norms = append(norms, map[string]interface{}{"type": "Lowercase"})
+	}
+	if dn.strip {
+		norms = append(norms, map[string]interface{}{"type": "Strip", "strip_left": true, "strip_right": true})
+	}
+
+	if len(norms) == 1 {
+		return util.MarshalTyped(norms[0]["type"].(string), norms[0])
+	}
+
+	return util.MarshalTyped("Sequence", map[string]interface{}{"normalizers": norms})
+}
+
+// String returns the `tokenizer.json` name of the behavior.
+func (b SplitDelimiterBehavior) String() string {
+	switch b {
+	case RemovedBehavior:
+		return "Removed"
+	case IsolatedBehavior:
+		return "Isolated"
+	case MergedWithPreviousBehavior:
+		return "MergedWithPrevious"
+	case MergedWithNextBehavior:
+		return "MergedWithNext"
+	case ContiguousBehavior:
+		return "Contiguous"
+	default:
+		return fmt.Sprintf("SplitDelimiterBehavior(%d)", int(b))
+	}
+}
@@ -1,10 +1,13 @@
 package normalizer
 
+// Strip removes the whitespaces on the left and/or right of the string.
 type Strip struct {
 	stripLeft  bool
 	stripRight bool
 }
 
+// NewStrip creates a Strip normalizer, stripping the whitespaces on the
+// given sides.
 func NewStrip(stripLeft, stripRight bool) *Strip {
 	return &Strip{
 		stripLeft:  stripLeft,
@@ -32,6 +35,11 @@ func (s *Strip) Normalize(normalized *NormalizedString) (*NormalizedString, erro
 	return normalized, nil
 }
 
+// StripAccents removes the accents of the string: it decomposes it (NFD) then
+// removes the Unicode Mn group (M non-spacing) chars.
+//
+// NOTE. Unlike HuggingFace `StripAccents`, it does not need a `NFD` normalizer
+// beforehand. The output is the same when there is one.
 type StripAccents struct{}
 
 func NewStripAccents() *StripAccents {
@@ -39,5 +47,17 @@ func NewStripAccents() *StripAccents {
 }
 
 func (sa *StripAccents) Normalize(normalized *NormalizedString) (*NormalizedString, error) {
-	return normalized.RemoveAccents(), nil
+	return normalized.NFD().RemoveAccents(), nil
+}
+
+// StripAccentsLatinOnly removes accents attached to Latin characters only.
+// See `NormalizedString.RemoveAccentsLatinOnly`.
+type StripAccentsLatinOnly struct{}
+
+func NewStripAccentsLatinOnly() *StripAccentsLatinOnly {
+	return new(StripAccentsLatinOnly)
+}
+
+func (sa *StripAccentsLatinOnly) Normalize(normalized *NormalizedString) (*NormalizedString, error) {
+	return normalized.NFD().RemoveAccentsLatinOnly(), nil
 }
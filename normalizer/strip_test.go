@@ -0,0 +1,67 @@
+package normalizer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStrip_Normalize(t *testing.T) {
+	tests := []struct {
+		left, right bool
+		want        string
+	}{
+		{true, true, "Hello there"},
+		{true, false, "Hello there \t"},
+		{false, true, "  Hello there"},
+		{false, false, "  Hello there \t"},
+	}
+
+	for _, tt := range tests {
+		n := NewNormalizedFrom("  Hello there \t")
+		got, err := NewStrip(tt.left, tt.right).Normalize(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.GetNormalized() != tt.want {
+			t.Errorf("want %q, got %q\n", tt.want, got.GetNormalized())
+		}
+	}
+
+	// Offsets still refer to the original string
+	n, _ := NewStrip(true, true).Normalize(NewNormalizedFrom("  Hello  "))
+	want := []int{2, 7}
+	got := n.ConvertOffset(NewRange(0, 5, NormalizedTarget)).Values()
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v\n", want, got)
+	}
+}
+
+func TestStripAccents_Normalize(t *testing.T) {
+	// precomposed and decomposed accents
+	n, err := NewStripAccents().Normalize(NewNormalizedFrom("élégant café"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "elegant cafe"
+	if got := n.GetNormalized(); got != want {
+		t.Errorf("want %q, got %q\n", want, got)
+	}
+
+	// "é" (2 bytes) is aligned with "e"
+	wantRange := []int{0, 2}
+	gotRange := n.ConvertOffset(NewRange(0, 1, NormalizedTarget)).Values()
+	if !reflect.DeepEqual(wantRange, gotRange) {
+		t.Errorf("want %v, got %v\n", wantRange, gotRange)
+	}
+
+	// Same output after a NFD normalizer
+	seq := NewSequence([]Normalizer{NewNFD(), NewStripAccents()})
+	n, err = seq.Normalize(NewNormalizedFrom("élégant café"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := n.GetNormalized(); got != want {
+		t.Errorf("want %q, got %q\n", want, got)
+	}
+}
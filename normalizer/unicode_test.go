@@ -0,0 +1,63 @@
+package normalizer
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestUnicodeNormalizer_Normalize(t *testing.T) {
+	tests := []struct {
+		form  norm.Form
+		input string
+		want  string
+		// original range of each normalized char
+		wantOrigs [][]int
+	}{
+		// composition: aligned with the first original rune
+		{norm.NFC, "e\u0301t", "\u00e9t", [][]int{{0, 1}, {3, 4}}},
+		// decomposition: all aligned with the original rune
+		{norm.NFD, "\u00e9t", "e\u0301t", [][]int{{0, 2}, {0, 2}, {2, 3}}},
+		{norm.NFKD, "\ufb01\u00e9", "fie\u0301", [][]int{{0, 3}, {0, 3}, {3, 5}, {3, 5}}},
+		{norm.NFKC, "\ufb01e\u0301", "fi\u00e9", [][]int{{0, 3}, {0, 3}, {3, 4}}},
+		{norm.NFKC, "\uff28\uff49", "Hi", [][]int{{0, 3}, {3, 6}}},
+		// already normalized
+		{norm.NFC, "\u00e9t\u00e9", "\u00e9t\u00e9", [][]int{{0, 2}, {2, 3}, {3, 5}}},
+		{norm.NFKD, "hello", "hello", [][]int{{0, 1}, {1, 2}, {2, 3}, {3, 4}, {4, 5}}},
+	}
+
+	for _, tt := range tests {
+		n, err := NewUnicodeNormalizer(tt.form).Normalize(NewNormalizedFrom(tt.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == nil || n.GetNormalized() != tt.want {
+			t.Fatalf("%q: want %q, got %+v\n", tt.input, tt.want, n)
+		}
+
+		var gotOrigs [][]int
+		for i, r := range tt.want {
+			size := len(string(r))
+			gotOrigs = append(gotOrigs, n.ConvertOffset(NewRange(i, i+size, NormalizedTarget)).Values())
+		}
+		if !reflect.DeepEqual(tt.wantOrigs, gotOrigs) {
+			t.Errorf("%q: want %v, got %v\n", tt.input, tt.wantOrigs, gotOrigs)
+		}
+	}
+}
+
+func TestUnicodeNormalizer_MarshalJSON(t *testing.T) {
+	got, err := json.Marshal(NewUnicodeNormalizer(norm.NFKC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := json.Marshal(NewNFKC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(want) != string(got) {
+		t.Errorf("want %s, got %s\n", want, got)
+	}
+}
@@ -4,7 +4,6 @@ package tokenizer
 
 import (
 	"fmt"
-	"log"
 	// "reflect"
 
 	"github.com/sugarme/tokenizer/normalizer"
@@ -95,17 +94,18 @@ func (pt *PreTokenizedString) Split(splitFn SplitFn) *PreTokenizedString {
 }
 
 // Normalize normalizes all the splits that do not have attached `Tokens`,
-// using the provided `normalize` function.
+// using the provided `normalize` function. Splits with `Tokens` (e.g. added
+// tokens) are kept as they are.
 func (pt *PreTokenizedString) Normalize(nFn func(*normalizer.NormalizedString) *normalizer.NormalizedString) *PreTokenizedString {
 
 	var nSplits []Split
 
 	for _, split := range pt.splits {
+		newSplit := split
 		if split.tokens == nil {
-			newSplit := split
 			newSplit.normalized = nFn(split.normalized)
-			nSplits = append(nSplits, newSplit)
 		}
+		nSplits = append(nSplits, newSplit)
 	}
 
 	pt.splits = nSplits
@@ -140,88 +140,77 @@ func (pt *PreTokenizedString) Tokenize(tokFn func(*normalizer.NormalizedString)
 // input, that do not need the `PreTokenizedString` to generate word ids.
 //
 // This method will fail if some splits do not have associated `Token`.
+//
+// Char offsets out of the input (e.g. from buggy models) are clamped to it
+// (see `BytesToCharOffsetConverter.ConvertClamp`).
 func (pt *PreTokenizedString) IntoEncoding(typeId int, wordIdx int, offsetType OffsetType) (*Encoding, error) {
+	en, _, err := pt.intoEncoding(nil, typeId, wordIdx, offsetType)
+	return en, err
+}
+
+// intoEncoding is `IntoEncoding`, also returning the number of tokens whose
+// offsets were clamped. If en is not nil, the encoding is written into it,
+// reusing its slices.
+func (pt *PreTokenizedString) intoEncoding(en *Encoding, typeId int, wordIdx int, offsetType OffsetType) (*Encoding, int, error) {
 
 	if len(pt.splits) == 0 {
-		return DefaultEncoding(), nil
+		if en == nil {
+			return DefaultEncoding(), 0, nil
+		}
+		en.reuse(0)
+		en.Words = nil // as `DefaultEncoding`
+		return en, 0, nil
 	}
 
 	for _, s := range pt.splits {
 		if len(s.tokens) == 0 {
 			err := fmt.Errorf("Split has not been tokenized. Call 'PreTokenizeString.Tokenize()' method first.\n")
-			return nil, err
+			return nil, 0, err
 		}
 	}
 
-	charMap := make(map[int]int, 0) // map[byteIdx]runeIdx
-
+	var offsetConverter *BytesToCharOffsetConverter
 	switch {
 	case offsetType == Char:
-		currRuneIdx := 0
-		for byteIdx, r := range pt.original {
-			n := 0
-			for i := 0; i < len([]byte(string(r))); i++ {
-				charMap[byteIdx+n] = currRuneIdx
-				n += 1
-			}
-			currRuneIdx += 1
-		}
+		offsetConverter = NewBytesToCharOffsetConverter(pt.original)
 	case offsetType == Byte:
-		charMap = make(map[int]int, 0)
+		offsetConverter = nil
 
 	default:
 		err := fmt.Errorf("Invalid offsetType (%v).\n", offsetType)
-		return nil, err
+		return nil, 0, err
 	}
 
-	var (
-		enIds               []int
-		enTokens            []string
-		enWords             []int
-		enTypeIds           []int
-		enOffsets           [][]int
-		enSpecialTokensMask []int
-		enAttentionMask     []int
-	)
+	var n int
+	for _, split := range pt.splits {
+		n += len(split.tokens)
+	}
+	if en == nil {
+		en = allocEncoding(n)
+	} else {
+		en.reuse(n)
+	}
 
+	i, nClamped := 0, 0
 	for idx, split := range pt.splits {
 		normalized := split.normalized
 		offsets := normalized.OffsetsOriginal()
-		charMapSplit := charMap
-		var convertedOffsets []int
 		for _, tok := range split.tokens {
+			offset := en.Offsets[i]
 			o := normalized.ConvertOffset(normalizer.NewRange(tok.Offsets[0], tok.Offsets[1], normalizer.NormalizedTarget))
 			if o == nil {
-				convertedOffsets = []int{offsets[0] + tok.Offsets[0], offsets[0] + tok.Offsets[1]}
+				offset[0], offset[1] = offsets[0]+tok.Offsets[0], offsets[0]+tok.Offsets[1]
 			} else {
-				convertedOffsets = []int{offsets[0] + o.Start(), offsets[0] + o.End()}
+				offset[0], offset[1] = offsets[0]+o.Start(), offsets[0]+o.End()
 			}
 
 			// Convert to char offset if relevant
-			start, ok := charMapSplit[convertedOffsets[0]]
-			if !ok {
-				start = -1
-			}
-			end, ok := charMapSplit[convertedOffsets[1]]
-			if !ok {
-				end = -1
-			}
-
-			var newConvertedOffsets []int
-			switch {
-			case start != -1 && end != -1:
-				newConvertedOffsets = []int{start, end}
-			case start != -1 && end == -1: // If we reached the end, `end` is not in the map
-				// But the one just before should be
-				last, ok := charMapSplit[convertedOffsets[1]-1]
-				if !ok {
-					log.Printf("Something wrong here. Should find from map.\n")
-					last = start + 1
+			if offsetConverter != nil {
+				converted, clamped := offsetConverter.ConvertClamp(offset)
+				if clamped {
+					nClamped++
 				}
-				newConvertedOffsets = []int{start, last}
-
-			default:
-				newConvertedOffsets = convertedOffsets
+				copy(offset, converted)
 			}
 
 			var wordIndex int = wordIdx
@@ -232,26 +221,16 @@ func (pt *PreTokenizedString) IntoEncoding(typeId int, wordIdx int, offsetType O
 			// fmt.Printf("tok: ....%v - value: '%v'\n", tok, tok.Value)
 			// NOTE: we get token value from offsets on normalized.
 
-			enIds = append(enIds, tok.Id)
-			enTokens = append(enTokens, tok.Value)
-			enOffsets = append(enOffsets, newConvertedOffsets)
-			enWords = append(enWords, wordIndex)
-			enTypeIds = append(enTypeIds, typeId)
-			enSpecialTokensMask = append(enSpecialTokensMask, 0)
-			enAttentionMask = append(enAttentionMask, 1)
+			en.Ids[i] = tok.Id
+			en.Tokens[i] = tok.Value
+			en.Words[i] = wordIndex
+			en.TypeIds[i] = typeId
+			en.AttentionMask[i] = 1
+			i++
 		}
 	}
 
-	en := DefaultEncoding()
-	en.Ids = enIds
-	en.Tokens = enTokens
-	en.Offsets = enOffsets
-	en.Words = enWords
-	en.TypeIds = enTypeIds
-	en.SpecialTokenMask = enSpecialTokensMask
-	en.AttentionMask = enAttentionMask
-
-	return en, nil
+	return en, nClamped, nil
 }
 
 // GetSplits returns a list of splits, each of them being a slice of the normalized
@@ -313,7 +292,9 @@ type OffsetConverter interface {
 }
 
 type BytesToCharOffsetConverter struct {
-	b2c map[int]int // map of byteIndex to character(rune) index
+	b2c    map[int]int // map of byteIndex to character(rune) index
+	nBytes int         // length of sequence in bytes
+	nChars int         // length of sequence in characters
 }
 
 func NewBytesToCharOffsetConverter(sequence string) *BytesToCharOffsetConverter {
@@ -331,22 +312,45 @@ func NewBytesToCharOffsetConverter(sequence string) *BytesToCharOffsetConverter
 		n += nbytes
 	}
 
-	return &BytesToCharOffsetConverter{b2c}
+	return &BytesToCharOffsetConverter{b2c, n, len(chars)}
 }
 
-// Convert converts byte-indexed offsets to character-index offsets.
+// Convert converts byte-indexed offsets to character-index offsets. It
+// returns an error if an offset is out of the sequence (see `ConvertClamp`).
 func (c *BytesToCharOffsetConverter) Convert(offsets []int) ([]int, error) {
-	start, ok := c.b2c[offsets[0]]
-	if !ok {
-		err := fmt.Errorf("Invalid offsets start %v\n", offsets[0])
-		return nil, err
+	for _, o := range offsets {
+		if o < 0 || o > c.nBytes {
+			err := fmt.Errorf("Invalid offset %v: out of sequence of %v bytes\n", o, c.nBytes)
+			return nil, err
+		}
 	}
 
-	end, ok := c.b2c[offsets[1]]
-	if !ok {
-		err := fmt.Errorf("Invalid offsets end %v\n", offsets[1])
-		return nil, err
+	converted, _ := c.ConvertClamp(offsets)
+	return converted, nil
+}
+
+// ConvertClamp converts byte-indexed offsets to character-index offsets.
+// Offsets outside the sequence (e.g. from post-processor inserted tokens or
+// buggy models) are clamped to `[0, len(sequence)]`, in which case `clamped`
+// is true.
+func (c *BytesToCharOffsetConverter) ConvertClamp(offsets []int) (retVal []int, clamped bool) {
+	retVal = make([]int, len(offsets))
+	for i, o := range offsets {
+		switch {
+		case o < 0:
+			o = 0
+			clamped = true
+		case o > c.nBytes:
+			o = c.nBytes
+			clamped = true
+		}
+
+		if o == c.nBytes {
+			retVal[i] = c.nChars
+		} else {
+			retVal[i] = c.b2c[o]
+		}
 	}
 
-	return []int{start, end}, nil
+	return retVal, clamped
 }
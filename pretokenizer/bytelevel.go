@@ -1,8 +1,11 @@
 package pretokenizer
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/sugarme/tokenizer"
 	"github.com/sugarme/tokenizer/normalizer"
@@ -143,8 +146,18 @@ type ByteLevel struct {
 	// Whether the post processing step should trim offsets
 	// to avoid including whitespaces.
 	TrimOffsets bool
+
+	// SpaceMarker is the character used to display the space byte (32).
+	// Default to "Ġ" if empty. See `SetSpaceMarker`.
+	SpaceMarker string
+
+	// Whether the input is not split with the GPT-2 regex (see `SetUseRegex`)
+	noRegex bool
 }
 
+// DefaultSpaceMarker is the character mapped to the space byte (32).
+var DefaultSpaceMarker string = BytesChar[32]
+
 // NewByteLevel returns a default ByteLevel with both
 // AddPrefixSpace and TrimOffsets set true
 func NewByteLevel() *ByteLevel {
@@ -157,8 +170,8 @@ func NewByteLevel() *ByteLevel {
 // Alphabet returns set of first 256 unicode `char`
 func (bl *ByteLevel) Alphabet() map[string]struct{} {
 	var ab = make(map[string]struct{})
-	for _, c := range BytesChar {
-		ab[c] = struct{}{}
+	for b := range BytesChar {
+		ab[bl.byteChar(b)] = struct{}{}
 	}
 
 	return ab
@@ -174,6 +187,57 @@ func (bl *ByteLevel) SetTrimOffsets(v bool) {
 	bl.TrimOffsets = v
 }
 
+// SetUseRegex sets whether to split the input with the GPT-2 regex (default
+// true). Tokenizers splitting the input beforehand with another regex, e.g.
+// with a `Split` pre-tokenizer as GPT-4 ones, disable it.
+func (bl *ByteLevel) SetUseRegex(v bool) {
+	bl.noRegex = !v
+}
+
+// UseRegex returns whether the input is split with the GPT-2 regex.
+func (bl *ByteLevel) UseRegex() bool {
+	return !bl.noRegex
+}
+
+// SetSpaceMarker sets the character used to display the space byte instead
+// of "Ġ". To keep the byte-char mapping bijective, the marker must be a single
+// character not already used for another byte.
+func (bl *ByteLevel) SetSpaceMarker(marker string) error {
+	if utf8.RuneCountInString(marker) != 1 {
+		return fmt.Errorf("Invalid space marker %q: must be a single character.\n", marker)
+	}
+	if b, ok := CharBytes[marker]; ok && b != 32 {
+		return fmt.Errorf("Invalid space marker %q: already mapped to byte %v.\n", marker, b)
+	}
+
+	bl.SpaceMarker = marker
+	return nil
+}
+
+// spaceMarker returns the current space marker.
+func (bl *ByteLevel) spaceMarker() string {
+	if bl.SpaceMarker == "" {
+		return DefaultSpaceMarker
+	}
+	return bl.SpaceMarker
+}
+
+// byteChar maps a byte to its byte-level char taking the space marker into account.
+func (bl *ByteLevel) byteChar(b uint8) string {
+	if b == 32 {
+		return bl.spaceMarker()
+	}
+	return BytesChar[b]
+}
+
+// charByte maps a byte-level char back to its byte taking the space marker into account.
+func (bl *ByteLevel) charByte(c string) uint8 {
+	if c == bl.spaceMarker() {
+		return 32
+	}
+	return CharBytes[c]
+}
+
 // Implement `PreTokenizer` methods for `ByteLevel`:
 // =================================================
 
@@ -187,6 +251,10 @@ func (bl *ByteLevel) PreTokenize(pretokenized *tokenizer.PreTokenizedString) (*t
 			newNormalized = normalized.Prepend(" ")
 		}
 
+		if bl.noRegex {
+			return []tokenizer.SplitIdx{{Normalized: newNormalized, Tokens: nil}}
+		}
+
 		splitPattern := normalizer.NewRegexpPattern(splitRegStr)
 		splits := newNormalized.Split(splitPattern, normalizer.IsolatedBehavior)
 
@@ -210,7 +278,7 @@ func (bl *ByteLevel) PreTokenize(pretokenized *tokenizer.PreTokenizedString) (*t
 					change = 1
 				}
 
-				char := BytesChar[b]
+				char := bl.byteChar(b)
 				changeMap = append(changeMap, normalizer.ChangeMap{RuneVal: char, Changes: change})
 			}
 		}
@@ -235,7 +303,7 @@ func (bl *ByteLevel) Decode(tokens []string) string {
 	var bytes []byte
 
 	for _, c := range chars {
-		b := CharBytes[c]
+		b := bl.charByte(c)
 
 		bytes = append(bytes, b)
 	}
@@ -249,7 +317,7 @@ func (bl *ByteLevel) DecodeChain(tokens []string) []string {
 		chars := strings.Split(s, "")
 		var bytes []byte
 		for _, c := range chars {
-			b := CharBytes[c]
+			b := bl.charByte(c)
 
 			bytes = append(bytes, b)
 		}
@@ -263,19 +331,30 @@ func (bl *ByteLevel) DecodeChain(tokens []string) []string {
 // Implement PostProcessor for ByteLevel
 // =====================================
 
-func (bl *ByteLevel) AddedToken(isPair bool) int {
+var _ tokenizer.PostProcessor = new(ByteLevel)
+
+// AddedTokens returns the number of tokens added by `Process`. `ByteLevel`
+// only adjusts offsets, so this is always zero.
+func (bl *ByteLevel) AddedTokens(isPair bool) int {
 	return 0
 }
 
+// AddedToken is kept for backward compatibility.
+//
+// Deprecated: use AddedTokens.
+func (bl *ByteLevel) AddedToken(isPair bool) int {
+	return bl.AddedTokens(isPair)
+}
+
 func (bl *ByteLevel) Process(encoding, pairEncoding *tokenizer.Encoding, addSpecialTokens bool) *tokenizer.Encoding {
 	encodings := tokenizer.PrepareEncodings(encoding, pairEncoding)
 	var newEncodings []tokenizer.Encoding
 	if bl.TrimOffsets {
 		for _, enc := range encodings {
-			processedEnc := processOffsets(&enc, bl.AddPrefixSpace)
+			processedEnc := processOffsets(&enc, bl.AddPrefixSpace, bl.spaceMarker())
 			var overflowing []tokenizer.Encoding
 			for _, of := range processedEnc.GetOverflowing() {
-				processedOF := processOffsets(&of, bl.AddPrefixSpace)
+				processedOF := processOffsets(&of, bl.AddPrefixSpace, bl.spaceMarker())
 				overflowing = append(overflowing, *processedOF)
 			}
 			processedEnc.Overflowing = overflowing
@@ -297,7 +376,7 @@ func (bl *ByteLevel) Process(encoding, pairEncoding *tokenizer.Encoding, addSpec
 	}
 }
 
-func processOffsets(encoding *tokenizer.Encoding, addPrefixSpace bool) *tokenizer.Encoding {
+func processOffsets(encoding *tokenizer.Encoding, addPrefixSpace bool, spaceMarker string) *tokenizer.Encoding {
 	type Modif struct {
 		LeadingSpaces int
 		TrailingSpace int
@@ -306,12 +385,18 @@ func processOffsets(encoding *tokenizer.Encoding, addPrefixSpace bool) *tokenize
 	var modifs []Modif
 	var newOffsets [][]int
 
+	// Added tokens (e.g. a left stripped `<mask>`) can hold actual
+	// whitespaces rather than space markers.
+	isSpace := func(c rune) bool {
+		return string(c) == spaceMarker || unicode.IsSpace(c)
+	}
+
 	toks := encoding.GetTokens()
 	for _, tok := range toks {
 		var leadingSpaces int = 0
 		chars := []rune(tok)
 		for _, c := range chars {
-			if string(c) != "Ġ" {
+			if !isSpace(c) {
 				break
 			}
 			leadingSpaces += 1
@@ -319,7 +404,7 @@ func processOffsets(encoding *tokenizer.Encoding, addPrefixSpace bool) *tokenize
 
 		var trailingSpaces int = 0
 		for i := len(chars) - 1; i >= 0; i-- {
-			if string(chars[i]) != "Ġ" {
+			if !isSpace(chars[i]) {
 				break
 			}
 			trailingSpaces += 1
@@ -337,7 +422,9 @@ func processOffsets(encoding *tokenizer.Encoding, addPrefixSpace bool) *tokenize
 		ld := m.LeadingSpaces
 		offset0 = offsets[0]
 		if m.LeadingSpaces > 0 {
-			if i == 0 && addPrefixSpace && m.LeadingSpaces == 1 {
+			// Pre-tokenized inputs have several words starting at offset 0.
+			isFirst := i == 0 || offsets[0] == 0
+			if isFirst && addPrefixSpace && m.LeadingSpaces == 1 {
 				// If we are processing the first pair of offsets, with `addPrefixSpace`,
 				// then we shouldn't remove anything we added. If there are more than one
 				// leading spaces though, it means we didn't add them, and they should be
@@ -370,5 +457,5 @@ func processOffsets(encoding *tokenizer.Encoding, addPrefixSpace bool) *tokenize
 }
 
 func ProcessOffsets(encoding *tokenizer.Encoding, addPrefixSpace bool) *tokenizer.Encoding {
-	return processOffsets(encoding, addPrefixSpace)
+	return processOffsets(encoding, addPrefixSpace, DefaultSpaceMarker)
 }
@@ -328,3 +328,65 @@ func TestProcessorTrimsOffsets(t *testing.T) {
 		t.Errorf("Got: %#v\n", pairGot)
 	}
 }
+
+func TestByteLevel_SpaceMarker(t *testing.T) {
+	bytelevel := pretokenizer.NewByteLevel()
+	bytelevel.SetAddPrefixSpace(false)
+
+	// Already used for another byte
+	if err := bytelevel.SetSpaceMarker("!"); err == nil {
+		t.Errorf("want error for marker mapped to another byte\n")
+	}
+	// Not a single character
+	if err := bytelevel.SetSpaceMarker("__"); err == nil {
+		t.Errorf("want error for multi-char marker\n")
+	}
+
+	if err := bytelevel.SetSpaceMarker("▁"); err != nil {
+		t.Fatal(err)
+	}
+
+	input := "Hello my friend"
+	pretokenized := tokenizer.NewPreTokenizedString(input)
+	out, err := bytelevel.PreTokenize(pretokenized)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var toks []string
+	for _, pretok := range out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte) {
+		toks = append(toks, pretok.Value)
+	}
+
+	want := []string{"Hello", "▁my", "▁friend"}
+	if !reflect.DeepEqual(want, toks) {
+		t.Errorf("want %#v\ngot %#v\n", want, toks)
+	}
+
+	got := bytelevel.Decode(toks)
+	if !reflect.DeepEqual(input, got) {
+		t.Errorf("want %q\ngot %q\n", input, got)
+	}
+}
+
+func TestByteLevel_UseRegex(t *testing.T) {
+	bytelevel := pretokenizer.NewByteLevel()
+	bytelevel.SetAddPrefixSpace(false)
+	bytelevel.SetUseRegex(false)
+
+	pretokenized := tokenizer.NewPreTokenizedString("Hello my friend")
+	out, err := bytelevel.PreTokenize(pretokenized)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var toks []string
+	for _, pretok := range out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte) {
+		toks = append(toks, pretok.Value)
+	}
+
+	want := []string{"HelloĠmyĠfriend"}
+	if !reflect.DeepEqual(want, toks) {
+		t.Errorf("want %#v\ngot %#v\n", want, toks)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/sugarme/tokenizer/normalizer"
 )
 
+// CharDelimiterSplit splits on the given delimiter rune, removing it.
 type CharDelimiterSplit struct {
 	Delimiter rune
 }
@@ -0,0 +1,45 @@
+package pretokenizer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/normalizer"
+)
+
+func TestCharDelimiterSplit(t *testing.T) {
+	pretok := NewCharDelimiterSplit('-')
+
+	tests := []struct {
+		s   string
+		res []tokenizer.PreToken
+	}{
+		{
+			s: "the-final--countdown",
+			res: []tokenizer.PreToken{
+				{Value: "the", Offsets: []int{0, 3}, Tokens: nil},
+				{Value: "final", Offsets: []int{4, 9}, Tokens: nil},
+				{Value: "countdown", Offsets: []int{11, 20}, Tokens: nil},
+			},
+		},
+		{
+			s: "-héllo wörld-",
+			res: []tokenizer.PreToken{
+				{Value: "héllo wörld", Offsets: []int{1, 14}, Tokens: nil},
+			},
+		},
+	}
+
+	for _, data := range tests {
+		out, err := pretok.PreTokenize(tokenizer.NewPreTokenizedString(data.s))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte)
+		if !reflect.DeepEqual(data.res, got) {
+			t.Errorf("want %#v\ngot %#v\n", data.res, got)
+		}
+	}
+}
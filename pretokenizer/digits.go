@@ -7,6 +7,8 @@ import (
 	"github.com/sugarme/tokenizer/normalizer"
 )
 
+// Digits splits numbers from the rest of the input. With `IndividualDigits`
+// every digit is isolated, otherwise contiguous digits are kept together.
 type Digits struct {
 	IndividualDigits bool
 }
@@ -19,6 +21,10 @@ func DefaultDigits() *Digits {
 	return NewDigits(false)
 }
 
+// Implement tokenizer.PreTokenizer for Digits
+
+var _ tokenizer.PreTokenizer = new(Digits)
+
 // PreTokenize implements tokenizer.PreTokenizer.
 func (p *Digits) PreTokenize(pretokenized *tokenizer.PreTokenizedString) (*tokenizer.PreTokenizedString, error) {
 	isNumeric := normalizer.NewFnPattern(unicode.IsNumber)
@@ -0,0 +1,66 @@
+package pretokenizer
+
+import (
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/normalizer"
+)
+
+// FixedChunk splits the normalized string into chunks of `Size` runes. The
+// last chunk can be shorter if the string length is not a multiple of `Size`.
+//
+// E.g. with size 3: "abcdefg" => ["abc", "def", "g"]
+type FixedChunk struct {
+	Size int
+}
+
+// NewFixedChunk creates a FixedChunk pre-tokenizer. It panics if size is not
+// positive.
+func NewFixedChunk(size int) *FixedChunk {
+	if size <= 0 {
+		panic("FixedChunk size must be positive")
+	}
+
+	return &FixedChunk{size}
+}
+
+// Implement tokenizer.PreTokenizer for FixedChunk
+
+var _ tokenizer.PreTokenizer = new(FixedChunk)
+
+func (p *FixedChunk) PreTokenize(pretokenized *tokenizer.PreTokenizedString) (*tokenizer.PreTokenizedString, error) {
+	pretok := pretokenized.Split(func(noop int, normalized *normalizer.NormalizedString) []tokenizer.SplitIdx {
+		var (
+			splitIdxs []tokenizer.SplitIdx
+			start     int
+			count     int
+		)
+
+		s := normalized.GetNormalized()
+		for i := range s {
+			if count == p.Size {
+				splitIdxs = append(splitIdxs, p.chunk(normalized, start, i)...)
+				start = i
+				count = 0
+			}
+			count++
+		}
+		splitIdxs = append(splitIdxs, p.chunk(normalized, start, len(s))...)
+
+		return splitIdxs
+	})
+
+	return pretok, nil
+}
+
+func (p *FixedChunk) chunk(normalized *normalizer.NormalizedString, start, end int) []tokenizer.SplitIdx {
+	if start == end {
+		return nil
+	}
+
+	slice := normalized.Slice(normalizer.NewRange(start, end, normalizer.NormalizedTarget))
+	if slice == nil {
+		return nil
+	}
+
+	return []tokenizer.SplitIdx{{Normalized: slice, Tokens: nil}}
+}
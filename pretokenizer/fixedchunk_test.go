@@ -0,0 +1,57 @@
+package pretokenizer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/normalizer"
+)
+
+func TestFixedChunk(t *testing.T) {
+	pretok := NewFixedChunk(3)
+
+	tests := []struct {
+		s   string
+		res []tokenizer.PreToken
+	}{
+		{
+			s: "abcdefg",
+			res: []tokenizer.PreToken{
+				{Value: "abc", Offsets: []int{0, 3}, Tokens: nil},
+				{Value: "def", Offsets: []int{3, 6}, Tokens: nil},
+				{Value: "g", Offsets: []int{6, 7}, Tokens: nil},
+			},
+		},
+		{
+			s: "abcdef",
+			res: []tokenizer.PreToken{
+				{Value: "abc", Offsets: []int{0, 3}, Tokens: nil},
+				{Value: "def", Offsets: []int{3, 6}, Tokens: nil},
+			},
+		},
+		{
+			// multi-byte runes
+			s: "éàüö",
+			res: []tokenizer.PreToken{
+				{Value: "éàü", Offsets: []int{0, 6}, Tokens: nil},
+				{Value: "ö", Offsets: []int{6, 8}, Tokens: nil},
+			},
+		},
+	}
+
+	for _, data := range tests {
+		pretokenized := tokenizer.NewPreTokenizedString(data.s)
+		out, err := pretok.PreTokenize(pretokenized)
+		if err != nil {
+			t.Fail()
+		}
+
+		got := out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte)
+		want := data.res
+
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("want %#v\ngot %#v\n", want, got)
+		}
+	}
+}
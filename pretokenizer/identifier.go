@@ -0,0 +1,100 @@
+package pretokenizer
+
+import (
+	"unicode"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/normalizer"
+)
+
+// IdentifierSplit splits code identifiers into their sub-words.
+//
+// E.g. "getUserName" => ["get", "User", "Name"]; "HTTPServer" => ["HTTP", "Server"];
+// "get_user_name" => ["get", "user", "name"] (with `RemovedBehavior` on "_").
+type IdentifierSplit struct {
+	// Behavior is applied to the "_" delimiter of snake_case identifiers.
+	Behavior normalizer.SplitDelimiterBehavior
+}
+
+func NewIdentifierSplit(behavior normalizer.SplitDelimiterBehavior) *IdentifierSplit {
+	return &IdentifierSplit{behavior}
+}
+
+func DefaultIdentifierSplit() *IdentifierSplit {
+	return NewIdentifierSplit(normalizer.RemovedBehavior)
+}
+
+// Implement tokenizer.PreTokenizer for IdentifierSplit
+
+var _ tokenizer.PreTokenizer = new(IdentifierSplit)
+
+func (p *IdentifierSplit) PreTokenize(pretokenized *tokenizer.PreTokenizedString) (*tokenizer.PreTokenizedString, error) {
+	pretok := pretokenized.Split(func(noop int, normalized *normalizer.NormalizedString) []tokenizer.SplitIdx {
+		underscore := normalizer.NewRunePattern('_')
+		splits := normalized.Split(underscore, p.Behavior)
+
+		var splitIdxs []tokenizer.SplitIdx
+		for _, s := range splits {
+			for _, sub := range splitCase(&s) {
+				normalized := sub
+				splitIdx := tokenizer.SplitIdx{Normalized: &normalized, Tokens: nil}
+				splitIdxs = append(splitIdxs, splitIdx)
+			}
+		}
+
+		return splitIdxs
+	})
+
+	return pretok, nil
+}
+
+// splitCase splits a NormalizedString on camelCase/PascalCase boundaries.
+// A boundary is found:
+//   - before an upper-case letter that follows a lower-case letter or a digit. E.g. "getUser"
+//   - before the last upper-case letter of an upper-case run followed by a lower-case letter. E.g. "HTTPServer"
+func splitCase(normalized *normalizer.NormalizedString) []normalizer.NormalizedString {
+	s := normalized.GetNormalized()
+
+	type char struct {
+		r       rune
+		byteIdx int
+	}
+	var chars []char
+	for i, r := range s {
+		chars = append(chars, char{r, i})
+	}
+
+	var boundaries []int
+	for i := 1; i < len(chars); i++ {
+		prev, curr := chars[i-1].r, chars[i].r
+		if !unicode.IsUpper(curr) {
+			continue
+		}
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+			boundaries = append(boundaries, chars[i].byteIdx)
+			continue
+		}
+		if unicode.IsUpper(prev) && i+1 < len(chars) && unicode.IsLower(chars[i+1].r) {
+			boundaries = append(boundaries, chars[i].byteIdx)
+		}
+	}
+
+	if len(boundaries) == 0 {
+		return []normalizer.NormalizedString{*normalized}
+	}
+
+	var (
+		splits []normalizer.NormalizedString
+		start  int
+	)
+	boundaries = append(boundaries, len(s))
+	for _, end := range boundaries {
+		slice := normalized.Slice(normalizer.NewRange(start, end, normalizer.NormalizedTarget))
+		if slice != nil {
+			splits = append(splits, *slice)
+		}
+		start = end
+	}
+
+	return splits
+}
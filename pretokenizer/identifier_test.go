@@ -0,0 +1,79 @@
+package pretokenizer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/normalizer"
+)
+
+func TestIdentifierSplit(t *testing.T) {
+	pretok := DefaultIdentifierSplit()
+
+	tests := []struct {
+		s   string
+		res []tokenizer.PreToken
+	}{
+		{
+			s: "getUserName",
+			res: []tokenizer.PreToken{
+				{Value: "get", Offsets: []int{0, 3}, Tokens: nil},
+				{Value: "User", Offsets: []int{3, 7}, Tokens: nil},
+				{Value: "Name", Offsets: []int{7, 11}, Tokens: nil},
+			},
+		},
+		{
+			s: "GetHTTPServer",
+			res: []tokenizer.PreToken{
+				{Value: "Get", Offsets: []int{0, 3}, Tokens: nil},
+				{Value: "HTTP", Offsets: []int{3, 7}, Tokens: nil},
+				{Value: "Server", Offsets: []int{7, 13}, Tokens: nil},
+			},
+		},
+		{
+			s: "get_user_name",
+			res: []tokenizer.PreToken{
+				{Value: "get", Offsets: []int{0, 3}, Tokens: nil},
+				{Value: "user", Offsets: []int{4, 8}, Tokens: nil},
+				{Value: "name", Offsets: []int{9, 13}, Tokens: nil},
+			},
+		},
+	}
+
+	for _, data := range tests {
+		pretokenized := tokenizer.NewPreTokenizedString(data.s)
+		out, err := pretok.PreTokenize(pretokenized)
+		if err != nil {
+			t.Fail()
+		}
+
+		got := out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte)
+		want := data.res
+
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("want %#v\ngot %#v\n", want, got)
+		}
+	}
+}
+
+func TestIdentifierSplitIsolated(t *testing.T) {
+	pretok := NewIdentifierSplit(normalizer.IsolatedBehavior)
+	pretokenized := tokenizer.NewPreTokenizedString("user_Name")
+
+	out, err := pretok.PreTokenize(pretokenized)
+	if err != nil {
+		t.Fail()
+	}
+
+	got := out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte)
+	want := []tokenizer.PreToken{
+		{Value: "user", Offsets: []int{0, 4}, Tokens: nil},
+		{Value: "_", Offsets: []int{4, 5}, Tokens: nil},
+		{Value: "Name", Offsets: []int{5, 9}, Tokens: nil},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %#v\ngot %#v\n", want, got)
+	}
+}
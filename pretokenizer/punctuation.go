@@ -27,6 +27,8 @@ func isPunctuation(c rune) bool {
 	return unicode.In(c, bpunc, unicode.P)
 }
 
+// Punctuation splits on punctuation characters, handling them according to
+// the configured `Behavior`.
 type Punctuation struct {
 	Behavior normalizer.SplitDelimiterBehavior
 }
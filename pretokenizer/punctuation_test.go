@@ -33,3 +33,46 @@ func TestPunctuation(t *testing.T) {
 		t.Errorf("want: %#v\ngot %#v\n", want, got)
 	}
 }
+
+func TestPunctuation_Behaviors(t *testing.T) {
+	tests := []struct {
+		behavior normalizer.SplitDelimiterBehavior
+		want     []tokenizer.PreToken
+	}{
+		{
+			behavior: normalizer.RemovedBehavior,
+			want: []tokenizer.PreToken{
+				{Value: "Hey", Offsets: []int{0, 3}, Tokens: nil},
+				{Value: " you", Offsets: []int{4, 8}, Tokens: nil},
+			},
+		},
+		{
+			behavior: normalizer.MergedWithPreviousBehavior,
+			want: []tokenizer.PreToken{
+				{Value: "Hey,", Offsets: []int{0, 4}, Tokens: nil},
+				{Value: " you!", Offsets: []int{4, 9}, Tokens: nil},
+			},
+		},
+		{
+			behavior: normalizer.MergedWithNextBehavior,
+			want: []tokenizer.PreToken{
+				{Value: "Hey", Offsets: []int{0, 3}, Tokens: nil},
+				{Value: ", you", Offsets: []int{3, 8}, Tokens: nil},
+				{Value: "!", Offsets: []int{8, 9}, Tokens: nil},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		pretok := NewPunctuation(tt.behavior)
+		out, err := pretok.PreTokenize(tokenizer.NewPreTokenizedString("Hey, you!"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte)
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("behavior %v: want %#v\ngot %#v\n", tt.behavior, tt.want, got)
+		}
+	}
+}
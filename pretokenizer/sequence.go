@@ -4,6 +4,8 @@ import (
 	"github.com/sugarme/tokenizer"
 )
 
+// Sequence applies a list of pre-tokenizers in order, each one splitting
+// further the output of the previous one.
 type Sequence struct {
 	pretokenizers []tokenizer.PreTokenizer
 }
@@ -0,0 +1,38 @@
+package pretokenizer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/normalizer"
+)
+
+func TestSequence(t *testing.T) {
+	pretok := NewSequence([]tokenizer.PreTokenizer{
+		NewWhitespaceSplit(),
+		DefaultPunctuation(),
+	})
+
+	out, err := pretok.PreTokenize(tokenizer.NewPreTokenizedString("Hey friend!  How are you?!?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte)
+	want := []tokenizer.PreToken{
+		{Value: "Hey", Offsets: []int{0, 3}, Tokens: nil},
+		{Value: "friend", Offsets: []int{4, 10}, Tokens: nil},
+		{Value: "!", Offsets: []int{10, 11}, Tokens: nil},
+		{Value: "How", Offsets: []int{13, 16}, Tokens: nil},
+		{Value: "are", Offsets: []int{17, 20}, Tokens: nil},
+		{Value: "you", Offsets: []int{21, 24}, Tokens: nil},
+		{Value: "?", Offsets: []int{24, 25}, Tokens: nil},
+		{Value: "!", Offsets: []int{25, 26}, Tokens: nil},
+		{Value: "?", Offsets: []int{26, 27}, Tokens: nil},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+}
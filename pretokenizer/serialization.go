@@ -0,0 +1,103 @@
+package pretokenizer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/normalizer"
+	"github.com/sugarme/tokenizer/util"
+)
+
+// This file implements json.Marshaler for pre-tokenizers so that they are
+// serialized in HuggingFace `tokenizer.json` format.
+
+func (bt *BertPreTokenizer) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("BertPreTokenizer", nil)
+}
+
+// MarshalJSON serializes ByteLevel. The same data is used whether it acts as
+// a pre-tokenizer, a post-processor or a decoder.
+func (bl *ByteLevel) MarshalJSON() ([]byte, error) {
+	if bl.spaceMarker() != DefaultSpaceMarker {
+		return nil, fmt.Errorf("Cannot serialize ByteLevel with custom space marker %q", bl.SpaceMarker)
+	}
+	return util.MarshalTyped("ByteLevel", map[string]interface{}{
+		"add_prefix_space": bl.AddPrefixSpace,
+		"trim_offsets":     bl.TrimOffsets,
+		"use_regex":        bl.UseRegex(),
+	})
+}
+
+func (d *CharDelimiterSplit) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("CharDelimiterSplit", map[string]interface{}{
+		"delimiter": string(d.Delimiter),
+	})
+}
+
+func (d *Digits) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("Digits", map[string]interface{}{
+		"individual_digits": d.IndividualDigits,
+	})
+}
+
+// MarshalJSON serializes Metaspace. The same data is used whether it acts as
+// a pre-tokenizer or a decoder.
+func (m *Metaspace) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("Metaspace", map[string]interface{}{
+		"replacement":      m.Replacement,
+		"add_prefix_space": m.AddPrefixSpace,
+	})
+}
+
+func (p *Punctuation) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("Punctuation", map[string]interface{}{
+		"behavior": p.Behavior.String(),
+	})
+}
+
+func (s *Sequence) MarshalJSON() ([]byte, error) {
+	pretokenizers := s.pretokenizers
+	if pretokenizers == nil {
+		pretokenizers = []tokenizer.PreTokenizer{}
+	}
+	for _, p := range pretokenizers {
+		if _, ok := p.(json.Marshaler); !ok {
+			return nil, fmt.Errorf("Cannot serialize pre-tokenizer of type %T", p)
+		}
+	}
+	return util.MarshalTyped("Sequence", map[string]interface{}{
+		"pretokenizers": pretokenizers,
+	})
+}
+
+func (s *Split) MarshalJSON() ([]byte, error) {
+	pattern, err := normalizer.SerializePattern(s.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	return util.MarshalTyped("Split", map[string]interface{}{
+		"pattern":  pattern,
+		"behavior": s.Behavior.String(),
+		"invert":   s.Invert,
+	})
+}
+
+func (us *UnicodeScript) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("UnicodeScripts", nil)
+}
+
+func (uw *UnicodeWords) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("UnicodeWords", nil)
+}
+
+func (w *Whitespace) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("Whitespace", nil)
+}
+
+func (w *WhitespaceSplit) MarshalJSON() ([]byte, error) {
+	if w.IncludeTrailingSpace {
+		return nil, fmt.Errorf("Cannot serialize WhitespaceSplit with IncludeTrailingSpace")
+	}
+	return util.MarshalTyped("WhitespaceSplit", nil)
+}
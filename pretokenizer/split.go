@@ -20,6 +20,18 @@ func NewSplit(pattern normalizer.Pattern, behavior normalizer.SplitDelimiterBeha
 	}
 }
 
+// NewSplitFromRegex creates a Split pre-tokenizer from a raw regex pattern as
+// found in `tokenizer.json` files. See `normalizer.CompileRegexpPattern` for
+// supported constructs.
+func NewSplitFromRegex(pattern string, behavior normalizer.SplitDelimiterBehavior, invert bool) (*Split, error) {
+	re, err := normalizer.CompileRegexpPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSplit(re, behavior, invert), nil
+}
+
 // Implement tokenizer.PreTokenizer for Split
 var _ tokenizer.PreTokenizer = new(Split)
 
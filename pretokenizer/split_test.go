@@ -142,3 +142,71 @@ func TestSplitInvert(t *testing.T) {
 		t.Errorf("Expected got1 and got1 are equal. But \ngot1: %#v\ngot2: %#v\n", got1, got2)
 	}
 }
+
+func TestSplitFromRegex(t *testing.T) {
+	// GPT-2 pattern
+	pattern := `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`
+	pretok, err := NewSplitFromRegex(pattern, normalizer.IsolatedBehavior, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pretokenized := tokenizer.NewPreTokenizedString("Hello world  it's 42!\tok")
+	out, err := pretok.PreTokenize(pretokenized)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte)
+	want := []tokenizer.PreToken{
+		{Value: "Hello", Offsets: []int{0, 5}, Tokens: nil},
+		{Value: " world", Offsets: []int{5, 11}, Tokens: nil},
+		{Value: " ", Offsets: []int{11, 12}, Tokens: nil},
+		{Value: " it", Offsets: []int{12, 15}, Tokens: nil},
+		{Value: "'s", Offsets: []int{15, 17}, Tokens: nil},
+		{Value: " 42", Offsets: []int{17, 20}, Tokens: nil},
+		{Value: "!", Offsets: []int{20, 21}, Tokens: nil},
+		{Value: "\t", Offsets: []int{21, 22}, Tokens: nil},
+		{Value: "ok", Offsets: []int{22, 24}, Tokens: nil},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+
+	// Unsupported constructs
+	for _, p := range []string{`a(?=b)`, `(?<!a)b`, `(a)\1`, `[^\W]`} {
+		if _, err := NewSplitFromRegex(p, normalizer.IsolatedBehavior, false); err == nil {
+			t.Errorf("pattern %q: want error\n", p)
+		}
+	}
+}
+
+func TestSplitFromRegex_Llama3(t *testing.T) {
+	// GPT-4 (cl100k) / Llama-3 pattern
+	pattern := `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+	pretok, err := NewSplitFromRegex(pattern, normalizer.IsolatedBehavior, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pretokenized := tokenizer.NewPreTokenizedString("Hello  world\n\nI'M 12345!!\n   x")
+	out, err := pretok.PreTokenize(pretokenized)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, s := range out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte) {
+		got = append(got, s.Value)
+	}
+	want := []string{"Hello", " ", " world", "\n\n", "I", "'M", " ", "123", "45", "!!\n", "  ", " x"}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %q\ngot %q\n", want, got)
+	}
+
+	if got := pretok.Pattern.(*normalizer.RegexpPattern).String(); got != pattern {
+		t.Errorf("want pattern %q, got %q\n", pattern, got)
+	}
+}
@@ -0,0 +1,64 @@
+package pretokenizer
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/rivo/uniseg"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/normalizer"
+)
+
+// UnicodeWords splits on word boundaries as defined by the Unicode text
+// segmentation algorithm (UAX #29), removing whitespaces.
+//
+// Unlike `Whitespace`, which is regex based, it keeps emoji sequences (ZWJ,
+// flags, skin tones), numbers like "3.14" and words like "can't" in one
+// piece, and splits ideographs (CJK) into one piece per character. Note that
+// UAX #29 has no dictionary for scripts written without spaces between words,
+// such as Thai, which are split into grapheme clusters (combining vowels and
+// tone marks stay with their consonant).
+type UnicodeWords struct{}
+
+func NewUnicodeWords() *UnicodeWords {
+	return new(UnicodeWords)
+}
+
+func DefaultUnicodeWords() *UnicodeWords {
+	return new(UnicodeWords)
+}
+
+// Implement tokenizer.PreTokenizer for UnicodeWords
+
+var _ tokenizer.PreTokenizer = new(UnicodeWords)
+
+func (p *UnicodeWords) PreTokenize(pretokenized *tokenizer.PreTokenizedString) (*tokenizer.PreTokenizedString, error) {
+	pretok := pretokenized.Split(func(noop int, normalized *normalizer.NormalizedString) []tokenizer.SplitIdx {
+		var splitIdxs []tokenizer.SplitIdx
+
+		s := normalized.GetNormalized()
+		offset := 0
+		state := -1
+		for len(s) > 0 {
+			var word string
+			word, s, state = uniseg.FirstWordInString(s, state)
+			start := offset
+			offset += len(word)
+
+			if strings.TrimFunc(word, unicode.IsSpace) == "" {
+				continue
+			}
+
+			split := normalized.Slice(normalizer.NewRange(start, offset, normalizer.NormalizedTarget))
+			if split == nil {
+				continue
+			}
+			splitIdxs = append(splitIdxs, tokenizer.SplitIdx{Normalized: split, Tokens: nil})
+		}
+
+		return splitIdxs
+	})
+
+	return pretok, nil
+}
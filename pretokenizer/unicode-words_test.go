@@ -0,0 +1,75 @@
+package pretokenizer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/normalizer"
+)
+
+func TestUnicodeWords(t *testing.T) {
+	pretok := DefaultUnicodeWords()
+
+	tests := []struct {
+		s   string
+		res []tokenizer.PreToken
+	}{
+		{
+			s: "Hey man, it's 3.14!",
+			res: []tokenizer.PreToken{
+				{Value: "Hey", Offsets: []int{0, 3}, Tokens: nil},
+				{Value: "man", Offsets: []int{4, 7}, Tokens: nil},
+				{Value: ",", Offsets: []int{7, 8}, Tokens: nil},
+				{Value: "it's", Offsets: []int{9, 13}, Tokens: nil},
+				{Value: "3.14", Offsets: []int{14, 18}, Tokens: nil},
+				{Value: "!", Offsets: []int{18, 19}, Tokens: nil},
+			},
+		},
+		{
+			s: "東京へ行く",
+			res: []tokenizer.PreToken{
+				{Value: "東", Offsets: []int{0, 3}, Tokens: nil},
+				{Value: "京", Offsets: []int{3, 6}, Tokens: nil},
+				{Value: "へ", Offsets: []int{6, 9}, Tokens: nil},
+				{Value: "行", Offsets: []int{9, 12}, Tokens: nil},
+				{Value: "く", Offsets: []int{12, 15}, Tokens: nil},
+			},
+		},
+		{
+			s: "Hi 👩‍👩‍👧 🇫🇷",
+			res: []tokenizer.PreToken{
+				{Value: "Hi", Offsets: []int{0, 2}, Tokens: nil},
+				{Value: "👩‍👩‍👧", Offsets: []int{3, 21}, Tokens: nil},
+				{Value: "🇫🇷", Offsets: []int{22, 30}, Tokens: nil},
+			},
+		},
+		{
+			s: "สวัสดี ครับ",
+			res: []tokenizer.PreToken{
+				{Value: "ส", Offsets: []int{0, 3}, Tokens: nil},
+				{Value: "วั", Offsets: []int{3, 9}, Tokens: nil},
+				{Value: "ส", Offsets: []int{9, 12}, Tokens: nil},
+				{Value: "ดี", Offsets: []int{12, 18}, Tokens: nil},
+				{Value: "ค", Offsets: []int{19, 22}, Tokens: nil},
+				{Value: "รั", Offsets: []int{22, 28}, Tokens: nil},
+				{Value: "บ", Offsets: []int{28, 31}, Tokens: nil},
+			},
+		},
+	}
+
+	for _, data := range tests {
+		pretokenized := tokenizer.NewPreTokenizedString(data.s)
+		out, err := pretok.PreTokenize(pretokenized)
+		if err != nil {
+			t.Fail()
+		}
+
+		got := out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte)
+		want := data.res
+
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("want %#v\ngot %#v\n", want, got)
+		}
+	}
+}
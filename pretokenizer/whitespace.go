@@ -5,6 +5,17 @@ import (
 	"github.com/sugarme/tokenizer/normalizer"
 )
 
+// whitespacePattern matches words or runs of punctuation. `\w` and `\s` are
+// Unicode aware, as in the Rust reference implementation.
+var whitespacePattern = func() *normalizer.RegexpPattern {
+	re, err := normalizer.CompileRegexpPattern(`\w+|[^\w\s]+`)
+	if err != nil {
+		panic(err)
+	}
+	return re
+}()
+
+// Whitespace splits on word boundaries (`\w+|[^\w\s]+`), removing whitespaces.
 type Whitespace struct{}
 
 func NewWhitespace() *Whitespace {
@@ -21,9 +32,7 @@ var _ tokenizer.PreTokenizer = new(Whitespace)
 
 func (p *Whitespace) PreTokenize(pretokenized *tokenizer.PreTokenizedString) (*tokenizer.PreTokenizedString, error) {
 	pretok := pretokenized.Split(func(noop int, normalized *normalizer.NormalizedString) []tokenizer.SplitIdx {
-		s := `\w+|[^\w\s]+`
-		rePattern := normalizer.NewRegexpPattern(s)
-		invert := normalizer.NewInvertPattern(rePattern)
+		invert := normalizer.NewInvertPattern(whitespacePattern)
 		splits := normalized.Split(invert, normalizer.RemovedBehavior)
 
 		var splitIdxs []tokenizer.SplitIdx
@@ -39,12 +48,23 @@ func (p *Whitespace) PreTokenize(pretokenized *tokenizer.PreTokenizedString) (*t
 	return pretok, nil
 }
 
-type WhitespaceSplit struct{}
+// WhitespaceSplit splits on whitespace characters, removing them.
+type WhitespaceSplit struct {
+	// IncludeTrailingSpace extends the (original) offsets of each piece up to
+	// the start of the next piece, ie. including its trailing whitespace. The
+	// piece content itself does not include the whitespace.
+	IncludeTrailingSpace bool
+}
 
 func NewWhitespaceSplit() *WhitespaceSplit {
 	return new(WhitespaceSplit)
 }
 
+// SetIncludeTrailingSpace sets `IncludeTrailingSpace` property
+func (p *WhitespaceSplit) SetIncludeTrailingSpace(v bool) {
+	p.IncludeTrailingSpace = v
+}
+
 // Implement tokenizer.PreTokenizer for WhitespaceSplit
 
 var _ tokenizer.PreTokenizer = new(WhitespaceSplit)
@@ -54,6 +74,10 @@ func (p *WhitespaceSplit) PreTokenize(pretokenized *tokenizer.PreTokenizedString
 		isWhitespace := normalizer.NewFnPattern(normalizer.IsWhitespace)
 		splits := normalized.Split(isWhitespace, normalizer.RemovedBehavior)
 
+		if p.IncludeTrailingSpace {
+			splits = withTrailingSpace(normalized, splits)
+		}
+
 		var splitIdxs []tokenizer.SplitIdx
 		for _, s := range splits {
 			normalized := s
@@ -66,3 +90,34 @@ func (p *WhitespaceSplit) PreTokenize(pretokenized *tokenizer.PreTokenizedString
 
 	return pretok, nil
 }
+
+// withTrailingSpace re-slices each (non-empty) split so that its original part
+// spans up to the start of the next split, then strips the trailing whitespace
+// from the normalized part only.
+func withTrailingSpace(normalized *normalizer.NormalizedString, splits []normalizer.NormalizedString) []normalizer.NormalizedString {
+	var pieces []normalizer.NormalizedString
+	for _, s := range splits {
+		if s.GetNormalized() != "" {
+			pieces = append(pieces, s)
+		}
+	}
+
+	shift := normalized.Shift()
+	var out []normalizer.NormalizedString
+	for i, piece := range pieces {
+		start := piece.OffsetsOriginal()[0] - shift
+		end := normalized.LenOriginal()
+		if i+1 < len(pieces) {
+			end = pieces[i+1].OffsetsOriginal()[0] - shift
+		}
+
+		slice := normalized.Slice(normalizer.NewRange(start, end, normalizer.OriginalTarget))
+		if slice == nil {
+			out = append(out, piece)
+			continue
+		}
+		out = append(out, *slice.RStrip())
+	}
+
+	return out
+}
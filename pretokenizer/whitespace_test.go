@@ -33,6 +33,15 @@ func TestWhitespace(t *testing.T) {
 				{Value: "?", Offsets: []int{17, 18}, Tokens: nil},
 			},
 		},
+		{
+			s: "Café naïve, 東京\n",
+			res: []tokenizer.PreToken{
+				{Value: "Café", Offsets: []int{0, 5}, Tokens: nil},
+				{Value: "naïve", Offsets: []int{6, 12}, Tokens: nil},
+				{Value: ",", Offsets: []int{12, 13}, Tokens: nil},
+				{Value: "東京", Offsets: []int{14, 20}, Tokens: nil},
+			},
+		},
 	}
 
 	for _, data := range tests {
@@ -89,3 +98,44 @@ func TestWhitespaceSplit(t *testing.T) {
 		}
 	}
 }
+
+func TestWhitespaceSplit_IncludeTrailingSpace(t *testing.T) {
+	pretok := NewWhitespaceSplit()
+	pretok.SetIncludeTrailingSpace(true)
+
+	tests := []struct {
+		s   string
+		res []tokenizer.PreToken
+	}{
+		{
+			s: "a b c",
+			res: []tokenizer.PreToken{
+				{Value: "a", Offsets: []int{0, 2}, Tokens: nil},
+				{Value: "b", Offsets: []int{2, 4}, Tokens: nil},
+				{Value: "c", Offsets: []int{4, 5}, Tokens: nil},
+			},
+		},
+		{
+			s: " Hey  man!\t",
+			res: []tokenizer.PreToken{
+				{Value: "Hey", Offsets: []int{1, 6}, Tokens: nil},
+				{Value: "man!", Offsets: []int{6, 11}, Tokens: nil},
+			},
+		},
+	}
+
+	for _, data := range tests {
+		pretokenized := tokenizer.NewPreTokenizedString(data.s)
+		out, err := pretok.PreTokenize(pretokenized)
+		if err != nil {
+			t.Fail()
+		}
+
+		got := out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte)
+		want := data.res
+
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("want %#v\ngot %#v\n", want, got)
+		}
+	}
+}
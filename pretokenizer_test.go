@@ -3,6 +3,8 @@ package tokenizer
 import (
 	"reflect"
 	"testing"
+
+	"github.com/sugarme/tokenizer/normalizer"
 )
 
 func TestBytesToCharConverter(t *testing.T) {
@@ -40,3 +42,59 @@ func TestBytesToCharConverter(t *testing.T) {
 		t.Errorf("want %v, got %v\n", want, got)
 	}
 }
+
+func TestBytesToCharConverter_Clamp(t *testing.T) {
+	sequence := "Löwe 老虎"
+	converter := NewBytesToCharOffsetConverter(sequence)
+
+	tests := []struct {
+		offsets []int
+		want    []int
+		clamped bool
+	}{
+		{[]int{0, 0}, []int{0, 0}, false},
+		{[]int{0, 5}, []int{0, 4}, false},
+		{[]int{6, 12}, []int{5, 7}, false}, // end of sequence
+		{[]int{6, 20}, []int{5, 7}, true},  // end out of range
+		{[]int{15, 20}, []int{7, 7}, true}, // both out of range
+	}
+
+	for _, tt := range tests {
+		got, clamped := converter.ConvertClamp(tt.offsets)
+		if !reflect.DeepEqual(tt.want, got) || clamped != tt.clamped {
+			t.Errorf("offsets %v: want %v (clamped: %v), got %v (clamped: %v)\n", tt.offsets, tt.want, tt.clamped, got, clamped)
+		}
+
+		// Convert fails on offsets out of range.
+		got, err := converter.Convert(tt.offsets)
+		if tt.clamped {
+			if err == nil {
+				t.Errorf("offsets %v: want error, got %v\n", tt.offsets, got)
+			}
+		} else if err != nil || !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("offsets %v: want %v, got %v (err: %v)\n", tt.offsets, tt.want, got, err)
+		}
+	}
+}
+
+func TestPreTokenizedString_NormalizeKeepsTokens(t *testing.T) {
+	pt := &PreTokenizedString{
+		original: "ab",
+		splits: []Split{
+			{normalized: normalizer.NewNormalizedFrom("a")},
+			{normalized: normalizer.NewNormalizedFrom("b"), tokens: []Token{{Id: 1, Value: "b", Offsets: []int{0, 1}}}},
+		},
+	}
+
+	pt.Normalize(func(n *normalizer.NormalizedString) *normalizer.NormalizedString {
+		return n.Uppercase()
+	})
+
+	var got []string
+	for _, split := range pt.splits {
+		got = append(got, split.normalized.GetNormalized())
+	}
+	if want := []string{"A", "b"}; !reflect.DeepEqual(want, got) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
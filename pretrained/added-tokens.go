@@ -12,6 +12,7 @@ func CreateAddedTokens(data []tokenizer.TokenConfig) (specialToks, toks []tokeni
 		tok.Normalized = d.Normalized
 		tok.RStrip = d.Rstrip
 		tok.SingleWord = d.SingleWord
+		tok.Special = d.Special
 
 		if d.Special {
 			specialToks = append(specialToks, tok)
@@ -31,7 +31,7 @@ func CreateDecoder(config map[string]interface{}) (tokenizer.Decoder, error) {
 	typ := params.Get("type").(string)
 
 	switch typ {
-	case "BPE":
+	case "BPE", "BPEDecoder":
 		return createBPEDecoder(params)
 	case "ByteLevel":
 		return createByteLevelDecoder(params)
@@ -71,10 +71,13 @@ func createByteLevelDecoder(params *util.Params) (*pretokenizer.ByteLevel, error
 	addPrefixSpace := params.Get("add_prefix_space", false).(bool)
 	trimOffsets := params.Get("trim_offsets", false).(bool)
 
-	return &pretokenizer.ByteLevel{
+	bl := &pretokenizer.ByteLevel{
 		AddPrefixSpace: addPrefixSpace,
 		TrimOffsets:    trimOffsets,
-	}, nil
+	}
+	bl.SetUseRegex(params.Get("use_regex", true).(bool))
+
+	return bl, nil
 }
 
 func createMetaspaceDecoder(params *util.Params) (*pretokenizer.Metaspace, error) {
@@ -94,7 +97,7 @@ func createCTCDecoder(params *util.Params) (*decoder.CTC, error) {
 	}
 
 	padToken := params.Get("pad_token").(string)
-	wordDelimiter := params.Get("word_delimiter").(string)
+	wordDelimiter := params.Get("word_delimiter_token", params.Get("word_delimiter", "|")).(string)
 	cleanup := params.Get("cleanup").(bool)
 
 	return decoder.NewCTC(padToken, wordDelimiter, cleanup), nil
@@ -158,23 +161,7 @@ func createReplaceDecoder(params *util.Params) (*normalizer.Replace, error) {
 		return nil, nil
 	}
 
-	var pattern string
-	var patternType normalizer.ReplacePattern
-	patternParams := params.Get("pattern").(map[string]interface{})
-	pparams := util.NewParams(patternParams)
-	switch {
-	case pparams.Has("String"):
-		pattern = pparams.Get("String").(string)
-		patternType = normalizer.String
-
-	case params.Has("Regex"):
-		pattern = pparams.Get("Regex").(string)
-		patternType = normalizer.String
-	}
-
-	content := params.Get("content").(string)
-
-	return normalizer.NewReplace(patternType, pattern, content), nil
+	return createReplace(params)
 }
 
 func createFuseDecoder(params *util.Params) (*decoder.Fuse, error) {
@@ -188,8 +175,8 @@ func createByteFallbackDecoder(params *util.Params) (*decoder.ByteFallback, erro
 func createStripDecoder(params *util.Params) (*decoder.Strip, error) {
 
 	content := params.Get("content").(string)
-	start := int(params.Get("start").(float64))
-	stop := int(params.Get("stop").(float64))
+	start := int(params.Get("start", 0.0).(float64))
+	stop := int(params.Get("stop", 0.0).(float64))
 
 	return decoder.NewStrip(content, start, stop), nil
 }
@@ -0,0 +1,48 @@
+package pretrained
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+)
+
+func TestFromPretrained(t *testing.T) {
+	files := map[string]string{
+		"/org/model/resolve/main/tokenizer.json":        wordLevelTokenizerJSON,
+		"/org/model/resolve/main/tokenizer_config.json": `{"model_max_length": 6, "padding_side": "left"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	tk, err := FromPretrained("org/model", tokenizer.WithEndpoint(server.URL), tokenizer.WithCacheDir(cacheDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tk.GetModelMaxLength() != 6 || tk.GetPadding().Direction != tokenizer.Left {
+		t.Errorf("want tokenizer config applied")
+	}
+
+	// Offline, from the cache
+	server.Close()
+	tk, err = FromPretrained("org/model", tokenizer.WithOffline(true), tokenizer.WithCacheDir(cacheDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tk.GetModelMaxLength() != 6 {
+		t.Errorf("want tokenizer config applied")
+	}
+
+	if _, err := FromPretrained("org/other", tokenizer.WithOffline(true), tokenizer.WithCacheDir(cacheDir)); err == nil {
+		t.Errorf("want error on uncached repo in offline mode")
+	}
+}
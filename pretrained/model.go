@@ -3,10 +3,12 @@ package pretrained
 import (
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/sugarme/tokenizer"
 	"github.com/sugarme/tokenizer/model"
 	"github.com/sugarme/tokenizer/model/bpe"
+	"github.com/sugarme/tokenizer/model/unigram"
 	"github.com/sugarme/tokenizer/model/wordlevel"
 	"github.com/sugarme/tokenizer/model/wordpiece"
 	"github.com/sugarme/tokenizer/util"
@@ -99,13 +101,20 @@ func createBPE(params *util.Params) (tokenizer.Model, error) {
 		v := params.Get("end_of_word_suffix").(string)
 		endOfWordSuffix = &v
 	}
-	// fuseUnk := params.Get("use_unk").(bool)
-	// byteFallback := params.Get("byte_fallback").(bool)
+	fuseUnk := params.Get("fuse_unk", false).(bool)
+	byteFallback := params.Get("byte_fallback", false).(bool)
 
 	vocab := castVocab(params.Get("vocab").(map[string]interface{}))
 	merges := castMerge(params.Get("merges").([]interface{}))
 
-	return bpe.New(vocab, merges, dropout, unkToken, continuingSubwordPrefix, endOfWordSuffix)
+	m, err := bpe.New(vocab, merges, dropout, unkToken, continuingSubwordPrefix, endOfWordSuffix)
+	if err != nil {
+		return nil, err
+	}
+	m.FuseUnk = fuseUnk
+	m.ByteFallback = byteFallback
+
+	return m, nil
 }
 
 // WordPiece json format:
@@ -124,7 +133,7 @@ func createWordPiece(params *util.Params) (tokenizer.Model, error) {
 	}
 	if params.Has("continuing_subword_prefix") {
 		v := params.Get("continuing_subword_prefix").(string)
-		opts.Get("continuing_subword_prefix", v)
+		opts.Set("continuing_subword_prefix", v)
 	}
 
 	if params.Has("max_input_chars_per_word") {
@@ -149,8 +158,48 @@ func createWordLevel(params *util.Params) (tokenizer.Model, error) {
 	return wordlevel.New(vocab, unkToken)
 }
 
+// Unigram json format:
+// --------------------
+// "type": "Unigram",
+// "unk_id": 0,
+// "vocab": [["<unk>", 0.0], ...],
+// "byte_fallback": false
+
 func createUnigram(params *util.Params) (tokenizer.Model, error) {
-	panic("NotImplementedError")
+	var unkId *int
+	if params.Has("unk_id") {
+		if v, ok := params.Get("unk_id").(float64); ok {
+			id := int(v)
+			unkId = &id
+		}
+	}
+
+	var byteFallback bool
+	if params.Has("byte_fallback") {
+		if v, ok := params.Get("byte_fallback").(bool); ok {
+			byteFallback = v
+		}
+	}
+
+	var vocab []unigram.TokenScore
+	if params.Has("vocab") {
+		for _, item := range params.Get("vocab").([]interface{}) {
+			entry, ok := item.([]interface{})
+			if !ok || len(entry) != 2 {
+				err := fmt.Errorf("Invalid Unigram vocab entry: %v", item)
+				return nil, err
+			}
+			tok, tokOk := entry[0].(string)
+			score, scoreOk := entry[1].(float64)
+			if !tokOk || !scoreOk {
+				err := fmt.Errorf("Invalid Unigram vocab entry: %v", item)
+				return nil, err
+			}
+			vocab = append(vocab, unigram.TokenScore{Token: tok, Score: score})
+		}
+	}
+
+	return unigram.New(vocab, unkId, byteFallback)
 }
 
 func castVocab(input map[string]interface{}) model.Vocab {
@@ -162,10 +211,18 @@ func castVocab(input map[string]interface{}) model.Vocab {
 	return out
 }
 
+// castMerge accepts both merges formats: `"a b"` strings and `["a", "b"]`
+// pairs.
 func castMerge(input []interface{}) []string {
 	out := make([]string, len(input))
 	for i, v := range input {
-		out[i] = v.(string)
+		switch m := v.(type) {
+		case string:
+			out[i] = m
+		case []interface{}:
+			parts := util.CastSlice[string](m)
+			out[i] = strings.Join(parts, " ")
+		}
 	}
 
 	return out
@@ -10,12 +10,13 @@ package pretrained
 // 7. NFKD
 // 8. Sequence
 // 9. Lowercase
-// 10. Nmt (TODO)
-// 11. Precompiled (TODO)
+// 10. Nmt
+// 11. Precompiled
 // 12. Replace
 // 13. Prepend
 
 import (
+	"encoding/base64"
 	"fmt"
 
 	"github.com/sugarme/tokenizer/normalizer"
@@ -36,7 +37,7 @@ func CreateNormalizer(config map[string]interface{}) (normalizer.Normalizer, err
 	switch typ {
 	case "BertNormalizer":
 		return createBertNormalizer(params)
-	case "StripNormalizer":
+	case "Strip", "StripNormalizer":
 		return createStripNormalizer(params)
 	case "StripAccents":
 		return createStripAccents(params)
@@ -89,8 +90,9 @@ func createBertNormalizer(params *util.Params) (normalizer.Normalizer, error) {
 
 	cleanText := params.Get("clean_text", false).(bool)
 	handleChineseChars := params.Get("handle_chinese_chars", false).(bool)
-	stripAccents := params.Get("strip_accents", false).(bool)
 	lowercase := params.Get("lowercase", false).(bool)
+	// A `null` strip_accents follows lowercase
+	stripAccents := params.Get("strip_accents", lowercase).(bool)
 
 	return normalizer.NewBertNormalizer(cleanText, lowercase, handleChineseChars, stripAccents), nil
 }
@@ -100,23 +102,37 @@ func createReplaceNormalizer(params *util.Params) (normalizer.Normalizer, error)
 		return nil, nil
 	}
 
-	var pattern string
-	var patternType normalizer.ReplacePattern
-	patternParams := params.Get("pattern").(map[string]interface{})
+	return createReplace(params)
+}
+
+// createReplace creates a Replace normalizer or decoder from its
+// `{"pattern": {"String"|"Regex": ...}, "content": ...}` params.
+func createReplace(params *util.Params) (*normalizer.Replace, error) {
+	patternParams, ok := params.Get("pattern").(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Replace: missing pattern")
+	}
 	pparams := util.NewParams(patternParams)
+
+	var (
+		pattern normalizer.Pattern
+		err     error
+	)
 	switch {
 	case pparams.Has("String"):
-		pattern = pparams.Get("String").(string)
-		patternType = normalizer.String
-
-	case params.Has("Regex"):
-		pattern = pparams.Get("Regex").(string)
-		patternType = normalizer.String
+		pattern = normalizer.NewStringPattern(pparams.Get("String").(string))
+	case pparams.Has("Regex"):
+		pattern, err = normalizer.CompileRegexpPattern(pparams.Get("Regex").(string))
+		if err != nil {
+			return nil, fmt.Errorf("Replace: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("Replace: unsupported pattern %v", patternParams)
 	}
 
-	content := params.Get("content").(string)
+	content, _ := params.Get("content", "").(string)
 
-	return normalizer.NewReplace(patternType, pattern, content), nil
+	return normalizer.NewReplacePattern(pattern, content)
 }
 
 func createPrependNormalizer(params *util.Params) (normalizer.Normalizer, error) {
@@ -139,14 +155,26 @@ func createStripAccents(params *util.Params) (normalizer.Normalizer, error) {
 	return normalizer.NewStripAccents(), nil
 }
 
+// Precompiled json data:
+// ----------------------
+// "type":"Precompiled"
+// "precompiled_charsmap":"<base64 encoded bytes>"
 func createPrecompiledNormalizer(params *util.Params) (normalizer.Normalizer, error) {
-	// TODO
-	panic("NotImplementedError")
+	charsmap, ok := params.Get("precompiled_charsmap", "").(string)
+	if !ok || charsmap == "" {
+		return nil, fmt.Errorf("Precompiled normalizer: missing 'precompiled_charsmap'")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(charsmap)
+	if err != nil {
+		return nil, fmt.Errorf("Precompiled normalizer: invalid 'precompiled_charsmap': %v", err)
+	}
+
+	return normalizer.NewPrecompiled(data)
 }
 
 func createNmtNormalizer(params *util.Params) (normalizer.Normalizer, error) {
-	// TODO
-	panic("NotImplementedError")
+	return normalizer.NewNmt(), nil
 }
 
 func createSequenceNormalizer(params *util.Params) (normalizer.Normalizer, error) {
@@ -2,6 +2,8 @@ package pretrained
 
 import (
 	"testing"
+
+	"github.com/sugarme/tokenizer/normalizer"
 )
 
 func TestCreateSequenceNormalizer(t *testing.T) {
@@ -55,3 +57,37 @@ func TestNullNormalizer(t *testing.T) {
 		panic(err)
 	}
 }
+
+func TestCreateReplaceNormalizer(t *testing.T) {
+	n, err := CreateNormalizer(map[string]interface{}{
+		"type":    "Replace",
+		"pattern": map[string]interface{}{"Regex": `\s+`},
+		"content": "▁",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := n.Normalize(normalizer.NewNormalizedFrom("Hey  friend"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Hey▁friend"
+	if got := out.GetNormalized(); got != want {
+		t.Errorf("want %q, got %q\n", want, got)
+	}
+
+	// Invalid patterns are reported
+	for _, pattern := range []map[string]interface{}{
+		{"Regex": `(?<=a)b`},
+		{"Unknown": "a"},
+	} {
+		_, err = CreateNormalizer(map[string]interface{}{
+			"type":    "Replace",
+			"pattern": pattern,
+			"content": "",
+		})
+		if err == nil {
+			t.Errorf("want error for pattern %v, got nil\n", pattern)
+		}
+	}
+}
@@ -1,47 +1,67 @@
 package pretrained
 
 import (
+	"fmt"
+
 	"github.com/sugarme/tokenizer"
 	"github.com/sugarme/tokenizer/util"
 )
 
+// Padding json data:
+// ------------------
+// "strategy": "BatchLongest" or {"Fixed": 512}
+// "direction": "Right"
+// "pad_to_multiple_of": null
+// "pad_id": 0
+// "pad_type_id": 0
+// "pad_token": "[PAD]"
 func CreatePaddingParams(config map[string]interface{}) (*tokenizer.PaddingParams, error) {
 	if config == nil {
 		return nil, nil
 	}
 
 	params := util.NewParams(config)
-	strategyName := params.Get("strategy").(string)
-	// TODO. verify???
-	strategySize := int(params.Get("size").(float64))
+
 	var strategy *tokenizer.PaddingStrategy
-	switch strategyName {
-	case "BatchLongest":
-		opt := tokenizer.WithBatchLongest()
-		strategy = tokenizer.NewPaddingStrategy(opt)
-	case "Fixed":
-		opt := tokenizer.WithFixed(strategySize)
-		strategy = tokenizer.NewPaddingStrategy(opt)
+	switch v := params.Get("strategy").(type) {
+	case string:
+		switch v {
+		case "BatchLongest":
+			strategy = tokenizer.NewPaddingStrategy(tokenizer.WithBatchLongest())
+		case "Fixed": // legacy format with a separate `size` field
+			size, _ := params.Get("size", 0.0).(float64)
+			strategy = tokenizer.NewPaddingStrategy(tokenizer.WithFixed(int(size)))
+		}
+	case map[string]interface{}:
+		if size, ok := v["Fixed"].(float64); ok {
+			strategy = tokenizer.NewPaddingStrategy(tokenizer.WithFixed(int(size)))
+		}
+	}
+	if strategy == nil {
+		err := fmt.Errorf("Invalid padding strategy: %v", params.Get("strategy"))
+		return nil, err
 	}
 
-	directionName := params.Get("direction").(string)
 	var direction tokenizer.PaddingDirection
+	directionName, _ := params.Get("direction", "Right").(string)
 	switch directionName {
-	case "left":
+	case "left", "Left":
 		direction = tokenizer.Left
-	case "right":
+	case "right", "Right":
 		direction = tokenizer.Right
 	}
 
-	id := int(params.Get("pad_id").(float64))
-	typeId := int(params.Get("pad_type_id").(float64))
-	token := params.Get("pad_token").(string)
+	id, _ := params.Get("pad_id", 0.0).(float64)
+	typeId, _ := params.Get("pad_type_id", 0.0).(float64)
+	token, _ := params.Get("pad_token", "[PAD]").(string)
+	multiple, _ := params.Get("pad_to_multiple_of", 0.0).(float64)
 
 	return &tokenizer.PaddingParams{
-		Strategy:  *strategy,
-		Direction: direction,
-		PadId:     id,
-		PadTypeId: typeId,
-		PadToken:  token,
+		Strategy:        *strategy,
+		Direction:       direction,
+		PadId:           int(id),
+		PadTypeId:       int(typeId),
+		PadToken:        token,
+		PadToMultipleOf: int(multiple),
 	}, nil
 }
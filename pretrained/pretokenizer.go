@@ -12,6 +12,7 @@ package pretrained
 // 9. WhitespaceSplit
 // 10. Digits
 // 11. UnicodeScripts
+// 12. UnicodeWords
 
 import (
 	"fmt"
@@ -35,7 +36,7 @@ func CreatePreTokenizer(config map[string]interface{}) (tokenizer.PreTokenizer,
 		return pretokenizer.NewBertPreTokenizer(), nil
 	case "ByteLevel":
 		return createByteLevelPreTokenizer(params)
-	case "Delimiter":
+	case "CharDelimiterSplit", "Delimiter":
 		return createDelimiterPreTokenizer(params)
 	case "Metaspace":
 		return createMetaspacePreTokenizer(params)
@@ -51,6 +52,8 @@ func CreatePreTokenizer(config map[string]interface{}) (tokenizer.PreTokenizer,
 		return createDigitsPreTokenizer(params)
 	case "UnicodeScripts":
 		return createUnicodeScriptsPreTokenizer(params)
+	case "UnicodeWords":
+		return pretokenizer.NewUnicodeWords(), nil
 	case "Split":
 		return createSplitPreTokenizer(params)
 
@@ -68,10 +71,13 @@ func createByteLevelPreTokenizer(params *util.Params) (tokenizer.PreTokenizer, e
 	addPrefixSpace := params.Get("add_prefix_space", false).(bool)
 	trimOffsets := params.Get("trim_offsets", false).(bool)
 
-	return &pretokenizer.ByteLevel{
+	bl := &pretokenizer.ByteLevel{
 		AddPrefixSpace: addPrefixSpace,
 		TrimOffsets:    trimOffsets,
-	}, nil
+	}
+	bl.SetUseRegex(params.Get("use_regex", true).(bool))
+
+	return bl, nil
 }
 
 func createDelimiterPreTokenizer(params *util.Params) (tokenizer.PreTokenizer, error) {
@@ -162,13 +168,17 @@ func createSplitPreTokenizer(params *util.Params) (tokenizer.PreTokenizer, error
 
 	patternMap := params.Get("pattern").(map[string]interface{})
 	behaviorVal := params.Get("behavior").(string)
-	invert := params.Get("invert").(bool)
+	invert := params.Get("invert", false).(bool)
 
 	var pattern normalizer.Pattern
 	if v, ok := patternMap["Regex"]; ok {
-		pattern = normalizer.NewRegexpPattern(v.(string))
+		re, err := normalizer.CompileRegexpPattern(v.(string))
+		if err != nil {
+			return nil, err
+		}
+		pattern = re
 	} else if v, ok := patternMap["String"]; ok {
-		pattern = normalizer.NewRegexpPattern(v.(string))
+		pattern = normalizer.NewStringPattern(v.(string))
 	} else {
 		err := fmt.Errorf("Unsupported pattern: %#v\n", patternMap)
 		return nil, err
@@ -1,7 +1,11 @@
 package pretrained
 
 import (
+	"reflect"
 	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/normalizer"
 )
 
 func TestCreatePreTokenizer(t *testing.T) {
@@ -29,3 +33,60 @@ func TestNullPreTokenizer(t *testing.T) {
 		panic(err)
 	}
 }
+
+func TestCreateSplitPreTokenizer(t *testing.T) {
+	tests := []struct {
+		config map[string]interface{}
+		input  string
+		want   []string
+	}{
+		{
+			config: map[string]interface{}{
+				"type":     "Split",
+				"pattern":  map[string]interface{}{"Regex": `\p{N}{1,3}`},
+				"behavior": "Isolated",
+				"invert":   false,
+			},
+			input: "a12345b",
+			want:  []string{"a", "123", "45", "b"},
+		},
+		{
+			config: map[string]interface{}{
+				"type":     "Split",
+				"pattern":  map[string]interface{}{"String": "-"},
+				"behavior": "MergedWithPrevious",
+			},
+			input: "a12345-b",
+			want:  []string{"a12345-", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		pretok, err := CreatePreTokenizer(tt.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := pretok.PreTokenize(tokenizer.NewPreTokenizedString(tt.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, s := range out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte) {
+			got = append(got, s.Value)
+		}
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("want %q, got %q\n", tt.want, got)
+		}
+	}
+
+	config := map[string]interface{}{
+		"type":     "Split",
+		"pattern":  map[string]interface{}{"Regex": `\p{N}`},
+		"behavior": "Unknown",
+	}
+	if _, err := CreatePreTokenizer(config); err == nil {
+		t.Errorf("want error for unknown behavior")
+	}
+}
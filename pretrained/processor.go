@@ -9,6 +9,7 @@ package pretrained
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/sugarme/tokenizer"
 	"github.com/sugarme/tokenizer/pretokenizer"
@@ -16,6 +17,24 @@ import (
 	"github.com/sugarme/tokenizer/util"
 )
 
+var (
+	postProcessorsMu sync.RWMutex
+	postProcessors   = make(map[string]func(config map[string]interface{}) (tokenizer.PostProcessor, error))
+)
+
+// RegisterPostProcessor registers a function creating the custom
+// post-processors of given type from their `tokenizer.json` config, ie. the
+// "post_processor" object. Registered types take precedence over the
+// built-in ones.
+func RegisterPostProcessor(typ string, create func(config map[string]interface{}) (tokenizer.PostProcessor, error)) {
+	postProcessorsMu.Lock()
+	defer postProcessorsMu.Unlock()
+
+	postProcessors[typ] = create
+}
+
+// CreatePostProcessor creates a PostProcessor from its `tokenizer.json`
+// config, nil if config is nil.
 func CreatePostProcessor(config map[string]interface{}) (tokenizer.PostProcessor, error) {
 	if config == nil {
 		return nil, nil
@@ -25,6 +44,13 @@ func CreatePostProcessor(config map[string]interface{}) (tokenizer.PostProcessor
 
 	typ := params.Get("type").(string)
 
+	postProcessorsMu.RLock()
+	create, ok := postProcessors[typ]
+	postProcessorsMu.RUnlock()
+	if ok {
+		return create(config)
+	}
+
 	switch typ {
 	case "RobertaProcessing": // Bart
 		return createRobertaProcessing(params), nil
@@ -1,7 +1,10 @@
 package pretrained
 
 import (
+	"encoding/json"
 	"log"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/sugarme/tokenizer"
@@ -92,3 +95,69 @@ func TestCreatePostProcessor(t *testing.T) {
 	}
 
 }
+
+// suffixProcessing is a custom post-processor appending a token to each
+// sequence.
+type suffixProcessing struct {
+	Token string
+	Id    int
+}
+
+func (p *suffixProcessing) AddedTokens(isPair bool) int {
+	if isPair {
+		return 2
+	}
+	return 1
+}
+
+func (p *suffixProcessing) Process(encoding, pairEncoding *tokenizer.Encoding, addSpecialTokens bool) *tokenizer.Encoding {
+	if addSpecialTokens {
+		suffix := tokenizer.NewEncoding([]int{p.Id}, []int{0}, []string{p.Token}, [][]int{{0, 0}}, []int{1}, []int{1}, nil, tokenizer.WithWordsEncodingOpt([]int{-1}))
+		encoding = encoding.MergeWith(suffix, false)
+		if pairEncoding != nil {
+			pairEncoding = pairEncoding.MergeWith(suffix, false)
+		}
+	}
+
+	return tokenizer.DefaultProcess(encoding, pairEncoding, addSpecialTokens)
+}
+
+func (p *suffixProcessing) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{"type": "Suffix", "token": p.Token, "id": p.Id})
+}
+
+func TestRegisterPostProcessor(t *testing.T) {
+	RegisterPostProcessor("Suffix", func(config map[string]interface{}) (tokenizer.PostProcessor, error) {
+		return &suffixProcessing{Token: config["token"].(string), Id: int(config["id"].(float64))}, nil
+	})
+
+	tk, err := FromReader(strings.NewReader(wordLevelTokenizerJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk.WithPostProcessor(&suffixProcessing{Token: "[SEP]", Id: 3})
+
+	serialized, err := tk.Serialize(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk2, err := FromReader(strings.NewReader(serialized))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tk2.GetPostProcessor().(*suffixProcessing); !ok {
+		t.Fatalf("want a *suffixProcessing, got %T", tk2.GetPostProcessor())
+	}
+
+	// Truncation leaves room for the added token (max length is 6).
+	en, err := tk2.EncodeSingle("hello world hello world hello world", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"hello", "world", "hello", "world", "hello", "[SEP]"}; !reflect.DeepEqual(want, en.Tokens) {
+		t.Errorf("want %q, got %q", want, en.Tokens)
+	}
+	if want := []int{0, 0, 0, 0, 0, 1}; !reflect.DeepEqual(want, en.SpecialTokenMask) {
+		t.Errorf("want %v, got %v", want, en.SpecialTokenMask)
+	}
+}
@@ -0,0 +1,236 @@
+package pretrained
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/model"
+	"github.com/sugarme/tokenizer/model/bpe"
+	"github.com/sugarme/tokenizer/normalizer"
+	"github.com/sugarme/tokenizer/pretokenizer"
+)
+
+// TiktokenEncoding is an OpenAI tiktoken encoding, less its ranks which come
+// from a `.tiktoken` file.
+type TiktokenEncoding struct {
+	Name string
+	// Pattern splits the input before BPE.
+	Pattern string
+	// SpecialTokens maps special tokens to their ids.
+	SpecialTokens map[string]int
+}
+
+// Cl100kBase is the encoding of GPT-4 and GPT-3.5 models. Its ranks are at
+// "https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken".
+var Cl100kBase = &TiktokenEncoding{
+	Name:    "cl100k_base",
+	Pattern: `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`,
+	SpecialTokens: map[string]int{
+		"<|endoftext|>":   100257,
+		"<|fim_prefix|>":  100258,
+		"<|fim_middle|>":  100259,
+		"<|fim_suffix|>":  100260,
+		"<|endofprompt|>": 100276,
+	},
+}
+
+// O200kBase is the encoding of GPT-4o models. Its ranks are at
+// "https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken".
+var O200kBase = &TiktokenEncoding{
+	Name: "o200k_base",
+	Pattern: strings.Join([]string{
+		`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?`,
+		`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?`,
+		`\p{N}{1,3}`,
+		` ?[^\s\p{L}\p{N}]+[\r\n/]*`,
+		`\s*[\r\n]+`,
+		`\s+(?!\S)`,
+		`\s+`,
+	}, "|"),
+	SpecialTokens: map[string]int{
+		"<|endoftext|>":   199999,
+		"<|endofprompt|>": 200018,
+	},
+}
+
+// TiktokenEncodings are the known encodings, by name.
+var TiktokenEncodings = map[string]*TiktokenEncoding{
+	Cl100kBase.Name: Cl100kBase,
+	O200kBase.Name:  O200kBase,
+}
+
+// ReadTiktokenRanks reads the ranks of a tiktoken `.tiktoken` file: a token
+// per line, base64 encoded, followed by its rank. Tokens are byte strings.
+func ReadTiktokenRanks(r io.Reader) (map[string]int, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		fields := bytes.Fields(scanner.Bytes())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Reading tiktoken ranks failed: invalid line %v", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(string(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("Reading tiktoken ranks failed: line %v: %w", line, err)
+		}
+		rank, err := strconv.Atoi(string(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("Reading tiktoken ranks failed: line %v: %w", line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return ranks, nil
+}
+
+// FromTiktoken constructs a new Tokenizer from a tiktoken `.tiktoken` file,
+// which gives the same tokens as tiktoken for the encoding. Special tokens
+// are split out of the input (ie. all are allowed).
+//
+// Example:
+//
+//	tk, err := pretrained.FromTiktoken("cl100k_base.tiktoken", pretrained.Cl100kBase)
+func FromTiktoken(file string, encoding *TiktokenEncoding) (*tokenizer.Tokenizer, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return FromTiktokenReader(f, encoding)
+}
+
+// FromTiktokenReader constructs a new Tokenizer from `.tiktoken` data. See
+// FromTiktoken.
+func FromTiktokenReader(r io.Reader, encoding *TiktokenEncoding) (*tokenizer.Tokenizer, error) {
+	ranks, err := ReadTiktokenRanks(r)
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := newTiktokenBPE(ranks, encoding.SpecialTokens)
+	if err != nil {
+		err = fmt.Errorf("Creating Model failed: %w", err)
+		return nil, err
+	}
+	tk := tokenizer.NewTokenizer(model)
+
+	split, err := pretokenizer.NewSplitFromRegex(encoding.Pattern, normalizer.IsolatedBehavior, false)
+	if err != nil {
+		err = fmt.Errorf("Creating PreTokenizer failed: %w", err)
+		return nil, err
+	}
+	byteLevel := pretokenizer.NewByteLevel()
+	byteLevel.SetAddPrefixSpace(false)
+	byteLevel.SetTrimOffsets(false)
+	byteLevel.SetUseRegex(false)
+	tk.WithPreTokenizer(pretokenizer.NewSequence([]tokenizer.PreTokenizer{split, byteLevel}))
+	tk.WithDecoder(byteLevel)
+
+	var specialTokens []tokenizer.AddedToken
+	for _, tok := range sortedSpecialTokens(encoding.SpecialTokens) {
+		specialTokens = append(specialTokens, tokenizer.NewAddedToken(tok, true))
+	}
+	tk.AddSpecialTokens(specialTokens)
+
+	return tk, nil
+}
+
+// sortedSpecialTokens returns the special tokens sorted by id.
+func sortedSpecialTokens(specialTokens map[string]int) []string {
+	var toks []string
+	for tok := range specialTokens {
+		toks = append(toks, tok)
+	}
+	sort.Slice(toks, func(i, j int) bool {
+		return specialTokens[toks[i]] < specialTokens[toks[j]]
+	})
+
+	return toks
+}
+
+// newTiktokenBPE creates a byte-level BPE model from tiktoken ranks, as
+// HuggingFace conversion does.
+//
+// tiktoken merges the adjacent parts whose concatenation has the lowest
+// rank. So the merge of a token is the last one of running this on its
+// bytes, stopping before its own rank, and merges are ranked as the tokens
+// they give. Special tokens are part of the vocab to keep their ids, as ids
+// may be missing (e.g. 100256 in cl100k_base).
+func newTiktokenBPE(ranks map[string]int, specialTokens map[string]int) (*bpe.BPE, error) {
+	var vocab model.Vocab = make(map[string]int, len(ranks)+len(specialTokens))
+	for token, rank := range ranks {
+		vocab[byteLevelString(token)] = rank
+	}
+	for tok, id := range specialTokens {
+		vocab[tok] = id
+	}
+
+	var merges bpe.Merges = make(map[bpe.Pair]bpe.PairVal)
+	for token, rank := range ranks {
+		if len(token) < 2 {
+			continue
+		}
+		parts := tiktokenMerge(ranks, token, rank)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("tiktoken token %q of rank %v is not a merge of two tokens", token, rank)
+		}
+		pair := bpe.Pair{C1: ranks[parts[0]], C2: ranks[parts[1]]}
+		merges[pair] = bpe.PairVal{Rank: rank, NewId: rank}
+	}
+
+	b := bpe.NewBpeBuilder()
+	b.VocabAndMerges(vocab, merges)
+
+	return b.Build()
+}
+
+// tiktokenMerge runs tiktoken BPE on the bytes of token, with merges of rank
+// lower than maxRank only, and returns the parts.
+func tiktokenMerge(ranks map[string]int, token string, maxRank int) []string {
+	parts := make([]string, len(token))
+	for i := 0; i < len(token); i++ {
+		parts[i] = token[i : i+1]
+	}
+
+	for len(parts) > 1 {
+		minIdx, minRank := -1, maxRank
+		for i := 0; i+1 < len(parts); i++ {
+			if rank, ok := ranks[parts[i]+parts[i+1]]; ok && rank < minRank {
+				minIdx, minRank = i, rank
+			}
+		}
+		if minIdx < 0 {
+			break
+		}
+		parts[minIdx] += parts[minIdx+1]
+		parts = append(parts[:minIdx+1], parts[minIdx+2:]...)
+	}
+
+	return parts
+}
+
+// byteLevelString maps the bytes of s to byte-level chars.
+func byteLevelString(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		sb.WriteString(pretokenizer.BytesChar[s[i]])
+	}
+
+	return sb.String()
+}
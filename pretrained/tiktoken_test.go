@@ -0,0 +1,118 @@
+package pretrained
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/normalizer"
+	"github.com/sugarme/tokenizer/pretokenizer"
+)
+
+// tiktokenData returns `.tiktoken` data of the 256 bytes and a few merges.
+func tiktokenData() string {
+	var sb strings.Builder
+	rank := 0
+	add := func(token string) {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+		rank++
+	}
+	for b := 0; b < 256; b++ {
+		add(string([]byte{byte(b)}))
+	}
+	for _, token := range []string{"th", "the", " t", " the", "12", "é", "fé"} {
+		add(token)
+	}
+
+	return sb.String()
+}
+
+func TestFromTiktokenReader(t *testing.T) {
+	tk, err := FromTiktokenReader(strings.NewReader(tiktokenData()), Cl100kBase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := "the the 12345<|endoftext|>"
+	en, err := tk.EncodeSingle(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "the", " the", " ", "123" (3 digits at most), "45", "<|endoftext|>"
+	wantIds := []int{257, 259, 32, 260, 51, 52, 53, 100257}
+	if !reflect.DeepEqual(wantIds, en.Ids) {
+		t.Errorf("want %v\ngot  %v\n", wantIds, en.Ids)
+	}
+	wantTokens := []string{"the", "Ġthe", "Ġ", "12", "3", "4", "5", "<|endoftext|>"}
+	if !reflect.DeepEqual(wantTokens, en.Tokens) {
+		t.Errorf("want %q\ngot  %q\n", wantTokens, en.Tokens)
+	}
+
+	if got := tk.Decode(en.Ids, false); got != input {
+		t.Errorf("want %q\ngot  %q\n", input, got)
+	}
+
+	// Multi-byte tokens are merges of their bytes, unknown chars are split
+	// into byte tokens.
+	en, err = tk.EncodeSingle("café à")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{'c', 'a', 262, ' ', 0xc3, 0xa0}; !reflect.DeepEqual(want, en.Ids) {
+		t.Errorf("want %v\ngot  %v\n", want, en.Ids)
+	}
+	if got := tk.Decode(en.Ids, false); got != "café à" {
+		t.Errorf("want %q\ngot  %q\n", "café à", got)
+	}
+}
+
+func TestReadTiktokenRanks(t *testing.T) {
+	ranks, err := ReadTiktokenRanks(strings.NewReader("IQ== 0\nIiM= 1\n\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"!": 0, `"#`: 1}; !reflect.DeepEqual(want, ranks) {
+		t.Errorf("want %v\ngot  %v\n", want, ranks)
+	}
+
+	for _, data := range []string{"IQ==\n", "IQ== x\n", "!!! 0\n"} {
+		if _, err := ReadTiktokenRanks(strings.NewReader(data)); err == nil {
+			t.Errorf("want error on %q", data)
+		}
+	}
+
+	// Not a merge of two tokens
+	if _, err := FromTiktokenReader(strings.NewReader("IQ== 0\nISEh 1\n"), O200kBase); err == nil {
+		t.Errorf("want error on token not being a merge")
+	}
+}
+
+func TestTiktokenEncodings_Pattern(t *testing.T) {
+	input := "HelloWorld's  2024!\n\n x"
+	want := map[string][]string{
+		"cl100k_base": {"HelloWorld", "'s", " ", " ", "202", "4", "!\n\n", " x"},
+		"o200k_base":  {"Hello", "World's", " ", " ", "202", "4", "!\n\n", " x"},
+	}
+
+	for name, encoding := range TiktokenEncodings {
+		split, err := pretokenizer.NewSplitFromRegex(encoding.Pattern, normalizer.IsolatedBehavior, false)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		pretokenized, err := split.PreTokenize(tokenizer.NewPreTokenizedString(input))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var got []string
+		for _, pretok := range pretokenized.GetSplits(normalizer.OriginalTarget, tokenizer.Byte) {
+			got = append(got, pretok.Value)
+		}
+		if !reflect.DeepEqual(want[name], got) {
+			t.Errorf("%s: want %q\ngot  %q\n", name, want[name], got)
+		}
+	}
+}
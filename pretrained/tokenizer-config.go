@@ -0,0 +1,211 @@
+package pretrained
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/sugarme/tokenizer"
+)
+
+// TokenizerConfig is the data of transformers `tokenizer_config.json` and
+// `special_tokens_map.json` files a Tokenizer uses.
+type TokenizerConfig struct {
+	// Named special tokens, with their added token options.
+	SpecialTokens map[string]tokenizer.AddedToken
+	// Additional special tokens (`additional_special_tokens`)
+	AdditionalSpecialTokens []tokenizer.AddedToken
+	// Maximum number of tokens of the model inputs, zero if unknown.
+	ModelMaxLength int
+	// Side padding tokens are added to: "left", "right" or empty if unset.
+	PaddingSide string
+	// Whether decoding cleans up tokenization spaces, nil if unset.
+	CleanUpTokenizationSpaces *bool
+}
+
+// specialTokenKeys are the fields of named special tokens.
+var specialTokenKeys = []string{
+	"bos_token", "eos_token", "unk_token", "pad_token", "cls_token", "sep_token", "mask_token",
+}
+
+// LoadTokenizerConfig reads `tokenizer_config.json` and
+// `special_tokens_map.json` from dir, the latter taking precedence, as
+// transformers does. Missing files are skipped.
+func LoadTokenizerConfig(dir string) (*TokenizerConfig, error) {
+	config := &TokenizerConfig{SpecialTokens: make(map[string]tokenizer.AddedToken)}
+	for _, name := range []string{"tokenizer_config.json", "special_tokens_map.json"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			err = fmt.Errorf("Reading %q failed: %w", name, err)
+			return nil, err
+		}
+		if err := config.update(fields); err != nil {
+			err = fmt.Errorf("Reading %q failed: %w", name, err)
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+func (c *TokenizerConfig) update(fields map[string]json.RawMessage) error {
+	for _, key := range specialTokenKeys {
+		raw, ok := fields[key]
+		if !ok || string(raw) == "null" {
+			continue
+		}
+		tok, err := parseSpecialToken(raw)
+		if err != nil {
+			return fmt.Errorf("invalid %q: %w", key, err)
+		}
+		c.SpecialTokens[key] = tok
+	}
+
+	if raw, ok := fields["additional_special_tokens"]; ok && string(raw) != "null" {
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return fmt.Errorf("invalid 'additional_special_tokens': %w", err)
+		}
+		c.AdditionalSpecialTokens = nil
+		for _, item := range items {
+			tok, err := parseSpecialToken(item)
+			if err != nil {
+				return fmt.Errorf("invalid 'additional_special_tokens': %w", err)
+			}
+			c.AdditionalSpecialTokens = append(c.AdditionalSpecialTokens, tok)
+		}
+	}
+
+	// transformers writes a very large number (int(1e30)) if unknown.
+	if raw, ok := fields["model_max_length"]; ok {
+		var n float64
+		if err := json.Unmarshal(raw, &n); err == nil {
+			c.ModelMaxLength = 0
+			if n > 0 && n < math.MaxInt32 {
+				c.ModelMaxLength = int(n)
+			}
+		}
+	}
+
+	if raw, ok := fields["padding_side"]; ok {
+		var side string
+		if err := json.Unmarshal(raw, &side); err != nil || (side != "left" && side != "right") {
+			return fmt.Errorf("invalid 'padding_side': %s", raw)
+		}
+		c.PaddingSide = side
+	}
+
+	if raw, ok := fields["clean_up_tokenization_spaces"]; ok {
+		var cleanUp bool
+		if err := json.Unmarshal(raw, &cleanUp); err == nil {
+			c.CleanUpTokenizationSpaces = &cleanUp
+		}
+	}
+
+	return nil
+}
+
+// parseSpecialToken parses a special token, either a string or an added
+// token object.
+func parseSpecialToken(raw json.RawMessage) (tokenizer.AddedToken, error) {
+	var content string
+	if err := json.Unmarshal(raw, &content); err == nil {
+		return tokenizer.NewAddedToken(content, true), nil
+	}
+
+	var tc tokenizer.TokenConfig
+	if err := json.Unmarshal(raw, &tc); err != nil {
+		return tokenizer.AddedToken{}, err
+	}
+	tok := tokenizer.NewAddedToken(tc.Content, true)
+	tok.SingleWord = tc.SingleWord
+	tok.LStrip = tc.Lstrip
+	tok.RStrip = tc.Rstrip
+	tok.Normalized = tc.Normalized
+
+	return tok, nil
+}
+
+// ApplyTokenizerConfig sets the special tokens, model max length, padding
+// side and decoding clean-up of config to tk. Special tokens not yet known
+// as such are added to tk. The padding side applies to the padding params of
+// tk, if any, which also pad with the `pad_token`.
+func ApplyTokenizerConfig(tk *tokenizer.Tokenizer, config *TokenizerConfig) {
+	if config == nil {
+		return
+	}
+
+	m := tk.GetSpecialTokensMap()
+	fields := map[string]*string{
+		"bos_token":  &m.BosToken,
+		"eos_token":  &m.EosToken,
+		"unk_token":  &m.UnkToken,
+		"pad_token":  &m.PadToken,
+		"cls_token":  &m.ClsToken,
+		"sep_token":  &m.SepToken,
+		"mask_token": &m.MaskToken,
+	}
+	var toAdd []tokenizer.AddedToken
+	known := make(map[string]bool)
+	for _, tok := range tk.GetSpecialTokens() {
+		known[tok] = true
+	}
+	for _, key := range specialTokenKeys {
+		tok, ok := config.SpecialTokens[key]
+		if !ok {
+			continue
+		}
+		*fields[key] = tok.Content
+		if tok.Content != "" && !known[tok.Content] {
+			toAdd = append(toAdd, tok)
+			known[tok.Content] = true
+		}
+	}
+	if config.AdditionalSpecialTokens != nil {
+		m.AdditionalSpecialTokens = nil
+		for _, tok := range config.AdditionalSpecialTokens {
+			m.AdditionalSpecialTokens = append(m.AdditionalSpecialTokens, tok.Content)
+			if tok.Content != "" && !known[tok.Content] {
+				toAdd = append(toAdd, tok)
+				known[tok.Content] = true
+			}
+		}
+	}
+	tk.WithSpecialTokensMap(m)
+	if len(toAdd) > 0 {
+		tk.AddSpecialTokens(toAdd)
+	}
+
+	if config.ModelMaxLength > 0 {
+		tk.WithModelMaxLength(config.ModelMaxLength)
+	}
+
+	if config.CleanUpTokenizationSpaces != nil {
+		tk.WithCleanUpTokenizationSpaces(*config.CleanUpTokenizationSpaces)
+	}
+
+	if padding := tk.GetPadding(); padding != nil {
+		switch config.PaddingSide {
+		case "left":
+			padding.Direction = tokenizer.Left
+		case "right":
+			padding.Direction = tokenizer.Right
+		}
+		if m.PadToken != "" {
+			if id, ok := tk.TokenToId(m.PadToken); ok {
+				padding.PadToken = m.PadToken
+				padding.PadId = id
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package pretrained
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+)
+
+func TestFromFile_TokenizerConfig(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"tokenizer.json": wordLevelTokenizerJSON,
+		"tokenizer_config.json": `{
+			"model_max_length": 1000000000000000019884624838656,
+			"padding_side": "left",
+			"clean_up_tokenization_spaces": true,
+			"unk_token": "[UNK]",
+			"pad_token": "[CLS]",
+			"cls_token": "[CLS]"
+		}`,
+		"special_tokens_map.json": `{
+			"pad_token": {"content": "[PAD]", "lstrip": false, "normalized": false, "rstrip": false, "single_word": false},
+			"sep_token": "[SEP]",
+			"mask_token": {"content": "[MASK]", "lstrip": false, "normalized": false, "rstrip": false, "single_word": false},
+			"additional_special_tokens": ["<extra>"]
+		}`,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tk, err := FromFile(filepath.Join(dir, "tokenizer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := tokenizer.SpecialTokensMap{
+		UnkToken:                "[UNK]",
+		PadToken:                "[PAD]", // special_tokens_map.json takes precedence
+		ClsToken:                "[CLS]",
+		SepToken:                "[SEP]",
+		MaskToken:               "[MASK]",
+		AdditionalSpecialTokens: []string{"<extra>"},
+	}
+	if got := tk.GetSpecialTokensMap(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v\ngot  %+v\n", want, got)
+	}
+
+	// Unknown length
+	if got := tk.GetModelMaxLength(); got != 0 {
+		t.Errorf("want model max length 0, got %v", got)
+	}
+	if !tk.GetCleanUpTokenizationSpaces() {
+		t.Errorf("want clean up of tokenization spaces")
+	}
+	if got := tk.GetPadding().Direction; got != tokenizer.Left {
+		t.Errorf("want left padding, got %v", got)
+	}
+
+	// New special tokens are added.
+	en, err := tk.EncodeSingle("hello [MASK] <extra>", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantTokens := []string{"[PAD]", "[PAD]", "[PAD]", "hello", "[MASK]", "<extra>"}
+	if !reflect.DeepEqual(wantTokens, en.Tokens) {
+		t.Errorf("want %q\ngot  %q\n", wantTokens, en.Tokens)
+	}
+	wantIds := []int{0, 0, 0, 4, 6, 7}
+	if !reflect.DeepEqual(wantIds, en.Ids) {
+		t.Errorf("want %v\ngot  %v\n", wantIds, en.Ids)
+	}
+}
+
+func TestLoadTokenizerConfig(t *testing.T) {
+	dir := t.TempDir()
+	data := `{"model_max_length": 512, "bos_token": null, "eos_token": {"content": "</s>", "__type": "AddedToken"}}`
+	if err := os.WriteFile(filepath.Join(dir, "tokenizer_config.json"), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadTokenizerConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.ModelMaxLength != 512 {
+		t.Errorf("want model max length 512, got %v", config.ModelMaxLength)
+	}
+	if _, ok := config.SpecialTokens["bos_token"]; ok {
+		t.Errorf("want no bos token")
+	}
+	if got := config.SpecialTokens["eos_token"].Content; got != "</s>" {
+		t.Errorf("want eos token %q, got %q", "</s>", got)
+	}
+	if config.PaddingSide != "" || config.CleanUpTokenizationSpaces != nil {
+		t.Errorf("want unset padding side and clean up, got %+v", config)
+	}
+
+	// No config files
+	config, err = LoadTokenizerConfig(t.TempDir())
+	if err != nil || len(config.SpecialTokens) != 0 {
+		t.Errorf("want empty config, got %+v, %v", config, err)
+	}
+}
@@ -0,0 +1,176 @@
+package pretrained
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const wordLevelTokenizerJSON = `{
+  "version": "1.0",
+  "truncation": {"direction": "Right", "max_length": 6, "strategy": "LongestFirst", "stride": 0},
+  "padding": {"strategy": {"Fixed": 6}, "direction": "Right", "pad_to_multiple_of": null, "pad_id": 0, "pad_type_id": 0, "pad_token": "[PAD]"},
+  "added_tokens": [
+    {"id": 0, "content": "[PAD]", "single_word": false, "lstrip": false, "rstrip": false, "normalized": false, "special": true},
+    {"id": 2, "content": "[CLS]", "single_word": false, "lstrip": false, "rstrip": false, "normalized": false, "special": true},
+    {"id": 3, "content": "[SEP]", "single_word": false, "lstrip": false, "rstrip": false, "normalized": false, "special": true}
+  ],
+  "normalizer": {"type": "Sequence", "normalizers": [{"type": "Nmt"}, {"type": "Lowercase"}, {"type": "Strip", "strip_left": true, "strip_right": true}]},
+  "pre_tokenizer": {"type": "Whitespace"},
+  "post_processor": {"type": "BertProcessing", "sep": ["[SEP]", 3], "cls": ["[CLS]", 2]},
+  "decoder": null,
+  "model": {"type": "WordLevel", "unk_token": "[UNK]", "vocab": {"[PAD]": 0, "[UNK]": 1, "[CLS]": 2, "[SEP]": 3, "hello": 4, "world": 5}}
+}`
+
+func TestFromReader(t *testing.T) {
+	tk, err := FromReader(strings.NewReader(wordLevelTokenizerJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	en, err := tk.EncodeSingle("  Hello\tWORLD foo ", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantTokens := []string{"[CLS]", "hello", "world", "[UNK]", "[SEP]", "[PAD]"}
+	if !reflect.DeepEqual(wantTokens, en.Tokens) {
+		t.Errorf("want %#v\ngot %#v\n", wantTokens, en.Tokens)
+	}
+	wantIds := []int{2, 4, 5, 1, 3, 0}
+	if !reflect.DeepEqual(wantIds, en.Ids) {
+		t.Errorf("want %#v\ngot %#v\n", wantIds, en.Ids)
+	}
+}
+
+func TestFromReader_MissingModel(t *testing.T) {
+	_, err := FromReader(strings.NewReader(`{"version": "1.0", "model": null}`))
+	if err == nil {
+		t.Errorf("want error on missing model section\n")
+	}
+}
+
+const bpeTokenizerJSON = `{
+  "version": "1.0",
+  "truncation": null,
+  "padding": null,
+  "added_tokens": [
+    {"id": 0, "content": "<s>", "single_word": false, "lstrip": false, "rstrip": false, "normalized": false, "special": true}
+  ],
+  "normalizer": {"type": "Replace", "pattern": {"Regex": "\\s+"}, "content": " "},
+  "pre_tokenizer": {"type": "Sequence", "pretokenizers": [
+    {"type": "Split", "pattern": {"String": "-"}, "behavior": "Isolated", "invert": false},
+    {"type": "ByteLevel", "add_prefix_space": false, "trim_offsets": true, "use_regex": true}
+  ]},
+  "post_processor": {
+    "type": "TemplateProcessing",
+    "single": [{"SpecialToken": {"id": "<s>", "type_id": 0}}, {"Sequence": {"id": "A", "type_id": 0}}],
+    "pair": [{"SpecialToken": {"id": "<s>", "type_id": 0}}, {"Sequence": {"id": "A", "type_id": 0}}, {"Sequence": {"id": "B", "type_id": 1}}],
+    "special_tokens": {"<s>": {"id": "<s>", "ids": [0], "tokens": ["<s>"]}}
+  },
+  "decoder": {"type": "ByteLevel", "add_prefix_space": false, "trim_offsets": true, "use_regex": true},
+  "model": {"type": "BPE", "dropout": null, "unk_token": null, "continuing_subword_prefix": null, "end_of_word_suffix": null,
+    "fuse_unk": false, "byte_fallback": false,
+    "vocab": {"<s>": 0, "a": 1, "b": 2, "-": 3, "Ġ": 4, "ab": 5, "Ġa": 6},
+    "merges": ["a b", ["Ġ", "a"]]}
+}`
+
+func TestSerialize_RoundTrip(t *testing.T) {
+	for _, data := range []string{wordLevelTokenizerJSON, bpeTokenizerJSON} {
+		tk, err := FromReader(strings.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		serialized, err := tk.Serialize(true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tk2, err := FromReader(strings.NewReader(serialized))
+		if err != nil {
+			t.Fatalf("reloading failed: %v\n%s", err, serialized)
+		}
+
+		input := "ab  a-b	ab"
+		want, err := tk.EncodeSingle(input, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := tk2.EncodeSingle(input, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want.Ids, got.Ids) || !reflect.DeepEqual(want.Offsets, got.Offsets) {
+			t.Errorf("want %v %v\ngot %v %v\n", want.Ids, want.Offsets, got.Ids, got.Offsets)
+		}
+		if len(want.Ids) < 3 {
+			t.Errorf("unexpectedly short encoding: %v\n", want.Tokens)
+		}
+
+		reserialized, err := tk2.Serialize(true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if serialized != reserialized {
+			t.Errorf("serialization is not stable:\n%s\n%s\n", serialized, reserialized)
+		}
+	}
+}
+
+// Llama like tokenizer: characters missing from the vocab are encoded as
+// `<0xXX>` byte tokens.
+const byteFallbackTokenizerJSON = `{
+  "version": "1.0",
+  "truncation": null,
+  "padding": null,
+  "added_tokens": [
+    {"id": 0, "content": "<unk>", "single_word": false, "lstrip": false, "rstrip": false, "normalized": false, "special": true}
+  ],
+  "normalizer": {"type": "Sequence", "normalizers": [
+    {"type": "Prepend", "prepend": "▁"},
+    {"type": "Replace", "pattern": {"String": " "}, "content": "▁"}
+  ]},
+  "pre_tokenizer": null,
+  "post_processor": null,
+  "decoder": {"type": "Sequence", "decoders": [
+    {"type": "Replace", "pattern": {"String": "▁"}, "content": " "},
+    {"type": "ByteFallback"},
+    {"type": "Fuse"},
+    {"type": "Strip", "content": " ", "start": 1, "stop": 0}
+  ]},
+  "model": {"type": "BPE", "dropout": null, "unk_token": "<unk>", "continuing_subword_prefix": null, "end_of_word_suffix": null,
+    "fuse_unk": true, "byte_fallback": true,
+    "vocab": {"<unk>": 0, "<0xE5>": 1, "<0x8F>": 2, "<0xAB>": 3, "▁": 4, "h": 5, "i": 6, "▁h": 7, "▁hi": 8},
+    "merges": ["▁ h", "▁h i"]}
+}`
+
+func TestFromReader_ByteFallback(t *testing.T) {
+	tk, err := FromReader(strings.NewReader(byteFallbackTokenizerJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	en, err := tk.EncodeSingle("hi 叫xy", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantTokens := []string{"▁hi", "▁", "<0xE5>", "<0x8F>", "<0xAB>", "<unk>"}
+	if !reflect.DeepEqual(wantTokens, en.Tokens) {
+		t.Errorf("want %#v\ngot %#v\n", wantTokens, en.Tokens)
+	}
+
+	got := tk.Decode([]int{8, 4, 1, 2, 3}, false)
+	if want := "hi 叫"; want != got {
+		t.Errorf("want %q, got %q\n", want, got)
+	}
+
+	serialized, err := tk.Serialize(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(serialized, `"byte_fallback": true`) || !strings.Contains(serialized, `"fuse_unk": true`) {
+		t.Errorf("byte_fallback/fuse_unk not serialized:\n%s\n", serialized)
+	}
+}
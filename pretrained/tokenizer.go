@@ -2,27 +2,87 @@ package pretrained
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 
 	"github.com/sugarme/tokenizer"
 )
 
-// FromFile constructs a new Tokenizer from json data file (normally 'tokenizer.json')
+// FromFile constructs a new Tokenizer from json data file (normally 'tokenizer.json').
+//
+// The `tokenizer_config.json` and `special_tokens_map.json` files next to it,
+// if any, are applied too (see `ApplyTokenizerConfig`).
 func FromFile(file string) (*tokenizer.Tokenizer, error) {
 	f, err := os.Open(file)
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
 
-	dec := json.NewDecoder(f)
+	tk, err := FromReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := LoadTokenizerConfig(filepath.Dir(file))
+	if err != nil {
+		return nil, err
+	}
+	ApplyTokenizerConfig(tk, config)
+
+	return tk, nil
+}
+
+// FromPretrained constructs a new Tokenizer from a HuggingFace Hub repo, as
+// transformers `AutoTokenizer.from_pretrained` does. The `tokenizer.json`
+// file of the repo is downloaded and cached, with `tokenizer_config.json`
+// and `special_tokens_map.json` if the repo has them.
+//
+// Params:
+// - name: repo name, e.g. "bert-base-uncased", or path to a local directory
+// - opts: optional hub options, e.g. `tokenizer.WithRevision("v1.0")`,
+// `tokenizer.WithAuthToken(token)` or `tokenizer.WithOffline(true)`
+func FromPretrained(name string, opts ...tokenizer.HubOpt) (*tokenizer.Tokenizer, error) {
+	file, err := tokenizer.HubFile(name, tokenizer.TokenizerName, opts...)
+	if err != nil {
+		err = fmt.Errorf("FromPretrained(%q) failed: %w", name, err)
+		return nil, err
+	}
+
+	// Optional files, cached next to 'tokenizer.json' where `FromFile` reads them.
+	for _, fileName := range []string{"tokenizer_config.json", "special_tokens_map.json"} {
+		_, err := tokenizer.HubFile(name, fileName, opts...)
+		if err != nil && !errors.Is(err, tokenizer.ErrFileNotFound) && !errors.Is(err, tokenizer.ErrNotCached) {
+			err = fmt.Errorf("FromPretrained(%q) failed: %w", name, err)
+			return nil, err
+		}
+	}
+
+	return FromFile(file)
+}
+
+// FromReader constructs a new Tokenizer from HuggingFace `tokenizer.json` data.
+func FromReader(r io.Reader) (*tokenizer.Tokenizer, error) {
+	dec := json.NewDecoder(r)
 
 	var config *tokenizer.Config
-	err = dec.Decode(&config)
+	err := dec.Decode(&config)
 	if err != nil {
 		return nil, err
 	}
 
+	return FromConfig(config)
+}
+
+// FromConfig constructs a new Tokenizer from decoded `tokenizer.json` data.
+func FromConfig(config *tokenizer.Config) (*tokenizer.Tokenizer, error) {
+	if config == nil || config.Model == nil {
+		return nil, fmt.Errorf("Invalid tokenizer config: missing 'model' section")
+	}
+
 	model, err := CreateModel(config)
 	if err != nil {
 		err := fmt.Errorf("Creating Model failed: %v", err)
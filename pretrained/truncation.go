@@ -12,9 +12,9 @@ func CreateTruncationParams(config map[string]interface{}) (*tokenizer.Truncatio
 
 	params := util.NewParams(config)
 	// direction := params.Get("direction").(string)
-	maxLen := int(params.Get("max_length").(float64))
-	stride := int(params.Get("stride").(float64))
-	strategyName := params.Get("strategy").(string)
+	maxLen := int(params.Get("max_length", 512.0).(float64))
+	stride := int(params.Get("stride", 0.0).(float64))
+	strategyName := params.Get("strategy", "LongestFirst").(string)
 
 	var strategy tokenizer.TruncationStrategy
 	switch strategyName {
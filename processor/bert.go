@@ -112,7 +112,8 @@ func (bp *BertProcessing) addSpecialToken(encoding *tokenizer.Encoding) *tokeniz
 	}
 
 	wordsOpt := tokenizer.WithWordsEncodingOpt(words)
-	return tokenizer.NewEncoding(ids, typeIds, tokens, offsets, specialTokens, attentionMask, []tokenizer.Encoding{}, wordsOpt)
+	rangeOpt := tokenizer.WithSequenceRangeEncodingOpt(sequenceRange(0, 1, encoding.Len()))
+	return tokenizer.NewEncoding(ids, typeIds, tokens, offsets, specialTokens, attentionMask, []tokenizer.Encoding{}, wordsOpt, rangeOpt)
 }
 
 // pairAddSpecialToken adds special token "[SEP]" to input encoding. It ignores
@@ -151,6 +152,18 @@ func (bp *BertProcessing) pairAddSpecialToken(pairEncoding *tokenizer.Encoding)
 	pairAttentionMask = append(pairAttentionMask, 1)
 
 	pairWordsOpt := tokenizer.WithWordsEncodingOpt(pairWords)
+	pairRangeOpt := tokenizer.WithSequenceRangeEncodingOpt(sequenceRange(1, 0, pairEncoding.Len()))
 
-	return tokenizer.NewEncoding(pairIds, pairTypeIds, pairTokens, pairOffsets, pairSpecialTokens, pairAttentionMask, []tokenizer.Encoding{}, pairWordsOpt)
+	return tokenizer.NewEncoding(pairIds, pairTypeIds, pairTokens, pairOffsets, pairSpecialTokens, pairAttentionMask, []tokenizer.Encoding{}, pairWordsOpt, pairRangeOpt)
+}
+
+// sequenceRange returns the sequence ranges of an encoding where sequence
+// `seqId` has `n` tokens starting at index `start`.
+func sequenceRange(seqId, start, n int) map[int]tokenizer.Range {
+	ranges := make(map[int]tokenizer.Range)
+	if n > 0 {
+		ranges[seqId] = tokenizer.NewRange(start, start+n)
+	}
+
+	return ranges
 }
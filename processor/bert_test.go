@@ -0,0 +1,64 @@
+package processor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+)
+
+func newTestEncoding(ids []int, tokens []string, offsets [][]int, typeId int) *tokenizer.Encoding {
+	n := len(ids)
+	typeIds := make([]int, n)
+	words := make([]int, n)
+	specialTokens := make([]int, n)
+	attentionMask := make([]int, n)
+	for i := 0; i < n; i++ {
+		typeIds[i] = typeId
+		words[i] = i
+		attentionMask[i] = 1
+	}
+
+	return tokenizer.NewEncoding(ids, typeIds, tokens, offsets, specialTokens, attentionMask, []tokenizer.Encoding{}, tokenizer.WithWordsEncodingOpt(words))
+}
+
+func TestBertProcessing(t *testing.T) {
+	processor := NewBertProcessing(PostToken{Value: "[SEP]", Id: 102}, PostToken{Value: "[CLS]", Id: 101})
+
+	if got := processor.AddedTokens(false); got != 2 {
+		t.Errorf("want 2, got %v\n", got)
+	}
+	if got := processor.AddedTokens(true); got != 3 {
+		t.Errorf("want 3, got %v\n", got)
+	}
+
+	encoding := newTestEncoding([]int{12, 14}, []string{"Hello", "there"}, [][]int{{0, 5}, {6, 11}}, 0)
+	pair := newTestEncoding([]int{15}, []string{"pair"}, [][]int{{0, 4}}, 1)
+
+	got := processor.Process(encoding, pair, true)
+
+	if want := []int{101, 12, 14, 102, 15, 102}; !reflect.DeepEqual(want, got.Ids) {
+		t.Errorf("want %v, got %v\n", want, got.Ids)
+	}
+	if want := []string{"[CLS]", "Hello", "there", "[SEP]", "pair", "[SEP]"}; !reflect.DeepEqual(want, got.Tokens) {
+		t.Errorf("want %v, got %v\n", want, got.Tokens)
+	}
+	if want := []int{0, 0, 0, 0, 1, 1}; !reflect.DeepEqual(want, got.TypeIds) {
+		t.Errorf("want %v, got %v\n", want, got.TypeIds)
+	}
+	if want := [][]int{{0, 0}, {0, 5}, {6, 11}, {0, 0}, {0, 4}, {0, 0}}; !reflect.DeepEqual(want, got.Offsets) {
+		t.Errorf("want %v, got %v\n", want, got.Offsets)
+	}
+	if want := []int{1, 0, 0, 1, 0, 1}; !reflect.DeepEqual(want, got.SpecialTokenMask) {
+		t.Errorf("want %v, got %v\n", want, got.SpecialTokenMask)
+	}
+	if want := []int{-1, 0, 0, -1, 1, -1}; !reflect.DeepEqual(want, got.GetSequenceIds()) {
+		t.Errorf("want %v, got %v\n", want, got.GetSequenceIds())
+	}
+
+	// Without special tokens, encodings are simply merged
+	got = processor.Process(encoding, nil, false)
+	if want := []string{"Hello", "there"}; !reflect.DeepEqual(want, got.Tokens) {
+		t.Errorf("want %v, got %v\n", want, got.Tokens)
+	}
+}
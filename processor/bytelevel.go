@@ -19,7 +19,7 @@ func NewByteLevelProcessing(pretok *pretokenizer.ByteLevel) (retVal *ByteLevelPr
 // =====================================================
 
 func (blp *ByteLevelProcessing) AddedTokens(isPair bool) (retVal int) {
-	return blp.pretok.AddedToken(isPair)
+	return blp.pretok.AddedTokens(isPair)
 }
 
 func (blp *ByteLevelProcessing) Process(encoding, pairEncoding *tokenizer.Encoding, addSpecialTokens bool) (retVal *tokenizer.Encoding) {
@@ -62,9 +62,9 @@ func (rp *RobertaProcessing) AddedTokens(isPair bool) int {
 func (rp *RobertaProcessing) Process(encoding, pairEncoding *tokenizer.Encoding, addSpecialTokens bool) *tokenizer.Encoding {
 
 	var (
-		newEncoding             *tokenizer.Encoding
+		newEncoding             *tokenizer.Encoding = encoding
 		newOverflowEncodings    []tokenizer.Encoding
-		newPairEncoding         *tokenizer.Encoding
+		newPairEncoding         *tokenizer.Encoding = pairEncoding
 		newOverflowPairEncoding []tokenizer.Encoding
 	)
 	if rp.trimOffsets {
@@ -168,7 +168,8 @@ func (rp *RobertaProcessing) addSpecialToken(encoding *tokenizer.Encoding) *toke
 	attentionMask = append(attentionMask, 1)
 
 	wordsOpt := tokenizer.WithWordsEncodingOpt(words)
-	return tokenizer.NewEncoding(ids, typeIds, tokens, offsets, specialTokens, attentionMask, []tokenizer.Encoding{}, wordsOpt)
+	rangeOpt := tokenizer.WithSequenceRangeEncodingOpt(sequenceRange(0, 1, encoding.Len()))
+	return tokenizer.NewEncoding(ids, typeIds, tokens, offsets, specialTokens, attentionMask, []tokenizer.Encoding{}, wordsOpt, rangeOpt)
 }
 
 // addSpecialToken adds special tokens to input pair encoding. It ignores the `Overflowing` field
@@ -216,7 +217,6 @@ func (rp *RobertaProcessing) pairAddSpecialToken(pair *tokenizer.Encoding) *toke
 	pairAttentionMask = append(pairAttentionMask, 1)
 
 	pairWordsOpt := tokenizer.WithWordsEncodingOpt(pairWords)
-	return tokenizer.NewEncoding(pairIds, pairTypeIds, pairTokens, pairOffsets, pairSpecialTokens, pairAttentionMask, []tokenizer.Encoding{}, pairWordsOpt)
+	pairRangeOpt := tokenizer.WithSequenceRangeEncodingOpt(sequenceRange(1, 1, pair.Len()))
+	return tokenizer.NewEncoding(pairIds, pairTypeIds, pairTokens, pairOffsets, pairSpecialTokens, pairAttentionMask, []tokenizer.Encoding{}, pairWordsOpt, pairRangeOpt)
 }
-
-// TODO: implement Serialize interface for RobertaProcessing
@@ -0,0 +1,75 @@
+package processor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRobertaProcessing(t *testing.T) {
+	processor := DefaultRobertaProcessing()
+
+	if got := processor.AddedTokens(false); got != 2 {
+		t.Errorf("want 2, got %v\n", got)
+	}
+	if got := processor.AddedTokens(true); got != 4 {
+		t.Errorf("want 4, got %v\n", got)
+	}
+
+	// Tokens as produced by the ByteLevel pre-tokenizer for "Hello there" and "pair"
+	encoding := newTestEncoding([]int{31414, 89}, []string{"ĠHello", "Ġthere"}, [][]int{{0, 5}, {5, 11}}, 0)
+	pair := newTestEncoding([]int{15}, []string{"Ġpair"}, [][]int{{0, 4}}, 1)
+
+	got := processor.Process(encoding, pair, true)
+
+	if want := []string{"<s>", "ĠHello", "Ġthere", "</s>", "</s>", "Ġpair", "</s>"}; !reflect.DeepEqual(want, got.Tokens) {
+		t.Errorf("want %v, got %v\n", want, got.Tokens)
+	}
+	if want := []int{0, 31414, 89, 2, 2, 15, 2}; !reflect.DeepEqual(want, got.Ids) {
+		t.Errorf("want %v, got %v\n", want, got.Ids)
+	}
+	// Offsets are trimmed from their leading space
+	if want := [][]int{{0, 0}, {0, 5}, {6, 11}, {0, 0}, {0, 0}, {0, 4}, {0, 0}}; !reflect.DeepEqual(want, got.Offsets) {
+		t.Errorf("want %v, got %v\n", want, got.Offsets)
+	}
+	if want := []int{1, 0, 0, 1, 1, 0, 1}; !reflect.DeepEqual(want, got.SpecialTokenMask) {
+		t.Errorf("want %v, got %v\n", want, got.SpecialTokenMask)
+	}
+	if want := []int{-1, 0, 0, -1, -1, 1, -1}; !reflect.DeepEqual(want, got.GetSequenceIds()) {
+		t.Errorf("want %v, got %v\n", want, got.GetSequenceIds())
+	}
+}
+
+func TestRobertaProcessing_NoTrimOffsets(t *testing.T) {
+	processor := NewRobertaProcessing(PostToken{Value: "</s>", Id: 2}, PostToken{Value: "<s>", Id: 0}, false, false)
+
+	encoding := newTestEncoding([]int{31414, 89}, []string{"Hello", "Ġthere"}, [][]int{{0, 5}, {5, 11}}, 0)
+
+	got := processor.Process(encoding, nil, true)
+
+	if want := [][]int{{0, 0}, {0, 5}, {5, 11}, {0, 0}}; !reflect.DeepEqual(want, got.Offsets) {
+		t.Errorf("want %v, got %v\n", want, got.Offsets)
+	}
+
+	got = processor.Process(encoding, nil, false)
+	if want := []string{"Hello", "Ġthere"}; !reflect.DeepEqual(want, got.Tokens) {
+		t.Errorf("want %v, got %v\n", want, got.Tokens)
+	}
+}
+
+func TestRobertaProcessing_TrimOffsets(t *testing.T) {
+	processor := DefaultRobertaProcessing()
+
+	// "Hello <mask>!": a left stripped `<mask>` holds the actual whitespace.
+	encoding := newTestEncoding([]int{31414, 50264, 328}, []string{"ĠHello", " <mask>", "!"}, [][]int{{0, 5}, {5, 12}, {12, 13}}, 0)
+	got := processor.Process(encoding, nil, false)
+	if want := [][]int{{0, 5}, {6, 12}, {12, 13}}; !reflect.DeepEqual(want, got.Offsets) {
+		t.Errorf("want %v, got %v\n", want, got.Offsets)
+	}
+
+	// Pre-tokenized words all start at 0, their added prefix space is kept.
+	encoding = newTestEncoding([]int{31414, 232}, []string{"ĠHello", "Ġworld"}, [][]int{{0, 5}, {0, 5}}, 0)
+	got = processor.Process(encoding, nil, false)
+	if want := [][]int{{0, 5}, {0, 5}}; !reflect.DeepEqual(want, got.Offsets) {
+		t.Errorf("want %v, got %v\n", want, got.Offsets)
+	}
+}
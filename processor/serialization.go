@@ -0,0 +1,104 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/util"
+)
+
+// This file implements json.Marshaler for post-processors so that they are
+// serialized in HuggingFace `tokenizer.json` format.
+
+// MarshalJSON serializes PostToken as a `[token, id]` pair.
+func (pt PostToken) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{pt.Value, pt.Id})
+}
+
+func (bp *BertProcessing) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("BertProcessing", map[string]interface{}{
+		"sep": bp.sep,
+		"cls": bp.cls,
+	})
+}
+
+func (rp *RobertaProcessing) MarshalJSON() ([]byte, error) {
+	return util.MarshalTyped("RobertaProcessing", map[string]interface{}{
+		"sep":              rp.sep,
+		"cls":              rp.cls,
+		"trim_offsets":     rp.trimOffsets,
+		"add_prefix_space": rp.addPrefixSpace,
+	})
+}
+
+func (bl *ByteLevelProcessing) MarshalJSON() ([]byte, error) {
+	return json.Marshal(bl.pretok)
+}
+
+func (seq *Sequence) MarshalJSON() ([]byte, error) {
+	processors := seq.processors
+	if processors == nil {
+		processors = []tokenizer.PostProcessor{}
+	}
+	for _, p := range processors {
+		if _, ok := p.(json.Marshaler); !ok {
+			return nil, fmt.Errorf("Cannot serialize post-processor of type %T", p)
+		}
+	}
+	return util.MarshalTyped("Sequence", map[string]interface{}{
+		"processors": processors,
+	})
+}
+
+func serializeTemplate(tpl Template) ([]interface{}, error) {
+	out := []interface{}{}
+	for _, p := range tpl {
+		switch v := p.(type) {
+		case *SequencePiece:
+			id := "A"
+			if v.Id == B {
+				id = "B"
+			}
+			out = append(out, map[string]interface{}{
+				"Sequence": map[string]interface{}{"id": id, "type_id": v.TypeId},
+			})
+		case *SpecialTokenPiece:
+			out = append(out, map[string]interface{}{
+				"SpecialToken": map[string]interface{}{"id": v.Id, "type_id": v.TypeId},
+			})
+		default:
+			return nil, fmt.Errorf("Cannot serialize template piece of type %T", p)
+		}
+	}
+
+	return out, nil
+}
+
+func (tp *TemplateProcessing) MarshalJSON() ([]byte, error) {
+	single, err := serializeTemplate(tp.Single)
+	if err != nil {
+		return nil, err
+	}
+	pair, err := serializeTemplate(tp.Pair)
+	if err != nil {
+		return nil, err
+	}
+
+	specialTokens := make(map[string]interface{})
+	if tp.SpecialTokens != nil {
+		for k, tok := range tp.SpecialTokens.TokenMap {
+			specialTokens[k] = map[string]interface{}{
+				"id":     tok.Id,
+				"ids":    tok.Ids,
+				"tokens": tok.Tokens,
+			}
+		}
+	}
+
+	return util.MarshalTyped("TemplateProcessing", map[string]interface{}{
+		"single":         single,
+		"pair":           pair,
+		"special_tokens": specialTokens,
+	})
+}
@@ -0,0 +1,139 @@
+// Package qa prepares (question, context) pairs for extractive question
+// answering models and maps their predictions back to the context text.
+//
+// Contexts longer than the model input are split into overlapping windows:
+//
+//	windows, err := qa.Encode(tk, question, context, 384, 128)
+//	for _, w := range windows {
+//		// run the model on w.Encoding, predict the [start, end) tokens
+//		answer, start, end, ok := w.Answer(context, startTok, endTok)
+//	}
+package qa
+
+import (
+	"fmt"
+
+	"github.com/sugarme/tokenizer"
+)
+
+// ContextSequence is the sequence id of the context in a window encoding.
+const ContextSequence = 1
+
+// Window is one encoded (question, context window) pair.
+type Window struct {
+	// Encoding is the post-processed encoding of the pair.
+	Encoding *tokenizer.Encoding
+	// ContextOffsets holds, for each token of Encoding, its offsets in the
+	// original context. It is nil for tokens outside of the context (ie.
+	// question, special and padding tokens).
+	ContextOffsets [][]int
+	// ContextStart and ContextEnd are the [start, end) tokens of the context
+	// window in Encoding.
+	ContextStart, ContextEnd int
+
+	offsetType tokenizer.OffsetType
+}
+
+// Encode encodes question and context with tk, splitting the context in
+// windows so that each encoded pair fits in maxLength tokens, special tokens
+// included. Consecutive windows share stride context tokens.
+//
+// Offsets are expressed in the offset type of tk. The tokenizer padding is
+// applied to each window; its truncation should be disabled.
+func Encode(tk *tokenizer.Tokenizer, question, context string, maxLength, stride int) ([]Window, error) {
+	offsetType := tk.GetOffsetType()
+	q, err := tk.EncodeSingleSequence(tokenizer.NewInputSequence(question), 0, offsetType)
+	if err != nil {
+		return nil, err
+	}
+	c, err := tk.EncodeSingleSequence(tokenizer.NewInputSequence(context), 1, offsetType)
+	if err != nil {
+		return nil, err
+	}
+
+	nAddedTokens := 0
+	if p := tk.GetPostProcessor(); p != nil {
+		nAddedTokens = p.AddedTokens(true)
+	}
+	windowLen := maxLength - q.Len() - nAddedTokens
+	if windowLen <= 0 {
+		return nil, fmt.Errorf("Encode failed: question of %d tokens does not fit in max length %d.", q.Len(), maxLength)
+	}
+	if stride < 0 || stride >= windowLen {
+		return nil, fmt.Errorf("Encode failed: stride %d must be in [0, %d).", stride, windowLen)
+	}
+
+	c, err = c.Truncate(windowLen, stride)
+	if err != nil {
+		return nil, err
+	}
+	parts := append([]tokenizer.Encoding{*c}, c.TakeOverflowing()...)
+
+	windows := make([]Window, 0, len(parts))
+	for _, part := range parts {
+		part.Overflowing = nil
+		en, err := tk.PostProcess(q.Clone(), part.Clone(), true)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, newWindow(en, offsetType))
+	}
+
+	return windows, nil
+}
+
+func newWindow(en *tokenizer.Encoding, offsetType tokenizer.OffsetType) Window {
+	w := Window{
+		Encoding:       en,
+		ContextOffsets: make([][]int, en.Len()),
+		ContextStart:   -1,
+		offsetType:     offsetType,
+	}
+	offsets := en.GetOffsets()
+	for i, seq := range en.GetSequenceIds() {
+		if seq != ContextSequence || en.GetSpecialTokenMask()[i] == 1 {
+			continue
+		}
+		w.ContextOffsets[i] = offsets[i]
+		if w.ContextStart < 0 {
+			w.ContextStart = i
+		}
+		w.ContextEnd = i + 1
+	}
+	if w.ContextStart < 0 {
+		w.ContextStart = 0
+	}
+
+	return w
+}
+
+// IsContext returns whether the given token of the window belongs to the
+// context.
+func (w *Window) IsContext(token int) bool {
+	return token >= 0 && token < len(w.ContextOffsets) && w.ContextOffsets[token] != nil
+}
+
+// Answer maps the predicted [startTok, endTok) tokens of the window back to
+// context. It returns the answer text and its [start, end) offsets in context.
+// ok is false if the span is empty or its first or last token is not part of
+// the context.
+func (w *Window) Answer(context string, startTok, endTok int) (text string, start, end int, ok bool) {
+	if startTok >= endTok || !w.IsContext(startTok) || !w.IsContext(endTok-1) {
+		return "", 0, 0, false
+	}
+
+	start = w.ContextOffsets[startTok][0]
+	end = w.ContextOffsets[endTok-1][1]
+	if w.offsetType == tokenizer.Char {
+		runes := []rune(context)
+		if end > len(runes) {
+			return "", 0, 0, false
+		}
+		return string(runes[start:end]), start, end, true
+	}
+	if end > len(context) {
+		return "", 0, 0, false
+	}
+
+	return context[start:end], start, end, true
+}
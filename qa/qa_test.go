@@ -0,0 +1,95 @@
+package qa_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/model/wordlevel"
+	"github.com/sugarme/tokenizer/pretokenizer"
+	"github.com/sugarme/tokenizer/processor"
+	"github.com/sugarme/tokenizer/qa"
+)
+
+func newTokenizer(t *testing.T) *tokenizer.Tokenizer {
+	vocab := map[string]int{"[UNK]": 0, "[CLS]": 1, "[SEP]": 2, "who": 3, "are": 4, "you": 5, "i": 6, "am": 7, "a": 8, "good": 9, "day": 10, "bot": 11}
+	model, err := wordlevel.New(vocab, "[UNK]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := tokenizer.NewTokenizer(model)
+	tk.WithPreTokenizer(pretokenizer.NewWhitespaceSplit())
+	tk.WithPostProcessor(processor.NewBertProcessing(processor.PostToken{Value: "[SEP]", Id: 2}, processor.PostToken{Value: "[CLS]", Id: 1}))
+	tk.AddSpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[CLS]", true), tokenizer.NewAddedToken("[SEP]", true)})
+
+	return tk
+}
+
+func TestEncode(t *testing.T) {
+	tk := newTokenizer(t)
+	context := "good day i am a bot"
+
+	// [CLS] who are you [SEP] + 3 context tokens + [SEP]
+	windows, err := qa.Encode(tk, "who are you", context, 9, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantTokens := [][]string{
+		{"[CLS]", "who", "are", "you", "[SEP]", "good", "day", "i", "[SEP]"},
+		{"[CLS]", "who", "are", "you", "[SEP]", "i", "am", "a", "[SEP]"},
+		{"[CLS]", "who", "are", "you", "[SEP]", "a", "bot", "[SEP]"},
+	}
+	if len(windows) != len(wantTokens) {
+		t.Fatalf("want %d windows, got %d", len(wantTokens), len(windows))
+	}
+	for i, w := range windows {
+		if got := w.Encoding.GetTokens(); !reflect.DeepEqual(wantTokens[i], got) {
+			t.Errorf("window %d: want %q, got %q", i, wantTokens[i], got)
+		}
+		if w.ContextStart != 5 || w.ContextEnd != len(wantTokens[i])-1 {
+			t.Errorf("window %d: want context tokens [5, %d), got [%d, %d)", i, len(wantTokens[i])-1, w.ContextStart, w.ContextEnd)
+		}
+		for j := range w.ContextOffsets {
+			if got := w.IsContext(j); got != (j >= w.ContextStart && j < w.ContextEnd) {
+				t.Errorf("window %d: token %d: want context %v", i, j, !got)
+			}
+		}
+	}
+
+	text, start, end, ok := windows[2].Answer(context, 5, 7)
+	if !ok || text != "a bot" || start != 14 || end != 19 {
+		t.Errorf("want %q [14, 19), got %q [%d, %d) %v", "a bot", text, start, end, ok)
+	}
+	for _, span := range [][2]int{{0, 3}, {3, 6}, {6, 6}, {6, 9}} {
+		if _, _, _, ok := windows[1].Answer(context, span[0], span[1]); ok {
+			t.Errorf("want no answer for tokens %v", span)
+		}
+	}
+
+	if _, err := qa.Encode(tk, "who are you", context, 5, 0); err == nil {
+		t.Errorf("want error when the question does not fit")
+	}
+	if _, err := qa.Encode(tk, "who are you", context, 9, 3); err == nil {
+		t.Errorf("want error on stride not less than the window length")
+	}
+}
+
+func TestEncode_CharOffsets(t *testing.T) {
+	tk := newTokenizer(t)
+	tk.WithOffsetType(tokenizer.Char)
+	context := "ï am a bot"
+
+	windows, err := qa.Encode(tk, "who are you", context, 32, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 1 {
+		t.Fatalf("want 1 window, got %d", len(windows))
+	}
+
+	text, start, end, ok := windows[0].Answer(context, 6, 8)
+	if !ok || text != "am a" || start != 2 || end != 6 {
+		t.Errorf("want %q [2, 6), got %q [%d, %d) %v", "am a", text, start, end, ok)
+	}
+}
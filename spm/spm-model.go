@@ -0,0 +1,347 @@
+package spm
+
+// This file reads SentencePiece `.model` files, ie. serialized `ModelProto`
+// protobuf messages.
+// Ref. https://github.com/google/sentencepiece/blob/master/src/sentencepiece_model.proto
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+)
+
+// ModelType is the type of algorithm of a SentencePiece model.
+type ModelType int
+
+const (
+	UnigramModel ModelType = 1
+	BpeModel     ModelType = 2
+	WordModel    ModelType = 3
+	CharModel    ModelType = 4
+)
+
+// PieceType is the type of a SentencePiece piece.
+type PieceType int
+
+const (
+	NormalPiece      PieceType = 1
+	UnknownPiece     PieceType = 2
+	ControlPiece     PieceType = 3
+	UserDefinedPiece PieceType = 4
+	UnusedPiece      PieceType = 5
+	BytePiece        PieceType = 6
+)
+
+// Piece is a vocab entry of a SentencePiece model. Its id is its index in
+// the model pieces.
+type Piece struct {
+	Piece string
+	Score float32
+	Type  PieceType
+}
+
+// TrainerSpec holds the training parameters of a SentencePiece model that
+// are needed to use it.
+type TrainerSpec struct {
+	ModelType    ModelType
+	VocabSize    int
+	ByteFallback bool
+	UnkId        int
+	BosId        int
+	EosId        int
+	PadId        int // -1 if none
+	UnkPiece     string
+	BosPiece     string
+	EosPiece     string
+	PadPiece     string
+}
+
+// NormalizerSpec holds the normalization parameters of a SentencePiece model.
+type NormalizerSpec struct {
+	Name                   string
+	PrecompiledCharsmap    []byte
+	AddDummyPrefix         bool
+	RemoveExtraWhitespaces bool
+	EscapeWhitespaces      bool
+}
+
+// Model is a SentencePiece model.
+type Model struct {
+	Pieces         []Piece
+	TrainerSpec    TrainerSpec
+	NormalizerSpec NormalizerSpec
+}
+
+// ReadModel reads a SentencePiece `.model` file.
+func ReadModel(file string) (*Model, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := ParseModel(data)
+	if err != nil {
+		return nil, fmt.Errorf("Reading SentencePiece model %q: %w", file, err)
+	}
+
+	return m, nil
+}
+
+// ParseModel parses a serialized SentencePiece `ModelProto`.
+func ParseModel(data []byte) (*Model, error) {
+	m := &Model{
+		TrainerSpec:    defaultTrainerSpec(),
+		NormalizerSpec: defaultNormalizerSpec(),
+	}
+
+	err := readFields(data, func(num int, f field) error {
+		switch num {
+		case 1: // pieces
+			p, err := parsePiece(f.bytes)
+			if err != nil {
+				return err
+			}
+			m.Pieces = append(m.Pieces, p)
+		case 2: // trainer_spec
+			return parseTrainerSpec(f.bytes, &m.TrainerSpec)
+		case 3: // normalizer_spec
+			return parseNormalizerSpec(f.bytes, &m.NormalizerSpec)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.Pieces) == 0 {
+		return nil, fmt.Errorf("Invalid SentencePiece model: no pieces")
+	}
+
+	return m, nil
+}
+
+func defaultTrainerSpec() TrainerSpec {
+	return TrainerSpec{
+		ModelType: UnigramModel,
+		VocabSize: 8000,
+		UnkId:     0,
+		BosId:     1,
+		EosId:     2,
+		PadId:     -1,
+		UnkPiece:  "<unk>",
+		BosPiece:  "<s>",
+		EosPiece:  "</s>",
+		PadPiece:  "<pad>",
+	}
+}
+
+func defaultNormalizerSpec() NormalizerSpec {
+	return NormalizerSpec{
+		AddDummyPrefix:         true,
+		RemoveExtraWhitespaces: true,
+		EscapeWhitespaces:      true,
+	}
+}
+
+func parsePiece(data []byte) (Piece, error) {
+	p := Piece{Type: NormalPiece}
+	err := readFields(data, func(num int, f field) error {
+		switch num {
+		case 1:
+			p.Piece = string(f.bytes)
+		case 2:
+			p.Score = math.Float32frombits(uint32(f.varint))
+		case 3:
+			p.Type = PieceType(f.varint)
+		}
+		return nil
+	})
+
+	return p, err
+}
+
+func parseTrainerSpec(data []byte, spec *TrainerSpec) error {
+	return readFields(data, func(num int, f field) error {
+		switch num {
+		case 3:
+			spec.ModelType = ModelType(f.varint)
+		case 4:
+			spec.VocabSize = f.int32()
+		case 35:
+			spec.ByteFallback = f.varint != 0
+		case 40:
+			spec.UnkId = f.int32()
+		case 41:
+			spec.BosId = f.int32()
+		case 42:
+			spec.EosId = f.int32()
+		case 43:
+			spec.PadId = f.int32()
+		case 45:
+			spec.UnkPiece = string(f.bytes)
+		case 46:
+			spec.BosPiece = string(f.bytes)
+		case 47:
+			spec.EosPiece = string(f.bytes)
+		case 48:
+			spec.PadPiece = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+func parseNormalizerSpec(data []byte, spec *NormalizerSpec) error {
+	return readFields(data, func(num int, f field) error {
+		switch num {
+		case 1:
+			spec.Name = string(f.bytes)
+		case 2:
+			spec.PrecompiledCharsmap = f.bytes
+		case 3:
+			spec.AddDummyPrefix = f.varint != 0
+		case 4:
+			spec.RemoveExtraWhitespaces = f.varint != 0
+		case 5:
+			spec.EscapeWhitespaces = f.varint != 0
+		}
+		return nil
+	})
+}
+
+// field is a protobuf field value: `varint` holds varint and fixed size
+// values, `bytes` holds length-delimited ones.
+type field struct {
+	varint uint64
+	bytes  []byte
+}
+
+// int32 returns the value of an `int32` field, negative values being encoded
+// as 64 bits varints.
+func (f field) int32() int {
+	return int(int32(f.varint))
+}
+
+// readFields calls fn with the number and value of each field of a protobuf
+// message.
+func readFields(data []byte, fn func(num int, f field) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("Invalid protobuf message: bad field key")
+		}
+		data = data[n:]
+
+		var f field
+		switch wireType := key & 7; wireType {
+		case 0: // varint
+			f.varint, n = binary.Uvarint(data)
+			if n <= 0 {
+				return fmt.Errorf("Invalid protobuf message: bad varint")
+			}
+			data = data[n:]
+		case 1: // 64 bits
+			if len(data) < 8 {
+				return fmt.Errorf("Invalid protobuf message: truncated fixed64")
+			}
+			f.varint = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case 2: // length-delimited
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return fmt.Errorf("Invalid protobuf message: truncated field")
+			}
+			f.bytes = data[n : n+int(l)]
+			data = data[n+int(l):]
+		case 5: // 32 bits
+			if len(data) < 4 {
+				return fmt.Errorf("Invalid protobuf message: truncated fixed32")
+			}
+			f.varint = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		default:
+			return fmt.Errorf("Invalid protobuf message: unsupported wire type %v", wireType)
+		}
+
+		if err := fn(int(key>>3), f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Marshal serializes m as a `ModelProto` message. Only the fields of m are
+// written, other fields of the original `.model` file (ie. self test data)
+// are not kept.
+func (m *Model) Marshal() []byte {
+	var data []byte
+	for _, p := range m.Pieces {
+		var piece []byte
+		piece = appendBytes(piece, 1, []byte(p.Piece))
+		piece = appendFixed32(piece, 2, math.Float32bits(p.Score))
+		piece = appendVarint(piece, 3, uint64(p.Type))
+		data = appendBytes(data, 1, piece)
+	}
+
+	t := m.TrainerSpec
+	var trainer []byte
+	trainer = appendVarint(trainer, 3, uint64(t.ModelType))
+	trainer = appendVarint(trainer, 4, uint64(int64(t.VocabSize)))
+	trainer = appendVarint(trainer, 35, boolToUint(t.ByteFallback))
+	trainer = appendVarint(trainer, 40, uint64(int64(t.UnkId)))
+	trainer = appendVarint(trainer, 41, uint64(int64(t.BosId)))
+	trainer = appendVarint(trainer, 42, uint64(int64(t.EosId)))
+	trainer = appendVarint(trainer, 43, uint64(int64(t.PadId)))
+	trainer = appendBytes(trainer, 45, []byte(t.UnkPiece))
+	trainer = appendBytes(trainer, 46, []byte(t.BosPiece))
+	trainer = appendBytes(trainer, 47, []byte(t.EosPiece))
+	trainer = appendBytes(trainer, 48, []byte(t.PadPiece))
+	data = appendBytes(data, 2, trainer)
+
+	n := m.NormalizerSpec
+	var normalizer []byte
+	normalizer = appendBytes(normalizer, 1, []byte(n.Name))
+	normalizer = appendBytes(normalizer, 2, n.PrecompiledCharsmap)
+	normalizer = appendVarint(normalizer, 3, boolToUint(n.AddDummyPrefix))
+	normalizer = appendVarint(normalizer, 4, boolToUint(n.RemoveExtraWhitespaces))
+	normalizer = appendVarint(normalizer, 5, boolToUint(n.EscapeWhitespaces))
+	data = appendBytes(data, 3, normalizer)
+
+	return data
+}
+
+func appendUvarint(data []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(data, buf[:n]...)
+}
+
+func appendKey(data []byte, num int, wireType uint64) []byte {
+	return appendUvarint(data, uint64(num)<<3|wireType)
+}
+
+func appendVarint(data []byte, num int, v uint64) []byte {
+	data = appendKey(data, num, 0)
+	return appendUvarint(data, v)
+}
+
+func appendFixed32(data []byte, num int, v uint32) []byte {
+	data = appendKey(data, num, 5)
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(data, buf[:]...)
+}
+
+func appendBytes(data []byte, num int, v []byte) []byte {
+	data = appendKey(data, num, 2)
+	data = appendUvarint(data, uint64(len(v)))
+	return append(data, v...)
+}
+
+func boolToUint(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,75 @@
+package spm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModel_MarshalParse(t *testing.T) {
+	want := &Model{
+		Pieces: []Piece{
+			{Piece: "<unk>", Score: 0, Type: UnknownPiece},
+			{Piece: "<s>", Score: 0, Type: ControlPiece},
+			{Piece: "</s>", Score: 0, Type: ControlPiece},
+			{Piece: "▁hello", Score: -1.5, Type: NormalPiece},
+			{Piece: "<0x41>", Score: -10.25, Type: BytePiece},
+		},
+		TrainerSpec: TrainerSpec{
+			ModelType:    BpeModel,
+			VocabSize:    5,
+			ByteFallback: true,
+			UnkId:        0,
+			BosId:        1,
+			EosId:        2,
+			PadId:        -1,
+			UnkPiece:     "<unk>",
+			BosPiece:     "<s>",
+			EosPiece:     "</s>",
+			PadPiece:     "<pad>",
+		},
+		NormalizerSpec: NormalizerSpec{
+			Name:                "nmt_nfkc",
+			PrecompiledCharsmap: []byte{1, 2, 3},
+			AddDummyPrefix:      true,
+		},
+	}
+
+	got, err := ParseModel(want.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestParseModel_Defaults(t *testing.T) {
+	// A single piece `{piece: "a"}`
+	got, err := ParseModel([]byte{0x0a, 0x03, 0x0a, 0x01, 'a'})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Model{
+		Pieces:         []Piece{{Piece: "a", Type: NormalPiece}},
+		TrainerSpec:    defaultTrainerSpec(),
+		NormalizerSpec: defaultNormalizerSpec(),
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestParseModel_Invalid(t *testing.T) {
+	for _, data := range [][]byte{
+		nil,                      // no pieces
+		{0x0a, 0x05, 0x0a},       // truncated field
+		{0x0b},                   // unsupported wire type
+		{0x0a, 0x02, 0x10, 0x80}, // truncated varint in piece
+	} {
+		if _, err := ParseModel(data); err == nil {
+			t.Errorf("want error for %v", data)
+		}
+	}
+}
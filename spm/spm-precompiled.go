@@ -131,7 +131,15 @@ func Parse(precompiledCharsmap []byte) ([]byte, Array) {
 	return normalizedBlob, trieBlob
 }
 
+// NewPrecompiledFrom creates a Precompiled from a `precompiled_charsmap`.
 func NewPrecompiledFrom(data []byte) (*Precompiled, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("Invalid precompiled charsmap: too short (%v bytes)", len(data))
+	}
+	if trieSize := binary.LittleEndian.Uint32(data[:4]); int(trieSize) > len(data)-4 || trieSize%4 != 0 {
+		return nil, fmt.Errorf("Invalid precompiled charsmap: trie size %v for %v bytes", trieSize, len(data))
+	}
+
 	normalizedBlob, trieBlob := Parse(data)
 
 	normalized := string(normalizedBlob)
@@ -144,10 +152,19 @@ func NewPrecompiledFrom(data []byte) (*Precompiled, error) {
 	}, nil
 }
 
+// Transform returns the normalized form of chunk, or an empty string if there
+// is none. See `Lookup` to tell a chunk normalized to an empty string (ie.
+// removed) from a chunk without normalization.
 func (m *Precompiled) Transform(chunk string) string {
+	normalized, _ := m.Lookup(chunk)
+	return normalized
+}
+
+// Lookup returns the normalized form of chunk, and whether chunk has one.
+func (m *Precompiled) Lookup(chunk string) (string, bool) {
 	results := m.Trie.CommonPrefixSearch([]byte(chunk))
 	if len(results) == 0 {
-		return ""
+		return "", false
 	}
 
 	index := results[0]
@@ -159,11 +176,12 @@ func (m *Precompiled) Transform(chunk string) string {
 		index2 += 1
 	}
 
-	normalized := m.Normalized[index:index2]
-
-	return string(normalized)
+	return m.Normalized[index:index2], true
 }
 
+// NormalizeMn turns non-spacing marks of input into their `U+XXXX` notation.
+//
+// Deprecated: non-spacing marks are kept as is by the precompiled normalizer.
 func NormalizeMn(input string) string {
 	return normalizeMn(input)
 }
@@ -183,8 +201,9 @@ func normalizeMn(input string) string {
 	return strings.Join(out, "")
 }
 
+// NormalizeString normalizes original string, grapheme by grapheme.
 func (m *Precompiled) NormalizeString(original string) string {
-	var chars []string
+	var sb strings.Builder
 
 	graphemes := uniseg.NewGraphemes(original)
 
@@ -202,33 +221,20 @@ func (m *Precompiled) NormalizeString(original string) string {
 		// break a single test.
 		// You don't pass.
 		if len(grapheme) < 6 {
-			norm := m.Transform(grapheme)
-			if len(norm) > 0 {
-				for _, c := range strings.Split(norm, "") {
-					chars = append(chars, c)
-				}
-				return strings.Join(chars, "")
+			if norm, ok := m.Lookup(grapheme); ok {
+				sb.WriteString(norm)
+				continue
 			}
 		}
 
-		// TT. This is a hacky way to turn non-spacing marks into hexa string
-		// and pass the unit tests.
-		grapheme = normalizeMn(grapheme)
-		var charIdx int = 0
 		for _, r := range grapheme {
-			part := string(grapheme)[charIdx : charIdx+len(string(r))]
-			norm := m.Transform(part)
-			norm = normalizeMn(norm)
-			if len(norm) > 0 {
-				for _, c := range strings.Split(norm, "") {
-					chars = append(chars, c)
-				}
+			if norm, ok := m.Lookup(string(r)); ok {
+				sb.WriteString(norm)
 			} else {
-				chars = append(chars, string(r))
+				sb.WriteRune(r)
 			}
-			charIdx += len(string(r))
 		}
 	}
 
-	return strings.Join(chars, "")
+	return sb.String()
 }
@@ -0,0 +1,86 @@
+package tokenizer
+
+import (
+	"unicode/utf8"
+)
+
+// StreamDecoder decodes token ids one at a time, as a language model
+// generates them, returning only the text each new id finalizes.
+//
+// Decoding the ids one by one does not give the text of decoding them at
+// once: a char may be split over several byte tokens (byte-level BPE, byte
+// fallback), and decoders such as `Metaspace` or `WordPiece` handle a token
+// depending on the previous one. So the decoder keeps the ids of the last
+// finalized text as context, decodes them along with the new ids and returns
+// what comes after the context text, once it does not end with an
+// incomplete char.
+//
+// NOTE: tokenization spaces are not cleaned up (see
+// `Tokenizer.WithCleanUpTokenizationSpaces`), as it depends on the next
+// tokens.
+type StreamDecoder struct {
+	tokenizer         *Tokenizer
+	skipSpecialTokens bool
+
+	ids []int
+	// ids[:prefix] are context, ids[prefix:read] were decoded into the last
+	// finalized text, ids[read:] are pending.
+	prefix, read int
+}
+
+// NewStreamDecoder creates a StreamDecoder decoding with tk.
+func NewStreamDecoder(tk *Tokenizer, skipSpecialTokens bool) *StreamDecoder {
+	return &StreamDecoder{
+		tokenizer:         tk,
+		skipSpecialTokens: skipSpecialTokens,
+	}
+}
+
+// Step adds id to the decoded ids. It returns the text finalized by id and
+// true, or an empty string and false if more ids are needed (ie. id is part
+// of an incomplete char).
+func (d *StreamDecoder) Step(id int) (string, bool) {
+	d.ids = append(d.ids, id)
+
+	text, complete := d.pending()
+	if !complete {
+		return "", false
+	}
+
+	// The ids of this text are the context of the next one.
+	d.ids = d.ids[d.read:]
+	d.prefix, d.read = 0, len(d.ids)
+
+	return text, true
+}
+
+// Flush returns the pending text, even if it ends with an incomplete char,
+// and resets the decoder.
+func (d *StreamDecoder) Flush() string {
+	text, _ := d.pending()
+	d.Reset()
+
+	return text
+}
+
+// Reset clears the decoder, to decode a new sequence.
+func (d *StreamDecoder) Reset() {
+	d.ids = d.ids[:0]
+	d.prefix, d.read = 0, 0
+}
+
+// pending returns the text of the pending ids and whether it is complete.
+func (d *StreamDecoder) pending() (string, bool) {
+	prefixText := d.tokenizer.decode(d.ids[d.prefix:d.read], d.skipSpecialTokens, false)
+	text := d.tokenizer.decode(d.ids[d.prefix:], d.skipSpecialTokens, false)
+	if len(text) <= len(prefixText) {
+		return "", false
+	}
+
+	text = text[len(prefixText):]
+	if r, _ := utf8.DecodeLastRuneInString(text); r == utf8.RuneError {
+		return text, false
+	}
+
+	return text, true
+}
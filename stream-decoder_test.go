@@ -0,0 +1,85 @@
+package tokenizer_test
+
+import (
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/decoder"
+	"github.com/sugarme/tokenizer/model/wordlevel"
+	"github.com/sugarme/tokenizer/pretokenizer"
+)
+
+func TestStreamDecoder(t *testing.T) {
+	vocab := map[string]int{
+		"<unk>":  0,
+		"<s>":    1,
+		"▁hello": 2,
+		"▁caf":   3,
+		"<0xC3>": 4,
+		"<0xA9>": 5,
+		"▁world": 6,
+		"Ġcaf":   7,
+		"Ã":      8,
+		"©":      9,
+	}
+	model, err := wordlevel.New(vocab, "<unk>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		decoder tokenizer.Decoder
+		ids     []int
+		want    []string
+	}{
+		{
+			name: "byte fallback",
+			decoder: decoder.NewSequence([]tokenizer.Decoder{
+				decoder.NewByteFallback(),
+				pretokenizer.NewMetaspace("▁", true),
+				decoder.NewFuse(),
+			}),
+			ids:  []int{1, 2, 3, 4, 5, 6},
+			want: []string{"", "hello", " caf", "", "é", " world"},
+		},
+		{
+			name:    "byte level",
+			decoder: pretokenizer.NewByteLevel(),
+			ids:     []int{7, 8, 9},
+			want:    []string{" caf", "", "é"},
+		},
+	}
+
+	for _, tt := range tests {
+		tk := tokenizer.NewTokenizer(model)
+		tk.AddSpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("<s>", true)})
+		tk.WithDecoder(tt.decoder)
+
+		d := tokenizer.NewStreamDecoder(tk, true)
+		var got string
+		for i, id := range tt.ids {
+			text, ok := d.Step(id)
+			if text != tt.want[i] || ok != (tt.want[i] != "") {
+				t.Errorf("%s: step %v: want %q, got %q (%v)", tt.name, i, tt.want[i], text, ok)
+			}
+			got += text
+		}
+		if want := tk.Decode(tt.ids, true); got != want {
+			t.Errorf("%s: want %q, got %q", tt.name, want, got)
+		}
+	}
+
+	// Flush returns incomplete chars.
+	tk := tokenizer.NewTokenizer(model)
+	tk.WithDecoder(pretokenizer.NewByteLevel())
+	d := tokenizer.NewStreamDecoder(tk, false)
+	d.Step(7)
+	d.Step(8)
+	if got, want := d.Flush(), "\xc3"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if got, ok := d.Step(7); got != " caf" || !ok {
+		t.Errorf("want %q after flush, got %q (%v)", " caf", got, ok)
+	}
+}
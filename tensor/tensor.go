@@ -0,0 +1,82 @@
+// Package tensor converts encodings to the matrix and tensor types of Go ML
+// frameworks: gonum `mat.Dense` and gorgonia `tensor.Dense`.
+//
+// It lives in its own module so that the tokenizer library does not depend on
+// gonum or gorgonia. Plain `[][]int64` matrices are built by
+// `tokenizer.EncodingsToMatrix`.
+package tensor
+
+import (
+	"fmt"
+
+	"gonum.org/v1/gonum/mat"
+	gtensor "gorgonia.org/tensor"
+
+	"github.com/sugarme/tokenizer"
+)
+
+// shape returns the shape of m, or an error if its rows have different
+// lengths.
+func shape(m [][]int64) (rows, cols int, err error) {
+	rows = len(m)
+	if rows == 0 {
+		return 0, 0, fmt.Errorf("empty matrix")
+	}
+	cols = len(m[0])
+	for i, row := range m {
+		if len(row) != cols {
+			return 0, 0, fmt.Errorf("row %d has %d columns, want %d", i, len(row), cols)
+		}
+	}
+	if cols == 0 {
+		return 0, 0, fmt.Errorf("empty rows")
+	}
+
+	return rows, cols, nil
+}
+
+// ToDense converts a matrix of ids to a gonum matrix. As gonum matrices hold
+// float64, ids above 2^53 lose precision.
+func ToDense(m [][]int64) (*mat.Dense, error) {
+	rows, cols, err := shape(m)
+	if err != nil {
+		return nil, fmt.Errorf("ToDense failed: %w", err)
+	}
+
+	data := make([]float64, 0, rows*cols)
+	for _, row := range m {
+		for _, v := range row {
+			data = append(data, float64(v))
+		}
+	}
+
+	return mat.NewDense(rows, cols, data), nil
+}
+
+// ToTensor converts a matrix of ids to an int64 gorgonia tensor of shape
+// (rows, cols).
+func ToTensor(m [][]int64) (*gtensor.Dense, error) {
+	rows, cols, err := shape(m)
+	if err != nil {
+		return nil, fmt.Errorf("ToTensor failed: %w", err)
+	}
+
+	data := make([]int64, 0, rows*cols)
+	for _, row := range m {
+		data = append(data, row...)
+	}
+
+	return gtensor.New(gtensor.WithShape(rows, cols), gtensor.WithBacking(data)), nil
+}
+
+// IdsDense returns the ids of encodings as a gonum matrix, one row per
+// encoding right padded with padId.
+func IdsDense(encodings []tokenizer.Encoding, padId int) (*mat.Dense, error) {
+	return ToDense(tokenizer.EncodingsToMatrix(encodings, padId))
+}
+
+// IdsTensor returns the ids of encodings as an int64 gorgonia tensor of shape
+// (batch, sequence), right padded with padId.
+func IdsTensor(encodings []tokenizer.Encoding, padId int) (*gtensor.Dense, error) {
+	return ToTensor(tokenizer.EncodingsToMatrix(encodings, padId))
+}
@@ -0,0 +1,53 @@
+package tensor_test
+
+import (
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/tensor"
+)
+
+var encodings = []tokenizer.Encoding{
+	{Ids: []int{4, 5}},
+	{Ids: []int{6, 7, 8}},
+}
+
+func TestIdsDense(t *testing.T) {
+	got, err := tensor.IdsDense(encodings, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := mat.NewDense(2, 3, []float64{4, 5, 0, 6, 7, 8})
+	if !mat.Equal(want, got) {
+		t.Errorf("want %v\ngot %v", mat.Formatted(want), mat.Formatted(got))
+	}
+}
+
+func TestIdsTensor(t *testing.T) {
+	got, err := tensor.IdsTensor(encodings, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []int{2, 3}; !reflect.DeepEqual(want, []int(got.Shape())) {
+		t.Errorf("want shape %v, got %v", want, got.Shape())
+	}
+	if want := []int64{4, 5, 1, 6, 7, 8}; !reflect.DeepEqual(want, got.Data()) {
+		t.Errorf("want %v, got %v", want, got.Data())
+	}
+}
+
+func TestToTensor_Invalid(t *testing.T) {
+	for _, m := range [][][]int64{nil, {{}}, {{1, 2}, {3}}} {
+		if _, err := tensor.ToTensor(m); err == nil {
+			t.Errorf("%v: want error", m)
+		}
+		if _, err := tensor.ToDense(m); err == nil {
+			t.Errorf("%v: want error", m)
+		}
+	}
+}
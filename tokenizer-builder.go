@@ -0,0 +1,80 @@
+package tokenizer
+
+import (
+	"github.com/sugarme/tokenizer/normalizer"
+)
+
+// TokenizerBuilder is a builder to assemble a Tokenizer pipeline:
+// normalizer -> pre-tokenizer -> model -> post-processor (-> decoder).
+//
+// E.g.
+//
+//	tk := NewTokenizerBuilder(model).
+//		Normalizer(normalizer.Lowercase()).
+//		PreTokenizer(pretokenizer.NewWhitespaceSplit()).
+//		PostProcessor(processor.NewBertProcessing(sep, cls)).
+//		Build()
+type TokenizerBuilder struct {
+	tokenizer *Tokenizer
+}
+
+// NewTokenizerBuilder creates a TokenizerBuilder with given model.
+// All other parts are optional.
+func NewTokenizerBuilder(model Model) *TokenizerBuilder {
+	return &TokenizerBuilder{
+		tokenizer: NewTokenizer(model),
+	}
+}
+
+// Normalizer sets the normalizer
+func (b *TokenizerBuilder) Normalizer(n normalizer.Normalizer) *TokenizerBuilder {
+	b.tokenizer.WithNormalizer(n)
+	return b
+}
+
+// PreTokenizer sets the pre-tokenizer
+func (b *TokenizerBuilder) PreTokenizer(preTokenizer PreTokenizer) *TokenizerBuilder {
+	b.tokenizer.WithPreTokenizer(preTokenizer)
+	return b
+}
+
+// PostProcessor sets the post-processor
+func (b *TokenizerBuilder) PostProcessor(postProcessor PostProcessor) *TokenizerBuilder {
+	b.tokenizer.WithPostProcessor(postProcessor)
+	return b
+}
+
+// Decoder sets the decoder
+func (b *TokenizerBuilder) Decoder(decoder Decoder) *TokenizerBuilder {
+	b.tokenizer.WithDecoder(decoder)
+	return b
+}
+
+// Truncation sets truncation parameters
+func (b *TokenizerBuilder) Truncation(trunc *TruncationParams) *TokenizerBuilder {
+	b.tokenizer.WithTruncation(trunc)
+	return b
+}
+
+// Padding sets padding parameters
+func (b *TokenizerBuilder) Padding(padding *PaddingParams) *TokenizerBuilder {
+	b.tokenizer.WithPadding(padding)
+	return b
+}
+
+// AddedTokens adds (non-special) tokens to the vocab
+func (b *TokenizerBuilder) AddedTokens(tokens []AddedToken) *TokenizerBuilder {
+	b.tokenizer.AddTokens(tokens)
+	return b
+}
+
+// SpecialTokens adds special tokens to the vocab
+func (b *TokenizerBuilder) SpecialTokens(tokens []AddedToken) *TokenizerBuilder {
+	b.tokenizer.AddSpecialTokens(tokens)
+	return b
+}
+
+// Build returns the assembled Tokenizer.
+func (b *TokenizerBuilder) Build() *Tokenizer {
+	return b.tokenizer
+}
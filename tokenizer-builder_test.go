@@ -0,0 +1,53 @@
+package tokenizer_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/model/wordlevel"
+	"github.com/sugarme/tokenizer/normalizer"
+	"github.com/sugarme/tokenizer/pretokenizer"
+	"github.com/sugarme/tokenizer/processor"
+)
+
+func TestTokenizerBuilder(t *testing.T) {
+	vocab := map[string]int{
+		"[UNK]": 0,
+		"[CLS]": 1,
+		"[SEP]": 2,
+		"hello": 3,
+		"world": 4,
+	}
+	model, err := wordlevel.New(vocab, "[UNK]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tk := tokenizer.NewTokenizerBuilder(model).
+		Normalizer(normalizer.Lowercase()).
+		PreTokenizer(pretokenizer.NewWhitespaceSplit()).
+		PostProcessor(processor.NewBertProcessing(processor.PostToken{Value: "[SEP]", Id: 2}, processor.PostToken{Value: "[CLS]", Id: 1})).
+		SpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[CLS]", true), tokenizer.NewAddedToken("[SEP]", true)}).
+		Build()
+
+	en, err := tk.EncodePair("Hello World", "HELLO", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantToks := []string{"[CLS]", "hello", "world", "[SEP]", "hello", "[SEP]"}
+	if !reflect.DeepEqual(wantToks, en.Tokens) {
+		t.Errorf("want %#v\ngot %#v\n", wantToks, en.Tokens)
+	}
+
+	wantIds := []int{1, 3, 4, 2, 3, 2}
+	if !reflect.DeepEqual(wantIds, en.Ids) {
+		t.Errorf("want %#v\ngot %#v\n", wantIds, en.Ids)
+	}
+
+	wantTypeIds := []int{0, 0, 0, 0, 1, 1}
+	if !reflect.DeepEqual(wantTypeIds, en.TypeIds) {
+		t.Errorf("want %#v\ngot %#v\n", wantTypeIds, en.TypeIds)
+	}
+}
@@ -4,15 +4,25 @@ package tokenizer
 import (
 	"bufio"
 	// "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"os"
 	"reflect"
+	"runtime"
+	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	// "regexp"
 	"sync"
+	"sync/atomic"
 
 	progressbar "github.com/schollz/progressbar/v2"
 	// "golang.org/x/sync/errgroup"
@@ -58,9 +68,27 @@ type Model interface {
 	Save(path string, prefixOpt ...string) error
 }
 
+// VocabPruner is a Model able to prune its vocabulary, see
+// `Tokenizer.PruneVocab`.
+type VocabPruner interface {
+	Model
+	// PruneVocab keeps the tokens for which keep returns true and remaps
+	// their ids contiguously. It returns the mapping from old to new ids.
+	PruneVocab(keep func(token string, id int) bool) (map[int]int, error)
+}
+
 // PostProcessor is in charge of post-processing an encoded output of
 // the `Tokenizer`.
 // It adds any special tokens that a language model would require.
+//
+// Custom implementations can be set with `Tokenizer.WithPostProcessor` for
+// conventions the built-in processors don't cover. `Process` should flag the
+// tokens it adds in `SpecialTokenMask`, set `TypeIds`, record the sequence of
+// each token (see `DefaultProcess`) and process `Overflowing` encodings
+// alike. `AddedTokens` must match the number of tokens `Process` adds, so
+// that truncation leaves room for them. To be saved to and loaded from
+// `tokenizer.json`, an implementation marshals to JSON with a "type" field
+// and is registered with `pretrained.RegisterPostProcessor`.
 type PostProcessor interface {
 	// AddedTokens returns the number of tokens that will be added during the processing step
 	AddedTokens(isPair bool) int
@@ -70,17 +98,22 @@ type PostProcessor interface {
 }
 
 // DefaultProcess is a helper function of PostProcessor's Process method
-// It helps to fast track by just merging encoding and its pair.
+// It helps to fast track by just merging encoding and its pair, recording
+// their sequence ids (0 and 1), overflowing encodings included. Offsets of
+// the pair are kept relative to the pair sequence.
 func DefaultProcess(encoding, pairEncoding *Encoding, addSpecialTokens bool) *Encoding {
 	if pairEncoding == nil {
 		return encoding
 	}
 
-	if pairEncoding != nil {
-		return encoding.MergeWith(pairEncoding, false)
+	for i, e := range []*Encoding{encoding, pairEncoding} {
+		e.SetSequenceIds(i)
+		for j := range e.Overflowing {
+			e.Overflowing[j].SetSequenceIds(i)
+		}
 	}
 
-	return encoding
+	return encoding.MergeWith(pairEncoding, false)
 }
 
 // PrepareEncodings prepares encoding and pairEncoding if any before `ProcessEncodings` call.
@@ -165,6 +198,26 @@ type Trainer interface {
 	ProcessTokens(words map[string]int, tokens []string)
 }
 
+// StageCountWords is the `Progress` stage of counting words of the training
+// data, before the trainer starts.
+const StageCountWords = "Count words"
+
+// Progress reports the progress of a training stage.
+type Progress struct {
+	Stage   string // `StageCountWords` or a trainer stage, e.g. "Compute merges"
+	Current int    // number of items (sequences, merges...) processed
+	Total   int    // total number of items (possibly estimated), 0 if unknown
+}
+
+// ProgressTrainer is a Trainer able to report its progress to a function,
+// see `Tokenizer.TrainFromIterator`.
+type ProgressTrainer interface {
+	Trainer
+	// SetProgressFunc sets the function called on progress, nil to unset it.
+	// It is called for every item processed and should be fast.
+	SetProgressFunc(fn func(Progress))
+}
+
 // Implement methods for `Token`
 // NewToken generate new token from input data
 func NewToken(id int, value string, offsets []int) Token {
@@ -185,16 +238,33 @@ const (
 	PretokenizedInput
 	PretokenizedOwnedInput
 	PretokenizedCowInput
+	PretokenizedStringInput
 )
 
 type InputSequence struct {
-	input     []string
-	inputType InputType
+	input        []string
+	pretokenized []PreTokenizedString
+	inputType    InputType
 }
 
 // NewInputSequence creates a new InputSequence from input
-// A valid input can be a string type (RawInput) or slice of string (PretokenizedInput)
+// A valid input can be a string type (RawInput), slice of string (PretokenizedInput)
+// or slice of PreTokenizedString (PretokenizedStringInput).
+//
+// Each word of a PretokenizedInput is still normalized and pre-tokenized
+// (ie. ByteLevel needs it to map bytes), whereas each PreTokenizedString of a
+// PretokenizedStringInput is tokenized by the model as is: its splits are
+// neither normalized nor pre-tokenized again.
+//
+// In both cases, tokens get the index of their word in the input as word
+// index, and their offsets are relative to their word.
 func NewInputSequence(input interface{}) (retVal InputSequence) {
+	if words, ok := input.([]PreTokenizedString); ok {
+		return InputSequence{
+			pretokenized: words,
+			inputType:    PretokenizedStringInput,
+		}
+	}
 
 	switch reflect.TypeOf(input).Kind().String() {
 	case "string":
@@ -235,9 +305,51 @@ func NewDualEncodeInput(sentence, pairSentence InputSequence) (retVal EncodeInpu
 	return Dual{sentence, pairSentence}
 }
 
+// ErrInputTooLong is returned, wrapped, when an input sequence is longer than
+// the limit set with `Tokenizer.WithMaxInputChars`.
+var ErrInputTooLong = errors.New("input too long")
+
+// SpecialTokensMap names the special tokens of a tokenizer, as transformers
+// `special_tokens_map.json` does. Empty fields are not set.
+type SpecialTokensMap struct {
+	BosToken                string
+	EosToken                string
+	UnkToken                string
+	PadToken                string
+	ClsToken                string
+	SepToken                string
+	MaskToken               string
+	AdditionalSpecialTokens []string
+}
+
+// MaxInputStrategy is how input sequences longer than the limit set with
+// `Tokenizer.WithMaxInputChars` are handled.
+type MaxInputStrategy int
+
+const (
+	// RejectLongInput fails encoding with `ErrInputTooLong`.
+	RejectLongInput MaxInputStrategy = iota
+	// ChunkLongInput encodes the input in chunks of at most the limit, cut
+	// after a whitespace when possible, and merges them back. No token spans
+	// 2 chunks, so a word cut in the middle is tokenized as 2 words.
+	ChunkLongInput
+)
+
 // Tokenizer represents a tokenization pipeline.
 // It can implement any encoding or decoding of any text.
+//
+// A Tokenizer is safe for concurrent use once configured: encoding and
+// decoding methods can be called from multiple goroutines, as well as
+// `AddTokens` and `AddSpecialTokens`. The `WithX` setters, `Train` and the
+// components themselves (e.g. setters of a pre-tokenizer) must not be used
+// concurrently with encoding.
 type Tokenizer struct {
+	// Number of tokens whose char offsets were clamped to the input (see
+	// `ClampedOffsets`).
+	// NOTE. first field, as it is accessed atomically and must be 64-bit
+	// aligned on 32-bit platforms.
+	clampedOffsets int64
+
 	// Parts
 	normalizer    normalizer.Normalizer // optional
 	preTokenizer  PreTokenizer          // optional
@@ -251,6 +363,40 @@ type Tokenizer struct {
 	// General processing parameters
 	trunc   *TruncationParams // optional
 	padding *PaddingParams    // optional
+
+	// Whether to attach the normalized input to the encodings (see `Encoding.Normalized`)
+	attachNormalized bool
+
+	// Referential of the encoding offsets: the original input (default) or
+	// the normalized one.
+	offsetsReferential normalizer.IndexOn
+
+	// Unit of the encoding offsets of `Encode`: bytes (default) or chars.
+	offsetType OffsetType
+
+	// Whether alignments are not tracked while normalizing (see `WithAlignments`)
+	noAlignments bool
+
+	// Whether a leading byte order mark is kept (see `WithStripBOM`)
+	keepBOM bool
+
+	// Number of goroutines used by `EncodeBatch` and `DecodeBatch`.
+	// Zero or less means `runtime.NumCPU()`.
+	batchWorkers int
+
+	// Whether `Decode` cleans up tokenization spaces (see `CleanUpTokenization`)
+	cleanUpTokenizationSpaces bool
+
+	// Maximum number of chars of an input sequence, no limit if zero (see
+	// `WithMaxInputChars`)
+	maxInputChars    int
+	maxInputStrategy MaxInputStrategy
+
+	// Named special tokens (see `WithSpecialTokensMap`)
+	specialTokensMap SpecialTokensMap
+
+	// Maximum number of tokens of the model inputs, unknown if zero
+	modelMaxLength int
 }
 
 // Implementing methods for Tokenizer
@@ -307,6 +453,9 @@ func (t *Tokenizer) GetModel() Model {
 	return t.model
 }
 
+// WithTruncation sets the truncation applied by `Encode` and `EncodeBatch`,
+// before post-processing. `MaxLength` accounts for the special tokens added
+// by the post-processor. A nil value disables truncation.
 func (t *Tokenizer) WithTruncation(trunc *TruncationParams) {
 	t.trunc = trunc
 }
@@ -315,6 +464,9 @@ func (t *Tokenizer) GetTruncation() *TruncationParams {
 	return t.trunc
 }
 
+// WithPadding sets the padding applied by `Encode` and `EncodeBatch`, after
+// post-processing. With the `BatchLongest` strategy, `EncodeBatch` pads all
+// encodings to the longest one. A nil value disables padding.
 func (t *Tokenizer) WithPadding(padding *PaddingParams) {
 	t.padding = padding
 }
@@ -323,47 +475,194 @@ func (t *Tokenizer) GetPadding() (retVal *PaddingParams) {
 	return t.padding
 }
 
-// GetVocab get the vocabulary
+// WithAttachNormalized sets whether `Encode` attaches the normalized input
+// sequences to the returned encoding.
+func (t *Tokenizer) WithAttachNormalized(attach bool) {
+	t.attachNormalized = attach
+}
+
+func (t *Tokenizer) GetAttachNormalized() bool {
+	return t.attachNormalized
+}
+
+// WithOffsetsReferential sets whether `Encoding.Offsets` refer to the original
+// input (`normalizer.OriginalTarget`, the default) or to the normalized one
+// (`normalizer.NormalizedTarget`).
+//
+// Original offsets are what span extraction (NER, QA, ...) needs to slice the
+// input text. Normalized offsets index into the normalized input as attached
+// with `WithAttachNormalized`.
+func (t *Tokenizer) WithOffsetsReferential(ref normalizer.IndexOn) {
+	t.offsetsReferential = ref
+}
+
+// GetOffsetsReferential returns the referential of the encoding offsets.
+func (t *Tokenizer) GetOffsetsReferential() normalizer.IndexOn {
+	return t.offsetsReferential
+}
+
+// WithOffsetType sets whether `Encode` (and so `EncodeBatch`, `EncodeSingle`
+// and `EncodePair`) reports offsets as byte positions (`Byte`, the default) or
+// as char positions (`Char`) in the input. `EncodeCharOffsets` always reports
+// char positions.
+func (t *Tokenizer) WithOffsetType(offsetType OffsetType) {
+	t.offsetType = offsetType
+}
+
+// GetOffsetType returns the unit of the offsets reported by `Encode`.
+func (t *Tokenizer) GetOffsetType() OffsetType {
+	return t.offsetType
+}
+
+// WithAlignments sets whether the alignments between the original and the
+// normalized input are tracked (the default). Disabling them speeds up
+// throughput-only workloads (ie. ids for training or inference): encoding
+// offsets are then byte offsets on the normalized input, whatever the offsets
+// referential and type.
+func (t *Tokenizer) WithAlignments(enabled bool) {
+	t.noAlignments = !enabled
+}
+
+// GetAlignments returns whether alignments are tracked.
+func (t *Tokenizer) GetAlignments() bool {
+	return !t.noAlignments
+}
+
+// WithStripBOM sets whether a leading byte order mark (U+FEFF) is removed
+// from input sequences before normalization (the default). Offsets are still
+// expressed on the original input, ie. the first real character starts at
+// byte 3.
+func (t *Tokenizer) WithStripBOM(strip bool) {
+	t.keepBOM = !strip
+}
+
+// GetStripBOM returns whether a leading byte order mark is removed.
+func (t *Tokenizer) GetStripBOM() bool {
+	return !t.keepBOM
+}
+
+// WithBatchWorkers sets the number of goroutines used by `EncodeBatch` and
+// `DecodeBatch`. Zero or less means `runtime.NumCPU()`.
+func (t *Tokenizer) WithBatchWorkers(n int) {
+	t.batchWorkers = n
+}
+
+// GetBatchWorkers returns the number of goroutines used for batch processing.
+func (t *Tokenizer) GetBatchWorkers() int {
+	if t.batchWorkers <= 0 {
+		return runtime.NumCPU()
+	}
+	return t.batchWorkers
+}
+
+// WithMaxInputChars limits each input sequence to n chars. The limit is
+// checked before normalization, so that pathologically long untrusted inputs
+// blow up neither memory nor latency when serving. Longer inputs are either
+// rejected with `ErrInputTooLong` (`RejectLongInput`, the default) or encoded
+// in chunks of at most n chars (`ChunkLongInput`). Zero or less means no
+// limit.
+func (t *Tokenizer) WithMaxInputChars(n int, strategyOpt ...MaxInputStrategy) {
+	t.maxInputChars = n
+	t.maxInputStrategy = RejectLongInput
+	if len(strategyOpt) > 0 {
+		t.maxInputStrategy = strategyOpt[0]
+	}
+}
+
+// GetMaxInputChars returns the limit of chars of an input sequence and how
+// longer ones are handled.
+func (t *Tokenizer) GetMaxInputChars() (int, MaxInputStrategy) {
+	return t.maxInputChars, t.maxInputStrategy
+}
+
+// WithSpecialTokensMap sets the named special tokens, e.g. loaded from
+// `special_tokens_map.json`. They are looked up by name only: to be handled as
+// special tokens when encoding, they must be added with `AddSpecialTokens`.
+func (t *Tokenizer) WithSpecialTokensMap(m SpecialTokensMap) {
+	t.specialTokensMap = m
+}
+
+// GetSpecialTokensMap returns the named special tokens.
+func (t *Tokenizer) GetSpecialTokensMap() SpecialTokensMap {
+	return t.specialTokensMap
+}
+
+// WithModelMaxLength sets the maximum number of tokens the model takes as
+// input, as transformers `model_max_length` does. It is informative only: use
+// `WithTruncation` to truncate encodings. Zero or less means unknown.
+func (t *Tokenizer) WithModelMaxLength(n int) {
+	t.modelMaxLength = n
+}
+
+// GetModelMaxLength returns the maximum number of tokens the model takes as
+// input, or zero if unknown.
+func (t *Tokenizer) GetModelMaxLength() int {
+	if t.modelMaxLength < 0 {
+		return 0
+	}
+	return t.modelMaxLength
+}
+
+// WithCleanUpTokenizationSpaces sets whether `Decode` and `DecodeBatch` clean
+// up the spaces introduced by tokenization, as transformers
+// `clean_up_tokenization_spaces` does (see `CleanUpTokenization`).
+func (t *Tokenizer) WithCleanUpTokenizationSpaces(cleanUp bool) {
+	t.cleanUpTokenizationSpaces = cleanUp
+}
+
+// GetCleanUpTokenizationSpaces returns whether decoding cleans up
+// tokenization spaces.
+func (t *Tokenizer) GetCleanUpTokenizationSpaces() bool {
+	return t.cleanUpTokenizationSpaces
+}
+
+// GetVocab returns a copy of the model vocabulary, with the added tokens if
+// withAddedTokens is true.
 func (t *Tokenizer) GetVocab(withAddedTokens bool) map[string]int {
-	finalVocab := t.model.GetVocab()
+	modelVocab := t.model.GetVocab()
+	finalVocab := make(map[string]int, len(modelVocab))
+	for k, v := range modelVocab {
+		finalVocab[k] = v
+	}
 	if withAddedTokens {
-		addedVocab := t.addedVocabulary.GetVocab()
-		if len(addedVocab) > 0 {
-			for k, v := range addedVocab {
-				finalVocab[k] = v
-			}
+		for k, v := range t.addedVocabulary.GetVocab() {
+			finalVocab[k] = v
 		}
 	}
 
 	return finalVocab
 }
 
-// GetVocabSize get the size of vocabulary
+// GetVocabSize returns the size of the vocabulary. Added tokens that are
+// already part of the model vocabulary are counted once.
 func (t *Tokenizer) GetVocabSize(withAddedTokens bool) int {
 	if !withAddedTokens {
 		return t.model.GetVocabSize()
 	}
 
-	return t.model.GetVocabSize() + t.addedVocabulary.Len()
+	return len(t.GetVocab(true))
 }
 
 // GetSpecialTokens returns a slice of special tokens.
 func (t *Tokenizer) GetSpecialTokens() []string {
 	var tokens []string
-	for k := range t.addedVocabulary.specialTokensSet {
-		tokens = append(tokens, k)
+	special, _ := t.addedVocabulary.tokens()
+	for _, tok := range special {
+		tokens = append(tokens, tok.Content)
 	}
 
 	return tokens
 }
 
-// TokenToId converts a token to a corresponding id
+// TokenToId converts a token to a corresponding id, looking up the added
+// tokens first, then the model vocabulary.
 func (t *Tokenizer) TokenToId(token string) (id int, ok bool) {
 	id, ok = t.addedVocabulary.TokenToId(token, t.model)
 	return id, ok
 }
 
-// IdToToken converts an Id to a corresponding token
+// IdToToken converts an Id to a corresponding token, looking up the added
+// tokens first, then the model vocabulary.
 func (t *Tokenizer) IdToToken(id int) (token string, ok bool) {
 	token, ok = t.addedVocabulary.IdToToken(id, t.model)
 	return token, ok
@@ -371,9 +670,46 @@ func (t *Tokenizer) IdToToken(id int) (token string, ok bool) {
 
 // EncodeSingleSequence encodes a single sequence
 func (t *Tokenizer) EncodeSingleSequence(sequence InputSequence, typeId int, offsetType OffsetType) (*Encoding, error) {
+	return t.encodeSingleSequence(sequence, typeId, offsetType, nil)
+}
+
+// encodeSingleSequence is `EncodeSingleSequence`, writing the encoding of a
+// raw input into dst if not nil.
+func (t *Tokenizer) encodeSingleSequence(sequence InputSequence, typeId int, offsetType OffsetType, dst *Encoding) (*Encoding, error) {
+
+	tokenize := func(pretokenized *PreTokenizedString, wordIdx int, subseq string, dst *Encoding) (*Encoding, error) {
+		if t.noAlignments {
+			// offsets are already on the normalized input.
+			subseqEncoding, err := t.doTokenize(pretokenized, typeId, wordIdx, Byte, dst)
+			if err != nil {
+				return nil, err
+			}
+			return t.markSpecialTokens(subseqEncoding), nil
+		}
 
-	encode := func(isPreTokenized bool, subseqIdx int, subseq string) (*Encoding, error) {
-		normalized := t.addedVocabulary.ExtractAndNormalize(subseq, t.normalizer)
+		if t.offsetsReferential == normalizer.NormalizedTarget {
+			// offsets are converted to chars once in the normalized referential.
+			subseqEncoding, err := t.doTokenize(pretokenized, typeId, wordIdx, Byte, dst)
+			if err != nil {
+				return nil, err
+			}
+			err = t.toNormalizedOffsets(subseqEncoding, subseq, offsetType)
+			if err != nil {
+				return nil, err
+			}
+			return t.markSpecialTokens(subseqEncoding), nil
+		}
+
+		subseqEncoding, err := t.doTokenize(pretokenized, typeId, wordIdx, offsetType, dst)
+		if err != nil {
+			return nil, err
+		}
+
+		return t.markSpecialTokens(subseqEncoding), nil
+	}
+
+	encode := func(isPreTokenized bool, subseqIdx int, subseq string, dst *Encoding) (*Encoding, error) {
+		normalized := t.addedVocabulary.extractAndNormalize(subseq, t.normalizer, !t.noAlignments, !t.keepBOM)
 		var (
 			pretokenized *PreTokenizedString = normalized
 			err          error
@@ -386,40 +722,90 @@ func (t *Tokenizer) EncodeSingleSequence(sequence InputSequence, typeId int, off
 			}
 		}
 
-		// fmt.Printf("============doPreTokenize result:=================================== \n")
-		// for i, s := range pretokenized.splits {
-		// fmt.Printf("%v - normalized: %+v - tokens: %+v\n", i, s.normalized, s.tokens)
-		// }
-
 		wordIdx := -1
 		if isPreTokenized {
 			wordIdx = subseqIdx
 		}
 
-		subseqEncoding, err := t.doTokenize(pretokenized, typeId, wordIdx, offsetType)
+		return tokenize(pretokenized, wordIdx, subseq, dst)
+	}
+
+	// encodeLimited encodes subseq as `encode` does, enforcing the limit of
+	// `WithMaxInputChars` first.
+	encodeLimited := func(isPreTokenized bool, subseqIdx int, subseq string, dst *Encoding) (*Encoding, error) {
+		if t.maxInputChars <= 0 || len(subseq) <= t.maxInputChars {
+			return encode(isPreTokenized, subseqIdx, subseq, dst)
+		}
+		n := utf8.RuneCountInString(subseq)
+		if n <= t.maxInputChars {
+			return encode(isPreTokenized, subseqIdx, subseq, dst)
+		}
+		if t.maxInputStrategy != ChunkLongInput {
+			return nil, fmt.Errorf("EncodeSingleSequence failed: %w (%v chars, limit %v)", ErrInputTooLong, n, t.maxInputChars)
+		}
+
+		en := DefaultEncoding()
+		var shift, nextWord int
+		for _, chunk := range chunkInput(subseq, t.maxInputChars) {
+			chunkEn, err := encode(isPreTokenized, subseqIdx, chunk, nil)
+			if err != nil {
+				return nil, err
+			}
+			for _, o := range chunkEn.Offsets {
+				o[0] += shift
+				o[1] += shift
+			}
+			// Words are counted from zero in each chunk of a raw input.
+			if !isPreTokenized && len(chunkEn.Words) > 0 {
+				for i := range chunkEn.Words {
+					chunkEn.Words[i] += nextWord
+				}
+				nextWord = chunkEn.Words[len(chunkEn.Words)-1] + 1
+			}
+			en.MergeWith(chunkEn, false)
 
-		// fmt.Printf("==========doTokenizer result: =====================\n")
-		// fmt.Printf("encoding: %+v\n", subseqEncoding)
+			l, err := t.offsetsLen(chunk, offsetType)
+			if err != nil {
+				return nil, err
+			}
+			shift += l
+		}
 
-		return subseqEncoding, err
+		return en, nil
 	}
 
 	var encodings []Encoding
 	switch {
 	case sequence.inputType == PretokenizedInput, sequence.inputType == PretokenizedCowInput, sequence.inputType == PretokenizedOwnedInput:
 		for i, subseq := range sequence.input {
-			en, err := encode(true, i, subseq)
+			en, err := encodeLimited(true, i, subseq, nil)
 			if err != nil {
 				return nil, err
 			}
 			encodings = append(encodings, *en)
 		}
 	case sequence.inputType == RawInput:
-		en, err := encode(false, 0, sequence.input[0])
+		en, err := encodeLimited(false, 0, sequence.input[0], dst)
 		if err != nil {
 			return nil, err
 		}
+		if en == dst {
+			// Already the final encoding, no need to merge it into a new one.
+			// NOTE. `Merge` leaves no overflowing encodings as nil.
+			en.Overflowing = nil
+			return en, nil
+		}
 		encodings = append(encodings, *en)
+	case sequence.inputType == PretokenizedStringInput:
+		for i := range sequence.pretokenized {
+			// NOTE. a copy, tokenizing replaces its splits.
+			pretokenized := sequence.pretokenized[i]
+			en, err := tokenize(&pretokenized, i, pretokenized.original, nil)
+			if err != nil {
+				return nil, err
+			}
+			encodings = append(encodings, *en)
+		}
 
 	default:
 		log.Fatalf("EncodingSingleSequence method call: invalid InputType\n")
@@ -431,28 +817,116 @@ func (t *Tokenizer) EncodeSingleSequence(sequence InputSequence, typeId int, off
 	return finalEncoding, nil
 }
 
+// chunkInput splits s into chunks of at most maxChars chars, each one cut
+// after its last whitespace if any.
+func chunkInput(s string, maxChars int) []string {
+	var chunks []string
+	for len(s) > 0 {
+		end, cut := len(s), 0
+		for i, n := 0, 0; i < len(s); n++ {
+			if n == maxChars {
+				end = i
+				break
+			}
+			r, size := utf8.DecodeRuneInString(s[i:])
+			i += size
+			if unicode.IsSpace(r) {
+				cut = i
+			}
+		}
+		if end < len(s) && cut > 0 {
+			end = cut
+		}
+		chunks = append(chunks, s[:end])
+		s = s[end:]
+	}
+
+	return chunks
+}
+
+// offsetsLen returns the length of sequence in the unit and referential of
+// the offsets of `EncodeSingleSequence`.
+func (t *Tokenizer) offsetsLen(sequence string, offsetType OffsetType) (int, error) {
+	if t.noAlignments || t.offsetsReferential == normalizer.NormalizedTarget {
+		normalized, err := t.doNormalize(sequence)
+		if err != nil {
+			return 0, err
+		}
+		sequence = normalized.GetNormalized()
+	}
+	if offsetType == Char && !t.noAlignments {
+		return utf8.RuneCountInString(sequence), nil
+	}
+
+	return len(sequence), nil
+}
+
+// markSpecialTokens sets the special tokens mask of special added tokens.
+func (t *Tokenizer) markSpecialTokens(encoding *Encoding) *Encoding {
+	for i, id := range encoding.Ids {
+		if t.addedVocabulary.isSpecialId(id) {
+			encoding.SpecialTokenMask[i] = 1
+		}
+	}
+
+	return encoding
+}
+
+// toNormalizedOffsets converts the byte offsets of encoding, relative to the
+// original sequence, to offsets relative to the normalized sequence.
+func (t *Tokenizer) toNormalizedOffsets(encoding *Encoding, sequence string, offsetType OffsetType) error {
+	normalized, err := t.doNormalize(sequence)
+	if err != nil {
+		return err
+	}
+
+	var converter *BytesToCharOffsetConverter
+	if offsetType == Char {
+		converter = NewBytesToCharOffsetConverter(normalized.GetNormalized())
+	}
+
+	for i, o := range encoding.Offsets {
+		offsets := o
+		if r := normalized.ConvertOffset(normalizer.NewRange(o[0], o[1], normalizer.OriginalTarget)); r != nil {
+			offsets = []int{r.Start(), r.End()}
+		}
+		if converter != nil {
+			offsets, _ = converter.ConvertClamp(offsets)
+		}
+		encoding.Offsets[i] = offsets
+	}
+
+	return nil
+}
+
 // Encode the given input. This method accepts both single sequences, as well as pair
 // sequences. Also, a sequence can be a string, or already pre-tokenized input directly:
 func (t *Tokenizer) Encode(input EncodeInput, addSpecialTokens bool) (retVal *Encoding, err error) {
+	return t.encode(input, addSpecialTokens, nil)
+}
+
+// encode is `Encode`, writing the encoding of the first sequence into dst if
+// not nil. The returned encoding may be dst or another one.
+func (t *Tokenizer) encode(input EncodeInput, addSpecialTokens bool, dst *Encoding) (retVal *Encoding, err error) {
 	var encoding, pairEncoding *Encoding
 
 	// Encode and Postprocess
 	switch reflect.TypeOf(input).Name() {
 	case "Single":
 		seq := input.(Single).Sentence
-		encoding, err = t.EncodeSingleSequence(seq, 0, Byte)
+		encoding, err = t.encodeSingleSequence(seq, 0, t.offsetType, dst)
 		if err != nil {
 			return retVal, err
 		}
 
 	case "Dual":
 		seq := input.(Dual).Sentence
-		encoding, err = t.EncodeSingleSequence(seq, 0, Byte)
+		encoding, err = t.encodeSingleSequence(seq, 0, t.offsetType, dst)
 		if err != nil {
 			return retVal, err
 		}
 		pairSeq := input.(Dual).Pair
-		pairEncoding, err = t.EncodeSingleSequence(pairSeq, 1, Byte)
+		pairEncoding, err = t.EncodeSingleSequence(pairSeq, 1, t.offsetType)
 		if err != nil {
 			return retVal, err
 		}
@@ -461,7 +935,43 @@ func (t *Tokenizer) Encode(input EncodeInput, addSpecialTokens bool) (retVal *En
 		log.Fatalf("Invalid input type - '%v'. \n", reflect.TypeOf(input).Name())
 	}
 
-	return t.PostProcess(encoding, pairEncoding, addSpecialTokens), nil
+	finalEncoding, err := t.PostProcess(encoding, pairEncoding, addSpecialTokens)
+	if err != nil {
+		return nil, err
+	}
+	if t.attachNormalized {
+		err = t.doAttachNormalized(finalEncoding, input)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return finalEncoding, nil
+}
+
+// EncodeInto encodes the given input as `Encode` does, but writes the result
+// into enc, reusing its slices instead of allocating new ones. Along with
+// `GetEncoding` and `PutEncoding`, it reduces the allocations retained by
+// encodings when serving many requests:
+//
+//	enc := tokenizer.GetEncoding()
+//	defer tokenizer.PutEncoding(enc)
+//	err := tk.EncodeInto(input, enc, true)
+//
+// The tokens of a raw input are written directly into the slices of enc.
+// NOTE. Normalization, pre-tokenization and the model still allocate their
+// own results, as do the steps growing the encoding (special tokens, padding,
+// pairs), in which case enc takes the slices they allocated.
+func (t *Tokenizer) EncodeInto(input EncodeInput, enc *Encoding, addSpecialTokens bool) error {
+	en, err := t.encode(input, addSpecialTokens, enc)
+	if err != nil {
+		return err
+	}
+	if en != enc {
+		*enc = *en
+	}
+
+	return nil
 }
 
 // EncodeCharOffsets encodes the given input, using offsets relative to chars instead of bytes.
@@ -498,12 +1008,55 @@ func (t *Tokenizer) EncodeCharOffsets(input EncodeInput, addSpecialTokens bool)
 		log.Fatalf("Invalid input type - '%v'. \n", reflect.TypeOf(input).Name())
 	}
 
-	return t.PostProcess(encoding, pairEncoding, addSpecialTokens), nil
+	finalEncoding, err := t.PostProcess(encoding, pairEncoding, addSpecialTokens)
+	if err != nil {
+		return nil, err
+	}
+	if t.attachNormalized {
+		err = t.doAttachNormalized(finalEncoding, input)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return finalEncoding, nil
+}
+
+// doAttachNormalized normalizes each raw input sequence and attaches it to the
+// encoding. Pre-tokenized sequences get a `nil` NormalizedString.
+func (t *Tokenizer) doAttachNormalized(encoding *Encoding, input EncodeInput) error {
+	var sequences []InputSequence
+	switch input := input.(type) {
+	case Single:
+		sequences = []InputSequence{input.Sentence}
+	case Dual:
+		sequences = []InputSequence{input.Sentence, input.Pair}
+	}
+
+	encoding.Normalized = nil
+	for _, seq := range sequences {
+		if seq.inputType != RawInput {
+			encoding.Normalized = append(encoding.Normalized, nil)
+			continue
+		}
+		normalized, err := t.doNormalize(seq.input[0])
+		if err != nil {
+			return err
+		}
+		encoding.Normalized = append(encoding.Normalized, normalized)
+	}
+
+	return nil
 }
 
 // Decode decodes the given ids, back to a String
 func (t *Tokenizer) Decode(ids []int, skipSpecialTokens bool) (retVal string) {
+	return t.decode(ids, skipSpecialTokens, t.cleanUpTokenizationSpaces)
+}
 
+// decode decodes ids as `Decode` does, cleaning up tokenization spaces if
+// cleanUp is set.
+func (t *Tokenizer) decode(ids []int, skipSpecialTokens, cleanUp bool) (retVal string) {
 	var tokens []string
 	for _, id := range ids {
 		if tok, ok := t.addedVocabulary.IdToToken(id, t.model); ok {
@@ -514,10 +1067,42 @@ func (t *Tokenizer) Decode(ids []int, skipSpecialTokens bool) (retVal string) {
 	}
 
 	if t.decoder != nil {
-		return (t.decoder).Decode(tokens)
+		retVal = (t.decoder).Decode(tokens)
+	} else {
+		retVal = strings.Join(tokens, " ")
+	}
+
+	if cleanUp {
+		retVal = CleanUpTokenization(retVal)
+	}
+
+	return retVal
+}
+
+// cleanUpReplacements are the spaces tokenization leaves before punctuation
+// and English contractions, replaced in order.
+var cleanUpReplacements = [][2]string{
+	{" .", "."},
+	{" ?", "?"},
+	{" !", "!"},
+	{" ,", ","},
+	{" ' ", "'"},
+	{" n't", "n't"},
+	{" 'm", "'m"},
+	{" 's", "'s"},
+	{" 've", "'ve"},
+	{" 're", "'re"},
+}
+
+// CleanUpTokenization removes the spaces tokenization leaves before
+// punctuation and English contractions (e.g. "it 's fine ." becomes
+// "it's fine."), like transformers `clean_up_tokenization`.
+func CleanUpTokenization(s string) string {
+	for _, r := range cleanUpReplacements {
+		s = strings.ReplaceAll(s, r[0], r[1])
 	}
 
-	return strings.Join(tokens, " ")
+	return s
 }
 
 // AddSpecialTokens registers the given tokens as special tokens. This is especially useful for removing
@@ -535,6 +1120,9 @@ func (t *Tokenizer) AddTokens(tokens []AddedToken) (retVal int) {
 func (t *Tokenizer) doNormalize(s string) (retVal *normalizer.NormalizedString, err error) {
 
 	normalized := normalizer.NewNormalizedFrom(s)
+	if !t.keepBOM {
+		normalized.StripBOM()
+	}
 	if t.normalizer != nil {
 		normalized, err = (t.normalizer).Normalize(normalized)
 		if err != nil {
@@ -556,7 +1144,8 @@ func (t *Tokenizer) doPreTokenize(pretokenized *PreTokenizedString) (*PreTokeniz
 
 // doTokenize does Tokenization logic, makes the bridge between the pre-tokenization phase and the real
 // tokenization phase, and converting offsets back to the original referential.
-func (t *Tokenizer) doTokenize(pretokenized *PreTokenizedString, typeId int, wordIdx int, offsetType OffsetType) (*Encoding, error) {
+// The encoding is written into dst if not nil.
+func (t *Tokenizer) doTokenize(pretokenized *PreTokenizedString, typeId int, wordIdx int, offsetType OffsetType, dst *Encoding) (*Encoding, error) {
 
 	pretok, err := pretokenized.Tokenize(func(normalized *normalizer.NormalizedString) ([]Token, error) {
 		if t.model == nil {
@@ -574,11 +1163,24 @@ func (t *Tokenizer) doTokenize(pretokenized *PreTokenizedString, typeId int, wor
 	// fmt.Printf("%v - normalized: %+v - tokens: %+v\n", i, s.normalized, s.tokens)
 	// }
 
-	return pretok.IntoEncoding(typeId, wordIdx, offsetType)
+	en, nClamped, err := pretok.intoEncoding(dst, typeId, wordIdx, offsetType)
+	if nClamped > 0 {
+		atomic.AddInt64(&t.clampedOffsets, int64(nClamped))
+	}
+
+	return en, err
 }
 
-// PostProcess does post-processing logic, handling the case where there is no PostProcessor set
-func (t *Tokenizer) PostProcess(encoding, pairEncoding *Encoding, addSpecialTokens bool) (retVal *Encoding) {
+// ClampedOffsets returns the number of tokens encoded so far whose char
+// offsets were out of the input, and so clamped to it. Such offsets come from
+// models returning tokens that do not match the input.
+func (t *Tokenizer) ClampedOffsets() int64 {
+	return atomic.LoadInt64(&t.clampedOffsets)
+}
+
+// PostProcess does post-processing logic, handling the case where there is no PostProcessor set.
+// It truncates the encodings if needed, post-processes them and pads the result if needed.
+func (t *Tokenizer) PostProcess(encoding, pairEncoding *Encoding, addSpecialTokens bool) (retVal *Encoding, err error) {
 	var tEncoding, tPairEncoding *Encoding
 
 	// 1. Truncate if needed
@@ -594,14 +1196,21 @@ func (t *Tokenizer) PostProcess(encoding, pairEncoding *Encoding, addSpecialToke
 
 		if addSpecialTokens && nAddedTokens > 0 {
 			maxLength := trunc.MaxLength - nAddedTokens
+			if maxLength < 0 {
+				maxLength = 0
+			}
 			params := &TruncationParams{
-				MaxLength: maxLength,
-				Strategy:  trunc.Strategy,
-				Stride:    trunc.Stride,
+				MaxLength:      maxLength,
+				Strategy:       trunc.Strategy,
+				Stride:         trunc.Stride,
+				MaxOverflowing: trunc.MaxOverflowing,
 			}
-			tEncoding, tPairEncoding = TruncateEncodings(encoding, pairEncoding, params)
+			tEncoding, tPairEncoding, err = TruncateEncodings(encoding, pairEncoding, params)
 		} else {
-			tEncoding, tPairEncoding = TruncateEncodings(encoding, pairEncoding, trunc)
+			tEncoding, tPairEncoding, err = TruncateEncodings(encoding, pairEncoding, trunc)
+		}
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -616,47 +1225,37 @@ func (t *Tokenizer) PostProcess(encoding, pairEncoding *Encoding, addSpecialToke
 
 	// 3. Pad if needed
 	if t.padding == nil {
-		return finalEncoding
+		return finalEncoding, nil
 	}
 
-	var padEncodings []Encoding
-	encodings := []Encoding{*finalEncoding}
-	padEncodings = PadEncodings(encodings, *t.padding)
-	if len(padEncodings) == 1 {
-		return &padEncodings[0]
-	} else {
-		return padEncodings[0].Merge(padEncodings[1:], true)
-	}
+	padEncodings := PadEncodings([]Encoding{*finalEncoding}, *t.padding)
+
+	return &padEncodings[0], nil
 }
 
 // EncodeBatch encodes all sentences in concurrency
 func (t *Tokenizer) EncodeBatch(inputs []EncodeInput, addSpecialTokens bool) (retVal []Encoding, err error) {
 	var (
 		encodings []Encoding = make([]Encoding, len(inputs))
-		wg        sync.WaitGroup
-		mu        = &sync.Mutex{}
+		errs      []error    = make([]error, len(inputs))
 	)
 
-	wg.Add(len(inputs))
-
-	// Encoding concurrently
-	for i := 0; i < len(inputs); i++ {
-		go func(i int) {
-			defer wg.Done()
-
-			e, err := t.Encode(inputs[i], addSpecialTokens)
-			if err != nil {
-				log.Fatal(err)
-			}
-			mu.Lock()
-			encodings[i] = *e
-			mu.Unlock()
+	t.runBatch(len(inputs), func(i int) {
+		e, err := t.Encode(inputs[i], addSpecialTokens)
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		encodings[i] = *e
+	})
 
-		}(i)
+	for i, err := range errs {
+		if err != nil {
+			err = fmt.Errorf("Encoding input %v failed: %w", i, err)
+			return nil, err
+		}
 	}
 
-	wg.Wait()
-
 	// Do padding if included
 	if t.padding != nil {
 		encodings = PadEncodings(encodings, *t.padding)
@@ -665,27 +1264,44 @@ func (t *Tokenizer) EncodeBatch(inputs []EncodeInput, addSpecialTokens bool) (re
 	return encodings, nil
 }
 
-// DecodeBatch decodes all sentences in concurrency
+// DecodeBatch decodes all sentences in concurrency. The output is in the
+// same order as the input.
 func (t *Tokenizer) DecodeBatch(sentences [][]int, skipSpecialTokens bool) []string {
-	var decodings []string
-	var wg sync.WaitGroup
+	decodings := make([]string, len(sentences))
 
-	wg.Add(len(sentences))
+	t.runBatch(len(sentences), func(i int) {
+		decodings[i] = t.Decode(sentences[i], skipSpecialTokens)
+	})
 
-	// Decoding concurrently
-	for i := 0; i < len(sentences); i++ {
-		go func(i int) {
-			defer wg.Done()
+	return decodings
+}
 
-			s := t.Decode(sentences[i], skipSpecialTokens)
-			decodings = append(decodings, s)
+// runBatch calls fn for each index in [0, n) using a pool of
+// `GetBatchWorkers()` goroutines.
+func (t *Tokenizer) runBatch(n int, fn func(i int)) {
+	workers := t.GetBatchWorkers()
+	if workers > n {
+		workers = n
+	}
 
-		}(i)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
 	}
 
-	wg.Wait()
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
 
-	return decodings
+	wg.Wait()
 }
 
 // wordCount returns a map of word and its count
@@ -719,18 +1335,186 @@ func NewTokenizerFromFile(file string) (retVal *Tokenizer) {
 	return
 }
 
-// Serialize serializes current Tokenizer to string
-func (t *Tokenizer) Serialize(pretty bool) (retVal string) {
+// Serialize serializes current Tokenizer to HuggingFace `tokenizer.json`
+// format. All the pipeline components must implement `json.Marshaler`.
+func (t *Tokenizer) Serialize(pretty bool) (string, error) {
+	components := map[string]interface{}{
+		"normalizer":     t.normalizer,
+		"pre_tokenizer":  t.preTokenizer,
+		"model":          t.model,
+		"post_processor": t.postProcessor,
+		"decoder":        t.decoder,
+	}
+	for name, c := range components {
+		if c == nil || (reflect.ValueOf(c).Kind() == reflect.Ptr && reflect.ValueOf(c).IsNil()) {
+			components[name] = nil
+			continue
+		}
+		if _, ok := c.(json.Marshaler); !ok {
+			err := fmt.Errorf("Cannot serialize %v of type %T: it does not implement json.Marshaler", name, c)
+			return "", err
+		}
+	}
 
-	// TODO: implement
-	return
+	data := struct {
+		Version       string        `json:"version"`
+		Truncation    interface{}   `json:"truncation"`
+		Padding       interface{}   `json:"padding"`
+		AddedTokens   []TokenConfig `json:"added_tokens"`
+		Normalizer    interface{}   `json:"normalizer"`
+		PreTokenizer  interface{}   `json:"pre_tokenizer"`
+		Model         interface{}   `json:"model"`
+		PostProcessor interface{}   `json:"post_processor"`
+		Decoder       interface{}   `json:"decoder"`
+	}{
+		Version:       "1.0",
+		Truncation:    serializeTruncation(t.trunc),
+		Padding:       serializePadding(t.padding),
+		AddedTokens:   t.serializeAddedTokens(),
+		Normalizer:    components["normalizer"],
+		PreTokenizer:  components["pre_tokenizer"],
+		Model:         components["model"],
+		PostProcessor: components["post_processor"],
+		Decoder:       components["decoder"],
+	}
+
+	var (
+		out []byte
+		err error
+	)
+	if pretty {
+		out, err = json.MarshalIndent(data, "", "  ")
+	} else {
+		out, err = json.Marshal(data)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
+
+// Fingerprint returns a stable hash (hex encoded SHA-256) of the tokenizer
+// configuration: normalizer, pre-tokenizer, model (vocab, merges, ...), added
+// tokens, post-processor, decoder, truncation, padding, and the encoding and
+// decoding options (offsets, BOM, input limit, ...).
+// Tokenizers with the same fingerprint tokenize (and decode) identically, so
+// it can be used to check that deployments are consistent.
+//
+// It is computed from the `Serialize` output and fails as it does.
+func (t *Tokenizer) Fingerprint() (string, error) {
+	data, err := t.Serialize(false)
+	if err != nil {
+		err = fmt.Errorf("Fingerprint failed: %w", err)
+		return "", err
+	}
+
+	h := sha256.New()
+	io.WriteString(h, data)
+	// Options not part of `tokenizer.json`
+	fmt.Fprintf(h, "\nclean_up_tokenization_spaces: %v", t.GetCleanUpTokenizationSpaces())
+	fmt.Fprintf(h, "\nstrip_bom: %v", t.GetStripBOM())
+	fmt.Fprintf(h, "\nmax_input_chars: %v, %v", t.maxInputChars, t.maxInputStrategy)
+	fmt.Fprintf(h, "\noffsets: %v, %v, alignments: %v", t.offsetType, t.offsetsReferential, t.GetAlignments())
+	fmt.Fprintf(h, "\nattach_normalized: %v", t.attachNormalized)
+	if t.trunc != nil {
+		fmt.Fprintf(h, "\nmax_overflowing: %v", t.trunc.maxOverflowing())
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
-// Save saves the current tokenizer at the given path
-func (t *Tokenizer) Save(path string, pretty bool) (err error) {
+// Save saves the current tokenizer at the given path in HuggingFace
+// `tokenizer.json` format.
+func (t *Tokenizer) Save(path string, pretty bool) error {
+	data, err := t.Serialize(pretty)
+	if err != nil {
+		return err
+	}
 
-	// TODO: implement
-	return
+	return os.WriteFile(path, []byte(data), 0644)
+}
+
+func serializeTruncation(trunc *TruncationParams) interface{} {
+	if trunc == nil {
+		return nil
+	}
+
+	strategy := "LongestFirst"
+	switch trunc.Strategy {
+	case OnlyFirst:
+		strategy = "OnlyFirst"
+	case OnlySecond:
+		strategy = "OnlySecond"
+	}
+
+	return map[string]interface{}{
+		"direction":  "Right",
+		"max_length": trunc.MaxLength,
+		"strategy":   strategy,
+		"stride":     trunc.Stride,
+	}
+}
+
+func serializePadding(padding *PaddingParams) interface{} {
+	if padding == nil {
+		return nil
+	}
+
+	var strategy interface{} = "BatchLongest"
+	if padding.Strategy.Name == "Fixed" {
+		strategy = map[string]interface{}{"Fixed": padding.Strategy.Value}
+	}
+
+	direction := "Right"
+	if padding.Direction == Left {
+		direction = "Left"
+	}
+
+	var padToMultipleOf interface{}
+	if padding.PadToMultipleOf > 0 {
+		padToMultipleOf = padding.PadToMultipleOf
+	}
+
+	return map[string]interface{}{
+		"strategy":           strategy,
+		"direction":          direction,
+		"pad_to_multiple_of": padToMultipleOf,
+		"pad_id":             padding.PadId,
+		"pad_type_id":        padding.PadTypeId,
+		"pad_token":          padding.PadToken,
+	}
+}
+
+// serializeAddedTokens returns special and classic added tokens sorted by id.
+func (t *Tokenizer) serializeAddedTokens() []TokenConfig {
+	out := []TokenConfig{}
+	add := func(toks []AddedToken, special bool) {
+		for _, tok := range toks {
+			id, ok := t.TokenToId(tok.Content)
+			if !ok {
+				continue
+			}
+			out = append(out, TokenConfig{
+				Id:         int64(id),
+				Content:    tok.Content,
+				SingleWord: tok.SingleWord,
+				Lstrip:     tok.LStrip,
+				Rstrip:     tok.RStrip,
+				Normalized: tok.Normalized,
+				Special:    special,
+			})
+		}
+	}
+	special, added := t.addedVocabulary.tokens()
+	add(special, true)
+	add(added, false)
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Id < out[j].Id
+	})
+
+	return out
 }
 
 // Train trains a model and replaces the current model using a given trainer
@@ -853,6 +1637,71 @@ func (t *Tokenizer) Train(trainer Trainer, files []string) error {
 	return nil
 }
 
+// TrainFromIterator trains a model and replaces the current model like
+// `Train`, reading the training sequences from it until it returns false.
+// It lets training consume any source (databases, compressed archives...).
+//
+// If progress is not nil, it is called for every sequence counted, then on
+// progress of the trainer if it is a `ProgressTrainer`.
+func (t *Tokenizer) TrainFromIterator(it func() (string, bool), trainer Trainer, progress func(Progress)) error {
+	if pt, ok := trainer.(ProgressTrainer); ok && progress != nil {
+		pt.SetProgressFunc(progress)
+		defer pt.SetProgressFunc(nil)
+	}
+
+	words := make(map[string]int)
+	var n int
+	for {
+		sequence, ok := it()
+		if !ok {
+			break
+		}
+
+		tokens, err := t.trainingTokens(sequence)
+		if err != nil {
+			return fmt.Errorf("TrainFromIterator failed: %w", err)
+		}
+		trainer.ProcessTokens(words, tokens)
+
+		n++
+		if progress != nil {
+			progress(Progress{Stage: StageCountWords, Current: n})
+		}
+	}
+
+	model, specialTokens := trainer.Train(words)
+
+	// Replace with trained model
+	t.model = model
+	t.AddSpecialTokens(specialTokens)
+
+	return nil
+}
+
+// trainingTokens normalizes and pre-tokenizes a training sequence into the
+// tokens to be counted by the trainer.
+func (t *Tokenizer) trainingTokens(sequence string) ([]string, error) {
+	normalized, err := t.doNormalize(sequence)
+	if err != nil {
+		return nil, fmt.Errorf("call 'doNormalize' method error: %w", err)
+	}
+
+	pretok := NewPreTokenizedStringFromNS(normalized)
+	pretokenized, err := t.doPreTokenize(pretok)
+	if err != nil {
+		return nil, fmt.Errorf("call 'doPreTokenize' method error: %w", err)
+	}
+
+	// NOTE. should we get OffsetType as input parameter: either Byte or Char?
+	pretoks := pretokenized.GetSplits(normalizer.OriginalTarget, Byte)
+	var tokens []string
+	for _, pretok := range pretoks {
+		tokens = append(tokens, pretok.Value)
+	}
+
+	return tokens, nil
+}
+
 // processChunk reads file chunk and processes it to word-count and sends off to channel
 // offset: start bound
 // limit: end bound
@@ -896,22 +1745,9 @@ func (t *Tokenizer) processChunk(offset int64, limit int64, filename string, cha
 		 *   log.Fatalf("call 'Encode' method error: %v\n", err)
 		 * } */
 
-		normalized, err := t.doNormalize(line)
+		tokens, err := t.trainingTokens(line)
 		if err != nil {
-			log.Fatalf("call 'doNormalize' method error: %v\n", err)
-		}
-
-		pretok := NewPreTokenizedStringFromNS(normalized)
-		pretokenized, err := t.doPreTokenize(pretok)
-		if err != nil {
-			log.Fatalf("call 'doPreTokenize' method error: %v\n", err)
-		}
-
-		// NOTE. should we get OffsetType as input parameter: either Byte or Char?
-		pretoks := pretokenized.GetSplits(normalizer.OriginalTarget, Byte)
-		var tokens []string
-		for _, pretok := range pretoks {
-			tokens = append(tokens, pretok.Value)
+			log.Fatal(err)
 		}
 
 		/*
@@ -1085,7 +1921,9 @@ func (t *Tokenizer) EncodeSingle(input string, addSpecialTokensOpt ...bool) (*En
 	return t.Encode(encodeInput, addSpecialTokens)
 }
 
-// EncodePair encodes a pair of string sequences.
+// EncodePair encodes a pair of string sequences. Tokens of input get type id
+// 0 and tokens of pair type id 1 (unless the post-processor sets others),
+// then both are merged by the post-processor, or by DefaultProcess if none.
 //
 // Params:
 // - input: the sequence string to be tokenized
@@ -1124,3 +1962,322 @@ func (t *Tokenizer) Tokenize(input string, addSpecialTokensOpt ...bool) ([]strin
 
 	return en.Tokens, nil
 }
+
+// TokenFrequencies encodes all sequences of a corpus and counts occurrences
+// of each token id. Special tokens are not added.
+//
+// Params:
+// - it: iterator over the corpus. It returns the next sequence and `false`
+// when there are no more sequences.
+func (t *Tokenizer) TokenFrequencies(it func() (string, bool)) (map[int]int, error) {
+	freqs := make(map[int]int)
+	for {
+		sequence, ok := it()
+		if !ok {
+			break
+		}
+
+		en, err := t.EncodeSingle(sequence)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range en.Ids {
+			freqs[id] += 1
+		}
+	}
+
+	return freqs, nil
+}
+
+// PruneVocab shrinks the vocabulary to the tokens for which keep returns true
+// and remaps their ids contiguously. Added tokens are always kept and get new
+// ids after the model vocabulary. It returns the mapping from old to new ids
+// of the kept tokens.
+//
+// The model must implement `VocabPruner`. The padding id is remapped, but the
+// ids of the special tokens of the post-processor must be updated by the
+// caller using the returned mapping.
+func (t *Tokenizer) PruneVocab(keep func(token string, id int) bool) (map[int]int, error) {
+	pruner, ok := t.model.(VocabPruner)
+	if !ok {
+		return nil, fmt.Errorf("PruneVocab failed: model %T does not support vocabulary pruning.", t.model)
+	}
+
+	// Added tokens are added back in their id order, after pruning the model.
+	special, added := t.addedVocabulary.tokens()
+	tokens := make([]AddedToken, 0, len(special)+len(added))
+	tokens = append(tokens, special...)
+	tokens = append(tokens, added...)
+	oldIds := make(map[string]int, len(tokens))
+	for _, tok := range tokens {
+		oldIds[tok.Content], _ = t.TokenToId(tok.Content)
+	}
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return oldIds[tokens[i].Content] < oldIds[tokens[j].Content]
+	})
+
+	mapping, err := pruner.PruneVocab(func(token string, id int) bool {
+		_, isAdded := oldIds[token]
+		return isAdded || keep(token, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	t.addedVocabulary = NewAddedVocabulary()
+	t.addedVocabulary.AddTokens(tokens, t.model, t.normalizer)
+	for _, tok := range tokens {
+		newId, _ := t.TokenToId(tok.Content)
+		mapping[oldIds[tok.Content]] = newId
+	}
+
+	if t.padding != nil {
+		if padId, ok := mapping[t.padding.PadId]; ok {
+			t.padding.PadId = padId
+		}
+	}
+
+	return mapping, nil
+}
+
+// PruneVocabByFrequency shrinks the vocabulary to the tokens occurring at
+// least minFreq times in freqs (token id => count, see `TokenFrequencies`).
+// See `PruneVocab` for details.
+func (t *Tokenizer) PruneVocabByFrequency(freqs map[int]int, minFreq int) (map[int]int, error) {
+	return t.PruneVocab(func(token string, id int) bool {
+		return freqs[id] >= minFreq
+	})
+}
+
+// EncodeWithPieceIndex encodes a single sequence (without special tokens) and
+// returns, for each token, the index of the pre-tokenized piece it comes from.
+// It exposes how the model split each piece into sub-tokens.
+func (t *Tokenizer) EncodeWithPieceIndex(input string) (*Encoding, []int, error) {
+	en, err := t.EncodeSingle(input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// NOTE. words are indexes of the pre-tokenized pieces for raw input
+	// (see `PreTokenizedString.IntoEncoding`).
+	pieceIdxs := make([]int, len(en.Words))
+	copy(pieceIdxs, en.Words)
+
+	return en, pieceIdxs, nil
+}
+
+// EncodeReader encodes a (large) document read from `r` in chunks of about
+// `bufSize` bytes. Chunks are cut right before a whitespace so that words
+// are not split up. Offsets and word indexes of each chunk are shifted so
+// that they are absolute in the whole document, in the offset type and
+// referential of the tokenizer as with `Encode`.
+//
+// NOTE. The result is the same as encoding the whole document at once
+// as long as the normalizer and pre-tokenizer do not depend on the context
+// across whitespaces.
+func (t *Tokenizer) EncodeReader(r io.Reader, bufSize int, addSpecialTokens bool) (*Encoding, error) {
+	if bufSize <= 0 {
+		err := fmt.Errorf("Invalid bufSize (%v). It must be greater than zero.\n", bufSize)
+		return nil, err
+	}
+
+	var (
+		encodings []Encoding
+		pending   []byte // bytes read but not encoded yet
+		offset    int    // offset of `pending` in the document
+		wordShift int
+		buf       = make([]byte, bufSize)
+		eof       bool
+	)
+
+	encodeChunk := func(chunk string) error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		en, err := t.EncodeSingleSequence(NewInputSequence(chunk), 0, t.offsetType)
+		if err != nil {
+			return err
+		}
+
+		maxWord := -1
+		for i := range en.Offsets {
+			en.Offsets[i] = []int{en.Offsets[i][0] + offset, en.Offsets[i][1] + offset}
+		}
+		for i, w := range en.Words {
+			if w < 0 {
+				continue
+			}
+			en.Words[i] = w + wordShift
+			if en.Words[i] > maxWord {
+				maxWord = en.Words[i]
+			}
+		}
+		if maxWord >= 0 {
+			wordShift = maxWord + 1
+		}
+
+		encodings = append(encodings, *en)
+		l, err := t.offsetsLen(chunk, t.offsetType)
+		if err != nil {
+			return err
+		}
+		offset += l
+		return nil
+	}
+
+	for !eof {
+		n, err := r.Read(buf)
+		pending = append(pending, buf[:n]...)
+		switch {
+		case err == io.EOF:
+			eof = true
+		case err != nil:
+			return nil, err
+		}
+
+		if eof || len(pending) < bufSize {
+			continue
+		}
+
+		// Cut right before the last whitespace run
+		cut := lastWhitespaceRun(pending)
+		if cut <= 0 {
+			continue // no whitespace yet, keep reading
+		}
+
+		if err := encodeChunk(string(pending[:cut])); err != nil {
+			return nil, err
+		}
+		pending = append([]byte{}, pending[cut:]...)
+	}
+
+	if err := encodeChunk(string(pending)); err != nil {
+		return nil, err
+	}
+
+	encoding := DefaultEncoding()
+	encoding.Merge(encodings, false)
+
+	return t.PostProcess(encoding, nil, addSpecialTokens)
+}
+
+// StreamOpts are the options of `Tokenizer.EncodeStream`.
+type StreamOpts struct {
+	// Split splits the input into sequences. Default is `bufio.ScanLines`.
+	Split bufio.SplitFunc
+	// MaxSequenceSize is the maximum size in bytes of a sequence. Default is
+	// `bufio.MaxScanTokenSize`.
+	MaxSequenceSize int
+	// AddSpecialTokens is whether adding special tokens to the encodings.
+	AddSpecialTokens bool
+}
+
+// EncodeStream encodes the sequences read from `r`, one per line by default,
+// without loading the whole input in memory. Sequences are encoded
+// concurrently by `GetBatchWorkers()` goroutines and their encodings are sent
+// in input order.
+//
+// Both channels are closed when `r` is exhausted or at the first error, which
+// is then sent to the error channel. The encodings channel has to be drained.
+func (t *Tokenizer) EncodeStream(r io.Reader, opts StreamOpts) (<-chan Encoding, <-chan error) {
+	type result struct {
+		encoding *Encoding
+		err      error
+	}
+	type job struct {
+		idx      int
+		sequence string
+		result   chan result
+	}
+
+	var (
+		out     = make(chan Encoding)
+		errc    = make(chan error, 1)
+		workers = t.GetBatchWorkers()
+		jobs    = make(chan job)
+		pending = make(chan chan result, workers) // results in input order
+		done    = make(chan struct{})
+	)
+
+	for w := 0; w < workers; w++ {
+		go func() {
+			for j := range jobs {
+				input := NewSingleEncodeInput(NewInputSequence(j.sequence))
+				en, err := t.Encode(input, opts.AddSpecialTokens)
+				if err != nil {
+					err = fmt.Errorf("Encoding sequence %v failed: %w", j.idx, err)
+				}
+				j.result <- result{en, err}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		defer close(pending)
+
+		scanner := bufio.NewScanner(r)
+		if opts.Split != nil {
+			scanner.Split(opts.Split)
+		}
+		if opts.MaxSequenceSize > 0 {
+			scanner.Buffer(nil, opts.MaxSequenceSize)
+		}
+
+		for idx := 0; scanner.Scan(); idx++ {
+			res := make(chan result, 1)
+			select {
+			case pending <- res:
+			case <-done:
+				return
+			}
+			jobs <- job{idx, scanner.Text(), res}
+		}
+
+		if err := scanner.Err(); err != nil {
+			res := make(chan result, 1)
+			res <- result{err: err}
+			select {
+			case pending <- res:
+			case <-done:
+			}
+		}
+	}()
+
+	go func() {
+		defer close(out)
+		defer close(errc)
+		defer close(done)
+
+		for res := range pending {
+			r := <-res
+			if r.err != nil {
+				errc <- r.err
+				return
+			}
+			out <- *r.encoding
+		}
+	}()
+
+	return out, errc
+}
+
+// lastWhitespaceRun returns the byte index of the start of the last run of
+// whitespaces in `b`, or -1 if there's no whitespace.
+func lastWhitespaceRun(b []byte) int {
+	idx := -1
+	for len(b) > 0 {
+		r, size := utf8.DecodeLastRune(b)
+		isSpace := unicode.IsSpace(r)
+		switch {
+		case isSpace:
+			idx = len(b) - size
+		case idx != -1:
+			return idx
+		}
+		b = b[:len(b)-size]
+	}
+
+	return idx
+}
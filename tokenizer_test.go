@@ -0,0 +1,932 @@
+package tokenizer_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/decoder"
+	"github.com/sugarme/tokenizer/model"
+	"github.com/sugarme/tokenizer/model/bpe"
+	"github.com/sugarme/tokenizer/model/wordlevel"
+	"github.com/sugarme/tokenizer/model/wordpiece"
+	"github.com/sugarme/tokenizer/normalizer"
+	"github.com/sugarme/tokenizer/pretokenizer"
+	"github.com/sugarme/tokenizer/processor"
+)
+
+func TestTokenizer_StripBOM(t *testing.T) {
+	tk := newWordLevelTokenizer()
+
+	en, err := tk.EncodeSingle("\uFEFFhello world")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantToks := []string{"hello", "world"}
+	if !reflect.DeepEqual(wantToks, en.Tokens) {
+		t.Errorf("want %#v\ngot %#v\n", wantToks, en.Tokens)
+	}
+
+	wantOffsets := [][]int{{3, 8}, {9, 14}}
+	if !reflect.DeepEqual(wantOffsets, en.Offsets) {
+		t.Errorf("want %#v\ngot %#v\n", wantOffsets, en.Offsets)
+	}
+
+	// Opt out: BOM is kept and becomes part of the first word, which is then
+	// unknown to the vocab.
+	tk.WithStripBOM(false)
+
+	en, err = tk.EncodeSingle("\uFEFFhello world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantToks = []string{"[UNK]", "world"}
+	if !reflect.DeepEqual(wantToks, en.Tokens) {
+		t.Errorf("want %#v\ngot %#v\n", wantToks, en.Tokens)
+	}
+	wantOffsets = [][]int{{0, 8}, {9, 14}}
+	if !reflect.DeepEqual(wantOffsets, en.Offsets) {
+		t.Errorf("want %#v\ngot %#v\n", wantOffsets, en.Offsets)
+	}
+}
+
+func TestTokenizer_TokenFrequencies(t *testing.T) {
+	tk := newWordLevelTokenizer()
+
+	corpus := []string{
+		"hello world",
+		"hello how are you",
+		"good day world hello",
+	}
+	idx := 0
+	it := func() (string, bool) {
+		if idx >= len(corpus) {
+			return "", false
+		}
+		idx++
+		return corpus[idx-1], true
+	}
+
+	got, err := tk.TokenFrequencies(it)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// hello: 4, world: 5, day: 10
+	want := map[int]int{4: 3, 5: 2, 10: 1}
+	for id, count := range want {
+		if got[id] != count {
+			t.Errorf("id %v: want %v\ngot %v\n", id, count, got[id])
+		}
+	}
+}
+
+func TestTokenizer_AttachNormalized(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	tk.WithNormalizer(normalizer.Lowercase())
+
+	input := "Hello World"
+
+	en, err := tk.EncodeSingle(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if en.Normalized != nil {
+		t.Errorf("want no normalized string attached by default, got %v\n", en.Normalized)
+	}
+
+	tk.WithAttachNormalized(true)
+	en, err = tk.EncodeSingle(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(en.Normalized) != 1 {
+		t.Fatalf("want 1 normalized string, got %v\n", len(en.Normalized))
+	}
+
+	got := en.Normalized[0].GetOriginal()
+	if !reflect.DeepEqual(input, got) {
+		t.Errorf("want %#v\ngot %#v\n", input, got)
+	}
+
+	wantNormalized := "hello world"
+	gotNormalized := en.Normalized[0].GetNormalized()
+	if !reflect.DeepEqual(wantNormalized, gotNormalized) {
+		t.Errorf("want %#v\ngot %#v\n", wantNormalized, gotNormalized)
+	}
+
+	// Deep copied by Clone
+	cloned := en.Clone().Normalized[0]
+	if cloned == en.Normalized[0] || !reflect.DeepEqual(cloned, en.Normalized[0]) {
+		t.Errorf("want %#v\ngot %#v\n", en.Normalized[0], cloned)
+	}
+}
+
+func TestTokenizer_EncodeCharOffsets(t *testing.T) {
+	tk := newWordLevelTokenizer()
+
+	input := tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("Löwe 老虎 hello"))
+	en, err := tk.EncodeCharOffsets(input, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]int{{0, 4}, {5, 7}, {8, 13}}
+	if !reflect.DeepEqual(want, en.Offsets) {
+		t.Errorf("want %#v\ngot %#v\n", want, en.Offsets)
+	}
+}
+
+func TestTokenizer_EncodeWithPieceIndex(t *testing.T) {
+	vocab := model.Vocab{
+		"[UNK]":  0,
+		"the":    1,
+		"token":  2,
+		"##izer": 3,
+		"##s":    4,
+	}
+	m := wordpiece.NewWordPieceBuilder().Vocab(&vocab).UnkToken("[UNK]").Build()
+	tk := tokenizer.NewTokenizer(m)
+	tk.WithPreTokenizer(pretokenizer.NewWhitespaceSplit())
+
+	en, got, err := tk.EncodeWithPieceIndex("the tokenizers")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantToks := []string{"the", "token", "##izer", "##s"}
+	if !reflect.DeepEqual(wantToks, en.Tokens) {
+		t.Errorf("want %#v\ngot %#v\n", wantToks, en.Tokens)
+	}
+
+	want := []int{0, 1, 1, 1}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+}
+
+func TestTokenizer_EncodeReader(t *testing.T) {
+	tk := newWordLevelTokenizer()
+
+	doc := "hello world how are you\n good day  world, hello you how are\tyou good day"
+
+	want, err := tk.EncodeSingle(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Small buffer reading one byte at a time
+	r := iotest.OneByteReader(strings.NewReader(doc))
+	got, err := tk.EncodeReader(r, 8, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := len(want.Offsets) - 1
+	if !reflect.DeepEqual(want.Offsets[last], got.Offsets[len(got.Offsets)-1]) {
+		t.Errorf("want last offsets %v\ngot %v\n", want.Offsets[last], got.Offsets[len(got.Offsets)-1])
+	}
+
+	if !reflect.DeepEqual(want.Tokens, got.Tokens) {
+		t.Errorf("want %#v\ngot %#v\n", want.Tokens, got.Tokens)
+	}
+	if !reflect.DeepEqual(want.Offsets, got.Offsets) {
+		t.Errorf("want %#v\ngot %#v\n", want.Offsets, got.Offsets)
+	}
+	if !reflect.DeepEqual(want.Words, got.Words) {
+		t.Errorf("want %#v\ngot %#v\n", want.Words, got.Words)
+	}
+
+	// Char offsets on multibyte input, in both referentials, with a
+	// normalizer changing the length.
+	doc = "héllo wörld hello world ﬁ héllo"
+	for _, ref := range []normalizer.IndexOn{normalizer.OriginalTarget, normalizer.NormalizedTarget} {
+		tk := newWordLevelTokenizer()
+		tk.WithNormalizer(normalizer.NewNFKC())
+		tk.WithOffsetType(tokenizer.Char)
+		tk.WithOffsetsReferential(ref)
+
+		want, err := tk.EncodeSingle(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := tk.EncodeReader(iotest.OneByteReader(strings.NewReader(doc)), 8, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want.Offsets, got.Offsets) {
+			t.Errorf("referential %v: want %v\ngot %v\n", ref, want.Offsets, got.Offsets)
+		}
+		if !reflect.DeepEqual(want.Tokens, got.Tokens) {
+			t.Errorf("referential %v: want %q\ngot %q\n", ref, want.Tokens, got.Tokens)
+		}
+	}
+}
+
+func TestTokenizer_Serialize(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	tk.AddSpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[CLS]", true), tokenizer.NewAddedToken("[SEP]", true)})
+	tk.WithPadding(&tokenizer.PaddingParams{
+		Strategy:  *tokenizer.NewPaddingStrategy(tokenizer.WithFixed(8)),
+		Direction: tokenizer.Right,
+		PadId:     0,
+		PadToken:  "[PAD]",
+	})
+
+	data, err := tk.Serialize(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var config tokenizer.Config
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Model["type"] != "WordLevel" || config.PreTokenizer["type"] != "WhitespaceSplit" {
+		t.Errorf("unexpected model/pre_tokenizer: %v, %v\n", config.Model["type"], config.PreTokenizer["type"])
+	}
+	if config.Normalizer != nil || config.Truncation != nil {
+		t.Errorf("want null normalizer and truncation, got %v, %v\n", config.Normalizer, config.Truncation)
+	}
+	wantPadding := map[string]interface{}{"Fixed": float64(8)}
+	if !reflect.DeepEqual(wantPadding, config.Padding["strategy"]) {
+		t.Errorf("want %#v\ngot %#v\n", wantPadding, config.Padding["strategy"])
+	}
+	wantAdded := []tokenizer.TokenConfig{
+		{Id: 2, Content: "[CLS]", Special: true},
+		{Id: 3, Content: "[SEP]", Special: true},
+	}
+	if !reflect.DeepEqual(wantAdded, config.AddedTokens) {
+		t.Errorf("want %#v\ngot %#v\n", wantAdded, config.AddedTokens)
+	}
+
+	file := filepath.Join(t.TempDir(), "tokenizer.json")
+	if err := tk.Save(file, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTokenizer_EncodeBatch(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	tk.WithBatchWorkers(3)
+
+	sentences := []string{"hello world", "how are you", "good day", "hello", "you are good"}
+	var inputs []tokenizer.EncodeInput
+	for i := 0; i < 20; i++ {
+		s := sentences[i%len(sentences)]
+		inputs = append(inputs, tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence(s)))
+	}
+
+	got, err := tk.EncodeBatch(inputs, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(inputs) {
+		t.Fatalf("want %v encodings, got %v\n", len(inputs), len(got))
+	}
+	for i, input := range inputs {
+		want, err := tk.Encode(input, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want.Ids, got[i].Ids) {
+			t.Errorf("input %v: want %#v\ngot %#v\n", i, want.Ids, got[i].Ids)
+		}
+	}
+
+	var ids [][]int
+	for _, en := range got {
+		ids = append(ids, en.Ids)
+	}
+	decoded := tk.DecodeBatch(ids, false)
+	for i := range inputs {
+		want := sentences[i%len(sentences)]
+		if decoded[i] != want {
+			t.Errorf("sentence %v: want %q, got %q\n", i, want, decoded[i])
+		}
+	}
+
+	// Errors are reported rather than aborting the process.
+	model, err := wordlevel.New(map[string]int{"hello": 0}, "[UNK]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := tokenizer.NewTokenizer(model)
+	bad.WithPreTokenizer(pretokenizer.NewWhitespaceSplit())
+	_, err = bad.EncodeBatch(inputs[:2], false)
+	if err == nil {
+		t.Errorf("want error on unknown word without unk token\n")
+	}
+}
+
+func TestTokenizer_Decode(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	tk.AddSpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[CLS]", true), tokenizer.NewAddedToken("[SEP]", true)})
+	tk.WithDecoder(decoder.DefaultWordpieceDecoder())
+
+	ids := []int{2, 4, 5, 3}
+
+	got := tk.Decode(ids, true)
+	if got != "hello world" {
+		t.Errorf("want %q, got %q\n", "hello world", got)
+	}
+
+	got = tk.Decode(ids, false)
+	if got != "[CLS] hello world [SEP]" {
+		t.Errorf("want %q, got %q\n", "[CLS] hello world [SEP]", got)
+	}
+}
+
+func TestTokenizer_CleanUpTokenizationSpaces(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	tk.AddTokens([]tokenizer.AddedToken{
+		tokenizer.NewAddedToken(",", false),
+		tokenizer.NewAddedToken("!", false),
+		tokenizer.NewAddedToken("'re", false),
+	})
+
+	ids := []int{4, 11, 6, 7, 8, 12, 8, 13, 9, 12} // hello , how are you ! you 're good !
+	if got, want := tk.Decode(ids, false), "hello , how are you ! you 're good !"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	tk.WithCleanUpTokenizationSpaces(true)
+	want := "hello, how are you! you're good!"
+	if got := tk.Decode(ids, false); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if got := tk.DecodeBatch([][]int{ids, {4, 5}}, false); !reflect.DeepEqual([]string{want, "hello world"}, got) {
+		t.Errorf("want %q, got %q", []string{want, "hello world"}, got)
+	}
+
+	if got, want := tokenizer.CleanUpTokenization("it 's fine . is n't it ?"), "it's fine. isn't it?"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestTokenizer_SpecialTokensMask(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	tk.AddSpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[CLS]", true)})
+	tk.AddTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("hi", false)})
+
+	en, err := tk.EncodeSingle("[CLS] hello hi", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "hi" is assigned an id above the model vocab
+	wantIds := []int{2, 4, 11}
+	if !reflect.DeepEqual(wantIds, en.Ids) {
+		t.Errorf("want %#v\ngot %#v\n", wantIds, en.Ids)
+	}
+
+	wantMask := []int{1, 0, 0}
+	if !reflect.DeepEqual(wantMask, en.SpecialTokenMask) {
+		t.Errorf("want %#v\ngot %#v\n", wantMask, en.SpecialTokenMask)
+	}
+}
+
+func TestTokenizer_TruncationPadding(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	tk.WithPostProcessor(processor.NewBertProcessing(processor.PostToken{Value: "[SEP]", Id: 3}, processor.PostToken{Value: "[CLS]", Id: 2}))
+	trunc := tokenizer.DefaultTruncationParams()
+	trunc.MaxLength = 5
+	tk.WithTruncation(trunc)
+	tk.WithPadding(tokenizer.DefaultPaddingParams())
+
+	// Truncation accounts for the special tokens added by the post-processor.
+	got, err := tk.EncodeSingle("hello world how are you", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantIds := []int{2, 4, 5, 6, 3}
+	if !reflect.DeepEqual(wantIds, got.Ids) {
+		t.Errorf("want %#v\ngot %#v\n", wantIds, got.Ids)
+	}
+	wantOverflowing := []int{2, 7, 8, 3, 0}
+	if len(got.Overflowing) != 1 || !reflect.DeepEqual(wantOverflowing, got.Overflowing[0].Ids) {
+		t.Errorf("want overflowing %#v\ngot %#v\n", wantOverflowing, got.Overflowing)
+	}
+
+	// Batches are padded to their longest encoding.
+	inputs := []tokenizer.EncodeInput{
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("hello")),
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("good day")),
+	}
+	batch, err := tk.EncodeBatch(inputs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBatch := [][]int{{2, 4, 3, 0}, {2, 9, 10, 3}}
+	wantMasks := [][]int{{1, 1, 1, 0}, {1, 1, 1, 1}}
+	for i, en := range batch {
+		if !reflect.DeepEqual(wantBatch[i], en.Ids) {
+			t.Errorf("want %#v\ngot %#v\n", wantBatch[i], en.Ids)
+		}
+		if !reflect.DeepEqual(wantMasks[i], en.AttentionMask) {
+			t.Errorf("want %#v\ngot %#v\n", wantMasks[i], en.AttentionMask)
+		}
+	}
+}
+
+func TestTokenizer_OffsetsReferential(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	tk.WithNormalizer(normalizer.NewBertNormalizer(true, true, true, true))
+
+	input := "Héllo  Wörld"
+
+	en, err := tk.EncodeSingle(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]int{{0, 6}, {8, 14}}
+	if !reflect.DeepEqual(want, en.Offsets) {
+		t.Errorf("want %#v\ngot %#v\n", want, en.Offsets)
+	}
+
+	tk.WithOffsetsReferential(normalizer.NormalizedTarget)
+	en, err = tk.EncodeSingle(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = [][]int{{0, 5}, {7, 12}}
+	if !reflect.DeepEqual(want, en.Offsets) {
+		t.Errorf("want %#v\ngot %#v\n", want, en.Offsets)
+	}
+
+	// Char offsets are relative to the normalized chars
+	tk.WithNormalizer(normalizer.Lowercase())
+	en, err = tk.EncodeCharOffsets(tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("ÀÀ HELLO")), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = [][]int{{0, 2}, {3, 8}}
+	if !reflect.DeepEqual(want, en.Offsets) {
+		t.Errorf("want %#v\ngot %#v\n", want, en.Offsets)
+	}
+}
+
+func TestTokenizer_VocabLookup(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	tk.AddTokens([]tokenizer.AddedToken{
+		tokenizer.NewAddedToken("hello", false), // already in vocab
+		tokenizer.NewAddedToken("<new>", false),
+	})
+
+	if got := tk.GetVocabSize(false); got != 11 {
+		t.Errorf("GetVocabSize(false): want 11, got %v", got)
+	}
+	if got := tk.GetVocabSize(true); got != 12 {
+		t.Errorf("GetVocabSize(true): want 12, got %v", got)
+	}
+
+	vocab := tk.GetVocab(true)
+	if id := vocab["<new>"]; id != 11 {
+		t.Errorf("GetVocab(true): want <new> = 11, got %v", id)
+	}
+	// The model vocabulary must not be modified.
+	if _, ok := tk.GetVocab(false)["<new>"]; ok {
+		t.Errorf("GetVocab(false): unexpected added token")
+	}
+
+	if id, ok := tk.TokenToId("<new>"); !ok || id != 11 {
+		t.Errorf("TokenToId(<new>): want 11, got %v, %v", id, ok)
+	}
+	if id, ok := tk.TokenToId("world"); !ok || id != 5 {
+		t.Errorf("TokenToId(world): want 5, got %v, %v", id, ok)
+	}
+	if _, ok := tk.TokenToId("missing"); ok {
+		t.Errorf("TokenToId(missing): want not found")
+	}
+	if tok, ok := tk.IdToToken(11); !ok || tok != "<new>" {
+		t.Errorf("IdToToken(11): want <new>, got %q, %v", tok, ok)
+	}
+	if tok, ok := tk.IdToToken(4); !ok || tok != "hello" {
+		t.Errorf("IdToToken(4): want hello, got %q, %v", tok, ok)
+	}
+	if _, ok := tk.IdToToken(100); ok {
+		t.Errorf("IdToToken(100): want not found")
+	}
+}
+
+func TestTokenizer_EncodePreTokenizedString(t *testing.T) {
+	tk := newWordLevelTokenizer()
+
+	howAre, err := pretokenizer.NewWhitespaceSplit().PreTokenize(tokenizer.NewPreTokenizedString("how are"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := []tokenizer.PreTokenizedString{
+		*tokenizer.NewPreTokenizedString("hello"),
+		*howAre,
+		// Not pre-tokenized again by the tokenizer whitespace pre-tokenizer.
+		*tokenizer.NewPreTokenizedString("good day"),
+	}
+
+	input := tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence(words))
+	en, err := tk.Encode(input, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantTokens := []string{"hello", "how", "are", "[UNK]"}
+	wantWords := []int{0, 1, 1, 2}
+	wantOffsets := [][]int{{0, 5}, {0, 3}, {4, 7}, {0, 8}}
+	if !reflect.DeepEqual(wantTokens, en.Tokens) {
+		t.Errorf("tokens: want %v, got %v", wantTokens, en.Tokens)
+	}
+	if !reflect.DeepEqual(wantWords, en.Words) {
+		t.Errorf("words: want %v, got %v", wantWords, en.Words)
+	}
+	if !reflect.DeepEqual(wantOffsets, en.Offsets) {
+		t.Errorf("offsets: want %v, got %v", wantOffsets, en.Offsets)
+	}
+
+	// The input can be encoded again.
+	en2, err := tk.Encode(input, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(en.Tokens, en2.Tokens) {
+		t.Errorf("second encoding: want %v, got %v", en.Tokens, en2.Tokens)
+	}
+}
+
+func TestTokenizer_EncodeStream(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	tk.WithBatchWorkers(3)
+
+	var lines []string
+	for i := 0; i < 20; i++ {
+		lines = append(lines, "hello world", "how are you", "", "good day")
+	}
+
+	out, errc := tk.EncodeStream(strings.NewReader(strings.Join(lines, "\n")), tokenizer.StreamOpts{})
+
+	var got []string
+	for en := range out {
+		got = append(got, strings.Join(en.Tokens, " "))
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lines, got) {
+		t.Errorf("want %#v\ngot %#v\n", lines, got)
+	}
+
+	// Custom splitter
+	out, errc = tk.EncodeStream(strings.NewReader("hello world\nyou"), tokenizer.StreamOpts{Split: bufio.ScanWords})
+	got = nil
+	for en := range out {
+		got = append(got, strings.Join(en.Tokens, " "))
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"hello", "world", "you"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+
+	// Read error
+	r := io.MultiReader(strings.NewReader("hello\nworld\n"), iotest.ErrReader(io.ErrUnexpectedEOF))
+	out, errc = tk.EncodeStream(r, tokenizer.StreamOpts{})
+	n := 0
+	for range out {
+		n++
+	}
+	if err := <-errc; err != io.ErrUnexpectedEOF {
+		t.Errorf("want %v, got %v", io.ErrUnexpectedEOF, err)
+	}
+	if n != 2 {
+		t.Errorf("want 2 encodings before the error, got %v", n)
+	}
+}
+
+func TestTokenizer_TrainFromIterator(t *testing.T) {
+	lines := strings.Split(strings.Repeat("roses are red\nviolets are blue\n", 5), "\n")
+	var i int
+	it := func() (string, bool) {
+		if i >= len(lines) {
+			return "", false
+		}
+		i++
+		return lines[i-1], true
+	}
+
+	trainer := bpe.NewBpeTrainer(2, 30)
+	trainer.ShowProgress = false
+
+	var (
+		stages  []string
+		counted int
+	)
+	progress := func(p tokenizer.Progress) {
+		if len(stages) == 0 || stages[len(stages)-1] != p.Stage {
+			stages = append(stages, p.Stage)
+		}
+		if p.Stage == tokenizer.StageCountWords {
+			counted = p.Current
+		}
+	}
+
+	tk := tokenizer.NewTokenizer(nil)
+	tk.WithPreTokenizer(pretokenizer.NewWhitespaceSplit())
+	if err := tk.TrainFromIterator(it, trainer, progress); err != nil {
+		t.Fatal(err)
+	}
+
+	if counted != len(lines) {
+		t.Errorf("want %d sequences counted, got %d", len(lines), counted)
+	}
+	wantStages := []string{tokenizer.StageCountWords, "Tokenize words", "Count pairs", "Compute merges"}
+	if !reflect.DeepEqual(wantStages, stages) {
+		t.Errorf("want stages %q, got %q", wantStages, stages)
+	}
+
+	en, err := tk.EncodeSingle("roses are")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"roses", "are"}; !reflect.DeepEqual(want, en.Tokens) {
+		t.Errorf("want %q, got %q", want, en.Tokens)
+	}
+}
+
+func TestTokenizer_Fingerprint(t *testing.T) {
+	fingerprint := func(tk *tokenizer.Tokenizer) string {
+		fp, err := tk.Fingerprint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fp
+	}
+
+	// Stable across instances
+	want := fingerprint(newWordLevelTokenizer())
+	if got := fingerprint(newWordLevelTokenizer()); got != want {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	if len(want) != 64 {
+		t.Errorf("want hex encoded SHA-256, got %q", want)
+	}
+
+	for name, update := range map[string]func(tk *tokenizer.Tokenizer){
+		"normalizer": func(tk *tokenizer.Tokenizer) { tk.WithNormalizer(normalizer.Lowercase()) },
+		"truncation": func(tk *tokenizer.Tokenizer) {
+			tk.WithTruncation(&tokenizer.TruncationParams{MaxLength: 3, Strategy: tokenizer.OnlyFirst})
+		},
+		"added tokens": func(tk *tokenizer.Tokenizer) {
+			tk.AddSpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[MASK]", true)})
+		},
+		"clean up": func(tk *tokenizer.Tokenizer) { tk.WithCleanUpTokenizationSpaces(true) },
+		"BOM":      func(tk *tokenizer.Tokenizer) { tk.WithStripBOM(false) },
+		"max input chars": func(tk *tokenizer.Tokenizer) {
+			tk.WithMaxInputChars(10)
+		},
+		"offset type": func(tk *tokenizer.Tokenizer) { tk.WithOffsetType(tokenizer.Char) },
+		"offsets referential": func(tk *tokenizer.Tokenizer) {
+			tk.WithOffsetsReferential(normalizer.NormalizedTarget)
+		},
+		"alignments":        func(tk *tokenizer.Tokenizer) { tk.WithAlignments(false) },
+		"attach normalized": func(tk *tokenizer.Tokenizer) { tk.WithAttachNormalized(true) },
+	} {
+		tk := newWordLevelTokenizer()
+		update(tk)
+		if got := fingerprint(tk); got == want {
+			t.Errorf("%v: want a different fingerprint", name)
+		}
+	}
+
+	// Long input strategy
+	tk := newWordLevelTokenizer()
+	tk.WithMaxInputChars(10, tokenizer.RejectLongInput)
+	rejected := fingerprint(tk)
+	tk.WithMaxInputChars(10, tokenizer.ChunkLongInput)
+	if got := fingerprint(tk); got == rejected {
+		t.Errorf("max input strategy: want a different fingerprint")
+	}
+
+	// Overflowing cap, not part of the serialized truncation
+	tk = newWordLevelTokenizer()
+	trunc := tokenizer.DefaultTruncationParams()
+	tk.WithTruncation(trunc)
+	capped := fingerprint(tk)
+	trunc.MaxOverflowing = 2
+	if got := fingerprint(tk); got == capped {
+		t.Errorf("max overflowing: want a different fingerprint")
+	}
+
+	// Not serializable
+	tk = newWordLevelTokenizer()
+	pretok := pretokenizer.NewWhitespaceSplit()
+	pretok.SetIncludeTrailingSpace(true)
+	tk.WithPreTokenizer(pretok)
+	if _, err := tk.Fingerprint(); err == nil {
+		t.Errorf("want error")
+	}
+}
+
+func TestTokenizer_PruneVocab(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	tk.AddSpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[PAD]", true), tokenizer.NewAddedToken("[MASK]", true)})
+	tk.AddTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("<new>", false)})
+	padding := tokenizer.DefaultPaddingParams()
+	padding.PadId = 0
+	tk.WithPadding(padding)
+
+	lines := []string{"hello you", "good day you"}
+	var i int
+	it := func() (string, bool) {
+		if i >= len(lines) {
+			return "", false
+		}
+		i++
+		return lines[i-1], true
+	}
+	freqs, err := tk.TokenFrequencies(it)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping, err := tk.PruneVocabByFrequency(freqs, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Kept: [PAD] (added), [UNK] (unk), hello, you, good, day, then the added
+	// tokens [MASK] (11) and <new> (12).
+	wantMapping := map[int]int{0: 0, 1: 1, 4: 2, 8: 3, 9: 4, 10: 5, 11: 6, 12: 7}
+	if !reflect.DeepEqual(wantMapping, mapping) {
+		t.Errorf("want %v, got %v", wantMapping, mapping)
+	}
+	if got := tk.GetVocabSize(true); got != 8 {
+		t.Errorf("want vocab size 8, got %v", got)
+	}
+
+	en, err := tk.EncodeSingle("[MASK] hello world <new>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{6, 2, 1, 7}; !reflect.DeepEqual(want, en.Ids) {
+		t.Errorf("want %v, got %v", want, en.Ids)
+	}
+	if got := tk.GetPadding().PadId; got != 0 {
+		t.Errorf("want pad id 0, got %v", got)
+	}
+}
+
+func TestTokenizer_WithAlignments(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	tk.WithNormalizer(normalizer.NewBertNormalizer(true, true, true, true))
+	if !tk.GetAlignments() {
+		t.Fatalf("want alignments tracked by default")
+	}
+
+	en, err := tk.EncodeSingle("Héllo WÖrld [SEP]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]int{{0, 6}, {7, 13}, {14, 19}}; !reflect.DeepEqual(want, en.Offsets) {
+		t.Errorf("want %v, got %v", want, en.Offsets)
+	}
+
+	// Same ids, offsets on the normalized input
+	tk.WithAlignments(false)
+	got, err := tk.EncodeSingle("Héllo WÖrld [SEP]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(en.Ids, got.Ids) {
+		t.Errorf("want %v, got %v", en.Ids, got.Ids)
+	}
+	if want := [][]int{{0, 5}, {6, 11}, {12, 17}}; !reflect.DeepEqual(want, got.Offsets) {
+		t.Errorf("want %v, got %v", want, got.Offsets)
+	}
+}
+
+func TestTokenizer_WithMaxInputChars(t *testing.T) {
+	input := "hello world how are you"
+	tk := newWordLevelTokenizer()
+	want, err := tk.EncodeSingle(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tk.WithMaxInputChars(10)
+	_, err = tk.EncodeSingle(input)
+	if !errors.Is(err, tokenizer.ErrInputTooLong) {
+		t.Errorf("want ErrInputTooLong, got %v", err)
+	}
+	if _, err := tk.EncodeSingle("hello you"); err != nil {
+		t.Errorf("want no error below the limit, got %v", err)
+	}
+
+	// Chunks "hello ", "world how " and "are you"
+	tk.WithMaxInputChars(10, tokenizer.ChunkLongInput)
+	for _, offsetType := range []tokenizer.OffsetType{tokenizer.Byte, tokenizer.Char} {
+		tk.WithOffsetType(offsetType)
+		got, err := tk.EncodeSingle(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want.Ids, got.Ids) {
+			t.Errorf("want %v, got %v", want.Ids, got.Ids)
+		}
+		if !reflect.DeepEqual(want.Offsets, got.Offsets) {
+			t.Errorf("want %v, got %v", want.Offsets, got.Offsets)
+		}
+		if !reflect.DeepEqual(want.Words, got.Words) {
+			t.Errorf("want %v, got %v", want.Words, got.Words)
+		}
+	}
+
+	tk.WithOffsetType(tokenizer.Byte)
+	tk.WithOffsetsReferential(normalizer.NormalizedTarget)
+	got, err := tk.EncodeSingle(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want.Offsets, got.Offsets) {
+		t.Errorf("want %v, got %v", want.Offsets, got.Offsets)
+	}
+}
+
+// Added tokens are split out before pre-tokenization, and must survive the
+// byte-level mapping of the remaining splits.
+func TestTokenizer_ByteLevelKeepsAddedTokens(t *testing.T) {
+	vocab := map[string]int{"[UNK]": 0, "hello": 1, "Ġworld": 2, "[SEP]": 3}
+	model, err := wordlevel.New(vocab, "[UNK]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := tokenizer.NewTokenizer(model)
+	byteLevel := pretokenizer.NewByteLevel()
+	byteLevel.SetAddPrefixSpace(false)
+	tk.WithPreTokenizer(byteLevel)
+	tk.AddSpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[SEP]", true)})
+
+	en, err := tk.EncodeSingle("hello[SEP] world")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantToks := []string{"hello", "[SEP]", "Ġworld"}
+	if !reflect.DeepEqual(wantToks, en.Tokens) {
+		t.Errorf("want %#v\ngot %#v\n", wantToks, en.Tokens)
+	}
+	wantOffsets := [][]int{{0, 5}, {5, 10}, {10, 16}}
+	if !reflect.DeepEqual(wantOffsets, en.Offsets) {
+		t.Errorf("want %#v\ngot %#v\n", wantOffsets, en.Offsets)
+	}
+}
+
+// overflowingModel returns token offsets past the end of the input.
+type overflowingModel struct {
+	tokenizer.Model
+}
+
+func (m overflowingModel) Tokenize(sequence string) ([]tokenizer.Token, error) {
+	toks, err := m.Model.Tokenize(sequence)
+	for i := range toks {
+		toks[i].Offsets = []int{toks[i].Offsets[0] + 20, toks[i].Offsets[1] + 20}
+	}
+
+	return toks, err
+}
+
+func TestTokenizer_ClampedOffsets(t *testing.T) {
+	vocab := map[string]int{"[UNK]": 0, "héllo": 1, "world": 2}
+	model, err := wordlevel.New(vocab, "[UNK]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := tokenizer.NewTokenizer(overflowingModel{model})
+	tk.WithPreTokenizer(pretokenizer.NewWhitespaceSplit())
+
+	input := tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("héllo world"))
+	en, err := tk.EncodeCharOffsets(input, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]int{{11, 11}, {11, 11}}
+	if !reflect.DeepEqual(want, en.Offsets) {
+		t.Errorf("want %#v\ngot %#v\n", want, en.Offsets)
+	}
+	if got := tk.ClampedOffsets(); got != 2 {
+		t.Errorf("want 2 clamped offsets, got %v", got)
+	}
+}
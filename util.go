@@ -2,21 +2,71 @@ package tokenizer
 
 import (
 	"errors"
-	"log"
+	"fmt"
 )
 
+// TruncationParams configures the truncation applied by the tokenizer when
+// encoding (see `Tokenizer.WithTruncation`).
 type TruncationParams struct {
-	MaxLength int
-	Strategy  TruncationStrategy
-	Stride    int
+	MaxLength int                // Maximum number of tokens, special tokens included
+	Strategy  TruncationStrategy // How to truncate a pair of sequences
+	Stride    int                // Number of tokens repeated between overflowing parts
+	// MaxOverflowing caps the number of overflowing encodings of a truncated
+	// pair of sequences, whose overflowing parts are merged in all
+	// combinations: zero for `DefaultMaxOverflowing`, negative for no cap.
+	MaxOverflowing int
 }
 
+// DefaultMaxOverflowing is the default cap of overflowing encodings of a
+// truncated pair of sequences (see `TruncationParams.MaxOverflowing`).
+const DefaultMaxOverflowing int = 1024
+
+// DefaultTruncationParams returns truncation params with default values:
+// 512 tokens, `LongestFirst` strategy and no stride.
+func DefaultTruncationParams() *TruncationParams {
+	return &TruncationParams{
+		MaxLength:      512,
+		Strategy:       LongestFirst,
+		Stride:         0,
+		MaxOverflowing: DefaultMaxOverflowing,
+	}
+}
+
+// maxOverflowing returns the cap of overflowing encodings, -1 if none.
+func (p *TruncationParams) maxOverflowing() int {
+	switch {
+	case p.MaxOverflowing == 0:
+		return DefaultMaxOverflowing
+	case p.MaxOverflowing < 0:
+		return -1
+	}
+
+	return p.MaxOverflowing
+}
+
+// PaddingParams configures the padding applied by the tokenizer when encoding
+// (see `Tokenizer.WithPadding`).
 type PaddingParams struct {
-	Strategy  PaddingStrategy
-	Direction PaddingDirection
+	Strategy  PaddingStrategy  // Pad to a fixed length or to the longest encoding of the batch
+	Direction PaddingDirection // Side to add padding tokens to
 	PadId     int
 	PadTypeId int
 	PadToken  string
+	// PadToMultipleOf, if greater than zero, rounds the padding length up to
+	// a multiple of it (e.g. 8 to use tensor cores efficiently).
+	PadToMultipleOf int
+}
+
+// DefaultPaddingParams returns padding params with default values: padding
+// to the longest encoding of the batch on the right, with `[PAD]` of id 0.
+func DefaultPaddingParams() *PaddingParams {
+	return &PaddingParams{
+		Strategy:  *NewPaddingStrategy(WithBatchLongest()),
+		Direction: Right,
+		PadId:     0,
+		PadTypeId: 0,
+		PadToken:  "[PAD]",
+	}
 }
 
 // PaddingStrategy is a enum of either
@@ -80,74 +130,116 @@ const (
 	SequenceTooShort          = "Truncation error: Sequence to truncate too short to respect the provided max_length"
 )
 
-func TruncateEncodings(encoding, pairEncoding *Encoding, params *TruncationParams) (tEncoding, tPairEncoding *Encoding) {
-	var (
-		totalLength int
-		toRemove    int
-		err         error
-	)
+// TruncateEncodings truncates a sequence, or a pair of sequences, so that
+// their total length does not exceed `params.MaxLength`. The removed tokens
+// are kept in `Overflowing`.
+//
+// The truncated encodings are capped to `params.MaxOverflowing` overflowing
+// encodings when merged with `Encoding.MergeWith` (ie. by post-processors).
+//
+// A pair of sequences is truncated according to `params.Strategy`:
+//   - `LongestFirst` removes tokens from the longest sequence first, so both
+//     end up with about the same length when both have to be cut.
+//   - `OnlyFirst` and `OnlySecond` only truncate the given sequence. It returns
+//     an error if that sequence is too short to respect `MaxLength`.
+func TruncateEncodings(encoding, pairEncoding *Encoding, params *TruncationParams) (tEncoding, tPairEncoding *Encoding, err error) {
+	defer func() {
+		if err != nil {
+			return
+		}
+		encoding.maxOverflowing = params.maxOverflowing()
+		if pairEncoding != nil {
+			pairEncoding.maxOverflowing = params.maxOverflowing()
+		}
+	}()
 
 	if params.MaxLength == 0 {
-		return encoding, pairEncoding
+		if _, err = encoding.Truncate(0, params.Stride); err != nil {
+			return nil, nil, err
+		}
+		if pairEncoding != nil {
+			if _, err = pairEncoding.Truncate(0, params.Stride); err != nil {
+				return nil, nil, err
+			}
+		}
+		return encoding, pairEncoding, nil
 	}
 
-	totalLength = len(encoding.GetIds())
+	totalLength := encoding.Len()
 	if pairEncoding != nil {
-		totalLength = len(encoding.GetIds()) + len(pairEncoding.GetIds())
+		totalLength += pairEncoding.Len()
 	}
-
-	if totalLength < params.MaxLength {
-		return encoding, pairEncoding
+	if totalLength <= params.MaxLength {
+		return encoding, pairEncoding, nil
 	}
-
-	toRemove = totalLength - params.MaxLength
+	toRemove := totalLength - params.MaxLength
 
 	switch params.Strategy {
 	case LongestFirst:
-		nFirst := len(encoding.GetIds())
-		nSecond := len(pairEncoding.GetIds())
+		if pairEncoding == nil {
+			_, err = encoding.Truncate(params.MaxLength, params.Stride)
+			break
+		}
 
-		for i := 0; i < toRemove; i++ {
-			if nFirst > nSecond {
-				nFirst -= 1
-			}
-			nSecond -= 1
+		// With n1 <= n2, either only the longest sequence is truncated
+		// (n2 = MaxLength - n1), or both are cut to about MaxLength / 2.
+		n1, n2 := encoding.Len(), pairEncoding.Len()
+		swap := n1 > n2
+		if swap {
+			n1, n2 = n2, n1
+		}
+		if n1 > params.MaxLength {
+			n2 = n1
+		} else if n2 = params.MaxLength - n1; n2 < n1 {
+			n2 = n1
+		}
+		if n1+n2 > params.MaxLength {
+			n1 = params.MaxLength / 2
+			n2 = n1 + params.MaxLength%2
+		}
+		if swap {
+			n1, n2 = n2, n1
 		}
 
-		encoding.Truncate(nFirst, params.Stride)
-		if pairEncoding != nil {
-			pairEncoding.Truncate(nSecond, params.Stride)
+		if _, err = encoding.Truncate(n1, params.Stride); err != nil {
+			break
 		}
+		_, err = pairEncoding.Truncate(n2, params.Stride)
 
 	case OnlyFirst, OnlySecond:
-		var truncateFunc = func(target *Encoding) (*Encoding, error) {
-			targetLength := len(target.GetIds())
-			if targetLength > toRemove {
-				target.Truncate(targetLength-toRemove, params.Stride)
-				return target, nil
-			} else {
-				err := errors.New(SequenceTooShort)
-				return nil, err
+		target := encoding
+		if params.Strategy == OnlySecond {
+			if pairEncoding == nil {
+				err = errors.New(SecondSequenceNotProvided)
+				break
 			}
+			target = pairEncoding
 		}
 
-		if params.Strategy == OnlyFirst {
-			encoding, err = truncateFunc(encoding)
-		} else if pairEncoding != nil {
-			pairEncoding, err = truncateFunc(pairEncoding)
-		} else {
-			err = errors.New(SecondSequenceNotProvided)
+		if target.Len() <= toRemove {
+			err = errors.New(SequenceTooShort)
+			break
 		}
+		_, err = target.Truncate(target.Len()-toRemove, params.Stride)
 
+	default:
+		err = fmt.Errorf("Invalid truncation strategy: %v", params.Strategy)
 	}
 
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, err
 	}
 
-	return encoding, pairEncoding
+	return encoding, pairEncoding, nil
 }
 
+// PadEncodings pads all encodings in place, including their `Overflowing`,
+// to the same length: the fixed length of the strategy or the length of the
+// longest encoding of the batch, rounded up to `params.PadToMultipleOf`.
+// Encodings already longer than this length are left untouched.
+//
+// It returns the padded encodings, see `AttentionMasks` to get their
+// attention masks for batched inference.
 func PadEncodings(encodings []Encoding, params PaddingParams) []Encoding {
 	if len(encodings) == 0 {
 		return encodings
@@ -169,14 +261,104 @@ func PadEncodings(encodings []Encoding, params PaddingParams) []Encoding {
 	}
 
 	// TODO: implement concurrency with for loop
-	var newEncodings []Encoding
-	for _, e := range encodings {
-		en := e
-		paddedEn := en.Pad(padLength, params.PadId, params.PadTypeId, params.PadToken, params.Direction)
-		newEncodings = append(newEncodings, *paddedEn)
+	for i := range encodings {
+		encodings[i].Pad(padLength, params.PadId, params.PadTypeId, params.PadToken, params.Direction, params.PadToMultipleOf)
 	}
 
-	return newEncodings
+	return encodings
+}
+
+// AttentionMasks returns the attention mask of each encoding, ie. a batch of
+// masks where padding positions are 0.
+func AttentionMasks(encodings []Encoding) [][]int {
+	masks := make([][]int, len(encodings))
+	for i := range encodings {
+		masks[i] = encodings[i].GetAttentionMask()
+	}
+
+	return masks
+}
+
+// EncodingsToMatrix returns the ids of encodings as a matrix of int64, one row
+// per encoding. Rows shorter than the longest encoding are right padded with
+// padId, encodings are left untouched.
+func EncodingsToMatrix(encodings []Encoding, padId int) [][]int64 {
+	var maxLen int
+	for i := range encodings {
+		if l := encodings[i].Len(); l > maxLen {
+			maxLen = l
+		}
+	}
+
+	m := make([][]int64, len(encodings))
+	for i := range encodings {
+		row := make([]int64, maxLen)
+		for j := range row {
+			row[j] = int64(padId)
+			if j < len(encodings[i].Ids) {
+				row[j] = int64(encodings[i].Ids[j])
+			}
+		}
+		m[i] = row
+	}
+
+	return m
+}
+
+// FlattenOverflowing returns each encoding followed by its overflowing
+// encodings, as HuggingFace `return_overflowing_tokens` does, and the index in
+// encodings of the source of each returned encoding (ie. HuggingFace
+// `overflow_to_sample_mapping`). Returned encodings have no overflowing
+// encodings.
+func FlattenOverflowing(encodings []Encoding) (flat []Encoding, sampleMapping []int) {
+	for i, en := range encodings {
+		overflowing := en.Overflowing
+		en.Overflowing = nil
+		flat = append(flat, en)
+		sampleMapping = append(sampleMapping, i)
+		for _, o := range overflowing {
+			o.Overflowing = nil
+			flat = append(flat, o)
+			sampleMapping = append(sampleMapping, i)
+		}
+	}
+
+	return flat, sampleMapping
+}
+
+// Padder pads encodings to a fixed length configured once. It helps to get
+// identically-shaped outputs across separate `EncodeBatch` calls (e.g. over an epoch).
+type Padder struct {
+	params PaddingParams
+}
+
+// NewPadder creates a Padder padding to given length with given pad values.
+func NewPadder(length int, padId, padTypeId int, padToken string, direction PaddingDirection) *Padder {
+	strategy := NewPaddingStrategy(WithFixed(length))
+	return &Padder{
+		params: PaddingParams{
+			Strategy:  *strategy,
+			Direction: direction,
+			PadId:     padId,
+			PadTypeId: padTypeId,
+			PadToken:  padToken,
+		},
+	}
+}
+
+// Length returns the fixed length the Padder pads to.
+func (p *Padder) Length() int {
+	return p.params.Strategy.Value.(int)
+}
+
+// Pad pads a single encoding to the Padder fixed length.
+func (p *Padder) Pad(encoding *Encoding) *Encoding {
+	return encoding.Pad(p.Length(), p.params.PadId, p.params.PadTypeId, p.params.PadToken, p.params.Direction)
+}
+
+// PadBatch pads all encodings to the Padder fixed length.
+func (p *Padder) PadBatch(encodings []Encoding) []Encoding {
+	return PadEncodings(encodings, p.params)
 }
 
 type Range []int
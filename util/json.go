@@ -0,0 +1,32 @@
+package util
+
+import (
+	"encoding/json"
+)
+
+// MarshalTyped marshals v as a JSON object with an extra "type" field set to
+// typ. This is the layout used by HuggingFace `tokenizer.json` files for
+// normalizers, pre-tokenizers, models, post-processors and decoders.
+//
+// v must marshal to a JSON object (or be nil).
+func MarshalTyped(typ string, v interface{}) ([]byte, error) {
+	fields := make(map[string]json.RawMessage)
+	if v != nil {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(data, &fields)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	t, err := json.Marshal(typ)
+	if err != nil {
+		return nil, err
+	}
+	fields["type"] = t
+
+	return json.Marshal(fields)
+}
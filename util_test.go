@@ -0,0 +1,201 @@
+package tokenizer_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+)
+
+func TestPadder(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	padder := tokenizer.NewPadder(6, 0, 0, "[PAD]", tokenizer.Right)
+
+	batch1, err := tk.EncodeBatch([]tokenizer.EncodeInput{
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("hello world")),
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("how are you")),
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batch2, err := tk.EncodeBatch([]tokenizer.EncodeInput{
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("good day")),
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var padded []tokenizer.Encoding
+	padded = append(padded, padder.PadBatch(batch1)...)
+	padded = append(padded, *padder.Pad(&batch2[0]))
+
+	for _, en := range padded {
+		if en.Len() != 6 {
+			t.Errorf("Want length 6, got %v: %v\n", en.Len(), en.Tokens)
+		}
+	}
+
+	testMapping(t, padded[2].Ids, []int{9, 10, 0, 0, 0, 0})
+	testMapping(t, padded[2].AttentionMask, []int{1, 1, 0, 0, 0, 0})
+}
+
+func TestTruncateEncodings(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	encode := func(s string, typeId int) *tokenizer.Encoding {
+		en, err := tk.EncodeSingleSequence(tokenizer.NewInputSequence(s), typeId, tokenizer.Byte)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return en
+	}
+	first := "hello world how are you" // 5 tokens
+	second := "good day"               // 2 tokens
+
+	tests := []struct {
+		strategy   tokenizer.TruncationStrategy
+		maxLength  int
+		wantFirst  []int
+		wantSecond []int
+	}{
+		// only the longest sequence is truncated
+		{tokenizer.LongestFirst, 5, []int{4, 5, 6}, []int{9, 10}},
+		// both are truncated
+		{tokenizer.LongestFirst, 3, []int{4, 5}, []int{9}},
+		{tokenizer.LongestFirst, 2, []int{4}, []int{9}},
+		{tokenizer.OnlyFirst, 4, []int{4, 5}, []int{9, 10}},
+		{tokenizer.OnlySecond, 6, []int{4, 5, 6, 7, 8}, []int{9}},
+		// nothing to truncate
+		{tokenizer.OnlySecond, 7, []int{4, 5, 6, 7, 8}, []int{9, 10}},
+	}
+
+	for _, tt := range tests {
+		params := &tokenizer.TruncationParams{MaxLength: tt.maxLength, Strategy: tt.strategy}
+		en, pair, err := tokenizer.TruncateEncodings(encode(first, 0), encode(second, 1), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tt.wantFirst, en.Ids) || !reflect.DeepEqual(tt.wantSecond, pair.Ids) {
+			t.Errorf("%v/%v: want %v %v\ngot %v %v\n", tt.strategy, tt.maxLength, tt.wantFirst, tt.wantSecond, en.Ids, pair.Ids)
+		}
+	}
+
+	// Errors are returned rather than aborting the process.
+	params := &tokenizer.TruncationParams{MaxLength: 4, Strategy: tokenizer.OnlySecond}
+	if _, _, err := tokenizer.TruncateEncodings(encode(first, 0), encode(second, 1), params); err == nil {
+		t.Errorf("want error on too short second sequence\n")
+	}
+	if _, _, err := tokenizer.TruncateEncodings(encode(first, 0), nil, params); err == nil {
+		t.Errorf("want error on missing second sequence\n")
+	}
+
+	// Single sequences are cut to MaxLength.
+	params = &tokenizer.TruncationParams{MaxLength: 2, Strategy: tokenizer.LongestFirst, Stride: 1}
+	en, _, err := tokenizer.TruncateEncodings(encode(first, 0), nil, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantOverflowing := [][]int{{5, 6}, {6, 7}, {7, 8}}
+	var gotOverflowing [][]int
+	for _, o := range en.Overflowing {
+		gotOverflowing = append(gotOverflowing, o.Ids)
+	}
+	if !reflect.DeepEqual([]int{4, 5}, en.Ids) || !reflect.DeepEqual(wantOverflowing, gotOverflowing) {
+		t.Errorf("want %v %v\ngot %v %v\n", []int{4, 5}, wantOverflowing, en.Ids, gotOverflowing)
+	}
+}
+
+func TestPadEncodings(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	batch, err := tk.EncodeBatch([]tokenizer.EncodeInput{
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("hello world how are you")),
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("good day")),
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := batch[0].Truncate(3, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	params := tokenizer.DefaultPaddingParams()
+	tokenizer.PadEncodings(batch, *params)
+
+	// Encodings are padded in place, overflowing included.
+	wantIds := [][]int{{4, 5, 6}, {9, 10, 0}}
+	for i, en := range batch {
+		if !reflect.DeepEqual(wantIds[i], en.Ids) {
+			t.Errorf("want %#v\ngot %#v\n", wantIds[i], en.Ids)
+		}
+	}
+	wantOverflowing := []int{7, 8, 0}
+	if !reflect.DeepEqual(wantOverflowing, batch[0].Overflowing[0].Ids) {
+		t.Errorf("want %#v\ngot %#v\n", wantOverflowing, batch[0].Overflowing[0].Ids)
+	}
+
+	wantMasks := [][]int{{1, 1, 1}, {1, 1, 0}}
+	if got := tokenizer.AttentionMasks(batch); !reflect.DeepEqual(wantMasks, got) {
+		t.Errorf("want %#v\ngot %#v\n", wantMasks, got)
+	}
+}
+
+func TestEncodingsToMatrix(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	batch, err := tk.EncodeBatch([]tokenizer.EncodeInput{
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("hello world")),
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("how are you")),
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := batch[0].ToInt64Slice(), []int64{4, 5}; !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+
+	want := [][]int64{{4, 5, 0}, {6, 7, 8}}
+	if got := tokenizer.EncodingsToMatrix(batch, 0); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %#v\ngot %#v\n", want, got)
+	}
+	if batch[0].Len() != 2 {
+		t.Errorf("want encodings left untouched, got %v", batch[0].Ids)
+	}
+}
+
+func TestFlattenOverflowing(t *testing.T) {
+	tk := newWordLevelTokenizer()
+	tk.WithTruncation(&tokenizer.TruncationParams{MaxLength: 2})
+
+	batch, err := tk.EncodeBatch([]tokenizer.EncodeInput{
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("hello world how are you")),
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("good")),
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("good day you")),
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flat, mapping := tokenizer.FlattenOverflowing(batch)
+
+	wantTokens := [][]string{{"hello", "world"}, {"how", "are"}, {"you"}, {"good"}, {"good", "day"}, {"you"}}
+	var gotTokens [][]string
+	for _, en := range flat {
+		gotTokens = append(gotTokens, en.Tokens)
+		if len(en.Overflowing) > 0 {
+			t.Errorf("want no overflowing, got %v", len(en.Overflowing))
+		}
+	}
+	if !reflect.DeepEqual(wantTokens, gotTokens) {
+		t.Errorf("want %#v\ngot %#v\n", wantTokens, gotTokens)
+	}
+
+	wantMapping := []int{0, 0, 0, 1, 2, 2}
+	if !reflect.DeepEqual(wantMapping, mapping) {
+		t.Errorf("want %#v\ngot %#v\n", wantMapping, mapping)
+	}
+
+	// Source encodings keep their overflowing encodings.
+	if len(batch[0].Overflowing) != 2 {
+		t.Errorf("want 2 overflowing encodings, got %v", len(batch[0].Overflowing))
+	}
+}